@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/artwork"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
+)
+
+// albumArtMPD is the part of the MPD client the album art handler uses.
+type albumArtMPD interface {
+	AlbumArt(uri string) ([]byte, error)
+	ReadPicture(uri string) ([]byte, error)
+	Capabilities() (mpd.CapabilityFlags, bool)
+}
+
+// artSource is one place /albumart looks for a track's artwork.
+type artSource string
+
+const (
+	artFromFilesystem  artSource = "filesystem"  // Image files beside the track
+	artFromAlbumArt    artSource = "albumart"    // MPD folder art (0.21+)
+	artFromReadPicture artSource = "readpicture" // MPD embedded art (0.22+)
+)
+
+// albumArtSources returns where to look for art, in order. By default MPD is
+// asked first since embedded pictures are specific to the track, and image
+// files beside the track are the final fallback, which covers rips without
+// embedded art when MPD's albumart is unavailable. With PreferFolder the
+// order is reversed so cover files win over embedded thumbnails. Commands the
+// server doesn't support are skipped so old servers aren't sent commands that
+// can only fail, and until capabilities are known both are tried.
+func albumArtSources(caps mpd.CapabilityFlags, known bool, priority artwork.Priority) []artSource {
+	var sources []artSource
+	if !known || caps.HasReadPicture {
+		sources = append(sources, artFromReadPicture)
+	}
+	if !known || caps.HasAlbumArt {
+		sources = append(sources, artFromAlbumArt)
+	}
+	sources = append(sources, artFromFilesystem)
+	if priority == artwork.PreferFolder {
+		slices.Reverse(sources)
+	}
+	return sources
+}
+
+// artPlaceholder is served by /albumart instead of a 404 for tracks without
+// art, so clients can show a consistent tile.
+type artPlaceholder struct {
+	byDefault bool   // Serve it unless the request has placeholder=0
+	image     []byte // Configured image; nil generates one from the album title
+}
+
+// placeholderCacheAge is how long clients may cache a placeholder. It is
+// shorter than for real art, which may be added to the album later.
+const placeholderCacheAge = "public, max-age=3600"
+
+// wanted reports whether a request should get the placeholder. The
+// placeholder query parameter overrides the server default either way.
+func (p artPlaceholder) wanted(r *http.Request) bool {
+	if v := r.URL.Query().Get("placeholder"); v != "" {
+		on, err := strconv.ParseBool(v)
+		return err == nil && on
+	}
+	return p.byDefault
+}
+
+// serve writes the placeholder for the album titled title.
+func (p artPlaceholder) serve(w http.ResponseWriter, title string) {
+	data := p.image
+	if data == nil {
+		data = artwork.Placeholder(title)
+	}
+	w.Header().Set("Content-Type", artwork.DetectMimeType(data))
+	w.Header().Set("Cache-Control", placeholderCacheAge)
+	w.Write(data)
+}
+
+// albumArtHandler serves a track's album art by MPD URI, preferring embedded
+// or folder art as priorities says. Tracks without art get a 404, or the
+// placeholder if the server or request asks for it; its color comes from the
+// title parameter, or the album directory's name when there is none.
+func albumArtHandler(finder *artwork.FilesystemFinder, client albumArtMPD, priorities *artwork.PriorityStore, placeholder artPlaceholder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri := r.URL.Query().Get("path")
+		if uri == "" {
+			http.Error(w, "path parameter required", http.StatusBadRequest)
+			return
+		}
+		// The path reaches MPD commands and the filesystem, so anything that
+		// could escape the music directory is refused outright
+		if !artwork.ValidTrackURI(uri) {
+			log.Warn().Str("path", uri).Msg("Rejected album art path")
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		uri = path.Clean(uri)
+
+		caps, known := client.Capabilities()
+		for _, source := range albumArtSources(caps, known, priorities.Get()) {
+			var data []byte
+			var err error
+			switch source {
+			case artFromFilesystem:
+				// Cover files in the track's directory or its parents, within
+				// the music directory and mount roots
+				var artPath string
+				if artPath, err = finder.FindArtwork(uri); err == nil && artPath != "" {
+					data, err = finder.ReadArtwork(artPath)
+				}
+			case artFromAlbumArt:
+				data, err = client.AlbumArt(uri)
+			case artFromReadPicture:
+				data, err = client.ReadPicture(uri)
+			}
+			if err == nil && len(data) > 0 {
+				log.Debug().Str("path", uri).Str("source", string(source)).Msg("Serving artwork")
+				serveArtwork(w, data)
+				return
+			}
+		}
+
+		if placeholder.wanted(r) {
+			title := r.URL.Query().Get("title")
+			if title == "" {
+				title = path.Base(path.Dir(uri))
+			}
+			log.Debug().Str("path", uri).Msg("Album art not found, serving placeholder")
+			placeholder.serve(w, title)
+			return
+		}
+
+		log.Debug().Str("path", uri).Msg("Album art not found")
+		http.Error(w, "album art not found", http.StatusNotFound)
+	}
+}
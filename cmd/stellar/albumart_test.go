@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/artwork"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
+)
+
+// fakeArtMPD records which MPD art commands the handler sends.
+type fakeArtMPD struct {
+	caps     mpd.CapabilityFlags
+	known    bool
+	albumArt []byte
+	picture  []byte
+	commands []string
+}
+
+func (f *fakeArtMPD) AlbumArt(uri string) ([]byte, error) {
+	f.commands = append(f.commands, "albumart")
+	if f.albumArt == nil {
+		return nil, errors.New("no file exists")
+	}
+	return f.albumArt, nil
+}
+
+func (f *fakeArtMPD) ReadPicture(uri string) ([]byte, error) {
+	f.commands = append(f.commands, "readpicture")
+	if f.picture == nil {
+		return nil, errors.New("no picture")
+	}
+	return f.picture, nil
+}
+
+func (f *fakeArtMPD) Capabilities() (mpd.CapabilityFlags, bool) {
+	return f.caps, f.known
+}
+
+func TestAlbumArtSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		caps     mpd.CapabilityFlags
+		known    bool
+		priority artwork.Priority
+		want     []artSource
+	}{
+		{"unknown tries everything", mpd.CapabilityFlags{}, false, artwork.PreferEmbedded,
+			[]artSource{artFromReadPicture, artFromAlbumArt, artFromFilesystem}},
+		{"modern MPD", mpd.CapabilityFlags{HasAlbumArt: true, HasReadPicture: true}, true, artwork.PreferEmbedded,
+			[]artSource{artFromReadPicture, artFromAlbumArt, artFromFilesystem}},
+		{"MPD 0.21", mpd.CapabilityFlags{HasAlbumArt: true}, true, artwork.PreferEmbedded,
+			[]artSource{artFromAlbumArt, artFromFilesystem}},
+		{"neither command", mpd.CapabilityFlags{}, true, artwork.PreferEmbedded,
+			[]artSource{artFromFilesystem}},
+		{"folder first", mpd.CapabilityFlags{HasAlbumArt: true, HasReadPicture: true}, true, artwork.PreferFolder,
+			[]artSource{artFromFilesystem, artFromAlbumArt, artFromReadPicture}},
+		{"folder first on MPD 0.21", mpd.CapabilityFlags{HasAlbumArt: true}, true, artwork.PreferFolder,
+			[]artSource{artFromFilesystem, artFromAlbumArt}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := albumArtSources(tt.caps, tt.known, tt.priority); !slices.Equal(got, tt.want) {
+				t.Errorf("albumArtSources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlbumArtHandler(t *testing.T) {
+	musicDir := t.TempDir()
+	os.MkdirAll(filepath.Join(musicDir, "NAS", "WithCover"), 0755)
+	os.MkdirAll(filepath.Join(musicDir, "NAS", "Bare"), 0755)
+	os.WriteFile(filepath.Join(musicDir, "NAS", "WithCover", "cover.jpg"), []byte("\xff\xd8\xffcover"), 0644)
+	finder := artwork.NewFilesystemFinder(musicDir)
+	priorities := artwork.NewPriorityStore(t.TempDir())
+	var placeholder artPlaceholder
+
+	get := func(client albumArtMPD, path string, params ...string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		target := "/albumart?path=" + url.QueryEscape(path)
+		for _, param := range params {
+			target += "&" + param
+		}
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		albumArtHandler(finder, client, priorities, placeholder).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("old MPD uses the filesystem without MPD commands", func(t *testing.T) {
+		client := &fakeArtMPD{known: true}
+		rec := get(client, "NAS/WithCover/01.flac")
+		if rec.Code != http.StatusOK || rec.Body.String() != "\xff\xd8\xffcover" {
+			t.Errorf("got %d %q, want the cover file", rec.Code, rec.Body.String())
+		}
+		if len(client.commands) != 0 {
+			t.Errorf("sent %v to an MPD without art commands", client.commands)
+		}
+	})
+
+	t.Run("skips unsupported readpicture", func(t *testing.T) {
+		client := &fakeArtMPD{known: true, caps: mpd.CapabilityFlags{HasAlbumArt: true}}
+		if rec := get(client, "NAS/Bare/01.flac"); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", rec.Code)
+		}
+		if !slices.Equal(client.commands, []string{"albumart"}) {
+			t.Errorf("commands = %v, want only albumart", client.commands)
+		}
+	})
+
+	t.Run("embedded art", func(t *testing.T) {
+		client := &fakeArtMPD{known: true, caps: mpd.CapabilityFlags{HasAlbumArt: true, HasReadPicture: true}, picture: []byte("\x89PNG\r\n\x1a\nembedded")}
+		rec := get(client, "NAS/Bare/01.flac")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+			t.Errorf("got %d %s, want embedded PNG", rec.Code, rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("cover file is the last resort", func(t *testing.T) {
+		client := &fakeArtMPD{known: true, caps: mpd.CapabilityFlags{HasAlbumArt: true, HasReadPicture: true}}
+		rec := get(client, "NAS/WithCover/01.flac")
+		if rec.Code != http.StatusOK || rec.Body.String() != "\xff\xd8\xffcover" {
+			t.Errorf("got %d %q, want the cover file", rec.Code, rec.Body.String())
+		}
+		if !slices.Equal(client.commands, []string{"readpicture", "albumart"}) {
+			t.Errorf("commands = %v, want MPD tried first", client.commands)
+		}
+	})
+
+	t.Run("folder priority serves the cover file over embedded art", func(t *testing.T) {
+		if err := priorities.Set(artwork.PreferFolder); err != nil {
+			t.Fatal(err)
+		}
+		defer priorities.Set(artwork.PreferEmbedded)
+
+		client := &fakeArtMPD{known: true, caps: mpd.CapabilityFlags{HasAlbumArt: true, HasReadPicture: true}, picture: []byte("\x89PNG\r\n\x1a\nembedded")}
+		rec := get(client, "NAS/WithCover/01.flac")
+		if rec.Code != http.StatusOK || rec.Body.String() != "\xff\xd8\xffcover" {
+			t.Errorf("got %d %q, want the cover file", rec.Code, rec.Body.String())
+		}
+		if len(client.commands) != 0 {
+			t.Errorf("sent %v to MPD although a cover file was found", client.commands)
+		}
+	})
+
+	t.Run("placeholder on request", func(t *testing.T) {
+		rec := get(&fakeArtMPD{known: true}, "NAS/Bare/01.flac", "placeholder=1")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("got %d %s, want a PNG placeholder", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if !slices.Equal(rec.Body.Bytes(), artwork.Placeholder("Bare")) {
+			t.Error("placeholder not generated from the album directory name")
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != placeholderCacheAge {
+			t.Errorf("Cache-Control = %q, want %q", cc, placeholderCacheAge)
+		}
+	})
+
+	t.Run("placeholder by default unless refused", func(t *testing.T) {
+		placeholder = artPlaceholder{byDefault: true, image: []byte("\xff\xd8\xffplaceholder")}
+		defer func() { placeholder = artPlaceholder{} }()
+
+		rec := get(&fakeArtMPD{known: true}, "NAS/Bare/01.flac")
+		if rec.Code != http.StatusOK || rec.Body.String() != "\xff\xd8\xffplaceholder" || rec.Header().Get("Content-Type") != "image/jpeg" {
+			t.Errorf("got %d %s %q, want the configured placeholder", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+		}
+		if rec := get(&fakeArtMPD{known: true}, "NAS/Bare/01.flac", "placeholder=0"); rec.Code != http.StatusNotFound {
+			t.Errorf("placeholder=0: status = %d, want 404", rec.Code)
+		}
+		if rec := get(&fakeArtMPD{known: true}, "NAS/WithCover/01.flac"); rec.Body.String() != "\xff\xd8\xffcover" {
+			t.Errorf("got %q, want real art over the placeholder", rec.Body.String())
+		}
+	})
+
+	t.Run("rejects traversal before MPD or the filesystem", func(t *testing.T) {
+		os.WriteFile(filepath.Join(filepath.Dir(musicDir), "cover.jpg"), []byte("outside"), 0644)
+		for _, path := range []string{
+			"../../etc/passwd",
+			"../cover.jpg",
+			"NAS/../../cover.jpg",
+			"/etc/passwd",
+			"NAS/Album/01.flac\nclear",
+		} {
+			client := &fakeArtMPD{known: true, caps: mpd.CapabilityFlags{HasAlbumArt: true, HasReadPicture: true}}
+			if rec := get(client, path); rec.Code != http.StatusBadRequest {
+				t.Errorf("%q: status = %d, want 400", path, rec.Code)
+			}
+			if len(client.commands) != 0 {
+				t.Errorf("%q: sent %v to MPD", path, client.commands)
+			}
+		}
+	})
+
+	t.Run("cleans redundant separators", func(t *testing.T) {
+		client := &fakeArtMPD{known: true}
+		if rec := get(client, "NAS//WithCover/./01.flac"); rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		if rec := get(&fakeArtMPD{}, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/auth"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/transport/socketio"
+)
+
+// maxConfigImportSize bounds the body of a config import.
+const maxConfigImportSize = 8 << 20
+
+// configTransfer exports and imports the user's configuration. It is
+// satisfied by *socketio.Server.
+type configTransfer interface {
+	ExportConfig() socketio.ConfigExport
+	ImportConfig(req socketio.ImportConfigRequest) socketio.ImportConfigResponse
+}
+
+// registerConfigRoutes adds GET /api/v1/config/export and
+// POST /api/v1/config/import, mirroring the exportConfig and importConfig
+// Socket.io events. Like those events they need full access, guests
+// included, since the export lists NAS addresses and usernames.
+func registerConfigRoutes(mux *http.ServeMux, transfer configTransfer, authenticator *auth.Authenticator) {
+	mux.Handle("/api/v1/config/export", authenticator.RequireFull(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		export := transfer.ExportConfig()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="stellar-config-%s.json"`, export.ExportedAt.Format("20060102-150405")))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(export)
+	})))
+
+	mux.Handle("/api/v1/config/import", authenticator.RequireFull(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxConfigImportSize+1))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(body) > maxConfigImportSize {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "config import too large")
+			return
+		}
+		req, err := socketio.DecodeImportConfigRequest(body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		resp := transfer.ImportConfig(req)
+		status := http.StatusOK
+		if resp.Error != "" {
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	})))
+}
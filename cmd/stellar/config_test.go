@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/auth"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/sources"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/transport/socketio"
+)
+
+// fakeConfigTransfer records the last import it was asked to run.
+type fakeConfigTransfer struct {
+	imported *socketio.ImportConfigRequest
+}
+
+func (f *fakeConfigTransfer) ExportConfig() socketio.ConfigExport {
+	return socketio.ConfigExport{
+		Version:    socketio.ConfigExportVersion,
+		ExportedAt: time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+		NasShares:  []sources.ShareExport{{Name: "Office", IP: "192.168.1.10", Path: "Music", FSType: "cifs", HasPassword: true}},
+	}
+}
+
+func (f *fakeConfigTransfer) ImportConfig(req socketio.ImportConfigRequest) socketio.ImportConfigResponse {
+	f.imported = &req
+	if req.Config.Version == 0 {
+		return socketio.ImportConfigResponse{Error: "not a config export: missing version"}
+	}
+	return socketio.ImportConfigResponse{Success: true}
+}
+
+func TestConfigRoutes_Export(t *testing.T) {
+	mux := http.NewServeMux()
+	registerConfigRoutes(mux, &fakeConfigTransfer{}, auth.New(""))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/config/export", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "stellar-config-20261016-093000.json") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	var export socketio.ConfigExport
+	if err := json.Unmarshal(rec.Body.Bytes(), &export); err != nil {
+		t.Fatalf("invalid export: %v", err)
+	}
+	if len(export.NasShares) != 1 || !export.NasShares[0].HasPassword {
+		t.Errorf("export = %+v", export)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/config/export", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST export status = %d, want 405", rec.Code)
+	}
+}
+
+func TestConfigRoutes_ExportRequiresFullAccess(t *testing.T) {
+	mux := http.NewServeMux()
+	registerConfigRoutes(mux, &fakeConfigTransfer{}, auth.New("s3cret", auth.WithGuestToken("visitor")))
+
+	for token, want := range map[string]int{"visitor": http.StatusForbidden, "s3cret": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/config/export", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("export with %s token status = %d, want %d", token, rec.Code, want)
+		}
+	}
+}
+
+func TestConfigRoutes_Import(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantPassword string
+	}{
+		{"request with passwords", `{"config": {"version": 1}, "passwords": {"Office": "secret"}}`, http.StatusOK, "secret"},
+		{"bare export file", `{"version": 1, "nasShares": []}`, http.StatusOK, ""},
+		{"not an export", `{"hello": "world"}`, http.StatusBadRequest, ""},
+		{"invalid JSON", `{`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer := &fakeConfigTransfer{}
+			mux := http.NewServeMux()
+			registerConfigRoutes(mux, transfer, auth.New(""))
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/config/import", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantPassword != "" && (transfer.imported == nil || transfer.imported.Passwords["Office"] != tt.wantPassword) {
+				t.Errorf("imported request = %+v, want the password passed on", transfer.imported)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
+)
+
+// transportController runs transport commands and reports the resulting state.
+// It is satisfied by *player.Service.
+type transportController interface {
+	ExecuteCommand(cmd string, value int, hasValue bool) error
+	GetState() (*player.PlayerState, error)
+}
+
+// registerControlRoutes adds POST /api/v1/<command> endpoints for the
+// transport commands, mirroring the Socket.io events of the same names.
+func registerControlRoutes(mux *http.ServeMux, controller transportController) {
+	for _, cmd := range player.Commands {
+		mux.HandleFunc("/api/v1/"+cmd, controlHandler(controller, cmd))
+	}
+}
+
+// controlHandler runs a single transport command. The optional argument is
+// read from a JSON body ({"value": n}) or a "value" query parameter, and the
+// player state after the command is returned as JSON.
+func controlHandler(controller transportController, cmd string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		value, hasValue := 0, false
+		if q := r.URL.Query().Get("value"); q != "" {
+			value, hasValue = player.ParseCommandValue(q)
+			if !hasValue {
+				writeJSONError(w, http.StatusBadRequest, "value must be a number")
+				return
+			}
+		} else if body, err := io.ReadAll(io.LimitReader(r.Body, 4096)); err == nil && len(strings.TrimSpace(string(body))) > 0 {
+			var payload interface{}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+			value, hasValue = player.ParseCommandValue(payload)
+		}
+
+		if err := controller.ExecuteCommand(cmd, value, hasValue); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, player.ErrInvalidCommandValue) {
+				status = http.StatusBadRequest
+			} else if errors.Is(err, player.ErrUnknownCommand) {
+				status = http.StatusNotFound
+			}
+			log.Warn().Err(err).Str("command", cmd).Msg("REST transport command failed")
+			writeJSONError(w, status, err.Error())
+			return
+		}
+
+		state, err := controller.GetState()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+	}
+}
+
+// writeJSONError writes an error as {"error": message}.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
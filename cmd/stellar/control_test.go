@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
+)
+
+// fakeController records the last command it was asked to run.
+type fakeController struct {
+	cmd      string
+	value    int
+	hasValue bool
+	err      error
+}
+
+func (f *fakeController) ExecuteCommand(cmd string, value int, hasValue bool) error {
+	f.cmd, f.value, f.hasValue = cmd, value, hasValue
+	return f.err
+}
+
+func (f *fakeController) GetState() (*player.PlayerState, error) {
+	return &player.PlayerState{Status: player.StatusPlay, Volume: 42}, nil
+}
+
+func TestControlRoutes_RunCommandAndReturnState(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		wantCmd      string
+		wantValue    int
+		wantHasValue bool
+	}{
+		{"play resumes", "/api/v1/play", "", player.CommandPlay, 0, false},
+		{"pause", "/api/v1/pause", "", player.CommandPause, 0, false},
+		{"prev", "/api/v1/prev", "", player.CommandPrev, 0, false},
+		{"seek from body", "/api/v1/seek", `{"value": 90}`, player.CommandSeek, 90, true},
+		{"volume from bare body", "/api/v1/volume", `35`, player.CommandVolume, 35, true},
+		{"volume from query", "/api/v1/volume?value=60", "", player.CommandVolume, 60, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := &fakeController{}
+			mux := http.NewServeMux()
+			registerControlRoutes(mux, controller)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			if controller.cmd != tt.wantCmd || controller.value != tt.wantValue || controller.hasValue != tt.wantHasValue {
+				t.Errorf("ran %s(%d, %v), want %s(%d, %v)",
+					controller.cmd, controller.value, controller.hasValue, tt.wantCmd, tt.wantValue, tt.wantHasValue)
+			}
+
+			var state map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil || state["status"] != "play" {
+				t.Errorf("body = %s, want player state", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestControlRoutes_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"GET not allowed", http.MethodGet, "/api/v1/play", "", nil, http.StatusMethodNotAllowed},
+		{"bad JSON", http.MethodPost, "/api/v1/seek", `{"value":`, nil, http.StatusBadRequest},
+		{"non-numeric query", http.MethodPost, "/api/v1/volume?value=loud", "", nil, http.StatusBadRequest},
+		{"invalid value", http.MethodPost, "/api/v1/seek", "", fmt.Errorf("%w: missing", player.ErrInvalidCommandValue), http.StatusBadRequest},
+		{"MPD failure", http.MethodPost, "/api/v1/next", "", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			registerControlRoutes(mux, &fakeController{err: tt.err})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == "" {
+				t.Errorf("body = %s, want JSON error", rec.Body.String())
+			}
+		})
+	}
+}
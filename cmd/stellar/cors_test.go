@@ -59,8 +59,8 @@ func TestCorsMiddleware_HandlesPreflight(t *testing.T) {
 	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, OPTIONS" {
 		t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, "GET, POST, OPTIONS")
 	}
-	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type" {
-		t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, "Content-Type")
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, "Content-Type, Authorization")
 	}
 }
 
@@ -2,16 +2,15 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -20,11 +19,13 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/auth"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/artwork"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/localmusic"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/sources"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/system/network"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/transport/socketio"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/version"
 )
@@ -35,17 +36,31 @@ func main() {
 	mpdHost := flag.String("mpd-host", "localhost", "MPD host")
 	mpdPort := flag.Int("mpd-port", 6600, "MPD port")
 	mpdPassword := flag.String("mpd-password", "", "MPD password")
-	exclusive := flag.Bool("exclusive", false, "Enable exclusive MPD access mode (requires password, blocks other clients)")
+	mpdConnectTimeout := flag.Duration("mpd-connect-timeout", mpd.DefaultConnectTimeout, "How long connecting to MPD may take before failing (0 waits indefinitely)")
+	mpdTimeout := flag.Duration("mpd-timeout", mpd.DefaultCommandTimeout, "How long an MPD command may take before failing (0 waits indefinitely)")
+	mpdQueryConns := flag.Int("mpd-query-connections", mpd.DefaultQueryConnections, "MPD connections for library queries, kept apart from playback commands (0 shares the playback connection)")
+	exclusive := flag.Bool("exclusive", false, "Enable exclusive MPD access mode (requires an MPD password that gates playback commands; other connected clients are reported)")
 	bitPerfect := flag.Bool("bit-perfect", true, "Enable bit-perfect audio mode (default true)")
 	staticDir := flag.String("static", "", "Directory to serve static files from (optional)")
+	nasCheckInterval := flag.Duration("nas-check-interval", sources.DefaultHealthCheckInterval, "Interval between NAS mount health checks")
+	restartCmd := flag.String("restart-cmd", "sudo systemctl restart", "Command used to restart MPD after config changes (service name is appended)")
+	mqttBroker := flag.String("mqtt-broker", "", "MQTT broker address (host:port); MQTT is disabled when empty")
+	mqttPrefix := flag.String("mqtt-prefix", "stellar", "MQTT topic prefix")
+	advertise := flag.Bool("mdns", true, "Advertise the service on the LAN via mDNS")
+	authToken := flag.String("auth-token", os.Getenv("STELLAR_AUTH_TOKEN"), "Bearer token required by the control API and Socket.io (default $STELLAR_AUTH_TOKEN; open when empty)")
+	guestToken := flag.String("guest-token", os.Getenv("STELLAR_GUEST_TOKEN"), "Token granting read-only guest access (default $STELLAR_GUEST_TOKEN; needs --auth-token)")
+	rateLimits := flag.String("rate-limits", "", "Per-client Socket.io rate limit overrides, e.g. \"discoverNasDevices=10s,qobuzSearch=1s/3\" (0 disables)")
+	queueLimit := flag.Int("queue-limit", player.DefaultQueueLimit.Max, "Maximum number of tracks in the queue")
+	queueOverflow := flag.String("queue-overflow", string(player.DefaultQueueLimit.Policy), "What to do when an add would exceed --queue-limit: \"reject\" the add or \"trim\" the oldest tracks")
+	artworkWorkers := flag.Int("artwork-workers", artwork.DefaultPrewarmConcurrency, "Albums whose artwork is cached in parallel after library scans (0 disables pre-warming)")
+	historySize := flag.Int("history-size", localmusic.DefaultHistorySize, "Play history entries kept; the oldest are evicted beyond this")
+	sourcePaths := flag.String("source-paths", "", "Extra music directory classification rules, e.g. \"Network=nas,Stick=usb\" (checked before NAS, USB and INTERNAL)")
+	artPlaceholderDefault := flag.Bool("art-placeholder", false, "Serve a placeholder image instead of a 404 from /albumart when a track has no art (clients can override with placeholder=0/1)")
+	artPlaceholderFile := flag.String("art-placeholder-file", "", "Image served as the album art placeholder (default: a tile colored from the album title)")
+	autoplay := flag.Bool("autoplay", false, "Restore the last queue on startup and resume playback unless it was stopped")
 	debug := flag.Bool("debug", false, "Enable debug logging")
 	flag.Parse()
 
-	// Warn if exclusive mode is enabled without password
-	if *exclusive && *mpdPassword == "" {
-		log.Warn().Msg("Exclusive mode enabled but no MPD password set - MPD may still accept other connections")
-	}
-
 	// Setup logging
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	if *debug {
@@ -69,10 +84,13 @@ func main() {
 		Bool("exclusive", *exclusive).
 		Bool("bit_perfect", *bitPerfect).
 		Bool("password_set", *mpdPassword != "").
+		Bool("auth", *authToken != "").
 		Msg("Configuration")
 
 	// Create MPD client
 	mpdClient := mpd.NewClient(*mpdHost, *mpdPort, *mpdPassword)
+	mpdClient.SetTimeouts(*mpdConnectTimeout, *mpdTimeout)
+	mpdClient.SetQueryConnections(*mpdQueryConns)
 	if err := mpdClient.Connect(); err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to MPD")
 	}
@@ -84,8 +102,23 @@ func main() {
 	}
 	log.Info().Msg("MPD connection verified")
 
+	// Exclusive mode relies on MPD's password: other clients can only be kept
+	// out if MPD refuses them playback commands until they authenticate.
+	if *exclusive {
+		if *mpdPassword == "" {
+			log.Fatal().Msg("Exclusive mode requires an MPD password (--mpd-password)")
+		}
+		if err := mpdClient.VerifyExclusive(); err != nil {
+			log.Fatal().Err(err).Msg("Cannot guarantee exclusive MPD access - set default_permissions to exclude control in mpd.conf")
+		}
+		log.Info().Msg("Exclusive MPD access verified")
+	}
+
 	// Create services
 	playerService := player.NewService(mpdClient)
+	if err := playerService.SetQueueLimit(player.QueueLimit{Max: *queueLimit, Policy: player.QueueOverflowPolicy(*queueOverflow)}); err != nil {
+		log.Fatal().Err(err).Msg("Invalid queue limit")
+	}
 
 	// Create sources service for NAS/USB management
 	sourcesConfigPath := filepath.Join("/data/stellar", "sources.json")
@@ -94,8 +127,11 @@ func main() {
 		log.Warn().Err(err).Msg("Failed to create sources service - NAS/USB management disabled")
 		sourcesService = nil
 	} else {
-		// Set up NAS discoverer for Phase 2 discovery functionality
-		sourcesService.SetDiscoverer(sources.NewLinuxDiscoverer())
+		// Set up NAS discoverer for Phase 2 discovery functionality; shares are
+		// browsed over SMB2 with go-smb2, falling back to smbclient
+		sourcesService.SetDiscoverer(sources.NewNativeDiscoverer(sources.NewLinuxDiscoverer()))
+		// Set up USB detector for hotplug auto-mount
+		sourcesService.SetUsbDetector(sources.NewLinuxUsbDetector())
 		log.Info().Str("config", sourcesConfigPath).Msg("Sources service initialized with NAS discovery")
 
 		// Auto-mount all configured NAS shares on startup
@@ -150,12 +186,26 @@ func main() {
 	localMusicDataDir := filepath.Join("/data/stellar")
 	mpdMusicDir := "/var/lib/mpd/music"
 	mpdAdapter := &mpdClientAdapter{client: mpdClient}
-	localMusicService := localmusic.NewService(mpdAdapter, localMusicDataDir, mpdMusicDir)
+	sourceRules, err := localmusic.ParseSourceRules(*sourcePaths)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid --source-paths")
+	}
+	localMusicService := localmusic.NewService(mpdAdapter, localMusicDataDir, mpdMusicDir, sourceRules...)
+	localMusicService.SetHistorySize(*historySize)
+	// Shares and drives are linked into the music directory from their mounts;
+	// follow links from anywhere in it so they are never taken for local disk
+	localMusicService.GetClassifier().WithLinkTargets(map[string]localmusic.SourceType{
+		sources.NasMountBase: localmusic.SourceNAS,
+		sources.UsbMountBase: localmusic.SourceUSB,
+	})
 	log.Info().
 		Str("dataDir", localMusicDataDir).
 		Str("mpdMusicDir", mpdMusicDir).
 		Msg("Local music service initialized")
 
+	// Move favorites and play counts into MPD stickers without holding up startup
+	go localMusicService.SyncStickers()
+
 	// Create Socket.io server
 	socketServer, err := socketio.NewServer(playerService, mpdClient, sourcesService, localMusicService, *bitPerfect)
 	if err != nil {
@@ -163,6 +213,44 @@ func main() {
 	}
 	defer socketServer.Close()
 
+	// Use the configured restart command for MPD config changes
+	socketServer.SetAudioConfig(socketio.NewAudioConfig(
+		socketio.NewSudoConfigWriter(),
+		socketio.NewCommandServiceManager(strings.Fields(*restartCmd)...),
+	))
+
+	// URLs handed to DLNA renderers point back at this server
+	socketServer.SetHTTPPort(*port)
+	if *guestToken != "" && *authToken == "" {
+		log.Warn().Msg("Guest token ignored: set --auth-token to enable authentication")
+	}
+	authenticator := auth.New(*authToken, auth.WithGuestToken(*guestToken))
+	socketServer.SetAuthenticator(authenticator)
+	limits, err := socketio.ParseRateLimits(*rateLimits, socketio.DefaultRateLimits)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid --rate-limits")
+	}
+	socketServer.SetRateLimits(limits)
+	if *exclusive {
+		socketServer.SetExclusive(mpd.NewProcClientCounter(*mpdHost, *mpdPort))
+	}
+
+	// Create filesystem artwork finder
+	// NAS shares and USB drives are symlinked into the music directory
+	filesystemFinder := artwork.NewFilesystemFinder(mpdMusicDir).WithRoots(sources.NasMountBase, sources.UsbMountBase)
+	if *artworkWorkers > 0 {
+		socketServer.EnableArtworkPrewarm(filesystemFinder, *artworkWorkers)
+	}
+	// Whether /albumart prefers embedded or folder art, changeable by clients
+	artPriority := artwork.NewPriorityStore(localMusicDataDir)
+	socketServer.SetArtPriority(artPriority)
+	placeholder := artPlaceholder{byDefault: *artPlaceholderDefault}
+	if *artPlaceholderFile != "" {
+		if placeholder.image, err = os.ReadFile(*artPlaceholderFile); err != nil {
+			log.Fatal().Err(err).Msg("Invalid --art-placeholder-file")
+		}
+	}
+
 	// Initialize library cache (triggers background build if empty)
 	socketServer.InitializeCache()
 
@@ -174,11 +262,63 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to start MPD watcher")
 	}
 
+	// Restore the last queue after a restart or power cut, then keep saving it
+	queueStore := player.NewQueueStore(localMusicDataDir)
+	if *autoplay {
+		if snap, err := queueStore.Load(); err != nil {
+			log.Warn().Err(err).Msg("Failed to load saved queue")
+		} else if snap != nil {
+			result, err := playerService.Restore(*snap, true)
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to restore saved queue")
+			} else {
+				log.Info().
+					Int("restored", result.Restored).
+					Int("skipped", result.Skipped).
+					Int("position", result.Position).
+					Bool("playing", result.Playing).
+					Msg("Saved queue restored")
+			}
+		}
+	}
+	playerService.StartQueuePersistence(ctx, queueStore, player.DefaultQueueSaveInterval)
+
+	// Fire scheduled alarms
+	alarmStore := player.NewAlarmStore(localMusicDataDir)
+	socketServer.SetAlarmStore(alarmStore)
+	socketServer.SetDataDir(localMusicDataDir)
+	playerService.StartAlarms(ctx, alarmStore)
+
 	// Start network watcher for Socket.IO push notifications
 	socketServer.StartNetworkWatcher(ctx)
 
-	// Start mount watcher for periodic NAS share re-mount
-	socketServer.StartMountWatcher(ctx)
+	// Start mount watcher for periodic NAS health checks and re-mount
+	socketServer.StartMountWatcher(ctx, *nasCheckInterval)
+
+	// Start USB watcher for hotplug detection and auto-mount
+	socketServer.StartUsbWatcher(ctx)
+
+	// Start AirPlay watcher so shairport-sync sessions show up in player state
+	socketServer.StartAirplayWatcher(ctx)
+
+	// Watch for other MPD clients in exclusive mode
+	socketServer.StartExclusiveWatcher(ctx)
+
+	// Advertise on the LAN so companion apps can discover the player
+	if *advertise {
+		if responder, err := startMDNS(*port); err != nil {
+			log.Warn().Err(err).Msg("Failed to start mDNS advertisement")
+		} else {
+			defer responder.Shutdown()
+			// Re-advertise under the new name when the hostname changes
+			socketServer.SetHostnameListener(func(info socketio.SystemInfo) {
+				responder.Update(mdnsService(info, responder.Port()))
+			})
+		}
+	}
+
+	// Start MQTT bridge (no-op without a broker)
+	mqttDone := startMQTT(ctx, *mqttBroker, *mqttPrefix, playerService, socketServer.Events())
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
@@ -203,50 +343,22 @@ func main() {
 		json.NewEncoder(w).Encode(version.GetInfo())
 	})
 
-	// Create filesystem artwork finder
-	filesystemFinder := artwork.NewFilesystemFinder(mpdMusicDir)
-
-	// Album art endpoint
-	mux.HandleFunc("/albumart", func(w http.ResponseWriter, r *http.Request) {
-		path := r.URL.Query().Get("path")
-		if path == "" {
-			http.Error(w, "path parameter required", http.StatusBadRequest)
-			return
-		}
-
-		var data []byte
-		var err error
-
-		// 1. Try filesystem search first (various filenames, parent dirs)
-		artPath, fsErr := filesystemFinder.FindArtwork(path)
-		if fsErr == nil && artPath != "" {
-			data, err = filesystemFinder.ReadArtwork(artPath)
-			if err == nil && len(data) > 0 {
-				log.Debug().Str("path", path).Str("artPath", artPath).Msg("Serving artwork from filesystem")
-				serveArtwork(w, data)
-				return
-			}
-		}
-
-		// 2. Try MPD albumart (folder-based) - fallback for remote sources
-		data, err = mpdClient.AlbumArt(path)
-		if err == nil && len(data) > 0 {
-			log.Debug().Str("path", path).Msg("Serving artwork from MPD albumart")
-			serveArtwork(w, data)
+	// MPD version, capabilities and database statistics
+	mux.HandleFunc("/api/v1/stats", func(w http.ResponseWriter, r *http.Request) {
+		stats, err := socketServer.GetStats()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
 			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	})
 
-		// 3. Try embedded picture (ReadPicture)
-		data, err = mpdClient.ReadPicture(path)
-		if err == nil && len(data) > 0 {
-			log.Debug().Str("path", path).Msg("Serving artwork from embedded picture")
-			serveArtwork(w, data)
-			return
-		}
+	// Album art endpoint
+	mux.HandleFunc("/albumart", albumArtHandler(filesystemFinder, mpdClient, artPriority, placeholder))
 
-		log.Debug().Str("path", path).Msg("Album art not found")
-		http.Error(w, "album art not found", http.StatusNotFound)
-	})
+	// Audio stream endpoint - lets DLNA renderers fetch the track being cast
+	mux.HandleFunc("/stream", streamHandler(mpdMusicDir, sources.NasMountBase, sources.UsbMountBase))
 
 	// Artist art endpoint - serves artist images from cache or redirects to external URLs
 	mux.HandleFunc("/artistart", func(w http.ResponseWriter, r *http.Request) {
@@ -282,11 +394,14 @@ func main() {
 
 	// Network status endpoint
 	mux.HandleFunc("/api/v1/network", func(w http.ResponseWriter, r *http.Request) {
-		status := getNetworkStatus()
+		status := network.GetStatus()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(status)
 	})
 
+	// Server-Sent Events stream of state, queue and network pushes (Socket.io fallback)
+	mux.HandleFunc("/api/v1/events", socketServer.ServeEvents)
+
 	// Basic state endpoint (REST fallback)
 	mux.HandleFunc("/api/v1/getState", func(w http.ResponseWriter, r *http.Request) {
 		state, err := playerService.GetState()
@@ -295,33 +410,15 @@ func main() {
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		// Simple JSON encoding
-		data := "{"
-		first := true
-		for k, v := range state {
-			if !first {
-				data += ","
-			}
-			first = false
-			switch val := v.(type) {
-			case string:
-				data += `"` + k + `":"` + val + `"`
-			case int:
-				data += `"` + k + `":` + itoa(val)
-			case bool:
-				if val {
-					data += `"` + k + `":true`
-				} else {
-					data += `"` + k + `":false`
-				}
-			default:
-				data += `"` + k + `":null`
-			}
-		}
-		data += "}"
-		w.Write([]byte(data))
+		json.NewEncoder(w).Encode(state)
 	})
 
+	// Transport control endpoints (REST mirror of the Socket.io commands)
+	registerControlRoutes(mux, playerService)
+
+	// Configuration export and import
+	registerConfigRoutes(mux, socketServer, authenticator)
+
 	// Serve static files if directory specified (SPA mode)
 	if *staticDir != "" {
 		log.Info().Str("dir", *staticDir).Msg("Serving static files")
@@ -344,7 +441,7 @@ func main() {
 	// Start HTTP server
 	server := &http.Server{
 		Addr:         ":" + *port,
-		Handler:      corsMiddleware(mux),
+		Handler:      corsMiddleware(authenticator.Middleware(mux, "/api/")),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
@@ -371,6 +468,12 @@ func main() {
 		log.Fatal().Err(err).Msg("HTTP server error")
 	}
 
+	// Give MQTT a moment to publish offline and disconnect
+	select {
+	case <-mqttDone:
+	case <-time.After(2 * time.Second):
+	}
+
 	log.Info().Msg("Server stopped")
 }
 
@@ -382,170 +485,6 @@ func serveArtwork(w http.ResponseWriter, data []byte) {
 	w.Write(data)
 }
 
-func itoa(i int) string {
-	if i == 0 {
-		return "0"
-	}
-	neg := i < 0
-	if neg {
-		i = -i
-	}
-	var buf [20]byte
-	pos := len(buf)
-	for i > 0 {
-		pos--
-		buf[pos] = byte('0' + i%10)
-		i /= 10
-	}
-	if neg {
-		pos--
-		buf[pos] = '-'
-	}
-	return string(buf[pos:])
-}
-
-// NetworkStatus represents the current network connection status
-type NetworkStatus struct {
-	Type     string `json:"type"`     // "wifi", "ethernet", "none"
-	SSID     string `json:"ssid"`     // WiFi network name (if wifi)
-	Signal   int    `json:"signal"`   // WiFi signal strength 0-100 (if wifi)
-	IP       string `json:"ip"`       // IP address
-	Strength int    `json:"strength"` // Signal strength level 0-3 (for icon)
-}
-
-// getNetworkStatus returns the current network connection status
-func getNetworkStatus() NetworkStatus {
-	status := NetworkStatus{
-		Type:     "none",
-		Signal:   0,
-		Strength: 0,
-	}
-
-	// Check ethernet first (usually eth0 or end0 on newer Pi)
-	for _, iface := range []string{"eth0", "end0"} {
-		carrierPath := "/sys/class/net/" + iface + "/carrier"
-		if data, err := os.ReadFile(carrierPath); err == nil {
-			if strings.TrimSpace(string(data)) == "1" {
-				status.Type = "ethernet"
-				status.IP = getIPAddress(iface)
-				status.Signal = 100
-				status.Strength = 3
-				return status
-			}
-		}
-	}
-
-	// Check WiFi (usually wlan0)
-	for _, iface := range []string{"wlan0", "wlan1"} {
-		operstatePath := "/sys/class/net/" + iface + "/operstate"
-		if data, err := os.ReadFile(operstatePath); err == nil {
-			if strings.TrimSpace(string(data)) == "up" {
-				status.Type = "wifi"
-				status.IP = getIPAddress(iface)
-				status.SSID, status.Signal = getWifiInfo(iface)
-				// Convert signal to strength level (0-3)
-				switch {
-				case status.Signal >= 70:
-					status.Strength = 3 // Full signal
-				case status.Signal >= 50:
-					status.Strength = 2 // Medium
-				case status.Signal >= 30:
-					status.Strength = 1 // Weak
-				default:
-					status.Strength = 0 // Very weak
-				}
-				return status
-			}
-		}
-	}
-
-	return status
-}
-
-// getIPAddress returns the IP address for a given interface
-func getIPAddress(iface string) string {
-	out, err := exec.Command("ip", "-4", "addr", "show", iface).Output()
-	if err != nil {
-		return ""
-	}
-
-	lines := strings.Split(string(out), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "inet ") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				// Remove CIDR notation
-				ip := strings.Split(parts[1], "/")[0]
-				return ip
-			}
-		}
-	}
-	return ""
-}
-
-// getWifiInfo returns SSID and signal strength (0-100) for a WiFi interface
-func getWifiInfo(iface string) (string, int) {
-	ssid := ""
-	signal := 0
-
-	// Get SSID using iwgetid
-	out, err := exec.Command("iwgetid", iface, "-r").Output()
-	if err == nil {
-		ssid = strings.TrimSpace(string(out))
-	}
-
-	// Get signal from /proc/net/wireless
-	file, err := os.Open("/proc/net/wireless")
-	if err != nil {
-		return ssid, signal
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, iface) {
-			fields := strings.Fields(line)
-			if len(fields) >= 4 {
-				// Signal level is in field 3 (link quality) or field 4 (signal level)
-				// Format is usually: interface: status link level noise
-				// Signal level is typically in dBm (negative) or link quality (0-100)
-				linkQuality := strings.TrimSuffix(fields[2], ".")
-				if q, err := strconv.Atoi(linkQuality); err == nil {
-					// If it's a percentage (0-100), use directly
-					if q >= 0 && q <= 100 {
-						signal = q
-					} else if q >= 0 && q <= 70 {
-						// It's likely link quality out of 70
-						signal = (q * 100) / 70
-					}
-				}
-
-				// Also try signal level in dBm (field 3)
-				if signal == 0 && len(fields) >= 4 {
-					sigLevel := strings.TrimSuffix(fields[3], ".")
-					if dbm, err := strconv.Atoi(sigLevel); err == nil {
-						// Convert dBm to percentage (-100 dBm = 0%, -50 dBm = 100%)
-						if dbm < 0 {
-							signal = 2 * (dbm + 100)
-							if signal < 0 {
-								signal = 0
-							}
-							if signal > 100 {
-								signal = 100
-							}
-						}
-					}
-				}
-			}
-			break
-		}
-	}
-
-	return ssid, signal
-}
-
 // mpdClientAdapter adapts the MPD client to the localmusic.MPDClient interface.
 // This is needed because gompd uses mpd.Attrs (a type alias) instead of map[string]string.
 type mpdClientAdapter struct {
@@ -578,16 +517,59 @@ func (a *mpdClientAdapter) GetAlbumDetails(basePath string) ([]localmusic.AlbumD
 	result := make([]localmusic.AlbumDetails, len(details))
 	for i, d := range details {
 		result[i] = localmusic.AlbumDetails{
-			Album:       d.Album,
-			AlbumArtist: d.AlbumArtist,
-			TrackCount:  d.TrackCount,
-			FirstTrack:  d.FirstTrack,
-			TotalTime:   d.TotalTime,
+			Album:          d.Album,
+			AlbumArtist:    d.AlbumArtist,
+			ArtistInferred: d.ArtistInferred,
+			TrackArtists:   d.TrackArtists,
+			TrackCount:     d.TrackCount,
+			FirstTrack:     d.FirstTrack,
+			TotalTime:      d.TotalTime,
+			AddedAt:        d.AddedAt,
+			IsCompilation:  d.IsCompilation,
 		}
 	}
 	return result, nil
 }
 
+func (a *mpdClientAdapter) FindAlbumTracks(album, albumArtist string) ([]map[string]string, error) {
+	attrs, err := a.client.FindAlbumTracks(album, albumArtist)
+	if err != nil {
+		return nil, err
+	}
+	return attrsToMaps(attrs), nil
+}
+
+func (a *mpdClientAdapter) ListRecentlyAdded(basePath string, limit int) ([]string, error) {
+	return a.client.ListRecentlyAdded(basePath, limit)
+}
+
+func (a *mpdClientAdapter) GetSticker(uri, name string) (string, error) {
+	value, err := a.client.GetSticker(uri, name)
+	return value, stickerError(err)
+}
+
+func (a *mpdClientAdapter) SetSticker(uri, name, value string) error {
+	return stickerError(a.client.SetSticker(uri, name, value))
+}
+
+func (a *mpdClientAdapter) DeleteSticker(uri, name string) error {
+	return stickerError(a.client.DeleteSticker(uri, name))
+}
+
+func (a *mpdClientAdapter) FindStickers(name string) (map[string]string, error) {
+	found, err := a.client.FindStickers(name)
+	return found, stickerError(err)
+}
+
+// stickerError maps a disabled sticker database to the localmusic sentinel
+// so the service can fall back to its own files.
+func stickerError(err error) error {
+	if errors.Is(err, mpd.ErrStickersDisabled) {
+		return fmt.Errorf("%w: %v", localmusic.ErrStickersUnavailable, err)
+	}
+	return err
+}
+
 // attrsToMaps converts gompd Attrs slice to map slice.
 func attrsToMaps(attrs []gompd.Attrs) []map[string]string {
 	result := make([]map[string]string, len(attrs))
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mdns"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/transport/socketio"
+)
+
+// startMDNS advertises the HTTP/Socket.io port as _<serviceName>._tcp, named
+// and described with the same system info clients get in pushSystemInfo.
+func startMDNS(port string) (*mdns.Responder, error) {
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %w", port, err)
+	}
+
+	responder := mdns.NewResponder(mdnsService(socketio.GetSystemInfo(), portNum))
+	if err := responder.Start(); err != nil {
+		return nil, err
+	}
+	return responder, nil
+}
+
+// mdnsService describes the service advertised for the given system info.
+func mdnsService(info socketio.SystemInfo, port int) mdns.Service {
+	return mdns.Service{
+		Instance: info.Name,
+		Type:     "_" + info.ServiceName + "._tcp",
+		Host:     info.Host,
+		Port:     port,
+		TXT: []string{
+			"id=" + info.ID,
+			"name=" + info.Name,
+			"version=" + info.SystemVersion,
+			"hardware=" + info.Hardware,
+			"path=/socket.io/",
+		},
+	}
+}
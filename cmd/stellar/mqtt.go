@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mqtt"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/transport/socketio"
+)
+
+// MQTT availability payloads, published retained on <prefix>/availability.
+const (
+	mqttOnline  = "online"
+	mqttOffline = "offline"
+)
+
+// mqttPublisher is the part of the MQTT client the bridge publishes through.
+type mqttPublisher interface {
+	Publish(topic string, payload []byte, retain bool) error
+}
+
+// mqttBridge mirrors player state to MQTT topics and runs commands received
+// on <prefix>/cmd/<command>:
+//
+//	<prefix>/state         full pushState JSON (retained)
+//	<prefix>/volume        volume 0-100 (retained)
+//	<prefix>/status        play, pause or stop (retained)
+//	<prefix>/availability  online or offline (retained, also the last will)
+type mqttBridge struct {
+	prefix     string
+	controller transportController
+
+	mu         sync.Mutex
+	lastVolume string
+	lastStatus string
+}
+
+// newMQTTBridge creates a bridge for the given topic prefix.
+func newMQTTBridge(prefix string, controller transportController) *mqttBridge {
+	return &mqttBridge{
+		prefix:     strings.TrimSuffix(prefix, "/"),
+		controller: controller,
+	}
+}
+
+// topic joins the prefix and a subtopic.
+func (b *mqttBridge) topic(name string) string {
+	return b.prefix + "/" + name
+}
+
+// onConnect announces availability, subscribes to commands and publishes the
+// current state so retained topics are fresh after a reconnect.
+func (b *mqttBridge) onConnect(c *mqtt.Client) {
+	if err := c.Publish(b.topic("availability"), []byte(mqttOnline), true); err != nil {
+		log.Warn().Err(err).Msg("Failed to publish MQTT availability")
+	}
+	if err := c.Subscribe(b.topic("cmd/+")); err != nil {
+		log.Warn().Err(err).Msg("Failed to subscribe to MQTT commands")
+	}
+
+	// Force volume and status out again on the new connection
+	b.mu.Lock()
+	b.lastVolume, b.lastStatus = "", ""
+	b.mu.Unlock()
+
+	state, err := b.controller.GetState()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get state for MQTT")
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	b.publishState(c, data)
+}
+
+// publishState publishes a pushState payload, and volume and status when
+// they changed.
+func (b *mqttBridge) publishState(pub mqttPublisher, data []byte) {
+	if err := pub.Publish(b.topic("state"), data, true); err != nil {
+		log.Debug().Err(err).Msg("Failed to publish MQTT state")
+		return
+	}
+
+	var state struct {
+		Status string      `json:"status"`
+		Volume json.Number `json:"volume"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	volumeChanged := state.Volume != "" && string(state.Volume) != b.lastVolume
+	statusChanged := state.Status != "" && state.Status != b.lastStatus
+	b.lastVolume = string(state.Volume)
+	b.lastStatus = state.Status
+	b.mu.Unlock()
+
+	if volumeChanged {
+		pub.Publish(b.topic("volume"), []byte(state.Volume), true)
+	}
+	if statusChanged {
+		pub.Publish(b.topic("status"), []byte(state.Status), true)
+	}
+}
+
+// handleMessage runs a command received on <prefix>/cmd/<command>. The
+// payload is an optional number or {"value": n}.
+func (b *mqttBridge) handleMessage(topic string, payload []byte) {
+	cmd, ok := strings.CutPrefix(topic, b.topic("cmd/"))
+	if !ok {
+		return
+	}
+
+	value, hasValue := 0, false
+	if text := strings.TrimSpace(string(payload)); text != "" {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+			decoded = text
+		}
+		value, hasValue = player.ParseCommandValue(decoded)
+	}
+
+	if err := b.controller.ExecuteCommand(cmd, value, hasValue); err != nil {
+		log.Warn().Err(err).Str("command", cmd).Msg("MQTT command failed")
+		return
+	}
+	log.Debug().Str("command", cmd).Msg("MQTT command executed")
+}
+
+// forward publishes state broadcasts until ctx ends.
+func (b *mqttBridge) forward(ctx context.Context, pub mqttPublisher, events *socketio.EventHub) {
+	ch, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-ch:
+			if msg.Name == "pushState" {
+				b.publishState(pub, msg.Data)
+			}
+		}
+	}
+}
+
+// startMQTT connects to the broker and bridges player state and commands.
+// It does nothing when broker is empty. The returned channel is closed once
+// the client has disconnected after ctx ends.
+func startMQTT(ctx context.Context, broker, prefix string, controller transportController, events *socketio.EventHub) <-chan struct{} {
+	done := make(chan struct{})
+	if broker == "" {
+		close(done)
+		return done
+	}
+
+	hostname, _ := os.Hostname()
+	bridge := newMQTTBridge(prefix, controller)
+	client := mqtt.NewClient(mqtt.Options{
+		Broker:      broker,
+		ClientID:    fmt.Sprintf("stellar-%s", hostname),
+		Username:    os.Getenv("MQTT_USERNAME"),
+		Password:    os.Getenv("MQTT_PASSWORD"),
+		WillTopic:   bridge.topic("availability"),
+		WillPayload: []byte(mqttOffline),
+		WillRetain:  true,
+		OnConnect:   bridge.onConnect,
+		OnMessage:   bridge.handleMessage,
+	})
+
+	go bridge.forward(ctx, client, events)
+	go func() {
+		defer close(done)
+		client.Run(ctx)
+	}()
+
+	log.Info().Str("broker", mqtt.BrokerAddress(broker)).Str("prefix", bridge.prefix).Msg("MQTT bridge started")
+	return done
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+)
+
+// recordingPublisher captures published messages by topic.
+type recordingPublisher struct {
+	messages map[string]string
+	count    int
+}
+
+func (p *recordingPublisher) Publish(topic string, payload []byte, retain bool) error {
+	if p.messages == nil {
+		p.messages = make(map[string]string)
+	}
+	p.messages[topic] = string(payload)
+	p.count++
+	return nil
+}
+
+func TestMQTTBridge_PublishState(t *testing.T) {
+	bridge := newMQTTBridge("stellar/", &fakeController{})
+	pub := &recordingPublisher{}
+
+	bridge.publishState(pub, []byte(`{"status":"play","volume":42}`))
+	if pub.messages["stellar/state"] != `{"status":"play","volume":42}` {
+		t.Errorf("state = %q", pub.messages["stellar/state"])
+	}
+	if pub.messages["stellar/volume"] != "42" || pub.messages["stellar/status"] != "play" {
+		t.Errorf("volume = %q status = %q, want 42 play", pub.messages["stellar/volume"], pub.messages["stellar/status"])
+	}
+
+	// Unchanged volume and status are not republished
+	pub.count = 0
+	bridge.publishState(pub, []byte(`{"status":"play","volume":42,"seek":1000}`))
+	if pub.count != 1 {
+		t.Errorf("published %d messages for unchanged volume/status, want 1", pub.count)
+	}
+}
+
+func TestMQTTBridge_HandleMessage(t *testing.T) {
+	tests := []struct {
+		topic    string
+		payload  string
+		wantCmd  string
+		wantVal  int
+		hasValue bool
+	}{
+		{"stellar/cmd/pause", "", "pause", 0, false},
+		{"stellar/cmd/volume", "35", "volume", 35, true},
+		{"stellar/cmd/seek", `{"value": 90}`, "seek", 90, true},
+		{"stellar/other/next", "", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		controller := &fakeController{}
+		bridge := newMQTTBridge("stellar", controller)
+		bridge.handleMessage(tt.topic, []byte(tt.payload))
+
+		if controller.cmd != tt.wantCmd || controller.value != tt.wantVal || controller.hasValue != tt.hasValue {
+			t.Errorf("%s %q: got (%q, %d, %v), want (%q, %d, %v)", tt.topic, tt.payload,
+				controller.cmd, controller.value, controller.hasValue, tt.wantCmd, tt.wantVal, tt.hasValue)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/artwork"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/dlna"
+)
+
+// streamHandler serves audio files from the MPD music directory by MPD URI,
+// so network renderers can fetch the track being cast. Range requests are
+// supported for seeking. Renderers can't authenticate, so only audio files
+// are served, and only from the music directory or the roots its symlinks
+// may lead into, such as the NAS and USB mount bases.
+func streamHandler(musicDir string, roots ...string) http.HandlerFunc {
+	roots = append([]string{musicDir}, roots...)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		uri := r.URL.Query().Get("path")
+		if uri == "" {
+			http.Error(w, "path parameter required", http.StatusBadRequest)
+			return
+		}
+
+		if !artwork.ValidTrackURI(uri) || !dlna.IsAudioFile(uri) {
+			log.Warn().Str("path", uri).Msg("Rejected stream path")
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		fullPath := filepath.Join(musicDir, uri)
+
+		// Symlinks may only lead into the allowed roots. The resolved path
+		// is opened so the link can't be swapped in between.
+		resolved, err := filepath.EvalSymlinks(fullPath)
+		if err == nil && (!artwork.WithinRoots(resolved, roots...) || !dlna.IsAudioFile(resolved)) {
+			log.Warn().Str("path", uri).Str("resolved", resolved).Msg("Rejected stream path outside the music roots")
+			err = os.ErrPermission
+		}
+		if err != nil {
+			log.Debug().Err(err).Str("path", uri).Msg("Stream file not found")
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+
+		file, err := os.Open(resolved)
+		if err != nil {
+			log.Debug().Err(err).Str("path", uri).Msg("Stream file not found")
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+
+		// A renderer reads the track as it plays, outliving the server's
+		// write timeout
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Debug().Err(err).Msg("Failed to clear stream write deadline")
+		}
+
+		w.Header().Set("Content-Type", dlna.MimeType(fullPath))
+		w.Header().Set("transferMode.dlna.org", "Streaming")
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	}
+}
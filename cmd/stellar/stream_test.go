@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamHandler(t *testing.T) {
+	musicDir := t.TempDir()
+	os.MkdirAll(filepath.Join(musicDir, "NAS", "Album"), 0755)
+	os.WriteFile(filepath.Join(musicDir, "NAS", "Album", "01.flac"), []byte("fLaC-data"), 0644)
+	os.WriteFile(filepath.Join(musicDir, "NAS", "Album", "notes.txt"), []byte("notes"), 0644)
+	os.MkdirAll(filepath.Join(musicDir, "NAS", "Album.flac"), 0755)
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.flac"), []byte("secret"), 0644)
+	os.Symlink(filepath.Join(outside, "secret.flac"), filepath.Join(musicDir, "escape.flac"))
+	mount := t.TempDir()
+	os.WriteFile(filepath.Join(mount, "02.flac"), []byte("mounted"), 0644)
+	os.Symlink(mount, filepath.Join(musicDir, "USB"))
+
+	handler := streamHandler(musicDir, mount)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"serves file", "NAS/Album/01.flac", http.StatusOK, "fLaC-data"},
+		{"missing path", "", http.StatusBadRequest, ""},
+		{"missing file", "NAS/Album/02.flac", http.StatusNotFound, ""},
+		{"directory", "NAS/Album.flac", http.StatusNotFound, ""},
+		{"traversal", "../secret.flac", http.StatusBadRequest, ""},
+		{"absolute", "/etc/passwd", http.StatusBadRequest, ""},
+		{"not audio", "NAS/Album/notes.txt", http.StatusBadRequest, ""},
+		{"symlink outside roots", "escape.flac", http.StatusNotFound, ""},
+		{"symlink into mount", "USB/02.flac", http.StatusOK, "mounted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/stream?path="+url.QueryEscape(tt.path), nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestStreamHandler_Range(t *testing.T) {
+	musicDir := t.TempDir()
+	os.WriteFile(filepath.Join(musicDir, "a.flac"), []byte("0123456789"), 0644)
+
+	req := httptest.NewRequest(http.MethodGet, "/stream?path=a.flac", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rec := httptest.NewRecorder()
+	streamHandler(musicDir)(rec, req)
+
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" {
+		t.Errorf("got %d %q, want 206 \"2345\"", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/flac" {
+		t.Errorf("Content-Type = %q, want audio/flac", ct)
+	}
+}
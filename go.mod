@@ -6,16 +6,19 @@ toolchain go1.24.12
 
 require (
 	github.com/fhs/gompd/v2 v2.3.0
+	github.com/google/uuid v1.6.0
 	github.com/googollee/go-socket.io v1.7.0
+	github.com/hirochachacha/go-smb2 v1.1.0
 	github.com/rs/zerolog v1.31.0
+	golang.org/x/net v0.49.0
 )
 
 require (
 	github.com/andybalholm/brotli v1.2.0 // indirect
 	github.com/dunglas/httpsfv v1.1.0 // indirect
+	github.com/geoffgarside/ber v1.2.0 // indirect
 	github.com/gofrs/uuid v4.0.0+incompatible // indirect
 	github.com/gomodule/redigo v1.8.4 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/gookit/color v1.6.0 // indirect
 	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/klauspost/compress v1.18.2 // indirect
@@ -37,7 +40,6 @@ require (
 	github.com/zishang520/socket.io/v3 v3.0.0-rc.11 // indirect
 	golang.org/x/crypto v0.47.0 // indirect
 	golang.org/x/image v0.35.0 // indirect
-	golang.org/x/net v0.49.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
 	resty.dev/v3 v3.0.0-beta.6 // indirect
@@ -3,7 +3,6 @@ package audio
 
 import (
 	"strconv"
-	"strings"
 	"sync"
 
 	"github.com/rs/zerolog/log"
@@ -15,21 +14,37 @@ type AudioFormat struct {
 	BitDepth     int    `json:"bitDepth"`     // Bit depth (16, 24, 32)
 	Channels     int    `json:"channels"`     // Number of channels (usually 2)
 	Format       string `json:"format"`       // Format string ("PCM", "DSD64", "DSD128", etc.)
+	Float        bool   `json:"float"`        // True for 32-bit float PCM
+	Codec        string `json:"codec"`        // Codec from the file type ("FLAC"), empty if unknown
+	Label        string `json:"label"`        // Human-readable format ("16/44.1 FLAC", "DSD128 (Native)")
+	DsdRate      int    `json:"dsdRate"`      // DSD multiple of 44.1kHz (64, 128, 256, 512), 0 for PCM
+	DsdMode      string `json:"dsdMode"`      // How DSD reaches the DAC ("native" or "dop"), empty for PCM
 	IsBitPerfect bool   `json:"isBitPerfect"` // True if bit-perfect output (no resampling)
 }
 
 // AudioStatus represents the current audio output status.
+//
+// Locked means the DAC is receiving a stream: MPD has an ALSA playback
+// substream running with a confirmed rate, reported in Hardware. If
+// /proc/asound can't be read, Locked falls back to MPD's play state, which
+// can be true while the device is still opening or failing to. LockSource
+// tells the two apart.
 type AudioStatus struct {
-	Locked bool         `json:"locked"` // True if device is locked for exclusive playback
-	Format *AudioFormat `json:"format"` // Current audio format (nil if not playing)
+	Locked     bool            `json:"locked"`             // True if the DAC is receiving a stream from MPD
+	LockSource string          `json:"lockSource"`         // LockSourceALSA or LockSourcePlayback
+	Hardware   *HardwareParams `json:"hardware,omitempty"` // Format the device was opened with, if locked via ALSA
+	Format    *AudioFormat  `json:"format"`              // Current audio format (nil if not playing)
+	Owners    []DeviceOwner `json:"owners,omitempty"`    // Processes holding ALSA playback devices
+	BlockedBy *DeviceOwner  `json:"blockedBy,omitempty"` // First owner that isn't MPD
 }
 
 // Controller manages audio format detection and device lock status.
 type Controller struct {
 	mu           sync.RWMutex
-	isLocked     bool
+	isLocked     bool // MPD is playing; see AudioStatus for the real lock state
 	currentFormat *AudioFormat
-	bitPerfect   bool // Configuration flag for bit-perfect mode
+	bitPerfect   bool   // Configuration flag for bit-perfect mode
+	dsdMode      string // Configured DSD output, DsdModeNative or DsdModeDoP
 }
 
 // NewController creates a new audio controller.
@@ -39,32 +54,47 @@ func NewController(bitPerfect bool) *Controller {
 	}
 }
 
-// GetStatus returns the current audio status.
+// GetStatus returns the current audio status, including which processes
+// hold the ALSA playback devices and whether the DAC is locked.
 func (c *Controller) GetStatus() AudioStatus {
+	owners := FindDeviceOwners()
+	hardware, hardwareKnown := ReadHardwareLock()
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return AudioStatus{
-		Locked: c.isLocked,
-		Format: c.currentFormat,
+	status := AudioStatus{
+		Format:    c.currentFormat,
+		Owners:    owners,
+		BlockedBy: BlockingOwner(owners),
+	}
+	if hardwareKnown {
+		status.Locked = hardware != nil
+		status.LockSource = LockSourceALSA
+		status.Hardware = hardware
+	} else {
+		status.Locked = c.isLocked
+		status.LockSource = LockSourcePlayback
 	}
+	return status
 }
 
 // UpdateFromMPDStatus updates audio status from MPD status fields.
 // mpdState is the playback state ("play", "pause", "stop")
-// audio is the MPD audio field format "samplerate:bits:channels" (e.g., "192000:24:2")
-func (c *Controller) UpdateFromMPDStatus(mpdState, audio string) (changed bool) {
+// audio is the MPD audio field (e.g., "192000:24:2", "dsd64:2"); see ParseMPDFormat
+// trackType is the current file's extension (e.g., "flac"), used as the codec
+func (c *Controller) UpdateFromMPDStatus(mpdState, audio, trackType string) (changed bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Determine lock state based on playback
+	// Play state, the lock fallback when ALSA state can't be read
 	wasLocked := c.isLocked
 	c.isLocked = mpdState == "play"
 
 	// Parse audio format
 	var newFormat *AudioFormat
 	if audio != "" {
-		newFormat = c.parseAudioFormat(audio)
+		newFormat = c.parseAudioFormat(audio, trackType)
 	}
 
 	// Check if format changed
@@ -83,56 +113,58 @@ func (c *Controller) UpdateFromMPDStatus(mpdState, audio string) (changed bool)
 	return changed
 }
 
-// OnPlaybackStart marks the device as locked.
+// SetDsdMode sets how MPD sends DSD to the DAC, as read from its config,
+// so DSD formats are labelled "DSD128 (Native)" or "DSD128 (DoP)". MPD
+// reports the same audio format either way. Unknown modes clear it. It
+// returns true if the current format changed.
+func (c *Controller) SetDsdMode(mode string) (changed bool) {
+	if mode != DsdModeNative && mode != DsdModeDoP {
+		mode = ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dsdMode = mode
+	if c.currentFormat == nil || c.currentFormat.DsdRate == 0 || c.currentFormat.DsdMode == mode {
+		return false
+	}
+
+	// Replace rather than modify, as GetStatus hands out the current format
+	format := *c.currentFormat
+	format.DsdMode = mode
+	format.Label = formatLabel(&format)
+	c.currentFormat = &format
+	return true
+}
+
+// OnPlaybackStart marks the device as locked when ALSA state can't be read.
 func (c *Controller) OnPlaybackStart() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.isLocked = true
 }
 
-// OnPlaybackStop releases the device lock.
+// OnPlaybackStop releases the device lock when ALSA state can't be read.
 func (c *Controller) OnPlaybackStop() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.isLocked = false
 }
 
-// parseAudioFormat parses MPD's audio format string.
-// Format: "samplerate:bits:channels" (e.g., "192000:24:2")
-// DSD is indicated by special sample rates (DSD64 = 2822400 Hz, etc.)
-func (c *Controller) parseAudioFormat(audio string) *AudioFormat {
-	parts := strings.Split(audio, ":")
-	if len(parts) < 2 {
+// parseAudioFormat parses MPD's audio format string and labels it with the
+// codec and the configured bit-perfect mode.
+func (c *Controller) parseAudioFormat(audio, trackType string) *AudioFormat {
+	format := ParseMPDFormat(audio)
+	if format == nil {
 		return nil
 	}
-
-	sampleRate, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return nil
-	}
-
-	bitDepth, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return nil
+	format.IsBitPerfect = c.bitPerfect
+	format.Codec = codecName(trackType)
+	if format.DsdRate > 0 {
+		format.DsdMode = c.dsdMode
 	}
-
-	channels := 2 // Default to stereo
-	if len(parts) >= 3 {
-		if ch, err := strconv.Atoi(parts[2]); err == nil {
-			channels = ch
-		}
-	}
-
-	format := &AudioFormat{
-		SampleRate:   sampleRate,
-		BitDepth:     bitDepth,
-		Channels:     channels,
-		IsBitPerfect: c.bitPerfect,
-	}
-
-	// Determine format string
-	format.Format = detectAudioFormatType(sampleRate, bitDepth)
-
+	format.Label = formatLabel(format)
 	return format
 }
 
@@ -187,5 +219,8 @@ func audioFormatEqual(a, b *AudioFormat) bool {
 		a.BitDepth == b.BitDepth &&
 		a.Channels == b.Channels &&
 		a.Format == b.Format &&
+		a.Float == b.Float &&
+		a.Codec == b.Codec &&
+		a.DsdMode == b.DsdMode &&
 		a.IsBitPerfect == b.IsBitPerfect
 }
@@ -202,7 +202,7 @@ func TestUpdateFromMPDStatus(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctrl := audio.NewController(tt.bitPerfect)
-			changed := ctrl.UpdateFromMPDStatus(tt.mpdState, tt.audio)
+			changed := ctrl.UpdateFromMPDStatus(tt.mpdState, tt.audio, "")
 
 			status := ctrl.GetStatus()
 
@@ -271,26 +271,26 @@ func TestNoChangeDetection(t *testing.T) {
 	ctrl := audio.NewController(true)
 
 	// First update should change
-	changed1 := ctrl.UpdateFromMPDStatus("play", "44100:16:2")
+	changed1 := ctrl.UpdateFromMPDStatus("play", "44100:16:2", "")
 	if !changed1 {
 		t.Error("expected first update to report changed")
 	}
 
 	// Same state should not change
-	changed2 := ctrl.UpdateFromMPDStatus("play", "44100:16:2")
+	changed2 := ctrl.UpdateFromMPDStatus("play", "44100:16:2", "")
 	if changed2 {
 		t.Error("expected same state to not report changed")
 	}
 
 	// Different state should change
-	changed3 := ctrl.UpdateFromMPDStatus("pause", "44100:16:2")
+	changed3 := ctrl.UpdateFromMPDStatus("pause", "44100:16:2", "")
 	if !changed3 {
 		t.Error("expected state change to report changed")
 	}
 
 	// Different format should change
-	ctrl.UpdateFromMPDStatus("play", "44100:16:2")
-	changed4 := ctrl.UpdateFromMPDStatus("play", "96000:24:2")
+	ctrl.UpdateFromMPDStatus("play", "44100:16:2", "")
+	changed4 := ctrl.UpdateFromMPDStatus("play", "96000:24:2", "")
 	if !changed4 {
 		t.Error("expected format change to report changed")
 	}
@@ -355,9 +355,9 @@ func TestConcurrentAccess(t *testing.T) {
 		go func(i int) {
 			for j := 0; j < 100; j++ {
 				if j%2 == 0 {
-					ctrl.UpdateFromMPDStatus("play", "44100:16:2")
+					ctrl.UpdateFromMPDStatus("play", "44100:16:2", "")
 				} else {
-					ctrl.UpdateFromMPDStatus("pause", "96000:24:2")
+					ctrl.UpdateFromMPDStatus("pause", "96000:24:2", "")
 				}
 			}
 			done <- true
@@ -0,0 +1,118 @@
+// Package audio provides audio format detection and device lock status.
+package audio
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dsdBaseRate is the CD sample rate DSD rates are multiples of (DSD64 is 64x).
+const dsdBaseRate = 44100
+
+// DSD output modes, matching the MPD ALSA output's dop setting.
+const (
+	DsdModeNative = "native" // Native DSD to a DAC that supports it
+	DsdModeDoP    = "dop"    // DSD over PCM frames
+)
+
+// ParseMPDFormat parses MPD's audio status field. PCM is reported as
+// "samplerate:bits:channels", where bits is "f" for 32-bit float, and native
+// DSD as "dsdN:channels" with N the multiple of 44.1 kHz (dsd64, dsd128...).
+// DSD sent as 1-bit PCM at the DSD rate ("2822400:1:2") is recognised too.
+// It returns nil if audio can't be parsed.
+func ParseMPDFormat(audio string) *AudioFormat {
+	parts := strings.Split(audio, ":")
+
+	if rate, ok := strings.CutPrefix(strings.ToLower(parts[0]), "dsd"); ok {
+		multiple, err := strconv.Atoi(rate)
+		if err != nil || multiple <= 0 {
+			return nil
+		}
+		format := &AudioFormat{
+			SampleRate: multiple * dsdBaseRate,
+			BitDepth:   1,
+			Channels:   parseChannels(parts[1:]),
+			Format:     "DSD" + rate,
+			DsdRate:    multiple,
+		}
+		format.Label = format.Format
+		return format
+	}
+
+	if len(parts) < 2 {
+		return nil
+	}
+	sampleRate, err := strconv.Atoi(parts[0])
+	if err != nil || sampleRate <= 0 {
+		return nil
+	}
+
+	format := &AudioFormat{
+		SampleRate: sampleRate,
+		Channels:   parseChannels(parts[2:]),
+	}
+	if parts[1] == "f" {
+		format.BitDepth = 32
+		format.Float = true
+	} else if format.BitDepth, err = strconv.Atoi(parts[1]); err != nil {
+		return nil
+	}
+
+	format.Format = detectAudioFormatType(sampleRate, format.BitDepth)
+	if format.Format != "PCM" {
+		format.DsdRate = sampleRate / dsdBaseRate
+	}
+	format.Label = formatLabel(format)
+	return format
+}
+
+// parseChannels returns the channel count from the remaining fields of an
+// audio format, defaulting to stereo.
+func parseChannels(rest []string) int {
+	if len(rest) > 0 {
+		if ch, err := strconv.Atoi(rest[0]); err == nil && ch > 0 {
+			return ch
+		}
+	}
+	return 2
+}
+
+// formatLabel returns a short human-readable format, "16/44.1" or "32f/96"
+// for PCM followed by the codec if known ("16/44.1 FLAC"), and "DSD64" for
+// DSD followed by the output mode if known ("DSD64 (DoP)"). DSD labels leave
+// out the codec as DSF and DFF carry the same stream.
+func formatLabel(f *AudioFormat) string {
+	if f.DsdRate > 0 {
+		switch f.DsdMode {
+		case DsdModeNative:
+			return f.Format + " (Native)"
+		case DsdModeDoP:
+			return f.Format + " (DoP)"
+		}
+		return f.Format
+	}
+	label := strconv.Itoa(f.BitDepth)
+	if f.Float {
+		label += "f"
+	}
+	label += "/" + strconv.FormatFloat(float64(f.SampleRate)/1000, 'f', -1, 64)
+	if f.Codec != "" {
+		label += " " + f.Codec
+	}
+	return label
+}
+
+// codecName returns the codec shown for a track type (file extension), or ""
+// if trackType doesn't look like one, e.g. for a stream URL without an
+// extension.
+func codecName(trackType string) string {
+	if trackType == "" || len(trackType) > 5 {
+		return ""
+	}
+	for _, r := range trackType {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return strings.ToUpper(trackType)
+}
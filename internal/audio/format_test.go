@@ -0,0 +1,160 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/audio"
+)
+
+func TestParseMPDFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		audio string
+		want  *audio.AudioFormat
+	}{
+		{
+			name:  "PCM 16/44.1",
+			audio: "44100:16:2",
+			want:  &audio.AudioFormat{SampleRate: 44100, BitDepth: 16, Channels: 2, Format: "PCM", Label: "16/44.1"},
+		},
+		{
+			name:  "PCM 24/96",
+			audio: "96000:24:2",
+			want:  &audio.AudioFormat{SampleRate: 96000, BitDepth: 24, Channels: 2, Format: "PCM", Label: "24/96"},
+		},
+		{
+			name:  "PCM 24/176.4 mono",
+			audio: "176400:24:1",
+			want:  &audio.AudioFormat{SampleRate: 176400, BitDepth: 24, Channels: 1, Format: "PCM", Label: "24/176.4"},
+		},
+		{
+			name:  "float",
+			audio: "48000:f:2",
+			want:  &audio.AudioFormat{SampleRate: 48000, BitDepth: 32, Channels: 2, Format: "PCM", Float: true, Label: "32f/48"},
+		},
+		{
+			name:  "native DSD64",
+			audio: "dsd64:2",
+			want:  &audio.AudioFormat{SampleRate: 2822400, BitDepth: 1, Channels: 2, Format: "DSD64", Label: "DSD64", DsdRate: 64},
+		},
+		{
+			name:  "native DSD512 multichannel",
+			audio: "dsd512:6",
+			want:  &audio.AudioFormat{SampleRate: 22579200, BitDepth: 1, Channels: 6, Format: "DSD512", Label: "DSD512", DsdRate: 512},
+		},
+		{
+			name:  "DSD as 1-bit PCM",
+			audio: "5644800:1:2",
+			want:  &audio.AudioFormat{SampleRate: 5644800, BitDepth: 1, Channels: 2, Format: "DSD128", Label: "DSD128", DsdRate: 128},
+		},
+		{
+			name:  "channels missing",
+			audio: "44100:16",
+			want:  &audio.AudioFormat{SampleRate: 44100, BitDepth: 16, Channels: 2, Format: "PCM", Label: "16/44.1"},
+		},
+		{name: "empty", audio: ""},
+		{name: "rate only", audio: "44100"},
+		{name: "bad bits", audio: "44100:x:2"},
+		{name: "bad DSD rate", audio: "dsdx:2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := audio.ParseMPDFormat(tt.audio)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("ParseMPDFormat(%q) = %+v, want nil", tt.audio, got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Errorf("ParseMPDFormat(%q) = %+v, want %+v", tt.audio, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateFromMPDStatusLabelsCodec(t *testing.T) {
+	tests := []struct {
+		audio, trackType string
+		wantCodec        string
+		wantLabel        string
+	}{
+		{"44100:16:2", "flac", "FLAC", "16/44.1 FLAC"},
+		{"96000:f:2", "wav", "WAV", "32f/96 WAV"},
+		{"dsd64:2", "dsf", "DSF", "DSD64"},
+		{"44100:16:2", "", "", "16/44.1"},
+		{"44100:16:2", "com/live", "", "16/44.1"}, // Stream URL without an extension
+	}
+
+	for _, tt := range tests {
+		ctrl := audio.NewController(true)
+		ctrl.UpdateFromMPDStatus("play", tt.audio, tt.trackType)
+
+		format := ctrl.GetStatus().Format
+		if format == nil {
+			t.Fatalf("%s %s: no format", tt.audio, tt.trackType)
+		}
+		if format.Codec != tt.wantCodec || format.Label != tt.wantLabel {
+			t.Errorf("%s %s: codec %q label %q, want %q and %q",
+				tt.audio, tt.trackType, format.Codec, format.Label, tt.wantCodec, tt.wantLabel)
+		}
+	}
+}
+
+func TestDsdModeLabels(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		audio     string
+		wantMode  string
+		wantRate  int
+		wantLabel string
+	}{
+		{"native DSD128", audio.DsdModeNative, "dsd128:2", "native", 128, "DSD128 (Native)"},
+		{"DoP DSD64", audio.DsdModeDoP, "dsd64:2", "dop", 64, "DSD64 (DoP)"},
+		{"DoP DSD256 as 1-bit PCM", audio.DsdModeDoP, "11289600:1:2", "dop", 256, "DSD256 (DoP)"},
+		{"mode unknown", "", "dsd64:2", "", 64, "DSD64"},
+		{"invalid mode ignored", "bogus", "dsd64:2", "", 64, "DSD64"},
+		{"PCM ignores mode", audio.DsdModeDoP, "44100:16:2", "", 0, "16/44.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := audio.NewController(true)
+			ctrl.SetDsdMode(tt.mode)
+			ctrl.UpdateFromMPDStatus("play", tt.audio, "")
+
+			format := ctrl.GetStatus().Format
+			if format.DsdMode != tt.wantMode || format.DsdRate != tt.wantRate || format.Label != tt.wantLabel {
+				t.Errorf("got mode %q rate %d label %q, want %q, %d and %q",
+					format.DsdMode, format.DsdRate, format.Label, tt.wantMode, tt.wantRate, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestSetDsdModeWhilePlaying(t *testing.T) {
+	ctrl := audio.NewController(true)
+	ctrl.SetDsdMode(audio.DsdModeNative)
+	ctrl.UpdateFromMPDStatus("play", "dsd128:2", "dsf")
+	before := ctrl.GetStatus().Format
+
+	if !ctrl.SetDsdMode(audio.DsdModeDoP) {
+		t.Error("SetDsdMode should report a change while playing DSD")
+	}
+	if got := ctrl.GetStatus().Format.Label; got != "DSD128 (DoP)" {
+		t.Errorf("label after switching to DoP = %q", got)
+	}
+	if before.Label != "DSD128 (Native)" {
+		t.Errorf("earlier status was modified: %q", before.Label)
+	}
+	if ctrl.SetDsdMode(audio.DsdModeDoP) {
+		t.Error("setting the same mode again should not report a change")
+	}
+
+	ctrl.UpdateFromMPDStatus("play", "44100:16:2", "flac")
+	if ctrl.SetDsdMode(audio.DsdModeNative) {
+		t.Error("SetDsdMode should not report a change while playing PCM")
+	}
+}
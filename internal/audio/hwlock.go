@@ -0,0 +1,94 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Lock sources reported in AudioStatus.LockSource.
+const (
+	LockSourceALSA     = "alsa"     // From the ALSA substream state and hw_params
+	LockSourcePlayback = "playback" // From MPD's play state; /proc/asound isn't readable
+)
+
+// HardwareParams is the format an ALSA playback substream was opened with,
+// i.e. what the DAC is actually being sent.
+type HardwareParams struct {
+	Card     int    `json:"card"`
+	Device   int    `json:"device"`
+	Format   string `json:"format"` // ALSA sample format, e.g. S32_LE or DSD_U32_BE
+	Rate     int    `json:"rate"`   // Rate in Hz; for DoP and native DSD this is the frame rate, not the DSD rate
+	Channels int    `json:"channels"`
+}
+
+// ReadHardwareLock returns the hw_params of the playback substream MPD is
+// running, or nil if MPD isn't streaming to any device. ok is false if
+// /proc/asound can't be read, in which case the lock state is unknown.
+func ReadHardwareLock() (params *HardwareParams, ok bool) {
+	if _, err := os.Stat(filepath.Join(procRoot, "asound")); err != nil {
+		return nil, false
+	}
+	paths, err := filepath.Glob(filepath.Join(procRoot, "asound", "card*", "pcm*p", "sub*", "status"))
+	if err != nil {
+		return nil, false
+	}
+
+	for _, path := range paths {
+		m := subStatusPattern.FindStringSubmatch(filepath.ToSlash(path))
+		if m == nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		state, pid, open := ParseSubStatus(string(data))
+		if !open || state != "RUNNING" || processName(pid) != mpdCommand {
+			continue
+		}
+
+		data, err = os.ReadFile(filepath.Join(filepath.Dir(path), "hw_params"))
+		if err != nil {
+			continue
+		}
+		hw, open := ParseHwParams(string(data))
+		if !open || hw.Rate <= 0 {
+			continue
+		}
+		hw.Card, _ = strconv.Atoi(m[1])
+		hw.Device, _ = strconv.Atoi(m[2])
+		return &hw, true
+	}
+	return nil, true
+}
+
+// ParseHwParams parses a substream hw_params file. A closed substream
+// contains just "closed"; an open one lists "format:", "channels:" and
+// "rate: 96000 (96000/1)" among others.
+func ParseHwParams(content string) (params HardwareParams, open bool) {
+	content = strings.TrimSpace(content)
+	if content == "" || content == "closed" {
+		return HardwareParams{}, false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "format":
+			params.Format = value
+		case "channels":
+			params.Channels, _ = strconv.Atoi(value)
+		case "rate":
+			// The exact rate follows in parentheses as a fraction
+			rate, _, _ := strings.Cut(value, " ")
+			params.Rate, _ = strconv.Atoi(rate)
+		}
+	}
+	return params, true
+}
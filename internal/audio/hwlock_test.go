@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain points procRoot at a missing directory so tests don't read the
+// host's ALSA state; lock tests build their own with fakeProc.
+func TestMain(m *testing.M) {
+	procRoot = filepath.Join(os.TempDir(), "stellar-audio-test-no-proc")
+	os.Exit(m.Run())
+}
+
+const hwParams96k = `access: RW_INTERLEAVED
+format: S32_LE
+subformat: STD
+channels: 2
+rate: 96000 (96000/1)
+period_size: 4096
+buffer_size: 16384
+`
+
+func TestParseHwParams(t *testing.T) {
+	hw, open := ParseHwParams(hwParams96k)
+	if !open {
+		t.Fatal("open substream reported as closed")
+	}
+	want := HardwareParams{Format: "S32_LE", Rate: 96000, Channels: 2}
+	if hw != want {
+		t.Errorf("got %+v, want %+v", hw, want)
+	}
+
+	if _, open := ParseHwParams("closed\n"); open {
+		t.Error("closed substream reported as open")
+	}
+}
+
+func TestReadHardwareLock(t *testing.T) {
+	t.Run("no proc falls back", func(t *testing.T) {
+		if _, ok := ReadHardwareLock(); ok {
+			t.Error("expected lock state to be unknown without /proc/asound")
+		}
+	})
+
+	t.Run("MPD running", func(t *testing.T) {
+		fakeProc(t, map[string]string{
+			"card0/pcm0p/sub0/status":    "closed\n",
+			"card1/pcm0p/sub0/status":    "state: RUNNING\nowner_pid   : 812\n",
+			"card1/pcm0p/sub0/hw_params": hwParams96k,
+		}, map[string]string{"812": "mpd"})
+
+		hw, ok := ReadHardwareLock()
+		if !ok || hw == nil {
+			t.Fatalf("got (%v, %v), want a lock", hw, ok)
+		}
+		want := HardwareParams{Card: 1, Device: 0, Format: "S32_LE", Rate: 96000, Channels: 2}
+		if *hw != want {
+			t.Errorf("got %+v, want %+v", *hw, want)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		status string
+		comm   string
+		params string
+	}{
+		{"MPD prepared but not running", "state: PREPARED\nowner_pid   : 812\n", "mpd", hwParams96k},
+		{"MPD paused", "state: PAUSED\nowner_pid   : 812\n", "mpd", hwParams96k},
+		{"another process running", "state: RUNNING\nowner_pid   : 812\n", "shairport-sync", hwParams96k},
+		{"no confirmed rate", "state: RUNNING\nowner_pid   : 812\n", "mpd", "closed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProc(t, map[string]string{
+				"card0/pcm0p/sub0/status":    tt.status,
+				"card0/pcm0p/sub0/hw_params": tt.params,
+			}, map[string]string{"812": tt.comm})
+
+			hw, ok := ReadHardwareLock()
+			if !ok || hw != nil {
+				t.Errorf("got (%+v, %v), want (nil, true)", hw, ok)
+			}
+		})
+	}
+}
+
+func TestGetStatusLockSource(t *testing.T) {
+	ctrl := NewController(true)
+	ctrl.UpdateFromMPDStatus("play", "96000:24:2", "flac")
+
+	// Without /proc/asound the play state stands in for the lock
+	status := ctrl.GetStatus()
+	if !status.Locked || status.LockSource != LockSourcePlayback || status.Hardware != nil {
+		t.Errorf("fallback: got locked=%v source=%q hardware=%v", status.Locked, status.LockSource, status.Hardware)
+	}
+
+	// MPD says play, but the device isn't running yet
+	fakeProc(t, map[string]string{
+		"card0/pcm0p/sub0/status": "state: PREPARED\nowner_pid   : 812\n",
+	}, map[string]string{"812": "mpd"})
+	status = ctrl.GetStatus()
+	if status.Locked || status.LockSource != LockSourceALSA {
+		t.Errorf("prepared: got locked=%v source=%q, want unlocked from ALSA", status.Locked, status.LockSource)
+	}
+
+	fakeProc(t, map[string]string{
+		"card0/pcm0p/sub0/status":    "state: RUNNING\nowner_pid   : 812\n",
+		"card0/pcm0p/sub0/hw_params": hwParams96k,
+	}, map[string]string{"812": "mpd"})
+	status = ctrl.GetStatus()
+	if !status.Locked || status.Hardware == nil || status.Hardware.Rate != 96000 {
+		t.Errorf("running: got locked=%v hardware=%+v, want locked at 96000", status.Locked, status.Hardware)
+	}
+}
@@ -0,0 +1,148 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mpdCommand is MPD's process name; MPD holding the device is expected.
+const mpdCommand = "mpd"
+
+// procRoot is the proc filesystem mount point; replaced in tests.
+var procRoot = "/proc"
+
+// subStatusPattern matches /proc/asound/cardN/pcmMp/subK/status paths.
+var subStatusPattern = regexp.MustCompile(`card(\d+)/pcm(\d+)p/sub\d+/status$`)
+
+// DeviceOwner is a process that has an ALSA playback device open.
+type DeviceOwner struct {
+	Card    int    `json:"card"`
+	Device  int    `json:"device"`
+	PID     int    `json:"pid"`
+	Command string `json:"command"` // Process name from /proc/<pid>/comm
+	State   string `json:"state"`   // ALSA stream state, e.g. RUNNING or PREPARED
+}
+
+// String describes the owner for error messages.
+func (o DeviceOwner) String() string {
+	name := o.Command
+	if name == "" {
+		name = "unknown process"
+	}
+	return fmt.Sprintf("%s (PID %d) on hw:%d,%d", name, o.PID, o.Card, o.Device)
+}
+
+// FindDeviceOwners returns the processes holding ALSA playback substreams,
+// ordered by card and device.
+func FindDeviceOwners() []DeviceOwner {
+	paths, err := filepath.Glob(filepath.Join(procRoot, "asound", "card*", "pcm*p", "sub*", "status"))
+	if err != nil {
+		return nil
+	}
+
+	var owners []DeviceOwner
+	for _, path := range paths {
+		m := subStatusPattern.FindStringSubmatch(filepath.ToSlash(path))
+		if m == nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		state, pid, open := ParseSubStatus(string(data))
+		if !open {
+			continue
+		}
+
+		card, _ := strconv.Atoi(m[1])
+		device, _ := strconv.Atoi(m[2])
+		owners = append(owners, DeviceOwner{
+			Card:    card,
+			Device:  device,
+			PID:     pid,
+			Command: processName(pid),
+			State:   state,
+		})
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].Card != owners[j].Card {
+			return owners[i].Card < owners[j].Card
+		}
+		return owners[i].Device < owners[j].Device
+	})
+	return owners
+}
+
+// ParseSubStatus parses a substream status file. A closed substream contains
+// just "closed"; an open one lists "state:" and "owner_pid :" among others.
+func ParseSubStatus(content string) (state string, pid int, open bool) {
+	content = strings.TrimSpace(content)
+	if content == "" || content == "closed" {
+		return "", 0, false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "state":
+			state = strings.TrimSpace(value)
+		case "owner_pid":
+			pid, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	return state, pid, true
+}
+
+// processName returns the command name of a PID, or "" if it has exited.
+func processName(pid int) string {
+	if pid <= 0 {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// BlockingOwner returns the first owner that isn't MPD, or nil. Such a
+// process keeps MPD from opening the device in exclusive (hw:) mode.
+func BlockingOwner(owners []DeviceOwner) *DeviceOwner {
+	for i := range owners {
+		if owners[i].Command != mpdCommand {
+			return &owners[i]
+		}
+	}
+	return nil
+}
+
+// isDeviceOpenError reports whether an MPD error is a failure to open the
+// output device.
+func isDeviceOpenError(msg string) bool {
+	lower := strings.ToLower(msg)
+	return strings.Contains(lower, "device or resource busy") ||
+		strings.Contains(lower, "failed to open")
+}
+
+// ExplainPlaybackError adds the blocking process to an MPD device-open error,
+// turning an opaque ALSA message into one that names the culprit. Other
+// errors are returned unchanged.
+func ExplainPlaybackError(msg string, owners []DeviceOwner) string {
+	if !isDeviceOpenError(msg) {
+		return msg
+	}
+	if owner := BlockingOwner(owners); owner != nil {
+		return fmt.Sprintf("%s (audio device in use by %s)", msg, owner)
+	}
+	return msg
+}
@@ -0,0 +1,98 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeProc builds a /proc tree with the given substream status files and
+// process names, and points procRoot at it for the test.
+func fakeProc(t *testing.T, statuses map[string]string, comms map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range statuses {
+		path := filepath.Join(root, "asound", rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	for pid, comm := range comms {
+		os.MkdirAll(filepath.Join(root, pid), 0755)
+		os.WriteFile(filepath.Join(root, pid, "comm"), []byte(comm+"\n"), 0644)
+	}
+
+	old := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = old })
+}
+
+const runningStatus = `state: RUNNING
+owner_pid   : 6448
+trigger_time: 1234.567890
+tstamp      : 1234.600000
+delay       : 4096
+avail       : 4096
+avail_max   : 8192
+-----
+hw_ptr      : 12345
+appl_ptr    : 16441
+`
+
+func TestParseSubStatus(t *testing.T) {
+	state, pid, open := ParseSubStatus(runningStatus)
+	if !open || state != "RUNNING" || pid != 6448 {
+		t.Errorf("got (%q, %d, %v), want (RUNNING, 6448, true)", state, pid, open)
+	}
+
+	if _, _, open := ParseSubStatus("closed\n"); open {
+		t.Error("closed substream reported as open")
+	}
+}
+
+func TestFindDeviceOwners(t *testing.T) {
+	fakeProc(t, map[string]string{
+		"card1/pcm0p/sub0/status": runningStatus,
+		"card0/pcm0p/sub0/status": "closed\n",
+		"card0/pcm1p/sub0/status": "state: PREPARED\nowner_pid   : 812\n",
+		"card0/pcm0c/sub0/status": "state: RUNNING\nowner_pid   : 900\n", // Capture, ignored
+	}, map[string]string{
+		"6448": "audirvanaStudio",
+		"812":  "mpd",
+	})
+
+	owners := FindDeviceOwners()
+	if len(owners) != 2 {
+		t.Fatalf("got %d owners, want 2: %+v", len(owners), owners)
+	}
+	if owners[0] != (DeviceOwner{Card: 0, Device: 1, PID: 812, Command: "mpd", State: "PREPARED"}) {
+		t.Errorf("owners[0] = %+v", owners[0])
+	}
+	if owners[1] != (DeviceOwner{Card: 1, Device: 0, PID: 6448, Command: "audirvanaStudio", State: "RUNNING"}) {
+		t.Errorf("owners[1] = %+v", owners[1])
+	}
+
+	blocking := BlockingOwner(owners)
+	if blocking == nil || blocking.Command != "audirvanaStudio" {
+		t.Errorf("BlockingOwner = %+v, want audirvanaStudio", blocking)
+	}
+}
+
+func TestExplainPlaybackError(t *testing.T) {
+	owners := []DeviceOwner{{Card: 0, Device: 0, PID: 612, Command: "shairport-sync", State: "RUNNING"}}
+	msg := `Failed to open "USB DAC" (alsa); Failed to open ALSA device "hw:0,0": Device or resource busy`
+
+	got := ExplainPlaybackError(msg, owners)
+	if !strings.HasSuffix(got, "(audio device in use by shairport-sync (PID 612) on hw:0,0)") {
+		t.Errorf("got %q", got)
+	}
+
+	// Unrelated errors and MPD-only owners leave the message alone
+	if got := ExplainPlaybackError("Not found", owners); got != "Not found" {
+		t.Errorf("unrelated error changed: %q", got)
+	}
+	mpdOnly := []DeviceOwner{{PID: 812, Command: "mpd"}}
+	if got := ExplainPlaybackError(msg, mpdOnly); got != msg {
+		t.Errorf("MPD-owned device changed message: %q", got)
+	}
+}
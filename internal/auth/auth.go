@@ -0,0 +1,157 @@
+// Package auth provides optional bearer-token authentication for the
+// control API. With no token configured every request is allowed, which
+// keeps the player open on a trusted LAN as before.
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// QueryParam is the query parameter carrying the token for clients that
+// cannot set headers, such as EventSource and Socket.IO v2 apps.
+const QueryParam = "token"
+
+// Role is the access level a token grants.
+type Role string
+
+const (
+	// RoleNone is the role of a missing or unknown token.
+	RoleNone Role = ""
+	// RoleGuest may read state and browse but not change anything.
+	RoleGuest Role = "guest"
+	// RoleFull has unrestricted access.
+	RoleFull Role = "full"
+)
+
+// Authenticator checks presented tokens against the configured ones.
+type Authenticator struct {
+	token      string
+	guestToken string
+}
+
+// Option configures an Authenticator.
+type Option func(*Authenticator)
+
+// WithGuestToken adds a token that grants read-only guest access.
+func WithGuestToken(token string) Option {
+	return func(a *Authenticator) {
+		a.guestToken = token
+	}
+}
+
+// New creates an Authenticator. An empty token disables authentication,
+// including any guest token.
+func New(token string, opts ...Option) *Authenticator {
+	a := &Authenticator{token: token}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Enabled reports whether a token is required.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && a.token != ""
+}
+
+// Role returns the access level token grants. Everyone has full access
+// when authentication is disabled.
+func (a *Authenticator) Role(token string) Role {
+	if !a.Enabled() || tokenEqual(token, a.token) {
+		return RoleFull
+	}
+	if a.guestToken != "" && tokenEqual(token, a.guestToken) {
+		return RoleGuest
+	}
+	return RoleNone
+}
+
+// Valid reports whether token grants any access.
+func (a *Authenticator) Valid(token string) bool {
+	return a.Role(token) != RoleNone
+}
+
+// tokenEqual compares tokens in constant time.
+func tokenEqual(presented, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(want)) == 1
+}
+
+// RequestToken returns the token from an "Authorization: Bearer" header,
+// falling back to the token query parameter.
+func RequestToken(r *http.Request) string {
+	if token, ok := BearerToken(r.Header.Get("Authorization")); ok {
+		return token
+	}
+	return r.URL.Query().Get(QueryParam)
+}
+
+// BearerToken extracts the token from an Authorization header value.
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// Middleware rejects requests under any of the given path prefixes unless
+// they carry a valid token; guests may only use GET and HEAD. Other paths,
+// such as artwork and health checks, stay open so image tags and monitors
+// keep working.
+func (a *Authenticator) Middleware(next http.Handler, prefixes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() || !hasPrefix(r.URL.Path, prefixes) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch a.Role(RequestToken(r)) {
+		case RoleNone:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="stellar"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		case RoleGuest:
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				writeError(w, http.StatusForbidden, "read-only access")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireFull rejects requests without a full-access token, for routes that
+// guests may not even read, such as a configuration export.
+func (a *Authenticator) RequireFull(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch a.Role(RequestToken(r)) {
+		case RoleFull:
+			next.ServeHTTP(w, r)
+		case RoleGuest:
+			writeError(w, http.StatusForbidden, "full access required")
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="stellar"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+		}
+	})
+}
+
+// writeError writes a JSON error response.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":%q}`, msg)
+}
+
+// hasPrefix reports whether path starts with any of prefixes.
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
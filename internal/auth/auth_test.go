@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticator_Valid(t *testing.T) {
+	a := New("s3cret")
+	if !a.Enabled() {
+		t.Fatal("Enabled() = false with a token set")
+	}
+	if !a.Valid("s3cret") {
+		t.Error("Valid(correct token) = false")
+	}
+	for _, token := range []string{"", "s3cre", "s3cret2", "S3CRET"} {
+		if a.Valid(token) {
+			t.Errorf("Valid(%q) = true, want false", token)
+		}
+	}
+}
+
+func TestAuthenticator_DisabledAllowsAll(t *testing.T) {
+	for _, a := range []*Authenticator{New(""), nil} {
+		if a.Enabled() {
+			t.Error("Enabled() = true without a token")
+		}
+		if !a.Valid("") || !a.Valid("anything") {
+			t.Error("Valid() = false with authentication disabled")
+		}
+	}
+}
+
+func TestAuthenticator_Role(t *testing.T) {
+	a := New("s3cret", WithGuestToken("visitor"))
+
+	tests := []struct {
+		token string
+		want  Role
+	}{
+		{"s3cret", RoleFull},
+		{"visitor", RoleGuest},
+		{"", RoleNone},
+		{"other", RoleNone},
+	}
+	for _, tt := range tests {
+		if got := a.Role(tt.token); got != tt.want {
+			t.Errorf("Role(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+
+	// Without a full token everyone is full, guest token or not
+	if got := New("", WithGuestToken("visitor")).Role(""); got != RoleFull {
+		t.Errorf("Role() with auth disabled = %q, want full", got)
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		url    string
+		want   string
+	}{
+		{"bearer header", "Bearer abc", "/api/v1/getState", "abc"},
+		{"case-insensitive scheme", "bearer abc", "/api/v1/getState", "abc"},
+		{"query fallback", "", "/api/v1/events?token=xyz", "xyz"},
+		{"header wins over query", "Bearer abc", "/api/v1/events?token=xyz", "abc"},
+		{"basic auth ignored", "Basic dXNlcjpwYXNz", "/api/v1/getState", ""},
+		{"none", "", "/api/v1/getState", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if got := RequestToken(r); got != tt.want {
+				t.Errorf("RequestToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{"protected without token", "s3cret", http.MethodGet, "/api/v1/getState", "", http.StatusUnauthorized},
+		{"protected with wrong token", "s3cret", http.MethodGet, "/api/v1/getState", "Bearer nope", http.StatusUnauthorized},
+		{"protected with token", "s3cret", http.MethodPost, "/api/v1/play", "Bearer s3cret", http.StatusOK},
+		{"guest read", "s3cret", http.MethodGet, "/api/v1/getState", "Bearer visitor", http.StatusOK},
+		{"guest write", "s3cret", http.MethodPost, "/api/v1/play", "Bearer visitor", http.StatusForbidden},
+		{"open path", "s3cret", http.MethodGet, "/albumart?path=a", "", http.StatusOK},
+		{"auth disabled", "", http.MethodPost, "/api/v1/play", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.token, WithGuestToken("visitor")).Middleware(ok, "/api/")
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 response missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestRequireFull(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		auth  string
+		want  int
+	}{
+		{"full", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"guest", "s3cret", "Bearer visitor", http.StatusForbidden},
+		{"no token", "s3cret", "", http.StatusUnauthorized},
+		{"auth disabled", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.token, WithGuestToken("visitor")).RequireFull(ok)
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/config/export", nil)
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
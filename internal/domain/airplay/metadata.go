@@ -0,0 +1,103 @@
+package airplay
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Item is one entry from the shairport-sync metadata pipe. Type and Code are
+// four-character codes such as "core"/"minm" (title) or "ssnc"/"pbeg"
+// (playback begins).
+type Item struct {
+	Type string
+	Code string
+	Data []byte
+}
+
+// rawItem is an item as written to the pipe, with hex-encoded codes and
+// base64 data.
+type rawItem struct {
+	Type string `xml:"type"`
+	Code string `xml:"code"`
+	Data string `xml:"data"`
+}
+
+// ReadItems decodes metadata items from r until it fails, calling fn for each.
+// Malformed items are skipped.
+func ReadItems(r io.Reader, fn func(Item)) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		var raw rawItem
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+
+		item, ok := decodeItem(raw)
+		if ok {
+			fn(item)
+		}
+	}
+}
+
+// decodeItem converts the pipe encoding to an Item.
+func decodeItem(raw rawItem) (Item, bool) {
+	typ, err := hex.DecodeString(strings.TrimSpace(raw.Type))
+	if err != nil {
+		return Item{}, false
+	}
+	code, err := hex.DecodeString(strings.TrimSpace(raw.Code))
+	if err != nil {
+		return Item{}, false
+	}
+
+	item := Item{Type: string(typ), Code: string(code)}
+	if data := strings.Join(strings.Fields(raw.Data), ""); data != "" {
+		item.Data, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return Item{}, false
+		}
+	}
+	return item, true
+}
+
+// Apply updates the playback state from an item and reports whether clients
+// should be told. Track fields are collected as they arrive and announced
+// when the metadata bundle ends.
+func (p *Playback) Apply(item Item) bool {
+	switch item.Type {
+	case "core":
+		switch item.Code {
+		case "minm":
+			p.Track.Title = string(item.Data)
+		case "asar":
+			p.Track.Artist = string(item.Data)
+		case "asal":
+			p.Track.Album = string(item.Data)
+		case "asgn":
+			p.Track.Genre = string(item.Data)
+		}
+		return false
+
+	case "ssnc":
+		switch item.Code {
+		case "pbeg": // Play stream begins
+			p.Active, p.Playing = true, true
+		case "pend": // Play stream ends
+			*p = Playback{}
+		case "pfls": // Flush, sent on pause
+			p.Playing = false
+		case "prsm": // Resume
+			p.Active, p.Playing = true, true
+		case "snam": // Client device name
+			p.Client = string(item.Data)
+		case "mden": // Metadata bundle ends
+		default:
+			return false
+		}
+		return true
+	}
+	return false
+}
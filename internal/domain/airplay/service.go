@@ -0,0 +1,154 @@
+package airplay
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/external"
+)
+
+const (
+	// pipeRetryInterval is how long to wait for a missing metadata pipe
+	pipeRetryInterval = 30 * time.Second
+
+	// pipeReopenInterval is how long to wait after shairport-sync closed the pipe
+	pipeReopenInterval = 1 * time.Second
+)
+
+// Service provides shairport-sync detection and follows its metadata pipe.
+type Service struct {
+	pipePath string
+
+	mu       sync.RWMutex
+	playback Playback
+}
+
+// Service can be managed alongside other audio daemons.
+var _ external.ExternalSource = (*Service)(nil)
+
+// NewService creates a new AirPlay service reading the default metadata pipe.
+func NewService() *Service {
+	return &Service{pipePath: Paths.MetadataPipe}
+}
+
+// GetStatus returns the complete AirPlay status.
+func (s *Service) GetStatus() Status {
+	return Status{
+		Installed: s.checkInstalled(),
+		Service:   s.getServiceStatus(),
+		Playback:  s.Playback(),
+	}
+}
+
+// Playback returns the current AirPlay session state.
+func (s *Service) Playback() Playback {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.playback
+}
+
+// Watch follows the metadata pipe until ctx is cancelled, calling onChange
+// whenever the session state changes.
+func (s *Service) Watch(ctx context.Context, onChange func(Playback)) {
+	go func() {
+		log.Info().Str("pipe", s.pipePath).Msg("AirPlay metadata watcher started")
+		for {
+			delay := pipeReopenInterval
+			if opened, err := s.follow(ctx, onChange); !opened {
+				log.Debug().Err(err).Msg("AirPlay metadata pipe unavailable")
+				delay = pipeRetryInterval
+			}
+
+			// The session is over once the pipe closes
+			s.update(func(p *Playback) bool {
+				if !p.Active {
+					return false
+				}
+				*p = Playback{}
+				return true
+			}, onChange)
+
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("AirPlay metadata watcher stopped")
+				return
+			case <-time.After(delay):
+			}
+		}
+	}()
+}
+
+// follow reads the metadata pipe until it closes or ctx is cancelled. It
+// reports whether the pipe could be opened.
+func (s *Service) follow(ctx context.Context, onChange func(Playback)) (bool, error) {
+	if _, err := os.Stat(s.pipePath); err != nil {
+		return false, err
+	}
+
+	// Opening a FIFO blocks until shairport-sync has it open for writing
+	pipe, err := os.Open(s.pipePath)
+	if err != nil {
+		return false, err
+	}
+	defer pipe.Close()
+
+	stop := context.AfterFunc(ctx, func() { pipe.Close() })
+	defer stop()
+
+	err = ReadItems(pipe, func(item Item) {
+		s.update(func(p *Playback) bool { return p.Apply(item) }, onChange)
+	})
+	return true, err
+}
+
+// update applies fn under the lock and notifies onChange if it reports a change.
+func (s *Service) update(fn func(*Playback) bool, onChange func(Playback)) {
+	s.mu.Lock()
+	changed := fn(&s.playback)
+	playback := s.playback
+	s.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange(playback)
+	}
+}
+
+// checkInstalled checks if the shairport-sync binary is installed.
+func (s *Service) checkInstalled() bool {
+	return external.AnyExists(Paths.Binaries...)
+}
+
+// getServiceStatus returns the systemd service status.
+func (s *Service) getServiceStatus() ServiceStatus {
+	return external.SystemctlStatus(Paths.SystemdUnit)
+}
+
+// Name returns the external source identifier.
+func (s *Service) Name() string {
+	return "shairport-sync"
+}
+
+// Status returns the external source status.
+func (s *Service) Status() external.SourceStatus {
+	return external.SourceStatus{
+		Name:        s.Name(),
+		DisplayName: "AirPlay (shairport-sync)",
+		Installed:   s.checkInstalled(),
+		Service:     s.getServiceStatus(),
+	}
+}
+
+// Start starts shairport-sync.
+func (s *Service) Start() error {
+	return exec.Command("sudo", "systemctl", "start", Paths.SystemdUnit).Run()
+}
+
+// Stop stops shairport-sync.
+func (s *Service) Stop() error {
+	return exec.Command("sudo", "systemctl", "stop", Paths.SystemdUnit).Run()
+}
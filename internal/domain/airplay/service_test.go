@@ -0,0 +1,87 @@
+package airplay
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// pipeItem renders an item the way shairport-sync writes it to the pipe.
+func pipeItem(typ, code, data string) string {
+	s := fmt.Sprintf("<item><type>%s</type><code>%s</code><length>%d</length>",
+		hex.EncodeToString([]byte(typ)), hex.EncodeToString([]byte(code)), len(data))
+	if data != "" {
+		s += "\n<data encoding=\"base64\">\n" + base64.StdEncoding.EncodeToString([]byte(data)) + "</data>"
+	}
+	return s + "</item>\n"
+}
+
+func TestReadItems(t *testing.T) {
+	stream := pipeItem("ssnc", "pbeg", "") +
+		pipeItem("core", "minm", "So What") +
+		pipeItem("core", "asar", "Miles Davis")
+
+	var items []Item
+	err := ReadItems(strings.NewReader(stream), func(item Item) {
+		items = append(items, item)
+	})
+	if err != io.EOF {
+		t.Fatalf("ReadItems error = %v, want EOF", err)
+	}
+
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3", len(items))
+	}
+	if items[0].Type != "ssnc" || items[0].Code != "pbeg" {
+		t.Errorf("item 0 = %s/%s, want ssnc/pbeg", items[0].Type, items[0].Code)
+	}
+	if items[1].Code != "minm" || string(items[1].Data) != "So What" {
+		t.Errorf("item 1 = %s %q, want minm \"So What\"", items[1].Code, items[1].Data)
+	}
+}
+
+func TestPlayback_Apply(t *testing.T) {
+	var p Playback
+
+	steps := []struct {
+		item       Item
+		wantChange bool
+	}{
+		{Item{Type: "ssnc", Code: "snam", Data: []byte("iPhone")}, true},
+		{Item{Type: "ssnc", Code: "pbeg"}, true},
+		{Item{Type: "ssnc", Code: "mdst"}, false},
+		{Item{Type: "core", Code: "minm", Data: []byte("So What")}, false},
+		{Item{Type: "core", Code: "asar", Data: []byte("Miles Davis")}, false},
+		{Item{Type: "core", Code: "asal", Data: []byte("Kind of Blue")}, false},
+		{Item{Type: "ssnc", Code: "mden"}, true},
+		{Item{Type: "ssnc", Code: "prgr", Data: []byte("1/2/3")}, false},
+	}
+	for _, step := range steps {
+		if changed := p.Apply(step.item); changed != step.wantChange {
+			t.Errorf("Apply(%s/%s) changed = %v, want %v", step.item.Type, step.item.Code, changed, step.wantChange)
+		}
+	}
+
+	want := Playback{
+		Active:  true,
+		Playing: true,
+		Client:  "iPhone",
+		Track:   NowPlaying{Title: "So What", Artist: "Miles Davis", Album: "Kind of Blue"},
+	}
+	if p != want {
+		t.Errorf("playback = %+v, want %+v", p, want)
+	}
+
+	p.Apply(Item{Type: "ssnc", Code: "pfls"})
+	if !p.Active || p.Playing {
+		t.Errorf("after pause: active=%v playing=%v, want true false", p.Active, p.Playing)
+	}
+
+	p.Apply(Item{Type: "ssnc", Code: "pend"})
+	if p != (Playback{}) {
+		t.Errorf("after end: %+v, want empty", p)
+	}
+}
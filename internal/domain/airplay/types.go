@@ -0,0 +1,43 @@
+// Package airplay provides shairport-sync detection and AirPlay now-playing
+// metadata.
+package airplay
+
+import "github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/external"
+
+// NowPlaying is the track an AirPlay client is streaming.
+type NowPlaying struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	Genre  string `json:"genre,omitempty"`
+}
+
+// Playback is the AirPlay session state read from the metadata pipe.
+type Playback struct {
+	Active  bool       `json:"active"`  // A client is connected and streaming
+	Playing bool       `json:"playing"` // False while the client is paused
+	Client  string     `json:"client,omitempty"`
+	Track   NowPlaying `json:"track"`
+}
+
+// ServiceStatus represents the systemd service status of shairport-sync.
+type ServiceStatus = external.ServiceStatus
+
+// Status represents the complete AirPlay status.
+type Status struct {
+	Installed bool          `json:"installed"`
+	Service   ServiceStatus `json:"service"`
+	Playback
+	Error string `json:"error,omitempty"`
+}
+
+// Paths contains the default shairport-sync locations.
+var Paths = struct {
+	Binaries     []string
+	MetadataPipe string
+	SystemdUnit  string
+}{
+	Binaries:     []string{"/usr/bin/shairport-sync", "/usr/local/bin/shairport-sync"},
+	MetadataPipe: "/tmp/shairport-sync-metadata",
+	SystemdUnit:  "shairport-sync",
+}
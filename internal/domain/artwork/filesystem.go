@@ -2,9 +2,11 @@
 package artwork
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/rs/zerolog/log"
 )
@@ -26,10 +28,15 @@ var ArtworkExtensions = []string{
 	".webp",
 }
 
+// ErrInvalidPath is returned for track URIs that are absolute or contain ".."
+// components.
+var ErrInvalidPath = errors.New("invalid track path")
+
 // FilesystemFinder searches for artwork files on the filesystem.
 type FilesystemFinder struct {
-	musicDir  string // MPD music directory (e.g., /var/lib/mpd/music)
-	maxLevels int    // Maximum parent directories to search (default: 3)
+	musicDir  string   // MPD music directory (e.g., /var/lib/mpd/music)
+	roots     []string // Other directories symlinks in musicDir may point into
+	maxLevels int      // Maximum parent directories to search (default: 3)
 }
 
 // NewFilesystemFinder creates a new filesystem artwork finder.
@@ -40,12 +47,42 @@ func NewFilesystemFinder(musicDir string) *FilesystemFinder {
 	}
 }
 
+// WithRoots allows searching directories that symlinks in the music
+// directory resolve into, such as the NAS and USB mount bases. Anything
+// else a symlink points at is not searched.
+func (f *FilesystemFinder) WithRoots(roots ...string) *FilesystemFinder {
+	f.roots = append(f.roots, roots...)
+	return f
+}
+
+// ValidTrackURI reports whether uri is a relative MPD URI that stays inside
+// the music directory: not absolute, without ".." components and without
+// control characters, which could break out of an MPD command line.
+func ValidTrackURI(uri string) bool {
+	if uri == "" || strings.HasPrefix(uri, "/") || filepath.IsAbs(uri) {
+		return false
+	}
+	if strings.IndexFunc(uri, unicode.IsControl) >= 0 {
+		return false
+	}
+	for _, part := range strings.Split(uri, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
 // FindArtwork searches for artwork file starting from the track's directory.
 // Returns the full path to the artwork file if found, empty string otherwise.
+// URIs rejected by ValidTrackURI return ErrInvalidPath.
 func (f *FilesystemFinder) FindArtwork(trackURI string) (string, error) {
 	if trackURI == "" {
 		return "", nil
 	}
+	if !ValidTrackURI(trackURI) {
+		return "", ErrInvalidPath
+	}
 
 	// Calculate full path from relative URI
 	fullPath := filepath.Join(f.musicDir, trackURI)
@@ -71,13 +108,17 @@ func (f *FilesystemFinder) FindArtwork(trackURI string) (string, error) {
 		if err != nil {
 			break
 		}
-		if !strings.HasPrefix(currentDirAbs, musicDirAbs) {
+		if !withinDir(currentDirAbs, musicDirAbs) {
 			log.Debug().
 				Str("currentDir", currentDirAbs).
 				Str("musicDir", musicDirAbs).
 				Msg("Reached music root boundary, stopping search")
 			break // Don't search outside music directory
 		}
+		if !f.allowedDir(currentDir) {
+			log.Debug().Str("currentDir", currentDirAbs).Msg("Directory resolves outside the allowed roots, stopping search")
+			break
+		}
 
 		// Search for artwork in current directory
 		if artPath := f.searchDirectory(currentDir); artPath != "" {
@@ -155,6 +196,33 @@ func (f *FilesystemFinder) searchDirectory(dir string) string {
 	return ""
 }
 
+// allowedDir reports whether dir, with symlinks resolved, lies in the music
+// directory or one of the extra roots.
+func (f *FilesystemFinder) allowedDir(dir string) bool {
+	return WithinRoots(dir, append([]string{f.musicDir}, f.roots...)...)
+}
+
+// WithinRoots reports whether path, with symlinks resolved, is one of roots
+// or lies inside one. Paths that don't exist are in none.
+func WithinRoots(path string, roots ...string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		if rootResolved, err := filepath.EvalSymlinks(root); err == nil && withinDir(resolved, rootResolved) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinDir reports whether path is dir or inside it.
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
 // fileExists checks if a file exists and is not a directory.
 func fileExists(path string) bool {
 	info, err := os.Stat(path)
@@ -1,6 +1,7 @@
 package artwork
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -328,3 +329,95 @@ func TestFilesystemFinder_EmptyTrackURI(t *testing.T) {
 		t.Errorf("Expected empty result for empty URI, got %s", result)
 	}
 }
+
+func TestFilesystemFinder_FindArtwork_RejectsTraversal(t *testing.T) {
+	tmpDir := t.TempDir()
+	musicDir := filepath.Join(tmpDir, "music")
+	if err := os.MkdirAll(musicDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "cover.jpg"), []byte("outside cover"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	finder := NewFilesystemFinder(musicDir)
+	for _, uri := range []string{"../cover.jpg", "Album/../../x.flac", "/etc/passwd"} {
+		if result, err := finder.FindArtwork(uri); !errors.Is(err, ErrInvalidPath) || result != "" {
+			t.Errorf("FindArtwork(%q) = %q, %v; want ErrInvalidPath", uri, result, err)
+		}
+	}
+}
+
+func TestFilesystemFinder_FindArtwork_SymlinkedMountRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	musicDir := filepath.Join(tmpDir, "music")
+	nasRoot := filepath.Join(tmpDir, "mnt", "NAS")
+	albumDir := filepath.Join(nasRoot, "Share", "Album")
+	if err := os.MkdirAll(albumDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(musicDir, "NAS"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(nasRoot, "Share"), filepath.Join(musicDir, "NAS", "Share")); err != nil {
+		t.Fatal(err)
+	}
+	coverPath := filepath.Join(albumDir, "folder.png")
+	if err := os.WriteFile(coverPath, []byte("nas cover"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the mount root the symlink target is off limits
+	if result, _ := NewFilesystemFinder(musicDir).FindArtwork("NAS/Share/Album/01.flac"); result != "" {
+		t.Errorf("Searched a symlink target outside the allowed roots: %s", result)
+	}
+
+	finder := NewFilesystemFinder(musicDir).WithRoots(nasRoot)
+	result, err := finder.FindArtwork("NAS/Share/Album/01.flac")
+	if err != nil {
+		t.Fatalf("FindArtwork returned error: %v", err)
+	}
+	if filepath.Base(result) != "folder.png" {
+		t.Errorf("Expected folder.png on the NAS mount, got %q", result)
+	}
+}
+
+func TestFilesystemFinder_FindArtwork_SiblingDirPrefix(t *testing.T) {
+	// "music2" shares a prefix with "music" but is outside it
+	tmpDir := t.TempDir()
+	musicDir := filepath.Join(tmpDir, "music")
+	if err := os.MkdirAll(filepath.Join(tmpDir, "music2", "Album"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(musicDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(tmpDir, "music2", "Album"), filepath.Join(musicDir, "Album")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "music2", "Album", "cover.jpg"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, _ := NewFilesystemFinder(musicDir).FindArtwork("Album/01.flac"); result != "" {
+		t.Errorf("Searched a sibling directory sharing the music dir prefix: %s", result)
+	}
+}
+
+func TestValidTrackURI(t *testing.T) {
+	tests := map[string]bool{
+		"NAS/Album/01.flac":    true,
+		"Album..Live/01.flac":  true,
+		"":                     false,
+		"/etc/passwd":          false,
+		"../etc/passwd":        false,
+		"NAS/../../etc/passwd": false,
+		"NAS/Album/..":         false,
+		"NAS/01.flac\nclear":   false,
+	}
+	for uri, want := range tests {
+		if got := ValidTrackURI(uri); got != want {
+			t.Errorf("ValidTrackURI(%q) = %v, want %v", uri, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package artwork
+
+import (
+	"bytes"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// PlaceholderSize is the width and height of generated placeholders.
+const PlaceholderSize = 300
+
+// Placeholder returns a PNG tile for an album without artwork, filled with
+// a muted color derived from the title so each album keeps its own color.
+func Placeholder(title string) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, PlaceholderSize, PlaceholderSize))
+	draw.Draw(img, img.Bounds(), &image.Uniform{PlaceholderColor(title)}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	// Encoding an in-memory RGBA image cannot fail
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// PlaceholderColor returns the placeholder color for an album title: the
+// hue comes from the title's hash, saturation and brightness are fixed so
+// white text stays readable on any of them.
+func PlaceholderColor(title string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(title))
+	hue := float64(h.Sum32()%360) / 60
+
+	const saturation, value = 0.45, 0.55
+	chroma := value * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+	var r, g, b float64
+	switch int(hue) {
+	case 0:
+		r, g = chroma, x
+	case 1:
+		r, g = x, chroma
+	case 2:
+		g, b = chroma, x
+	case 3:
+		g, b = x, chroma
+	case 4:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+	m := value - chroma
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 255,
+	}
+}
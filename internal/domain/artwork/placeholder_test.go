@@ -0,0 +1,49 @@
+package artwork
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	data := Placeholder("Kind of Blue")
+	if mime := DetectMimeType(data); mime != "image/png" {
+		t.Fatalf("Placeholder() MIME type = %s, want image/png", mime)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Placeholder() is not a valid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != PlaceholderSize || b.Dy() != PlaceholderSize {
+		t.Errorf("Placeholder() size = %dx%d, want %dx%d", b.Dx(), b.Dy(), PlaceholderSize, PlaceholderSize)
+	}
+	r, g, b, _ := img.At(PlaceholderSize/2, PlaceholderSize/2).RGBA()
+	want := PlaceholderColor("Kind of Blue")
+	if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+		t.Errorf("Placeholder() color = %d,%d,%d, want %v", r>>8, g>>8, b>>8, want)
+	}
+}
+
+func TestPlaceholderColor(t *testing.T) {
+	if PlaceholderColor("Abbey Road") != PlaceholderColor("Abbey Road") {
+		t.Error("PlaceholderColor() differs for the same title")
+	}
+
+	colors := make(map[[3]uint8]bool)
+	for _, title := range []string{"Abbey Road", "Blue Train", "Kind of Blue", "Revolver", "Thriller", ""} {
+		c := PlaceholderColor(title)
+		if c.A != 255 {
+			t.Errorf("PlaceholderColor(%q) is not opaque: %v", title, c)
+		}
+		// Muted enough for white text: no channel near full brightness
+		if c.R > 150 || c.G > 150 || c.B > 150 {
+			t.Errorf("PlaceholderColor(%q) = %v, too bright", title, c)
+		}
+		colors[[3]uint8{c.R, c.G, c.B}] = true
+	}
+	if len(colors) < 4 {
+		t.Errorf("PlaceholderColor() gave %d distinct colors for 6 titles, want variety", len(colors))
+	}
+}
@@ -0,0 +1,216 @@
+// Package artwork provides artwork resolution and caching for albums and artists.
+package artwork
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultPrewarmConcurrency is how many albums are resolved at once.
+	// Enough to overlap NAS and MPD latency without starving playback.
+	DefaultPrewarmConcurrency = 4
+
+	// prewarmIdlePoll is how often paused workers check whether playback stopped.
+	prewarmIdlePoll = 5 * time.Second
+
+	// prewarmProgressSteps limits progress reports to about one per percent.
+	prewarmProgressSteps = 100
+)
+
+// AlbumResolver finds and caches artwork for one album.
+type AlbumResolver interface {
+	Resolve(albumID, trackURI string) (*ResolveResult, error)
+}
+
+// PrewarmAlbum is an album whose artwork should be cached.
+type PrewarmAlbum struct {
+	ID         string
+	FirstTrack string
+}
+
+// PrewarmProgress reports a pre-warm run, pushed on pushArtworkProgress.
+type PrewarmProgress struct {
+	Running bool `json:"running"`
+	Paused  bool `json:"paused"` // Waiting for playback to stop
+	Total   int  `json:"total"`
+	Done    int  `json:"done"`
+	Found   int  `json:"found"` // Albums whose artwork was found and cached
+}
+
+// Prewarmer fills the artwork cache for many albums with a bounded worker
+// pool, so the library grid doesn't resolve art on first view. Workers hold
+// off while the busy check reports playback, as the extra NAS and SD card
+// I/O can cause audio dropouts on a Pi.
+type Prewarmer struct {
+	resolver    AlbumResolver
+	concurrency int
+	busy        func() bool
+	onProgress  func(PrewarmProgress)
+	idlePoll    time.Duration
+
+	mu       sync.Mutex
+	progress PrewarmProgress
+	reported int // Done count at the last progress report
+}
+
+// NewPrewarmer creates a pre-warmer running concurrency workers, or
+// DefaultPrewarmConcurrency if concurrency < 1.
+func NewPrewarmer(resolver AlbumResolver, concurrency int) *Prewarmer {
+	if concurrency < 1 {
+		concurrency = DefaultPrewarmConcurrency
+	}
+	return &Prewarmer{
+		resolver:    resolver,
+		concurrency: concurrency,
+		idlePoll:    prewarmIdlePoll,
+	}
+}
+
+// WithBusy sets a check that pauses the workers while it returns true.
+func (p *Prewarmer) WithBusy(busy func() bool) *Prewarmer {
+	p.busy = busy
+	return p
+}
+
+// WithProgress sets a function called as the run progresses. It is called
+// about once per percent, on pause and resume, and when the run ends.
+func (p *Prewarmer) WithProgress(fn func(PrewarmProgress)) *Prewarmer {
+	p.onProgress = fn
+	return p
+}
+
+// WithIdlePoll sets how often paused workers re-check the busy function.
+func (p *Prewarmer) WithIdlePoll(d time.Duration) *Prewarmer {
+	p.idlePoll = d
+	return p
+}
+
+// Progress returns the progress of the current or last run.
+func (p *Prewarmer) Progress() PrewarmProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.progress
+}
+
+// Run resolves artwork for albums and blocks until all are done or ctx is
+// cancelled, returning ctx.Err() in that case. Only one run happens at a
+// time; calling Run while one is in progress returns immediately.
+func (p *Prewarmer) Run(ctx context.Context, albums []PrewarmAlbum) error {
+	p.mu.Lock()
+	if p.progress.Running {
+		p.mu.Unlock()
+		return nil
+	}
+	p.progress = PrewarmProgress{Running: true, Total: len(albums)}
+	p.reported = 0
+	p.mu.Unlock()
+
+	log.Info().Int("albums", len(albums)).Int("workers", p.concurrency).Msg("Pre-warming album artwork")
+	p.report()
+
+	jobs := make(chan PrewarmAlbum)
+	var wg sync.WaitGroup
+	for range p.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for album := range jobs {
+				p.resolve(album)
+			}
+		}()
+	}
+
+feed:
+	for _, album := range albums {
+		if !p.waitIdle(ctx) {
+			break
+		}
+		select {
+		case jobs <- album:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	p.mu.Lock()
+	p.progress.Running = false
+	p.progress.Paused = false
+	final := p.progress
+	p.mu.Unlock()
+	p.report()
+
+	if err := ctx.Err(); err != nil {
+		log.Info().Int("done", final.Done).Int("total", final.Total).Msg("Artwork pre-warm cancelled")
+		return err
+	}
+	log.Info().Int("found", final.Found).Int("total", final.Total).Msg("Artwork pre-warm complete")
+	return nil
+}
+
+// resolve caches one album's artwork and records the outcome.
+func (p *Prewarmer) resolve(album PrewarmAlbum) {
+	found := false
+	if album.FirstTrack != "" {
+		result, err := p.resolver.Resolve(album.ID, album.FirstTrack)
+		if err != nil {
+			log.Debug().Err(err).Str("albumID", album.ID).Msg("Artwork pre-warm failed")
+		}
+		found = err == nil && result != nil && result.Source != "placeholder"
+	}
+
+	p.mu.Lock()
+	p.progress.Done++
+	if found {
+		p.progress.Found++
+	}
+	due := p.progress.Done-p.reported >= max(p.progress.Total/prewarmProgressSteps, 1)
+	if due {
+		p.reported = p.progress.Done
+	}
+	p.mu.Unlock()
+
+	if due {
+		p.report()
+	}
+}
+
+// waitIdle blocks while the busy check reports playback. It returns false
+// if ctx is cancelled first.
+func (p *Prewarmer) waitIdle(ctx context.Context) bool {
+	if p.busy == nil || !p.busy() {
+		return ctx.Err() == nil
+	}
+
+	p.setPaused(true)
+	defer p.setPaused(false)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(p.idlePoll):
+		}
+		if !p.busy() {
+			return true
+		}
+	}
+}
+
+func (p *Prewarmer) setPaused(paused bool) {
+	p.mu.Lock()
+	p.progress.Paused = paused
+	p.mu.Unlock()
+	log.Debug().Bool("paused", paused).Msg("Artwork pre-warm paused state changed")
+	p.report()
+}
+
+func (p *Prewarmer) report() {
+	if p.onProgress != nil {
+		p.onProgress(p.Progress())
+	}
+}
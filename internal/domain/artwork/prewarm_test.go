@@ -0,0 +1,162 @@
+package artwork_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/artwork"
+)
+
+// fakeResolver finds art for every album except those in missing, tracking
+// how many resolves run at once.
+type fakeResolver struct {
+	missing map[string]bool
+	delay   time.Duration
+
+	mu       sync.Mutex
+	active   int
+	peak     int
+	resolved []string
+}
+
+func (f *fakeResolver) Resolve(albumID, trackURI string) (*artwork.ResolveResult, error) {
+	f.mu.Lock()
+	f.active++
+	f.peak = max(f.peak, f.active)
+	f.resolved = append(f.resolved, albumID)
+	f.mu.Unlock()
+
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+
+	if f.missing[albumID] {
+		return &artwork.ResolveResult{Source: "placeholder"}, nil
+	}
+	return &artwork.ResolveResult{Source: "folder"}, nil
+}
+
+func prewarmAlbums(n int) []artwork.PrewarmAlbum {
+	albums := make([]artwork.PrewarmAlbum, n)
+	for i := range albums {
+		albums[i] = artwork.PrewarmAlbum{ID: string(rune('a' + i)), FirstTrack: "NAS/album/01.flac"}
+	}
+	return albums
+}
+
+func TestPrewarmerRun(t *testing.T) {
+	resolver := &fakeResolver{missing: map[string]bool{"b": true}, delay: 10 * time.Millisecond}
+	var mu sync.Mutex
+	var last artwork.PrewarmProgress
+	p := artwork.NewPrewarmer(resolver, 3).WithProgress(func(progress artwork.PrewarmProgress) {
+		mu.Lock()
+		last = progress
+		mu.Unlock()
+	})
+
+	if err := p.Run(context.Background(), prewarmAlbums(10)); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := artwork.PrewarmProgress{Total: 10, Done: 10, Found: 9}
+	if got := p.Progress(); got != want {
+		t.Errorf("Progress() = %+v, want %+v", got, want)
+	}
+	if last != want {
+		t.Errorf("last reported progress = %+v, want %+v", last, want)
+	}
+	if resolver.peak > 3 {
+		t.Errorf("%d resolves ran at once, want at most 3", resolver.peak)
+	}
+	if resolver.peak < 2 {
+		t.Errorf("resolves never overlapped (peak %d)", resolver.peak)
+	}
+}
+
+func TestPrewarmerSkipsAlbumsWithoutTracks(t *testing.T) {
+	resolver := &fakeResolver{}
+	p := artwork.NewPrewarmer(resolver, 2)
+
+	p.Run(context.Background(), []artwork.PrewarmAlbum{{ID: "a"}, {ID: "b", FirstTrack: "b.flac"}})
+
+	if len(resolver.resolved) != 1 || resolver.resolved[0] != "b" {
+		t.Errorf("resolved %v, want [b]", resolver.resolved)
+	}
+	if got := p.Progress(); got.Done != 2 || got.Found != 1 {
+		t.Errorf("Progress() = %+v, want 2 done, 1 found", got)
+	}
+}
+
+func TestPrewarmerPausesWhileBusy(t *testing.T) {
+	resolver := &fakeResolver{}
+	var busy atomic.Bool
+	busy.Store(true)
+
+	var sawPaused atomic.Bool
+	p := artwork.NewPrewarmer(resolver, 2).
+		WithIdlePoll(5 * time.Millisecond).
+		WithBusy(busy.Load).
+		WithProgress(func(progress artwork.PrewarmProgress) {
+			if progress.Paused {
+				sawPaused.Store(true)
+			}
+		})
+
+	done := make(chan error)
+	go func() { done <- p.Run(context.Background(), prewarmAlbums(4)) }()
+
+	time.Sleep(30 * time.Millisecond)
+	if got := p.Progress(); !got.Paused || got.Done != 0 {
+		t.Errorf("while busy: Progress() = %+v, want paused with nothing done", got)
+	}
+
+	busy.Store(false)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not resume after playback stopped")
+	}
+	if !sawPaused.Load() {
+		t.Error("pause was not reported")
+	}
+	if got := p.Progress(); got.Done != 4 || got.Paused {
+		t.Errorf("Progress() = %+v, want 4 done and not paused", got)
+	}
+}
+
+func TestPrewarmerCancel(t *testing.T) {
+	resolver := &fakeResolver{}
+	p := artwork.NewPrewarmer(resolver, 2).
+		WithIdlePoll(time.Hour).
+		WithBusy(func() bool { return true })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx, prewarmAlbums(4)) }()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+	if p.Progress().Running {
+		t.Error("still running after cancel")
+	}
+	if len(resolver.resolved) != 0 {
+		t.Errorf("resolved %v while paused", resolver.resolved)
+	}
+}
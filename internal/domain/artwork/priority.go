@@ -0,0 +1,109 @@
+package artwork
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/atomicfile"
+)
+
+// Priority chooses which kind of album art /albumart prefers when a track
+// has both.
+type Priority string
+
+const (
+	// PreferEmbedded serves the picture in the track's tags first. It is the
+	// default since embedded art is specific to the track.
+	PreferEmbedded Priority = "embedded"
+	// PreferFolder serves cover files such as folder.jpg first, for
+	// libraries whose embedded art is only a small thumbnail.
+	PreferFolder Priority = "folder"
+)
+
+// ParsePriority validates a priority name.
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(s); p {
+	case PreferEmbedded, PreferFolder:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid album art priority %q: must be %q or %q", s, PreferEmbedded, PreferFolder)
+}
+
+// PriorityStore persists the album art priority.
+type PriorityStore struct {
+	filePath string
+	mu       sync.RWMutex
+	priority Priority
+}
+
+// persistedPriority is the format stored on disk.
+type persistedPriority struct {
+	Priority Priority `json:"priority"`
+}
+
+// NewPriorityStore creates a priority store in dataDir and loads the saved
+// choice, falling back to PreferEmbedded.
+func NewPriorityStore(dataDir string) *PriorityStore {
+	p := &PriorityStore{
+		filePath: filepath.Join(dataDir, "artwork.json"),
+		priority: PreferEmbedded,
+	}
+	p.load()
+	return p
+}
+
+// Get returns the current priority.
+func (p *PriorityStore) Get() Priority {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.priority
+}
+
+// Set validates and saves a new priority.
+func (p *PriorityStore) Set(priority Priority) error {
+	if _, err := ParsePriority(string(priority)); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, err := json.MarshalIndent(persistedPriority{Priority: priority}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.Write(p.filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save album art priority: %w", err)
+	}
+	p.priority = priority
+
+	log.Info().Str("priority", string(priority)).Msg("Album art priority set")
+	return nil
+}
+
+// load reads the saved priority, ignoring a missing or invalid file.
+func (p *PriorityStore) load() {
+	data, err := os.ReadFile(p.filePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", p.filePath).Msg("Failed to read album art priority")
+		}
+		return
+	}
+	var saved persistedPriority
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Warn().Err(err).Str("path", p.filePath).Msg("Failed to parse album art priority")
+		return
+	}
+	priority, err := ParsePriority(string(saved.Priority))
+	if err != nil {
+		log.Warn().Err(err).Str("path", p.filePath).Msg("Ignoring saved album art priority")
+		return
+	}
+	p.priority = priority
+}
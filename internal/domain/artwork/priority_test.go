@@ -0,0 +1,50 @@
+package artwork
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePriority(t *testing.T) {
+	for _, name := range []string{"embedded", "folder"} {
+		if got, err := ParsePriority(name); err != nil || string(got) != name {
+			t.Errorf("ParsePriority(%q) = %q, %v", name, got, err)
+		}
+	}
+	for _, name := range []string{"", "Folder", "web"} {
+		if _, err := ParsePriority(name); err == nil {
+			t.Errorf("ParsePriority(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestPriorityStore_Persists(t *testing.T) {
+	dir := t.TempDir()
+
+	store := NewPriorityStore(dir)
+	if got := store.Get(); got != PreferEmbedded {
+		t.Errorf("default priority = %q, want %q", got, PreferEmbedded)
+	}
+	if err := store.Set("thumbnail"); err == nil {
+		t.Error("Set(thumbnail) succeeded, want error")
+	}
+	if err := store.Set(PreferFolder); err != nil {
+		t.Fatalf("Set(folder) failed: %v", err)
+	}
+
+	if got := NewPriorityStore(dir).Get(); got != PreferFolder {
+		t.Errorf("reloaded priority = %q, want %q", got, PreferFolder)
+	}
+}
+
+func TestPriorityStore_IgnoresInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "artwork.json"), []byte(`{"priority": "web"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := NewPriorityStore(dir).Get(); got != PreferEmbedded {
+		t.Errorf("priority from invalid file = %q, want %q", got, PreferEmbedded)
+	}
+}
@@ -8,11 +8,16 @@ import (
 	"strings"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/external"
 )
 
 // Service provides Audirvana detection and discovery functionality.
 type Service struct{}
 
+// Service can be managed alongside other audio daemons.
+var _ external.ExternalSource = (*Service)(nil)
+
 // NewService creates a new Audirvana service.
 func NewService() *Service {
 	return &Service{}
@@ -36,12 +41,7 @@ func (s *Service) checkInstalled() bool {
 
 // getServiceStatus returns the systemd service status.
 func (s *Service) getServiceStatus() ServiceStatus {
-	out, err := exec.Command("systemctl", "status", "audirvanaStudio", "--no-pager").CombinedOutput()
-	if err != nil {
-		// Command may fail with exit code 3 for inactive services, but output is still valid
-		log.Debug().Err(err).Msg("systemctl status returned non-zero exit code")
-	}
-	return ParseSystemctlStatus(string(out))
+	return external.SystemctlStatus("audirvanaStudio")
 }
 
 // discoverInstances uses avahi-browse to find Audirvana instances on the network.
@@ -66,6 +66,32 @@ func (s *Service) StopService() error {
 	return cmd.Run()
 }
 
+// Name returns the external source identifier.
+func (s *Service) Name() string {
+	return "audirvana"
+}
+
+// Status returns the external source status. Unlike GetStatus it skips
+// network discovery of Audirvana instances.
+func (s *Service) Status() external.SourceStatus {
+	return external.SourceStatus{
+		Name:        s.Name(),
+		DisplayName: "Audirvana Studio",
+		Installed:   s.checkInstalled(),
+		Service:     s.getServiceStatus(),
+	}
+}
+
+// Start starts the Audirvana service.
+func (s *Service) Start() error {
+	return s.StartService()
+}
+
+// Stop stops the Audirvana service.
+func (s *Service) Stop() error {
+	return s.StopService()
+}
+
 // ParseAvahiBrowseOutput parses the output of avahi-browse -r _audirvana-ap._tcp --terminate.
 func ParseAvahiBrowseOutput(output string) []Instance {
 	if output == "" || strings.TrimSpace(output) == "" {
@@ -193,46 +219,7 @@ func deduplicateInstances(instances []Instance) []Instance {
 	return result
 }
 
-// ParseSystemctlStatus parses systemctl status audirvanaStudio output.
+// ParseSystemctlStatus parses the output of systemctl status audirvanaStudio.
 func ParseSystemctlStatus(output string) ServiceStatus {
-	result := ServiceStatus{
-		Loaded:  false,
-		Enabled: false,
-		Active:  false,
-		Running: false,
-		PID:     0,
-	}
-
-	if output == "" || strings.Contains(output, "could not be found") {
-		return result
-	}
-
-	// Check if loaded
-	// Loaded: loaded (/etc/systemd/system/audirvanaStudio.service; enabled; preset: enabled)
-	loadedMatch := regexp.MustCompile(`Loaded:\s+loaded\s+\([^;]+;\s*(enabled|disabled)`).FindStringSubmatch(output)
-	if loadedMatch != nil {
-		result.Loaded = true
-		result.Enabled = loadedMatch[1] == "enabled"
-	}
-
-	// Check if active
-	// Active: active (running) since ...
-	// Active: inactive (dead)
-	// Active: failed (Result: exit-code)
-	activeMatch := regexp.MustCompile(`Active:\s+(active|inactive|failed)\s*\(([^)]+)\)`).FindStringSubmatch(output)
-	if activeMatch != nil {
-		result.Active = activeMatch[1] == "active"
-		result.Running = activeMatch[1] == "active" && activeMatch[2] == "running"
-	}
-
-	// Extract PID if running
-	// Main PID: 6448 (audirvanaStudio)
-	if result.Running {
-		pidMatch := regexp.MustCompile(`Main PID:\s+(\d+)`).FindStringSubmatch(output)
-		if pidMatch != nil {
-			result.PID, _ = strconv.Atoi(pidMatch[1])
-		}
-	}
-
-	return result
+	return external.ParseSystemctlStatus(output)
 }
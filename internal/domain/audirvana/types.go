@@ -1,6 +1,8 @@
 // Package audirvana provides Audirvana Studio detection and discovery.
 package audirvana
 
+import "github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/external"
+
 // Instance represents a discovered Audirvana instance on the network.
 type Instance struct {
 	Name            string `json:"name"`
@@ -12,13 +14,7 @@ type Instance struct {
 }
 
 // ServiceStatus represents the systemd service status of Audirvana.
-type ServiceStatus struct {
-	Loaded  bool `json:"loaded"`
-	Enabled bool `json:"enabled"`
-	Active  bool `json:"active"`
-	Running bool `json:"running"`
-	PID     int  `json:"pid,omitempty"`
-}
+type ServiceStatus = external.ServiceStatus
 
 // Status represents the complete Audirvana status.
 type Status struct {
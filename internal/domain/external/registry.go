@@ -0,0 +1,78 @@
+package external
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry holds the external sources shown to clients, in registration order.
+type Registry struct {
+	mu      sync.RWMutex
+	sources []ExternalSource
+}
+
+// NewRegistry creates a registry with the given sources.
+func NewRegistry(sources ...ExternalSource) *Registry {
+	r := &Registry{}
+	for _, src := range sources {
+		r.Register(src)
+	}
+	return r
+}
+
+// Register adds a source, replacing any source with the same name.
+func (r *Registry) Register(src ExternalSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.sources {
+		if existing.Name() == src.Name() {
+			r.sources[i] = src
+			return
+		}
+	}
+	r.sources = append(r.sources, src)
+}
+
+// Get returns a source by name.
+func (r *Registry) Get(name string) (ExternalSource, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, src := range r.sources {
+		if src.Name() == name {
+			return src, true
+		}
+	}
+	return nil, false
+}
+
+// List returns the status of every source.
+func (r *Registry) List() []SourceStatus {
+	r.mu.RLock()
+	sources := append([]ExternalSource(nil), r.sources...)
+	r.mu.RUnlock()
+
+	statuses := make([]SourceStatus, 0, len(sources))
+	for _, src := range sources {
+		statuses = append(statuses, src.Status())
+	}
+	return statuses
+}
+
+// Control starts or stops a source.
+func (r *Registry) Control(name, action string) error {
+	src, ok := r.Get(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, name)
+	}
+
+	switch action {
+	case ActionStart:
+		return src.Start()
+	case ActionStop:
+		return src.Stop()
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownAction, action)
+	}
+}
@@ -0,0 +1,113 @@
+package external
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeSource records start/stop calls.
+type fakeSource struct {
+	name    string
+	running bool
+	err     error
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) Status() SourceStatus {
+	return SourceStatus{Name: f.name, Installed: true, Service: ServiceStatus{Running: f.running}}
+}
+
+func (f *fakeSource) Start() error {
+	if f.err != nil {
+		return f.err
+	}
+	f.running = true
+	return nil
+}
+
+func (f *fakeSource) Stop() error {
+	f.running = false
+	return f.err
+}
+
+func TestRegistry_ListAndControl(t *testing.T) {
+	roon := &fakeSource{name: "roonbridge"}
+	squeeze := &fakeSource{name: "squeezelite", running: true}
+	r := NewRegistry(roon, squeeze)
+
+	list := r.List()
+	if len(list) != 2 || list[0].Name != "roonbridge" || list[1].Name != "squeezelite" {
+		t.Fatalf("List = %+v, want roonbridge then squeezelite", list)
+	}
+
+	if err := r.Control("roonbridge", ActionStart); err != nil || !roon.running {
+		t.Errorf("start: err=%v running=%v", err, roon.running)
+	}
+	if err := r.Control("squeezelite", ActionStop); err != nil || squeeze.running {
+		t.Errorf("stop: err=%v running=%v", err, squeeze.running)
+	}
+
+	if err := r.Control("missing", ActionStart); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("unknown source err = %v, want ErrUnknownSource", err)
+	}
+	if err := r.Control("roonbridge", "restart"); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("unknown action err = %v, want ErrUnknownAction", err)
+	}
+}
+
+func TestRegistry_RegisterReplacesSameName(t *testing.T) {
+	r := NewRegistry(&fakeSource{name: "roonbridge"})
+	replacement := &fakeSource{name: "roonbridge", running: true}
+	r.Register(replacement)
+
+	list := r.List()
+	if len(list) != 1 || !list[0].Service.Running {
+		t.Errorf("List = %+v, want the replacement only", list)
+	}
+}
+
+func TestRegistry_ControlError(t *testing.T) {
+	r := NewRegistry(&fakeSource{name: "roonbridge", err: errors.New("sudo failed")})
+
+	if err := r.Control("roonbridge", ActionStart); err == nil {
+		t.Error("expected start error to propagate")
+	}
+}
+
+func TestParseSystemctlStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   ServiceStatus
+	}{
+		{
+			name: "running",
+			output: `● shairport-sync.service - Shairport Sync - AirPlay Audio Receiver
+     Loaded: loaded (/lib/systemd/system/shairport-sync.service; enabled; preset: enabled)
+     Active: active (running) since Mon 2024-01-15 10:00:00 UTC; 2h ago
+   Main PID: 612 (shairport-sync)`,
+			want: ServiceStatus{Loaded: true, Enabled: true, Active: true, Running: true, PID: 612},
+		},
+		{
+			name: "stopped",
+			output: `○ roonbridge.service - RoonBridge
+     Loaded: loaded (/etc/systemd/system/roonbridge.service; disabled; preset: enabled)
+     Active: inactive (dead)`,
+			want: ServiceStatus{Loaded: true},
+		},
+		{
+			name:   "missing unit",
+			output: "Unit squeezelite.service could not be found.",
+			want:   ServiceStatus{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSystemctlStatus(tt.output); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
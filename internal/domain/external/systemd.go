@@ -0,0 +1,123 @@
+package external
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Patterns for systemctl status output
+var (
+	loadedPattern = regexp.MustCompile(`Loaded:\s+loaded\s+\([^;]+;\s*(enabled|disabled)`)
+	activePattern = regexp.MustCompile(`Active:\s+(active|inactive|failed)\s*\(([^)]+)\)`)
+	pidPattern    = regexp.MustCompile(`Main PID:\s+(\d+)`)
+)
+
+// SystemdSource is an external source run as a systemd unit.
+type SystemdSource struct {
+	name        string
+	displayName string
+	unit        string
+	binaries    []string // Any of these existing means the daemon is installed
+}
+
+// NewSystemdSource creates a source controlled with systemctl.
+func NewSystemdSource(name, displayName, unit string, binaries ...string) *SystemdSource {
+	return &SystemdSource{
+		name:        name,
+		displayName: displayName,
+		unit:        unit,
+		binaries:    binaries,
+	}
+}
+
+// NewRoonBridge creates the Roon Bridge source.
+func NewRoonBridge() *SystemdSource {
+	return NewSystemdSource("roonbridge", "Roon Bridge", "roonbridge", "/opt/RoonBridge/start.sh")
+}
+
+// NewSqueezelite creates the squeezelite (Logitech Media Server player) source.
+func NewSqueezelite() *SystemdSource {
+	return NewSystemdSource("squeezelite", "Squeezelite", "squeezelite", "/usr/bin/squeezelite", "/usr/local/bin/squeezelite")
+}
+
+// Name returns the source identifier.
+func (s *SystemdSource) Name() string {
+	return s.name
+}
+
+// Status returns the installation and service state.
+func (s *SystemdSource) Status() SourceStatus {
+	return SourceStatus{
+		Name:        s.name,
+		DisplayName: s.displayName,
+		Installed:   AnyExists(s.binaries...),
+		Service:     SystemctlStatus(s.unit),
+	}
+}
+
+// Start starts the unit.
+func (s *SystemdSource) Start() error {
+	return exec.Command("sudo", "systemctl", "start", s.unit).Run()
+}
+
+// Stop stops the unit.
+func (s *SystemdSource) Stop() error {
+	return exec.Command("sudo", "systemctl", "stop", s.unit).Run()
+}
+
+// AnyExists reports whether any of the paths exists.
+func AnyExists(paths ...string) bool {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SystemctlStatus returns the status of a systemd unit.
+func SystemctlStatus(unit string) ServiceStatus {
+	out, err := exec.Command("systemctl", "status", unit, "--no-pager").CombinedOutput()
+	if err != nil {
+		// Command may fail with exit code 3 for inactive services, but output is still valid
+		log.Debug().Err(err).Str("unit", unit).Msg("systemctl status returned non-zero exit code")
+	}
+	return ParseSystemctlStatus(string(out))
+}
+
+// ParseSystemctlStatus parses the output of systemctl status <unit>.
+func ParseSystemctlStatus(output string) ServiceStatus {
+	result := ServiceStatus{}
+
+	if output == "" || strings.Contains(output, "could not be found") {
+		return result
+	}
+
+	// Loaded: loaded (/etc/systemd/system/audirvanaStudio.service; enabled; preset: enabled)
+	if m := loadedPattern.FindStringSubmatch(output); m != nil {
+		result.Loaded = true
+		result.Enabled = m[1] == "enabled"
+	}
+
+	// Active: active (running) since ...
+	// Active: inactive (dead)
+	// Active: failed (Result: exit-code)
+	if m := activePattern.FindStringSubmatch(output); m != nil {
+		result.Active = m[1] == "active"
+		result.Running = m[1] == "active" && m[2] == "running"
+	}
+
+	// Main PID: 6448 (audirvanaStudio)
+	if result.Running {
+		if m := pidPattern.FindStringSubmatch(output); m != nil {
+			result.PID, _ = strconv.Atoi(m[1])
+		}
+	}
+
+	return result
+}
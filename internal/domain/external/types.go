@@ -0,0 +1,62 @@
+// Package external manages alternate audio daemons such as Audirvana, Roon
+// Bridge, shairport-sync and squeezelite through a common interface.
+package external
+
+import "errors"
+
+// Control actions accepted by Registry.Control.
+const (
+	ActionStart = "start"
+	ActionStop  = "stop"
+)
+
+// Registry errors
+var (
+	// ErrUnknownSource is returned for a source name that isn't registered
+	ErrUnknownSource = errors.New("unknown external source")
+
+	// ErrUnknownAction is returned for an action other than start or stop
+	ErrUnknownAction = errors.New("unknown action")
+)
+
+// ExternalSource is an audio daemon that plays independently of MPD.
+type ExternalSource interface {
+	// Name returns the stable identifier used by clients, e.g. "roonbridge"
+	Name() string
+
+	// Status reports installation and service state
+	Status() SourceStatus
+
+	// Start starts the daemon
+	Start() error
+
+	// Stop stops the daemon
+	Stop() error
+}
+
+// ServiceStatus represents the systemd service status of a daemon.
+type ServiceStatus struct {
+	Loaded  bool `json:"loaded"`
+	Enabled bool `json:"enabled"`
+	Active  bool `json:"active"`
+	Running bool `json:"running"`
+	PID     int  `json:"pid,omitempty"`
+}
+
+// SourceStatus is the status of one external source.
+type SourceStatus struct {
+	Name        string        `json:"name"`
+	DisplayName string        `json:"displayName"`
+	Installed   bool          `json:"installed"`
+	Service     ServiceStatus `json:"service"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// ControlResponse is the response to controlExternalSource.
+type ControlResponse struct {
+	Name    string       `json:"name"`
+	Action  string       `json:"action"`
+	Success bool         `json:"success"`
+	Status  SourceStatus `json:"status"`
+	Error   string       `json:"error,omitempty"`
+}
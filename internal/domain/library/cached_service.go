@@ -29,7 +29,11 @@ func NewCachedService(mpd MPDClient, classifier PathClassifier, cacheDB *cache.D
 
 	// Create cache DAO and builder
 	dao := cache.NewDAO(cacheDB)
-	builder := cache.NewBuilder(cacheDB, &mpdDataProviderAdapter{mpd: mpd}, nil)
+	var cacheClassifier cache.PathClassifier
+	if classifier != nil {
+		cacheClassifier = &pathClassifierAdapter{classifier: classifier}
+	}
+	builder := cache.NewBuilder(cacheDB, &mpdDataProviderAdapter{mpd: mpd}, cacheClassifier)
 
 	return &CachedService{
 		Service:      baseService,
@@ -100,14 +104,16 @@ func (s *CachedService) GetAlbums(req GetAlbumsRequest) AlbumsResponse {
 		}
 
 		albums = append(albums, Album{
-			ID:         ca.ID,
-			Title:      ca.Title,
-			Artist:     ca.AlbumArtist, // Use AlbumArtist from cache
-			URI:        ca.URI,
-			TrackCount: ca.TrackCount,
-			Source:     SourceType(ca.Source),
-			Year:       ca.Year,
-			AlbumArt:   albumArt,
+			ID:            ca.ID,
+			Title:         ca.Title,
+			Artist:        ca.AlbumArtist, // Use AlbumArtist from cache
+			URI:           ca.URI,
+			TrackCount:    ca.TrackCount,
+			Source:        SourceType(ca.Source),
+			Year:          ca.Year,
+			AlbumArt:      albumArt,
+			AddedAt:       ca.AddedAt,
+			IsCompilation: strings.EqualFold(ca.AlbumArtist, cache.VariousArtists),
 		})
 	}
 
@@ -129,6 +135,12 @@ func (s *CachedService) GetAlbums(req GetAlbumsRequest) AlbumsResponse {
 	}
 }
 
+// GetRecentlyAdded returns the newest albums across every source, from the
+// cache when it is populated.
+func (s *CachedService) GetRecentlyAdded(req GetRecentlyAddedRequest) RecentlyAddedResponse {
+	return recentlyAdded(s.GetAlbums, req)
+}
+
 // GetArtists returns artists, checking cache first.
 func (s *CachedService) GetArtists(req GetArtistsRequest) ArtistsResponse {
 	if !s.cacheEnabled || s.cacheDB == nil {
@@ -245,7 +257,144 @@ func (s *CachedService) GetRadioStations(req GetRadioRequest) RadioResponse {
 	}
 }
 
-// RebuildCache triggers a full cache rebuild.
+// GetAlbumsByYear returns albums released in a year or decade from the cache.
+func (s *CachedService) GetAlbumsByYear(req GetAlbumsByYearRequest) AlbumsByYearResponse {
+	resp := AlbumsByYearResponse{
+		Year:   req.Year,
+		Decade: req.Decade,
+		Albums: []Album{},
+	}
+
+	if !s.cacheEnabled || s.cacheDAO == nil {
+		resp.Error = "library cache not available"
+		return resp
+	}
+
+	var cachedAlbums []*cache.CachedAlbum
+	var err error
+	switch {
+	case req.Decade > 0:
+		cachedAlbums, err = s.cacheDAO.FindAlbumsByDecade(req.Decade)
+	case req.Year > 0:
+		cachedAlbums, err = s.cacheDAO.FindAlbumsByYear(req.Year)
+	default:
+		resp.Error = "year or decade is required"
+		return resp
+	}
+	if err != nil {
+		log.Warn().Err(err).Int("year", req.Year).Int("decade", req.Decade).Msg("Year query failed")
+		resp.Error = err.Error()
+		return resp
+	}
+
+	for _, ca := range cachedAlbums {
+		albumArt := ""
+		if ca.FirstTrack != "" {
+			albumArt = "/albumart?path=" + ca.FirstTrack
+		}
+
+		resp.Albums = append(resp.Albums, Album{
+			ID:            ca.ID,
+			Title:         ca.Title,
+			Artist:        ca.AlbumArtist,
+			URI:           ca.URI,
+			TrackCount:    ca.TrackCount,
+			Source:        SourceType(ca.Source),
+			Year:          ca.Year,
+			AlbumArt:      albumArt,
+			IsCompilation: strings.EqualFold(ca.AlbumArtist, cache.VariousArtists),
+		})
+	}
+
+	return resp
+}
+
+// GetDecades returns the decades that have albums in the cache, oldest first.
+func (s *CachedService) GetDecades() DecadesResponse {
+	resp := DecadesResponse{Decades: []Decade{}}
+
+	if !s.cacheEnabled || s.cacheDAO == nil {
+		resp.Error = "library cache not available"
+		return resp
+	}
+
+	decades, err := s.cacheDAO.ListDecades()
+	if err != nil {
+		log.Warn().Err(err).Msg("Decade query failed")
+		resp.Error = err.Error()
+		return resp
+	}
+
+	for _, dc := range decades {
+		resp.Decades = append(resp.Decades, Decade{Decade: dc.Decade, Count: dc.Count})
+	}
+	return resp
+}
+
+// GetArtistIndex returns the leading letters of cached artist names, with
+// how many artists start with each and the list offset of the first one.
+func (s *CachedService) GetArtistIndex(req GetArtistIndexRequest) ArtistIndexResponse {
+	resp := ArtistIndexResponse{Letters: []ArtistLetter{}}
+
+	if !s.cacheEnabled || s.cacheDAO == nil {
+		resp.Error = "library cache not available"
+		return resp
+	}
+
+	letters, err := s.cacheDAO.ListArtistIndex(req.Query)
+	if err != nil {
+		log.Warn().Err(err).Msg("Artist index query failed")
+		resp.Error = err.Error()
+		return resp
+	}
+
+	for _, lc := range letters {
+		resp.Letters = append(resp.Letters, ArtistLetter{Letter: lc.Letter, Count: lc.Count, Offset: lc.Offset})
+		resp.Total += lc.Count
+	}
+	return resp
+}
+
+// GetSourceStats returns how many cached albums and tracks come from each
+// source, and when each was last scanned.
+func (s *CachedService) GetSourceStats() SourceStatsResponse {
+	resp := SourceStatsResponse{Sources: []SourceStats{}}
+
+	if !s.cacheEnabled || s.cacheDAO == nil {
+		resp.Error = "library cache not available"
+		return resp
+	}
+
+	counts, err := s.cacheDAO.ListSourceCounts()
+	if err != nil {
+		log.Warn().Err(err).Msg("Source stats query failed")
+		resp.Error = err.Error()
+		return resp
+	}
+
+	bySource := make(map[string]cache.SourceCount, len(counts))
+	for _, sc := range counts {
+		bySource[sc.Source] = sc
+	}
+	for _, source := range []SourceType{SourceLocal, SourceUSB, SourceNAS} {
+		sc := bySource[string(source)]
+		resp.Sources = append(resp.Sources, SourceStats{
+			Source:      source,
+			AlbumCount:  sc.AlbumCount,
+			TrackCount:  sc.TrackCount,
+			LastScanned: sc.LastScanned,
+		})
+	}
+	// Totals include any album whose source is none of the above
+	for _, sc := range counts {
+		resp.TotalAlbums += sc.AlbumCount
+		resp.TotalTracks += sc.TrackCount
+	}
+	return resp
+}
+
+// RebuildCache wipes the cache and rebuilds it from MPD. It returns
+// cache.ErrBuildInProgress if a rebuild is already running.
 func (s *CachedService) RebuildCache() error {
 	if !s.cacheEnabled || s.cacheBuilder == nil {
 		return nil
@@ -255,6 +404,18 @@ func (s *CachedService) RebuildCache() error {
 	return s.cacheBuilder.FullBuild()
 }
 
+// SetBuildProgressFunc sets a function called as cache rebuilds progress.
+func (s *CachedService) SetBuildProgressFunc(fn func(cache.BuildProgress)) {
+	if s.cacheBuilder != nil {
+		s.cacheBuilder.SetProgressFunc(fn)
+	}
+}
+
+// IsRebuilding reports whether a cache rebuild is running.
+func (s *CachedService) IsRebuilding() bool {
+	return s.cacheBuilder != nil && s.cacheBuilder.IsBuilding()
+}
+
 // GetCacheStatus returns cache statistics.
 func (s *CachedService) GetCacheStatus() (*cache.CacheStats, error) {
 	if !s.cacheEnabled || s.cacheDB == nil {
@@ -268,6 +429,16 @@ func (s *CachedService) IsCacheEnabled() bool {
 	return s.cacheEnabled
 }
 
+// pathClassifierAdapter adapts the library PathClassifier to cache.PathClassifier,
+// so cached albums and tracks are classified the same way as uncached browsing.
+type pathClassifierAdapter struct {
+	classifier PathClassifier
+}
+
+func (a *pathClassifierAdapter) GetSourceType(uri string) string {
+	return string(a.classifier.GetSourceType(uri))
+}
+
 // mpdDataProviderAdapter adapts the library MPDClient to cache.MPDDataProvider.
 type mpdDataProviderAdapter struct {
 	mpd MPDClient
@@ -287,6 +458,8 @@ func (a *mpdDataProviderAdapter) GetAlbumDetails(basePath string) ([]cache.Album
 			TrackCount:  d.TrackCount,
 			FirstTrack:  d.FirstTrack,
 			TotalTime:   d.TotalTime,
+			Year:        d.Year,
+			AddedAt:     d.AddedAt,
 		})
 	}
 	return result, nil
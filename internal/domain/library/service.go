@@ -7,8 +7,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
 )
 
 // AlbumInfo matches the mpd.AlbumInfo type.
@@ -19,11 +22,16 @@ type AlbumInfo struct {
 
 // AlbumDetails matches the mpd.AlbumDetails type.
 type AlbumDetails struct {
-	Album       string
-	AlbumArtist string
-	TrackCount  int
-	FirstTrack  string
-	TotalTime   int
+	Album          string
+	AlbumArtist    string
+	ArtistInferred bool     // AlbumArtist was derived from the track artists
+	TrackArtists   []string // Distinct track artists
+	TrackCount     int
+	FirstTrack     string
+	TotalTime      int
+	Year           int
+	AddedAt        time.Time // When the newest track was added (zero if unknown)
+	IsCompilation  bool
 }
 
 // MPDClient interface for MPD operations needed by this service.
@@ -37,8 +45,16 @@ type MPDClient interface {
 	ListArtists() ([]string, error)
 	FindAlbumsByArtist(artist string) ([]AlbumInfo, error)
 
+	// Genre queries
+	ListGenres() ([]string, error)
+	FindAlbumsByGenre(genre string) ([]AlbumInfo, error)
+
 	// Track queries
 	FindAlbumTracks(album, albumArtist string) ([]map[string]string, error)
+	Search(terms ...string) ([]map[string]string, error) // Tag and value pairs, all of which must match
+
+	// Directory listing
+	ListInfo(uri string) ([]map[string]string, error)
 
 	// Playlist/radio queries
 	ListPlaylists() ([]string, error)
@@ -115,6 +131,37 @@ func (s *Service) GetAlbums(req GetAlbumsRequest) AlbumsResponse {
 	}
 }
 
+// GetRecentlyAdded returns the newest albums across every source, each with
+// the date it was added. Dates come from MPD's "added" tag where the server
+// has it and the file modification time otherwise.
+func (s *Service) GetRecentlyAdded(req GetRecentlyAddedRequest) RecentlyAddedResponse {
+	return recentlyAdded(s.GetAlbums, req)
+}
+
+// recentlyAdded lists the newest albums with getAlbums.
+func recentlyAdded(getAlbums func(GetAlbumsRequest) AlbumsResponse, req GetRecentlyAddedRequest) RecentlyAddedResponse {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultRecentlyAddedLimit
+	}
+
+	resp := getAlbums(GetAlbumsRequest{
+		Scope: ScopeAll,
+		Sort:  SortRecentlyAdded,
+		Page:  1,
+		Limit: limit,
+	})
+
+	// Albums with no known date sort last; they don't belong on the shelf
+	albums := make([]Album, 0, len(resp.Albums))
+	for _, album := range resp.Albums {
+		if !album.AddedAt.IsZero() {
+			albums = append(albums, album)
+		}
+	}
+	return RecentlyAddedResponse{Albums: albums}
+}
+
 // getBasePathsForScope returns the MPD base paths to query for a given scope.
 func (s *Service) getBasePathsForScope(scope Scope) []string {
 	switch scope {
@@ -182,13 +229,18 @@ func (s *Service) getAlbumsFromBasePath(basePath string, sourceType SourceType,
 		}
 
 		album := Album{
-			ID:         albumID,
-			Title:      details.Album,
-			Artist:     details.AlbumArtist,
-			URI:        uri,
-			AlbumArt:   albumArt,
-			TrackCount: details.TrackCount,
-			Source:     sourceType,
+			ID:             albumID,
+			Title:          details.Album,
+			Artist:         details.AlbumArtist,
+			ArtistInferred: details.ArtistInferred,
+			TrackArtists:   details.TrackArtists,
+			URI:            uri,
+			AlbumArt:       albumArt,
+			TrackCount:     details.TrackCount,
+			Source:         sourceType,
+			Year:           details.Year,
+			AddedAt:        details.AddedAt,
+			IsCompilation:  details.IsCompilation,
 		}
 
 		albums = append(albums, album)
@@ -377,6 +429,138 @@ func (s *Service) GetArtistAlbums(req GetArtistAlbumsRequest) ArtistAlbumsRespon
 	}
 }
 
+// GetGenres returns all genres with their album counts.
+func (s *Service) GetGenres(req GetGenresRequest) GenresResponse {
+	var genres []Genre
+
+	genreNames, err := s.mpd.ListGenres()
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to list genres")
+		return GenresResponse{
+			Genres:     []Genre{},
+			Pagination: Pagination{Page: 1, Limit: DefaultLimit},
+		}
+	}
+
+	queryLower := strings.ToLower(req.Query)
+
+	for _, name := range genreNames {
+		// Skip empty genre names
+		if name == "" {
+			continue
+		}
+
+		// Apply query filter if provided
+		if req.Query != "" && !strings.Contains(strings.ToLower(name), queryLower) {
+			continue
+		}
+
+		// Get album count for this genre
+		albumCount := 0
+		if albumInfos, err := s.mpd.FindAlbumsByGenre(name); err == nil {
+			albumCount = len(albumInfos)
+		}
+
+		genres = append(genres, Genre{
+			Name:       name,
+			AlbumCount: albumCount,
+		})
+	}
+
+	// Sort genres alphabetically
+	sort.Slice(genres, func(i, j int) bool {
+		return strings.ToLower(genres[i].Name) < strings.ToLower(genres[j].Name)
+	})
+
+	// Apply pagination
+	total := len(genres)
+	page := req.Page
+	limit := req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+
+	start := (page - 1) * limit
+	end := start + limit
+	if start > len(genres) {
+		start = len(genres)
+	}
+	if end > len(genres) {
+		end = len(genres)
+	}
+
+	return GenresResponse{
+		Genres: genres[start:end],
+		Pagination: Pagination{
+			Page:    page,
+			Limit:   limit,
+			Total:   total,
+			HasMore: end < total,
+		},
+	}
+}
+
+// GetGenreAlbums returns albums tagged with a specific genre.
+func (s *Service) GetGenreAlbums(req GetGenreAlbumsRequest) GenreAlbumsResponse {
+	var albums []Album
+
+	albumInfos, err := s.mpd.FindAlbumsByGenre(req.Genre)
+	if err != nil {
+		log.Debug().Err(err).Str("genre", req.Genre).Msg("Failed to find albums by genre")
+		return GenreAlbumsResponse{
+			Genre:      req.Genre,
+			Albums:     []Album{},
+			Pagination: Pagination{Page: 1, Limit: DefaultLimit},
+		}
+	}
+
+	for _, info := range albumInfos {
+		albums = append(albums, Album{
+			ID:     generateID(info.Album + "\x00" + info.AlbumArtist),
+			Title:  info.Album,
+			Artist: info.AlbumArtist,
+			Source: SourceLocal, // Default, would need track info to determine
+		})
+	}
+
+	// Sort albums
+	s.sortAlbums(albums, req.Sort)
+
+	// Apply pagination
+	total := len(albums)
+	page := req.Page
+	limit := req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+
+	start := (page - 1) * limit
+	end := start + limit
+	if start > len(albums) {
+		start = len(albums)
+	}
+	if end > len(albums) {
+		end = len(albums)
+	}
+
+	return GenreAlbumsResponse{
+		Genre:  req.Genre,
+		Albums: albums[start:end],
+		Pagination: Pagination{
+			Page:    page,
+			Limit:   limit,
+			Total:   total,
+			HasMore: end < total,
+		},
+	}
+}
+
 // GetAlbumTracks returns tracks for a specific album.
 func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse {
 	if req.Album == "" {
@@ -398,79 +582,241 @@ func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse
 	var resultTracks []Track
 	totalDuration := 0
 
-	for _, track := range tracks {
-		file := track["file"]
-		if file == "" {
+	for _, song := range tracks {
+		track, ok := s.trackFromSong(song)
+		if !ok {
 			continue
 		}
+		totalDuration += track.Duration
+		resultTracks = append(resultTracks, track)
+	}
 
-		// Parse track number
-		trackNum := 0
-		if tn := track["Track"]; tn != "" {
-			// Track can be "1" or "1/12"
-			if idx := strings.Index(tn, "/"); idx > 0 {
-				tn = tn[:idx]
-			}
-			if n, err := strconv.Atoi(tn); err == nil {
-				trackNum = n
-			}
+	// Sort by disc, then track number
+	sort.Slice(resultTracks, func(i, j int) bool {
+		if resultTracks[i].DiscNumber != resultTracks[j].DiscNumber {
+			return resultTracks[i].DiscNumber < resultTracks[j].DiscNumber
 		}
+		if resultTracks[i].TrackNumber != resultTracks[j].TrackNumber {
+			return resultTracks[i].TrackNumber < resultTracks[j].TrackNumber
+		}
+		return resultTracks[i].Title < resultTracks[j].Title
+	})
 
-		// Parse duration
-		duration := 0
-		if d := track["Time"]; d != "" {
-			if n, err := strconv.Atoi(d); err == nil {
-				duration = n
-			}
-		} else if d := track["duration"]; d != "" {
-			if f, err := strconv.ParseFloat(d, 64); err == nil {
-				duration = int(f)
-			}
+	return AlbumTracksResponse{
+		Album:         req.Album,
+		AlbumArtist:   req.AlbumArtist,
+		Tracks:        resultTracks,
+		TotalDuration: totalDuration,
+	}
+}
+
+// trackFromSong converts an MPD song to a track. It returns false for
+// entries without a file.
+func (s *Service) trackFromSong(song map[string]string) (Track, bool) {
+	file := song["file"]
+	if file == "" {
+		return Track{}, false
+	}
+
+	// Parse duration
+	duration := 0
+	if d := song["Time"]; d != "" {
+		if n, err := strconv.Atoi(d); err == nil {
+			duration = n
 		}
+	} else if d := song["duration"]; d != "" {
+		if f, err := strconv.ParseFloat(d, 64); err == nil {
+			duration = int(f)
+		}
+	}
 
-		totalDuration += duration
+	// Get title, fallback to filename
+	title := song["Title"]
+	if title == "" {
+		title = path.Base(file)
+		if ext := path.Ext(title); ext != "" {
+			title = title[:len(title)-len(ext)]
+		}
+	}
 
-		// Get title, fallback to filename
-		title := track["Title"]
-		if title == "" {
-			title = path.Base(file)
-			if ext := path.Ext(title); ext != "" {
-				title = title[:len(title)-len(ext)]
-			}
+	return Track{
+		ID:          generateID(file),
+		Title:       title,
+		Artist:      song["Artist"],
+		Album:       song["Album"],
+		URI:         file,
+		TrackNumber: cache.ParseNumberTag(song["Track"]),
+		DiscNumber:  cache.DiscNumber(song),
+		Duration:    duration,
+		AlbumArt:    "/albumart?path=" + file,
+		Source:      s.classifier.GetSourceType(file),
+	}, true
+}
+
+// SearchAdvanced finds the tracks matching every field of the request, and
+// the albums they belong to. Tracks are returned in MPD's database order,
+// albums alphabetically.
+func (s *Service) SearchAdvanced(req SearchAdvancedRequest) SearchAdvancedResponse {
+	resp := SearchAdvancedResponse{Albums: []Album{}, Tracks: []Track{}}
+
+	var terms []string
+	for _, field := range []struct{ tag, value string }{
+		{"artist", req.Artist},
+		{"album", req.Album},
+		{"title", req.Title},
+		{"genre", req.Genre},
+	} {
+		if value := strings.TrimSpace(field.value); value != "" {
+			terms = append(terms, field.tag, value)
 		}
+	}
+	if len(terms) == 0 {
+		resp.Error = "at least one search field is required"
+		return resp
+	}
 
-		// Determine source type from file path
-		sourceType := s.classifier.GetSourceType(file)
+	songs, err := s.mpd.Search(terms...)
+	if err != nil {
+		log.Debug().Err(err).Strs("terms", terms).Msg("Advanced search failed")
+		resp.Error = "search failed: " + err.Error()
+		return resp
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
 
-		resultTrack := Track{
-			ID:          generateID(file),
-			Title:       title,
-			Artist:      track["Artist"],
-			Album:       track["Album"],
+	albums := make(map[string]*Album)
+	for _, song := range songs {
+		track, ok := s.trackFromSong(song)
+		if !ok {
+			continue
+		}
+		resp.TotalTracks++
+		if len(resp.Tracks) < limit {
+			resp.Tracks = append(resp.Tracks, track)
+		}
+
+		if track.Album == "" {
+			continue
+		}
+		albumArtist := song["AlbumArtist"]
+		if albumArtist == "" {
+			albumArtist = track.Artist
+		}
+		key := track.Album + "\x00" + albumArtist
+		if album, exists := albums[key]; exists {
+			album.TrackCount++
+			continue
+		}
+		albums[key] = &Album{
+			ID:            generateID(key),
+			Title:         track.Album,
+			Artist:        albumArtist,
+			URI:           path.Dir(track.URI),
+			AlbumArt:      track.AlbumArt,
+			TrackCount:    1,
+			Source:        track.Source,
+			IsCompilation: strings.EqualFold(albumArtist, cache.VariousArtists),
+		}
+	}
+
+	for _, album := range albums {
+		resp.Albums = append(resp.Albums, *album)
+	}
+	s.sortAlbums(resp.Albums, SortAlphabetical)
+
+	log.Info().
+		Strs("terms", terms).
+		Int("albumCount", len(resp.Albums)).
+		Int("trackCount", resp.TotalTracks).
+		Msg("SearchAdvanced completed")
+
+	return resp
+}
+
+// BrowseFolder lists the directories and songs in a folder of the MPD music
+// directory. An empty URI lists the root.
+func (s *Service) BrowseFolder(req BrowseFolderRequest) FolderResponse {
+	uri := strings.Trim(req.URI, "/")
+
+	resp := FolderResponse{
+		URI:     uri,
+		Entries: []FolderEntry{},
+	}
+	if uri != "" {
+		resp.Parent = path.Dir(uri)
+		if resp.Parent == "." {
+			resp.Parent = ""
+		}
+	}
+
+	entries, err := s.mpd.ListInfo(uri)
+	if err != nil {
+		log.Debug().Err(err).Str("uri", uri).Msg("Failed to list folder")
+		resp.Error = "failed to browse folder: " + err.Error()
+		return resp
+	}
+
+	var dirs, files []FolderEntry
+	for _, entry := range entries {
+		if dir, ok := entry["directory"]; ok {
+			dirs = append(dirs, FolderEntry{
+				Type: FolderEntryDirectory,
+				Name: path.Base(dir),
+				URI:  dir,
+			})
+			continue
+		}
+
+		file, ok := entry["file"]
+		if !ok {
+			continue
+		}
+
+		duration := 0
+		if d := entry["Time"]; d != "" {
+			duration, _ = strconv.Atoi(d)
+		} else if d := entry["duration"]; d != "" {
+			if f, err := strconv.ParseFloat(d, 64); err == nil {
+				duration = int(f)
+			}
+		}
+
+		files = append(files, FolderEntry{
+			Type:        FolderEntryFile,
+			Name:        path.Base(file),
 			URI:         file,
-			TrackNumber: trackNum,
+			Playable:    true,
+			Title:       entry["Title"],
+			Artist:      entry["Artist"],
+			Album:       entry["Album"],
+			TrackNumber: cache.ParseNumberTag(entry["Track"]),
+			DiscNumber:  cache.DiscNumber(entry),
 			Duration:    duration,
 			AlbumArt:    "/albumart?path=" + file,
-			Source:      sourceType,
-		}
-
-		resultTracks = append(resultTracks, resultTrack)
+			Source:      s.classifier.GetSourceType(file),
+		})
 	}
 
-	// Sort by track number
-	sort.Slice(resultTracks, func(i, j int) bool {
-		if resultTracks[i].TrackNumber != resultTracks[j].TrackNumber {
-			return resultTracks[i].TrackNumber < resultTracks[j].TrackNumber
+	// Directories first by name, then files in disc/track order
+	sort.Slice(dirs, func(i, j int) bool {
+		return strings.ToLower(dirs[i].Name) < strings.ToLower(dirs[j].Name)
+	})
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].DiscNumber != files[j].DiscNumber {
+			return files[i].DiscNumber < files[j].DiscNumber
 		}
-		return resultTracks[i].Title < resultTracks[j].Title
+		if files[i].TrackNumber != files[j].TrackNumber {
+			return files[i].TrackNumber < files[j].TrackNumber
+		}
+		return strings.ToLower(files[i].Name) < strings.ToLower(files[j].Name)
 	})
 
-	return AlbumTracksResponse{
-		Album:         req.Album,
-		AlbumArtist:   req.AlbumArtist,
-		Tracks:        resultTracks,
-		TotalDuration: totalDuration,
-	}
+	resp.Entries = append(resp.Entries, dirs...)
+	resp.Entries = append(resp.Entries, files...)
+	return resp
 }
 
 // GetRadioStations returns radio stations from MPD playlists.
@@ -2,18 +2,22 @@ package library
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
 )
 
 // MockMPDClient implements the MPDClient interface for testing.
 type MockMPDClient struct {
 	// Album queries
-	ListAlbumsResponse      []AlbumInfo
-	ListAlbumsError         error
-	ListAlbumsInBaseResp    map[string][]AlbumInfo
-	ListAlbumsInBaseError   error
-	GetAlbumDetailsResp     map[string][]AlbumDetails
-	GetAlbumDetailsError    error
+	ListAlbumsResponse    []AlbumInfo
+	ListAlbumsError       error
+	ListAlbumsInBaseResp  map[string][]AlbumInfo
+	ListAlbumsInBaseError error
+	GetAlbumDetailsResp   map[string][]AlbumDetails
+	GetAlbumDetailsError  error
 
 	// Artist queries
 	ListArtistsResponse     []string
@@ -21,15 +25,28 @@ type MockMPDClient struct {
 	FindAlbumsByArtistResp  map[string][]AlbumInfo
 	FindAlbumsByArtistError error
 
+	// Genre queries
+	ListGenresResponse     []string
+	ListGenresError        error
+	FindAlbumsByGenreResp  map[string][]AlbumInfo
+	FindAlbumsByGenreError error
+
 	// Track queries
-	FindAlbumTracksResp     map[string][]map[string]string
-	FindAlbumTracksError    error
+	FindAlbumTracksResp  map[string][]map[string]string
+	FindAlbumTracksError error
+	SearchResp           []map[string]string
+	SearchError          error
+	SearchTerms          []string // Terms of the last Search call
+
+	// Directory listing
+	ListInfoResp  map[string][]map[string]string
+	ListInfoError error
 
 	// Playlist/radio queries
-	ListPlaylistsResponse   []string
-	ListPlaylistsError      error
-	ListPlaylistInfoResp    map[string][]map[string]string
-	ListPlaylistInfoError   error
+	ListPlaylistsResponse []string
+	ListPlaylistsError    error
+	ListPlaylistInfoResp  map[string][]map[string]string
+	ListPlaylistInfoError error
 }
 
 func (m *MockMPDClient) ListAlbums() ([]AlbumInfo, error) {
@@ -76,6 +93,23 @@ func (m *MockMPDClient) FindAlbumsByArtist(artist string) ([]AlbumInfo, error) {
 	return []AlbumInfo{}, nil
 }
 
+func (m *MockMPDClient) ListGenres() ([]string, error) {
+	if m.ListGenresError != nil {
+		return nil, m.ListGenresError
+	}
+	return m.ListGenresResponse, nil
+}
+
+func (m *MockMPDClient) FindAlbumsByGenre(genre string) ([]AlbumInfo, error) {
+	if m.FindAlbumsByGenreError != nil {
+		return nil, m.FindAlbumsByGenreError
+	}
+	if resp, ok := m.FindAlbumsByGenreResp[genre]; ok {
+		return resp, nil
+	}
+	return []AlbumInfo{}, nil
+}
+
 func (m *MockMPDClient) FindAlbumTracks(album, albumArtist string) ([]map[string]string, error) {
 	if m.FindAlbumTracksError != nil {
 		return nil, m.FindAlbumTracksError
@@ -87,6 +121,24 @@ func (m *MockMPDClient) FindAlbumTracks(album, albumArtist string) ([]map[string
 	return []map[string]string{}, nil
 }
 
+func (m *MockMPDClient) Search(terms ...string) ([]map[string]string, error) {
+	m.SearchTerms = terms
+	if m.SearchError != nil {
+		return nil, m.SearchError
+	}
+	return m.SearchResp, nil
+}
+
+func (m *MockMPDClient) ListInfo(uri string) ([]map[string]string, error) {
+	if m.ListInfoError != nil {
+		return nil, m.ListInfoError
+	}
+	if resp, ok := m.ListInfoResp[uri]; ok {
+		return resp, nil
+	}
+	return []map[string]string{}, nil
+}
+
 func (m *MockMPDClient) ListPlaylists() ([]string, error) {
 	if m.ListPlaylistsError != nil {
 		return nil, m.ListPlaylistsError
@@ -624,6 +676,142 @@ func TestService_GetAlbumTracks_WithTracks(t *testing.T) {
 	}
 }
 
+func TestService_GetAlbumTracks_MultiDisc(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		FindAlbumTracksResp: map[string][]map[string]string{
+			"Double Album\x00Artist": {
+				{"file": "INTERNAL/Double/2-01.flac", "Title": "Disc Two Opener", "Track": "1", "Disc": "2/2"},
+				{"file": "INTERNAL/Double/1-12.flac", "Title": "Disc One Closer", "Track": "12", "Disc": "1/2"},
+				{"file": "INTERNAL/Double/1-01.flac", "Title": "Disc One Opener", "Track": "1", "Disc": "1/2"},
+			},
+		},
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.GetAlbumTracks(GetAlbumTracksRequest{
+		Album:       "Double Album",
+		AlbumArtist: "Artist",
+	})
+
+	if len(resp.Tracks) != 3 {
+		t.Fatalf("Expected 3 tracks, got %d", len(resp.Tracks))
+	}
+
+	// Disc 2 track 1 must sort after disc 1 track 12
+	wantTitles := []string{"Disc One Opener", "Disc One Closer", "Disc Two Opener"}
+	wantDiscs := []int{1, 1, 2}
+	for i := range wantTitles {
+		if resp.Tracks[i].Title != wantTitles[i] || resp.Tracks[i].DiscNumber != wantDiscs[i] {
+			t.Errorf("Tracks[%d] = %q (disc %d), want %q (disc %d)",
+				i, resp.Tracks[i].Title, resp.Tracks[i].DiscNumber, wantTitles[i], wantDiscs[i])
+		}
+	}
+}
+
+// --- SearchAdvanced Tests ---
+
+func TestService_SearchAdvanced_RequiresField(t *testing.T) {
+	mockMPD := &MockMPDClient{}
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.SearchAdvanced(SearchAdvancedRequest{Artist: "  "})
+
+	if resp.Error == "" {
+		t.Error("Expected error when no field is set")
+	}
+	if mockMPD.SearchTerms != nil {
+		t.Errorf("MPD searched with %q, want no search", mockMPD.SearchTerms)
+	}
+}
+
+func TestService_SearchAdvanced_AlbumsAndTracks(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		SearchResp: []map[string]string{
+			{"file": "NAS/Jazz/Kind of Blue/01.flac", "Title": "So What", "Artist": "Miles Davis", "Album": "Kind of Blue", "Track": "1", "Time": "545"},
+			{"file": "NAS/Jazz/Kind of Blue/02.flac", "Title": "Freddie Freeloader", "Artist": "Miles Davis", "Album": "Kind of Blue", "Track": "2"},
+			{"file": "INTERNAL/Jazz Hits/05.flac", "Title": "So What (Live)", "Artist": "Miles Davis", "AlbumArtist": "Various Artists", "Album": "Jazz Hits"},
+			{"file": "INTERNAL/Loose/untagged.flac", "Artist": "Miles Davis"},
+		},
+	}
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.SearchAdvanced(SearchAdvancedRequest{Artist: "Miles Davis", Title: "so what", Limit: 2})
+
+	if resp.Error != "" {
+		t.Fatalf("Unexpected error: %s", resp.Error)
+	}
+	wantTerms := []string{"artist", "Miles Davis", "title", "so what"}
+	if fmt.Sprint(mockMPD.SearchTerms) != fmt.Sprint(wantTerms) {
+		t.Errorf("Search terms = %q, want %q", mockMPD.SearchTerms, wantTerms)
+	}
+
+	if resp.TotalTracks != 4 || len(resp.Tracks) != 2 {
+		t.Errorf("Expected 2 of 4 tracks, got %d of %d", len(resp.Tracks), resp.TotalTracks)
+	}
+	if resp.Tracks[0].Title != "So What" || resp.Tracks[0].Duration != 545 {
+		t.Errorf("Unexpected first track: %+v", resp.Tracks[0])
+	}
+
+	if len(resp.Albums) != 2 {
+		t.Fatalf("Expected 2 albums, got %+v", resp.Albums)
+	}
+	hits, blue := resp.Albums[0], resp.Albums[1]
+	if blue.Title != "Kind of Blue" || blue.TrackCount != 2 || blue.Source != SourceNAS || blue.URI != "NAS/Jazz/Kind of Blue" {
+		t.Errorf("Unexpected album: %+v", blue)
+	}
+	if hits.Title != "Jazz Hits" || hits.Artist != cache.VariousArtists || !hits.IsCompilation {
+		t.Errorf("Expected 'Jazz Hits' compilation, got %+v", hits)
+	}
+}
+
+func TestService_SearchAdvanced_MPDError(t *testing.T) {
+	mockMPD := &MockMPDClient{SearchError: fmt.Errorf("connection refused")}
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.SearchAdvanced(SearchAdvancedRequest{Genre: "Jazz"})
+
+	if resp.Error == "" {
+		t.Error("Expected error when MPD search fails")
+	}
+	if resp.Albums == nil || resp.Tracks == nil {
+		t.Error("Expected empty, non-nil albums and tracks")
+	}
+}
+
+// --- GetRecentlyAdded Tests ---
+
+func TestService_GetRecentlyAdded(t *testing.T) {
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsResp: map[string][]AlbumDetails{
+			"INTERNAL": {
+				{Album: "Old", AlbumArtist: "A", FirstTrack: "INTERNAL/Old/01.flac", AddedAt: base},
+				{Album: "Undated", AlbumArtist: "B", FirstTrack: "INTERNAL/Undated/01.flac"},
+			},
+			"NAS": {
+				{Album: "Newest", AlbumArtist: "C", FirstTrack: "NAS/Newest/01.flac", AddedAt: base.Add(48 * time.Hour)},
+				{Album: "Middle", AlbumArtist: "D", FirstTrack: "NAS/Middle/01.flac", AddedAt: base.Add(24 * time.Hour)},
+			},
+		},
+	}
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.GetRecentlyAdded(GetRecentlyAddedRequest{Limit: 2})
+	if len(resp.Albums) != 2 || resp.Albums[0].Title != "Newest" || resp.Albums[1].Title != "Middle" {
+		t.Fatalf("Expected [Newest Middle], got %+v", resp.Albums)
+	}
+	if !resp.Albums[0].AddedAt.Equal(base.Add(48 * time.Hour)) {
+		t.Errorf("AddedAt = %v, want %v", resp.Albums[0].AddedAt, base.Add(48*time.Hour))
+	}
+
+	// Albums with no known date are left off the shelf
+	all := service.GetRecentlyAdded(GetRecentlyAddedRequest{})
+	if len(all.Albums) != 3 {
+		t.Errorf("Expected 3 dated albums, got %d", len(all.Albums))
+	}
+}
+
 // --- GetRadioStations Tests ---
 
 func TestService_GetRadioStations_Empty(t *testing.T) {
@@ -713,3 +901,350 @@ func TestService_GetArtists_MPDError(t *testing.T) {
 		t.Error("Artists should not be nil on error")
 	}
 }
+
+func TestService_GetGenres_WithGenres(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		ListGenresResponse: []string{"Rock", "", "Jazz", "ambient"},
+		FindAlbumsByGenreResp: map[string][]AlbumInfo{
+			"Jazz": {{Album: "Kind of Blue", AlbumArtist: "Miles Davis"}, {Album: "Blue Train", AlbumArtist: "John Coltrane"}},
+			"Rock": {{Album: "Album 1", AlbumArtist: "Band"}},
+		},
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.GetGenres(GetGenresRequest{})
+
+	if len(resp.Genres) != 3 {
+		t.Fatalf("Expected 3 genres, got %d", len(resp.Genres))
+	}
+
+	// Sorted case-insensitively, empty names skipped
+	want := []Genre{{"ambient", 0}, {"Jazz", 2}, {"Rock", 1}}
+	for i, genre := range resp.Genres {
+		if genre != want[i] {
+			t.Errorf("Genres[%d] = %+v, want %+v", i, genre, want[i])
+		}
+	}
+	if resp.Pagination.Total != 3 {
+		t.Errorf("Expected total 3, got %d", resp.Pagination.Total)
+	}
+}
+
+func TestService_GetGenres_WithQuery(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		ListGenresResponse: []string{"Jazz", "Acid Jazz", "Rock"},
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.GetGenres(GetGenresRequest{Query: "jazz"})
+
+	if len(resp.Genres) != 2 {
+		t.Errorf("Expected 2 genres matching 'jazz', got %d", len(resp.Genres))
+	}
+}
+
+func TestService_GetGenres_Error(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		ListGenresError: fmt.Errorf("connection refused"),
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.GetGenres(GetGenresRequest{})
+
+	if resp.Genres == nil || len(resp.Genres) != 0 {
+		t.Errorf("Expected empty genre list on error, got %v", resp.Genres)
+	}
+}
+
+func TestService_GetGenreAlbums(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		FindAlbumsByGenreResp: map[string][]AlbumInfo{
+			"Jazz": {
+				{Album: "Kind of Blue", AlbumArtist: "Miles Davis"},
+				{Album: "Blue Train", AlbumArtist: "John Coltrane"},
+			},
+		},
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.GetGenreAlbums(GetGenreAlbumsRequest{
+		Genre: "Jazz",
+		Sort:  SortAlphabetical,
+	})
+
+	if resp.Genre != "Jazz" {
+		t.Errorf("Expected genre 'Jazz', got %q", resp.Genre)
+	}
+	if len(resp.Albums) != 2 {
+		t.Fatalf("Expected 2 albums, got %d", len(resp.Albums))
+	}
+	if resp.Albums[0].Title != "Blue Train" || resp.Albums[0].Artist != "John Coltrane" {
+		t.Errorf("Expected first album 'Blue Train' by John Coltrane, got %q by %q", resp.Albums[0].Title, resp.Albums[0].Artist)
+	}
+	if resp.Albums[0].ID == "" {
+		t.Error("Expected album ID to be set")
+	}
+}
+
+func TestCachedService_YearBrowsing_CacheDisabled(t *testing.T) {
+	service := NewCachedService(&MockMPDClient{}, &MockPathClassifier{}, nil)
+
+	albums := service.GetAlbumsByYear(GetAlbumsByYearRequest{Decade: 1970})
+	if albums.Error == "" || albums.Albums == nil {
+		t.Errorf("Expected error and empty album list without cache, got %+v", albums)
+	}
+
+	decades := service.GetDecades()
+	if decades.Error == "" || decades.Decades == nil {
+		t.Errorf("Expected error and empty decade list without cache, got %+v", decades)
+	}
+}
+
+func TestService_GetAlbums_Compilation(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsResp: map[string][]AlbumDetails{
+			"INTERNAL": {
+				{Album: "Mixed Hits", AlbumArtist: cache.VariousArtists, TrackCount: 12, FirstTrack: "INTERNAL/Mixed Hits/01.flac", IsCompilation: true},
+				{Album: "Solo", AlbumArtist: "Singer", TrackCount: 10, FirstTrack: "INTERNAL/Solo/01.flac"},
+			},
+		},
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.GetAlbums(GetAlbumsRequest{Scope: ScopeLocal, Sort: SortAlphabetical})
+
+	if len(resp.Albums) != 2 {
+		t.Fatalf("Expected 2 albums, got %d", len(resp.Albums))
+	}
+	if !resp.Albums[0].IsCompilation || resp.Albums[0].Artist != cache.VariousArtists {
+		t.Errorf("Expected 'Mixed Hits' to be a Various Artists compilation, got %+v", resp.Albums[0])
+	}
+	if resp.Albums[1].IsCompilation {
+		t.Errorf("Expected 'Solo' not to be a compilation")
+	}
+}
+
+func TestService_BrowseFolder(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		ListInfoResp: map[string][]map[string]string{
+			"NAS/Share/Artist": {
+				{"file": "NAS/Share/Artist/02 - Second.flac", "Title": "Second", "Artist": "Artist", "Album": "Loose", "Track": "2", "Time": "200"},
+				{"directory": "NAS/Share/Artist/Zeta"},
+				{"playlist": "NAS/Share/Artist/list.m3u"},
+				{"file": "NAS/Share/Artist/01 - First.flac", "Title": "First", "Track": "1/10", "duration": "180.500"},
+				{"directory": "NAS/Share/Artist/alpha"},
+			},
+		},
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.BrowseFolder(BrowseFolderRequest{URI: "/NAS/Share/Artist/"})
+
+	if resp.Error != "" {
+		t.Fatalf("Unexpected error: %s", resp.Error)
+	}
+	if resp.URI != "NAS/Share/Artist" || resp.Parent != "NAS/Share" {
+		t.Errorf("Expected uri 'NAS/Share/Artist' with parent 'NAS/Share', got %q / %q", resp.URI, resp.Parent)
+	}
+	if len(resp.Entries) != 4 {
+		t.Fatalf("Expected 4 entries (playlists skipped), got %d", len(resp.Entries))
+	}
+
+	wantNames := []string{"alpha", "Zeta", "01 - First.flac", "02 - Second.flac"}
+	for i, name := range wantNames {
+		if resp.Entries[i].Name != name {
+			t.Errorf("Entry %d: expected %q, got %q", i, name, resp.Entries[i].Name)
+		}
+	}
+
+	dir := resp.Entries[0]
+	if dir.Type != FolderEntryDirectory || dir.Playable || dir.AlbumArt != "" {
+		t.Errorf("Expected non-playable directory without art, got %+v", dir)
+	}
+
+	file := resp.Entries[2]
+	if file.Type != FolderEntryFile || !file.Playable {
+		t.Errorf("Expected playable file, got %+v", file)
+	}
+	if file.TrackNumber != 1 || file.Duration != 180 {
+		t.Errorf("Expected track 1 with duration 180, got %d / %d", file.TrackNumber, file.Duration)
+	}
+	if file.AlbumArt != "/albumart?path=NAS/Share/Artist/01 - First.flac" {
+		t.Errorf("Unexpected album art URL: %s", file.AlbumArt)
+	}
+	if resp.Entries[3].Duration != 200 {
+		t.Errorf("Expected duration 200 from Time tag, got %d", resp.Entries[3].Duration)
+	}
+}
+
+func TestService_BrowseFolder_Root(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		ListInfoResp: map[string][]map[string]string{
+			"": {{"directory": "NAS"}, {"directory": "INTERNAL"}},
+		},
+	}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.BrowseFolder(BrowseFolderRequest{})
+
+	if resp.URI != "" || resp.Parent != "" {
+		t.Errorf("Expected root with no parent, got %q / %q", resp.URI, resp.Parent)
+	}
+	if len(resp.Entries) != 2 || resp.Entries[0].URI != "INTERNAL" {
+		t.Errorf("Expected INTERNAL and NAS sorted, got %+v", resp.Entries)
+	}
+
+	child := service.BrowseFolder(BrowseFolderRequest{URI: "NAS"})
+	if child.Parent != "" {
+		t.Errorf("Expected top-level folder to have root parent, got %q", child.Parent)
+	}
+}
+
+func TestService_BrowseFolder_Error(t *testing.T) {
+	mockMPD := &MockMPDClient{ListInfoError: fmt.Errorf("No such directory")}
+
+	service := NewService(mockMPD, &MockPathClassifier{})
+
+	resp := service.BrowseFolder(BrowseFolderRequest{URI: "NAS/Missing"})
+
+	if resp.Error == "" {
+		t.Error("Expected error for missing folder")
+	}
+	if resp.Entries == nil {
+		t.Error("Expected empty entries slice, not nil")
+	}
+}
+
+// --- CachedService Tests ---
+
+func TestCachedService_AlbumTracksUseClassifier(t *testing.T) {
+	cacheDB := cache.NewDB(filepath.Join(t.TempDir(), "library.db"))
+	if err := cacheDB.Open(); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer cacheDB.Close()
+
+	mockMPD := &MockMPDClient{
+		FindAlbumTracksResp: map[string][]map[string]string{
+			"Mixed\x00Artist": {
+				{"file": "INTERNAL/Mixed/01.flac", "Title": "One", "Track": "1"},
+				{"file": "NAS/Mixed/02.flac", "Title": "Two", "Track": "2"},
+				{"file": "mirror/Mixed/03.flac", "Title": "Three", "Track": "3"},
+			},
+		},
+	}
+	// The classifier, not a path heuristic, decides where the mirror lives
+	classifier := &MockPathClassifier{SourceMap: map[string]SourceType{"mirror/Mixed/03.flac": SourceNAS}}
+
+	service := NewCachedService(mockMPD, classifier, cacheDB)
+	if err := service.cacheBuilder.BuildAlbumTracks("mixed", "Mixed", "Artist"); err != nil {
+		t.Fatalf("BuildAlbumTracks: %v", err)
+	}
+
+	tracks, err := service.cacheDAO.QueryTracks(cache.TrackFilter{AlbumID: "mixed", Scope: "nas"})
+	if err != nil {
+		t.Fatalf("QueryTracks: %v", err)
+	}
+	if len(tracks) != 2 || tracks[0].Title != "Two" || tracks[1].Title != "Three" {
+		t.Errorf("Expected NAS tracks Two and Three, got %+v", tracks)
+	}
+
+	local, err := service.cacheDAO.QueryTracks(cache.TrackFilter{AlbumID: "mixed", Scope: "local"})
+	if err != nil {
+		t.Fatalf("QueryTracks: %v", err)
+	}
+	if len(local) != 1 || local[0].Title != "One" || local[0].Source != string(SourceLocal) {
+		t.Errorf("Expected local track One, got %+v", local)
+	}
+}
+
+func TestCachedService_GetRecentlyAddedFromCache(t *testing.T) {
+	cacheDB := cache.NewDB(filepath.Join(t.TempDir(), "library.db"))
+	if err := cacheDB.Open(); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer cacheDB.Close()
+
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsResp: map[string][]AlbumDetails{
+			"INTERNAL": {{Album: "Old", AlbumArtist: "A", FirstTrack: "INTERNAL/Old/01.flac", AddedAt: base}},
+			"NAS": {
+				{Album: "Newest", AlbumArtist: "B", FirstTrack: "NAS/Newest/01.flac", AddedAt: base.Add(time.Hour).UTC()},
+			},
+		},
+	}
+	service := NewCachedService(mockMPD, &MockPathClassifier{}, cacheDB)
+	if err := service.RebuildCache(); err != nil {
+		t.Fatalf("RebuildCache: %v", err)
+	}
+
+	// Served from the cache, not MPD
+	mockMPD.GetAlbumDetailsError = fmt.Errorf("MPD should not be queried")
+
+	resp := service.GetRecentlyAdded(GetRecentlyAddedRequest{})
+	if len(resp.Albums) != 2 || resp.Albums[0].Title != "Newest" || resp.Albums[1].Title != "Old" {
+		t.Fatalf("Expected [Newest Old], got %+v", resp.Albums)
+	}
+	if !resp.Albums[1].AddedAt.Equal(base) {
+		t.Errorf("AddedAt = %v, want %v", resp.Albums[1].AddedAt, base)
+	}
+}
+
+func TestCachedService_GetSourceStats(t *testing.T) {
+	cacheDB := cache.NewDB(filepath.Join(t.TempDir(), "library.db"))
+	if err := cacheDB.Open(); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer cacheDB.Close()
+
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsResp: map[string][]AlbumDetails{
+			"INTERNAL": {{Album: "Local", AlbumArtist: "A", TrackCount: 9, FirstTrack: "INTERNAL/Local/01.flac"}},
+			"NAS": {
+				{Album: "Shared", AlbumArtist: "B", TrackCount: 11, FirstTrack: "NAS/Shared/01.flac"},
+				{Album: "Other", AlbumArtist: "C", TrackCount: 5, FirstTrack: "NAS/Other/01.flac"},
+			},
+		},
+	}
+	service := NewCachedService(mockMPD, &MockPathClassifier{}, cacheDB)
+	if err := service.RebuildCache(); err != nil {
+		t.Fatalf("RebuildCache: %v", err)
+	}
+
+	resp := service.GetSourceStats()
+	if resp.Error != "" {
+		t.Fatalf("Unexpected error: %s", resp.Error)
+	}
+	if len(resp.Sources) != 3 {
+		t.Fatalf("Expected local, usb and nas, got %+v", resp.Sources)
+	}
+	local, usb, nas := resp.Sources[0], resp.Sources[1], resp.Sources[2]
+	if local.Source != SourceLocal || local.AlbumCount != 1 || local.TrackCount != 9 || local.LastScanned.IsZero() {
+		t.Errorf("Unexpected local stats: %+v", local)
+	}
+	if usb.Source != SourceUSB || usb.AlbumCount != 0 || !usb.LastScanned.IsZero() {
+		t.Errorf("Expected empty usb stats, got %+v", usb)
+	}
+	if nas.Source != SourceNAS || nas.AlbumCount != 2 || nas.TrackCount != 16 {
+		t.Errorf("Unexpected nas stats: %+v", nas)
+	}
+	if resp.TotalAlbums != 3 || resp.TotalTracks != 25 {
+		t.Errorf("Totals = %d albums, %d tracks, want 3 and 25", resp.TotalAlbums, resp.TotalTracks)
+	}
+}
+
+func TestCachedService_GetSourceStatsWithoutCache(t *testing.T) {
+	service := NewCachedService(&MockMPDClient{}, &MockPathClassifier{}, nil)
+
+	if resp := service.GetSourceStats(); resp.Error == "" {
+		t.Error("Expected error without a cache")
+	}
+}
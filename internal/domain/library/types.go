@@ -36,15 +36,18 @@ const (
 
 // Album represents an album in the library.
 type Album struct {
-	ID         string     `json:"id"`
-	Title      string     `json:"title"`
-	Artist     string     `json:"artist"`
-	URI        string     `json:"uri"`
-	AlbumArt   string     `json:"albumArt,omitempty"`
-	TrackCount int        `json:"trackCount,omitempty"`
-	Source     SourceType `json:"source"`
-	Year       int        `json:"year,omitempty"`
-	AddedAt    time.Time  `json:"addedAt,omitempty"`
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Artist         string     `json:"artist"`                   // Album artist
+	ArtistInferred bool       `json:"artistInferred,omitempty"` // No album artist tag; artist was derived from the tracks
+	TrackArtists   []string   `json:"trackArtists,omitempty"`   // Distinct track artists, including featured guests
+	URI            string     `json:"uri"`
+	AlbumArt       string     `json:"albumArt,omitempty"`
+	TrackCount     int        `json:"trackCount,omitempty"`
+	Source         SourceType `json:"source"`
+	Year           int        `json:"year,omitempty"`
+	AddedAt        time.Time  `json:"addedAt,omitempty"`
+	IsCompilation  bool       `json:"isCompilation"`
 }
 
 // Artist represents an artist in the library.
@@ -54,6 +57,12 @@ type Artist struct {
 	AlbumArt   string `json:"albumArt,omitempty"` // From first album
 }
 
+// Genre represents a genre in the library.
+type Genre struct {
+	Name       string `json:"name"`
+	AlbumCount int    `json:"albumCount"`
+}
+
 // Track represents a track in an album.
 type Track struct {
 	ID          string     `json:"id"`
@@ -62,6 +71,7 @@ type Track struct {
 	Album       string     `json:"album"`
 	URI         string     `json:"uri"`
 	TrackNumber int        `json:"trackNumber,omitempty"`
+	DiscNumber  int        `json:"discNumber,omitempty"`
 	Duration    int        `json:"duration,omitempty"`
 	AlbumArt    string     `json:"albumArt,omitempty"`
 	Source      SourceType `json:"source"`
@@ -112,6 +122,26 @@ type ArtistsResponse struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+// GetArtistIndexRequest is the request for the artist A-Z index. Query
+// should match the one used to list artists so offsets line up.
+type GetArtistIndexRequest struct {
+	Query string `json:"query,omitempty"`
+}
+
+// ArtistLetter is a leading letter of artist names, for an A-Z jump bar.
+type ArtistLetter struct {
+	Letter string `json:"letter"` // "A" to "Z", or "#" for anything else
+	Count  int    `json:"count"`
+	Offset int    `json:"offset"` // Position of the first artist in the list
+}
+
+// ArtistIndexResponse is the response for the artist A-Z index.
+type ArtistIndexResponse struct {
+	Letters []ArtistLetter `json:"letters"`
+	Total   int            `json:"total"`
+	Error   string         `json:"error,omitempty"`
+}
+
 // GetArtistAlbumsRequest is the request for listing albums by an artist.
 type GetArtistAlbumsRequest struct {
 	Artist string    `json:"artist"`
@@ -127,6 +157,54 @@ type ArtistAlbumsResponse struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+// SearchAdvancedRequest is the request for a field-scoped library search.
+// Every non-empty field must match (case-insensitive substring); at least
+// one is required.
+type SearchAdvancedRequest struct {
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Genre  string `json:"genre,omitempty"`
+	Limit  int    `json:"limit,omitempty"` // Maximum tracks returned, 0 for the default
+}
+
+// SearchAdvancedResponse is the response for a field-scoped library search.
+// Albums are those of every matching track, even past the track limit.
+type SearchAdvancedResponse struct {
+	Albums      []Album `json:"albums"`
+	Tracks      []Track `json:"tracks"`
+	TotalTracks int     `json:"totalTracks"` // Matching tracks before the limit
+	Error       string  `json:"error,omitempty"`
+}
+
+// GetGenresRequest is the request for listing genres.
+type GetGenresRequest struct {
+	Query string `json:"query,omitempty"`
+	Page  int    `json:"page,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// GenresResponse is the response for listing genres.
+type GenresResponse struct {
+	Genres     []Genre    `json:"genres"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// GetGenreAlbumsRequest is the request for listing albums in a genre.
+type GetGenreAlbumsRequest struct {
+	Genre string    `json:"genre"`
+	Sort  SortOrder `json:"sort"`
+	Page  int       `json:"page,omitempty"`
+	Limit int       `json:"limit,omitempty"`
+}
+
+// GenreAlbumsResponse is the response for listing albums in a genre.
+type GenreAlbumsResponse struct {
+	Genre      string     `json:"genre"`
+	Albums     []Album    `json:"albums"`
+	Pagination Pagination `json:"pagination"`
+}
+
 // GetAlbumTracksRequest is the request for listing tracks in an album.
 type GetAlbumTracksRequest struct {
 	Album       string `json:"album"`
@@ -142,6 +220,70 @@ type AlbumTracksResponse struct {
 	Error         string  `json:"error,omitempty"`
 }
 
+// GetAlbumsByYearRequest is the request for listing albums from a year or decade.
+// Decade takes precedence when both are set.
+type GetAlbumsByYearRequest struct {
+	Year   int `json:"year,omitempty"`
+	Decade int `json:"decade,omitempty"` // First year of the decade, e.g. 1970
+}
+
+// AlbumsByYearResponse is the response for listing albums from a year or decade.
+type AlbumsByYearResponse struct {
+	Year   int     `json:"year,omitempty"`
+	Decade int     `json:"decade,omitempty"`
+	Albums []Album `json:"albums"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// Decade is a decade with the number of albums released in it.
+type Decade struct {
+	Decade int `json:"decade"`
+	Count  int `json:"count"`
+}
+
+// DecadesResponse is the response for listing decades.
+type DecadesResponse struct {
+	Decades []Decade `json:"decades"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// FolderEntryType distinguishes directories from playable files in a folder listing.
+type FolderEntryType string
+
+const (
+	FolderEntryDirectory FolderEntryType = "directory"
+	FolderEntryFile      FolderEntryType = "file"
+)
+
+// FolderEntry is a directory or song in a folder listing.
+type FolderEntry struct {
+	Type        FolderEntryType `json:"type"`
+	Name        string          `json:"name"`
+	URI         string          `json:"uri"`
+	Playable    bool            `json:"playable"`
+	Title       string          `json:"title,omitempty"`
+	Artist      string          `json:"artist,omitempty"`
+	Album       string          `json:"album,omitempty"`
+	TrackNumber int             `json:"trackNumber,omitempty"`
+	DiscNumber  int             `json:"discNumber,omitempty"`
+	Duration    int             `json:"duration,omitempty"`
+	AlbumArt    string          `json:"albumArt,omitempty"`
+	Source      SourceType      `json:"source,omitempty"`
+}
+
+// BrowseFolderRequest is the request for listing a folder.
+type BrowseFolderRequest struct {
+	URI string `json:"uri"` // Directory in the MPD music directory, empty for the root
+}
+
+// FolderResponse is the response for listing a folder.
+type FolderResponse struct {
+	URI     string        `json:"uri"`
+	Parent  string        `json:"parent,omitempty"` // Empty at the root and its direct children
+	Entries []FolderEntry `json:"entries"`
+	Error   string        `json:"error,omitempty"`
+}
+
 // GetRadioRequest is the request for listing radio stations.
 type GetRadioRequest struct {
 	Query string `json:"query,omitempty"`
@@ -155,6 +297,37 @@ type RadioResponse struct {
 	Pagination Pagination     `json:"pagination"`
 }
 
+// SourceStats is how much of the library one source contributes.
+type SourceStats struct {
+	Source      SourceType `json:"source"`
+	AlbumCount  int        `json:"albumCount"`
+	TrackCount  int        `json:"trackCount"`
+	LastScanned time.Time  `json:"lastScanned,omitempty"` // Zero if nothing from the source is cached
+}
+
+// SourceStatsResponse is the response for per-source library statistics.
+type SourceStatsResponse struct {
+	Sources     []SourceStats `json:"sources"` // Always local, usb and nas, in that order
+	TotalAlbums int           `json:"totalAlbums"`
+	TotalTracks int           `json:"totalTracks"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// GetRecentlyAddedRequest is the request for the newest albums in the library.
+type GetRecentlyAddedRequest struct {
+	Limit int `json:"limit,omitempty"` // 0 for DefaultRecentlyAddedLimit
+}
+
+// RecentlyAddedResponse is the response for the newest albums in the library.
+type RecentlyAddedResponse struct {
+	Albums []Album `json:"albums"` // Newest first
+	Error  string  `json:"error,omitempty"`
+}
+
+// DefaultRecentlyAddedLimit is how many albums the recently added shelf holds
+// by default.
+const DefaultRecentlyAddedLimit = 20
+
 // DefaultLimit is the default page size for listings.
 const DefaultLimit = 50
 
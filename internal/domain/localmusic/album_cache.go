@@ -0,0 +1,75 @@
+package localmusic
+
+import (
+	"strings"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
+	"github.com/rs/zerolog/log"
+)
+
+// SetCache makes GetLocalAlbums read albums from the library cache, where
+// SQLite sorts and pages them, instead of listing the whole library from
+// MPD on every request. MPD is still used while the cache is empty or a
+// query fails. It should be called before the service is used.
+func (s *Service) SetCache(db *cache.DB) {
+	if db == nil {
+		s.cacheDB, s.cacheDAO = nil, nil
+		return
+	}
+	s.cacheDB = db
+	s.cacheDAO = cache.NewDAO(db)
+}
+
+// cachedLocalAlbums returns a page of local and USB albums from the cache,
+// and how many match in total. ok is false on a cache miss.
+func (s *Service) cachedLocalAlbums(query string, sortOrder AlbumSortOrder, page, limit int) (albums []Album, total int, ok bool) {
+	if s.cacheDB == nil {
+		return nil, 0, false
+	}
+
+	stats, err := s.cacheDB.GetStats()
+	if err != nil || stats.AlbumCount == 0 {
+		log.Debug().Msg("Cache empty, listing local albums from MPD")
+		return nil, 0, false
+	}
+
+	pag := cache.AllRows
+	if limit > 0 {
+		pag = cache.Pagination{Page: page, Limit: limit, Offset: (page - 1) * limit}
+	}
+
+	filter := cache.AlbumFilter{Scope: "local", Query: query}
+	cached, total, err := s.cacheDAO.QueryAlbums(filter, cacheSortOrder(sortOrder), pag)
+	if err != nil {
+		log.Warn().Err(err).Msg("Cache query failed, listing local albums from MPD")
+		return nil, 0, false
+	}
+
+	albums = make([]Album, 0, len(cached))
+	for _, ca := range cached {
+		albums = append(albums, Album{
+			ID:            albumID(ca.Title, ca.AlbumArtist),
+			Title:         ca.Title,
+			Artist:        ca.AlbumArtist,
+			URI:           ca.URI,
+			AlbumArt:      "/albumart?path=" + ca.FirstTrack,
+			TrackCount:    ca.TrackCount,
+			Source:        SourceType(ca.Source),
+			AddedAt:       ca.AddedAt,
+			IsCompilation: strings.EqualFold(ca.AlbumArtist, "Various Artists"),
+		})
+	}
+	return albums, total, true
+}
+
+// cacheSortOrder maps an album sort order to the cache's.
+func cacheSortOrder(sortOrder AlbumSortOrder) cache.SortOrder {
+	switch sortOrder {
+	case AlbumSortRecentlyAdded:
+		return cache.SortRecentlyAdded
+	case AlbumSortByArtist:
+		return cache.SortByArtist
+	default:
+		return cache.SortAlphabetical
+	}
+}
@@ -2,6 +2,7 @@ package localmusic
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path"
 	"strings"
@@ -10,19 +11,112 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// SourceRule classifies the paths under a directory of MPD's music directory.
+type SourceRule struct {
+	Prefix string     // Directory relative to the music directory, e.g. "NAS"
+	Source SourceType // One of SourceLocal, SourceUSB or SourceNAS
+}
+
+// DefaultSourceRules are the base directories Stellar itself mounts sources
+// under. They apply after any configured rules.
+var DefaultSourceRules = []SourceRule{
+	{Prefix: "NAS", Source: SourceNAS},
+	{Prefix: "USB", Source: SourceUSB},
+	{Prefix: "INTERNAL", Source: SourceLocal},
+}
+
+// ParseSourceRules parses a comma-separated list of prefix=source rules,
+// e.g. "Network/Music=nas,Stick=usb". Sources are local, usb or nas.
+func ParseSourceRules(spec string) ([]SourceRule, error) {
+	var rules []SourceRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, source, ok := strings.Cut(entry, "=")
+		prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("invalid source rule %q: want prefix=source", entry)
+		}
+		switch sourceType := SourceType(strings.TrimSpace(source)); sourceType {
+		case SourceLocal, SourceUSB, SourceNAS:
+			rules = append(rules, SourceRule{Prefix: prefix, Source: sourceType})
+		default:
+			return nil, fmt.Errorf("invalid source %q for %s: want local, usb or nas", source, prefix)
+		}
+	}
+	return rules, nil
+}
+
 // PathClassifier classifies music file paths by source type.
 type PathClassifier struct {
 	mpdMusicDir string
-	mountCache  map[string]string // path -> mount type cache
+	rules       []SourceRule          // Checked in order; configured rules first
+	linkTargets map[string]SourceType // mount base -> source of symlinks into it
+	mountCache  map[string]string     // path -> mount type cache
+	linkCache   map[string]linkResult // directory -> source it links to
 	cacheMu     sync.RWMutex
 }
 
-// NewPathClassifier creates a new path classifier.
+// linkResult is the cached classification of a directory by where its
+// symlinks lead.
+type linkResult struct {
+	source SourceType
+	linked bool // False when the directory does not lead to a link target
+}
+
+// maxLinkHops bounds how many symlinks within the music directory are
+// followed, so a symlink loop cannot hang classification.
+const maxLinkHops = 8
+
+// NewPathClassifier creates a new path classifier using DefaultSourceRules.
 func NewPathClassifier(mpdMusicDir string) *PathClassifier {
 	return &PathClassifier{
 		mpdMusicDir: mpdMusicDir,
+		rules:       DefaultSourceRules,
 		mountCache:  make(map[string]string),
+		linkCache:   make(map[string]linkResult),
+	}
+}
+
+// WithRules makes the classifier check rules before DefaultSourceRules, for
+// music directories whose layout differs from the one Stellar creates. It
+// should be called before the classifier is used.
+func (c *PathClassifier) WithRules(rules []SourceRule) *PathClassifier {
+	c.rules = append(append([]SourceRule{}, rules...), DefaultSourceRules...)
+	return c
+}
+
+// WithLinkTargets makes the classifier follow symlinks within the music
+// directory: a path leading into one of the given directories, e.g. the NAS
+// mount base the sources service links shares from, takes that directory's
+// source type whatever its prefix. Only links inside the music directory are
+// read, so a dead NAS mount cannot block classification. It should be called
+// before the classifier is used.
+func (c *PathClassifier) WithLinkTargets(targets map[string]SourceType) *PathClassifier {
+	c.linkTargets = make(map[string]SourceType, len(targets))
+	for target, source := range targets {
+		c.linkTargets[path.Clean(target)] = source
+	}
+	return c
+}
+
+// Prefixes returns the directories classified as the given source types,
+// grouped in argument order and in rule order within each type. A prefix
+// shadowed by an earlier rule is only reported for that rule's type.
+func (c *PathClassifier) Prefixes(sourceTypes ...SourceType) []string {
+	var prefixes []string
+	for _, sourceType := range sourceTypes {
+		seen := make(map[string]bool)
+		for _, rule := range c.rules {
+			if !seen[rule.Prefix] && rule.Source == sourceType {
+				prefixes = append(prefixes, rule.Prefix)
+			}
+			seen[rule.Prefix] = true
+		}
 	}
+	return prefixes
 }
 
 // GetSourceType determines the source type for a given URI/path.
@@ -38,25 +132,18 @@ func (c *PathClassifier) GetSourceType(uri string) SourceType {
 	// Normalize the path
 	normalizedPath := c.normalizePath(uri)
 
-	// Check path prefixes for known source types
-	// These are the canonical paths within MPD's music directory
-
-	// NAS paths: music-library/NAS/... or NAS/...
-	if strings.HasPrefix(normalizedPath, "NAS/") ||
-		strings.HasPrefix(normalizedPath, "music-library/NAS/") {
-		return SourceNAS
-	}
-
-	// USB paths: music-library/USB/... or USB/...
-	if strings.HasPrefix(normalizedPath, "USB/") ||
-		strings.HasPrefix(normalizedPath, "music-library/USB/") {
-		return SourceUSB
+	// Music reached through a symlink into a known mount is classified by
+	// the mount, not by the directory holding the link
+	if source, ok := c.linkedSource(normalizedPath); ok {
+		return source
 	}
 
-	// INTERNAL paths: music-library/INTERNAL/... or INTERNAL/...
-	if strings.HasPrefix(normalizedPath, "INTERNAL/") ||
-		strings.HasPrefix(normalizedPath, "music-library/INTERNAL/") {
-		return SourceLocal
+	// Check path prefixes for known source types
+	// These are the base directories within MPD's music directory
+	for _, rule := range c.rules {
+		if strings.HasPrefix(normalizedPath, rule.Prefix+"/") {
+			return rule.Source
+		}
 	}
 
 	// If no known prefix, check if it's a mounted filesystem
@@ -90,6 +177,106 @@ func (c *PathClassifier) IsStreamingPath(uri string) bool {
 	return c.GetSourceType(uri) == SourceStreaming
 }
 
+// linkedSource returns the source type of a path whose directory leads
+// through symlinks into a link target, or into one of the rules' base
+// directories. Results are cached per directory until RefreshMountCache.
+func (c *PathClassifier) linkedSource(relativePath string) (SourceType, bool) {
+	if len(c.linkTargets) == 0 || path.IsAbs(relativePath) {
+		return "", false
+	}
+	dir := path.Dir(relativePath)
+
+	c.cacheMu.RLock()
+	cached, ok := c.linkCache[dir]
+	c.cacheMu.RUnlock()
+	if ok {
+		return cached.source, cached.linked
+	}
+
+	var result linkResult
+	if resolved, ok := c.resolveLinks(dir); ok {
+		result = c.classifyResolved(resolved)
+	}
+
+	c.cacheMu.Lock()
+	c.linkCache[dir] = result
+	c.cacheMu.Unlock()
+	return result.source, result.linked
+}
+
+// resolveLinks follows the symlinks in a directory relative to the music
+// directory. It stops at the first link leading outside the music directory
+// without touching the target, and reports false when no link was followed.
+func (c *PathClassifier) resolveLinks(dir string) (string, bool) {
+	root := path.Clean(c.mpdMusicDir)
+	components := strings.Split(dir, "/")
+	current := root
+	hops := 0
+	for i := 0; i < len(components); i++ {
+		if components[i] == "" || components[i] == "." {
+			continue
+		}
+		next := path.Join(current, components[i])
+		info, err := os.Lstat(next)
+		if err != nil {
+			return "", false
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		hops++
+		if hops > maxLinkHops {
+			return "", false
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", false
+		}
+		if !path.IsAbs(target) {
+			target = path.Join(current, target)
+		}
+		target = path.Clean(target)
+		rest := path.Join(components[i+1:]...)
+		if target != root && !strings.HasPrefix(target, root+"/") {
+			return path.Join(target, rest), true
+		}
+
+		// Keep walking from the music directory through the link target
+		components = strings.Split(strings.TrimPrefix(path.Join(target, rest), root), "/")
+		current = root
+		i = -1
+	}
+	return current, hops > 0
+}
+
+// classifyResolved classifies a directory a symlink resolved to.
+func (c *PathClassifier) classifyResolved(resolved string) linkResult {
+	longest := ""
+	var result linkResult
+	for target, source := range c.linkTargets {
+		if (resolved == target || strings.HasPrefix(resolved, target+"/")) && len(target) > len(longest) {
+			longest = target
+			result = linkResult{source: source, linked: true}
+		}
+	}
+	if result.linked {
+		return result
+	}
+
+	root := path.Clean(c.mpdMusicDir)
+	if strings.HasPrefix(resolved, root+"/") {
+		relative := strings.TrimPrefix(resolved, root+"/") + "/"
+		for _, rule := range c.rules {
+			if strings.HasPrefix(relative, rule.Prefix+"/") {
+				return linkResult{source: rule.Source, linked: true}
+			}
+		}
+	}
+	return linkResult{}
+}
+
 // normalizePath normalizes a URI/path for classification.
 func (c *PathClassifier) normalizePath(uri string) string {
 	// Strip music-library prefix if present
@@ -180,6 +367,7 @@ func (c *PathClassifier) RefreshMountCache() {
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 	c.mountCache = make(map[string]string)
+	c.linkCache = make(map[string]linkResult)
 }
 
 // FilterLocalOnly filters a list of URIs to only include local sources.
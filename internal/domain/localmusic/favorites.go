@@ -0,0 +1,183 @@
+package localmusic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/atomicfile"
+)
+
+// FavoritesStore manages favorite tracks and albums persistence.
+type FavoritesStore struct {
+	filePath   string
+	classifier *PathClassifier
+	favorites  map[string]Favorite // Keyed by URI
+	mu         sync.RWMutex
+	saveMu     sync.Mutex     // Serializes writes so the newest snapshot lands last
+	saving     sync.WaitGroup // Tracks in-flight saves
+}
+
+// NewFavoritesStore creates a new favorites store.
+func NewFavoritesStore(dataDir string, classifier *PathClassifier) *FavoritesStore {
+	f := &FavoritesStore{
+		filePath:   filepath.Join(dataDir, "favorites.json"),
+		classifier: classifier,
+		favorites:  make(map[string]Favorite),
+	}
+	f.load()
+	return f
+}
+
+// Add marks a track or album URI as a favorite. Adding an existing favorite is a no-op.
+func (f *FavoritesStore) Add(uri string, favType FavoriteType) error {
+	if uri == "" {
+		return fmt.Errorf("uri is required")
+	}
+	if favType != FavoriteTrack && favType != FavoriteAlbum {
+		return fmt.Errorf("invalid favorite type: must be 'track' or 'album'")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.favorites[uri]; exists {
+		return nil
+	}
+
+	f.favorites[uri] = Favorite{
+		URI:     uri,
+		Type:    favType,
+		Source:  f.classifier.GetSourceType(uri),
+		AddedAt: time.Now(),
+	}
+
+	log.Info().Str("uri", uri).Str("type", string(favType)).Msg("Added favorite")
+	f.saveAsync()
+	return nil
+}
+
+// Import adds favorites carried over from another device, keeping when they
+// were added. Favorites already present or invalid are skipped. It returns
+// the favorites that were added.
+func (f *FavoritesStore) Import(favorites []Favorite) []Favorite {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var added []Favorite
+	for _, fav := range favorites {
+		if fav.URI == "" || (fav.Type != FavoriteTrack && fav.Type != FavoriteAlbum) {
+			log.Warn().Str("uri", fav.URI).Str("type", string(fav.Type)).Msg("Skipping invalid imported favorite")
+			continue
+		}
+		if _, exists := f.favorites[fav.URI]; exists {
+			continue
+		}
+		// The source depends on this device's layout, not the exporting one's
+		fav.Source = f.classifier.GetSourceType(fav.URI)
+		if fav.AddedAt.IsZero() {
+			fav.AddedAt = time.Now()
+		}
+		f.favorites[fav.URI] = fav
+		added = append(added, fav)
+	}
+
+	if len(added) > 0 {
+		log.Info().Int("count", len(added)).Msg("Imported favorites")
+		f.saveAsync()
+	}
+	return added
+}
+
+// Remove unmarks a favorite. Removing a URI that is not a favorite is a no-op.
+func (f *FavoritesStore) Remove(uri string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.favorites[uri]; !exists {
+		return
+	}
+
+	delete(f.favorites, uri)
+	log.Info().Str("uri", uri).Msg("Removed favorite")
+	f.saveAsync()
+}
+
+// Contains returns true if the URI is a favorite.
+func (f *FavoritesStore) Contains(uri string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, exists := f.favorites[uri]
+	return exists
+}
+
+// List returns favorites of the given type, most recently added first.
+// An empty type returns all favorites.
+func (f *FavoritesStore) List(favType FavoriteType) []Favorite {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	favorites := make([]Favorite, 0, len(f.favorites))
+	for _, fav := range f.favorites {
+		if favType == "" || fav.Type == favType {
+			favorites = append(favorites, fav)
+		}
+	}
+
+	sort.Slice(favorites, func(i, j int) bool {
+		return favorites[i].AddedAt.After(favorites[j].AddedAt)
+	})
+	return favorites
+}
+
+// load reads favorites from disk.
+func (f *FavoritesStore) load() {
+	data, err := os.ReadFile(f.filePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("file", f.filePath).Msg("Failed to read favorites")
+		}
+		return
+	}
+
+	var favorites []Favorite
+	if err := json.Unmarshal(data, &favorites); err != nil {
+		log.Warn().Err(err).Str("file", f.filePath).Msg("Failed to parse favorites, starting fresh")
+		setAsideCorrupt(f.filePath)
+		return
+	}
+
+	for _, fav := range favorites {
+		f.favorites[fav.URI] = fav
+	}
+	log.Info().Int("count", len(favorites)).Msg("Loaded favorites")
+}
+
+// saveAsync saves favorites to disk asynchronously.
+func (f *FavoritesStore) saveAsync() {
+	f.saving.Add(1)
+	go func() {
+		defer f.saving.Done()
+		f.saveMu.Lock()
+		defer f.saveMu.Unlock()
+
+		favorites := f.List("")
+
+		data, err := json.MarshalIndent(favorites, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal favorites")
+			return
+		}
+
+		if err := atomicfile.Write(f.filePath, data, 0644); err != nil {
+			log.Error().Err(err).Msg("Failed to save favorites")
+		}
+	}()
+}
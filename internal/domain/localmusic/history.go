@@ -10,29 +10,65 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/atomicfile"
 )
 
+// DefaultHistorySize is how many play history entries are kept by default.
+const DefaultHistorySize = 1000
+
 // HistoryStore manages playback history persistence.
 type HistoryStore struct {
 	filePath   string
 	classifier *PathClassifier
-	entries    []PlayHistoryEntry
+	entries    []PlayHistoryEntry // Oldest first
 	mu         sync.RWMutex
 	maxEntries int
+	saveMu     sync.Mutex     // Serializes writes so the newest snapshot lands last
+	saving     sync.WaitGroup // Tracks in-flight saves
 }
 
-// NewHistoryStore creates a new history store.
+// NewHistoryStore creates a new history store keeping DefaultHistorySize
+// entries.
 func NewHistoryStore(dataDir string, classifier *PathClassifier) *HistoryStore {
 	h := &HistoryStore{
 		filePath:   filepath.Join(dataDir, "playback_history.json"),
 		classifier: classifier,
 		entries:    []PlayHistoryEntry{},
-		maxEntries: 1000, // Keep last 1000 entries
+		maxEntries: DefaultHistorySize,
 	}
 	h.load()
 	return h
 }
 
+// SetMaxEntries sets how many entries the history keeps, evicting the oldest
+// beyond it. Values below 1 restore DefaultHistorySize.
+func (h *HistoryStore) SetMaxEntries(n int) {
+	if n < 1 {
+		n = DefaultHistorySize
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.maxEntries = n
+	if h.trim() {
+		h.saveAsync()
+	}
+}
+
+// trim evicts the oldest entries beyond maxEntries, reporting whether any
+// were. The caller must hold the write lock.
+func (h *HistoryStore) trim() bool {
+	excess := len(h.entries) - h.maxEntries
+	if excess <= 0 {
+		return false
+	}
+	// Copy rather than reslice so the evicted entries can be collected
+	h.entries = append([]PlayHistoryEntry(nil), h.entries[excess:]...)
+	log.Debug().Int("evicted", excess).Int("max", h.maxEntries).Msg("Evicted oldest play history entries")
+	return true
+}
+
 // RecordPlay records a track play event.
 func (h *HistoryStore) RecordPlay(trackURI, title, artist, album, albumArt string, origin PlayOrigin) {
 	h.mu.Lock()
@@ -71,11 +107,7 @@ func (h *HistoryStore) RecordPlay(trackURI, title, artist, album, albumArt strin
 	}
 
 	h.entries = append(h.entries, entry)
-
-	// Trim to max entries
-	if len(h.entries) > h.maxEntries {
-		h.entries = h.entries[len(h.entries)-h.maxEntries:]
-	}
+	h.trim()
 
 	log.Info().
 		Str("uri", trackURI).
@@ -109,6 +141,10 @@ func (h *HistoryStore) GetLastPlayed(req GetLastPlayedRequest, localOnly bool, m
 		filtered = append(filtered, entry)
 	}
 
+	if req.Dedupe {
+		filtered = dedupeEntries(filtered)
+	}
+
 	// Sort entries
 	switch req.Sort {
 	case TrackSortLastPlayed:
@@ -145,6 +181,31 @@ func (h *HistoryStore) GetLastPlayed(req GetLastPlayedRequest, localOnly bool, m
 	}
 }
 
+// dedupeEntries collapses all plays of the same track URI into a single entry.
+// The merged entry keeps the metadata and timestamp of the most recent play and
+// the summed play count. Entries are returned in order of first appearance.
+func dedupeEntries(entries []PlayHistoryEntry) []PlayHistoryEntry {
+	index := make(map[string]int, len(entries))
+	deduped := make([]PlayHistoryEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		i, seen := index[entry.TrackURI]
+		if !seen {
+			index[entry.TrackURI] = len(deduped)
+			deduped = append(deduped, entry)
+			continue
+		}
+
+		total := deduped[i].PlayCount + entry.PlayCount
+		if entry.PlayedAt.After(deduped[i].PlayedAt) {
+			deduped[i] = entry
+		}
+		deduped[i].PlayCount = total
+	}
+
+	return deduped
+}
+
 // GetPlayCount returns the total play count for a track.
 func (h *HistoryStore) GetPlayCount(trackURI string) int {
 	h.mu.RLock()
@@ -159,6 +220,18 @@ func (h *HistoryStore) GetPlayCount(trackURI string) int {
 	return count
 }
 
+// playCounts returns the total play count of every track in the history.
+func (h *HistoryStore) playCounts() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, entry := range h.entries {
+		counts[entry.TrackURI] += entry.PlayCount
+	}
+	return counts
+}
+
 // ClearHistory clears all playback history.
 func (h *HistoryStore) ClearHistory() {
 	h.mu.Lock()
@@ -181,17 +254,24 @@ func (h *HistoryStore) load() {
 
 	var entries []PlayHistoryEntry
 	if err := json.Unmarshal(data, &entries); err != nil {
-		log.Warn().Err(err).Msg("Failed to parse playback history")
+		log.Warn().Err(err).Str("file", h.filePath).Msg("Failed to parse playback history, starting fresh")
+		setAsideCorrupt(h.filePath)
 		return
 	}
 
 	h.entries = entries
-	log.Info().Int("count", len(entries)).Msg("Loaded playback history")
+	h.trim()
+	log.Info().Int("count", len(h.entries)).Msg("Loaded playback history")
 }
 
 // saveAsync saves history to disk asynchronously.
 func (h *HistoryStore) saveAsync() {
+	h.saving.Add(1)
 	go func() {
+		defer h.saving.Done()
+		h.saveMu.Lock()
+		defer h.saveMu.Unlock()
+
 		h.mu.RLock()
 		entriesCopy := make([]PlayHistoryEntry, len(h.entries))
 		copy(entriesCopy, h.entries)
@@ -203,13 +283,7 @@ func (h *HistoryStore) saveAsync() {
 			return
 		}
 
-		// Ensure directory exists
-		if err := os.MkdirAll(filepath.Dir(h.filePath), 0755); err != nil {
-			log.Error().Err(err).Msg("Failed to create history directory")
-			return
-		}
-
-		if err := os.WriteFile(h.filePath, data, 0644); err != nil {
+		if err := atomicfile.Write(h.filePath, data, 0644); err != nil {
 			log.Error().Err(err).Msg("Failed to save playback history")
 		}
 	}()
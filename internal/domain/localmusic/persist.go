@@ -0,0 +1,21 @@
+package localmusic
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// setAsideCorrupt renames a file that failed to parse to a timestamped
+// .corrupt name, so the store starts fresh without losing what can be
+// recovered from it by hand.
+func setAsideCorrupt(filePath string) {
+	aside := fmt.Sprintf("%s.corrupt-%s", filePath, time.Now().Format("20060102-150405"))
+	if err := os.Rename(filePath, aside); err != nil {
+		log.Warn().Err(err).Str("file", filePath).Msg("Failed to set aside corrupt file")
+		return
+	}
+	log.Warn().Str("file", aside).Msg("Set aside corrupt file")
+}
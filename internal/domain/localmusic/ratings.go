@@ -0,0 +1,215 @@
+package localmusic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/atomicfile"
+)
+
+// defaultTopRatedMinRating is used when no minimum rating is requested.
+const defaultTopRatedMinRating = 4
+
+// RatingsStore manages track rating persistence.
+type RatingsStore struct {
+	filePath string
+	ratings  map[string]TrackRating // Keyed by URI
+	mu       sync.RWMutex
+	saveMu   sync.Mutex     // Serializes writes so the newest snapshot lands last
+	saving   sync.WaitGroup // Tracks in-flight saves
+}
+
+// NewRatingsStore creates a new ratings store.
+func NewRatingsStore(dataDir string) *RatingsStore {
+	r := &RatingsStore{
+		filePath: filepath.Join(dataDir, "ratings.json"),
+		ratings:  make(map[string]TrackRating),
+	}
+	r.load()
+	return r
+}
+
+// Set rates a track from 1 to MaxRating stars. A rating of 0 clears it.
+func (r *RatingsStore) Set(uri string, rating int) error {
+	if uri == "" {
+		return fmt.Errorf("uri is required")
+	}
+	if rating < 0 || rating > MaxRating {
+		return fmt.Errorf("invalid rating %d: must be between 0 and %d", rating, MaxRating)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rating == 0 {
+		if _, exists := r.ratings[uri]; !exists {
+			return nil
+		}
+		delete(r.ratings, uri)
+	} else {
+		r.ratings[uri] = TrackRating{URI: uri, Rating: rating, RatedAt: time.Now()}
+	}
+
+	log.Info().Str("uri", uri).Int("rating", rating).Msg("Set track rating")
+	r.saveAsync()
+	return nil
+}
+
+// Get returns a track's rating, or 0 if it is unrated.
+func (r *RatingsStore) Get(uri string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.ratings[uri].Rating
+}
+
+// List returns ratings of at least minRating, highest rated first and most
+// recently rated first within the same rating.
+func (r *RatingsStore) List(minRating int) []TrackRating {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ratings := make([]TrackRating, 0, len(r.ratings))
+	for _, rating := range r.ratings {
+		if rating.Rating >= minRating {
+			ratings = append(ratings, rating)
+		}
+	}
+
+	sort.Slice(ratings, func(i, j int) bool {
+		if ratings[i].Rating != ratings[j].Rating {
+			return ratings[i].Rating > ratings[j].Rating
+		}
+		return ratings[i].RatedAt.After(ratings[j].RatedAt)
+	})
+	return ratings
+}
+
+// load reads ratings from disk.
+func (r *RatingsStore) load() {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("file", r.filePath).Msg("Failed to read ratings")
+		}
+		return
+	}
+
+	var ratings []TrackRating
+	if err := json.Unmarshal(data, &ratings); err != nil {
+		log.Warn().Err(err).Str("file", r.filePath).Msg("Failed to parse ratings, starting fresh")
+		setAsideCorrupt(r.filePath)
+		return
+	}
+
+	for _, rating := range ratings {
+		r.ratings[rating.URI] = rating
+	}
+	log.Info().Int("count", len(ratings)).Msg("Loaded ratings")
+}
+
+// saveAsync saves ratings to disk asynchronously.
+func (r *RatingsStore) saveAsync() {
+	r.saving.Add(1)
+	go func() {
+		defer r.saving.Done()
+		r.saveMu.Lock()
+		defer r.saveMu.Unlock()
+
+		ratings := r.List(1)
+
+		data, err := json.MarshalIndent(ratings, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal ratings")
+			return
+		}
+
+		if err := atomicfile.Write(r.filePath, data, 0644); err != nil {
+			log.Error().Err(err).Msg("Failed to save ratings")
+		}
+	}()
+}
+
+// SetRating rates a track from 1 to MaxRating stars; 0 clears the rating.
+func (s *Service) SetRating(uri string, rating int) error {
+	if s.ratings == nil {
+		return fmt.Errorf("ratings not available")
+	}
+	if err := s.ratings.Set(uri, rating); err != nil {
+		return err
+	}
+	s.stickers.setRating(uri, rating)
+	return nil
+}
+
+// GetRating returns a track's rating, or 0 if it is unrated.
+func (s *Service) GetRating(uri string) int {
+	if s.ratings == nil {
+		return 0
+	}
+	return s.ratings.Get(uri)
+}
+
+// GetTopRated returns a queue-ready list of tracks rated at least minRating.
+func (s *Service) GetTopRated(minRating, limit int) TopRatedResponse {
+	if minRating <= 0 {
+		minRating = defaultTopRatedMinRating
+	}
+	if minRating > MaxRating {
+		return TopRatedResponse{
+			URIs:      []string{},
+			MinRating: minRating,
+			Error:     fmt.Sprintf("invalid minimum rating %d: must be between 1 and %d", minRating, MaxRating),
+		}
+	}
+	if limit <= 0 {
+		limit = defaultSmartPlaylistLimit
+	}
+
+	var ratings []TrackRating
+	if s.ratings != nil {
+		ratings = s.ratings.List(minRating)
+	}
+	if len(ratings) > limit {
+		ratings = ratings[:limit]
+	}
+
+	uris := make([]string, len(ratings))
+	for i, rating := range ratings {
+		uris[i] = rating.URI
+	}
+
+	return TopRatedResponse{
+		URIs:       uris,
+		TotalCount: len(uris),
+		MinRating:  minRating,
+	}
+}
+
+// syncStickerRatings merges ratings between MPD stickers and the ratings file.
+// Ratings already in the file win over sticker values.
+func (s *Service) syncStickerRatings() {
+	if !s.stickers.enabled() || s.ratings == nil {
+		return
+	}
+
+	stickered := s.stickers.ratings()
+	for uri, rating := range stickered {
+		if s.ratings.Get(uri) == 0 {
+			_ = s.ratings.Set(uri, rating)
+		}
+	}
+
+	for _, rating := range s.ratings.List(1) {
+		if stickered[rating.URI] != rating.Rating {
+			s.stickers.setRating(rating.URI, rating.Rating)
+		}
+	}
+}
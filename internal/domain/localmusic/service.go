@@ -3,11 +3,15 @@ package localmusic
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
 	"path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,14 +25,25 @@ type MPDClient interface {
 	GetAlbumDetails(basePath string) ([]AlbumDetails, error)
 }
 
+// AlbumTrackFinder is implemented by MPD clients that can find an album's
+// tracks by tag, wherever they are stored. It is optional so simpler clients
+// keep satisfying MPDClient; without it an album is read from its directory.
+type AlbumTrackFinder interface {
+	FindAlbumTracks(album, albumArtist string) ([]map[string]string, error)
+}
+
 // AlbumDetails represents album info from MPD database.
 // This is duplicated from mpd package to avoid circular imports.
 type AlbumDetails struct {
-	Album       string
-	AlbumArtist string
-	TrackCount  int
-	FirstTrack  string // Path to first track (for album art)
-	TotalTime   int    // Total duration in seconds
+	Album          string
+	AlbumArtist    string
+	ArtistInferred bool     // AlbumArtist was derived from the track artists
+	TrackArtists   []string // Distinct track artists
+	TrackCount     int
+	FirstTrack     string    // Path to first track (for album art)
+	TotalTime      int       // Total duration in seconds
+	AddedAt        time.Time // When the newest track was added (zero if unknown)
+	IsCompilation  bool      // Various-artists album
 }
 
 // Service provides local music operations.
@@ -36,19 +51,35 @@ type Service struct {
 	mpd         MPDClient
 	classifier  *PathClassifier
 	history     *HistoryStore
+	favorites   *FavoritesStore
+	ratings     *RatingsStore
+	stickers    *stickerStore // nil if the MPD client has no sticker support
+	cacheDB     *cache.DB     // nil to list albums from MPD; see SetCache
+	cacheDAO    *cache.DAO
+	albumTracks *albumTrackCache // nil to always list album tracks from MPD
 	mpdMusicDir string
+
+	playCountsMigrated string // Marker file written once play counts are copied to stickers
 }
 
-// NewService creates a new local music service.
-func NewService(mpd MPDClient, dataDir string, mpdMusicDir string) *Service {
-	classifier := NewPathClassifier(mpdMusicDir)
+// NewService creates a new local music service. Source rules are checked
+// before DefaultSourceRules when classifying paths.
+func NewService(mpd MPDClient, dataDir string, mpdMusicDir string, sourceRules ...SourceRule) *Service {
+	classifier := NewPathClassifier(mpdMusicDir).WithRules(sourceRules)
 	history := NewHistoryStore(dataDir, classifier)
+	favorites := NewFavoritesStore(dataDir, classifier)
 
 	return &Service{
 		mpd:         mpd,
 		classifier:  classifier,
 		history:     history,
+		favorites:   favorites,
+		ratings:     NewRatingsStore(dataDir),
+		stickers:    newStickerStore(mpd),
+		albumTracks: newAlbumTrackCache(DefaultAlbumTrackCacheSize),
 		mpdMusicDir: mpdMusicDir,
+
+		playCountsMigrated: filepath.Join(dataDir, "stickers_playcounts_migrated"),
 	}
 }
 
@@ -58,42 +89,70 @@ func (s *Service) GetClassifier() *PathClassifier {
 }
 
 // GetLocalAlbums returns albums from local sources only (local disk + USB).
-// This uses MPD's database for proper album metadata instead of folder scanning.
+// Albums come from the library cache when it is set and populated, sorted
+// and paged by SQLite; otherwise the whole library is read from MPD's
+// database and sorted and paged in memory.
 func (s *Service) GetLocalAlbums(req GetLocalAlbumsRequest) LocalAlbumsResponse {
-	var albums []Album
-	filteredOut := 0
-
-	// Get albums from INTERNAL (local disk) using MPD database
-	internalAlbums, internalFiltered := s.getAlbumsFromDatabase("INTERNAL", SourceLocal, req.Query)
-	albums = append(albums, internalAlbums...)
-	filteredOut += internalFiltered
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
 
-	// Get albums from USB using MPD database
-	usbAlbums, usbFiltered := s.getAlbumsFromDatabase("USB", SourceUSB, req.Query)
-	albums = append(albums, usbAlbums...)
-	filteredOut += usbFiltered
+	albums, total, fromCache := s.cachedLocalAlbums(req.Query, req.Sort, page, req.Limit)
+	filteredOut := 0
+	if !fromCache {
+		// Get albums from local disk and USB using MPD database
+		for _, sourceType := range []SourceType{SourceLocal, SourceUSB} {
+			for _, basePath := range s.classifier.Prefixes(sourceType) {
+				found, filtered := s.getAlbumsFromDatabase(basePath, sourceType, req.Query)
+				albums = append(albums, found...)
+				filteredOut += filtered
+			}
+		}
 
-	// Sort albums
-	s.sortAlbums(albums, req.Sort)
+		s.sortAlbums(albums, req.Sort)
+		total = len(albums)
+		albums = pageAlbums(albums, page, req.Limit)
+	}
 
-	// Apply limit
-	if req.Limit > 0 && len(albums) > req.Limit {
-		albums = albums[:req.Limit]
+	isFavorite := s.favoriteChecker()
+	for i := range albums {
+		albums[i].IsFavorite = isFavorite(albums[i].URI)
 	}
 
 	log.Info().
 		Int("albumCount", len(albums)).
+		Int("total", total).
 		Int("filteredOut", filteredOut).
 		Str("sort", string(req.Sort)).
+		Bool("fromCache", fromCache).
 		Msg("GetLocalAlbums completed")
 
 	return LocalAlbumsResponse{
 		Albums:      albums,
-		TotalCount:  len(albums),
+		TotalCount:  total,
+		HasMore:     req.Limit > 0 && (page-1)*req.Limit+len(albums) < total,
 		FilteredOut: filteredOut,
 	}
 }
 
+// pageAlbums returns the given page of sorted albums. A limit of 0 returns
+// them all.
+func pageAlbums(albums []Album, page, limit int) []Album {
+	if limit <= 0 {
+		return albums
+	}
+	start := (page - 1) * limit
+	if start >= len(albums) {
+		return []Album{}
+	}
+	end := start + limit
+	if end > len(albums) {
+		end = len(albums)
+	}
+	return albums[start:end]
+}
+
 // getAlbumsFromDatabase retrieves albums from MPD database for a specific base path.
 // This is much faster than recursive directory scanning and returns proper metadata.
 func (s *Service) getAlbumsFromDatabase(basePath string, sourceType SourceType, query string) ([]Album, int) {
@@ -120,9 +179,6 @@ func (s *Service) getAlbumsFromDatabase(basePath string, sourceType SourceType,
 			}
 		}
 
-		// Generate album ID from album name + artist
-		albumID := generateID(details.Album + "\x00" + details.AlbumArtist)
-
 		// Get directory path from first track for album art
 		albumPath := ""
 		if details.FirstTrack != "" {
@@ -130,13 +186,17 @@ func (s *Service) getAlbumsFromDatabase(basePath string, sourceType SourceType,
 		}
 
 		album := Album{
-			ID:         albumID,
-			Title:      details.Album,
-			Artist:     details.AlbumArtist,
-			URI:        albumPath,
-			AlbumArt:   "/albumart?path=" + details.FirstTrack,
-			TrackCount: details.TrackCount,
-			Source:     sourceType,
+			ID:             albumID(details.Album, details.AlbumArtist),
+			Title:          details.Album,
+			Artist:         details.AlbumArtist,
+			ArtistInferred: details.ArtistInferred,
+			TrackArtists:   details.TrackArtists,
+			URI:            albumPath,
+			AlbumArt:       "/albumart?path=" + details.FirstTrack,
+			TrackCount:     details.TrackCount,
+			Source:         sourceType,
+			AddedAt:        details.AddedAt,
+			IsCompilation:  details.IsCompilation,
 		}
 
 		albums = append(albums, album)
@@ -241,6 +301,10 @@ func (s *Service) createAlbumFromDirectory(dirPath string, firstTrack map[string
 	}
 
 	artist := firstTrack["AlbumArtist"]
+	isCompilation := isCompilationDirectory(entries)
+	if artist == "" && isCompilation {
+		artist = cache.VariousArtists
+	}
 	if artist == "" {
 		artist = firstTrack["Artist"]
 	}
@@ -250,11 +314,15 @@ func (s *Service) createAlbumFromDirectory(dirPath string, firstTrack map[string
 		artist = path.Base(parent)
 	}
 
-	// Count tracks
+	// Count tracks and find when the newest one was added
 	trackCount := 0
+	var addedAt time.Time
 	for _, entry := range entries {
 		if file, ok := entry["file"]; ok && isAudioFile(file) {
 			trackCount++
+			if added := cache.TrackAddedAt(entry); added.After(addedAt) {
+				addedAt = added
+			}
 		}
 	}
 
@@ -276,14 +344,36 @@ func (s *Service) createAlbumFromDirectory(dirPath string, firstTrack map[string
 	}
 
 	return Album{
-		ID:         albumID,
-		Title:      albumTitle,
-		Artist:     artist,
-		URI:        dirPath,
-		AlbumArt:   "/albumart?path=" + albumArtPath,
-		TrackCount: trackCount,
-		Source:     sourceType,
+		ID:            albumID,
+		Title:         albumTitle,
+		Artist:        artist,
+		URI:           dirPath,
+		AlbumArt:      "/albumart?path=" + albumArtPath,
+		TrackCount:    trackCount,
+		Source:        sourceType,
+		AddedAt:       addedAt,
+		IsCompilation: isCompilation || strings.EqualFold(artist, cache.VariousArtists),
+	}
+}
+
+// isCompilationDirectory reports whether an album directory holds a compilation:
+// a track carries the Compilation tag, or tracks without an AlbumArtist tag
+// name different artists.
+func isCompilationDirectory(entries []map[string]string) bool {
+	artists := make(map[string]bool)
+	for _, entry := range entries {
+		file, ok := entry["file"]
+		if !ok || !isAudioFile(file) {
+			continue
+		}
+		if entry["Compilation"] == "1" {
+			return true
+		}
+		if entry["AlbumArtist"] == "" && entry["Artist"] != "" {
+			artists[entry["Artist"]] = true
+		}
 	}
+	return len(artists) > 1
 }
 
 // sortAlbums sorts albums by the specified order.
@@ -291,10 +381,10 @@ func (s *Service) sortAlbums(albums []Album, sortOrder AlbumSortOrder) {
 	switch sortOrder {
 	case AlbumSortRecentlyAdded:
 		// Sort by AddedAt descending (most recent first)
-		// Note: If AddedAt is not populated, fall back to alphabetical
+		// Albums with the same or unknown AddedAt fall back to alphabetical
 		sort.Slice(albums, func(i, j int) bool {
-			if albums[i].AddedAt.IsZero() && albums[j].AddedAt.IsZero() {
-				return albums[i].Title < albums[j].Title
+			if albums[i].AddedAt.Equal(albums[j].AddedAt) {
+				return strings.ToLower(albums[i].Title) < strings.ToLower(albums[j].Title)
 			}
 			return albums[i].AddedAt.After(albums[j].AddedAt)
 		})
@@ -319,11 +409,13 @@ func (s *Service) sortAlbums(albums []Album, sortOrder AlbumSortOrder) {
 
 // GetLastPlayedTracks returns the last played tracks from local sources.
 func (s *Service) GetLastPlayedTracks(req GetLastPlayedRequest) LastPlayedResponse {
-	// Get last played from history, filtered to local-only and manual plays only
+	// Get last played from history, filtered to local-only and manual plays only.
+	// With req.Dedupe, repeated plays of a track are grouped with a summed playCount.
 	return s.history.GetLastPlayed(req, true, true)
 }
 
-// GetAlbumTracks returns tracks for a specific album directory.
+// GetAlbumTracks returns the tracks of the album in a directory, including
+// those in sibling directories such as the other discs of a multi-disc rip.
 func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse {
 	if req.AlbumURI == "" {
 		return AlbumTracksResponse{
@@ -331,14 +423,22 @@ func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse
 		}
 	}
 
-	// Get directory contents
-	entries, err := s.mpd.ListInfo(req.AlbumURI)
-	if err != nil {
-		log.Debug().Err(err).Str("uri", req.AlbumURI).Msg("Failed to list album directory")
-		return AlbumTracksResponse{
-			AlbumURI: req.AlbumURI,
-			Error:    "failed to get album tracks: " + err.Error(),
+	// Get the album's songs, from memory if the album was opened recently
+	entries, cached := s.albumTracks.get(req.AlbumURI)
+	if !cached {
+		var err error
+		entries, err = s.mpd.ListInfo(req.AlbumURI)
+		if err != nil {
+			log.Debug().Err(err).Str("uri", req.AlbumURI).Msg("Failed to list album directory")
+			return AlbumTracksResponse{
+				AlbumURI: req.AlbumURI,
+				Error:    "failed to get album tracks: " + err.Error(),
+			}
+		}
+		if found := s.findAlbumTracks(req.AlbumURI, entries); len(found) > 0 {
+			entries = found
 		}
+		s.albumTracks.put(req.AlbumURI, entries)
 	}
 
 	// Determine source type from album URI
@@ -346,23 +446,16 @@ func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse
 
 	// Extract tracks from entries
 	var tracks []Track
+	isFavorite := s.favoriteChecker()
 	for _, entry := range entries {
 		file, isFile := entry["file"]
 		if !isFile || !isAudioFile(file) {
 			continue
 		}
 
-		// Parse track number
-		trackNum := 0
-		if tn, ok := entry["Track"]; ok {
-			// Track can be "1" or "1/12"
-			if idx := strings.Index(tn, "/"); idx > 0 {
-				tn = tn[:idx]
-			}
-			if n, err := parseInt(tn); err == nil {
-				trackNum = n
-			}
-		}
+		// Parse track and disc number
+		trackNum := cache.ParseNumberTag(entry["Track"])
+		discNum := cache.DiscNumber(entry)
 
 		// Parse duration
 		duration := 0
@@ -393,16 +486,22 @@ func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse
 			Album:       entry["Album"],
 			URI:         file,
 			TrackNumber: trackNum,
+			DiscNumber:  discNum,
 			Duration:    duration,
 			AlbumArt:    "/albumart?path=" + file,
 			Source:      sourceType,
+			IsFavorite:  isFavorite(file),
+			Rating:      s.GetRating(file),
 		}
 
 		tracks = append(tracks, track)
 	}
 
-	// Sort by track number
+	// Sort by disc, then track number
 	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].DiscNumber != tracks[j].DiscNumber {
+			return tracks[i].DiscNumber < tracks[j].DiscNumber
+		}
 		if tracks[i].TrackNumber != tracks[j].TrackNumber {
 			return tracks[i].TrackNumber < tracks[j].TrackNumber
 		}
@@ -412,6 +511,7 @@ func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse
 	log.Info().
 		Str("albumUri", req.AlbumURI).
 		Int("trackCount", len(tracks)).
+		Bool("cached", cached).
 		Msg("GetAlbumTracks completed")
 
 	return AlbumTracksResponse{
@@ -421,9 +521,84 @@ func (s *Service) GetAlbumTracks(req GetAlbumTracksRequest) AlbumTracksResponse
 	}
 }
 
+// findAlbumTracks looks up all tracks of the album in albumURI by its Album
+// and AlbumArtist tags, so discs kept in separate directories are included.
+// Matches from other sources are dropped, as they are another copy of the
+// album. Without an AlbumArtist tag the title alone is too loose, so only
+// untagged tracks in albumURI or a directory beside it are kept. Tracks in
+// albumURI itself are always kept. It returns nil if the tracks cannot be
+// looked up.
+func (s *Service) findAlbumTracks(albumURI string, entries []map[string]string) []map[string]string {
+	finder, ok := s.mpd.(AlbumTrackFinder)
+	if !ok {
+		return nil
+	}
+
+	var album, albumArtist string
+	for _, entry := range entries {
+		if file, isFile := entry["file"]; isFile && isAudioFile(file) {
+			album, albumArtist = entry["Album"], entry["AlbumArtist"]
+			break
+		}
+	}
+	if album == "" {
+		return nil
+	}
+
+	found, err := finder.FindAlbumTracks(album, albumArtist)
+	if err != nil {
+		log.Debug().Err(err).Str("album", album).Str("albumArtist", albumArtist).Msg("Failed to find album tracks, using directory")
+		return nil
+	}
+
+	source := s.classifier.GetSourceType(albumURI)
+	parent := path.Dir(albumURI)
+	seen := make(map[string]bool)
+	var tracks []map[string]string
+	for _, track := range found {
+		file := track["file"]
+		if !isAudioFile(file) || s.classifier.GetSourceType(file) != source {
+			continue
+		}
+		if albumArtist == "" {
+			dir := path.Dir(file)
+			if track["AlbumArtist"] != "" || (dir != albumURI && path.Dir(dir) != parent) {
+				continue
+			}
+		}
+		seen[file] = true
+		tracks = append(tracks, track)
+	}
+
+	// Keep the directory's own tracks even if they are tagged differently
+	for _, entry := range entries {
+		if file, isFile := entry["file"]; isFile && isAudioFile(file) && !seen[file] {
+			tracks = append(tracks, entry)
+		}
+	}
+	return tracks
+}
+
+// InvalidateAlbumTracks forgets the album track listings kept in memory. It
+// should be called when MPD's database changes.
+func (s *Service) InvalidateAlbumTracks() {
+	s.albumTracks.clear()
+}
+
 // RecordTrackPlay records a track play event.
 func (s *Service) RecordTrackPlay(trackURI, title, artist, album, albumArt string, origin PlayOrigin) {
 	s.history.RecordPlay(trackURI, title, artist, album, albumArt, origin)
+	s.stickers.incrementPlayCount(trackURI)
+}
+
+// GetPlayCount returns how many times a track has been played. MPD stickers
+// are preferred since they outlive the capped history; the history is used
+// when stickers are unavailable.
+func (s *Service) GetPlayCount(trackURI string) int {
+	if count, ok := s.stickers.playCount(trackURI); ok {
+		return count
+	}
+	return s.history.GetPlayCount(trackURI)
 }
 
 // GetSourceType returns the source type for a URI.
@@ -436,6 +611,12 @@ func (s *Service) IsLocalSource(uri string) bool {
 	return s.classifier.IsLocalPath(uri)
 }
 
+// SetHistorySize sets how many play history entries are kept, evicting the
+// oldest beyond it. Values below 1 restore DefaultHistorySize.
+func (s *Service) SetHistorySize(n int) {
+	s.history.SetMaxEntries(n)
+}
+
 // GetHistoryStats returns playback history statistics.
 func (s *Service) GetHistoryStats() map[string]interface{} {
 	return s.history.Stats()
@@ -446,11 +627,135 @@ func (s *Service) ClearHistory() {
 	s.history.ClearHistory()
 }
 
+// AddFavorite marks a track or album URI as a favorite. Track favorites are
+// stored as MPD stickers; album favorites, and track favorites while stickers
+// are unavailable, go to the favorites file.
+func (s *Service) AddFavorite(uri string, favType FavoriteType) error {
+	if favType == FavoriteTrack && uri != "" {
+		if err := s.stickers.addFavorite(uri, time.Now()); err == nil {
+			log.Info().Str("uri", uri).Msg("Added favorite sticker")
+			return nil
+		}
+	}
+	if s.favorites == nil {
+		return fmt.Errorf("favorites not available")
+	}
+	return s.favorites.Add(uri, favType)
+}
+
+// ImportFavorites adds favorites exported from another device, skipping
+// ones already present, and returns how many were added.
+func (s *Service) ImportFavorites(favorites []Favorite) int {
+	stickered := s.stickers.favorites()
+	added := 0
+	var local []Favorite
+	for _, fav := range favorites {
+		if fav.Type == FavoriteTrack && fav.URI != "" && stickered != nil {
+			if _, exists := stickered[fav.URI]; exists {
+				continue
+			}
+			addedAt := fav.AddedAt
+			if addedAt.IsZero() {
+				addedAt = time.Now()
+			}
+			if err := s.stickers.addFavorite(fav.URI, addedAt); err == nil {
+				added++
+				continue
+			}
+		}
+		local = append(local, fav)
+	}
+	if s.favorites != nil {
+		added += len(s.favorites.Import(local))
+	}
+	return added
+}
+
+// RemoveFavorite unmarks a favorite track or album URI.
+func (s *Service) RemoveFavorite(uri string) {
+	if s.favorites != nil {
+		s.favorites.Remove(uri)
+	}
+	// Stickers only attach to songs; album favorites are directories
+	if isAudioFile(uri) {
+		s.stickers.removeFavorite(uri)
+	}
+}
+
+// ClearFavorites removes every favorite track and album.
+func (s *Service) ClearFavorites() {
+	for _, fav := range s.ListFavorites("").Favorites {
+		s.RemoveFavorite(fav.URI)
+	}
+	log.Info().Msg("Favorites cleared")
+}
+
+// IsFavorite returns true if the track or album URI is a favorite.
+func (s *Service) IsFavorite(uri string) bool {
+	if s.favorites != nil && s.favorites.Contains(uri) {
+		return true
+	}
+	return isAudioFile(uri) && s.stickers.isFavorite(uri)
+}
+
+// favoriteChecker returns a function reporting whether a URI is a favorite,
+// fetching the sticker favorites once for checking many URIs.
+func (s *Service) favoriteChecker() func(uri string) bool {
+	stickered := s.stickers.favorites()
+	return func(uri string) bool {
+		if _, ok := stickered[uri]; ok {
+			return true
+		}
+		return s.favorites != nil && s.favorites.Contains(uri)
+	}
+}
+
+// ListFavorites returns favorites of the given type, or all favorites if
+// favType is empty, most recently added first.
+func (s *Service) ListFavorites(favType FavoriteType) FavoritesResponse {
+	favorites := []Favorite{}
+	var stickered map[string]time.Time
+	if favType == "" || favType == FavoriteTrack {
+		stickered = s.stickers.favorites()
+		for uri, addedAt := range stickered {
+			favorites = append(favorites, Favorite{
+				URI:     uri,
+				Type:    FavoriteTrack,
+				Source:  s.classifier.GetSourceType(uri),
+				AddedAt: addedAt,
+			})
+		}
+	}
+	if s.favorites != nil {
+		for _, fav := range s.favorites.List(favType) {
+			if _, ok := stickered[fav.URI]; !ok {
+				favorites = append(favorites, fav)
+			}
+		}
+	}
+
+	sort.SliceStable(favorites, func(i, j int) bool {
+		return favorites[i].AddedAt.After(favorites[j].AddedAt)
+	})
+
+	return FavoritesResponse{
+		Favorites:  favorites,
+		TotalCount: len(favorites),
+		Type:       favType,
+	}
+}
+
 // RefreshMountCache refreshes the mount point cache.
 func (s *Service) RefreshMountCache() {
 	s.classifier.RefreshMountCache()
 }
 
+// albumID returns the ID of an album, the same whether it was read from
+// MPD or the cache.
+func albumID(title, artist string) string {
+	return generateID(title + "\x00" + artist)
+}
+
 // generateID generates a unique ID from a string.
 func generateID(input string) string {
 	hash := md5.Sum([]byte(input))
@@ -2,7 +2,15 @@ package localmusic
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
 )
 
 func TestSourceType_IsLocalSource(t *testing.T) {
@@ -70,6 +78,127 @@ func TestPathClassifier_GetSourceType(t *testing.T) {
 	}
 }
 
+func TestPathClassifier_WithRules(t *testing.T) {
+	rules, err := ParseSourceRules(" /Network/Music/ = nas, Stick=usb,,Disk=local")
+	if err != nil {
+		t.Fatalf("ParseSourceRules() error = %v", err)
+	}
+	want := []SourceRule{
+		{Prefix: "Network/Music", Source: SourceNAS},
+		{Prefix: "Stick", Source: SourceUSB},
+		{Prefix: "Disk", Source: SourceLocal},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("ParseSourceRules() = %+v, want %+v", rules, want)
+	}
+
+	classifier := NewPathClassifier("/var/lib/mpd/music").WithRules(rules)
+
+	tests := []struct {
+		uri      string
+		expected SourceType
+	}{
+		{"Network/Music/Artist/Album/track.flac", SourceNAS},
+		{"music-library/Network/Music/track.flac", SourceNAS},
+		{"Network/Other/track.flac", SourceLocal},
+		{"Stick/Album/track.flac", SourceUSB},
+		{"Sticker/Album/track.flac", SourceLocal},
+		{"Disk/Album/track.flac", SourceLocal},
+		// Default rules still apply
+		{"NAS/Server/track.flac", SourceNAS},
+		{"USB/Drive/track.flac", SourceUSB},
+	}
+	for _, tt := range tests {
+		if got := classifier.GetSourceType(tt.uri); got != tt.expected {
+			t.Errorf("GetSourceType(%q) = %v, want %v", tt.uri, got, tt.expected)
+		}
+	}
+
+	if got, want := classifier.Prefixes(SourceLocal, SourceUSB), []string{"Disk", "INTERNAL", "Stick", "USB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Prefixes() = %v, want %v", got, want)
+	}
+
+	// A configured rule shadows the default for the same directory
+	service := NewService(&MockMPDClient{}, t.TempDir(), "/var/lib/mpd/music", SourceRule{Prefix: "USB", Source: SourceNAS})
+	shadowed := service.GetClassifier()
+	if got := shadowed.Prefixes(SourceUSB); len(got) != 0 {
+		t.Errorf("Prefixes(usb) with USB=nas = %v, want none", got)
+	}
+	if got := shadowed.GetSourceType("USB/Drive/track.flac"); got != SourceNAS {
+		t.Errorf("GetSourceType() with USB=nas = %v, want nas", got)
+	}
+}
+
+func TestPathClassifier_WithLinkTargets(t *testing.T) {
+	root := t.TempDir()
+	musicDir := filepath.Join(root, "music")
+	nasBase := filepath.Join(root, "mnt", "NAS")
+	for _, dir := range []string{
+		filepath.Join(musicDir, "INTERNAL", "Album"),
+		filepath.Join(musicDir, "NAS"),
+		filepath.Join(nasBase, "Office", "Album"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		// Linked straight from the mount, outside the NAS directory
+		filepath.Join(musicDir, "INTERNAL", "Network"): filepath.Join(nasBase, "Office"),
+		// Linked relative to a share link made by the sources service
+		filepath.Join(musicDir, "NAS", "Office"):        filepath.Join(nasBase, "Office"),
+		filepath.Join(musicDir, "INTERNAL", "Shortcut"): "../NAS/Office",
+		// A loop never resolves
+		filepath.Join(musicDir, "INTERNAL", "Loop"):  "Loop2",
+		filepath.Join(musicDir, "INTERNAL", "Loop2"): "Loop",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uri := "INTERNAL/Network/Album/track.flac"
+	if got := NewPathClassifier(musicDir).GetSourceType(uri); got != SourceLocal {
+		t.Fatalf("without link targets GetSourceType(%q) = %v, want local", uri, got)
+	}
+
+	classifier := NewPathClassifier(musicDir).WithLinkTargets(map[string]SourceType{nasBase: SourceNAS})
+	tests := []struct {
+		uri      string
+		expected SourceType
+	}{
+		{uri, SourceNAS},
+		{"music-library/INTERNAL/Network/Album/track.flac", SourceNAS},
+		{"INTERNAL/Shortcut/Album/track.flac", SourceNAS},
+		{"NAS/Office/Album/track.flac", SourceNAS},
+		{"INTERNAL/Album/track.flac", SourceLocal},
+		{"INTERNAL/Loop/track.flac", SourceLocal},
+		{"INTERNAL/Missing/track.flac", SourceLocal},
+	}
+	for _, tt := range tests {
+		if got := classifier.GetSourceType(tt.uri); got != tt.expected {
+			t.Errorf("GetSourceType(%q) = %v, want %v", tt.uri, got, tt.expected)
+		}
+	}
+
+	local, filtered := classifier.FilterLocalOnly([]string{uri, "INTERNAL/Album/track.flac"})
+	if filtered != 1 || !reflect.DeepEqual(local, []string{"INTERNAL/Album/track.flac"}) {
+		t.Errorf("FilterLocalOnly() = %v, %d filtered, want only the local track", local, filtered)
+	}
+}
+
+func TestParseSourceRules_Errors(t *testing.T) {
+	for _, spec := range []string{"Network", "=nas", "/=usb", "Network=streaming", "Network=cloud"} {
+		if _, err := ParseSourceRules(spec); err == nil {
+			t.Errorf("ParseSourceRules(%q) expected error", spec)
+		}
+	}
+	if rules, err := ParseSourceRules(""); err != nil || len(rules) != 0 {
+		t.Errorf("ParseSourceRules(\"\") = %v, %v, want no rules", rules, err)
+	}
+}
+
 func TestPathClassifier_IsLocalPath(t *testing.T) {
 	classifier := NewPathClassifier("/var/lib/mpd/music")
 
@@ -325,6 +454,259 @@ func TestService_GetLocalAlbums_WithQuery(t *testing.T) {
 	}
 }
 
+func TestService_GetLocalAlbums_RecentlyAdded(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsResp: map[string][]AlbumDetails{
+			"INTERNAL": {
+				{Album: "Old", AlbumArtist: "A", FirstTrack: "INTERNAL/Old/01.flac", AddedAt: base},
+				{Album: "Newest", AlbumArtist: "B", FirstTrack: "INTERNAL/Newest/01.flac", AddedAt: base.Add(48 * time.Hour)},
+				{Album: "Unknown", AlbumArtist: "C", FirstTrack: "INTERNAL/Unknown/01.flac"},
+			},
+			"USB": {
+				{Album: "Middle", AlbumArtist: "D", FirstTrack: "USB/stick/Middle/01.flac", AddedAt: base.Add(24 * time.Hour)},
+			},
+		},
+	}
+
+	service := &Service{
+		mpd:        mockMPD,
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+
+	resp := service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortRecentlyAdded})
+
+	want := []string{"Newest", "Middle", "Old", "Unknown"}
+	var got []string
+	for _, album := range resp.Albums {
+		got = append(got, album.Title)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("album order = %v, want %v", got, want)
+	}
+	if !resp.Albums[0].AddedAt.Equal(base.Add(48 * time.Hour)) {
+		t.Errorf("AddedAt = %v, want %v", resp.Albums[0].AddedAt, base.Add(48*time.Hour))
+	}
+}
+
+func TestService_GetLocalAlbums_Paging(t *testing.T) {
+	var details []AlbumDetails
+	for _, title := range []string{"E", "C", "A", "D", "B"} {
+		details = append(details, AlbumDetails{Album: title, AlbumArtist: "X", FirstTrack: "INTERNAL/" + title + "/01.flac"})
+	}
+	service := &Service{
+		mpd:        &MockMPDClient{GetAlbumDetailsResp: map[string][]AlbumDetails{"INTERNAL": details}},
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+
+	resp := service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortAlphabetical, Page: 2, Limit: 2})
+	if len(resp.Albums) != 2 || resp.Albums[0].Title != "C" || resp.Albums[1].Title != "D" {
+		t.Fatalf("page 2 = %+v, want C, D", resp.Albums)
+	}
+	if resp.TotalCount != 5 || !resp.HasMore {
+		t.Errorf("TotalCount = %d, HasMore = %v, want 5, true", resp.TotalCount, resp.HasMore)
+	}
+
+	resp = service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortAlphabetical, Page: 3, Limit: 2})
+	if len(resp.Albums) != 1 || resp.HasMore {
+		t.Errorf("page 3 = %d albums, HasMore = %v, want 1, false", len(resp.Albums), resp.HasMore)
+	}
+}
+
+// openCache opens a library cache holding the given albums.
+func openCache(tb testing.TB, albums []*cache.CachedAlbum) *cache.DB {
+	tb.Helper()
+	db := cache.NewDB(filepath.Join(tb.TempDir(), "library.db"))
+	if err := db.Open(); err != nil {
+		tb.Fatalf("Failed to open cache: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	if _, err := cache.NewDAO(db).BulkInsert(cache.BulkData{Albums: albums}, nil); err != nil {
+		tb.Fatalf("Failed to fill cache: %v", err)
+	}
+	return db
+}
+
+func TestService_GetLocalAlbums_FromCache(t *testing.T) {
+	db := openCache(t, []*cache.CachedAlbum{
+		{ID: "1", Title: "Beta", AlbumArtist: "X", URI: "INTERNAL/Beta", FirstTrack: "INTERNAL/Beta/01.flac", Source: "local"},
+		{ID: "2", Title: "Alpha", AlbumArtist: "Y", URI: "USB/stick/Alpha", FirstTrack: "USB/stick/Alpha/01.flac", Source: "usb"},
+		{ID: "3", Title: "Gamma", AlbumArtist: "Z", URI: "INTERNAL/Gamma", FirstTrack: "INTERNAL/Gamma/01.flac", Source: "local"},
+		{ID: "4", Title: "Aardvark", AlbumArtist: "N", URI: "NAS/share/Aardvark", FirstTrack: "NAS/share/Aardvark/01.flac", Source: "nas"},
+	})
+	mockMPD := &MockMPDClient{GetAlbumDetailsError: fmt.Errorf("MPD should not be queried")}
+	service := &Service{mpd: mockMPD, classifier: NewPathClassifier("/var/lib/mpd/music")}
+	service.SetCache(db)
+
+	resp := service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortAlphabetical, Page: 1, Limit: 2})
+	if len(resp.Albums) != 2 || resp.Albums[0].Title != "Alpha" || resp.Albums[1].Title != "Beta" {
+		t.Fatalf("page 1 = %+v, want Alpha, Beta", resp.Albums)
+	}
+	if resp.TotalCount != 3 || !resp.HasMore {
+		t.Errorf("TotalCount = %d, HasMore = %v, want 3, true", resp.TotalCount, resp.HasMore)
+	}
+	if resp.Albums[0].Source != SourceUSB || resp.Albums[0].ID != albumID("Alpha", "Y") {
+		t.Errorf("Alpha = %+v, want USB source and the MPD-derived ID", resp.Albums[0])
+	}
+}
+
+func TestService_GetLocalAlbums_EmptyCacheFallsBackToMPD(t *testing.T) {
+	mockMPD := &MockMPDClient{GetAlbumDetailsResp: map[string][]AlbumDetails{
+		"INTERNAL": {{Album: "Album1", AlbumArtist: "Artist1", FirstTrack: "INTERNAL/Album1/01.flac"}},
+	}}
+	service := &Service{mpd: mockMPD, classifier: NewPathClassifier("/var/lib/mpd/music")}
+	service.SetCache(openCache(t, nil))
+
+	resp := service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortAlphabetical})
+	if len(resp.Albums) != 1 || resp.Albums[0].Title != "Album1" {
+		t.Errorf("Albums = %+v, want Album1 from MPD", resp.Albums)
+	}
+}
+
+const benchAlbumCount = 10000
+
+func benchmarkGetLocalAlbums(b *testing.B, service *Service) {
+	req := GetLocalAlbumsRequest{Sort: AlbumSortAlphabetical, Page: 3, Limit: 50}
+	for b.Loop() {
+		if resp := service.GetLocalAlbums(req); len(resp.Albums) != 50 {
+			b.Fatalf("got %d albums, want 50", len(resp.Albums))
+		}
+	}
+}
+
+func BenchmarkGetLocalAlbums_MPD(b *testing.B) {
+	details := make([]AlbumDetails, benchAlbumCount)
+	for i := range details {
+		details[i] = AlbumDetails{
+			Album:       fmt.Sprintf("Album %d", i),
+			AlbumArtist: fmt.Sprintf("Artist %d", i%500),
+			TrackCount:  10,
+			FirstTrack:  fmt.Sprintf("INTERNAL/Album %d/01.flac", i),
+		}
+	}
+	service := &Service{
+		mpd:        &MockMPDClient{GetAlbumDetailsResp: map[string][]AlbumDetails{"INTERNAL": details}},
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+	benchmarkGetLocalAlbums(b, service)
+}
+
+func BenchmarkGetLocalAlbums_Cache(b *testing.B) {
+	albums := make([]*cache.CachedAlbum, benchAlbumCount)
+	for i := range albums {
+		albums[i] = &cache.CachedAlbum{
+			ID:          fmt.Sprintf("album-%d", i),
+			Title:       fmt.Sprintf("Album %d", i),
+			AlbumArtist: fmt.Sprintf("Artist %d", i%500),
+			URI:         fmt.Sprintf("INTERNAL/Album %d", i),
+			FirstTrack:  fmt.Sprintf("INTERNAL/Album %d/01.flac", i),
+			TrackCount:  10,
+			Source:      "local",
+		}
+	}
+	service := &Service{mpd: &MockMPDClient{}, classifier: NewPathClassifier("/var/lib/mpd/music")}
+	service.SetCache(openCache(b, albums))
+	benchmarkGetLocalAlbums(b, service)
+}
+
+func TestService_GetLocalAlbums_RecentlyAddedDirectoryFallback(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsError: fmt.Errorf("database unavailable"),
+		ListInfoResponse: map[string][]map[string]string{
+			"INTERNAL": {
+				{"directory": "INTERNAL/Older"},
+				{"directory": "INTERNAL/Newer"},
+			},
+			"INTERNAL/Older": {
+				{"file": "INTERNAL/Older/01.flac", "Album": "Older", "Last-Modified": "2024-01-01T00:00:00Z"},
+			},
+			"INTERNAL/Newer": {
+				{"file": "INTERNAL/Newer/01.flac", "Album": "Newer", "Last-Modified": "2024-06-01T00:00:00Z"},
+			},
+		},
+	}
+
+	service := &Service{
+		mpd:        mockMPD,
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+
+	resp := service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortRecentlyAdded})
+
+	if len(resp.Albums) != 2 {
+		t.Fatalf("Expected 2 albums, got %d", len(resp.Albums))
+	}
+	if resp.Albums[0].Title != "Newer" || resp.Albums[1].Title != "Older" {
+		t.Errorf("album order = [%s %s], want [Newer Older]", resp.Albums[0].Title, resp.Albums[1].Title)
+	}
+}
+
+func TestService_GetLocalAlbums_Compilation(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsResp: map[string][]AlbumDetails{
+			"INTERNAL": {
+				{Album: "Now 42", AlbumArtist: cache.VariousArtists, TrackCount: 3, FirstTrack: "INTERNAL/Now 42/01.flac", IsCompilation: true},
+				{Album: "Solo", AlbumArtist: "Singer", TrackCount: 1, FirstTrack: "INTERNAL/Solo/01.flac"},
+			},
+		},
+	}
+
+	service := &Service{
+		mpd:        mockMPD,
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+
+	resp := service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortAlphabetical})
+
+	if len(resp.Albums) != 2 {
+		t.Fatalf("Expected 2 albums, got %d", len(resp.Albums))
+	}
+	if !resp.Albums[0].IsCompilation || resp.Albums[0].Artist != cache.VariousArtists {
+		t.Errorf("Expected 'Now 42' to be a Various Artists compilation, got %+v", resp.Albums[0])
+	}
+	if resp.Albums[1].IsCompilation {
+		t.Errorf("Expected 'Solo' not to be a compilation")
+	}
+}
+
+func TestService_GetLocalAlbums_CompilationDirectoryFallback(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		GetAlbumDetailsError: fmt.Errorf("database unavailable"),
+		ListInfoResponse: map[string][]map[string]string{
+			"INTERNAL": {
+				{"directory": "INTERNAL/Mixed"},
+				{"directory": "INTERNAL/Band/Album"},
+			},
+			"INTERNAL/Mixed": {
+				{"file": "INTERNAL/Mixed/01.flac", "Album": "Mixed", "Artist": "Artist A"},
+				{"file": "INTERNAL/Mixed/02.flac", "Album": "Mixed", "Artist": "Artist B"},
+			},
+			"INTERNAL/Band/Album": {
+				{"file": "INTERNAL/Band/Album/01.flac", "Album": "Album", "Artist": "Band"},
+				{"file": "INTERNAL/Band/Album/02.flac", "Album": "Album", "Artist": "Band"},
+			},
+		},
+	}
+
+	service := &Service{
+		mpd:        mockMPD,
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+
+	resp := service.GetLocalAlbums(GetLocalAlbumsRequest{Sort: AlbumSortAlphabetical})
+
+	if len(resp.Albums) != 2 {
+		t.Fatalf("Expected 2 albums, got %d", len(resp.Albums))
+	}
+	regular, mixed := resp.Albums[0], resp.Albums[1]
+	if !mixed.IsCompilation || mixed.Artist != cache.VariousArtists || mixed.TrackCount != 2 {
+		t.Errorf("Expected mixed-artist album grouped under Various Artists, got %+v", mixed)
+	}
+	if regular.IsCompilation || regular.Artist != "Band" {
+		t.Errorf("Expected single-artist album by 'Band', got %+v", regular)
+	}
+}
+
 func TestSortAlbums(t *testing.T) {
 	service := &Service{
 		classifier: NewPathClassifier("/var/lib/mpd/music"),
@@ -508,6 +890,112 @@ func TestService_GetAlbumTracks_TrackNumberFormats(t *testing.T) {
 	}
 }
 
+func TestService_GetAlbumTracks_MultiDisc(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		ListInfoResponse: map[string][]map[string]string{
+			"INTERNAL/Artist/Double Album": {
+				{"file": "INTERNAL/Artist/Double Album/2-01.flac", "Title": "Disc Two Opener", "Track": "1", "Disc": "2/2"},
+				{"file": "INTERNAL/Artist/Double Album/1-12.flac", "Title": "Disc One Closer", "Track": "12/12", "Disc": "1/2"},
+				{"file": "INTERNAL/Artist/Double Album/1-01.flac", "Title": "Disc One Opener", "Track": "1", "disc": "1"},
+				{"file": "INTERNAL/Artist/Double Album/2-02.flac", "Title": "Disc Two Second", "Track": "2", "DiscNumber": "2"},
+			},
+		},
+	}
+
+	service := &Service{
+		mpd:        mockMPD,
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+
+	resp := service.GetAlbumTracks(GetAlbumTracksRequest{AlbumURI: "INTERNAL/Artist/Double Album"})
+
+	want := []struct {
+		title string
+		disc  int
+		track int
+	}{
+		{"Disc One Opener", 1, 1},
+		{"Disc One Closer", 1, 12},
+		{"Disc Two Opener", 2, 1},
+		{"Disc Two Second", 2, 2},
+	}
+	if len(resp.Tracks) != len(want) {
+		t.Fatalf("Expected %d tracks, got %d", len(want), len(resp.Tracks))
+	}
+	for i, w := range want {
+		got := resp.Tracks[i]
+		if got.Title != w.title || got.DiscNumber != w.disc || got.TrackNumber != w.track {
+			t.Errorf("Tracks[%d] = {%q disc %d track %d}, want {%q disc %d track %d}",
+				i, got.Title, got.DiscNumber, got.TrackNumber, w.title, w.disc, w.track)
+		}
+	}
+}
+
+// finderMPDClient adds AlbumTrackFinder support to MockMPDClient.
+type finderMPDClient struct {
+	MockMPDClient
+	Albums map[string][]map[string]string // Keyed by album and album artist, joined by "|"
+}
+
+func (m *finderMPDClient) FindAlbumTracks(album, albumArtist string) ([]map[string]string, error) {
+	return m.Albums[album+"|"+albumArtist], nil
+}
+
+func TestService_GetAlbumTracks_SplitAcrossDirectories(t *testing.T) {
+	disc1 := []map[string]string{
+		{"file": "INTERNAL/Artist/Set/Disc 1/01.flac", "Title": "One", "Track": "1", "Disc": "1", "Album": "Set", "AlbumArtist": "Artist"},
+		{"file": "INTERNAL/Artist/Set/Disc 1/02.flac", "Title": "Two", "Track": "2", "Disc": "1", "Album": "Set", "AlbumArtist": "Artist"},
+	}
+	disc2 := []map[string]string{
+		{"file": "INTERNAL/Artist/Set/Disc 2/01.flac", "Title": "Three", "Track": "1", "Disc": "2", "Album": "Set", "AlbumArtist": "Artist"},
+	}
+	untagged := []map[string]string{
+		{"file": "INTERNAL/Band/Live/CD1/01.flac", "Title": "Intro", "Track": "1", "Disc": "1", "Album": "Live"},
+	}
+	mockMPD := &finderMPDClient{
+		MockMPDClient: MockMPDClient{
+			ListInfoResponse: map[string][]map[string]string{
+				"INTERNAL/Artist/Set/Disc 1": disc1,
+				"INTERNAL/Band/Live/CD1":     untagged,
+			},
+		},
+		Albums: map[string][]map[string]string{
+			// A copy of the album on USB is another album, not more discs
+			"Set|Artist": append(append(slices.Clone(disc1), disc2...),
+				map[string]string{"file": "USB/Stick/Set/01.flac", "Title": "One", "Album": "Set", "AlbumArtist": "Artist"}),
+			// Without an album artist, only the album's own directories count
+			"Live|": {
+				untagged[0],
+				{"file": "INTERNAL/Band/Live/CD2/01.flac", "Title": "Encore", "Track": "1", "Disc": "2", "Album": "Live"},
+				{"file": "INTERNAL/Other/Live/01.flac", "Title": "Elsewhere", "Album": "Live"},
+				{"file": "INTERNAL/Band/Live/Bonus/01.flac", "Title": "Tagged", "Album": "Live", "AlbumArtist": "Someone"},
+			},
+		},
+	}
+	service := &Service{
+		mpd:        mockMPD,
+		classifier: NewPathClassifier("/var/lib/mpd/music"),
+	}
+
+	tests := []struct {
+		albumURI string
+		want     []string
+	}{
+		{"INTERNAL/Artist/Set/Disc 1", []string{"One", "Two", "Three"}},
+		{"INTERNAL/Band/Live/CD1", []string{"Intro", "Encore"}},
+	}
+	for _, tt := range tests {
+		resp := service.GetAlbumTracks(GetAlbumTracksRequest{AlbumURI: tt.albumURI})
+		var titles []string
+		for _, track := range resp.Tracks {
+			titles = append(titles, track.Title)
+		}
+		if !slices.Equal(titles, tt.want) || resp.TotalCount != len(tt.want) {
+			t.Errorf("GetAlbumTracks(%q) = %q (total %d), want %q", tt.albumURI, titles, resp.TotalCount, tt.want)
+		}
+	}
+}
+
 func TestService_GetAlbumTracks_MissingMetadata(t *testing.T) {
 	mockMPD := &MockMPDClient{
 		ListInfoResponse: map[string][]map[string]string{
@@ -641,3 +1129,803 @@ func TestService_GetAlbumTracks_AlbumArt(t *testing.T) {
 		}
 	}
 }
+
+// newHistoryTestService creates a service whose history holds the given plays,
+// oldest first, one minute apart.
+// countingMPDClient counts ListInfo calls.
+type countingMPDClient struct {
+	MockMPDClient
+	listInfoCalls int
+}
+
+func (m *countingMPDClient) ListInfo(uri string) ([]map[string]string, error) {
+	m.listInfoCalls++
+	return m.MockMPDClient.ListInfo(uri)
+}
+
+func TestService_GetAlbumTracks_Cached(t *testing.T) {
+	mockMPD := &countingMPDClient{MockMPDClient: MockMPDClient{
+		ListInfoResponse: map[string][]map[string]string{
+			"INTERNAL/Album": {{"file": "INTERNAL/Album/01.flac", "Title": "One", "Track": "1"}},
+		},
+	}}
+	service := NewService(mockMPD, t.TempDir(), "/var/lib/mpd/music")
+	defer service.favorites.saving.Wait()
+	req := GetAlbumTracksRequest{AlbumURI: "INTERNAL/Album"}
+
+	service.GetAlbumTracks(req)
+	if err := service.AddFavorite("INTERNAL/Album/01.flac", FavoriteTrack); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+	resp := service.GetAlbumTracks(req)
+	if mockMPD.listInfoCalls != 1 {
+		t.Errorf("ListInfo called %d times, want 1", mockMPD.listInfoCalls)
+	}
+	if len(resp.Tracks) != 1 || !resp.Tracks[0].IsFavorite {
+		t.Errorf("cached tracks = %+v, want one favorite track", resp.Tracks)
+	}
+
+	service.InvalidateAlbumTracks()
+	service.GetAlbumTracks(req)
+	if mockMPD.listInfoCalls != 2 {
+		t.Errorf("ListInfo called %d times after invalidation, want 2", mockMPD.listInfoCalls)
+	}
+}
+
+func TestService_GetAlbumTracks_ErrorNotCached(t *testing.T) {
+	mockMPD := &MockMPDClient{ListInfoError: fmt.Errorf("connection refused")}
+	service := &Service{
+		mpd:         mockMPD,
+		classifier:  NewPathClassifier("/var/lib/mpd/music"),
+		albumTracks: newAlbumTrackCache(4),
+	}
+
+	service.GetAlbumTracks(GetAlbumTracksRequest{AlbumURI: "INTERNAL/Album"})
+	if n := service.albumTracks.size(); n != 0 {
+		t.Errorf("cached %d albums after a failed listing, want 0", n)
+	}
+}
+
+func TestAlbumTrackCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newAlbumTrackCache(2)
+	c.put("a", []map[string]string{{"file": "a/1.flac"}})
+	c.put("b", []map[string]string{{"file": "b/1.flac"}})
+	c.get("a")
+	c.put("c", []map[string]string{{"file": "c/1.flac"}})
+
+	if _, ok := c.get("b"); ok {
+		t.Error("least recently used album was not evicted")
+	}
+	for _, uri := range []string{"a", "c"} {
+		if _, ok := c.get(uri); !ok {
+			t.Errorf("album %q was evicted", uri)
+		}
+	}
+
+	c.clear()
+	if n := c.size(); n != 0 {
+		t.Errorf("size after clear = %d, want 0", n)
+	}
+}
+
+func TestAlbumTrackCache_Concurrent(t *testing.T) {
+	c := newAlbumTrackCache(8)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				uri := fmt.Sprintf("album-%d", (i+j)%16)
+				if _, ok := c.get(uri); !ok {
+					c.put(uri, nil)
+				}
+				if j%50 == 0 {
+					c.clear()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := c.size(); n > 8 {
+		t.Errorf("size = %d, want at most 8", n)
+	}
+}
+
+func newHistoryTestService(t *testing.T, uris ...string) *Service {
+	t.Helper()
+
+	classifier := NewPathClassifier("/var/lib/mpd/music")
+	history := NewHistoryStore(t.TempDir(), classifier)
+	start := time.Now().Add(-time.Hour)
+	for i, uri := range uris {
+		history.entries = append(history.entries, PlayHistoryEntry{
+			ID:        fmt.Sprintf("entry-%d", i),
+			TrackURI:  uri,
+			Title:     uri,
+			Source:    SourceLocal,
+			Origin:    PlayOriginManualTrack,
+			PlayedAt:  start.Add(time.Duration(i) * time.Minute),
+			PlayCount: 1,
+		})
+	}
+
+	return &Service{
+		classifier: classifier,
+		history:    history,
+	}
+}
+
+func TestHistoryStore_EvictsOldestBeyondCap(t *testing.T) {
+	dataDir := t.TempDir()
+	classifier := NewPathClassifier("/var/lib/mpd/music")
+	history := NewHistoryStore(dataDir, classifier)
+	history.SetMaxEntries(3)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		history.RecordPlay("INTERNAL/"+name+".flac", name, "Artist", "Album", "", PlayOriginManualTrack)
+	}
+	history.saving.Wait()
+
+	uris := func(entries []PlayHistoryEntry) []string {
+		var uris []string
+		for _, entry := range entries {
+			uris = append(uris, entry.TrackURI)
+		}
+		return uris
+	}
+	if got, want := uris(history.entries), []string{"INTERNAL/c.flac", "INTERNAL/d.flac", "INTERNAL/e.flac"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+	if got := history.Stats()["totalEntries"]; got != 3 {
+		t.Errorf("totalEntries = %v, want 3", got)
+	}
+	resp := history.GetLastPlayed(GetLastPlayedRequest{Sort: TrackSortLastPlayed}, true, true)
+	if len(resp.Tracks) != 3 || resp.Tracks[0].TrackURI != "INTERNAL/e.flac" {
+		t.Errorf("GetLastPlayed() = %v, want e, d, c", uris(resp.Tracks))
+	}
+	if got := history.GetPlayCount("INTERNAL/a.flac"); got != 0 {
+		t.Errorf("GetPlayCount(evicted) = %d, want 0", got)
+	}
+
+	// The capped history is what was persisted, and a lower cap evicts on load
+	reloaded := NewHistoryStore(dataDir, classifier)
+	if got := uris(reloaded.entries); len(got) != 3 {
+		t.Fatalf("reloaded entries = %v, want 3", got)
+	}
+	reloaded.SetMaxEntries(2)
+	reloaded.saving.Wait()
+	if got, want := uris(reloaded.entries), []string{"INTERNAL/d.flac", "INTERNAL/e.flac"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after lowering cap = %v, want %v", got, want)
+	}
+	if got := uris(NewHistoryStore(dataDir, classifier).entries); len(got) != 2 {
+		t.Errorf("persisted entries after lowering cap = %v, want 2", got)
+	}
+
+	// Saves leave no temporary files behind
+	files, err := os.ReadDir(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("data dir holds %d files, want only the history", len(files))
+	}
+}
+
+func TestStores_RecoverFromTruncatedFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	truncated := map[string]string{
+		"playback_history.json": `[{"id": "entry-1", "trackUri": "INTERNAL/a.fl`,
+		"favorites.json":        `[{"uri": "INTERNAL/Album", "type": "al`,
+	}
+	for name, data := range truncated {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	classifier := NewPathClassifier("/var/lib/mpd/music")
+	history := NewHistoryStore(dataDir, classifier)
+	favorites := NewFavoritesStore(dataDir, classifier)
+	if len(history.entries) != 0 || len(favorites.favorites) != 0 {
+		t.Fatalf("loaded %d history entries and %d favorites from truncated files, want none", len(history.entries), len(favorites.favorites))
+	}
+
+	// The corrupt files are kept aside and the stores save fresh ones
+	for name := range truncated {
+		matches, err := filepath.Glob(filepath.Join(dataDir, name+".corrupt-*"))
+		if err != nil || len(matches) != 1 {
+			t.Errorf("%s set aside as %v, want one .corrupt file", name, matches)
+		}
+	}
+	history.RecordPlay("INTERNAL/b.flac", "B", "Artist", "Album", "", PlayOriginManualTrack)
+	if err := favorites.Add("INTERNAL/Album", FavoriteAlbum); err != nil {
+		t.Fatal(err)
+	}
+	history.saving.Wait()
+	favorites.saving.Wait()
+
+	if got := NewHistoryStore(dataDir, classifier).GetPlayCount("INTERNAL/b.flac"); got != 1 {
+		t.Errorf("reloaded play count = %d, want 1", got)
+	}
+	if got := NewFavoritesStore(dataDir, classifier).List(""); len(got) != 1 {
+		t.Errorf("reloaded favorites = %v, want one", got)
+	}
+}
+
+func TestService_GetLastPlayedTracks_RawTimeline(t *testing.T) {
+	service := newHistoryTestService(t, "INTERNAL/a.flac", "INTERNAL/b.flac", "INTERNAL/a.flac")
+
+	resp := service.GetLastPlayedTracks(GetLastPlayedRequest{Sort: TrackSortLastPlayed})
+
+	if len(resp.Tracks) != 3 {
+		t.Fatalf("Expected 3 raw entries, got %d", len(resp.Tracks))
+	}
+	want := []string{"INTERNAL/a.flac", "INTERNAL/b.flac", "INTERNAL/a.flac"}
+	for i, track := range resp.Tracks {
+		if track.TrackURI != want[i] {
+			t.Errorf("Tracks[%d] = %q, want %q", i, track.TrackURI, want[i])
+		}
+	}
+}
+
+func TestService_GetLastPlayedTracks_Dedupe(t *testing.T) {
+	// Interleaved plays: a, b, a, c, a, b
+	service := newHistoryTestService(t,
+		"INTERNAL/a.flac", "INTERNAL/b.flac", "INTERNAL/a.flac",
+		"INTERNAL/c.flac", "INTERNAL/a.flac", "INTERNAL/b.flac",
+	)
+
+	resp := service.GetLastPlayedTracks(GetLastPlayedRequest{Sort: TrackSortLastPlayed, Dedupe: true})
+
+	if resp.TotalCount != 3 {
+		t.Fatalf("Expected 3 deduplicated entries, got %d", resp.TotalCount)
+	}
+
+	tests := []struct {
+		uri       string
+		playCount int
+		entryID   string // most recent play
+	}{
+		{"INTERNAL/b.flac", 2, "entry-5"},
+		{"INTERNAL/a.flac", 3, "entry-4"},
+		{"INTERNAL/c.flac", 1, "entry-3"},
+	}
+	for i, tt := range tests {
+		got := resp.Tracks[i]
+		if got.TrackURI != tt.uri || got.PlayCount != tt.playCount || got.ID != tt.entryID {
+			t.Errorf("Tracks[%d] = {%s, playCount %d, id %s}, want {%s, playCount %d, id %s}",
+				i, got.TrackURI, got.PlayCount, got.ID, tt.uri, tt.playCount, tt.entryID)
+		}
+	}
+}
+
+func TestService_GetLastPlayedTracks_DedupeMostPlayed(t *testing.T) {
+	service := newHistoryTestService(t,
+		"INTERNAL/a.flac", "INTERNAL/b.flac", "INTERNAL/b.flac", "INTERNAL/c.flac",
+	)
+
+	resp := service.GetLastPlayedTracks(GetLastPlayedRequest{Sort: TrackSortMostPlayed, Dedupe: true, Limit: 1})
+
+	if len(resp.Tracks) != 1 || resp.Tracks[0].TrackURI != "INTERNAL/b.flac" || resp.Tracks[0].PlayCount != 2 {
+		t.Errorf("Expected b.flac with 2 plays first, got %+v", resp.Tracks)
+	}
+}
+
+func TestService_Favorites(t *testing.T) {
+	dataDir := t.TempDir()
+	service := NewService(&MockMPDClient{}, dataDir, "/var/lib/mpd/music")
+
+	if err := service.AddFavorite("INTERNAL/Artist/Album/01.flac", FavoriteTrack); err != nil {
+		t.Fatalf("AddFavorite(track) failed: %v", err)
+	}
+	if err := service.AddFavorite("USB/Stick/Album", FavoriteAlbum); err != nil {
+		t.Fatalf("AddFavorite(album) failed: %v", err)
+	}
+	if err := service.AddFavorite("INTERNAL/x.flac", "playlist"); err == nil {
+		t.Error("AddFavorite with invalid type succeeded, want error")
+	}
+	if err := service.AddFavorite("", FavoriteTrack); err == nil {
+		t.Error("AddFavorite with empty URI succeeded, want error")
+	}
+
+	if !service.IsFavorite("INTERNAL/Artist/Album/01.flac") {
+		t.Error("IsFavorite(track) = false, want true")
+	}
+	if service.IsFavorite("INTERNAL/Artist/Album/02.flac") {
+		t.Error("IsFavorite(other track) = true, want false")
+	}
+
+	albums := service.ListFavorites(FavoriteAlbum)
+	if albums.TotalCount != 1 || albums.Favorites[0].URI != "USB/Stick/Album" {
+		t.Errorf("ListFavorites(album) = %+v, want USB/Stick/Album", albums.Favorites)
+	}
+	if albums.Favorites[0].Source != SourceUSB {
+		t.Errorf("album favorite source = %q, want %q", albums.Favorites[0].Source, SourceUSB)
+	}
+	if all := service.ListFavorites(""); all.TotalCount != 2 {
+		t.Errorf("ListFavorites(all) returned %d, want 2", all.TotalCount)
+	}
+
+	service.RemoveFavorite("USB/Stick/Album")
+	if service.IsFavorite("USB/Stick/Album") {
+		t.Error("IsFavorite after RemoveFavorite = true, want false")
+	}
+
+	// Favorites are persisted asynchronously
+	service.favorites.saving.Wait()
+	reloaded := NewFavoritesStore(dataDir, NewPathClassifier("/var/lib/mpd/music"))
+	if len(reloaded.List("")) != 1 || !reloaded.Contains("INTERNAL/Artist/Album/01.flac") {
+		t.Errorf("reloaded favorites = %+v, want only the track", reloaded.List(""))
+	}
+}
+
+func TestService_ImportFavorites(t *testing.T) {
+	service := NewService(&MockMPDClient{}, t.TempDir(), "/var/lib/mpd/music")
+	defer service.favorites.saving.Wait()
+	if err := service.AddFavorite("INTERNAL/a.flac", FavoriteTrack); err != nil {
+		t.Fatal(err)
+	}
+
+	addedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	added := service.ImportFavorites([]Favorite{
+		{URI: "INTERNAL/a.flac", Type: FavoriteTrack},
+		{URI: "USB/Stick/Album", Type: FavoriteAlbum, Source: SourceLocal, AddedAt: addedAt},
+		{URI: "INTERNAL/b.flac", Type: "playlist"},
+		{URI: "", Type: FavoriteTrack},
+	})
+	if added != 1 {
+		t.Errorf("ImportFavorites() = %d, want 1", added)
+	}
+
+	albums := service.ListFavorites(FavoriteAlbum)
+	if albums.TotalCount != 1 {
+		t.Fatalf("ListFavorites(album) = %+v, want the imported album", albums.Favorites)
+	}
+	if fav := albums.Favorites[0]; !fav.AddedAt.Equal(addedAt) || fav.Source != SourceUSB {
+		t.Errorf("imported favorite = %+v, want original AddedAt and source reclassified as usb", fav)
+	}
+	if all := service.ListFavorites(""); all.TotalCount != 2 {
+		t.Errorf("ListFavorites(all) returned %d, want 2", all.TotalCount)
+	}
+}
+
+func TestService_ClearFavorites(t *testing.T) {
+	dataDir := t.TempDir()
+	service := NewService(&MockMPDClient{}, dataDir, "/var/lib/mpd/music")
+	service.AddFavorite("INTERNAL/a.flac", FavoriteTrack)
+	service.AddFavorite("USB/Stick/Album", FavoriteAlbum)
+
+	service.ClearFavorites()
+	service.favorites.saving.Wait()
+
+	if all := service.ListFavorites(""); all.TotalCount != 0 {
+		t.Errorf("ListFavorites() after clear = %+v, want none", all.Favorites)
+	}
+	if service.IsFavorite("INTERNAL/a.flac") {
+		t.Error("IsFavorite() = true after clear")
+	}
+	if reloaded := NewFavoritesStore(dataDir, service.classifier); len(reloaded.List("")) != 0 {
+		t.Errorf("reloaded favorites = %+v, want none", reloaded.List(""))
+	}
+}
+
+func TestService_GetAlbumTracks_IsFavorite(t *testing.T) {
+	mockMPD := &MockMPDClient{
+		ListInfoResponse: map[string][]map[string]string{
+			"INTERNAL/Artist/Album": {
+				{"file": "INTERNAL/Artist/Album/01.flac", "Track": "1"},
+				{"file": "INTERNAL/Artist/Album/02.flac", "Track": "2"},
+			},
+		},
+	}
+
+	classifier := NewPathClassifier("/var/lib/mpd/music")
+	service := &Service{
+		mpd:        mockMPD,
+		classifier: classifier,
+		favorites:  NewFavoritesStore(t.TempDir(), classifier),
+	}
+	service.AddFavorite("INTERNAL/Artist/Album/02.flac", FavoriteTrack)
+	defer service.favorites.saving.Wait()
+
+	resp := service.GetAlbumTracks(GetAlbumTracksRequest{AlbumURI: "INTERNAL/Artist/Album"})
+	if len(resp.Tracks) != 2 {
+		t.Fatalf("Expected 2 tracks, got %d", len(resp.Tracks))
+	}
+	if resp.Tracks[0].IsFavorite || !resp.Tracks[1].IsFavorite {
+		t.Errorf("IsFavorite = [%v %v], want [false true]", resp.Tracks[0].IsFavorite, resp.Tracks[1].IsFavorite)
+	}
+}
+
+// recentMPDClient adds RecentlyAddedLister support to MockMPDClient.
+type recentMPDClient struct {
+	MockMPDClient
+	Recent map[string][]string
+}
+
+func (m *recentMPDClient) ListRecentlyAdded(basePath string, limit int) ([]string, error) {
+	uris := m.Recent[basePath]
+	if len(uris) > limit {
+		uris = uris[:limit]
+	}
+	return uris, nil
+}
+
+func TestService_GenerateSmartPlaylist_OnRepeat(t *testing.T) {
+	service := newHistoryTestService(t,
+		"INTERNAL/a.flac", "INTERNAL/b.flac", "INTERNAL/b.flac", "INTERNAL/c.flac", "INTERNAL/b.flac", "INTERNAL/c.flac")
+
+	uris, err := service.GenerateSmartPlaylist("on_repeat", 2)
+	if err != nil {
+		t.Fatalf("GenerateSmartPlaylist failed: %v", err)
+	}
+
+	want := []string{"INTERNAL/b.flac", "INTERNAL/c.flac"}
+	if !reflect.DeepEqual(uris, want) {
+		t.Errorf("uris = %v, want %v", uris, want)
+	}
+}
+
+func TestService_GenerateSmartPlaylist_Forgotten(t *testing.T) {
+	service := newHistoryTestService(t, "INTERNAL/a.flac", "INTERNAL/b.flac", "INTERNAL/c.flac", "INTERNAL/c.flac")
+
+	// a and c were last played long ago; b is recent
+	old := time.Now().Add(-90 * 24 * time.Hour)
+	for i := range service.history.entries {
+		if service.history.entries[i].TrackURI != "INTERNAL/b.flac" {
+			service.history.entries[i].PlayedAt = old.Add(time.Duration(i) * time.Minute)
+		}
+	}
+
+	uris, err := service.GenerateSmartPlaylist("forgotten", 0)
+	if err != nil {
+		t.Fatalf("GenerateSmartPlaylist failed: %v", err)
+	}
+
+	want := []string{"INTERNAL/c.flac", "INTERNAL/a.flac"}
+	if !reflect.DeepEqual(uris, want) {
+		t.Errorf("uris = %v, want %v", uris, want)
+	}
+}
+
+func TestService_GenerateSmartPlaylist_Recent(t *testing.T) {
+	service := newHistoryTestService(t)
+	service.mpd = &recentMPDClient{Recent: map[string][]string{
+		"INTERNAL": {"INTERNAL/new.flac", "INTERNAL/older.flac"},
+		"USB":      {"USB/stick/new.flac"},
+	}}
+
+	uris, err := service.GenerateSmartPlaylist("recent", 2)
+	if err != nil {
+		t.Fatalf("GenerateSmartPlaylist failed: %v", err)
+	}
+
+	want := []string{"INTERNAL/new.flac", "INTERNAL/older.flac"}
+	if !reflect.DeepEqual(uris, want) {
+		t.Errorf("uris = %v, want %v", uris, want)
+	}
+}
+
+func TestService_GenerateSmartPlaylist_Errors(t *testing.T) {
+	service := newHistoryTestService(t)
+	service.mpd = &MockMPDClient{}
+
+	if _, err := service.GenerateSmartPlaylist("bogus", 10); err == nil {
+		t.Error("GenerateSmartPlaylist with unknown kind returned no error")
+	}
+	if _, err := service.GenerateSmartPlaylist("recent", 10); err == nil {
+		t.Error("GenerateSmartPlaylist recent without RecentlyAddedLister returned no error")
+	}
+}
+
+// stickerMPDClient is a MockMPDClient with an in-memory sticker database.
+type stickerMPDClient struct {
+	MockMPDClient
+	Stickers map[string]map[string]string // uri -> name -> value
+	Disabled bool
+	Calls    int
+}
+
+func (m *stickerMPDClient) GetSticker(uri, name string) (string, error) {
+	m.Calls++
+	if m.Disabled {
+		return "", ErrStickersUnavailable
+	}
+	return m.Stickers[uri][name], nil
+}
+
+func (m *stickerMPDClient) SetSticker(uri, name, value string) error {
+	m.Calls++
+	if m.Disabled {
+		return ErrStickersUnavailable
+	}
+	if m.Stickers[uri] == nil {
+		m.Stickers[uri] = map[string]string{}
+	}
+	m.Stickers[uri][name] = value
+	return nil
+}
+
+func (m *stickerMPDClient) DeleteSticker(uri, name string) error {
+	m.Calls++
+	if m.Disabled {
+		return ErrStickersUnavailable
+	}
+	delete(m.Stickers[uri], name)
+	return nil
+}
+
+func (m *stickerMPDClient) FindStickers(name string) (map[string]string, error) {
+	m.Calls++
+	if m.Disabled {
+		return nil, ErrStickersUnavailable
+	}
+	found := map[string]string{}
+	for uri, stickers := range m.Stickers {
+		if value, ok := stickers[name]; ok {
+			found[uri] = value
+		}
+	}
+	return found, nil
+}
+
+func TestService_Stickers_Favorites(t *testing.T) {
+	mockMPD := &stickerMPDClient{Stickers: map[string]map[string]string{
+		"INTERNAL/Other/01.flac": {stickerFavorite: "1"},
+	}}
+	service := NewService(mockMPD, t.TempDir(), "/var/lib/mpd/music")
+	defer service.favorites.saving.Wait()
+
+	// Favorites set by other clients are read from stickers
+	if !service.IsFavorite("INTERNAL/Other/01.flac") {
+		t.Error("sticker favorite was not read")
+	}
+
+	if err := service.AddFavorite("INTERNAL/Artist/Album/01.flac", FavoriteTrack); err != nil {
+		t.Fatalf("AddFavorite(track) failed: %v", err)
+	}
+	if err := service.AddFavorite("INTERNAL/Artist/Album", FavoriteAlbum); err != nil {
+		t.Fatalf("AddFavorite(album) failed: %v", err)
+	}
+	if addedAt, ok := parseFavorite(mockMPD.Stickers["INTERNAL/Artist/Album/01.flac"][stickerFavorite]); !ok || time.Since(addedAt) > time.Minute {
+		t.Errorf("favorite sticker = %q, want the time it was added", mockMPD.Stickers["INTERNAL/Artist/Album/01.flac"][stickerFavorite])
+	}
+	if service.favorites.Contains("INTERNAL/Artist/Album/01.flac") {
+		t.Error("track favorite was written to the favorites file, want stickers only")
+	}
+	if _, ok := mockMPD.Stickers["INTERNAL/Artist/Album"]; ok {
+		t.Error("album favorite was written to stickers, want songs only")
+	}
+
+	list := service.ListFavorites("")
+	var uris []string
+	for _, fav := range list.Favorites {
+		uris = append(uris, fav.URI)
+	}
+	// Newest first; the other client's favorite has no time so comes last
+	if want := []string{"INTERNAL/Artist/Album", "INTERNAL/Artist/Album/01.flac", "INTERNAL/Other/01.flac"}; !reflect.DeepEqual(uris, want) {
+		t.Errorf("ListFavorites = %v, want %v", uris, want)
+	}
+	if tracks := service.ListFavorites(FavoriteTrack); tracks.TotalCount != 2 {
+		t.Errorf("ListFavorites(track) has %d favorites, want 2", tracks.TotalCount)
+	}
+
+	service.RemoveFavorite("INTERNAL/Artist/Album/01.flac")
+	if _, ok := mockMPD.Stickers["INTERNAL/Artist/Album/01.flac"][stickerFavorite]; ok {
+		t.Error("favorite sticker was not deleted")
+	}
+	if service.IsFavorite("INTERNAL/Artist/Album/01.flac") {
+		t.Error("IsFavorite after RemoveFavorite = true, want false")
+	}
+}
+
+func TestService_SyncStickers_Favorites(t *testing.T) {
+	dataDir := t.TempDir()
+	classifier := NewPathClassifier("/var/lib/mpd/music")
+	legacy := NewFavoritesStore(dataDir, classifier)
+	legacy.Add("INTERNAL/a.flac", FavoriteTrack)
+	legacy.Add("INTERNAL/Album", FavoriteAlbum)
+	legacy.saving.Wait()
+	addedAt := legacy.List(FavoriteTrack)[0].AddedAt
+
+	mockMPD := &stickerMPDClient{Stickers: map[string]map[string]string{}}
+	service := NewService(mockMPD, dataDir, "/var/lib/mpd/music")
+	if mockMPD.Calls != 0 {
+		t.Errorf("NewService made %d sticker calls, want 0", mockMPD.Calls)
+	}
+
+	service.SyncStickers()
+	service.favorites.saving.Wait()
+	if got, _ := parseFavorite(mockMPD.Stickers["INTERNAL/a.flac"][stickerFavorite]); !got.Equal(addedAt.Truncate(time.Second)) {
+		t.Errorf("migrated favorite added at %v, want %v", got, addedAt)
+	}
+	if service.favorites.Contains("INTERNAL/a.flac") {
+		t.Error("migrated track favorite is still in the favorites file")
+	}
+	if !service.favorites.Contains("INTERNAL/Album") {
+		t.Error("album favorite left the favorites file")
+	}
+
+	// A favorite removed from stickers by another client stays removed
+	delete(mockMPD.Stickers["INTERNAL/a.flac"], stickerFavorite)
+	service = NewService(mockMPD, dataDir, "/var/lib/mpd/music")
+	service.SyncStickers()
+	if service.IsFavorite("INTERNAL/a.flac") {
+		t.Error("favorite deleted from stickers came back after a restart")
+	}
+}
+
+func TestService_SyncStickers_PlayCounts(t *testing.T) {
+	dataDir := t.TempDir()
+	legacy := NewService(&MockMPDClient{}, dataDir, "/var/lib/mpd/music")
+	for _, uri := range []string{"INTERNAL/a.flac", "INTERNAL/b.flac"} {
+		legacy.history.RecordPlay(uri, "", "", "", "", PlayOriginManualTrack)
+		legacy.history.RecordPlay(uri, "", "", "", "", PlayOriginManualTrack)
+	}
+	legacy.history.saving.Wait()
+
+	mockMPD := &stickerMPDClient{Stickers: map[string]map[string]string{
+		"INTERNAL/b.flac": {stickerPlayCount: "7"},
+	}}
+	service := NewService(mockMPD, dataDir, "/var/lib/mpd/music")
+	service.SyncStickers()
+	if got := service.GetPlayCount("INTERNAL/a.flac"); got != 2 {
+		t.Errorf("GetPlayCount(a) = %d, want 2 carried over from history", got)
+	}
+	if got := service.GetPlayCount("INTERNAL/b.flac"); got != 7 {
+		t.Errorf("GetPlayCount(b) = %d, want the higher sticker count 7", got)
+	}
+
+	// Play counts are only carried over once
+	delete(mockMPD.Stickers["INTERNAL/a.flac"], stickerPlayCount)
+	service = NewService(mockMPD, dataDir, "/var/lib/mpd/music")
+	service.SyncStickers()
+	if got := service.GetPlayCount("INTERNAL/a.flac"); got != 0 {
+		t.Errorf("GetPlayCount(a) after a second sync = %d, want 0", got)
+	}
+}
+
+func TestService_Stickers_PlayCount(t *testing.T) {
+	mockMPD := &stickerMPDClient{Stickers: map[string]map[string]string{
+		"INTERNAL/b.flac": {stickerPlayCount: "7"},
+	}}
+	service := NewService(mockMPD, t.TempDir(), "/var/lib/mpd/music")
+	defer service.history.saving.Wait()
+
+	service.RecordTrackPlay("INTERNAL/a.flac", "A", "Artist", "Album", "", PlayOriginManualTrack)
+	service.RecordTrackPlay("INTERNAL/b.flac", "B", "Artist", "Album", "", PlayOriginManualTrack)
+
+	if got := service.GetPlayCount("INTERNAL/a.flac"); got != 1 {
+		t.Errorf("GetPlayCount(a) = %d, want 1", got)
+	}
+	if got := service.GetPlayCount("INTERNAL/b.flac"); got != 8 {
+		t.Errorf("GetPlayCount(b) = %d, want 8 from stickers", got)
+	}
+
+	// Sticker counts outrank the history's own counts
+	uris, err := service.GenerateSmartPlaylist(string(SmartPlaylistOnRepeat), 10)
+	if err != nil {
+		t.Fatalf("GenerateSmartPlaylist failed: %v", err)
+	}
+	if want := []string{"INTERNAL/b.flac", "INTERNAL/a.flac"}; !reflect.DeepEqual(uris, want) {
+		t.Errorf("on_repeat = %v, want %v", uris, want)
+	}
+}
+
+func TestService_Stickers_Disabled(t *testing.T) {
+	mockMPD := &stickerMPDClient{Disabled: true}
+	service := NewService(mockMPD, t.TempDir(), "/var/lib/mpd/music")
+	service.SyncStickers()
+
+	if service.stickers.enabled() {
+		t.Fatal("sticker store still enabled after MPD reported stickers unavailable")
+	}
+
+	calls := mockMPD.Calls
+	defer service.history.saving.Wait()
+	service.RecordTrackPlay("INTERNAL/a.flac", "A", "Artist", "Album", "", PlayOriginManualTrack)
+	if err := service.AddFavorite("INTERNAL/a.flac", FavoriteTrack); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+	if mockMPD.Calls != calls {
+		t.Errorf("made %d sticker calls after stickers were disabled, want 0", mockMPD.Calls-calls)
+	}
+
+	// Falls back to the local files
+	if got := service.GetPlayCount("INTERNAL/a.flac"); got != 1 {
+		t.Errorf("GetPlayCount = %d, want 1 from history", got)
+	}
+	if !service.IsFavorite("INTERNAL/a.flac") {
+		t.Error("IsFavorite = false, want true from favorites file")
+	}
+	service.favorites.saving.Wait()
+}
+
+func TestService_Ratings(t *testing.T) {
+	dataDir := t.TempDir()
+	service := NewService(&MockMPDClient{}, dataDir, "/var/lib/mpd/music")
+
+	for uri, rating := range map[string]int{
+		"INTERNAL/a.flac": 5,
+		"INTERNAL/b.flac": 3,
+		"INTERNAL/c.flac": 4,
+	} {
+		if err := service.SetRating(uri, rating); err != nil {
+			t.Fatalf("SetRating(%s, %d) failed: %v", uri, rating, err)
+		}
+	}
+	for _, rating := range []int{-1, 6} {
+		if err := service.SetRating("INTERNAL/a.flac", rating); err == nil {
+			t.Errorf("SetRating(%d) succeeded, want error", rating)
+		}
+	}
+	if err := service.SetRating("", 3); err == nil {
+		t.Error("SetRating with empty URI succeeded, want error")
+	}
+
+	if got := service.GetRating("INTERNAL/a.flac"); got != 5 {
+		t.Errorf("GetRating(a) = %d, want 5", got)
+	}
+	if got := service.GetRating("INTERNAL/unrated.flac"); got != 0 {
+		t.Errorf("GetRating(unrated) = %d, want 0", got)
+	}
+
+	top := service.GetTopRated(0, 0)
+	if want := []string{"INTERNAL/a.flac", "INTERNAL/c.flac"}; !reflect.DeepEqual(top.URIs, want) {
+		t.Errorf("GetTopRated = %v, want %v", top.URIs, want)
+	}
+	if top.MinRating != defaultTopRatedMinRating {
+		t.Errorf("MinRating = %d, want %d", top.MinRating, defaultTopRatedMinRating)
+	}
+	if top := service.GetTopRated(3, 1); top.TotalCount != 1 || top.URIs[0] != "INTERNAL/a.flac" {
+		t.Errorf("GetTopRated(3, 1) = %v, want [INTERNAL/a.flac]", top.URIs)
+	}
+	if top := service.GetTopRated(6, 0); top.Error == "" {
+		t.Error("GetTopRated(6) succeeded, want error")
+	}
+
+	// A rating of 0 clears it
+	if err := service.SetRating("INTERNAL/c.flac", 0); err != nil {
+		t.Fatalf("SetRating(0) failed: %v", err)
+	}
+	if got := service.GetRating("INTERNAL/c.flac"); got != 0 {
+		t.Errorf("GetRating after clear = %d, want 0", got)
+	}
+
+	service.ratings.saving.Wait()
+	reloaded := NewRatingsStore(dataDir)
+	if reloaded.Get("INTERNAL/a.flac") != 5 || reloaded.Get("INTERNAL/c.flac") != 0 || len(reloaded.List(1)) != 2 {
+		t.Errorf("reloaded ratings = %+v, want a=5 and b=3", reloaded.List(1))
+	}
+}
+
+func TestService_Ratings_Stickers(t *testing.T) {
+	mockMPD := &stickerMPDClient{Stickers: map[string]map[string]string{
+		"INTERNAL/other.flac": {stickerRating: "4"},
+		"INTERNAL/bogus.flac": {stickerRating: "9"},
+	}}
+	service := NewService(mockMPD, t.TempDir(), "/var/lib/mpd/music")
+	service.SyncStickers()
+
+	if got := service.GetRating("INTERNAL/other.flac"); got != 4 {
+		t.Errorf("imported sticker rating = %d, want 4", got)
+	}
+	if got := service.GetRating("INTERNAL/bogus.flac"); got != 0 {
+		t.Errorf("out-of-range sticker rating imported as %d, want 0", got)
+	}
+
+	if err := service.SetRating("INTERNAL/a.flac", 2); err != nil {
+		t.Fatalf("SetRating failed: %v", err)
+	}
+	if got := mockMPD.Stickers["INTERNAL/a.flac"][stickerRating]; got != "2" {
+		t.Errorf("rating sticker = %q, want \"2\"", got)
+	}
+	if err := service.SetRating("INTERNAL/a.flac", 0); err != nil {
+		t.Fatalf("SetRating(0) failed: %v", err)
+	}
+	if _, ok := mockMPD.Stickers["INTERNAL/a.flac"][stickerRating]; ok {
+		t.Error("rating sticker was not deleted")
+	}
+	service.ratings.saving.Wait()
+}
@@ -0,0 +1,149 @@
+package localmusic
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultSmartPlaylistLimit is used when no limit is requested.
+	defaultSmartPlaylistLimit = 50
+	// forgottenAfter is how long a track must go unplayed to count as forgotten.
+	forgottenAfter = 30 * 24 * time.Hour
+)
+
+// RecentlyAddedLister is implemented by MPD clients that can list songs by the
+// time they were added to the database. It is optional so simpler clients keep
+// satisfying MPDClient.
+type RecentlyAddedLister interface {
+	ListRecentlyAdded(basePath string, limit int) ([]string, error)
+}
+
+// GenerateSmartPlaylist builds a queue-ready list of track URIs for a smart
+// playlist kind ("on_repeat", "forgotten" or "recent"). Only local tracks are
+// included.
+func (s *Service) GenerateSmartPlaylist(kind string, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = defaultSmartPlaylistLimit
+	}
+
+	var uris []string
+	var err error
+
+	switch SmartPlaylistKind(kind) {
+	case SmartPlaylistOnRepeat:
+		uris = s.history.onRepeat(limit, s.stickers.playCounts())
+	case SmartPlaylistForgotten:
+		uris = s.history.forgotten(time.Now().Add(-forgottenAfter), limit, s.stickers.playCounts())
+	case SmartPlaylistRecent:
+		uris, err = s.recentlyAdded(limit)
+	default:
+		return nil, fmt.Errorf("unknown smart playlist kind: %s", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("kind", kind).
+		Int("trackCount", len(uris)).
+		Msg("GenerateSmartPlaylist completed")
+
+	return uris, nil
+}
+
+// recentlyAdded returns the newest local tracks across local disk and USB.
+func (s *Service) recentlyAdded(limit int) ([]string, error) {
+	lister, ok := s.mpd.(RecentlyAddedLister)
+	if !ok {
+		return nil, fmt.Errorf("recently added tracks not supported by MPD client")
+	}
+
+	uris := make([]string, 0, limit)
+	for _, basePath := range s.classifier.Prefixes(SourceLocal, SourceUSB) {
+		found, err := lister.ListRecentlyAdded(basePath, limit)
+		if err != nil {
+			log.Debug().Err(err).Str("path", basePath).Msg("Failed to list recently added (may not exist)")
+			continue
+		}
+		uris = append(uris, found...)
+	}
+
+	// Each base path is already newest first; with both present, keep the
+	// local disk's additions ahead of USB rather than guessing at a merge order.
+	if len(uris) > limit {
+		uris = uris[:limit]
+	}
+	return uris, nil
+}
+
+// onRepeat returns the most played local tracks, most played first. Counts
+// from MPD stickers, when given, replace the history's own counts.
+func (h *HistoryStore) onRepeat(limit int, counts map[string]int) []string {
+	stats := h.localTrackStats(counts)
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].PlayCount != stats[j].PlayCount {
+			return stats[i].PlayCount > stats[j].PlayCount
+		}
+		return stats[i].PlayedAt.After(stats[j].PlayedAt)
+	})
+
+	return entryURIs(stats, limit)
+}
+
+// forgotten returns local tracks last played before the cutoff, favouring the
+// ones that used to be played most.
+func (h *HistoryStore) forgotten(cutoff time.Time, limit int, counts map[string]int) []string {
+	var stats []PlayHistoryEntry
+	for _, entry := range h.localTrackStats(counts) {
+		if entry.PlayedAt.Before(cutoff) {
+			stats = append(stats, entry)
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].PlayCount != stats[j].PlayCount {
+			return stats[i].PlayCount > stats[j].PlayCount
+		}
+		return stats[i].PlayedAt.Before(stats[j].PlayedAt)
+	})
+
+	return entryURIs(stats, limit)
+}
+
+// localTrackStats returns one entry per local track with its summed play count
+// and most recent play time. Counts found in the given map take precedence.
+func (h *HistoryStore) localTrackStats(counts map[string]int) []PlayHistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var local []PlayHistoryEntry
+	for _, entry := range h.entries {
+		if entry.Source.IsLocalSource() {
+			local = append(local, entry)
+		}
+	}
+	stats := dedupeEntries(local)
+	for i := range stats {
+		if count, ok := counts[stats[i].TrackURI]; ok && count > 0 {
+			stats[i].PlayCount = count
+		}
+	}
+	return stats
+}
+
+// entryURIs returns the track URIs of up to limit entries.
+func entryURIs(entries []PlayHistoryEntry, limit int) []string {
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	uris := make([]string, len(entries))
+	for i, entry := range entries {
+		uris[i] = entry.TrackURI
+	}
+	return uris
+}
@@ -0,0 +1,318 @@
+package localmusic
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/atomicfile"
+)
+
+// ErrStickersUnavailable is returned by StickerClient implementations when
+// MPD has no sticker database.
+var ErrStickersUnavailable = errors.New("sticker database unavailable")
+
+// Sticker names used for per-song metadata.
+const (
+	stickerFavorite  = "favorite"
+	stickerPlayCount = "playcount"
+	stickerRating    = "rating"
+)
+
+// StickerClient is implemented by MPD clients that can store per-song
+// metadata in MPD's sticker database. It is optional so simpler clients keep
+// satisfying MPDClient.
+type StickerClient interface {
+	GetSticker(uri, name string) (string, error)
+	SetSticker(uri, name, value string) error
+	DeleteSticker(uri, name string) error
+	FindStickers(name string) (map[string]string, error)
+}
+
+// stickerStore keeps track favorites and play counts in MPD stickers so they
+// stay with MPD's database and are shared with other clients. While it is
+// enabled the stickers are authoritative; the JSON stores are only used when
+// stickers are unavailable, and the first ErrStickersUnavailable disables the
+// sticker store for the rest of the process.
+type stickerStore struct {
+	client   StickerClient
+	disabled atomic.Bool
+}
+
+// newStickerStore returns a sticker store if the MPD client supports stickers.
+func newStickerStore(mpd MPDClient) *stickerStore {
+	client, ok := mpd.(StickerClient)
+	if !ok {
+		return nil
+	}
+	return &stickerStore{client: client}
+}
+
+// enabled returns true if stickers can be used.
+func (st *stickerStore) enabled() bool {
+	return st != nil && !st.disabled.Load()
+}
+
+// failed logs a sticker error and disables the store if MPD has no sticker
+// database.
+func (st *stickerStore) failed(err error, uri, name string) {
+	if errors.Is(err, ErrStickersUnavailable) {
+		if !st.disabled.Swap(true) {
+			log.Warn().Err(err).Msg("MPD sticker database unavailable, using local files only")
+		}
+		return
+	}
+	log.Debug().Err(err).Str("uri", uri).Str("sticker", name).Msg("Sticker operation failed")
+}
+
+// addFavorite marks a song as a favorite. The sticker holds when the
+// favorite was added, in Unix seconds, so favorites keep their order.
+func (st *stickerStore) addFavorite(uri string, addedAt time.Time) error {
+	if !st.enabled() {
+		return ErrStickersUnavailable
+	}
+	err := st.client.SetSticker(uri, stickerFavorite, strconv.FormatInt(addedAt.Unix(), 10))
+	if err != nil {
+		st.failed(err, uri, stickerFavorite)
+	}
+	return err
+}
+
+// removeFavorite unmarks a song as a favorite.
+func (st *stickerStore) removeFavorite(uri string) {
+	if !st.enabled() {
+		return
+	}
+	if err := st.client.DeleteSticker(uri, stickerFavorite); err != nil {
+		st.failed(err, uri, stickerFavorite)
+	}
+}
+
+// isFavorite returns true if a song is marked as a favorite.
+func (st *stickerStore) isFavorite(uri string) bool {
+	if !st.enabled() {
+		return false
+	}
+
+	value, err := st.client.GetSticker(uri, stickerFavorite)
+	if err != nil {
+		st.failed(err, uri, stickerFavorite)
+		return false
+	}
+	_, ok := parseFavorite(value)
+	return ok
+}
+
+// favorites returns when each favorite song was added, or nil if stickers
+// are unavailable.
+func (st *stickerStore) favorites() map[string]time.Time {
+	if !st.enabled() {
+		return nil
+	}
+
+	found, err := st.client.FindStickers(stickerFavorite)
+	if err != nil {
+		st.failed(err, "", stickerFavorite)
+		return nil
+	}
+
+	favorites := make(map[string]time.Time, len(found))
+	for uri, value := range found {
+		if addedAt, ok := parseFavorite(value); ok {
+			favorites[uri] = addedAt
+		}
+	}
+	return favorites
+}
+
+// parseFavorite parses a favorite sticker's value. Any value but "" and "0"
+// marks a favorite; values without a time, such as the "1" other clients
+// write, give a zero time.
+func parseFavorite(value string) (time.Time, bool) {
+	if value == "" || value == "0" {
+		return time.Time{}, false
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil && secs > 1 {
+		return time.Unix(secs, 0), true
+	}
+	return time.Time{}, true
+}
+
+// setRating stores a song's rating; 0 removes it.
+func (st *stickerStore) setRating(uri string, rating int) {
+	if !st.enabled() {
+		return
+	}
+
+	var err error
+	if rating > 0 {
+		err = st.client.SetSticker(uri, stickerRating, strconv.Itoa(rating))
+	} else {
+		err = st.client.DeleteSticker(uri, stickerRating)
+	}
+	if err != nil {
+		st.failed(err, uri, stickerRating)
+	}
+}
+
+// ratings returns the rating of every rated song, or nil if stickers are
+// unavailable. Out-of-range values written by other clients are skipped.
+func (st *stickerStore) ratings() map[string]int {
+	if !st.enabled() {
+		return nil
+	}
+
+	found, err := st.client.FindStickers(stickerRating)
+	if err != nil {
+		st.failed(err, "", stickerRating)
+		return nil
+	}
+
+	ratings := make(map[string]int, len(found))
+	for uri, value := range found {
+		if rating, err := strconv.Atoi(value); err == nil && rating >= 1 && rating <= MaxRating {
+			ratings[uri] = rating
+		}
+	}
+	return ratings
+}
+
+// incrementPlayCount adds one to a song's play count.
+func (st *stickerStore) incrementPlayCount(uri string) {
+	if !st.enabled() {
+		return
+	}
+
+	value, err := st.client.GetSticker(uri, stickerPlayCount)
+	if err != nil {
+		st.failed(err, uri, stickerPlayCount)
+		return
+	}
+
+	count, _ := strconv.Atoi(value)
+	if err := st.client.SetSticker(uri, stickerPlayCount, strconv.Itoa(count+1)); err != nil {
+		st.failed(err, uri, stickerPlayCount)
+	}
+}
+
+// setPlayCount stores a song's play count.
+func (st *stickerStore) setPlayCount(uri string, count int) error {
+	if !st.enabled() {
+		return ErrStickersUnavailable
+	}
+	err := st.client.SetSticker(uri, stickerPlayCount, strconv.Itoa(count))
+	if err != nil {
+		st.failed(err, uri, stickerPlayCount)
+	}
+	return err
+}
+
+// playCount returns a song's play count and whether stickers answered.
+func (st *stickerStore) playCount(uri string) (int, bool) {
+	if !st.enabled() {
+		return 0, false
+	}
+
+	value, err := st.client.GetSticker(uri, stickerPlayCount)
+	if err != nil {
+		st.failed(err, uri, stickerPlayCount)
+		return 0, false
+	}
+
+	count, _ := strconv.Atoi(value)
+	return count, true
+}
+
+// playCounts returns the play count of every song that has one, or nil if
+// stickers are unavailable.
+func (st *stickerStore) playCounts() map[string]int {
+	if !st.enabled() {
+		return nil
+	}
+
+	found, err := st.client.FindStickers(stickerPlayCount)
+	if err != nil {
+		st.failed(err, "", stickerPlayCount)
+		return nil
+	}
+
+	counts := make(map[string]int, len(found))
+	for uri, value := range found {
+		if count, err := strconv.Atoi(value); err == nil {
+			counts[uri] = count
+		}
+	}
+	return counts
+}
+
+// SyncStickers moves track favorites and play counts kept in local files
+// into MPD stickers, and merges ratings with them. Track favorites leave the
+// favorites file once stored as stickers, so it only keeps albums and tracks
+// added while stickers were unavailable; play counts are carried over once.
+// It makes an MPD round trip per item moved, so call it in the background at
+// startup.
+func (s *Service) SyncStickers() {
+	if !s.stickers.enabled() {
+		return
+	}
+	s.migrateStickerFavorites()
+	s.migrateStickerPlayCounts()
+	s.syncStickerRatings()
+}
+
+// migrateStickerFavorites moves track favorites from the favorites file into
+// MPD stickers. Favorites that fail to move stay in the file and are retried
+// on the next sync.
+func (s *Service) migrateStickerFavorites() {
+	if s.favorites == nil {
+		return
+	}
+
+	moved := 0
+	for _, fav := range s.favorites.List(FavoriteTrack) {
+		if err := s.stickers.addFavorite(fav.URI, fav.AddedAt); err != nil {
+			if !s.stickers.enabled() {
+				break
+			}
+			continue
+		}
+		s.favorites.Remove(fav.URI)
+		moved++
+	}
+	if moved > 0 {
+		log.Info().Int("count", moved).Msg("Moved favorites to MPD stickers")
+	}
+}
+
+// migrateStickerPlayCounts copies the history's play counts into MPD
+// stickers, once; a marker file records that it has run, so counts deleted
+// from stickers later are not brought back. Plays recorded since stickers
+// were enabled are counted in both, so the higher count is kept.
+func (s *Service) migrateStickerPlayCounts() {
+	if _, err := os.Stat(s.playCountsMigrated); err == nil {
+		return
+	}
+	stickered := s.stickers.playCounts()
+	if stickered == nil {
+		return
+	}
+
+	for uri, count := range s.history.playCounts() {
+		if count <= stickered[uri] {
+			continue
+		}
+		if err := s.stickers.setPlayCount(uri, count); err != nil && !s.stickers.enabled() {
+			return
+		}
+	}
+
+	if err := atomicfile.Write(s.playCountsMigrated, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		log.Warn().Err(err).Msg("Failed to record play count migration")
+		return
+	}
+	log.Info().Msg("Copied play counts to MPD stickers")
+}
@@ -0,0 +1,96 @@
+package localmusic
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultAlbumTrackCacheSize is how many albums' track listings are kept in
+// memory.
+const DefaultAlbumTrackCacheSize = 64
+
+// albumTrackCache is a least-recently-used cache of the MPD directory
+// listings behind GetAlbumTracks, keyed by album URI. Only MPD's entries are
+// cached; favorites and ratings are applied on every request so they are
+// never stale. It is safe for concurrent use. A nil cache caches nothing.
+type albumTrackCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Most recently used at the front
+	entries  map[string]*list.Element
+}
+
+// albumTrackEntry is one cached album listing.
+type albumTrackEntry struct {
+	uri     string
+	entries []map[string]string
+}
+
+// newAlbumTrackCache returns a cache holding up to capacity albums.
+func newAlbumTrackCache(capacity int) *albumTrackCache {
+	return &albumTrackCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached listing of an album and marks it recently used.
+func (c *albumTrackCache) get(uri string) ([]map[string]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[uri]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*albumTrackEntry).entries, true
+}
+
+// put caches the listing of an album, evicting the least recently used
+// album when full.
+func (c *albumTrackCache) put(uri string, entries []map[string]string) {
+	if c == nil || c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[uri]; ok {
+		elem.Value.(*albumTrackEntry).entries = entries
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[uri] = c.order.PushFront(&albumTrackEntry{uri: uri, entries: entries})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*albumTrackEntry).uri)
+	}
+}
+
+// clear drops every cached listing.
+func (c *albumTrackCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
+
+// size returns how many albums are cached.
+func (c *albumTrackCache) size() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -39,18 +39,24 @@ const (
 	PlayOriginAutoplayNext PlayOrigin = "autoplay_next"
 	// PlayOriginQueue indicates the track was played from the queue.
 	PlayOriginQueue PlayOrigin = "queue"
+	// PlayOriginFolderContext indicates the track played as part of folder playback.
+	PlayOriginFolderContext PlayOrigin = "folder_context"
 )
 
 // Album represents a local music album.
 type Album struct {
-	ID         string     `json:"id"`
-	Title      string     `json:"title"`
-	Artist     string     `json:"artist"`
-	URI        string     `json:"uri"`
-	AlbumArt   string     `json:"albumArt,omitempty"`
-	TrackCount int        `json:"trackCount,omitempty"`
-	Source     SourceType `json:"source"`
-	AddedAt    time.Time  `json:"addedAt,omitempty"`
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Artist         string     `json:"artist"`                   // Album artist
+	ArtistInferred bool       `json:"artistInferred,omitempty"` // No album artist tag; artist was derived from the tracks
+	TrackArtists   []string   `json:"trackArtists,omitempty"`   // Distinct track artists, including featured guests
+	URI            string     `json:"uri"`
+	AlbumArt       string     `json:"albumArt,omitempty"`
+	TrackCount     int        `json:"trackCount,omitempty"`
+	Source         SourceType `json:"source"`
+	AddedAt        time.Time  `json:"addedAt,omitempty"`
+	IsFavorite     bool       `json:"isFavorite"`
+	IsCompilation  bool       `json:"isCompilation"`
 }
 
 // Track represents a local music track.
@@ -61,9 +67,12 @@ type Track struct {
 	Album       string     `json:"album"`
 	URI         string     `json:"uri"`
 	TrackNumber int        `json:"trackNumber,omitempty"`
+	DiscNumber  int        `json:"discNumber,omitempty"`
 	Duration    int        `json:"duration,omitempty"`
 	AlbumArt    string     `json:"albumArt,omitempty"`
 	Source      SourceType `json:"source"`
+	IsFavorite  bool       `json:"isFavorite"`
+	Rating      int        `json:"rating,omitempty"` // 1-5 stars, 0 if unrated
 }
 
 // PlayHistoryEntry represents a record of a track being played.
@@ -80,6 +89,74 @@ type PlayHistoryEntry struct {
 	PlayCount int        `json:"playCount,omitempty"`
 }
 
+// SmartPlaylistKind selects how a smart playlist is generated.
+type SmartPlaylistKind string
+
+const (
+	SmartPlaylistOnRepeat  SmartPlaylistKind = "on_repeat" // Highest play counts
+	SmartPlaylistForgotten SmartPlaylistKind = "forgotten" // Not played in a long time
+	SmartPlaylistRecent    SmartPlaylistKind = "recent"    // Newest additions to the library
+)
+
+// SmartPlaylistResponse represents a generated smart playlist.
+type SmartPlaylistResponse struct {
+	Kind       SmartPlaylistKind `json:"kind"`
+	URIs       []string          `json:"uris"`
+	TotalCount int               `json:"totalCount"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// FavoriteType is the kind of item marked as a favorite.
+type FavoriteType string
+
+const (
+	// FavoriteTrack marks a single track URI as a favorite.
+	FavoriteTrack FavoriteType = "track"
+	// FavoriteAlbum marks an album directory URI as a favorite.
+	FavoriteAlbum FavoriteType = "album"
+)
+
+// Favorite represents a track or album the user has marked as a favorite.
+type Favorite struct {
+	URI     string       `json:"uri"`
+	Type    FavoriteType `json:"type"`
+	Source  SourceType   `json:"source"`
+	AddedAt time.Time    `json:"addedAt"`
+}
+
+// FavoritesResponse represents the response for favorites.
+type FavoritesResponse struct {
+	Favorites  []Favorite   `json:"favorites"`
+	TotalCount int          `json:"totalCount"`
+	Type       FavoriteType `json:"type,omitempty"` // Type filter applied, empty for all
+	Error      string       `json:"error,omitempty"`
+}
+
+// MaxRating is the highest star rating a track can be given.
+const MaxRating = 5
+
+// TrackRating represents the star rating a user has given a track.
+type TrackRating struct {
+	URI     string    `json:"uri"`
+	Rating  int       `json:"rating"`
+	RatedAt time.Time `json:"ratedAt"`
+}
+
+// RatingResponse represents the rating of a single track.
+type RatingResponse struct {
+	URI    string `json:"uri"`
+	Rating int    `json:"rating"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TopRatedResponse represents a queue-ready list of highly rated tracks.
+type TopRatedResponse struct {
+	URIs       []string `json:"uris"`
+	TotalCount int      `json:"totalCount"`
+	MinRating  int      `json:"minRating"`
+	Error      string   `json:"error,omitempty"`
+}
+
 // AlbumSortOrder defines how albums should be sorted.
 type AlbumSortOrder string
 
@@ -102,19 +179,22 @@ const (
 type GetLocalAlbumsRequest struct {
 	Sort  AlbumSortOrder `json:"sort"`
 	Query string         `json:"query,omitempty"`
-	Limit int            `json:"limit,omitempty"`
+	Page  int            `json:"page,omitempty"`  // 1-based; used with Limit
+	Limit int            `json:"limit,omitempty"` // Albums per page, 0 for all
 }
 
 // GetLastPlayedRequest represents a request to get last played tracks.
 type GetLastPlayedRequest struct {
-	Sort  TrackSortOrder `json:"sort"`
-	Limit int            `json:"limit,omitempty"`
+	Sort   TrackSortOrder `json:"sort"`
+	Limit  int            `json:"limit,omitempty"`
+	Dedupe bool           `json:"dedupe,omitempty"` // Collapse repeated plays of a track into one entry
 }
 
 // LocalAlbumsResponse represents the response for local albums.
 type LocalAlbumsResponse struct {
 	Albums       []Album `json:"albums"`
-	TotalCount   int     `json:"totalCount"`
+	TotalCount   int     `json:"totalCount"` // Matching albums across all pages
+	HasMore      bool    `json:"hasMore"`
 	FilteredOut  int     `json:"filteredOut"` // Count of non-local albums filtered out (for debugging)
 }
 
@@ -0,0 +1,328 @@
+package player
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/atomicfile"
+)
+
+const (
+	// alarmCheckInterval bounds how long the scheduler sleeps, so wall-clock
+	// changes (NTP sync after boot, DST, manual changes) are noticed.
+	alarmCheckInterval = 30 * time.Second
+
+	// alarmGrace is how late an alarm may still fire. Alarms missed by more,
+	// e.g. while the device was off or the clock jumped forward, are skipped.
+	alarmGrace = time.Minute
+)
+
+// Alarm starts playback of a library path or saved playlist at a time of day.
+type Alarm struct {
+	ID     string `json:"id"`
+	Time   string `json:"time"`   // Local time of day, "HH:MM"
+	Days   []int  `json:"days"`   // Weekdays, 0 = Sunday; empty means every day
+	URI    string `json:"uri"`    // Library file or directory, or a saved playlist name
+	Volume int    `json:"volume"` // 1-100; 0 leaves the volume unchanged
+}
+
+// clock parses the alarm time into hour and minute.
+func (a Alarm) clock() (int, int, error) {
+	t, err := time.Parse("15:04", a.Time)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid alarm time %q: must be HH:MM", a.Time)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// validate checks the alarm's fields.
+func (a Alarm) validate() error {
+	if _, _, err := a.clock(); err != nil {
+		return err
+	}
+	if a.URI == "" {
+		return errors.New("uri is required")
+	}
+	for _, day := range a.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid alarm day %d: must be 0 (Sunday) to 6", day)
+		}
+	}
+	if a.Volume < 0 || a.Volume > 100 {
+		return fmt.Errorf("invalid alarm volume %d: must be 0-100", a.Volume)
+	}
+	return nil
+}
+
+// next returns the first time strictly after after at which the alarm fires,
+// in after's location. A time skipped by a DST change fires at the
+// equivalent wall-clock time after the change.
+func (a Alarm) next(after time.Time) (time.Time, bool) {
+	hour, minute, err := a.clock()
+	if err != nil {
+		return time.Time{}, false
+	}
+	year, month, day := after.Date()
+	for i := 0; i <= 7; i++ {
+		at := time.Date(year, month, day+i, hour, minute, 0, 0, after.Location())
+		if gap := time.Duration((hour-at.Hour())*60+minute-at.Minute()) * time.Minute; gap > 0 {
+			// time.Date resolved a wall-clock time skipped by DST to before the change
+			at = at.Add(gap)
+		}
+		if !at.After(after) {
+			continue
+		}
+		if len(a.Days) == 0 || slices.Contains(a.Days, int(at.Weekday())) {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextAlarm returns the alarm that fires first after after.
+func nextAlarm(alarms []Alarm, after time.Time) (Alarm, time.Time, bool) {
+	var (
+		first   Alarm
+		firstAt time.Time
+		found   bool
+	)
+	for _, a := range alarms {
+		at, ok := a.next(after)
+		if ok && (!found || at.Before(firstAt)) {
+			first, firstAt, found = a, at, true
+		}
+	}
+	return first, firstAt, found
+}
+
+// AlarmStore persists alarms.
+type AlarmStore struct {
+	filePath string
+	alarms   []Alarm
+	mu       sync.RWMutex
+	changed  chan struct{} // Signals the scheduler to recompute
+}
+
+// NewAlarmStore creates an alarm store in dataDir and loads saved alarms.
+func NewAlarmStore(dataDir string) *AlarmStore {
+	a := &AlarmStore{
+		filePath: filepath.Join(dataDir, "alarms.json"),
+		alarms:   make([]Alarm, 0),
+		changed:  make(chan struct{}, 1),
+	}
+	a.load()
+	return a
+}
+
+// List returns the alarms in the order they were added.
+func (a *AlarmStore) List() []Alarm {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return slices.Clone(a.alarms)
+}
+
+// Add validates and saves a new alarm, returning it with its ID.
+func (a *AlarmStore) Add(alarm Alarm) (Alarm, error) {
+	if err := alarm.validate(); err != nil {
+		return Alarm{}, err
+	}
+	alarm.ID = uuid.NewString()
+	slices.Sort(alarm.Days)
+	alarm.Days = slices.Compact(alarm.Days)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	alarms := append(slices.Clone(a.alarms), alarm)
+	if err := a.save(alarms); err != nil {
+		return Alarm{}, err
+	}
+	a.alarms = alarms
+	a.notify()
+
+	log.Info().Str("id", alarm.ID).Str("time", alarm.Time).Str("uri", alarm.URI).Msg("Added alarm")
+	return alarm, nil
+}
+
+// Import adds alarms carried over from another device, giving them new IDs.
+// Alarms matching an existing one in everything but the ID are skipped, as
+// are invalid ones. It returns how many were added.
+func (a *AlarmStore) Import(imported []Alarm) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	alarms := slices.Clone(a.alarms)
+	added := 0
+	for _, alarm := range imported {
+		if err := alarm.validate(); err != nil {
+			log.Warn().Err(err).Str("time", alarm.Time).Str("uri", alarm.URI).Msg("Skipping invalid imported alarm")
+			continue
+		}
+		alarm.Days = slices.Clone(alarm.Days)
+		slices.Sort(alarm.Days)
+		alarm.Days = slices.Compact(alarm.Days)
+		if slices.ContainsFunc(alarms, func(existing Alarm) bool { return sameAlarm(existing, alarm) }) {
+			continue
+		}
+		alarm.ID = uuid.NewString()
+		alarms = append(alarms, alarm)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := a.save(alarms); err != nil {
+		return 0, err
+	}
+	a.alarms = alarms
+	a.notify()
+
+	log.Info().Int("count", added).Msg("Imported alarms")
+	return added, nil
+}
+
+// sameAlarm reports whether two alarms differ only in their IDs.
+func sameAlarm(a, b Alarm) bool {
+	return a.Time == b.Time && a.URI == b.URI && a.Volume == b.Volume && slices.Equal(a.Days, b.Days)
+}
+
+// Remove deletes an alarm.
+func (a *AlarmStore) Remove(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i := slices.IndexFunc(a.alarms, func(alarm Alarm) bool { return alarm.ID == id })
+	if i < 0 {
+		return fmt.Errorf("alarm not found: %s", id)
+	}
+	alarms := slices.Delete(slices.Clone(a.alarms), i, i+1)
+	if err := a.save(alarms); err != nil {
+		return err
+	}
+	a.alarms = alarms
+	a.notify()
+
+	log.Info().Str("id", id).Msg("Removed alarm")
+	return nil
+}
+
+// notify wakes the scheduler without blocking.
+func (a *AlarmStore) notify() {
+	select {
+	case a.changed <- struct{}{}:
+	default:
+	}
+}
+
+// load reads saved alarms, ignoring a missing or invalid file.
+func (a *AlarmStore) load() {
+	data, err := os.ReadFile(a.filePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", a.filePath).Msg("Failed to read alarms")
+		}
+		return
+	}
+	var alarms []Alarm
+	if err := json.Unmarshal(data, &alarms); err != nil {
+		log.Warn().Err(err).Str("path", a.filePath).Msg("Failed to parse alarms")
+		return
+	}
+	for _, alarm := range alarms {
+		if err := alarm.validate(); err != nil {
+			log.Warn().Err(err).Str("id", alarm.ID).Msg("Ignoring invalid saved alarm")
+			continue
+		}
+		a.alarms = append(a.alarms, alarm)
+	}
+}
+
+// save writes alarms to disk. Must be called with mu held.
+func (a *AlarmStore) save(alarms []Alarm) error {
+	data, err := json.MarshalIndent(alarms, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(a.filePath, data, 0600)
+}
+
+// StartAlarms fires the store's alarms until ctx is cancelled. Alarms are
+// evaluated against the wall clock, so one that is missed by more than a
+// minute (device off or suspended, clock corrected) is skipped, not played late.
+func (s *Service) StartAlarms(ctx context.Context, store *AlarmStore) {
+	go func() {
+		log.Info().Msg("Alarm scheduler started")
+		cursor := wallNow()
+		for {
+			alarm, at, ok := nextAlarm(store.List(), cursor)
+			wait := alarmCheckInterval
+			if ok {
+				wait = min(wait, max(time.Until(at), 0))
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				log.Info().Msg("Alarm scheduler stopped")
+				return
+			case <-store.changed:
+				timer.Stop()
+				cursor = wallNow()
+				continue
+			case <-timer.C:
+			}
+
+			now := wallNow()
+			if now.Before(cursor) {
+				// Clock moved backwards; schedule from the corrected time
+				cursor = now
+				continue
+			}
+			if !ok || now.Before(at) {
+				continue
+			}
+			cursor = now
+
+			if late := now.Sub(at); late > alarmGrace {
+				log.Warn().Str("id", alarm.ID).Time("due", at).Dur("late", late).Msg("Skipping missed alarm")
+				continue
+			}
+			if err := s.playAlarm(alarm); err != nil {
+				log.Error().Err(err).Str("id", alarm.ID).Msg("Alarm failed to start playback")
+			}
+		}
+	}()
+}
+
+// wallNow returns the current time without its monotonic reading, so
+// comparisons follow wall-clock changes.
+func wallNow() time.Time {
+	return time.Now().Round(0)
+}
+
+// playAlarm sets the alarm volume and starts its playlist or library path.
+func (s *Service) playAlarm(alarm Alarm) error {
+	log.Info().Str("id", alarm.ID).Str("uri", alarm.URI).Int("volume", alarm.Volume).Msg("Alarm firing")
+
+	// Set the volume before playing so the previous level doesn't blast out
+	if alarm.Volume > 0 {
+		if err := s.mpd.SetVolume(alarm.Volume); err != nil {
+			log.Warn().Err(err).Msg("Alarm could not set volume (no mixer?)")
+		}
+	}
+
+	if playlists, err := s.mpd.ListPlaylists(); err == nil && slices.Contains(playlists, alarm.URI) {
+		return s.mpd.LoadPlaylist(alarm.URI, true)
+	}
+	return s.ReplaceAndPlay(alarm.URI)
+}
@@ -0,0 +1,151 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlarm_Next(t *testing.T) {
+	// Wednesday 2026-10-14 08:00 UTC
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		alarm Alarm
+		want  time.Time
+	}{
+		{"later today", Alarm{Time: "09:30"}, time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)},
+		{"passed today", Alarm{Time: "07:00"}, time.Date(2026, 10, 15, 7, 0, 0, 0, time.UTC)},
+		{"exactly now", Alarm{Time: "08:00"}, time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)},
+		{"weekend only", Alarm{Time: "07:00", Days: []int{0, 6}}, time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)},
+		{"same weekday passed", Alarm{Time: "07:00", Days: []int{3}}, time.Date(2026, 10, 21, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, ok := tt.alarm.next(now)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%s: next = %v, %v; want %v", tt.name, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := (Alarm{Time: "7am"}).next(now); ok {
+		t.Error("next accepted an invalid time")
+	}
+}
+
+func TestAlarm_NextAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data unavailable")
+	}
+
+	// 02:30 doesn't exist on 2026-03-08; the alarm fires once the clocks change
+	spring := time.Date(2026, 3, 7, 23, 0, 0, 0, loc)
+	got, _ := Alarm{Time: "02:30"}.next(spring)
+	if got.Day() != 8 || got.Hour() != 3 || got.Minute() != 30 {
+		t.Errorf("spring-forward next = %v, want 2026-03-08 03:30 EDT", got)
+	}
+
+	// 01:30 happens twice on 2026-11-01; the alarm fires only once
+	fall := time.Date(2026, 10, 31, 23, 0, 0, 0, loc)
+	first, _ := Alarm{Time: "01:30"}.next(fall)
+	second, _ := Alarm{Time: "01:30"}.next(first)
+	if first.Day() != 1 || second.Day() != 2 {
+		t.Errorf("fall-back occurrences = %v, %v; want Nov 1 then Nov 2", first, second)
+	}
+}
+
+func TestNextAlarm(t *testing.T) {
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	alarms := []Alarm{
+		{ID: "tomorrow", Time: "07:00"},
+		{ID: "tonight", Time: "22:00"},
+	}
+	alarm, at, ok := nextAlarm(alarms, now)
+	if !ok || alarm.ID != "tonight" || at.Hour() != 22 {
+		t.Errorf("nextAlarm = %s at %v, want tonight", alarm.ID, at)
+	}
+	if _, _, ok := nextAlarm(nil, now); ok {
+		t.Error("nextAlarm found an alarm in an empty list")
+	}
+}
+
+func TestAlarmStore_AddRemove(t *testing.T) {
+	dir := t.TempDir()
+	store := NewAlarmStore(dir)
+
+	alarm, err := store.Add(Alarm{Time: "06:45", Days: []int{5, 1, 1}, URI: "NAS/Morning", Volume: 30})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if alarm.ID == "" || len(alarm.Days) != 2 || alarm.Days[0] != 1 {
+		t.Errorf("Add() = %+v, want an ID and sorted unique days", alarm)
+	}
+	select {
+	case <-store.changed:
+	default:
+		t.Error("Add did not signal the scheduler")
+	}
+
+	invalid := []Alarm{
+		{Time: "25:00", URI: "x"},
+		{Time: "07:00"},
+		{Time: "07:00", URI: "x", Days: []int{7}},
+		{Time: "07:00", URI: "x", Volume: 101},
+	}
+	for _, a := range invalid {
+		if _, err := store.Add(a); err == nil {
+			t.Errorf("Add(%+v) succeeded, want error", a)
+		}
+	}
+
+	// Alarms survive a restart
+	reloaded := NewAlarmStore(dir).List()
+	if len(reloaded) != 1 || reloaded[0].ID != alarm.ID {
+		t.Fatalf("reloaded alarms = %+v", reloaded)
+	}
+
+	if err := store.Remove("missing"); err == nil {
+		t.Error("Remove of an unknown alarm succeeded")
+	}
+	if err := store.Remove(alarm.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if got := NewAlarmStore(dir).List(); len(got) != 0 {
+		t.Errorf("alarms after Remove = %+v", got)
+	}
+}
+
+func TestAlarmStore_Import(t *testing.T) {
+	dir := t.TempDir()
+	store := NewAlarmStore(dir)
+	existing, err := store.Add(Alarm{Time: "06:45", Days: []int{1, 5}, URI: "NAS/Morning", Volume: 30})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	<-store.changed
+
+	added, err := store.Import([]Alarm{
+		{ID: "other-device", Time: "06:45", Days: []int{5, 1}, URI: "NAS/Morning", Volume: 30},
+		{ID: "other-device-2", Time: "22:00", URI: "Evening", Volume: 10},
+		{Time: "25:00", URI: "x"},
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if added != 1 {
+		t.Errorf("Import() = %d, want 1", added)
+	}
+	select {
+	case <-store.changed:
+	default:
+		t.Error("Import did not signal the scheduler")
+	}
+
+	alarms := NewAlarmStore(dir).List()
+	if len(alarms) != 2 || alarms[0].ID != existing.ID || alarms[1].Time != "22:00" {
+		t.Fatalf("alarms after Import = %+v", alarms)
+	}
+	if alarms[1].ID == "" || alarms[1].ID == "other-device-2" {
+		t.Errorf("imported alarm ID = %q, want a new one", alarms[1].ID)
+	}
+}
@@ -0,0 +1,46 @@
+package player
+
+// TrackClassifier tells streaming tracks apart from files in the library.
+// It is satisfied by *localmusic.PathClassifier.
+type TrackClassifier interface {
+	IsStreamingPath(uri string) bool
+}
+
+// StreamingArtFunc returns the album art URL for a streaming track, or ""
+// if it has none.
+type StreamingArtFunc func(uri string) string
+
+// LibraryArtURL returns the /albumart URL for a file in the MPD library.
+func LibraryArtURL(file string) string {
+	if file == "" {
+		return ""
+	}
+	return "/albumart?path=" + file
+}
+
+// SetAlbumArtSources sets how AlbumArtURL tells streaming tracks apart and
+// finds their art. Without a classifier every track is treated as a
+// library file.
+func (s *Service) SetAlbumArtSources(classifier TrackClassifier, streamingArt StreamingArtFunc) {
+	s.artMu.Lock()
+	defer s.artMu.Unlock()
+	s.artClassifier = classifier
+	s.streamingArt = streamingArt
+}
+
+// AlbumArtURL returns the album art URL for a track: the streaming
+// service's art for streaming tracks, and the /albumart endpoint for library
+// files (local, USB and NAS).
+func (s *Service) AlbumArtURL(uri string) string {
+	s.artMu.RLock()
+	classifier, streamingArt := s.artClassifier, s.streamingArt
+	s.artMu.RUnlock()
+
+	if uri != "" && classifier != nil && classifier.IsStreamingPath(uri) {
+		if streamingArt == nil {
+			return ""
+		}
+		return streamingArt(uri)
+	}
+	return LibraryArtURL(uri)
+}
@@ -0,0 +1,59 @@
+package player
+
+import (
+	"strings"
+	"testing"
+)
+
+// prefixClassifier treats URIs with a scheme as streaming.
+type prefixClassifier struct{}
+
+func (prefixClassifier) IsStreamingPath(uri string) bool {
+	return strings.Contains(uri, "://")
+}
+
+func TestAlbumArtURL(t *testing.T) {
+	s := NewService(nil)
+	s.SetAlbumArtSources(prefixClassifier{}, func(uri string) string {
+		if strings.HasPrefix(uri, "qobuz://") {
+			return "https://static.qobuz.com/images/covers/cover.jpg"
+		}
+		return ""
+	})
+
+	tests := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{"local file", "INTERNAL/Artist/Album/01.flac", "/albumart?path=INTERNAL/Artist/Album/01.flac"},
+		{"NAS file", "NAS/Music/Album/01.flac", "/albumart?path=NAS/Music/Album/01.flac"},
+		{"qobuz track", "qobuz://track/123", "https://static.qobuz.com/images/covers/cover.jpg"},
+		{"other streaming track", "tidal://track/1", ""},
+		{"no track", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.AlbumArtURL(tt.uri); got != tt.want {
+				t.Errorf("AlbumArtURL(%q) = %q, want %q", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlbumArtURLWithoutClassifier(t *testing.T) {
+	s := NewService(nil)
+	if got := s.AlbumArtURL("qobuz://track/123"); got != "/albumart?path=qobuz://track/123" {
+		t.Errorf("AlbumArtURL = %q, want the library URL", got)
+	}
+}
+
+func TestBuildStateStreamingAlbumArt(t *testing.T) {
+	s := NewService(nil)
+	s.SetAlbumArtSources(prefixClassifier{}, func(uri string) string { return "https://example.com/art.jpg" })
+
+	state := s.buildState(map[string]string{"state": "play"}, map[string]string{"file": "qobuz://track/9"})
+	if state.AlbumArt != "https://example.com/art.jpg" {
+		t.Errorf("AlbumArt = %q, want the streaming art", state.AlbumArt)
+	}
+}
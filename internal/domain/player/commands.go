@@ -0,0 +1,99 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Transport commands accepted by ExecuteCommand. The names match the
+// Socket.io events and the REST endpoints.
+const (
+	CommandPlay   = "play"
+	CommandPause  = "pause"
+	CommandStop   = "stop"
+	CommandNext   = "next"
+	CommandPrev   = "prev"
+	CommandSeek   = "seek"
+	CommandVolume = "volume"
+)
+
+// Command errors
+var (
+	// ErrUnknownCommand is returned for a command name ExecuteCommand doesn't know
+	ErrUnknownCommand = errors.New("unknown command")
+
+	// ErrInvalidCommandValue is returned when a command's argument is missing or out of range
+	ErrInvalidCommandValue = errors.New("invalid command value")
+)
+
+// Commands lists the transport commands in a stable order.
+var Commands = []string{
+	CommandPlay, CommandPause, CommandStop, CommandNext, CommandPrev, CommandSeek, CommandVolume,
+}
+
+// ParseCommandValue extracts a command's numeric argument. It accepts a bare
+// number, a numeric string, or an object with a "value" field, which covers
+// both Socket.io payloads and decoded REST bodies.
+func ParseCommandValue(arg interface{}) (int, bool) {
+	switch v := arg.(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return int(n), true
+		}
+	case map[string]interface{}:
+		if value, ok := v["value"]; ok {
+			return ParseCommandValue(value)
+		}
+	}
+	return 0, false
+}
+
+// ExecuteCommand runs a transport command. Seek and volume require a value;
+// play uses it as a queue position and resumes when it is absent. Volumes are
+// clamped to 0-100.
+func (s *Service) ExecuteCommand(cmd string, value int, hasValue bool) error {
+	switch cmd {
+	case CommandPlay:
+		if !hasValue {
+			value = -1 // Resume
+		}
+		return s.Play(value)
+	case CommandPause:
+		return s.Pause()
+	case CommandStop:
+		return s.Stop()
+	case CommandNext:
+		return s.Next()
+	case CommandPrev:
+		return s.Previous()
+	case CommandSeek:
+		if !hasValue || value < 0 {
+			return fmt.Errorf("%w: seek needs a position in seconds", ErrInvalidCommandValue)
+		}
+		return s.Seek(value)
+	case CommandVolume:
+		if !hasValue {
+			return fmt.Errorf("%w: volume needs a level from 0 to 100", ErrInvalidCommandValue)
+		}
+		return s.SetVolume(clampVolume(value))
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, cmd)
+	}
+}
+
+// clampVolume limits a volume to 0-100.
+func clampVolume(vol int) int {
+	if vol < 0 {
+		return 0
+	}
+	if vol > 100 {
+		return 100
+	}
+	return vol
+}
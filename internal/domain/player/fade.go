@@ -0,0 +1,257 @@
+package player
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxFadeMs caps fade durations at 10 seconds.
+	MaxFadeMs = 10000
+
+	// fadeStepInterval is the time between volume steps during a fade.
+	fadeStepInterval = 50 * time.Millisecond
+)
+
+// FadeSettings configures volume fades on play, pause and stop.
+//
+// Fades drive MPD's software mixer, so they only run when MPD reports a
+// volume. In bit-perfect mode (mixer_type "none") there is no volume to ramp
+// and scaling samples would break bit-perfection, so playback starts and
+// stops abruptly regardless of these settings.
+type FadeSettings struct {
+	Enabled   bool `json:"enabled"`
+	FadeInMs  int  `json:"fadeInMs"`  // Ramp up after play; 0 disables
+	FadeOutMs int  `json:"fadeOutMs"` // Ramp down before pause/stop; 0 disables
+}
+
+// DefaultFadeSettings leaves fades off.
+var DefaultFadeSettings = FadeSettings{FadeInMs: 1500, FadeOutMs: 800}
+
+// volumeFade is an in-flight fade. Closing cancel stops it at the current
+// step; done is closed once its goroutine has exited.
+type volumeFade struct {
+	target int          // Volume the user had set, restored after a fade-out
+	halt   func() error // Pauses or stops playback once a fade-out ends
+	sleep  *sleepTimer  // Timer that started the fade; interrupting it ends the timer
+	cancel chan struct{}
+	done   chan struct{}
+}
+
+// SetFadeSettings replaces the fade settings.
+func (s *Service) SetFadeSettings(settings FadeSettings) error {
+	if settings.FadeInMs < 0 || settings.FadeInMs > MaxFadeMs || settings.FadeOutMs < 0 || settings.FadeOutMs > MaxFadeMs {
+		return fmt.Errorf("fade durations must be between 0 and %d ms", MaxFadeMs)
+	}
+	log.Info().
+		Bool("enabled", settings.Enabled).
+		Int("fadeInMs", settings.FadeInMs).
+		Int("fadeOutMs", settings.FadeOutMs).
+		Msg("SetFadeSettings")
+
+	s.fadeMu.Lock()
+	defer s.fadeMu.Unlock()
+	s.fadeSettings = settings
+	return nil
+}
+
+// FadeSettings returns the fade settings.
+func (s *Service) FadeSettings() FadeSettings {
+	s.fadeMu.Lock()
+	defer s.fadeMu.Unlock()
+	return s.fadeSettings
+}
+
+// Play starts playback at the given position, or resumes if pos < 0. With
+// fades enabled, playback starts silent and ramps up to the user's volume;
+// playing during a fade-out reverses it instead.
+func (s *Service) Play(pos int) error {
+	log.Info().Int("position", pos).Msg("Play")
+
+	s.transportMu.Lock()
+	defer s.transportMu.Unlock()
+
+	prev := s.interruptFade()
+	settings := s.FadeSettings()
+	status, err := s.mpd.Status()
+	volume := statusVolume(status)
+	if !settings.Enabled || settings.FadeInMs == 0 || err != nil || volume < 0 {
+		if prev >= 0 {
+			s.mpd.SetVolume(prev)
+		}
+		return s.mpd.Play(pos)
+	}
+
+	target := volume
+	if prev >= 0 {
+		target = prev
+	}
+	from := 0
+	if status["state"] == StatusPlay {
+		if prev < 0 {
+			// Already audible at the user's volume
+			return s.mpd.Play(pos)
+		}
+		from = volume // Ramp back up from where the interrupted fade left off
+	}
+
+	if from != volume {
+		s.mpd.SetVolume(from)
+	}
+	if err := s.mpd.Play(pos); err != nil {
+		s.mpd.SetVolume(target)
+		return err
+	}
+	s.startFade(from, target, target, time.Duration(settings.FadeInMs)*time.Millisecond, nil)
+	return nil
+}
+
+// Pause pauses playback, fading out first when fades are enabled.
+func (s *Service) Pause() error {
+	log.Info().Msg("Pause")
+	return s.fadeOut(func() error { return s.mpd.Pause(true) })
+}
+
+// Stop stops playback, cancelling any sleep timer and fading out first when
+// fades are enabled.
+func (s *Service) Stop() error {
+	log.Info().Msg("Stop")
+	err := s.fadeOut(s.mpd.Stop)
+	s.CancelSleepTimer()
+	return err
+}
+
+// StopNow stops playback before returning, skipping any fade-out. Use it
+// when the track's file must be released, e.g. before ejecting its drive.
+func (s *Service) StopNow() error {
+	log.Info().Msg("StopNow")
+
+	s.transportMu.Lock()
+	prev := s.interruptFade()
+	err := s.mpd.Stop()
+	if prev >= 0 {
+		s.mpd.SetVolume(prev)
+	}
+	s.transportMu.Unlock()
+
+	s.CancelSleepTimer()
+	return err
+}
+
+// fadeOut ramps the volume down, runs halt and restores the user's volume.
+// The fade runs in the background; without one, halt runs immediately.
+func (s *Service) fadeOut(halt func() error) error {
+	s.transportMu.Lock()
+	defer s.transportMu.Unlock()
+
+	prev := s.interruptFade()
+	settings := s.FadeSettings()
+	status, err := s.mpd.Status()
+	volume := statusVolume(status)
+	if !settings.Enabled || settings.FadeOutMs == 0 || err != nil || volume < 0 || status["state"] != StatusPlay {
+		err := halt()
+		if prev >= 0 {
+			s.mpd.SetVolume(prev)
+		}
+		return err
+	}
+
+	target := volume
+	if prev >= 0 {
+		target = prev
+	}
+	s.startFade(volume, 0, target, time.Duration(settings.FadeOutMs)*time.Millisecond, halt)
+	return nil
+}
+
+// startFade ramps the volume from one level to another in the background.
+// If halt is set, it runs once the ramp ends and the volume is then restored
+// to target, unless the fade is interrupted first. target is the user's
+// volume, handed to whoever interrupts the fade. Must be called with
+// transportMu held.
+func (s *Service) startFade(from, to, target int, duration time.Duration, halt func() error) *volumeFade {
+	f := &volumeFade{
+		target: target,
+		halt:   halt,
+		cancel: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.fadeMu.Lock()
+	s.fade = f
+	s.fadeMu.Unlock()
+
+	steps := max(int(duration/fadeStepInterval), 1)
+	go func() {
+		defer close(f.done)
+		ticker := time.NewTicker(duration / time.Duration(steps))
+		defer ticker.Stop()
+
+		for i := 1; i <= steps; i++ {
+			select {
+			case <-f.cancel:
+				return
+			case <-ticker.C:
+			}
+			if err := s.mpd.SetVolume(from + (to-from)*i/steps); err != nil {
+				log.Debug().Err(err).Msg("Fade step failed")
+			}
+		}
+		if halt != nil {
+			if err := halt(); err != nil {
+				log.Warn().Err(err).Msg("Failed to halt playback after fade-out")
+			}
+			s.mpd.SetVolume(target)
+		}
+
+		s.fadeMu.Lock()
+		if s.fade == f {
+			s.fade = nil
+		}
+		s.fadeMu.Unlock()
+	}()
+	return f
+}
+
+// interruptFade cancels the in-flight fade, if any, and waits for it to stop.
+// It returns the volume the user had set before the fade, or -1 if none was
+// running. Must be called with transportMu held.
+func (s *Service) interruptFade() int {
+	f := s.stopFade()
+	if f == nil {
+		return -1
+	}
+	return f.target
+}
+
+// stopFade cancels the in-flight fade, if any, waits for it to stop and
+// returns it. Stopping the sleep timer's fade cancels the timer. Must be
+// called with transportMu held.
+func (s *Service) stopFade() *volumeFade {
+	s.fadeMu.Lock()
+	f := s.fade
+	s.fade = nil
+	s.fadeMu.Unlock()
+
+	if f == nil {
+		return nil
+	}
+	close(f.cancel)
+	<-f.done
+	if f.sleep != nil {
+		s.endSleep(f.sleep)
+	}
+	return f
+}
+
+// statusVolume returns the volume in an MPD status, or -1 when MPD has no
+// mixer (bit-perfect) or the status is unavailable.
+func statusVolume(status map[string]string) int {
+	volume, err := strconv.Atoi(status["volume"])
+	if err != nil {
+		return -1
+	}
+	return volume
+}
@@ -0,0 +1,199 @@
+package player
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
+)
+
+// fakeMPD is an MPD server that tracks just the volume and playback state.
+type fakeMPD struct {
+	mu     sync.Mutex
+	volume int
+	state  string
+}
+
+// newFakeMPDService starts a fakeMPD and returns a Service connected to it.
+func newFakeMPDService(t *testing.T, volume int, state string) (*Service, *fakeMPD) {
+	t.Helper()
+
+	f := &fakeMPD{volume: volume, state: state}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	client := mpd.NewClient(addr.IP.String(), addr.Port, "")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return NewService(client), f
+}
+
+func (f *fakeMPD) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprint(conn, "OK MPD 0.23.5\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+		f.mu.Lock()
+		switch cmd {
+		case "status":
+			fmt.Fprintf(conn, "volume: %d\nstate: %s\n", f.volume, f.state)
+		case "setvol":
+			f.volume, _ = strconv.Atoi(arg)
+		case "play":
+			f.state = StatusPlay
+		case "pause":
+			if arg == "1" {
+				f.state = StatusPause
+			} else {
+				f.state = StatusPlay
+			}
+		case "stop":
+			f.state = StatusStop
+		case "close":
+			f.mu.Unlock()
+			return
+		}
+		f.mu.Unlock()
+		fmt.Fprint(conn, "OK\n")
+	}
+}
+
+func (f *fakeMPD) get() (int, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.volume, f.state
+}
+
+func TestFadeSettings(t *testing.T) {
+	s := NewService(nil)
+	if got := s.FadeSettings(); got.Enabled || got != DefaultFadeSettings {
+		t.Errorf("default FadeSettings() = %+v, want %+v with fades off", got, DefaultFadeSettings)
+	}
+
+	want := FadeSettings{Enabled: true, FadeInMs: 2000, FadeOutMs: 0}
+	if err := s.SetFadeSettings(want); err != nil {
+		t.Fatalf("SetFadeSettings failed: %v", err)
+	}
+	if got := s.FadeSettings(); got != want {
+		t.Errorf("FadeSettings() = %+v, want %+v", got, want)
+	}
+
+	for _, invalid := range []FadeSettings{
+		{FadeInMs: -1},
+		{FadeOutMs: MaxFadeMs + 1},
+	} {
+		if err := s.SetFadeSettings(invalid); err == nil {
+			t.Errorf("SetFadeSettings(%+v) succeeded, want error", invalid)
+		}
+	}
+	if got := s.FadeSettings(); got != want {
+		t.Errorf("invalid settings were applied: %+v", got)
+	}
+}
+
+func TestInterruptFade(t *testing.T) {
+	s := NewService(nil)
+	if got := s.interruptFade(); got != -1 {
+		t.Errorf("interruptFade() with no fade = %d, want -1", got)
+	}
+
+	// A fade whose goroutine exits as soon as it's cancelled
+	f := &volumeFade{target: 65, cancel: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		<-f.cancel
+		close(f.done)
+	}()
+	s.fade = f
+
+	if got := s.interruptFade(); got != 65 {
+		t.Errorf("interruptFade() = %d, want the user's volume 65", got)
+	}
+	if s.fade != nil {
+		t.Error("interruptFade left the fade registered")
+	}
+}
+
+func TestStatusVolume(t *testing.T) {
+	tests := []struct {
+		status map[string]string
+		want   int
+	}{
+		{map[string]string{"volume": "42"}, 42},
+		{map[string]string{"volume": "-1"}, -1},
+		{map[string]string{}, -1},
+		{nil, -1},
+	}
+	for _, tt := range tests {
+		if got := statusVolume(tt.status); got != tt.want {
+			t.Errorf("statusVolume(%v) = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestSetVolume_DuringFadeIn(t *testing.T) {
+	s, fake := newFakeMPDService(t, 80, StatusStop)
+	s.SetFadeSettings(FadeSettings{Enabled: true, FadeInMs: 1000})
+
+	if err := s.Play(-1); err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := s.SetVolume(30); err != nil {
+		t.Fatalf("SetVolume failed: %v", err)
+	}
+
+	// Later fade steps must not overwrite the new volume
+	time.Sleep(time.Second)
+	if volume, state := fake.get(); volume != 30 || state != StatusPlay {
+		t.Errorf("after SetVolume during fade-in: volume %d, state %q; want 30, play", volume, state)
+	}
+}
+
+func TestSetVolume_DuringFadeOut(t *testing.T) {
+	s, fake := newFakeMPDService(t, 80, StatusPlay)
+	s.SetFadeSettings(FadeSettings{Enabled: true, FadeOutMs: 1000})
+
+	if err := s.Pause(); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := s.SetVolume(30); err != nil {
+		t.Fatalf("SetVolume failed: %v", err)
+	}
+
+	// The pause still happens, and the old volume isn't restored over the new one
+	if volume, state := fake.get(); volume != 30 || state != StatusPause {
+		t.Errorf("after SetVolume during fade-out: volume %d, state %q; want 30, pause", volume, state)
+	}
+	time.Sleep(time.Second)
+	if volume, _ := fake.get(); volume != 30 {
+		t.Errorf("volume %d after the fade would have ended, want 30", volume)
+	}
+}
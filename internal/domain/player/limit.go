@@ -0,0 +1,117 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// QueueOverflowPolicy decides what happens when an add would take the queue
+// past its limit.
+type QueueOverflowPolicy string
+
+const (
+	// QueueOverflowReject refuses the add with ErrQueueFull.
+	QueueOverflowReject QueueOverflowPolicy = "reject"
+	// QueueOverflowTrim removes the oldest tracks, never the current one,
+	// to make room.
+	QueueOverflowTrim QueueOverflowPolicy = "trim"
+)
+
+// ErrQueueFull is returned when an add would exceed the queue limit.
+var ErrQueueFull = errors.New("queue is full")
+
+// QueueLimit caps the queue size. It is well above normal listening so it
+// only stops accidents like queueing an entire NAS library, which would make
+// MPD and the UI crawl.
+type QueueLimit struct {
+	Max    int                 `json:"max"`
+	Policy QueueOverflowPolicy `json:"policy"`
+}
+
+// DefaultQueueLimit allows 10000 tracks and rejects adds beyond that.
+var DefaultQueueLimit = QueueLimit{Max: 10000, Policy: QueueOverflowReject}
+
+// SetQueueLimit replaces the queue limit. The current queue is left as is
+// even if it is already over the new limit.
+func (s *Service) SetQueueLimit(limit QueueLimit) error {
+	if limit.Max < 1 {
+		return fmt.Errorf("queue limit must be at least 1")
+	}
+	if limit.Policy != QueueOverflowReject && limit.Policy != QueueOverflowTrim {
+		return fmt.Errorf("unknown queue overflow policy %q", limit.Policy)
+	}
+	log.Info().Int("max", limit.Max).Str("policy", string(limit.Policy)).Msg("SetQueueLimit")
+
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	s.queueLimit = limit
+	return nil
+}
+
+// QueueLimit returns the queue limit.
+func (s *Service) QueueLimit() QueueLimit {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	return s.queueLimit
+}
+
+// reserveQueue makes sure adding tracks keeps the queue within its limit,
+// trimming it first under QueueOverflowTrim. With replace the queue is about
+// to be cleared, so only the added tracks count.
+func (s *Service) reserveQueue(adding int, replace bool) error {
+	limit := s.QueueLimit()
+	if adding > limit.Max {
+		return fmt.Errorf("%w: %d tracks is more than the %d-track limit", ErrQueueFull, adding, limit.Max)
+	}
+	if replace {
+		return nil
+	}
+
+	status, err := s.mpd.Status()
+	if err != nil {
+		return err
+	}
+	length, _ := strconv.Atoi(status["playlistlength"])
+	excess := length + adding - limit.Max
+	if excess <= 0 {
+		return nil
+	}
+	if limit.Policy != QueueOverflowTrim {
+		return fmt.Errorf("%w: adding %d tracks to %d would exceed the %d-track limit", ErrQueueFull, adding, length, limit.Max)
+	}
+
+	current := -1
+	if pos, err := strconv.Atoi(status["song"]); err == nil {
+		current = pos
+	}
+	if current >= 0 && length-1 < excess {
+		// Trimming around the current track cannot free enough room
+		return fmt.Errorf("%w: cannot trim enough tracks without removing the current one", ErrQueueFull)
+	}
+
+	log.Info().Int("tracks", excess).Int("limit", limit.Max).Msg("Trimming oldest queue tracks")
+	for _, r := range trimRanges(current, excess) {
+		if err := s.mpd.DeleteRange(r[0], r[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trimRanges returns the [start, end) position ranges that remove the
+// oldest excess tracks while keeping the one at current. Later ranges come
+// first so deleting one doesn't shift the next.
+func trimRanges(current, excess int) [][2]int {
+	if current < 0 || current >= excess {
+		return [][2]int{{0, excess}}
+	}
+	var ranges [][2]int
+	ranges = append(ranges, [2]int{current + 1, excess + 1})
+	if current > 0 {
+		ranges = append(ranges, [2]int{0, current})
+	}
+	return ranges
+}
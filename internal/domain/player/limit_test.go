@@ -0,0 +1,64 @@
+package player
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTrimRanges(t *testing.T) {
+	tests := []struct {
+		name            string
+		current, excess int
+		want            [][2]int
+	}{
+		{"nothing playing", -1, 3, [][2]int{{0, 3}}},
+		{"current after trimmed tracks", 5, 3, [][2]int{{0, 3}}},
+		{"current at trim boundary", 3, 3, [][2]int{{0, 3}}},
+		{"current first", 0, 3, [][2]int{{1, 4}}},
+		{"current inside trimmed tracks", 2, 4, [][2]int{{3, 5}, {0, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimRanges(tt.current, tt.excess); !slices.Equal(got, tt.want) {
+				t.Errorf("trimRanges(%d, %d) = %v, want %v", tt.current, tt.excess, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetQueueLimit(t *testing.T) {
+	s := NewService(nil)
+	if got := s.QueueLimit(); got != DefaultQueueLimit {
+		t.Errorf("default limit = %+v, want %+v", got, DefaultQueueLimit)
+	}
+
+	for _, limit := range []QueueLimit{
+		{Max: 0, Policy: QueueOverflowReject},
+		{Max: 100, Policy: "drop"},
+	} {
+		if err := s.SetQueueLimit(limit); err == nil {
+			t.Errorf("SetQueueLimit(%+v) succeeded, want error", limit)
+		}
+	}
+
+	want := QueueLimit{Max: 500, Policy: QueueOverflowTrim}
+	if err := s.SetQueueLimit(want); err != nil {
+		t.Fatalf("SetQueueLimit: %v", err)
+	}
+	if got := s.QueueLimit(); got != want {
+		t.Errorf("limit = %+v, want %+v", got, want)
+	}
+}
+
+func TestReserveQueueRejectsOversizedAdd(t *testing.T) {
+	s := NewService(nil)
+	s.SetQueueLimit(QueueLimit{Max: 10, Policy: QueueOverflowTrim})
+
+	// Checked before MPD is consulted, whatever the policy
+	if err := s.reserveQueue(11, true); err == nil {
+		t.Error("reserveQueue allowed more tracks than the limit")
+	}
+	if err := s.reserveQueue(10, true); err != nil {
+		t.Errorf("reserveQueue(10, replace) = %v, want nil", err)
+	}
+}
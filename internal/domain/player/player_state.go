@@ -0,0 +1,98 @@
+package player
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PlayerState is a snapshot of MPD status and the current song, marshaled
+// as the Volumio-compatible pushState payload. Field names and JSON keys
+// follow Volumio, so existing clients read it unchanged.
+type PlayerState struct {
+	// Playback
+	Status   string  `json:"status"`   // play, pause or stop
+	Position int     `json:"position"` // Queue index of the current song, 0 if none
+	Seek     int     `json:"seek"`     // Elapsed time in milliseconds
+	Elapsed  float64 `json:"elapsed"`  // Elapsed time in seconds
+	Duration int     `json:"duration"` // Track length in seconds
+
+	// Playing and StateTimestamp let clients advance Elapsed locally between
+	// broadcasts instead of polling getState: while Playing, elapsed time at
+	// a later StateTimestamp t is Elapsed + (t - StateTimestamp) / 1000.
+	Playing        bool  `json:"playing"`        // Elapsed is advancing in real time
+	StateTimestamp int64 `json:"stateTimestamp"` // Milliseconds on the server's monotonic clock
+
+	// Revision is the server's state and queue revision when this snapshot
+	// was sent, which a reconnecting client passes back to skip a resync.
+	Revision uint64 `json:"revision,omitempty"`
+
+	// Queue
+	QueuePosition int `json:"queuePosition"` // Queue index of the current song, -1 if none
+	QueueLength   int `json:"queueLength"`
+	QueueLimit    int `json:"queueLimit"` // Maximum queue length adds are checked against
+
+	// Volume and playback options
+	Volume               int  `json:"volume"`
+	Mute                 bool `json:"mute"`
+	DisableVolumeControl bool `json:"disableVolumeControl"` // mixer_type is none
+	Random               bool `json:"random"`
+	Repeat               bool `json:"repeat"`
+	RepeatSingle         bool `json:"repeatSingle"`
+	Consume              bool `json:"consume"`
+
+	// Track metadata
+	Title     string `json:"title"`
+	Artist    string `json:"artist"`
+	Album     string `json:"album"`
+	URI       string `json:"uri"`
+	AlbumArt  string `json:"albumart"`
+	TrackType string `json:"trackType,omitempty"` // File extension, e.g. flac
+	Stream    string `json:"stream"`              // Internet radio stream name
+
+	// Audio format, as reported by MPD
+	SampleRate string `json:"samplerate,omitempty"` // e.g. "96000"
+	BitDepth   string `json:"bitdepth,omitempty"`   // e.g. "24"
+	Channels   string `json:"channels,omitempty"`   // e.g. "2"
+
+	Service    string `json:"service"`
+	BitPerfect bool   `json:"bitperfect"`
+	Volatile   bool   `json:"volatile"` // Set for external services like Spotify
+
+	// Error is the last playback error, e.g. the output device failing to open.
+	Error string `json:"error,omitempty"`
+
+	// ExternalSource and ExternalClient are set while another source, such
+	// as AirPlay, is playing instead of MPD.
+	ExternalSource string `json:"externalSource,omitempty"`
+	ExternalClient string `json:"externalClient,omitempty"`
+
+	audioFormat string // MPD's audio field the format fields were parsed from
+}
+
+// clockStart anchors StateTimestamp, which counts from process start so wall
+// clock adjustments, e.g. from NTP on a Pi without an RTC, don't make it jump.
+var clockStart = time.Now()
+
+// StateTimestamp returns the current time on the clock PlayerState.StateTimestamp
+// uses.
+func StateTimestamp() int64 {
+	return time.Since(clockStart).Milliseconds()
+}
+
+// AudioFormat returns the format as MPD reports it, e.g. "96000:24:2" or
+// "dsd64:2", or "" if unknown. See audio.ParseMPDFormat.
+func (s *PlayerState) AudioFormat() string {
+	return s.audioFormat
+}
+
+// Map returns the state as a generic map with its JSON keys, for code that
+// still works on untyped state.
+func (s *PlayerState) Map() map[string]interface{} {
+	m := make(map[string]interface{})
+	data, err := json.Marshal(s)
+	if err != nil {
+		return m
+	}
+	json.Unmarshal(data, &m)
+	return m
+}
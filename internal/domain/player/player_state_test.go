@@ -0,0 +1,116 @@
+package player
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildStatePopulatesPlayerState(t *testing.T) {
+	status := map[string]string{
+		"state":          "play",
+		"song":           "1",
+		"elapsed":        "12.5",
+		"duration":       "240.2",
+		"volume":         "80",
+		"random":         "1",
+		"single":         "1",
+		"playlistlength": "3",
+		"audio":          "96000:24:2",
+	}
+	song := map[string]string{"file": "NAS/Album/02 Song.FLAC", "Artist": "Artist", "Album": "Album"}
+
+	got := NewService(nil).buildState(status, song)
+
+	want := PlayerState{
+		Status:        StatusPlay,
+		Position:      1,
+		Seek:          12500,
+		Elapsed:       12.5,
+		Duration:      240,
+		Playing:       true,
+		QueuePosition: 1,
+		QueueLength:   3,
+		QueueLimit:    DefaultQueueLimit.Max,
+		Volume:        80,
+		Random:        true,
+		RepeatSingle:  true,
+		Title:         "02 Song.FLAC",
+		Artist:        "Artist",
+		Album:         "Album",
+		URI:           "NAS/Album/02 Song.FLAC",
+		AlbumArt:      "/albumart?path=NAS/Album/02 Song.FLAC",
+		TrackType:     "flac",
+		SampleRate:    "96000",
+		BitDepth:      "24",
+		Channels:      "2",
+		Service:       "mpd",
+		BitPerfect:    true,
+		audioFormat:   "96000:24:2",
+	}
+	if *got != want {
+		t.Errorf("buildState =\n%+v\nwant\n%+v", *got, want)
+	}
+	if f := got.AudioFormat(); f != "96000:24:2" {
+		t.Errorf("AudioFormat() = %q, want 96000:24:2", f)
+	}
+}
+
+func TestBuildStateAudioFormats(t *testing.T) {
+	tests := []struct {
+		audio                          string
+		sampleRate, bitDepth, channels string
+	}{
+		{"44100:16:2", "44100", "16", "2"},
+		{"96000:f:2", "96000", "32", "2"},
+		{"dsd64:2", "2822400", "1", "2"},
+		{"dsd256:6", "11289600", "1", "6"},
+		{"", "", "", ""},
+		{"garbage", "", "", ""},
+	}
+	s := NewService(nil)
+	for _, tt := range tests {
+		state := s.buildState(map[string]string{"state": "play", "audio": tt.audio}, map[string]string{})
+		if state.SampleRate != tt.sampleRate || state.BitDepth != tt.bitDepth || state.Channels != tt.channels {
+			t.Errorf("audio %q: got %s/%s/%s, want %s/%s/%s", tt.audio,
+				state.SampleRate, state.BitDepth, state.Channels, tt.sampleRate, tt.bitDepth, tt.channels)
+		}
+	}
+}
+
+func TestBuildStateStopped(t *testing.T) {
+	got := NewService(nil).buildState(map[string]string{"state": "stop", "volume": "-1"}, map[string]string{})
+
+	if got.Status != StatusStop || got.Title != "" || got.AlbumArt != "" || got.AudioFormat() != "" {
+		t.Errorf("stopped state = %+v", *got)
+	}
+	if !got.DisableVolumeControl {
+		t.Error("DisableVolumeControl = false with volume -1")
+	}
+}
+
+func TestPlayerStateJSONKeys(t *testing.T) {
+	// Clients read these Volumio keys; optional ones are left out when empty
+	state := &PlayerState{Status: StatusPause, RepeatSingle: true, AlbumArt: "/albumart?path=a.flac"}
+
+	var m map[string]interface{}
+	data, _ := json.Marshal(state)
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for _, key := range []string{"status", "position", "seek", "duration", "volume", "random", "repeat",
+		"repeatSingle", "title", "artist", "album", "uri", "albumart", "service", "bitperfect", "queuePosition",
+		"playing", "stateTimestamp"} {
+		if _, ok := m[key]; !ok {
+			t.Errorf("missing key %q", key)
+		}
+	}
+	for _, key := range []string{"samplerate", "bitdepth", "channels", "error", "externalSource"} {
+		if _, ok := m[key]; ok {
+			t.Errorf("unexpected empty key %q", key)
+		}
+	}
+
+	if got := state.Map(); got["status"] != "pause" || got["repeatSingle"] != true {
+		t.Errorf("Map() = %v", got)
+	}
+}
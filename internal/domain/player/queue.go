@@ -0,0 +1,115 @@
+package player
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxQueueSearchResults caps how many matches SearchQueue returns.
+	MaxQueueSearchResults = 100
+
+	// MaxQueuePriority is the highest priority MPD accepts.
+	MaxQueuePriority = 255
+)
+
+// SearchQueue returns the queue items whose title, artist or album contain
+// every word of query, case-insensitively, with their queue positions under
+// "position". At most MaxQueueSearchResults items are returned.
+func (s *Service) SearchQueue(query string) ([]map[string]interface{}, error) {
+	log.Debug().Str("query", query).Msg("SearchQueue")
+
+	playlist, err := s.mpd.PlaylistInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	queue := make([]map[string]interface{}, len(playlist))
+	for i, song := range playlist {
+		queue[i] = queueItem(song)
+	}
+	return filterQueue(queue, query, MaxQueueSearchResults), nil
+}
+
+// filterQueue returns up to limit items matching every word of query,
+// annotated with their queue positions. An empty query matches nothing.
+func filterQueue(queue []map[string]interface{}, query string, limit int) []map[string]interface{} {
+	terms := strings.Fields(strings.ToLower(query))
+	matches := make([]map[string]interface{}, 0)
+	if len(terms) == 0 {
+		return matches
+	}
+
+	for pos, item := range queue {
+		var text strings.Builder
+		for _, field := range []string{"title", "artist", "album"} {
+			if v, ok := item[field].(string); ok {
+				text.WriteString(strings.ToLower(v))
+				text.WriteByte('\n')
+			}
+		}
+
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(text.String(), term) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		item["position"] = pos
+		matches = append(matches, item)
+		if len(matches) == limit {
+			break
+		}
+	}
+	return matches
+}
+
+// ShuffleQueue permanently reorders the queue once. This is distinct from
+// random mode (SetRandom), which leaves the queue order alone and picks
+// tracks at random during playback until it is switched off.
+func (s *Service) ShuffleQueue() error {
+	log.Info().Msg("ShuffleQueue")
+	return s.mpd.Shuffle()
+}
+
+// AddAlbumShuffled appends every audio file under an album directory to the
+// queue in random order. The queued songs are returned in that order.
+func (s *Service) AddAlbumShuffled(uri string) ([]map[string]string, error) {
+	log.Info().Str("uri", uri).Msg("AddAlbumShuffled")
+
+	songs, err := s.folderSongs(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reserveQueue(len(songs), false); err != nil {
+		return nil, err
+	}
+
+	rand.Shuffle(len(songs), func(i, j int) {
+		songs[i], songs[j] = songs[j], songs[i]
+	})
+	return songs, s.mpd.AddMany(songURIs(songs))
+}
+
+// SetQueuePriority sets the priority of the track at a queue position. With
+// random mode on, higher priority tracks are picked sooner; the visible queue
+// order is unchanged. 0 is the default priority.
+func (s *Service) SetQueuePriority(pos, prio int) error {
+	if pos < 0 {
+		return fmt.Errorf("invalid queue position %d", pos)
+	}
+	if prio < 0 || prio > MaxQueuePriority {
+		return fmt.Errorf("priority must be between 0 and %d", MaxQueuePriority)
+	}
+	log.Info().Int("position", pos).Int("prio", prio).Msg("SetQueuePriority")
+	return s.mpd.SetPriority(pos, prio)
+}
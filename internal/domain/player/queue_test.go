@@ -0,0 +1,67 @@
+package player
+
+import "testing"
+
+func TestFilterQueue(t *testing.T) {
+	songs := []map[string]string{
+		{"file": "NAS/Miles Davis/Kind of Blue/01 So What.flac", "Title": "So What", "Artist": "Miles Davis", "Album": "Kind of Blue"},
+		{"file": "NAS/Miles Davis/Kind of Blue/02 Freddie Freeloader.flac", "Title": "Freddie Freeloader", "Artist": "Miles Davis", "Album": "Kind of Blue"},
+		{"file": "USB/Coltrane/Blue Train/01 Blue Train.flac", "Title": "Blue Train", "Artist": "John Coltrane", "Album": "Blue Train"},
+		{"file": "NAS/untagged/track.flac"},
+	}
+	queue := func() []map[string]interface{} {
+		items := make([]map[string]interface{}, len(songs))
+		for i, song := range songs {
+			items[i] = queueItem(song)
+		}
+		return items
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		limit     int
+		positions []int
+	}{
+		{"by title", "freddie", 10, []int{1}},
+		{"by artist, case-insensitive", "MILES", 10, []int{0, 1}},
+		{"by album", "blue", 10, []int{0, 1, 2}},
+		{"every word must match", "blue coltrane", 10, []int{2}},
+		{"words across fields", "so what miles", 10, []int{0}},
+		{"filename fallback title", "track.flac", 10, []int{3}},
+		{"limit", "blue", 2, []int{0, 1}},
+		{"no match", "mingus", 10, nil},
+		{"empty query", "   ", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterQueue(queue(), tt.query, tt.limit)
+			if len(got) != len(tt.positions) {
+				t.Fatalf("got %d matches, want %d: %v", len(got), len(tt.positions), got)
+			}
+			for i, item := range got {
+				if item["position"] != tt.positions[i] {
+					t.Errorf("match %d position = %v, want %d", i, item["position"], tt.positions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueueItemPriority(t *testing.T) {
+	if got := queueItem(map[string]string{"file": "a.flac"})["prio"]; got != 0 {
+		t.Errorf("prio without Prio tag = %v, want 0", got)
+	}
+	if got := queueItem(map[string]string{"file": "a.flac", "Prio": "200"})["prio"]; got != 200 {
+		t.Errorf("prio = %v, want 200", got)
+	}
+}
+
+func TestSetQueuePriorityRejectsInvalid(t *testing.T) {
+	s := NewService(nil)
+	for _, tt := range []struct{ pos, prio int }{{-1, 10}, {0, -1}, {0, MaxQueuePriority + 1}} {
+		if err := s.SetQueuePriority(tt.pos, tt.prio); err == nil {
+			t.Errorf("SetQueuePriority(%d, %d) succeeded, want error", tt.pos, tt.prio)
+		}
+	}
+}
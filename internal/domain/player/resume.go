@@ -0,0 +1,248 @@
+package player
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/atomicfile"
+)
+
+const (
+	// DefaultQueueSaveInterval is how often the queue and position are checked for saving.
+	DefaultQueueSaveInterval = 5 * time.Second
+
+	// elapsedSaveStep limits saves while a track simply plays on: the snapshot
+	// is rewritten when elapsed has moved this far since the last save.
+	elapsedSaveStep = 30 * time.Second
+)
+
+// QueueSnapshot is the persisted queue, position and last play/stop intent.
+type QueueSnapshot struct {
+	URIs     []string  `json:"uris"`
+	Position int       `json:"position"` // Queue index of the current song, -1 if none
+	Elapsed  float64   `json:"elapsed"`  // Seconds into the current song
+	Playing  bool      `json:"playing"`  // False once the user stopped or paused
+	SavedAt  time.Time `json:"savedAt"`
+}
+
+// QueueStore persists the queue snapshot so it survives restarts and power cuts.
+type QueueStore struct {
+	filePath string
+}
+
+// NewQueueStore creates a queue store in dataDir.
+func NewQueueStore(dataDir string) *QueueStore {
+	return &QueueStore{filePath: filepath.Join(dataDir, "queue.json")}
+}
+
+// Load reads the saved snapshot. It returns nil without error when none was saved.
+func (q *QueueStore) Load() (*QueueSnapshot, error) {
+	data, err := os.ReadFile(q.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snap QueueSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("invalid queue snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// Save writes the snapshot, replacing the previous one atomically so a power
+// cut mid-write leaves the old snapshot intact.
+func (q *QueueStore) Save(snap QueueSnapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(q.filePath, data, 0600)
+}
+
+// RestoreResult describes what Restore did.
+type RestoreResult struct {
+	Restored int  // Tracks in the queue after restoring
+	Skipped  int  // Saved tracks that could no longer be added
+	Position int  // Queue index playback resumes from, -1 if none
+	Playing  bool // Whether playback was started
+}
+
+// Restore loads a snapshot into the MPD queue and, when autoplay is set and
+// the user had not stopped playback, resumes from the saved position. Tracks
+// that are no longer in the library are skipped; if the current one is gone,
+// playback starts at the next surviving track.
+func (s *Service) Restore(snap QueueSnapshot, autoplay bool) (RestoreResult, error) {
+	result := RestoreResult{Position: -1}
+
+	current, err := s.queueURIs()
+	if err != nil {
+		return result, err
+	}
+
+	added := make([]bool, len(snap.URIs))
+	if slices.Equal(current, snap.URIs) {
+		// MPD restored the same queue from its own state file
+		for i := range added {
+			added[i] = true
+		}
+	} else {
+		if err := s.mpd.Clear(); err != nil {
+			return result, err
+		}
+		for i, uri := range snap.URIs {
+			if err := s.mpd.Add(uri); err != nil {
+				log.Debug().Err(err).Str("uri", uri).Msg("Skipping saved queue track")
+				continue
+			}
+			added[i] = true
+		}
+	}
+
+	for _, ok := range added {
+		if ok {
+			result.Restored++
+		} else {
+			result.Skipped++
+		}
+	}
+	result.Position = remapPosition(added, snap.Position)
+
+	if !autoplay || !snap.Playing || result.Position < 0 {
+		return result, nil
+	}
+
+	if err := s.mpd.Play(result.Position); err != nil {
+		return result, err
+	}
+	result.Playing = true
+
+	// Resume mid-track only if the saved track itself survived
+	if snap.Position >= 0 && snap.Position < len(added) && added[snap.Position] && snap.Elapsed >= 1 {
+		if err := s.mpd.Seek(int(snap.Elapsed)); err != nil {
+			log.Warn().Err(err).Msg("Failed to seek to saved position")
+		}
+	}
+	return result, nil
+}
+
+// remapPosition maps a saved queue index onto the restored queue, moving to
+// the next restored track when the saved one was skipped.
+func remapPosition(added []bool, position int) int {
+	if position < 0 {
+		position = 0
+	}
+	index := 0
+	for i, ok := range added {
+		if !ok {
+			continue
+		}
+		if i >= position {
+			return index
+		}
+		index++
+	}
+	return -1
+}
+
+// queueURIs returns the file URIs in the MPD queue.
+func (s *Service) queueURIs() ([]string, error) {
+	items, err := s.mpd.PlaylistInfo()
+	if err != nil {
+		return nil, err
+	}
+	uris := make([]string, len(items))
+	for i, item := range items {
+		uris[i] = item["file"]
+	}
+	return uris, nil
+}
+
+// StartQueuePersistence saves the queue snapshot to store whenever the
+// queue, position or play/stop intent changes, until ctx is cancelled.
+func (s *Service) StartQueuePersistence(ctx context.Context, store *QueueStore, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultQueueSaveInterval
+	}
+
+	go func() {
+		log.Info().Dur("interval", interval).Msg("Queue persistence started")
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var (
+			last        *QueueSnapshot
+			lastVersion string
+			uris        []string
+		)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("Queue persistence stopped")
+				return
+			case <-ticker.C:
+				status, err := s.mpd.Status()
+				if err != nil {
+					continue
+				}
+				// Only refetch the queue when MPD's playlist version moved
+				if version := status["playlist"]; version != lastVersion || uris == nil {
+					if uris, err = s.queueURIs(); err != nil {
+						continue
+					}
+					lastVersion = version
+				}
+
+				snap := snapshotFromStatus(status, uris)
+				if !needsSave(last, snap) {
+					continue
+				}
+				snap.SavedAt = time.Now()
+				if err := store.Save(snap); err != nil {
+					log.Warn().Err(err).Msg("Failed to save queue snapshot")
+					continue
+				}
+				last = &snap
+			}
+		}
+	}()
+}
+
+// snapshotFromStatus builds a snapshot from MPD status and the queue URIs.
+func snapshotFromStatus(status map[string]string, uris []string) QueueSnapshot {
+	snap := QueueSnapshot{
+		URIs:     uris,
+		Position: -1,
+		Playing:  status["state"] == StatusPlay,
+	}
+	if pos, err := strconv.Atoi(status["song"]); err == nil {
+		snap.Position = pos
+	}
+	if elapsed, err := strconv.ParseFloat(status["elapsed"], 64); err == nil {
+		snap.Elapsed = elapsed
+	}
+	return snap
+}
+
+// needsSave reports whether next differs enough from the last saved snapshot.
+func needsSave(last *QueueSnapshot, next QueueSnapshot) bool {
+	if last == nil {
+		return true
+	}
+	if last.Position != next.Position || last.Playing != next.Playing || !slices.Equal(last.URIs, next.URIs) {
+		return true
+	}
+	drift := time.Duration((next.Elapsed - last.Elapsed) * float64(time.Second))
+	return drift >= elapsedSaveStep || drift <= -elapsedSaveStep
+}
@@ -0,0 +1,101 @@
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueueStore_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	store := NewQueueStore(dir)
+
+	snap, err := store.Load()
+	if err != nil || snap != nil {
+		t.Fatalf("Load() with no file = %v, %v; want nil, nil", snap, err)
+	}
+
+	want := QueueSnapshot{URIs: []string{"NAS/a.flac", "NAS/b.flac"}, Position: 1, Elapsed: 42.5, Playing: true}
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(got.URIs) != 2 || got.Position != 1 || got.Elapsed != 42.5 || !got.Playing {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("data dir has %d entries, want only queue.json", len(entries))
+	}
+
+	os.WriteFile(filepath.Join(dir, "queue.json"), []byte("{"), 0644)
+	if _, err := store.Load(); err == nil {
+		t.Error("Load accepted a corrupt snapshot")
+	}
+}
+
+func TestRemapPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		added    []bool
+		position int
+		want     int
+	}{
+		{"all restored", []bool{true, true, true}, 2, 2},
+		{"earlier track skipped", []bool{false, true, true}, 2, 1},
+		{"current track skipped", []bool{true, false, true}, 1, 1},
+		{"current and later skipped", []bool{true, false, false}, 1, -1},
+		{"no current song", []bool{true, true}, -1, 0},
+		{"empty queue", nil, 0, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remapPosition(tt.added, tt.position); got != tt.want {
+				t.Errorf("remapPosition(%v, %d) = %d, want %d", tt.added, tt.position, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotFromStatus(t *testing.T) {
+	uris := []string{"a.flac", "b.flac"}
+
+	snap := snapshotFromStatus(map[string]string{"state": "play", "song": "1", "elapsed": "12.3"}, uris)
+	if snap.Position != 1 || snap.Elapsed != 12.3 || !snap.Playing {
+		t.Errorf("playing snapshot = %+v", snap)
+	}
+
+	snap = snapshotFromStatus(map[string]string{"state": "stop"}, uris)
+	if snap.Position != -1 || snap.Playing {
+		t.Errorf("stopped snapshot = %+v, want position -1 and not playing", snap)
+	}
+}
+
+func TestNeedsSave(t *testing.T) {
+	last := &QueueSnapshot{URIs: []string{"a.flac"}, Position: 0, Elapsed: 10, Playing: true}
+
+	tests := []struct {
+		name string
+		next QueueSnapshot
+		want bool
+	}{
+		{"unchanged", QueueSnapshot{URIs: []string{"a.flac"}, Position: 0, Elapsed: 15, Playing: true}, false},
+		{"elapsed moved on", QueueSnapshot{URIs: []string{"a.flac"}, Position: 0, Elapsed: 45, Playing: true}, true},
+		{"seek back", QueueSnapshot{URIs: []string{"a.flac"}, Position: 0, Elapsed: 0, Playing: true}, false},
+		{"stopped", QueueSnapshot{URIs: []string{"a.flac"}, Position: 0, Elapsed: 10}, true},
+		{"queue changed", QueueSnapshot{URIs: []string{"a.flac", "b.flac"}, Position: 0, Elapsed: 10, Playing: true}, true},
+	}
+	for _, tt := range tests {
+		if got := needsSave(last, tt.next); got != tt.want {
+			t.Errorf("%s: needsSave = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !needsSave(nil, QueueSnapshot{}) {
+		t.Error("needsSave(nil) = false, want true")
+	}
+}
@@ -2,11 +2,14 @@
 package player
 
 import (
+	"fmt"
 	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/audio"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
 	"github.com/rs/zerolog/log"
 )
@@ -36,17 +39,39 @@ var audioExtensions = map[string]bool{
 // Service handles player operations.
 type Service struct {
 	mpd *mpd.Client
+
+	// Sleep timer
+	sleepMu       sync.Mutex
+	sleep         *sleepTimer
+	sleepListener func(SleepTimerStatus)
+
+	// Volume fades
+	transportMu  sync.Mutex // Serializes play/pause/stop/volume while fades start and stop
+	fadeMu       sync.Mutex
+	fade         *volumeFade
+	fadeSettings FadeSettings
+
+	// Queue size limit
+	queueMu    sync.Mutex
+	queueLimit QueueLimit
+
+	// Album art URLs
+	artMu         sync.RWMutex
+	artClassifier TrackClassifier
+	streamingArt  StreamingArtFunc
 }
 
 // NewService creates a new player service.
 func NewService(mpdClient *mpd.Client) *Service {
 	return &Service{
-		mpd: mpdClient,
+		mpd:          mpdClient,
+		fadeSettings: DefaultFadeSettings,
+		queueLimit:   DefaultQueueLimit,
 	}
 }
 
 // GetState returns the current player state in Volumio-compatible format.
-func (s *Service) GetState() (map[string]interface{}, error) {
+func (s *Service) GetState() (*PlayerState, error) {
 	status, err := s.mpd.Status()
 	if err != nil {
 		return nil, err
@@ -59,138 +84,105 @@ func (s *Service) GetState() (map[string]interface{}, error) {
 	}
 
 	state := s.buildState(status, song)
+	state.StateTimestamp = StateTimestamp()
 	return state, nil
 }
 
 // buildState converts MPD status and song to Volumio-compatible state.
-func (s *Service) buildState(status, song map[string]string) map[string]interface{} {
-	state := make(map[string]interface{})
+func (s *Service) buildState(status, song map[string]string) *PlayerState {
+	state := &PlayerState{
+		Status:  StatusStop,
+		Volume:  100,
+		Service: "mpd",
+		// Bit-perfect indicator (we're always bit-perfect with our config)
+		BitPerfect: true,
+	}
 
 	// Playback status
-	mpdState := status["state"]
-	switch mpdState {
+	switch status["state"] {
 	case "play":
-		state["status"] = "play"
+		state.Status = StatusPlay
+		state.Playing = true
 	case "pause":
-		state["status"] = "pause"
-	default:
-		state["status"] = "stop"
+		state.Status = StatusPause
 	}
 
 	// Position in queue
 	if pos, err := strconv.Atoi(status["song"]); err == nil {
-		state["position"] = pos
-	} else {
-		state["position"] = 0
+		state.Position = pos
 	}
 
 	// Seek position in milliseconds (MPD returns seconds with decimal)
 	if elapsed, err := strconv.ParseFloat(status["elapsed"], 64); err == nil {
-		state["seek"] = int(elapsed * 1000)
-	} else {
-		state["seek"] = 0
+		state.Elapsed = elapsed
+		state.Seek = int(elapsed * 1000)
 	}
 
 	// Duration in seconds
 	if duration, err := strconv.ParseFloat(status["duration"], 64); err == nil {
-		state["duration"] = int(duration)
+		state.Duration = int(duration)
 	} else if duration, err := strconv.ParseFloat(song["Time"], 64); err == nil {
-		state["duration"] = int(duration)
-	} else {
-		state["duration"] = 0
+		state.Duration = int(duration)
 	}
 
 	// Volume
 	if vol, err := strconv.Atoi(status["volume"]); err == nil {
-		state["volume"] = vol
-	} else {
-		state["volume"] = 100
+		state.Volume = vol
 	}
 
 	// Playback options
-	state["random"] = status["random"] == "1"
-	state["repeat"] = status["repeat"] == "1"
-	state["repeatSingle"] = status["single"] == "1"
-	state["consume"] = status["consume"] == "1"
-	state["mute"] = false // MPD doesn't have mute, we'd track this separately
+	state.Random = status["random"] == "1"
+	state.Repeat = status["repeat"] == "1"
+	state.RepeatSingle = status["single"] == "1"
+	state.Consume = status["consume"] == "1"
+
+	// Queue size and the limit adds are checked against
+	state.QueueLength, _ = strconv.Atoi(status["playlistlength"])
+	state.QueuePosition = queuePosition(status["song"], state.QueueLength)
+	state.QueueLimit = s.QueueLimit().Max
 
 	// Track metadata
-	state["title"] = song["Title"]
-	if state["title"] == "" {
+	state.Title = song["Title"]
+	if state.Title == "" {
 		// Use filename if no title tag
 		if file := song["file"]; file != "" {
 			parts := strings.Split(file, "/")
-			state["title"] = parts[len(parts)-1]
-		} else {
-			state["title"] = ""
+			state.Title = parts[len(parts)-1]
 		}
 	}
 
-	state["artist"] = song["Artist"]
-	state["album"] = song["Album"]
-	state["uri"] = song["file"]
+	state.Artist = song["Artist"]
+	state.Album = song["Album"]
+	state.URI = song["file"]
 
-	// Album art - we'll need to implement albumart endpoint
-	if file := song["file"]; file != "" {
-		state["albumart"] = "/albumart?path=" + file
-	} else {
-		state["albumart"] = ""
-	}
-
-	// Audio format info
-	if audio := status["audio"]; audio != "" {
-		// Format: samplerate:bits:channels (e.g., "96000:24:2")
-		parts := strings.Split(audio, ":")
-		if len(parts) >= 2 {
-			state["samplerate"] = parts[0]
-			state["bitdepth"] = parts[1]
-		}
-		if len(parts) >= 3 {
-			state["channels"] = parts[2]
-		}
+	// Album art from the /albumart endpoint, or the streaming service
+	state.AlbumArt = s.AlbumArtURL(song["file"])
+
+	// Audio format info, e.g. "96000:24:2", "96000:f:2" or "dsd64:2"
+	if format := audio.ParseMPDFormat(status["audio"]); format != nil {
+		state.audioFormat = status["audio"]
+		state.SampleRate = strconv.Itoa(format.SampleRate)
+		state.BitDepth = strconv.Itoa(format.BitDepth)
+		state.Channels = strconv.Itoa(format.Channels)
 	}
 
 	// Track type from file extension
 	if file := song["file"]; file != "" {
 		if idx := strings.LastIndex(file, "."); idx != -1 {
-			state["trackType"] = strings.ToLower(file[idx+1:])
+			state.TrackType = strings.ToLower(file[idx+1:])
 		}
 	}
 
-	// Service identifier
-	state["service"] = "mpd"
-
-	// Bit-perfect indicator (we're always bit-perfect with our config)
-	state["bitperfect"] = true
-
-	// Volatile state (for external services like Spotify)
-	state["volatile"] = false
-
 	// Stream info (for internet radio)
-	state["stream"] = song["Name"] // Internet radio stream name
+	state.Stream = song["Name"] // Internet radio stream name
 
 	// Disable volume control indicator (when mixer_type is none)
-	state["disableVolumeControl"] = status["volume"] == "-1"
+	state.DisableVolumeControl = status["volume"] == "-1"
 
-	return state
-}
+	// Last playback error, e.g. the output device failing to open
+	state.Error = status["error"]
 
-// Play starts playback at the given position, or resumes if pos < 0.
-func (s *Service) Play(pos int) error {
-	log.Info().Int("position", pos).Msg("Play")
-	return s.mpd.Play(pos)
-}
-
-// Pause pauses playback.
-func (s *Service) Pause() error {
-	log.Info().Msg("Pause")
-	return s.mpd.Pause(true)
-}
-
-// Stop stops playback.
-func (s *Service) Stop() error {
-	log.Info().Msg("Stop")
-	return s.mpd.Stop()
+	return state
 }
 
 // Next plays the next track.
@@ -211,9 +203,21 @@ func (s *Service) Seek(pos int) error {
 	return s.mpd.Seek(pos)
 }
 
-// SetVolume sets the volume (0-100).
+// SetVolume sets the volume (0-100). It takes over from any fade in
+// progress: a fade-in stops where it is, and a fade-out pauses or stops
+// playback at once, leaving the new volume in place. The sleep timer's
+// fade-out is cancelled along with the timer.
 func (s *Service) SetVolume(vol int) error {
 	log.Info().Int("volume", vol).Msg("SetVolume")
+
+	s.transportMu.Lock()
+	defer s.transportMu.Unlock()
+
+	if f := s.stopFade(); f != nil && f.halt != nil {
+		if err := f.halt(); err != nil {
+			log.Warn().Err(err).Msg("Failed to halt playback after interrupted fade-out")
+		}
+	}
 	return s.mpd.SetVolume(vol)
 }
 
@@ -241,38 +245,49 @@ func (s *Service) GetQueue() ([]map[string]interface{}, error) {
 
 	queue := make([]map[string]interface{}, len(playlist))
 	for i, song := range playlist {
-		item := make(map[string]interface{})
-		item["uri"] = song["file"]
-		item["title"] = song["Title"]
-		if item["title"] == "" {
-			// Use filename if no title
-			parts := strings.Split(song["file"], "/")
-			item["title"] = parts[len(parts)-1]
-		}
-		item["artist"] = song["Artist"]
-		item["album"] = song["Album"]
-		item["service"] = "mpd"
+		queue[i] = queueItem(song)
+	}
 
-		if duration, err := strconv.Atoi(song["Time"]); err == nil {
-			item["duration"] = duration
-		}
+	return queue, nil
+}
 
-		// Track type from extension
-		if file := song["file"]; file != "" {
-			if idx := strings.LastIndex(file, "."); idx != -1 {
-				item["trackType"] = strings.ToLower(file[idx+1:])
-			}
-		}
+// queueItem converts an MPD queue entry to a Volumio-compatible queue item.
+func queueItem(song map[string]string) map[string]interface{} {
+	item := make(map[string]interface{})
+	item["uri"] = song["file"]
+	item["title"] = song["Title"]
+	if item["title"] == "" {
+		// Use filename if no title
+		parts := strings.Split(song["file"], "/")
+		item["title"] = parts[len(parts)-1]
+	}
+	item["artist"] = song["Artist"]
+	item["album"] = song["Album"]
+	item["service"] = "mpd"
 
-		// Album art
-		if file := song["file"]; file != "" {
-			item["albumart"] = "/albumart?path=" + file
+	if duration, err := strconv.Atoi(song["Time"]); err == nil {
+		item["duration"] = duration
+	}
+
+	// MPD only reports non-zero priorities
+	item["prio"] = 0
+	if prio, err := strconv.Atoi(song["Prio"]); err == nil {
+		item["prio"] = prio
+	}
+
+	// Track type from extension
+	if file := song["file"]; file != "" {
+		if idx := strings.LastIndex(file, "."); idx != -1 {
+			item["trackType"] = strings.ToLower(file[idx+1:])
 		}
+	}
 
-		queue[i] = item
+	// Album art
+	if file := song["file"]; file != "" {
+		item["albumart"] = "/albumart?path=" + file
 	}
 
-	return queue, nil
+	return item
 }
 
 // ClearQueue clears the queue.
@@ -284,9 +299,117 @@ func (s *Service) ClearQueue() error {
 // AddToQueue adds a URI to the queue.
 func (s *Service) AddToQueue(uri string) error {
 	log.Info().Str("uri", uri).Msg("AddToQueue")
+
+	// A directory adds everything beneath it
+	adding := 1
+	if !isAudioFile(uri) {
+		if songs, err := s.folderSongs(uri); err == nil {
+			adding = len(songs)
+		}
+	}
+	if err := s.reserveQueue(adding, false); err != nil {
+		return err
+	}
 	return s.mpd.Add(uri)
 }
 
+// PlayFolder queues every audio file under a directory, recursively and in
+// path order, and starts playing the first one. When replace is true the
+// queue is cleared first; otherwise the files are appended and playback
+// starts at the first appended file. The queued songs are returned.
+func (s *Service) PlayFolder(uri string, replace bool) ([]map[string]string, error) {
+	log.Info().Str("uri", uri).Bool("replace", replace).Msg("PlayFolder")
+
+	songs, err := s.folderSongs(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reserveQueue(len(songs), replace); err != nil {
+		return nil, err
+	}
+
+	startPos := 0
+	if replace {
+		if err := s.mpd.Clear(); err != nil {
+			return nil, err
+		}
+	} else if startPos, err = s.mpd.GetQueueLength(); err != nil {
+		return nil, err
+	}
+
+	if err := s.mpd.AddMany(songURIs(songs)); err != nil {
+		return nil, err
+	}
+
+	return songs, s.mpd.Play(startPos)
+}
+
+// AddFolder appends every audio file under a directory to the queue,
+// recursively and in path order. The queued songs are returned.
+func (s *Service) AddFolder(uri string) ([]map[string]string, error) {
+	log.Info().Str("uri", uri).Msg("AddFolder")
+
+	songs, err := s.folderSongs(uri)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reserveQueue(len(songs), false); err != nil {
+		return nil, err
+	}
+
+	return songs, s.mpd.AddMany(songURIs(songs))
+}
+
+// folderSongs lists the audio files under a directory.
+func (s *Service) folderSongs(uri string) ([]map[string]string, error) {
+	uri = strings.Trim(uri, "/")
+	if uri == "" {
+		return nil, fmt.Errorf("folder uri is required")
+	}
+
+	entries, err := s.mpd.ListAllInfo(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]map[string]string, len(entries))
+	for i, entry := range entries {
+		files[i] = entry
+	}
+
+	songs := filterAudioSongs(files)
+	if len(songs) == 0 {
+		return nil, fmt.Errorf("no audio files found in %s", uri)
+	}
+	return songs, nil
+}
+
+// filterAudioSongs keeps the audio file entries of a recursive listing,
+// sorted by path so albums and discs play in order.
+func filterAudioSongs(entries []map[string]string) []map[string]string {
+	var songs []map[string]string
+	for _, entry := range entries {
+		if file, ok := entry["file"]; ok && isAudioFile(file) {
+			songs = append(songs, entry)
+		}
+	}
+
+	sort.SliceStable(songs, func(i, j int) bool {
+		return songs[i]["file"] < songs[j]["file"]
+	})
+	return songs
+}
+
+// songURIs returns the file URIs of the given songs.
+func songURIs(songs []map[string]string) []string {
+	uris := make([]string, len(songs))
+	for i, song := range songs {
+		uris[i] = song["file"]
+	}
+	return uris
+}
+
 // BrowseLibrary returns directory contents in Volumio-compatible format.
 func (s *Service) BrowseLibrary(uri string) (map[string]interface{}, error) {
 	// Handle special URIs
@@ -543,23 +666,61 @@ func (s *Service) AddAndPlay(uri string) error {
 	return s.ReplaceAndPlay(uri)
 }
 
-// InsertNext adds a URI to the queue right after the currently playing track.
-// If nothing is playing (currentPos == -1), it adds to position 0 (beginning of queue).
+// InsertNext adds a URI to the queue right after the current track, so it
+// plays next rather than at the end. The track is appended with addid and
+// then moved by ID, which stays correct if the queue shifts in between. With
+// no current track it goes to the front of the queue, where play starts.
 func (s *Service) InsertNext(uri string) error {
 	log.Info().Str("uri", uri).Msg("InsertNext")
 
-	// Get current position (-1 if nothing playing)
+	// Trimming shifts positions, so make room before reading the current one
+	if err := s.reserveQueue(1, false); err != nil {
+		return err
+	}
+
+	// -1 if there is no current track
 	currentPos, err := s.mpd.GetCurrentPosition()
 	if err != nil {
 		currentPos = -1
 	}
 
-	// Insert after current track: if currentPos is -1, insertPos becomes 0 (beginning)
-	// if currentPos is 3, insertPos becomes 4 (after the current track)
-	insertPos := currentPos + 1
+	id, err := s.mpd.AddId(uri, -1)
+	if err != nil {
+		return err
+	}
+
+	length, err := s.mpd.GetQueueLength()
+	if err != nil {
+		return err
+	}
+	if to := nextPosition(currentPos, length); to >= 0 {
+		return s.mpd.MoveId(id, to)
+	}
+	return nil
+}
 
-	_, err = s.mpd.AddId(uri, insertPos)
-	return err
+// queuePosition returns the queue index of the current song for clients to
+// scroll to, or -1 if there is none. Unlike "position" it never defaults to
+// 0. MPD keeps "song" while stopped on a track and while repeating a single
+// track, and drops it once playback runs off the end of the queue; an index
+// past the end (status read mid-edit) also counts as none.
+func queuePosition(song string, queueLength int) int {
+	pos, err := strconv.Atoi(song)
+	if err != nil || pos < 0 || pos >= queueLength {
+		return -1
+	}
+	return pos
+}
+
+// nextPosition returns where a track just appended to a queue of length
+// songs must move to play after the track at current, or -1 if it is already
+// there.
+func nextPosition(current, length int) int {
+	to := current + 1
+	if to >= length-1 {
+		return -1
+	}
+	return to
 }
 
 // MoveQueueItem moves a track from one position to another in the queue.
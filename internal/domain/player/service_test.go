@@ -1,6 +1,7 @@
 package player
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -234,6 +235,34 @@ func (m *ExtendedMockMPDClient) GetQueueLength() (int, error) {
 	return m.QueueLengthReturn, nil
 }
 
+func TestFilterAudioSongs(t *testing.T) {
+	entries := []map[string]string{
+		{"directory": "NAS/Share/Artist/Album/CD2"},
+		{"file": "NAS/Share/Artist/Album/CD2/01 - Third.flac"},
+		{"file": "NAS/Share/Artist/Album/cover.jpg"},
+		{"file": "NAS/Share/Artist/Album/CD1/02 - Second.flac"},
+		{"playlist": "NAS/Share/Artist/Album/album.cue"},
+		{"file": "NAS/Share/Artist/Album/CD1/01 - First.flac"},
+	}
+
+	songs := filterAudioSongs(entries)
+
+	want := []string{
+		"NAS/Share/Artist/Album/CD1/01 - First.flac",
+		"NAS/Share/Artist/Album/CD1/02 - Second.flac",
+		"NAS/Share/Artist/Album/CD2/01 - Third.flac",
+	}
+	got := songURIs(songs)
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d songs, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Song %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
 // Test Toggle functionality
 func TestToggle_PlaysWhenStopped(t *testing.T) {
 	mock := &ExtendedMockMPDClient{
@@ -313,6 +342,51 @@ func TestInsertNext_InsertsAfterCurrent(t *testing.T) {
 	}
 }
 
+func TestNextPosition(t *testing.T) {
+	tests := []struct {
+		name            string
+		current, length int
+		want            int
+	}{
+		{"after current mid-queue", 3, 10, 4},
+		{"current is last", 9, 11, -1},
+		{"current is second to last", 8, 11, 9},
+		{"no current track", -1, 5, 0},
+		{"only track in queue", -1, 1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPosition(tt.current, tt.length); got != tt.want {
+				t.Errorf("nextPosition(%d, %d) = %d, want %d", tt.current, tt.length, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildStateQueuePosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		status map[string]string
+		want   int
+	}{
+		{"playing", map[string]string{"state": "play", "song": "3", "playlistlength": "10"}, 3},
+		{"stopped on a track", map[string]string{"state": "stop", "song": "0", "playlistlength": "4"}, 0},
+		{"repeating a single track", map[string]string{"state": "play", "song": "2", "playlistlength": "4", "repeat": "1", "single": "1"}, 2},
+		{"stopped after the last track", map[string]string{"state": "stop", "playlistlength": "4"}, -1},
+		{"empty queue", map[string]string{"state": "stop", "playlistlength": "0"}, -1},
+		{"song past the end", map[string]string{"state": "play", "song": "5", "playlistlength": "5"}, -1},
+	}
+	s := NewService(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := s.buildState(tt.status, map[string]string{})
+			if state.QueuePosition != tt.want {
+				t.Errorf("QueuePosition = %d, want %d", state.QueuePosition, tt.want)
+			}
+		})
+	}
+}
+
 // Test MoveQueueItem functionality
 func TestMoveQueueItem_ReordersCorrectly(t *testing.T) {
 	mock := &ExtendedMockMPDClient{}
@@ -342,3 +416,56 @@ func TestRemoveQueueItem_DeletesItem(t *testing.T) {
 		t.Errorf("RemoveQueueItem should delete position 3, got %d", mock.DeletePosition)
 	}
 }
+
+func TestParseCommandValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		arg    interface{}
+		want   int
+		wantOK bool
+	}{
+		{"float from JSON", float64(42), 42, true},
+		{"int", 7, 7, true},
+		{"numeric string", " 30 ", 30, true},
+		{"object with value", map[string]interface{}{"value": float64(3)}, 3, true},
+		{"object with string value", map[string]interface{}{"value": "12"}, 12, true},
+		{"object without value", map[string]interface{}{"uri": "x"}, 0, false},
+		{"non-numeric string", "loud", 0, false},
+		{"nil", nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseCommandValue(tt.arg)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseCommandValue(%v) = (%d, %v), want (%d, %v)", tt.arg, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestClampVolume(t *testing.T) {
+	for in, want := range map[int]int{-5: 0, 0: 0, 55: 55, 100: 100, 150: 100} {
+		if got := clampVolume(in); got != want {
+			t.Errorf("clampVolume(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestExecuteCommand_RejectsBadInput(t *testing.T) {
+	// These fail validation before reaching MPD, so no client is needed
+	s := &Service{}
+
+	if err := s.ExecuteCommand("rewind", 0, false); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("unknown command error = %v, want ErrUnknownCommand", err)
+	}
+	if err := s.ExecuteCommand(CommandSeek, 0, false); !errors.Is(err, ErrInvalidCommandValue) {
+		t.Errorf("seek without value error = %v, want ErrInvalidCommandValue", err)
+	}
+	if err := s.ExecuteCommand(CommandSeek, -10, true); !errors.Is(err, ErrInvalidCommandValue) {
+		t.Errorf("negative seek error = %v, want ErrInvalidCommandValue", err)
+	}
+	if err := s.ExecuteCommand(CommandVolume, 0, false); !errors.Is(err, ErrInvalidCommandValue) {
+		t.Errorf("volume without value error = %v, want ErrInvalidCommandValue", err)
+	}
+}
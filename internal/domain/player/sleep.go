@@ -0,0 +1,212 @@
+package player
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxSleepTimerMinutes caps the sleep timer at 12 hours.
+	MaxSleepTimerMinutes = 12 * 60
+
+	// sleepFadeDuration is how long the volume fades before stopping.
+	sleepFadeDuration = 30 * time.Second
+)
+
+// ErrInvalidSleepTimer is returned for sleep timers outside 1..MaxSleepTimerMinutes.
+var ErrInvalidSleepTimer = errors.New("sleep timer must be between 1 and 720 minutes")
+
+// SleepTimerStatus describes the sleep timer.
+type SleepTimerStatus struct {
+	Active    bool      `json:"active"`
+	Remaining int       `json:"remaining"`        // Seconds until playback stops
+	EndsAt    time.Time `json:"endsAt,omitempty"` // When playback stops
+}
+
+// sleepTimer is an armed sleep timer. timer first fires when the fade-out
+// is due, and is re-armed for endsAt when there is nothing to fade.
+type sleepTimer struct {
+	endsAt time.Time
+	timer  *time.Timer
+}
+
+// SetSleepTimerListener sets a function called whenever the sleep timer is
+// set, cancelled or expires.
+func (s *Service) SetSleepTimerListener(fn func(SleepTimerStatus)) {
+	s.sleepMu.Lock()
+	defer s.sleepMu.Unlock()
+	s.sleepListener = fn
+}
+
+// SetSleepTimer stops playback after minutes, replacing any running timer.
+// With a software mixer the volume fades out over the final 30 seconds and
+// is restored after stopping; without one (bit-perfect) playback just stops.
+// Play, pause, stop and volume changes during the fade cancel the timer.
+func (s *Service) SetSleepTimer(minutes int) (SleepTimerStatus, error) {
+	if minutes < 1 || minutes > MaxSleepTimerMinutes {
+		return s.SleepTimer(), ErrInvalidSleepTimer
+	}
+	log.Info().Int("minutes", minutes).Msg("SetSleepTimer")
+
+	s.transportMu.Lock()
+	s.cancelSleepFade()
+
+	s.sleepMu.Lock()
+	s.cancelSleepLocked()
+	t := &sleepTimer{endsAt: time.Now().Add(time.Duration(minutes) * time.Minute)}
+	t.timer = time.AfterFunc(time.Until(t.endsAt)-sleepFadeDuration, func() { s.runSleepTimer(t) })
+	s.sleep = t
+	status := t.status()
+	listener := s.sleepListener
+	s.sleepMu.Unlock()
+	s.transportMu.Unlock()
+
+	if listener != nil {
+		listener(status)
+	}
+	return status, nil
+}
+
+// CancelSleepTimer cancels the sleep timer, if any, restoring the volume if
+// it was fading out.
+func (s *Service) CancelSleepTimer() {
+	s.transportMu.Lock()
+	s.cancelSleepFade()
+
+	s.sleepMu.Lock()
+	if s.sleep == nil {
+		s.sleepMu.Unlock()
+		s.transportMu.Unlock()
+		return
+	}
+	log.Info().Msg("CancelSleepTimer")
+	s.cancelSleepLocked()
+	listener := s.sleepListener
+	s.sleepMu.Unlock()
+	s.transportMu.Unlock()
+
+	if listener != nil {
+		listener(SleepTimerStatus{})
+	}
+}
+
+// SleepTimer returns the sleep timer status.
+func (s *Service) SleepTimer() SleepTimerStatus {
+	s.sleepMu.Lock()
+	defer s.sleepMu.Unlock()
+	if s.sleep == nil {
+		return SleepTimerStatus{}
+	}
+	return s.sleep.status()
+}
+
+// cancelSleepLocked disarms the current timer. Must be called with sleepMu held.
+func (s *Service) cancelSleepLocked() {
+	if s.sleep == nil {
+		return
+	}
+	s.sleep.timer.Stop()
+	s.sleep = nil
+}
+
+// cancelSleepFade interrupts the sleep timer's fade-out, if one is running,
+// and restores the volume. Interrupting the fade ends the timer. Must be
+// called with transportMu held.
+func (s *Service) cancelSleepFade() {
+	s.fadeMu.Lock()
+	sleeping := s.fade != nil && s.fade.sleep != nil
+	s.fadeMu.Unlock()
+
+	if sleeping {
+		if prev := s.interruptFade(); prev >= 0 {
+			s.mpd.SetVolume(prev)
+		}
+	}
+}
+
+// endSleep clears t if it is still the current timer and notifies the
+// listener. It reports whether t was current.
+func (s *Service) endSleep(t *sleepTimer) bool {
+	s.sleepMu.Lock()
+	if s.sleep != t {
+		// Cancelled or replaced
+		s.sleepMu.Unlock()
+		return false
+	}
+	s.cancelSleepLocked()
+	listener := s.sleepListener
+	s.sleepMu.Unlock()
+
+	if listener != nil {
+		listener(SleepTimerStatus{})
+	}
+	return true
+}
+
+// runSleepTimer starts the sleep timer's fade-out, which stops playback
+// when it ends. Without a volume to fade, or while playback isn't running,
+// the timer is re-armed to stop playback when it ends instead.
+func (s *Service) runSleepTimer(t *sleepTimer) {
+	s.transportMu.Lock()
+	defer s.transportMu.Unlock()
+
+	s.sleepMu.Lock()
+	current := s.sleep == t
+	s.sleepMu.Unlock()
+	if !current {
+		return
+	}
+
+	s.fadeMu.Lock()
+	fadingOut := s.fade != nil && s.fade.halt != nil
+	s.fadeMu.Unlock()
+
+	status, err := s.mpd.Status()
+	volume := statusVolume(status)
+	if fadingOut || err != nil || volume < 0 || status["state"] != StatusPlay {
+		s.sleepMu.Lock()
+		if s.sleep == t {
+			t.timer = time.AfterFunc(time.Until(t.endsAt), func() {
+				s.transportMu.Lock()
+				defer s.transportMu.Unlock()
+				if err := s.expireSleepTimer(t); err != nil {
+					log.Warn().Err(err).Msg("Sleep timer failed to stop playback")
+				}
+			})
+		}
+		s.sleepMu.Unlock()
+		return
+	}
+
+	// Take over from a fade-in, fading down from wherever it got to
+	target := volume
+	if prev := s.interruptFade(); prev >= 0 {
+		target = prev
+	}
+	duration := max(time.Until(t.endsAt), fadeStepInterval)
+	f := s.startFade(volume, 0, target, duration, func() error { return s.expireSleepTimer(t) })
+	f.sleep = t
+}
+
+// expireSleepTimer stops playback unless t was cancelled or replaced.
+func (s *Service) expireSleepTimer(t *sleepTimer) error {
+	if !s.endSleep(t) {
+		return nil
+	}
+	log.Info().Msg("Sleep timer expired, stopping playback")
+	return s.mpd.Stop()
+}
+
+func (t *sleepTimer) status() SleepTimerStatus {
+	remaining := time.Until(t.endsAt).Round(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return SleepTimerStatus{
+		Active:    true,
+		Remaining: int(remaining.Seconds()),
+		EndsAt:    t.endsAt,
+	}
+}
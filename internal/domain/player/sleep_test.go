@@ -0,0 +1,138 @@
+package player
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSleepTimer_SetAndCancel(t *testing.T) {
+	s := NewService(nil)
+
+	var notified []SleepTimerStatus
+	s.SetSleepTimerListener(func(status SleepTimerStatus) {
+		notified = append(notified, status)
+	})
+
+	if status := s.SleepTimer(); status.Active {
+		t.Fatalf("SleepTimer() before setting = %+v, want inactive", status)
+	}
+
+	status, err := s.SetSleepTimer(30)
+	if err != nil {
+		t.Fatalf("SetSleepTimer failed: %v", err)
+	}
+	if !status.Active || status.Remaining != 30*60 {
+		t.Errorf("SetSleepTimer(30) = %+v, want active with 1800s remaining", status)
+	}
+
+	// Replacing the timer keeps a single one running
+	if status, _ = s.SetSleepTimer(10); status.Remaining != 10*60 {
+		t.Errorf("SetSleepTimer(10) remaining = %d, want 600", status.Remaining)
+	}
+	if got := s.SleepTimer(); !got.Active || got.Remaining > 10*60 {
+		t.Errorf("SleepTimer() = %+v, want the replacement timer", got)
+	}
+
+	s.CancelSleepTimer()
+	if got := s.SleepTimer(); got.Active {
+		t.Errorf("SleepTimer() after cancel = %+v, want inactive", got)
+	}
+	s.CancelSleepTimer() // No-op without a timer
+
+	if len(notified) != 3 {
+		t.Fatalf("listener called %d times, want 3 (set, set, cancel)", len(notified))
+	}
+	if !notified[0].Active || !notified[1].Active || notified[2].Active {
+		t.Errorf("listener statuses = %+v", notified)
+	}
+}
+
+func TestSleepTimer_RejectsInvalidMinutes(t *testing.T) {
+	s := NewService(nil)
+	for _, minutes := range []int{0, -5, MaxSleepTimerMinutes + 1} {
+		if _, err := s.SetSleepTimer(minutes); err != ErrInvalidSleepTimer {
+			t.Errorf("SetSleepTimer(%d) error = %v, want ErrInvalidSleepTimer", minutes, err)
+		}
+	}
+	if s.SleepTimer().Active {
+		t.Error("invalid SetSleepTimer armed a timer")
+	}
+}
+
+// startSleepFade arms a sleep timer ending after d and starts its fade-out.
+func startSleepFade(s *Service, d time.Duration) {
+	t := &sleepTimer{endsAt: time.Now().Add(d), timer: time.NewTimer(time.Hour)}
+	s.sleepMu.Lock()
+	s.sleep = t
+	s.sleepMu.Unlock()
+	s.runSleepTimer(t)
+}
+
+func TestSleepTimer_Fade(t *testing.T) {
+	tests := []struct {
+		name       string
+		interrupt  func(s *Service) // Runs mid-fade; nil lets the timer expire
+		wantVolume int
+		wantState  string
+	}{
+		{
+			name:       "expires",
+			wantVolume: 80,
+			wantState:  StatusStop,
+		},
+		{
+			name:       "play cancels",
+			interrupt:  func(s *Service) { s.Play(-1) },
+			wantVolume: 80,
+			wantState:  StatusPlay,
+		},
+		{
+			name:       "volume change cancels",
+			interrupt:  func(s *Service) { s.SetVolume(50) },
+			wantVolume: 50,
+			wantState:  StatusPlay,
+		},
+		{
+			name:       "cancel restores volume",
+			interrupt:  func(s *Service) { s.CancelSleepTimer() },
+			wantVolume: 80,
+			wantState:  StatusPlay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake := newFakeMPDService(t, 80, StatusPlay)
+			var mu sync.Mutex
+			var notified []SleepTimerStatus
+			s.SetSleepTimerListener(func(status SleepTimerStatus) {
+				mu.Lock()
+				defer mu.Unlock()
+				notified = append(notified, status)
+			})
+
+			startSleepFade(s, 400*time.Millisecond)
+			if tt.interrupt != nil {
+				time.Sleep(150 * time.Millisecond)
+				if volume, _ := fake.get(); volume >= 80 {
+					t.Errorf("volume %d mid-fade, want below 80", volume)
+				}
+				tt.interrupt(s)
+			}
+
+			time.Sleep(600 * time.Millisecond)
+			if volume, state := fake.get(); volume != tt.wantVolume || state != tt.wantState {
+				t.Errorf("volume %d, state %q; want %d, %q", volume, state, tt.wantVolume, tt.wantState)
+			}
+			if status := s.SleepTimer(); status.Active {
+				t.Errorf("SleepTimer() = %+v, want inactive", status)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(notified) != 1 || notified[0].Active {
+				t.Errorf("listener statuses = %+v, want one inactive", notified)
+			}
+		})
+	}
+}
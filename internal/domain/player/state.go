@@ -1,4 +1,3 @@
-// Package player provides the core player domain logic for audio playback control.
 package player
 
 import "sync"
@@ -1,10 +1,23 @@
 package sources
 
+import "context"
+
 // Discoverer defines the interface for NAS device discovery.
+// When ctx ends, implementations stop and return what they have found so far
+// together with ctx.Err().
 type Discoverer interface {
 	// DiscoverDevices finds NAS devices on the local network.
-	DiscoverDevices() ([]NasDevice, error)
+	DiscoverDevices(ctx context.Context) ([]NasDevice, error)
 
 	// BrowseShares lists available shares on a NAS host.
-	BrowseShares(host, username, password string) ([]ShareInfo, error)
+	BrowseShares(ctx context.Context, host, username, password string) ([]ShareInfo, error)
+}
+
+// UsbDetector defines the interface for finding USB storage partitions.
+type UsbDetector interface {
+	// ListPartitions returns the mountable partitions on attached USB storage devices.
+	ListPartitions() ([]UsbDrive, error)
+
+	// PowerOff powers down the USB device holding a partition so it can be removed safely.
+	PowerOff(device string) error
 }
@@ -0,0 +1,175 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultHealthCheckInterval is how often mounted NAS shares are checked.
+	DefaultHealthCheckInterval = 60 * time.Second
+	// mountStatTimeout bounds the stat of a mount point. A dead NAS mount can
+	// block stat calls for a long time, so a timeout counts as unhealthy.
+	mountStatTimeout = 5 * time.Second
+)
+
+// ShareHealthChange describes a NAS share whose mounted state changed during a health check.
+type ShareHealthChange struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Mounted   bool   `json:"mounted"`
+	Remounted bool   `json:"remounted"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CheckMountHealth checks every configured NAS share, remounting shares that are
+// unmounted or whose mount point no longer responds. It returns the shares whose
+// mounted state changed since the previous check.
+func (s *Service) CheckMountHealth() []ShareHealthChange {
+	s.mu.RLock()
+	mounter := s.mounter
+	shares := make(map[string]string, len(s.config.NasShares))
+	for id, cfg := range s.config.NasShares {
+		shares[id] = cfg.Name
+	}
+	s.mu.RUnlock()
+
+	if mounter == nil {
+		return nil
+	}
+
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	// Forget shares that have been deleted since the last check
+	for id := range s.lastMounted {
+		if _, exists := shares[id]; !exists {
+			delete(s.lastMounted, id)
+		}
+	}
+
+	var changes []ShareHealthChange
+	for id, name := range shares {
+		mountPoint := filepath.Join(NasMountBase, sanitizeName(name))
+		mounted := mounter.IsMounted(mountPoint)
+		healthy := mounted
+		change := ShareHealthChange{ID: id, Name: name}
+
+		if mounted {
+			// The stat goroutine of a hung mount can outlive its timeout. Leave
+			// the share alone until it returns rather than stacking up more.
+			if !s.beginStat(mountPoint) {
+				log.Debug().Str("id", id).Str("mountPoint", mountPoint).Msg("Previous NAS mount check still running, skipping")
+				continue
+			}
+			stat := func(path string) (os.FileInfo, error) {
+				defer s.endStat(path)
+				return s.statPath(path)
+			}
+			if err := statWithTimeout(stat, mountPoint, s.statTimeout); err != nil {
+				log.Warn().Err(err).Str("id", id).Str("mountPoint", mountPoint).Msg("NAS mount is stale")
+				healthy = false
+				if err := mounter.Unmount(mountPoint); err != nil {
+					log.Warn().Err(err).Str("id", id).Msg("Failed to unmount stale NAS share")
+				}
+			}
+		}
+
+		if !healthy {
+			result, err := s.MountNasShare(id)
+			switch {
+			case err != nil:
+				change.Error = err.Error()
+			case !result.Success:
+				change.Error = result.Error
+			default:
+				healthy = true
+				change.Remounted = true
+				log.Info().Str("id", id).Msg("NAS share remounted by health check")
+			}
+		}
+
+		previous, known := s.lastMounted[id]
+		if !known {
+			previous = mounted
+		}
+		s.lastMounted[id] = healthy
+
+		if healthy != previous || change.Remounted {
+			change.Mounted = healthy
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// StartHealthWatcher periodically runs CheckMountHealth until ctx is cancelled.
+// onChange is called with the changed shares whenever a check reports changes.
+func (s *Service) StartHealthWatcher(ctx context.Context, interval time.Duration, onChange func([]ShareHealthChange)) {
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+
+	go func() {
+		log.Info().Dur("interval", interval).Msg("NAS health watcher started")
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("NAS health watcher stopped")
+				return
+			case <-ticker.C:
+				changes := s.CheckMountHealth()
+				if len(changes) > 0 && onChange != nil {
+					onChange(changes)
+				}
+			}
+		}
+	}()
+}
+
+// beginStat marks a stat of mountPoint as running. It returns false if one
+// is already running.
+func (s *Service) beginStat(mountPoint string) bool {
+	s.statMu.Lock()
+	defer s.statMu.Unlock()
+	if s.statsRunning[mountPoint] {
+		return false
+	}
+	if s.statsRunning == nil {
+		s.statsRunning = make(map[string]bool)
+	}
+	s.statsRunning[mountPoint] = true
+	return true
+}
+
+// endStat marks the stat of mountPoint as returned.
+func (s *Service) endStat(mountPoint string) {
+	s.statMu.Lock()
+	defer s.statMu.Unlock()
+	delete(s.statsRunning, mountPoint)
+}
+
+// statWithTimeout stats a path, giving up after timeout.
+func statWithTimeout(stat func(string) (os.FileInfo, error), path string, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := stat(path)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("stat %s timed out after %s", path, timeout)
+	}
+}
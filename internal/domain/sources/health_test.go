@@ -0,0 +1,164 @@
+package sources
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHealthTestService creates a service with one mounted CIFS share.
+func newHealthTestService(t *testing.T) (*Service, *MockMounter) {
+	t.Helper()
+
+	mounter := NewMockMounter()
+	s, err := NewService(filepath.Join(t.TempDir(), "sources.json"), mounter)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	result, err := s.AddNasShare(AddNasShareRequest{
+		Name:   "Music",
+		IP:     "192.168.1.100",
+		Path:   "Music",
+		FSType: "cifs",
+	})
+	if err != nil || !result.Success {
+		t.Fatalf("AddNasShare failed: %v %v", err, result)
+	}
+
+	s.statPath = func(string) (os.FileInfo, error) { return nil, nil }
+	return s, mounter
+}
+
+func TestService_CheckMountHealth_Healthy(t *testing.T) {
+	s, mounter := newHealthTestService(t)
+	mounter.MountCalled = false
+
+	if changes := s.CheckMountHealth(); len(changes) != 0 {
+		t.Errorf("CheckMountHealth returned %d changes, want 0", len(changes))
+	}
+	if mounter.MountCalled {
+		t.Error("healthy share should not be remounted")
+	}
+}
+
+func TestService_CheckMountHealth_RemountsStaleShare(t *testing.T) {
+	s, mounter := newHealthTestService(t)
+	s.statPath = func(string) (os.FileInfo, error) {
+		return nil, errors.New("host is down")
+	}
+	mounter.MountCalled = false
+
+	changes := s.CheckMountHealth()
+
+	if !mounter.UnmountCalled {
+		t.Error("stale share should be unmounted before remounting")
+	}
+	if !mounter.MountCalled {
+		t.Error("stale share should be remounted")
+	}
+	if len(changes) != 1 {
+		t.Fatalf("CheckMountHealth returned %d changes, want 1", len(changes))
+	}
+	if !changes[0].Mounted || !changes[0].Remounted {
+		t.Errorf("change = %+v, want mounted and remounted", changes[0])
+	}
+}
+
+func TestService_CheckMountHealth_ReportsLostShare(t *testing.T) {
+	s, mounter := newHealthTestService(t)
+
+	// First check records the share as mounted
+	if changes := s.CheckMountHealth(); len(changes) != 0 {
+		t.Fatalf("first CheckMountHealth returned %d changes, want 0", len(changes))
+	}
+
+	// NAS goes away and cannot be remounted
+	mounter.MountedPaths = make(map[string]bool)
+	mounter.MountError = errors.New("connection refused")
+
+	changes := s.CheckMountHealth()
+	if len(changes) != 1 {
+		t.Fatalf("CheckMountHealth returned %d changes, want 1", len(changes))
+	}
+	if changes[0].Mounted {
+		t.Error("change.Mounted = true, want false")
+	}
+	if changes[0].Error == "" {
+		t.Error("change.Error is empty, want mount error")
+	}
+
+	// State unchanged on the next check, so nothing to report
+	if changes := s.CheckMountHealth(); len(changes) != 0 {
+		t.Errorf("repeat CheckMountHealth returned %d changes, want 0", len(changes))
+	}
+
+	// NAS comes back
+	mounter.MountError = nil
+	changes = s.CheckMountHealth()
+	if len(changes) != 1 || !changes[0].Mounted {
+		t.Errorf("CheckMountHealth after recovery = %+v, want one mounted change", changes)
+	}
+}
+
+func TestStatWithTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	hanging := func(string) (os.FileInfo, error) {
+		<-block
+		return nil, nil
+	}
+
+	if err := statWithTimeout(hanging, "/mnt/NAS/Music", 10*time.Millisecond); err == nil {
+		t.Error("statWithTimeout on hanging stat returned nil, want timeout error")
+	}
+
+	if err := statWithTimeout(os.Stat, t.TempDir(), time.Second); err != nil {
+		t.Errorf("statWithTimeout on existing dir returned %v, want nil", err)
+	}
+}
+
+func TestService_CheckMountHealth_SkipsShareWhileStatHangs(t *testing.T) {
+	s, mounter := newHealthTestService(t)
+
+	s.statTimeout = 10 * time.Millisecond
+
+	block := make(chan struct{})
+	var calls atomic.Int32
+	s.statPath = func(string) (os.FileInfo, error) {
+		if calls.Add(1) == 1 {
+			<-block
+		}
+		return nil, nil
+	}
+
+	// First check times out and remounts
+	s.CheckMountHealth()
+	mounter.UnmountCalled = false
+
+	// The hung stat is still running, so the share is left alone
+	if changes := s.CheckMountHealth(); len(changes) != 0 {
+		t.Errorf("CheckMountHealth returned %d changes, want 0", len(changes))
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("stat called %d times while the first was hung, want 1", n)
+	}
+	if mounter.UnmountCalled {
+		t.Error("share should not be unmounted while its stat is still running")
+	}
+
+	// Once the stat returns the share is checked again
+	close(block)
+	for i := 0; i < 100; i++ {
+		s.CheckMountHealth()
+		if calls.Load() == 2 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("stat called %d times after the hung stat returned, want 2", calls.Load())
+}
@@ -2,6 +2,7 @@ package sources
 
 import (
 	"bufio"
+	"context"
 	"net"
 	"os/exec"
 	"strings"
@@ -10,7 +11,9 @@ import (
 )
 
 // LinuxDiscoverer implements Discoverer using Linux tools.
-type LinuxDiscoverer struct{}
+type LinuxDiscoverer struct {
+	wsddAddr *net.UDPAddr // WS-Discovery probe destination; defaults to the multicast group
+}
 
 // NewLinuxDiscoverer creates a new Linux-based NAS discoverer.
 func NewLinuxDiscoverer() *LinuxDiscoverer {
@@ -18,22 +21,26 @@ func NewLinuxDiscoverer() *LinuxDiscoverer {
 }
 
 // DiscoverDevices finds NAS devices on the local network using nmblookup.
-func (d *LinuxDiscoverer) DiscoverDevices() ([]NasDevice, error) {
+func (d *LinuxDiscoverer) DiscoverDevices(ctx context.Context) ([]NasDevice, error) {
 	log.Info().Msg("Starting NAS discovery...")
 	devices := make([]NasDevice, 0)
 	seen := make(map[string]bool)
 
 	// Method 1: Use nmblookup to find SMB servers
-	nmbDevices := d.discoverViaNmblookup()
+	nmbDevices := d.discoverViaNmblookup(ctx)
 	for _, device := range nmbDevices {
 		if !seen[device.IP] {
 			devices = append(devices, device)
 			seen[device.IP] = true
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		log.Warn().Err(err).Int("count", len(devices)).Msg("NAS discovery stopped early")
+		return devices, err
+	}
 
 	// Method 2: Use avahi-browse for mDNS/Bonjour SMB services
-	avahiDevices := d.discoverViaAvahi()
+	avahiDevices := d.discoverViaAvahi(ctx)
 	for _, device := range avahiDevices {
 		if !seen[device.IP] {
 			devices = append(devices, device)
@@ -41,17 +48,43 @@ func (d *LinuxDiscoverer) DiscoverDevices() ([]NasDevice, error) {
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		log.Warn().Err(err).Int("count", len(devices)).Msg("NAS discovery stopped early")
+		return devices, err
+	}
+
+	// Method 3: WS-Discovery for Windows 10/11 hosts without NetBIOS or mDNS
+	wsddDevices := d.discoverViaWSDD(ctx)
+	for _, device := range wsddDevices {
+		if !seen[device.IP] {
+			devices = append(devices, device)
+			seen[device.IP] = true
+			continue
+		}
+		// Already found; keep its name but add the workgroup
+		for i := range devices {
+			if devices[i].IP == device.IP && devices[i].Workgroup == "" {
+				devices[i].Workgroup = device.Workgroup
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		log.Warn().Err(err).Int("count", len(devices)).Msg("NAS discovery stopped early")
+		return devices, err
+	}
+
 	log.Info().Int("count", len(devices)).Msg("NAS discovery complete")
 	return devices, nil
 }
 
 // discoverViaNmblookup uses nmblookup to find SMB servers.
-func (d *LinuxDiscoverer) discoverViaNmblookup() []NasDevice {
+func (d *LinuxDiscoverer) discoverViaNmblookup(ctx context.Context) []NasDevice {
 	devices := make([]NasDevice, 0)
 
 	// Run: nmblookup -S '*'
 	// This broadcasts to find all NetBIOS names on the network
-	cmd := exec.Command("nmblookup", "-S", "*")
+	cmd := exec.CommandContext(ctx, "nmblookup", "-S", "*")
 	output, err := cmd.Output()
 	if err != nil {
 		log.Debug().Err(err).Msg("nmblookup failed (may not be installed)")
@@ -92,12 +125,12 @@ func (d *LinuxDiscoverer) discoverViaNmblookup() []NasDevice {
 }
 
 // discoverViaAvahi uses avahi-browse to find SMB services.
-func (d *LinuxDiscoverer) discoverViaAvahi() []NasDevice {
+func (d *LinuxDiscoverer) discoverViaAvahi(ctx context.Context) []NasDevice {
 	devices := make([]NasDevice, 0)
 
 	// Run: avahi-browse -rt _smb._tcp
 	// -r = resolve addresses, -t = terminate after scanning
-	cmd := exec.Command("avahi-browse", "-rtp", "_smb._tcp")
+	cmd := exec.CommandContext(ctx, "avahi-browse", "-rtp", "_smb._tcp")
 	output, err := cmd.Output()
 	if err != nil {
 		log.Debug().Err(err).Msg("avahi-browse failed (may not be installed)")
@@ -135,7 +168,7 @@ func (d *LinuxDiscoverer) discoverViaAvahi() []NasDevice {
 }
 
 // BrowseShares lists available shares on a NAS host using smbclient.
-func (d *LinuxDiscoverer) BrowseShares(host, username, password string) ([]ShareInfo, error) {
+func (d *LinuxDiscoverer) BrowseShares(ctx context.Context, host, username, password string) ([]ShareInfo, error) {
 	log.Info().Str("host", host).Msg("Browsing NAS shares...")
 	shares := make([]ShareInfo, 0)
 
@@ -154,9 +187,12 @@ func (d *LinuxDiscoverer) BrowseShares(host, username, password string) ([]Share
 	// Add timeout
 	args = append(args, "--timeout=5")
 
-	cmd := exec.Command("smbclient", args...)
+	cmd := exec.CommandContext(ctx, "smbclient", args...)
 	output, err := cmd.CombinedOutput()
 
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
 	if err != nil {
 		outputStr := string(output)
 		// Check for specific errors
@@ -11,12 +11,23 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// smbFallbackVersions are the SMB protocol versions tried, in order, when a
+// CIFS mount fails with the requested version.
+var smbFallbackVersions = []string{"3.0", "2.1", "1.0"}
+
 // LinuxMounter implements the Mounter interface using Linux mount commands.
-type LinuxMounter struct{}
+type LinuxMounter struct {
+	// runCommand executes a mount command and returns its combined output.
+	runCommand func(name string, args ...string) ([]byte, error)
+}
 
 // NewLinuxMounter creates a new Linux mounter.
 func NewLinuxMounter() *LinuxMounter {
-	return &LinuxMounter{}
+	return &LinuxMounter{
+		runCommand: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).CombinedOutput()
+		},
+	}
 }
 
 // Mount mounts a NAS share using the appropriate protocol.
@@ -31,20 +42,24 @@ func (m *LinuxMounter) Mount(share *NasShare) error {
 	}
 }
 
-// mountCifs mounts a CIFS/SMB share.
+// mountCifs mounts a CIFS/SMB share. If the mount fails, older SMB protocol
+// versions are tried in turn; the version that succeeded is stored in
+// share.SMBVersion so it can be tried first next time.
 func (m *LinuxMounter) mountCifs(share *NasShare) error {
 	// Build the source path: //IP/SharePath
 	source := fmt.Sprintf("//%s/%s", share.IP, share.Path)
 
 	// Build mount options
 	opts := []string{
-		"ro",
 		"dir_mode=0777",
 		"file_mode=0666",
 		"iocharset=utf8",
 		"noauto",
 		"soft",
 	}
+	if share.ReadOnly {
+		opts = append(opts, "ro")
+	}
 
 	// Add credentials if provided
 	if share.Username != "" {
@@ -56,33 +71,74 @@ func (m *LinuxMounter) mountCifs(share *NasShare) error {
 		opts = append(opts, "guest")
 	}
 
-	// Add custom options if provided
-	if share.Options != "" {
-		opts = append(opts, share.Options)
+	// Add custom options if provided; the SMB version is set per attempt
+	requested, custom := splitSMBVersion(share.Options)
+	if custom != "" {
+		opts = append(opts, custom)
 	}
 
-	optStr := strings.Join(opts, ",")
+	var lastErr error
+	for _, vers := range smbVersionCandidates(share.SMBVersion, requested) {
+		optStr := strings.Join(append(opts, "vers="+vers), ",")
+
+		// Execute mount command with sudo
+		output, err := m.runCommand("sudo", "mount", "-t", "cifs", "-o", optStr, source, share.MountPoint)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("source", source).
+				Str("mountPoint", share.MountPoint).
+				Str("vers", vers).
+				Str("output", string(output)).
+				Msg("CIFS mount failed")
+			lastErr = fmt.Errorf("mount failed: %s", string(output))
+			continue
+		}
 
-	// Execute mount command with sudo
-	cmd := exec.Command("sudo", "mount", "-t", "cifs", "-o", optStr, source, share.MountPoint)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Error().
-			Err(err).
+		log.Info().
 			Str("source", source).
 			Str("mountPoint", share.MountPoint).
-			Str("output", string(output)).
-			Msg("CIFS mount failed")
-		return fmt.Errorf("mount failed: %s", string(output))
+			Str("vers", vers).
+			Msg("CIFS share mounted")
+
+		share.Mounted = true
+		share.SMBVersion = vers
+		return nil
 	}
 
-	log.Info().
-		Str("source", source).
-		Str("mountPoint", share.MountPoint).
-		Msg("CIFS share mounted")
+	return lastErr
+}
 
-	share.Mounted = true
-	return nil
+// splitSMBVersion extracts the vers= option from a mount option string,
+// returning the version and the remaining options.
+func splitSMBVersion(options string) (string, string) {
+	var vers string
+	var rest []string
+	for _, opt := range strings.Split(options, ",") {
+		if opt == "" {
+			continue
+		}
+		if v, ok := strings.CutPrefix(opt, "vers="); ok {
+			vers = v
+			continue
+		}
+		rest = append(rest, opt)
+	}
+	return vers, strings.Join(rest, ",")
+}
+
+// smbVersionCandidates returns the SMB versions to try, in order: the
+// previously negotiated version, the requested version, then the fallbacks.
+func smbVersionCandidates(negotiated, requested string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, vers := range append([]string{negotiated, requested}, smbFallbackVersions...) {
+		if vers != "" && !seen[vers] {
+			seen[vers] = true
+			candidates = append(candidates, vers)
+		}
+	}
+	return candidates
 }
 
 // mountNfs mounts an NFS share.
@@ -92,10 +148,12 @@ func (m *LinuxMounter) mountNfs(share *NasShare) error {
 
 	// Build mount options
 	opts := []string{
-		"ro",
 		"soft",
 		"noauto",
 	}
+	if share.ReadOnly {
+		opts = append(opts, "ro")
+	}
 
 	// Add custom options if provided
 	if share.Options != "" {
@@ -105,8 +163,7 @@ func (m *LinuxMounter) mountNfs(share *NasShare) error {
 	optStr := strings.Join(opts, ",")
 
 	// Execute mount command with sudo
-	cmd := exec.Command("sudo", "mount", "-t", "nfs", "-o", optStr, source, share.MountPoint)
-	output, err := cmd.CombinedOutput()
+	output, err := m.runCommand("sudo", "mount", "-t", "nfs", "-o", optStr, source, share.MountPoint)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -126,6 +183,27 @@ func (m *LinuxMounter) mountNfs(share *NasShare) error {
 	return nil
 }
 
+// MountDevice mounts a local block device read-only, letting mount detect the filesystem.
+func (m *LinuxMounter) MountDevice(device, mountPoint string) error {
+	output, err := m.runCommand("sudo", "mount", "-o", "ro", device, mountPoint)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("device", device).
+			Str("mountPoint", mountPoint).
+			Str("output", string(output)).
+			Msg("Device mount failed")
+		return fmt.Errorf("mount failed: %s", string(output))
+	}
+
+	log.Info().
+		Str("device", device).
+		Str("mountPoint", mountPoint).
+		Msg("Device mounted")
+
+	return nil
+}
+
 // Unmount unmounts a filesystem.
 func (m *LinuxMounter) Unmount(mountPoint string) error {
 	cmd := exec.Command("sudo", "umount", mountPoint)
@@ -0,0 +1,135 @@
+package sources
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFakeLinuxMounter returns a LinuxMounter whose mount commands only succeed
+// for the given SMB version, recording the version of each attempt.
+func newFakeLinuxMounter(workingVers string, attempts *[]string) *LinuxMounter {
+	return &LinuxMounter{
+		runCommand: func(name string, args ...string) ([]byte, error) {
+			var opts string
+			for i, arg := range args {
+				if arg == "-o" && i+1 < len(args) {
+					opts = args[i+1]
+				}
+			}
+			vers, _ := splitSMBVersion(opts)
+			*attempts = append(*attempts, vers)
+			if vers != workingVers {
+				return []byte("mount error(95): Operation not supported"), errors.New("exit status 32")
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestLinuxMounter_MountCifs_FallsBackToOlderVersions(t *testing.T) {
+	var attempts []string
+	m := newFakeLinuxMounter("1.0", &attempts)
+
+	share := &NasShare{IP: "192.168.1.100", Path: "Music", FSType: "cifs", Options: "vers=3.0,iocharset=utf8"}
+	if err := m.Mount(share); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+
+	want := []string{"3.0", "2.1", "1.0"}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempted versions = %v, want %v", attempts, want)
+	}
+	if share.SMBVersion != "1.0" {
+		t.Errorf("share.SMBVersion = %q, want %q", share.SMBVersion, "1.0")
+	}
+	if !share.Mounted {
+		t.Error("share.Mounted = false, want true")
+	}
+}
+
+func TestLinuxMounter_MountCifs_TriesNegotiatedVersionFirst(t *testing.T) {
+	var attempts []string
+	m := newFakeLinuxMounter("2.1", &attempts)
+
+	share := &NasShare{IP: "192.168.1.100", Path: "Music", FSType: "cifs", Options: "vers=3.0", SMBVersion: "2.1"}
+	if err := m.Mount(share); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+
+	if want := []string{"2.1"}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempted versions = %v, want %v", attempts, want)
+	}
+}
+
+func TestLinuxMounter_MountCifs_AllVersionsFail(t *testing.T) {
+	var attempts []string
+	m := newFakeLinuxMounter("", &attempts)
+
+	share := &NasShare{IP: "192.168.1.100", Path: "Music", FSType: "cifs", Options: "vers=2.0"}
+	err := m.Mount(share)
+	if err == nil {
+		t.Fatal("Mount succeeded, want error")
+	}
+	if !strings.Contains(err.Error(), "Operation not supported") {
+		t.Errorf("error = %q, want mount output", err.Error())
+	}
+
+	want := []string{"2.0", "3.0", "2.1", "1.0"}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempted versions = %v, want %v", attempts, want)
+	}
+	if share.Mounted || share.SMBVersion != "" {
+		t.Errorf("share = %+v, want unmounted with no SMB version", share)
+	}
+}
+
+func TestLinuxMounter_Mount_ReadOnlyOption(t *testing.T) {
+	for _, fsType := range []string{"cifs", "nfs"} {
+		for _, readOnly := range []bool{true, false} {
+			var opts string
+			m := &LinuxMounter{
+				runCommand: func(name string, args ...string) ([]byte, error) {
+					opts = args[len(args)-3]
+					return nil, nil
+				},
+			}
+
+			share := &NasShare{IP: "192.168.1.100", Path: "Music", FSType: fsType, ReadOnly: readOnly}
+			if err := m.Mount(share); err != nil {
+				t.Fatalf("Mount(%s) failed: %v", fsType, err)
+			}
+
+			hasRo := false
+			for _, opt := range strings.Split(opts, ",") {
+				if opt == "ro" {
+					hasRo = true
+				}
+			}
+			if hasRo != readOnly {
+				t.Errorf("%s mount options %q: ro present = %v, want %v", fsType, opts, hasRo, readOnly)
+			}
+		}
+	}
+}
+
+func TestSplitSMBVersion(t *testing.T) {
+	tests := []struct {
+		options  string
+		wantVers string
+		wantRest string
+	}{
+		{"", "", ""},
+		{"vers=3.0", "3.0", ""},
+		{"vers=2.1,iocharset=utf8,uid=1000", "2.1", "iocharset=utf8,uid=1000"},
+		{"iocharset=utf8", "", "iocharset=utf8"},
+	}
+
+	for _, tt := range tests {
+		vers, rest := splitSMBVersion(tt.options)
+		if vers != tt.wantVers || rest != tt.wantRest {
+			t.Errorf("splitSMBVersion(%q) = (%q, %q), want (%q, %q)", tt.options, vers, rest, tt.wantVers, tt.wantRest)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package sources
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// unmountableFSTypes are filesystem types reported by blkid that cannot hold music.
+var unmountableFSTypes = map[string]bool{
+	"swap":        true,
+	"LVM2_member": true,
+	"crypto_LUKS": true,
+}
+
+// LinuxUsbDetector implements UsbDetector by reading /sys/block and blkid.
+type LinuxUsbDetector struct {
+	sysBlockPath string
+	// blkid returns the blkid properties (TYPE, LABEL, ...) of a device.
+	blkid func(device string) (map[string]string, error)
+
+	mu sync.Mutex
+	// probed caches blkid results by device so polling only probes partitions
+	// it hasn't seen before. Entries are dropped once a partition disappears.
+	probed map[string]blkidResult
+}
+
+// blkidResult is a cached blkid probe. The size guards against a different
+// disk reappearing under the same device name between two polls.
+type blkidResult struct {
+	size  int64
+	props map[string]string
+}
+
+// NewLinuxUsbDetector creates a new Linux USB detector.
+func NewLinuxUsbDetector() *LinuxUsbDetector {
+	return &LinuxUsbDetector{
+		sysBlockPath: "/sys/block",
+		blkid:        runBlkid,
+	}
+}
+
+// ListPartitions returns the mountable partitions on attached USB disks.
+// blkid only runs for partitions not seen by a previous call.
+func (d *LinuxUsbDetector) ListPartitions() ([]UsbDrive, error) {
+	entries, err := os.ReadDir(d.sysBlockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.probed == nil {
+		d.probed = make(map[string]blkidResult)
+	}
+	seen := make(map[string]bool)
+
+	drives := make([]UsbDrive, 0)
+	for _, entry := range entries {
+		disk := entry.Name()
+		if !strings.HasPrefix(disk, "sd") || !d.isUsbDisk(disk) {
+			continue
+		}
+
+		// Partitions appear as subdirectories named after the disk (sda1, sda2, ...).
+		// A disk without a partition table is mounted whole.
+		diskPath := filepath.Join(d.sysBlockPath, disk)
+		partitions := []string{}
+		if children, err := os.ReadDir(diskPath); err == nil {
+			for _, child := range children {
+				if strings.HasPrefix(child.Name(), disk) {
+					partitions = append(partitions, child.Name())
+				}
+			}
+		}
+		if len(partitions) == 0 {
+			partitions = []string{disk}
+		}
+
+		for _, part := range partitions {
+			sizePath := filepath.Join(diskPath, part, "size")
+			if part == disk {
+				sizePath = filepath.Join(diskPath, "size")
+			}
+
+			drive := UsbDrive{
+				ID:     part,
+				Device: "/dev/" + part,
+				Size:   readSectorCount(sizePath) * 512,
+			}
+
+			seen[drive.Device] = true
+			result, ok := d.probed[drive.Device]
+			if !ok || result.size != drive.Size {
+				props, err := d.blkid(drive.Device)
+				if err != nil {
+					// Not cached, so a device that isn't ready yet is retried
+					log.Debug().Err(err).Str("device", drive.Device).Msg("blkid failed")
+					continue
+				}
+				result = blkidResult{size: drive.Size, props: props}
+				d.probed[drive.Device] = result
+			}
+			drive.FSType = result.props["TYPE"]
+			drive.Label = result.props["LABEL"]
+			if drive.FSType == "" || unmountableFSTypes[drive.FSType] {
+				continue
+			}
+
+			drives = append(drives, drive)
+		}
+	}
+
+	for device := range d.probed {
+		if !seen[device] {
+			delete(d.probed, device)
+		}
+	}
+
+	return drives, nil
+}
+
+// PowerOff powers down the USB disk holding a partition using udisksctl,
+// falling back to deleting the SCSI device via sysfs.
+func (d *LinuxUsbDetector) PowerOff(device string) error {
+	disk := usbDisk(device)
+
+	output, err := exec.Command("udisksctl", "power-off", "-b", "/dev/"+disk).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	log.Debug().Err(err).Str("output", string(output)).Msg("udisksctl power-off failed, trying sysfs")
+
+	cmd := exec.Command("sudo", "tee", filepath.Join(d.sysBlockPath, disk, "device", "delete"))
+	cmd.Stdin = strings.NewReader("1")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("power off failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// isUsbDisk reports whether a block device is attached via USB.
+func (d *LinuxUsbDetector) isUsbDisk(disk string) bool {
+	target, err := filepath.EvalSymlinks(filepath.Join(d.sysBlockPath, disk))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(target, "/usb")
+}
+
+// readSectorCount reads a sysfs size file (in 512-byte sectors).
+func readSectorCount(path string) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return n
+}
+
+// runBlkid runs `blkid -o export` for a device.
+func runBlkid(device string) (map[string]string, error) {
+	output, err := exec.Command("sudo", "blkid", "-o", "export", device).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseBlkidExport(string(output)), nil
+}
+
+// parseBlkidExport parses KEY=value lines from `blkid -o export`.
+// Values are shell-escaped, e.g. a label "MY MUSIC" is printed as MY\ MUSIC.
+func parseBlkidExport(output string) map[string]string {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		var b strings.Builder
+		escaped := false
+		for _, r := range value {
+			if r == '\\' && !escaped {
+				escaped = true
+				continue
+			}
+			escaped = false
+			b.WriteRune(r)
+		}
+		props[key] = b.String()
+	}
+	return props
+}
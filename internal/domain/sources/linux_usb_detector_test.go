@@ -0,0 +1,129 @@
+package sources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// makeSysBlock builds a fake /sys/block tree where each disk links to a device
+// directory under the given bus path.
+func makeSysBlock(t *testing.T, disks map[string]string, partitions map[string][]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	sysBlock := filepath.Join(root, "block")
+	os.MkdirAll(sysBlock, 0755)
+
+	for disk, bus := range disks {
+		devDir := filepath.Join(root, "devices", bus, disk)
+		os.MkdirAll(devDir, 0755)
+		os.WriteFile(filepath.Join(devDir, "size"), []byte("2048\n"), 0644)
+		for _, part := range partitions[disk] {
+			os.MkdirAll(filepath.Join(devDir, part), 0755)
+			os.WriteFile(filepath.Join(devDir, part, "size"), []byte("1024\n"), 0644)
+		}
+		if err := os.Symlink(devDir, filepath.Join(sysBlock, disk)); err != nil {
+			t.Fatalf("Symlink failed: %v", err)
+		}
+	}
+	return sysBlock
+}
+
+func TestLinuxUsbDetector_ListPartitions(t *testing.T) {
+	sysBlock := makeSysBlock(t,
+		map[string]string{
+			"sda": "pci0000:00/usb1/1-1",
+			"sdb": "pci0000:00/usb1/1-2",
+			"sdc": "pci0000:00/ata1",
+		},
+		map[string][]string{
+			"sda": {"sda1", "sda2"},
+			"sdc": {"sdc1"},
+		},
+	)
+
+	props := map[string]map[string]string{
+		"/dev/sda1": {"TYPE": "vfat", "LABEL": "MUSIC"},
+		"/dev/sda2": {"TYPE": "swap"},
+		"/dev/sdb":  {"TYPE": "exfat"},
+		"/dev/sdc1": {"TYPE": "ext4"},
+	}
+	d := &LinuxUsbDetector{
+		sysBlockPath: sysBlock,
+		blkid: func(device string) (map[string]string, error) {
+			if p, ok := props[device]; ok {
+				return p, nil
+			}
+			return nil, fmt.Errorf("no filesystem on %s", device)
+		},
+	}
+
+	drives, err := d.ListPartitions()
+	if err != nil {
+		t.Fatalf("ListPartitions failed: %v", err)
+	}
+
+	want := []UsbDrive{
+		{ID: "sda1", Device: "/dev/sda1", Label: "MUSIC", FSType: "vfat", Size: 1024 * 512},
+		{ID: "sdb", Device: "/dev/sdb", FSType: "exfat", Size: 2048 * 512},
+	}
+	if !reflect.DeepEqual(drives, want) {
+		t.Errorf("ListPartitions = %+v, want %+v", drives, want)
+	}
+}
+
+func TestParseBlkidExport(t *testing.T) {
+	output := "DEVNAME=/dev/sda1\nLABEL=MY\\ MUSIC\nUUID=1234-ABCD\nTYPE=vfat\n"
+	got := parseBlkidExport(output)
+
+	want := map[string]string{
+		"DEVNAME": "/dev/sda1",
+		"LABEL":   "MY MUSIC",
+		"UUID":    "1234-ABCD",
+		"TYPE":    "vfat",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBlkidExport = %v, want %v", got, want)
+	}
+}
+
+func TestLinuxUsbDetector_ListPartitions_CachesBlkid(t *testing.T) {
+	sysBlock := makeSysBlock(t,
+		map[string]string{"sda": "pci0000:00/usb1/1-1"},
+		map[string][]string{"sda": {"sda1"}},
+	)
+
+	calls := 0
+	d := &LinuxUsbDetector{
+		sysBlockPath: sysBlock,
+		blkid: func(device string) (map[string]string, error) {
+			calls++
+			return map[string]string{"TYPE": "vfat"}, nil
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		if drives, err := d.ListPartitions(); err != nil || len(drives) != 1 {
+			t.Fatalf("ListPartitions = %v, %v", drives, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("blkid called %d times for an unchanged partition, want 1", calls)
+	}
+
+	// Unplugging drops the cached result, so a replugged disk is probed again
+	link := filepath.Join(sysBlock, "sda")
+	target, _ := os.Readlink(link)
+	os.Remove(link)
+	if drives, _ := d.ListPartitions(); len(drives) != 0 {
+		t.Fatalf("ListPartitions after unplug = %v, want none", drives)
+	}
+	os.Symlink(target, link)
+	d.ListPartitions()
+	if calls != 2 {
+		t.Errorf("blkid called %d times after replug, want 2", calls)
+	}
+}
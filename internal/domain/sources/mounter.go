@@ -6,6 +6,9 @@ type Mounter interface {
 	// Mount mounts a NAS share and updates the share's Mounted status.
 	Mount(share *NasShare) error
 
+	// MountDevice mounts a local block device (e.g. a USB partition) read-only.
+	MountDevice(device, mountPoint string) error
+
 	// Unmount unmounts a filesystem at the given mount point.
 	Unmount(mountPoint string) error
 
@@ -0,0 +1,79 @@
+package sources
+
+import (
+	"context"
+	"errors"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/smb"
+	"github.com/rs/zerolog/log"
+)
+
+// NativeDiscoverer browses shares over SMB2 with go-smb2, so no samba client
+// tools are needed, and reports each share's access. Device discovery, and
+// share browsing the native client cannot complete (e.g. SMB1-only servers),
+// are delegated to a fallback Discoverer.
+type NativeDiscoverer struct {
+	fallback   Discoverer
+	listShares func(ctx context.Context, host, username, password string) ([]smb.Share, error)
+}
+
+// NewNativeDiscoverer creates a native SMB discoverer backed by fallback.
+func NewNativeDiscoverer(fallback Discoverer) *NativeDiscoverer {
+	return &NativeDiscoverer{
+		fallback:   fallback,
+		listShares: smb.ListShares,
+	}
+}
+
+// DiscoverDevices finds NAS devices using the fallback discoverer.
+func (d *NativeDiscoverer) DiscoverDevices(ctx context.Context) ([]NasDevice, error) {
+	return d.fallback.DiscoverDevices(ctx)
+}
+
+// BrowseShares lists the shares on a NAS host over SMB2, falling back on
+// failures other than rejected credentials.
+func (d *NativeDiscoverer) BrowseShares(ctx context.Context, host, username, password string) ([]ShareInfo, error) {
+	log.Info().Str("host", host).Msg("Browsing NAS shares...")
+
+	native, err := d.listShares(ctx, host, username, password)
+	if err == nil {
+		shares := make([]ShareInfo, 0, len(native))
+		for _, share := range native {
+			if share.Special {
+				continue
+			}
+			shares = append(shares, shareInfoFromSMB(share))
+		}
+		log.Info().Int("count", len(shares)).Str("host", host).Msg("Share browse complete")
+		return shares, nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if errors.Is(err, smb.ErrAccessDenied) {
+		return nil, &ShareBrowseError{
+			Code:    "AUTH_REQUIRED",
+			Message: "authentication required",
+		}
+	}
+
+	log.Warn().Err(err).Str("host", host).Msg("Native SMB browse failed, falling back to smbclient")
+	return d.fallback.BrowseShares(ctx, host, username, password)
+}
+
+// shareInfoFromSMB converts a native share, deriving Writable from the
+// access check when there was one. go-smb2 only lists share names, so
+// non-special shares are reported as disk shares.
+func shareInfoFromSMB(share smb.Share) ShareInfo {
+	writable := true
+	if share.Access != smb.AccessUnknown {
+		writable = share.Access == smb.AccessReadWrite
+	}
+	return ShareInfo{
+		Name:     share.Name,
+		Type:     "disk",
+		Writable: writable,
+		Access:   share.Access.String(),
+	}
+}
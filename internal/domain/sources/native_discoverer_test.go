@@ -0,0 +1,76 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/smb"
+)
+
+func TestNativeDiscoverer_BrowseShares(t *testing.T) {
+	fallback := &MockDiscoverer{Error: errors.New("fallback used")}
+	d := NewNativeDiscoverer(fallback)
+	d.listShares = func(ctx context.Context, host, username, password string) ([]smb.Share, error) {
+		return []smb.Share{
+			{Name: "Music", Access: smb.AccessReadWrite},
+			{Name: "Archive", Access: smb.AccessRead},
+			{Name: "Private", Access: smb.AccessNone},
+			{Name: "Scratch"},
+			{Name: "IPC$", Special: true},
+			{Name: "C$", Special: true},
+		}, nil
+	}
+
+	shares, err := d.BrowseShares(context.Background(), "nas", "", "")
+	if err != nil {
+		t.Fatalf("BrowseShares error = %v", err)
+	}
+
+	want := []ShareInfo{
+		{Name: "Music", Type: "disk", Writable: true, Access: "read-write"},
+		{Name: "Archive", Type: "disk", Access: "read-only"},
+		{Name: "Private", Type: "disk", Access: "none"},
+		{Name: "Scratch", Type: "disk", Writable: true},
+	}
+	if len(shares) != len(want) {
+		t.Fatalf("got %d shares, want %d: %+v", len(shares), len(want), shares)
+	}
+	for i := range want {
+		if shares[i] != want[i] {
+			t.Errorf("share %d = %+v, want %+v", i, shares[i], want[i])
+		}
+	}
+}
+
+func TestNativeDiscoverer_FallsBack(t *testing.T) {
+	fallback := &MockDiscoverer{Shares: map[string][]ShareInfo{
+		"nas": {{Name: "Music", Type: "disk", Writable: true}},
+	}}
+	d := NewNativeDiscoverer(fallback)
+	d.listShares = func(ctx context.Context, host, username, password string) ([]smb.Share, error) {
+		return nil, errors.New("smb: server only speaks SMB1")
+	}
+
+	shares, err := d.BrowseShares(context.Background(), "nas", "", "")
+	if err != nil {
+		t.Fatalf("BrowseShares error = %v", err)
+	}
+	if len(shares) != 1 || shares[0].Name != "Music" {
+		t.Errorf("shares = %+v, want the fallback's", shares)
+	}
+}
+
+func TestNativeDiscoverer_AuthFailureSkipsFallback(t *testing.T) {
+	fallback := &MockDiscoverer{Shares: map[string][]ShareInfo{"nas": {{Name: "Music"}}}}
+	d := NewNativeDiscoverer(fallback)
+	d.listShares = func(ctx context.Context, host, username, password string) ([]smb.Share, error) {
+		return nil, &smb.StatusError{Status: 0xC000006D} // STATUS_LOGON_FAILURE
+	}
+
+	_, err := d.BrowseShares(context.Background(), "nas", "alice", "wrong")
+	var browseErr *ShareBrowseError
+	if !errors.As(err, &browseErr) || browseErr.Code != "AUTH_REQUIRED" {
+		t.Errorf("BrowseShares error = %v, want AUTH_REQUIRED", err)
+	}
+}
@@ -3,8 +3,10 @@ package sources
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -21,8 +23,27 @@ const (
 	UsbMountBase = "/mnt/USB"
 	// MpdMusicDir is the MPD music directory.
 	MpdMusicDir = "/var/lib/mpd/music"
+	// DiscoveryTimeout bounds NAS discovery and share browsing.
+	DiscoveryTimeout = 15 * time.Second
 )
 
+// defaultMountOptions are the per-filesystem mount options applied to new
+// shares unless the user supplies an option with the same key.
+var defaultMountOptions = map[string][]string{
+	"nfs":  {"vers=3", "soft", "timeo=100", "retrans=3"},
+	"cifs": {"vers=3.0", "iocharset=utf8"},
+}
+
+// mountOptionAliases maps option keys to the key they override, so that
+// e.g. a user-supplied "hard" replaces the default "soft".
+var mountOptionAliases = map[string]string{
+	"nfsvers": "vers",
+	"hard":    "soft",
+}
+
+// unsafeMountOptionChars are rejected in user-supplied mount options.
+const unsafeMountOptionChars = ";|&$`<>\\\"'(){}\n\r\t "
+
 // Service manages music sources (NAS and USB).
 type Service struct {
 	config     *Config
@@ -30,6 +51,25 @@ type Service struct {
 	mounter    Mounter
 	discoverer Discoverer
 	mu         sync.RWMutex
+
+	// Mount health checking
+	statPath    func(string) (os.FileInfo, error)
+	statTimeout time.Duration
+	readDir     func(string) ([]os.DirEntry, error)
+	lastMounted map[string]bool
+	healthMu    sync.Mutex
+	// Mount points whose stat has not returned yet
+	statsRunning map[string]bool
+	statMu       sync.Mutex
+
+	// USB hotplug
+	usbDetector UsbDetector
+	usbDrives   map[string]*UsbDrive
+	usbMu       sync.Mutex
+	hasAudio    func(string) bool
+
+	// Storage usage
+	statFS func(string) (DiskUsage, error)
 }
 
 // NewService creates a new sources service.
@@ -40,6 +80,13 @@ func NewService(configPath string, mounter Mounter) (*Service, error) {
 		config: &Config{
 			NasShares: make(map[string]*NasShareConfig),
 		},
+		statPath:    os.Stat,
+		statTimeout: mountStatTimeout,
+		readDir:     os.ReadDir,
+		lastMounted: make(map[string]bool),
+		usbDrives:   make(map[string]*UsbDrive),
+		hasAudio:    containsAudioFiles,
+		statFS:      statFS,
 	}
 
 	// Load existing config if it exists
@@ -106,6 +153,8 @@ func (s *Service) AddNasShare(req AddNasShareRequest) (*SourceResult, error) {
 	// Generate ID and mount point
 	id := uuid.New().String()
 	mountPoint := filepath.Join(NasMountBase, sanitizeName(req.Name))
+	options := mergeMountOptions(req.FSType, req.Options)
+	readOnly := req.IsReadOnly()
 
 	// Create share config
 	shareConfig := &NasShareConfig{
@@ -115,7 +164,8 @@ func (s *Service) AddNasShare(req AddNasShareRequest) (*SourceResult, error) {
 		Path:     req.Path,
 		FSType:   req.FSType,
 		Username: req.Username,
-		Options:  req.Options,
+		Options:  options,
+		ReadOnly: &readOnly,
 	}
 
 	// Encrypt password if provided
@@ -132,9 +182,10 @@ func (s *Service) AddNasShare(req AddNasShareRequest) (*SourceResult, error) {
 		FSType:     req.FSType,
 		Username:   req.Username,
 		Password:   req.Password,
-		Options:    req.Options,
+		Options:    options,
 		MountPoint: mountPoint,
 		Mounted:    false,
+		ReadOnly:   req.IsReadOnly(),
 	}
 
 	// Mount the share if mounter is available
@@ -155,6 +206,8 @@ func (s *Service) AddNasShare(req AddNasShareRequest) (*SourceResult, error) {
 			}, nil
 		}
 
+		shareConfig.SMBVersion = share.SMBVersion
+
 		// Create symlink in MPD music directory
 		symlinkPath := filepath.Join(MpdMusicDir, "NAS", sanitizeName(req.Name))
 		if err := s.mounter.CreateSymlink(mountPoint, symlinkPath); err != nil {
@@ -178,6 +231,76 @@ func (s *Service) AddNasShare(req AddNasShareRequest) (*SourceResult, error) {
 	}, nil
 }
 
+// TestConnection checks that a NAS share can be mounted and read without saving it.
+// The share is mounted to a scratch mount point, its root directory is listed, and
+// it is then unmounted again.
+func (s *Service) TestConnection(req AddNasShareRequest) (*SourceResult, error) {
+	if err := validateAddNasShareRequest(req); err != nil {
+		return &SourceResult{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if s.mounter == nil {
+		return &SourceResult{
+			Success: false,
+			Error:   "mounter not available",
+		}, nil
+	}
+
+	mountPoint := filepath.Join(NasMountBase, ".test-"+uuid.New().String())
+	share := &NasShare{
+		Name:       req.Name,
+		IP:         req.IP,
+		Path:       req.Path,
+		FSType:     req.FSType,
+		Username:   req.Username,
+		Password:   req.Password,
+		Options:    mergeMountOptions(req.FSType, req.Options),
+		MountPoint: mountPoint,
+		ReadOnly:   req.IsReadOnly(),
+	}
+
+	if err := s.mounter.CreateMountPoint(mountPoint); err != nil {
+		return &SourceResult{
+			Success: false,
+			Error:   fmt.Sprintf("failed to create mount point: %v", err),
+		}, nil
+	}
+	defer s.mounter.RemoveMountPoint(mountPoint)
+
+	if err := s.mounter.Mount(share); err != nil {
+		return &SourceResult{
+			Success: false,
+			Error:   fmt.Sprintf("failed to mount share: %v", err),
+		}, nil
+	}
+	defer func() {
+		if err := s.mounter.Unmount(mountPoint); err != nil {
+			log.Warn().Err(err).Str("mountPoint", mountPoint).Msg("Failed to unmount test share")
+		}
+	}()
+
+	entries, err := s.readDir(mountPoint)
+	if err != nil {
+		return &SourceResult{
+			Success: false,
+			Error:   fmt.Sprintf("share mounted but could not be read: %v", err),
+		}, nil
+	}
+
+	message := fmt.Sprintf("Connected to '%s' (%d items found)", req.Name, len(entries))
+	if share.SMBVersion != "" {
+		message = fmt.Sprintf("Connected to '%s' using SMB %s (%d items found)", req.Name, share.SMBVersion, len(entries))
+	}
+
+	return &SourceResult{
+		Success: true,
+		Message: message,
+	}, nil
+}
+
 // ListNasShares returns all configured NAS shares.
 func (s *Service) ListNasShares() ([]NasShare, error) {
 	s.mu.RLock()
@@ -202,6 +325,8 @@ func (s *Service) ListNasShares() ([]NasShare, error) {
 			Options:    cfg.Options,
 			MountPoint: mountPoint,
 			Mounted:    mounted,
+			ReadOnly:   cfg.IsReadOnly(),
+			SMBVersion: cfg.SMBVersion,
 		})
 	}
 
@@ -234,9 +359,30 @@ func (s *Service) GetNasShareInfo(id string) (*NasShare, error) {
 		Options:    cfg.Options,
 		MountPoint: mountPoint,
 		Mounted:    mounted,
+		ReadOnly:   cfg.IsReadOnly(),
+		SMBVersion: cfg.SMBVersion,
 	}, nil
 }
 
+// NasShareMusicPath returns the path of a mounted NAS share within the MPD
+// music directory, suitable for a scoped database update.
+func (s *Service) NasShareMusicPath(id string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cfg, exists := s.config.NasShares[id]
+	if !exists {
+		return "", fmt.Errorf("share not found: %s", id)
+	}
+
+	mountPoint := filepath.Join(NasMountBase, sanitizeName(cfg.Name))
+	if s.mounter == nil || !s.mounter.IsMounted(mountPoint) {
+		return "", fmt.Errorf("share not mounted: %s", cfg.Name)
+	}
+
+	return path.Join("NAS", sanitizeName(cfg.Name)), nil
+}
+
 // DeleteNasShare unmounts and removes a NAS share.
 func (s *Service) DeleteNasShare(id string) (*SourceResult, error) {
 	s.mu.Lock()
@@ -330,6 +476,8 @@ func (s *Service) MountNasShare(id string) (*SourceResult, error) {
 		Password:   decryptPassword(cfg.EncryptedPassword),
 		Options:    cfg.Options,
 		MountPoint: mountPoint,
+		ReadOnly:   cfg.IsReadOnly(),
+		SMBVersion: cfg.SMBVersion,
 	}
 
 	// Ensure mount point exists
@@ -348,6 +496,14 @@ func (s *Service) MountNasShare(id string) (*SourceResult, error) {
 		}, nil
 	}
 
+	// Remember the negotiated SMB version for future mounts
+	if share.SMBVersion != cfg.SMBVersion {
+		cfg.SMBVersion = share.SMBVersion
+		if err := s.saveConfig(); err != nil {
+			log.Warn().Err(err).Str("id", id).Msg("Failed to save negotiated SMB version")
+		}
+	}
+
 	return &SourceResult{
 		Success: true,
 		Message: fmt.Sprintf("NAS share '%s' mounted successfully", cfg.Name),
@@ -410,9 +566,47 @@ func validateAddNasShareRequest(req AddNasShareRequest) error {
 	if req.FSType != "cifs" && req.FSType != "nfs" {
 		return fmt.Errorf("invalid filesystem type: must be 'cifs' or 'nfs'")
 	}
+	if strings.ContainsAny(req.Options, unsafeMountOptionChars) {
+		return fmt.Errorf("mount options contain invalid characters")
+	}
 	return nil
 }
 
+// mergeMountOptions combines the default options for a filesystem type with
+// user-supplied options. User options win: a default is dropped when the user
+// supplies an option with the same key (or an alias of it).
+func mergeMountOptions(fsType, userOptions string) string {
+	var user []string
+	overridden := make(map[string]bool)
+	for _, opt := range strings.Split(userOptions, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		user = append(user, opt)
+		overridden[mountOptionKey(opt)] = true
+	}
+
+	var merged []string
+	for _, opt := range defaultMountOptions[fsType] {
+		if !overridden[mountOptionKey(opt)] {
+			merged = append(merged, opt)
+		}
+	}
+	merged = append(merged, user...)
+
+	return strings.Join(merged, ",")
+}
+
+// mountOptionKey returns the canonical key of a mount option ("vers=3" -> "vers").
+func mountOptionKey(opt string) string {
+	key, _, _ := strings.Cut(opt, "=")
+	if alias, ok := mountOptionAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
 // sanitizeName sanitizes a name for use in file paths.
 func sanitizeName(name string) string {
 	// Replace unsafe characters with underscores
@@ -442,8 +636,10 @@ func (s *Service) SetDiscoverer(d Discoverer) {
 	s.discoverer = d
 }
 
-// DiscoverNasDevices finds NAS devices on the local network.
-func (s *Service) DiscoverNasDevices() (*DiscoverResult, error) {
+// DiscoverNasDevices finds NAS devices on the local network. Discovery stops
+// after DiscoveryTimeout or when ctx is cancelled; devices found by then are
+// returned with TimedOut set.
+func (s *Service) DiscoverNasDevices(ctx context.Context) (*DiscoverResult, error) {
 	s.mu.RLock()
 	discoverer := s.discoverer
 	s.mu.RUnlock()
@@ -455,11 +651,19 @@ func (s *Service) DiscoverNasDevices() (*DiscoverResult, error) {
 		}, nil
 	}
 
-	devices, err := discoverer.DiscoverDevices()
+	ctx, cancel := context.WithTimeout(ctx, DiscoveryTimeout)
+	defer cancel()
+
+	devices, err := discoverer.DiscoverDevices(ctx)
+	if devices == nil {
+		devices = []NasDevice{}
+	}
 	if err != nil {
+		timedOut, msg := discoveryError(err, "discovery")
 		return &DiscoverResult{
-			Devices: []NasDevice{},
-			Error:   err.Error(),
+			Devices:  devices,
+			TimedOut: timedOut,
+			Error:    msg,
 		}, nil
 	}
 
@@ -468,6 +672,18 @@ func (s *Service) DiscoverNasDevices() (*DiscoverResult, error) {
 	}, nil
 }
 
+// discoveryError describes a discovery failure, reporting whether it was a
+// timeout or cancellation rather than a real error.
+func discoveryError(err error, op string) (timedOut bool, msg string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return true, op + " timed out"
+	case errors.Is(err, context.Canceled):
+		return true, op + " cancelled"
+	}
+	return false, err.Error()
+}
+
 // MountAllShares attempts to mount all configured NAS shares.
 // Returns a summary of mount results for each share.
 func (s *Service) MountAllShares() []MountResult {
@@ -604,8 +820,9 @@ func (s *Service) MountAllSharesWithRetry(ctx context.Context, maxAttempts int,
 	return s.MountAllShares()
 }
 
-// BrowseNasShares lists available shares on a NAS host.
-func (s *Service) BrowseNasShares(host, username, password string) (*BrowseSharesResult, error) {
+// BrowseNasShares lists available shares on a NAS host, giving up after
+// DiscoveryTimeout or when ctx is cancelled.
+func (s *Service) BrowseNasShares(ctx context.Context, host, username, password string) (*BrowseSharesResult, error) {
 	s.mu.RLock()
 	discoverer := s.discoverer
 	s.mu.RUnlock()
@@ -617,11 +834,19 @@ func (s *Service) BrowseNasShares(host, username, password string) (*BrowseShare
 		}, nil
 	}
 
-	shares, err := discoverer.BrowseShares(host, username, password)
+	ctx, cancel := context.WithTimeout(ctx, DiscoveryTimeout)
+	defer cancel()
+
+	shares, err := discoverer.BrowseShares(ctx, host, username, password)
+	if shares == nil {
+		shares = []ShareInfo{}
+	}
 	if err != nil {
+		timedOut, msg := discoveryError(err, "share browsing")
 		return &BrowseSharesResult{
-			Shares: []ShareInfo{},
-			Error:  err.Error(),
+			Shares:   shares,
+			TimedOut: timedOut,
+			Error:    msg,
 		}, nil
 	}
 
@@ -3,7 +3,10 @@ package sources
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -163,6 +166,45 @@ func TestService_DeleteNasShare_NotFound(t *testing.T) {
 	}
 }
 
+func TestService_NasShareMusicPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "sources.json")
+
+	mounter := NewMockMounter()
+	s, err := NewService(configPath, mounter)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	result, err := s.AddNasShare(AddNasShareRequest{
+		Name:   "My Music",
+		IP:     "192.168.1.100",
+		Path:   "Music",
+		FSType: "cifs",
+	})
+	if err != nil || !result.Success {
+		t.Fatalf("AddNasShare failed: %v %+v", err, result)
+	}
+	shares, _ := s.ListNasShares()
+	shareID := shares[0].ID
+
+	path, err := s.NasShareMusicPath(shareID)
+	if err != nil {
+		t.Fatalf("NasShareMusicPath failed: %v", err)
+	}
+	if path != "NAS/My_Music" {
+		t.Errorf("NasShareMusicPath = %q, want NAS/My_Music", path)
+	}
+
+	mounter.Unmount(shares[0].MountPoint)
+	if _, err := s.NasShareMusicPath(shareID); err == nil {
+		t.Error("NasShareMusicPath succeeded for an unmounted share")
+	}
+	if _, err := s.NasShareMusicPath("nonexistent-id"); err == nil {
+		t.Error("NasShareMusicPath succeeded for a nonexistent share")
+	}
+}
+
 func TestService_ConfigPersistence(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "sources.json")
@@ -249,6 +291,21 @@ func TestService_AddNasShare_Validation(t *testing.T) {
 			req:     AddNasShareRequest{Name: "Test2", IP: "192.168.1.2", Path: "/export/music", FSType: "nfs"},
 			wantErr: false,
 		},
+		{
+			name:    "options with shell characters",
+			req:     AddNasShareRequest{Name: "Test3", IP: "192.168.1.3", Path: "Music", FSType: "cifs", Options: "vers=2.1;rm -rf /"},
+			wantErr: true,
+		},
+		{
+			name:    "options with whitespace",
+			req:     AddNasShareRequest{Name: "Test4", IP: "192.168.1.4", Path: "Music", FSType: "nfs", Options: "vers=4 hard"},
+			wantErr: true,
+		},
+		{
+			name:    "valid options",
+			req:     AddNasShareRequest{Name: "Test5", IP: "192.168.1.5", Path: "Music", FSType: "cifs", Options: "vers=2.1,uid=1000"},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -268,6 +325,297 @@ func TestService_AddNasShare_Validation(t *testing.T) {
 	}
 }
 
+func TestMergeMountOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsType  string
+		options string
+		want    string
+	}{
+		{"nfs defaults", "nfs", "", "vers=3,soft,timeo=100,retrans=3"},
+		{"nfs version override", "nfs", "vers=4.1", "soft,timeo=100,retrans=3,vers=4.1"},
+		{"nfs nfsvers override", "nfs", "nfsvers=4", "soft,timeo=100,retrans=3,nfsvers=4"},
+		{"nfs hard replaces soft", "nfs", "hard", "vers=3,timeo=100,retrans=3,hard"},
+		{"nfs extra option", "nfs", "rsize=65536", "vers=3,soft,timeo=100,retrans=3,rsize=65536"},
+		{"cifs defaults", "cifs", "", "vers=3.0,iocharset=utf8"},
+		{"cifs version override", "cifs", "vers=2.1,uid=1000", "iocharset=utf8,vers=2.1,uid=1000"},
+		{"cifs empty entries ignored", "cifs", ",uid=1000,,", "vers=3.0,iocharset=utf8,uid=1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeMountOptions(tt.fsType, tt.options)
+			if got != tt.want {
+				t.Errorf("mergeMountOptions(%q, %q) = %q, want %q", tt.fsType, tt.options, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_AddNasShare_MergesDefaultOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "sources.json")
+
+	s, err := NewService(configPath, NewMockMounter())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	result, err := s.AddNasShare(AddNasShareRequest{
+		Name:    "NfsShare",
+		IP:      "192.168.1.100",
+		Path:    "/export/music",
+		FSType:  "nfs",
+		Options: "vers=4.1",
+	})
+	if err != nil {
+		t.Fatalf("AddNasShare failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("AddNasShare returned success=false: %s", result.Error)
+	}
+
+	// Reload from disk to check the merged options were persisted
+	s2, err := NewService(configPath, NewMockMounter())
+	if err != nil {
+		t.Fatalf("NewService (reload) failed: %v", err)
+	}
+
+	shares, err := s2.ListNasShares()
+	if err != nil {
+		t.Fatalf("ListNasShares failed: %v", err)
+	}
+	if len(shares) != 1 {
+		t.Fatalf("ListNasShares returned %d shares, want 1", len(shares))
+	}
+
+	want := "soft,timeo=100,retrans=3,vers=4.1"
+	if shares[0].Options != want {
+		t.Errorf("share.Options = %q, want %q", shares[0].Options, want)
+	}
+}
+
+func TestService_NegotiatedSMBVersionPersisted(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "sources.json")
+
+	mounter := NewMockMounter()
+	mounter.SMBVersion = "2.1"
+	s, err := NewService(configPath, mounter)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	result, err := s.AddNasShare(AddNasShareRequest{Name: "Music", IP: "192.168.1.100", Path: "Music", FSType: "cifs"})
+	if err != nil || !result.Success {
+		t.Fatalf("AddNasShare failed: %v %v", err, result)
+	}
+
+	// Reload and remount: the negotiated version is listed and passed back to the mounter
+	mounter2 := NewMockMounter()
+	s2, err := NewService(configPath, mounter2)
+	if err != nil {
+		t.Fatalf("NewService (reload) failed: %v", err)
+	}
+
+	shares, _ := s2.ListNasShares()
+	if len(shares) != 1 {
+		t.Fatalf("ListNasShares returned %d shares, want 1", len(shares))
+	}
+	if shares[0].SMBVersion != "2.1" {
+		t.Errorf("share.SMBVersion = %q, want %q", shares[0].SMBVersion, "2.1")
+	}
+
+	if result, _ := s2.MountNasShare(shares[0].ID); !result.Success {
+		t.Fatalf("MountNasShare failed: %s", result.Error)
+	}
+	if len(mounter2.MountVersions) != 1 || mounter2.MountVersions[0] != "2.1" {
+		t.Errorf("Mount received SMB versions %v, want [2.1]", mounter2.MountVersions)
+	}
+}
+
+func TestService_TestConnection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "sources.json")
+
+	mounter := NewMockMounter()
+	s, err := NewService(configPath, mounter)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	s.readDir = func(string) ([]os.DirEntry, error) {
+		return make([]os.DirEntry, 3), nil
+	}
+
+	req := AddNasShareRequest{Name: "Music", IP: "192.168.1.100", Path: "Music", FSType: "cifs", Username: "user", Password: "pass"}
+	result, err := s.TestConnection(req)
+	if err != nil {
+		t.Fatalf("TestConnection returned error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("TestConnection failed: %s", result.Error)
+	}
+	if !strings.Contains(result.Message, "3 items") {
+		t.Errorf("Message = %q, want item count", result.Message)
+	}
+
+	if !mounter.UnmountCalled || len(mounter.MountedPaths) != 0 {
+		t.Error("test mount was not unmounted")
+	}
+
+	// Nothing is persisted
+	shares, _ := s.ListNasShares()
+	if len(shares) != 0 {
+		t.Errorf("ListNasShares returned %d shares, want 0", len(shares))
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Error("TestConnection should not write the config file")
+	}
+}
+
+func TestService_TestConnection_Failures(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       AddNasShareRequest
+		mountErr  error
+		readErr   error
+		wantError string
+	}{
+		{
+			name:      "invalid request",
+			req:       AddNasShareRequest{Name: "Music", IP: "192.168.1.100", FSType: "cifs"},
+			wantError: "path is required",
+		},
+		{
+			name:      "mount failure",
+			req:       AddNasShareRequest{Name: "Music", IP: "192.168.1.100", Path: "Music", FSType: "cifs"},
+			mountErr:  fmt.Errorf("permission denied"),
+			wantError: "failed to mount share: permission denied",
+		},
+		{
+			name:      "read failure",
+			req:       AddNasShareRequest{Name: "Music", IP: "192.168.1.100", Path: "Music", FSType: "nfs"},
+			readErr:   fmt.Errorf("stale file handle"),
+			wantError: "share mounted but could not be read: stale file handle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mounter := NewMockMounter()
+			mounter.MountError = tt.mountErr
+			s, err := NewService(filepath.Join(t.TempDir(), "sources.json"), mounter)
+			if err != nil {
+				t.Fatalf("NewService failed: %v", err)
+			}
+			s.readDir = func(string) ([]os.DirEntry, error) {
+				return nil, tt.readErr
+			}
+
+			result, err := s.TestConnection(tt.req)
+			if err != nil {
+				t.Fatalf("TestConnection returned error: %v", err)
+			}
+			if result.Success {
+				t.Fatal("TestConnection succeeded, want failure")
+			}
+			if result.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", result.Error, tt.wantError)
+			}
+			if len(mounter.MountedPaths) != 0 {
+				t.Error("test mount was left mounted")
+			}
+		})
+	}
+}
+
+func TestService_AddNasShare_ReadOnly(t *testing.T) {
+	readWrite := false
+	tests := []struct {
+		name     string
+		readOnly *bool
+		want     bool
+	}{
+		{"defaults to read-only", nil, true},
+		{"explicit read-write", &readWrite, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "sources.json")
+			s, err := NewService(configPath, NewMockMounter())
+			if err != nil {
+				t.Fatalf("NewService failed: %v", err)
+			}
+
+			req := AddNasShareRequest{Name: "Music", IP: "192.168.1.100", Path: "Music", FSType: "cifs", ReadOnly: tt.readOnly}
+			if result, _ := s.AddNasShare(req); !result.Success {
+				t.Fatalf("AddNasShare failed: %s", result.Error)
+			}
+
+			s2, err := NewService(configPath, NewMockMounter())
+			if err != nil {
+				t.Fatalf("NewService (reload) failed: %v", err)
+			}
+			shares, _ := s2.ListNasShares()
+			if len(shares) != 1 {
+				t.Fatalf("ListNasShares returned %d shares, want 1", len(shares))
+			}
+			if shares[0].ReadOnly != tt.want {
+				t.Errorf("share.ReadOnly = %v, want %v", shares[0].ReadOnly, tt.want)
+			}
+		})
+	}
+}
+
+// optionsMounter records the options a LinuxMounter would pass to mount.
+type optionsMounter struct {
+	*MockMounter
+	options string
+}
+
+func (m *optionsMounter) Mount(share *NasShare) error {
+	linux := &LinuxMounter{
+		runCommand: func(name string, args ...string) ([]byte, error) {
+			m.options = args[len(args)-3]
+			return nil, nil
+		},
+	}
+	if err := linux.Mount(share); err != nil {
+		return err
+	}
+	return m.MockMounter.Mount(share)
+}
+
+func TestService_LegacyShareMountsReadOnly(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "sources.json")
+	legacy := `{"nasShares": {"abc": {"id": "abc", "name": "Old", "ip": "192.168.1.5", "path": "Music", "fsType": "cifs"}}}`
+	if err := os.WriteFile(configPath, []byte(legacy), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	mounter := &optionsMounter{MockMounter: NewMockMounter()}
+	s, err := NewService(configPath, mounter)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	share, err := s.GetNasShareInfo("abc")
+	if err != nil {
+		t.Fatalf("GetNasShareInfo failed: %v", err)
+	}
+	if !share.ReadOnly {
+		t.Error("legacy share.ReadOnly = false, want true")
+	}
+
+	if result, _ := s.MountNasShare("abc"); !result.Success {
+		t.Fatalf("MountNasShare failed: %s", result.Error)
+	}
+	if !slices.Contains(strings.Split(mounter.options, ","), "ro") {
+		t.Errorf("mount options %q do not include ro", mounter.options)
+	}
+}
+
 // MockMounter implements Mounter interface for testing
 type MockMounter struct {
 	MountCalled   bool
@@ -276,6 +624,8 @@ type MockMounter struct {
 	UnmountError  error
 	IsMountedVal  bool
 	MountedPaths  map[string]bool
+	SMBVersion    string   // Version reported for successful CIFS mounts
+	MountVersions []string // SMB versions passed in on each Mount call
 }
 
 func NewMockMounter() *MockMounter {
@@ -286,14 +636,27 @@ func NewMockMounter() *MockMounter {
 
 func (m *MockMounter) Mount(share *NasShare) error {
 	m.MountCalled = true
+	m.MountVersions = append(m.MountVersions, share.SMBVersion)
 	if m.MountError != nil {
 		return m.MountError
 	}
 	share.Mounted = true
+	if share.FSType == "cifs" && m.SMBVersion != "" {
+		share.SMBVersion = m.SMBVersion
+	}
 	m.MountedPaths[share.MountPoint] = true
 	return nil
 }
 
+func (m *MockMounter) MountDevice(device, mountPoint string) error {
+	m.MountCalled = true
+	if m.MountError != nil {
+		return m.MountError
+	}
+	m.MountedPaths[mountPoint] = true
+	return nil
+}
+
 func (m *MockMounter) Unmount(mountPoint string) error {
 	m.UnmountCalled = true
 	if m.UnmountError != nil {
@@ -349,7 +712,7 @@ func TestService_DiscoverNasDevices(t *testing.T) {
 	}
 	s.SetDiscoverer(mockDiscoverer)
 
-	result, err := s.DiscoverNasDevices()
+	result, err := s.DiscoverNasDevices(context.Background())
 	if err != nil {
 		t.Fatalf("DiscoverNasDevices failed: %v", err)
 	}
@@ -377,7 +740,7 @@ func TestService_DiscoverNasDevices_Empty(t *testing.T) {
 	}
 	s.SetDiscoverer(mockDiscoverer)
 
-	result, err := s.DiscoverNasDevices()
+	result, err := s.DiscoverNasDevices(context.Background())
 	if err != nil {
 		t.Fatalf("DiscoverNasDevices failed: %v", err)
 	}
@@ -397,7 +760,7 @@ func TestService_DiscoverNasDevices_NoDiscoverer(t *testing.T) {
 	}
 	// No discoverer set
 
-	result, err := s.DiscoverNasDevices()
+	result, err := s.DiscoverNasDevices(context.Background())
 	if err != nil {
 		t.Fatalf("DiscoverNasDevices failed: %v", err)
 	}
@@ -407,6 +770,71 @@ func TestService_DiscoverNasDevices_NoDiscoverer(t *testing.T) {
 	}
 }
 
+func TestService_DiscoverNasDevices_TimeoutReturnsPartial(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "sources.json")
+
+	s, err := NewService(configPath, NewMockMounter())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	s.SetDiscoverer(&MockDiscoverer{
+		Devices: []NasDevice{
+			{Name: "NAS1", IP: "192.168.1.10"},
+			{Name: "NAS2", IP: "192.168.1.11"},
+		},
+		Hang:    true,
+		Partial: 1,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	result, err := s.DiscoverNasDevices(ctx)
+	if err != nil {
+		t.Fatalf("DiscoverNasDevices failed: %v", err)
+	}
+	if !result.TimedOut {
+		t.Error("TimedOut = false, want true")
+	}
+	if len(result.Devices) != 1 || result.Devices[0].Name != "NAS1" {
+		t.Errorf("Devices = %+v, want the one found before the timeout", result.Devices)
+	}
+}
+
+func TestService_BrowseNasShares_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "sources.json")
+
+	s, err := NewService(configPath, NewMockMounter())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	s.SetDiscoverer(&MockDiscoverer{Hang: true})
+
+	// Simulates the client disconnecting mid-browse
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan *BrowseSharesResult, 1)
+	go func() {
+		result, _ := s.BrowseNasShares(ctx, "192.168.1.10", "", "")
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		if !result.TimedOut || result.Error != "share browsing cancelled" {
+			t.Errorf("result = %+v, want cancelled", result)
+		}
+		if result.Shares == nil {
+			t.Error("Shares = nil, want empty slice")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("BrowseNasShares did not return after cancellation")
+	}
+}
+
 func TestService_BrowseNasShares(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "sources.json")
@@ -426,7 +854,7 @@ func TestService_BrowseNasShares(t *testing.T) {
 	}
 	s.SetDiscoverer(mockDiscoverer)
 
-	result, err := s.BrowseNasShares("192.168.1.10", "", "")
+	result, err := s.BrowseNasShares(context.Background(), "192.168.1.10", "", "")
 	if err != nil {
 		t.Fatalf("BrowseNasShares failed: %v", err)
 	}
@@ -460,7 +888,7 @@ func TestService_BrowseNasShares_WithCredentials(t *testing.T) {
 	s.SetDiscoverer(mockDiscoverer)
 
 	// Without credentials - should fail
-	result, err := s.BrowseNasShares("192.168.1.10", "", "")
+	result, err := s.BrowseNasShares(context.Background(), "192.168.1.10", "", "")
 	if err != nil {
 		t.Fatalf("BrowseNasShares failed: %v", err)
 	}
@@ -469,7 +897,7 @@ func TestService_BrowseNasShares_WithCredentials(t *testing.T) {
 	}
 
 	// With credentials - should succeed
-	result, err = s.BrowseNasShares("192.168.1.10", "user", "pass")
+	result, err = s.BrowseNasShares(context.Background(), "192.168.1.10", "user", "pass")
 	if err != nil {
 		t.Fatalf("BrowseNasShares with auth failed: %v", err)
 	}
@@ -495,7 +923,7 @@ func TestService_BrowseNasShares_NotFound(t *testing.T) {
 	}
 	s.SetDiscoverer(mockDiscoverer)
 
-	result, err := s.BrowseNasShares("192.168.1.99", "", "")
+	result, err := s.BrowseNasShares(context.Background(), "192.168.1.99", "", "")
 	if err != nil {
 		t.Fatalf("BrowseNasShares failed: %v", err)
 	}
@@ -925,19 +1353,30 @@ type MockDiscoverer struct {
 	Shares      map[string][]ShareInfo
 	RequireAuth bool
 	Error       error
+	// Hang makes discovery find Devices[:Partial] and then block until ctx ends
+	Hang    bool
+	Partial int
 }
 
-func (m *MockDiscoverer) DiscoverDevices() ([]NasDevice, error) {
+func (m *MockDiscoverer) DiscoverDevices(ctx context.Context) ([]NasDevice, error) {
 	if m.Error != nil {
 		return nil, m.Error
 	}
+	if m.Hang {
+		<-ctx.Done()
+		return m.Devices[:m.Partial], ctx.Err()
+	}
 	return m.Devices, nil
 }
 
-func (m *MockDiscoverer) BrowseShares(host, username, password string) ([]ShareInfo, error) {
+func (m *MockDiscoverer) BrowseShares(ctx context.Context, host, username, password string) ([]ShareInfo, error) {
 	if m.Error != nil {
 		return nil, m.Error
 	}
+	if m.Hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
 
 	if m.RequireAuth && (username == "" || password == "") {
 		return nil, fmt.Errorf("authentication required")
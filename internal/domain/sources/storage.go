@@ -0,0 +1,105 @@
+package sources
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// statFSTimeout bounds the statfs of a source. Like stat, statfs on a dead
+// NAS mount can block for a long time.
+const statFSTimeout = mountStatTimeout
+
+// GetSourceUsage returns total, used and free space for the internal music
+// directory, each mounted USB drive and each configured NAS share. NAS shares
+// report the remote capacity as seen through the mount. A source that cannot
+// be measured carries an Error instead of failing the whole call.
+func (s *Service) GetSourceUsage() []SourceUsage {
+	usage := []SourceUsage{
+		s.measure(SourceUsage{ID: "internal", Name: "Internal storage", Type: "internal", Path: MpdMusicDir}),
+	}
+
+	for _, drive := range s.ListUsbDrives() {
+		if !drive.Mounted {
+			continue
+		}
+		name := drive.Label
+		if name == "" {
+			name = drive.ID
+		}
+		usage = append(usage, s.measure(SourceUsage{
+			ID:   drive.ID,
+			Name: name,
+			Type: "usb",
+			Path: drive.MountPoint,
+		}))
+	}
+
+	s.mu.RLock()
+	mounter := s.mounter
+	shares := make([]SourceUsage, 0, len(s.config.NasShares))
+	for id, cfg := range s.config.NasShares {
+		shares = append(shares, SourceUsage{
+			ID:   id,
+			Name: cfg.Name,
+			Type: "nas",
+			Path: filepath.Join(NasMountBase, sanitizeName(cfg.Name)),
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(shares, func(i, j int) bool { return shares[i].Name < shares[j].Name })
+	for _, share := range shares {
+		if mounter == nil || !mounter.IsMounted(share.Path) {
+			share.Error = "not mounted"
+			usage = append(usage, share)
+			continue
+		}
+		usage = append(usage, s.measure(share))
+	}
+
+	return usage
+}
+
+// measure fills in the disk usage of a source's path, or its error.
+func (s *Service) measure(source SourceUsage) SourceUsage {
+	type result struct {
+		usage DiskUsage
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		usage, err := s.statFS(source.Path)
+		done <- result{usage, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			source.Error = r.err.Error()
+		} else {
+			source.DiskUsage = r.usage
+		}
+	case <-time.After(statFSTimeout):
+		source.Error = fmt.Sprintf("statfs %s timed out after %s", source.Path, statFSTimeout)
+	}
+	return source
+}
+
+// statFS reads the capacity of the filesystem holding path.
+func statFS(path string) (DiskUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return DiskUsage{}, err
+	}
+	bsize := uint64(st.Bsize)
+	total := st.Blocks * bsize
+	free := st.Bfree * bsize
+	return DiskUsage{
+		Total: total,
+		Used:  total - free,
+		Free:  st.Bavail * bsize,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package sources
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestService_GetSourceUsage(t *testing.T) {
+	s, mounter := newHealthTestService(t)
+
+	for _, name := range []string{"Archive", "Offline"} {
+		result, err := s.AddNasShare(AddNasShareRequest{Name: name, IP: "192.168.1.100", Path: name, FSType: "cifs"})
+		if err != nil || !result.Success {
+			t.Fatalf("AddNasShare failed: %v %v", err, result)
+		}
+	}
+	mounter.Unmount(filepath.Join(NasMountBase, "Offline"))
+
+	s.usbDrives["sda1"] = &UsbDrive{ID: "sda1", Label: "STICK", Mounted: true, MountPoint: "/mnt/USB/STICK"}
+	s.usbDrives["sdb1"] = &UsbDrive{ID: "sdb1"} // Not mounted; skipped
+
+	s.statFS = func(path string) (DiskUsage, error) {
+		if path == filepath.Join(NasMountBase, "Archive") {
+			return DiskUsage{}, errors.New("host is down")
+		}
+		return DiskUsage{Total: 100, Used: 40, Free: 50}, nil
+	}
+
+	usage := s.GetSourceUsage()
+
+	want := []struct {
+		id, typ, err string
+	}{
+		{"internal", "internal", ""},
+		{"sda1", "usb", ""},
+		{"", "nas", "host is down"}, // Archive
+		{"", "nas", ""},             // Music
+		{"", "nas", "not mounted"},  // Offline
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("got %d sources, want %d: %+v", len(usage), len(want), usage)
+	}
+	for i, w := range want {
+		got := usage[i]
+		if (w.id != "" && got.ID != w.id) || got.Type != w.typ || got.Error != w.err {
+			t.Errorf("source %d = %+v, want id=%q type=%q error=%q", i, got, w.id, w.typ, w.err)
+		}
+		if w.err == "" && got.Total != 100 {
+			t.Errorf("source %d total = %d, want 100", i, got.Total)
+		}
+		if w.err != "" && got.Total != 0 {
+			t.Errorf("source %d reported usage despite an error", i)
+		}
+	}
+	if usage[1].Name != "STICK" || usage[2].Name != "Archive" {
+		t.Errorf("names = %q, %q; want STICK, Archive", usage[1].Name, usage[2].Name)
+	}
+}
+
+func TestStatFS(t *testing.T) {
+	usage, err := statFS(t.TempDir())
+	if err != nil {
+		t.Fatalf("statFS failed: %v", err)
+	}
+	if usage.Total == 0 || usage.Used > usage.Total || usage.Free > usage.Total {
+		t.Errorf("statFS = %+v, want consistent non-zero totals", usage)
+	}
+	if _, err := statFS(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("statFS succeeded for a missing path")
+	}
+}
@@ -0,0 +1,153 @@
+package sources
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportNasShares returns the configured NAS shares, sorted by name, for
+// carrying to another device. Passwords are left out.
+func (s *Service) ExportNasShares() []ShareExport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shares := make([]ShareExport, 0, len(s.config.NasShares))
+	for _, cfg := range s.config.NasShares {
+		shares = append(shares, ShareExport{
+			Name:        cfg.Name,
+			IP:          cfg.IP,
+			Path:        cfg.Path,
+			FSType:      cfg.FSType,
+			Username:    cfg.Username,
+			HasPassword: cfg.EncryptedPassword != "",
+			Options:     cfg.Options,
+			ReadOnly:    cfg.IsReadOnly(),
+		})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].Name < shares[j].Name })
+	return shares
+}
+
+// ImportNasShares adds exported shares and mounts them. passwords holds the
+// passwords of shares exported with HasPassword, keyed by share name; such a
+// share is skipped with NeedsPassword set until its password is given, so
+// the import can be repeated once the user has entered it. Shares whose name
+// is already configured are left as they are, which makes repeating an
+// import safe.
+func (s *Service) ImportNasShares(shares []ShareExport, passwords map[string]string) []MountResult {
+	results := make([]MountResult, 0, len(shares))
+	var added []string
+
+	s.mu.Lock()
+	for _, share := range shares {
+		result := MountResult{ShareName: share.Name}
+		req := AddNasShareRequest{
+			Name:     share.Name,
+			IP:       share.IP,
+			Path:     share.Path,
+			FSType:   share.FSType,
+			Username: share.Username,
+			Password: passwords[share.Name],
+			Options:  share.Options,
+		}
+		if err := validateAddNasShareRequest(req); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if id, exists := s.shareIDByName(share.Name); exists {
+			result.ShareID = id
+			result.Success = true
+			result.Message = "already configured"
+			results = append(results, result)
+			continue
+		}
+		if share.HasPassword && req.Password == "" {
+			result.NeedsPassword = true
+			result.Error = "password required"
+			results = append(results, result)
+			continue
+		}
+
+		readOnly := share.ReadOnly
+		cfg := &NasShareConfig{
+			ID:       uuid.New().String(),
+			Name:     req.Name,
+			IP:       req.IP,
+			Path:     req.Path,
+			FSType:   req.FSType,
+			Username: req.Username,
+			Options:  mergeMountOptions(req.FSType, req.Options),
+			ReadOnly: &readOnly,
+		}
+		if req.Password != "" {
+			cfg.EncryptedPassword = encryptPassword(req.Password)
+		}
+		s.config.NasShares[cfg.ID] = cfg
+		added = append(added, cfg.ID)
+		result.ShareID = cfg.ID
+		results = append(results, result)
+	}
+	var saveErr error
+	if len(added) > 0 {
+		saveErr = s.saveConfig()
+		if saveErr != nil {
+			// Nothing was persisted, so don't keep the shares either
+			for _, id := range added {
+				delete(s.config.NasShares, id)
+			}
+		}
+	}
+	mounter := s.mounter
+	s.mu.Unlock()
+
+	for i := range results {
+		if results[i].Success || results[i].Error != "" {
+			continue
+		}
+		if saveErr != nil {
+			results[i].Error = fmt.Sprintf("failed to save config: %v", saveErr)
+			continue
+		}
+		results[i].Success = true
+		results[i].Message = "imported"
+
+		mountResult, err := s.MountNasShare(results[i].ShareID)
+		if err != nil || !mountResult.Success {
+			msg := "mount failed"
+			if err != nil {
+				msg = err.Error()
+			} else if mountResult.Error != "" {
+				msg = mountResult.Error
+			}
+			log.Warn().Str("share", results[i].ShareName).Str("error", msg).Msg("Imported NAS share did not mount")
+			results[i].Message = "imported but not mounted: " + msg
+			continue
+		}
+		results[i].Mounted = true
+
+		// Link the share into the music directory, as AddNasShare does
+		name := sanitizeName(results[i].ShareName)
+		if err := mounter.CreateSymlink(filepath.Join(NasMountBase, name), filepath.Join(MpdMusicDir, "NAS", name)); err != nil {
+			log.Warn().Err(err).Str("share", results[i].ShareName).Msg("Failed to link imported NAS share into music directory")
+		}
+	}
+
+	log.Info().Int("shares", len(shares)).Int("added", len(added)).Msg("Imported NAS shares")
+	return results
+}
+
+// shareIDByName returns the ID of the configured share that would use the
+// same mount point as name. Must be called with mu held.
+func (s *Service) shareIDByName(name string) (string, bool) {
+	for id, cfg := range s.config.NasShares {
+		if sanitizeName(cfg.Name) == sanitizeName(name) {
+			return id, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,106 @@
+package sources
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestService_ExportImportNasShares(t *testing.T) {
+	src, err := NewService(filepath.Join(t.TempDir(), "sources.json"), NewMockMounter())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	for _, req := range []AddNasShareRequest{
+		{Name: "Office", IP: "192.168.1.10", Path: "Music", FSType: "cifs", Username: "user", Password: "secret"},
+		{Name: "Archive", IP: "192.168.1.20", Path: "/export/music", FSType: "nfs"},
+	} {
+		if result, err := src.AddNasShare(req); err != nil || !result.Success {
+			t.Fatalf("AddNasShare(%s) = %+v, %v", req.Name, result, err)
+		}
+	}
+
+	exported := src.ExportNasShares()
+	if len(exported) != 2 || exported[0].Name != "Archive" || exported[1].Name != "Office" {
+		t.Fatalf("ExportNasShares() = %+v, want Archive and Office", exported)
+	}
+	if !exported[1].HasPassword || exported[0].HasPassword {
+		t.Errorf("HasPassword = %v/%v, want only Office", exported[0].HasPassword, exported[1].HasPassword)
+	}
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("export leaks the password: %s", data)
+	}
+
+	mounter := NewMockMounter()
+	dst, err := NewService(filepath.Join(t.TempDir(), "sources.json"), mounter)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	// Without the password only the NFS share is imported
+	results := dst.ImportNasShares(exported, nil)
+	if len(results) != 2 {
+		t.Fatalf("ImportNasShares() returned %d results, want 2", len(results))
+	}
+	if !results[0].Success || !results[0].Mounted {
+		t.Errorf("Archive result = %+v, want imported and mounted", results[0])
+	}
+	if results[1].Success || !results[1].NeedsPassword {
+		t.Errorf("Office result = %+v, want skipped for its password", results[1])
+	}
+	if !mounter.IsMounted(filepath.Join(NasMountBase, "Archive")) {
+		t.Error("Archive not mounted after import")
+	}
+
+	// Repeating the import with the password adds the rest
+	results = dst.ImportNasShares(exported, map[string]string{"Office": "secret"})
+	if results[0].Message != "already configured" {
+		t.Errorf("Archive result on repeat = %+v, want already configured", results[0])
+	}
+	if !results[1].Success || !results[1].Mounted {
+		t.Errorf("Office result with password = %+v, want imported and mounted", results[1])
+	}
+	shares, err := dst.ListNasShares()
+	if err != nil || len(shares) != 2 {
+		t.Fatalf("ListNasShares() = %v, %v, want 2 shares", shares, err)
+	}
+	id := results[1].ShareID
+	if got := dst.config.NasShares[id].EncryptedPassword; got != encryptPassword("secret") {
+		t.Errorf("imported password = %q, want the one supplied", got)
+	}
+
+	// The imported shares were persisted
+	reloaded, err := NewService(dst.configPath, NewMockMounter())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	if got := reloaded.ExportNasShares(); len(got) != 2 || got[1].Username != "user" || !got[1].HasPassword {
+		t.Errorf("reloaded shares = %+v", got)
+	}
+}
+
+func TestService_ImportNasShares_Invalid(t *testing.T) {
+	s, err := NewService(filepath.Join(t.TempDir(), "sources.json"), NewMockMounter())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	results := s.ImportNasShares([]ShareExport{
+		{Name: "NoIP", Path: "Music", FSType: "cifs"},
+		{Name: "Bad", IP: "192.168.1.10", Path: "Music", FSType: "smb"},
+		{Name: "Evil", IP: "192.168.1.10", Path: "Music", FSType: "cifs", Options: "uid=0;reboot"},
+	}, nil)
+	for _, result := range results {
+		if result.Success || result.Error == "" {
+			t.Errorf("result for %s = %+v, want an error", result.ShareName, result)
+		}
+	}
+	if shares, _ := s.ListNasShares(); len(shares) != 0 {
+		t.Errorf("invalid shares were imported: %+v", shares)
+	}
+}
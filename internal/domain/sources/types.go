@@ -13,13 +13,16 @@ type NasShare struct {
 	Options    string `json:"options,omitempty"`
 	Mounted    bool   `json:"mounted"`
 	MountPoint string `json:"mountPoint"`
+	ReadOnly   bool   `json:"readOnly"`
+	SMBVersion string `json:"smbVersion,omitempty"` // Negotiated SMB protocol version (CIFS only)
 }
 
 // NasDevice represents a discovered NAS device on the network.
 type NasDevice struct {
-	Name     string `json:"name"`
-	IP       string `json:"ip"`
-	Hostname string `json:"hostname,omitempty"`
+	Name      string `json:"name"`
+	IP        string `json:"ip"`
+	Hostname  string `json:"hostname,omitempty"`
+	Workgroup string `json:"workgroup,omitempty"` // Workgroup or domain advertised over WS-Discovery
 }
 
 // ShareInfo represents an available share on a NAS device.
@@ -28,22 +31,51 @@ type ShareInfo struct {
 	Type     string `json:"type"` // "disk", "printer", "ipc"
 	Comment  string `json:"comment,omitempty"`
 	Writable bool   `json:"writable"`
+	Access   string `json:"access,omitempty"` // "read-write", "read-only" or "none"; empty when not checked
 }
 
 // DiscoverResult represents the result of NAS discovery.
 type DiscoverResult struct {
-	Devices []NasDevice `json:"devices"`
-	Error   string      `json:"error,omitempty"`
+	Devices  []NasDevice `json:"devices"`
+	TimedOut bool        `json:"timedOut,omitempty"` // Devices holds what was found before the timeout
+	Error    string      `json:"error,omitempty"`
 }
 
 // BrowseSharesResult represents the result of browsing NAS shares.
 type BrowseSharesResult struct {
-	Shares []ShareInfo `json:"shares"`
-	Error  string      `json:"error,omitempty"`
+	Shares   []ShareInfo `json:"shares"`
+	TimedOut bool        `json:"timedOut,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// UsbDrive represents a partition on a USB storage device.
+type UsbDrive struct {
+	ID         string `json:"id"`     // Kernel partition name, e.g. "sda1"
+	Device     string `json:"device"` // Block device path, e.g. "/dev/sda1"
+	Label      string `json:"label,omitempty"`
+	FSType     string `json:"fstype,omitempty"`
+	Size       int64  `json:"size"` // Bytes
+	Mounted    bool   `json:"mounted"`
+	MountPoint string `json:"mountPoint,omitempty"`
+	HasAudio   bool   `json:"hasAudio"`
+}
+
+// DiskUsage is the capacity of a filesystem in bytes.
+type DiskUsage struct {
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+	Free  uint64 `json:"free"` // Available to unprivileged users
 }
 
-// NOTE: UsbDrive types will be added in Phase 3
-// when USB detection is implemented.
+// SourceUsage reports the capacity of one music source.
+type SourceUsage struct {
+	ID   string `json:"id"` // Share or drive ID; "internal" for the music directory
+	Name string `json:"name"`
+	Type string `json:"type"` // "internal", "usb" or "nas"
+	Path string `json:"path"`
+	DiskUsage
+	Error string `json:"error,omitempty"` // Set when the source could not be measured
+}
 
 // SourceResult represents the result of a source operation.
 type SourceResult struct {
@@ -54,12 +86,27 @@ type SourceResult struct {
 
 // MountResult represents the result of mounting a single share.
 type MountResult struct {
-	ShareID   string `json:"shareId"`
-	ShareName string `json:"shareName"`
-	Success   bool   `json:"success"`
-	Mounted   bool   `json:"mounted"`
-	Message   string `json:"message,omitempty"`
-	Error     string `json:"error,omitempty"`
+	ShareID       string `json:"shareId"`
+	ShareName     string `json:"shareName"`
+	Success       bool   `json:"success"`
+	Mounted       bool   `json:"mounted"`
+	NeedsPassword bool   `json:"needsPassword,omitempty"` // Import skipped the share until its password is given
+	Message       string `json:"message,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ShareExport is a NAS share as carried between devices in a config export.
+// Passwords are never exported; HasPassword marks shares whose password must
+// be supplied again when importing.
+type ShareExport struct {
+	Name        string `json:"name"`
+	IP          string `json:"ip"`
+	Path        string `json:"path"`
+	FSType      string `json:"fstype"`
+	Username    string `json:"username,omitempty"`
+	HasPassword bool   `json:"hasPassword,omitempty"`
+	Options     string `json:"options,omitempty"`
+	ReadOnly    bool   `json:"readOnly"`
 }
 
 // AddNasShareRequest represents a request to add a NAS share.
@@ -71,6 +118,12 @@ type AddNasShareRequest struct {
 	Username string `json:"username,omitempty"`
 	Password string `json:"password,omitempty"`
 	Options  string `json:"options,omitempty"`
+	ReadOnly *bool  `json:"readOnly,omitempty"` // Defaults to true when omitted
+}
+
+// IsReadOnly reports whether the share should be mounted read-only.
+func (r AddNasShareRequest) IsReadOnly() bool {
+	return r.ReadOnly == nil || *r.ReadOnly
 }
 
 // Config represents the persistent configuration for music sources.
@@ -88,4 +141,13 @@ type NasShareConfig struct {
 	Username          string `json:"username,omitempty"`
 	EncryptedPassword string `json:"password,omitempty"` // Stored encrypted
 	Options           string `json:"options,omitempty"`
+	ReadOnly          *bool  `json:"readOnly,omitempty"`   // Absent in older configs, which stay read-only
+	SMBVersion        string `json:"smbVersion,omitempty"` // Last SMB version that mounted successfully
+}
+
+// IsReadOnly reports whether the share should be mounted read-only. Shares
+// saved before the option existed were always mounted read-only, so a
+// missing value keeps them that way.
+func (c *NasShareConfig) IsReadOnly() bool {
+	return c.ReadOnly == nil || *c.ReadOnly
 }
@@ -0,0 +1,353 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultUsbPollInterval is how often attached USB devices are checked.
+	DefaultUsbPollInterval = 3 * time.Second
+	// audioScanMaxDepth limits how deep a new drive is searched for audio files.
+	audioScanMaxDepth = 4
+)
+
+// audioExtensions are the file extensions treated as music when scanning a drive.
+var audioExtensions = map[string]bool{
+	".flac": true, ".mp3": true, ".wav": true, ".aiff": true, ".aif": true,
+	".m4a": true, ".aac": true, ".alac": true, ".ogg": true, ".opus": true,
+	".dsf": true, ".dff": true, ".wv": true, ".ape": true,
+}
+
+// errAudioFound stops the directory walk once a music file has been seen.
+var errAudioFound = errors.New("audio found")
+
+// SetUsbDetector sets the USB detector used for hotplug detection.
+func (s *Service) SetUsbDetector(d UsbDetector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usbDetector = d
+}
+
+// ListUsbDrives returns the currently attached USB partitions.
+func (s *Service) ListUsbDrives() []UsbDrive {
+	s.usbMu.Lock()
+	defer s.usbMu.Unlock()
+
+	drives := make([]UsbDrive, 0, len(s.usbDrives))
+	for _, drive := range s.usbDrives {
+		drives = append(drives, *drive)
+	}
+	sort.Slice(drives, func(i, j int) bool { return drives[i].ID < drives[j].ID })
+	return drives
+}
+
+// LibraryPath returns the drive's directory in the MPD library, e.g. "USB/MUSIC".
+// It is empty when the drive is not mounted.
+func (d UsbDrive) LibraryPath() string {
+	if !d.Mounted {
+		return ""
+	}
+	return "USB/" + filepath.Base(d.MountPoint)
+}
+
+// GetUsbDrive returns an attached USB partition by ID.
+func (s *Service) GetUsbDrive(id string) (*UsbDrive, error) {
+	s.usbMu.Lock()
+	defer s.usbMu.Unlock()
+
+	drive, exists := s.usbDrives[id]
+	if !exists {
+		return nil, fmt.Errorf("USB drive not found: %s", id)
+	}
+	result := *drive
+	return &result, nil
+}
+
+// UsbEjectLibraryPaths returns the MPD library paths that ejecting a USB
+// partition takes offline: those of every mounted partition on its disk.
+func (s *Service) UsbEjectLibraryPaths(id string) ([]string, error) {
+	s.usbMu.Lock()
+	defer s.usbMu.Unlock()
+
+	drive, exists := s.usbDrives[id]
+	if !exists {
+		return nil, fmt.Errorf("USB drive not found: %s", id)
+	}
+
+	var paths []string
+	for _, partID := range s.diskPartitions(usbDisk(drive.Device)) {
+		if path := s.usbDrives[partID].LibraryPath(); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// EjectUsbDrive flushes pending writes, unmounts a USB partition and powers the
+// device down so it can be unplugged safely. Powering down takes the whole
+// disk, so the disk's other partitions are unmounted too.
+func (s *Service) EjectUsbDrive(id string) (*SourceResult, error) {
+	s.mu.RLock()
+	detector := s.usbDetector
+	mounter := s.mounter
+	s.mu.RUnlock()
+
+	if detector == nil || mounter == nil {
+		return &SourceResult{
+			Success: false,
+			Error:   "USB support not available",
+		}, nil
+	}
+
+	s.usbMu.Lock()
+	defer s.usbMu.Unlock()
+
+	drive, exists := s.usbDrives[id]
+	if !exists {
+		return &SourceResult{
+			Success: false,
+			Error:   "USB drive not found",
+		}, nil
+	}
+
+	syscall.Sync()
+
+	for _, partID := range s.diskPartitions(usbDisk(drive.Device)) {
+		part := s.usbDrives[partID]
+		if !part.Mounted {
+			continue
+		}
+		if err := unmountUsbDrive(mounter, part); err != nil {
+			return &SourceResult{
+				Success: false,
+				Error:   fmt.Sprintf("failed to unmount %s: %v", partID, err),
+			}, nil
+		}
+	}
+
+	name := drive.Label
+	if name == "" {
+		name = drive.ID
+	}
+
+	// The drive is safe to unplug once unmounted; powering it off is a courtesy.
+	// It stays in the list, unmounted, until the next scan sees it disappear.
+	if err := detector.PowerOff(drive.Device); err != nil {
+		log.Warn().Err(err).Str("device", drive.Device).Msg("Failed to power off USB drive")
+	}
+
+	return &SourceResult{
+		Success: true,
+		Message: fmt.Sprintf("USB drive '%s' can be removed safely", name),
+	}, nil
+}
+
+// diskPartitions returns the sorted IDs of the known partitions on disk.
+// The caller must hold usbMu.
+func (s *Service) diskPartitions(disk string) []string {
+	ids := make([]string, 0, len(s.usbDrives))
+	for id, drive := range s.usbDrives {
+		if usbDisk(drive.Device) == disk {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// unmountUsbDrive unlinks a mounted partition from the MPD music directory and
+// unmounts it.
+func unmountUsbDrive(mounter Mounter, drive *UsbDrive) error {
+	mounter.RemoveSymlink(filepath.Join(MpdMusicDir, "USB", filepath.Base(drive.MountPoint)))
+	if mounter.IsMounted(drive.MountPoint) {
+		if err := mounter.Unmount(drive.MountPoint); err != nil {
+			return err
+		}
+	}
+	mounter.RemoveMountPoint(drive.MountPoint)
+	drive.Mounted = false
+	drive.MountPoint = ""
+	return nil
+}
+
+// usbDisk returns the disk a partition is on, e.g. "sda" for /dev/sda1. A
+// disk without a partition table is its own disk.
+func usbDisk(device string) string {
+	return strings.TrimRight(filepath.Base(device), "0123456789")
+}
+
+// ScanUsbDrives compares attached USB partitions with the known set, mounting
+// new partitions that contain music and cleaning up removed ones.
+// It returns true if the set of drives changed.
+func (s *Service) ScanUsbDrives() bool {
+	s.mu.RLock()
+	detector := s.usbDetector
+	mounter := s.mounter
+	s.mu.RUnlock()
+
+	if detector == nil || mounter == nil {
+		return false
+	}
+
+	partitions, err := detector.ListPartitions()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to list USB partitions")
+		return false
+	}
+
+	s.usbMu.Lock()
+	defer s.usbMu.Unlock()
+
+	changed := false
+	present := make(map[string]bool, len(partitions))
+	for _, part := range partitions {
+		present[part.ID] = true
+		if _, known := s.usbDrives[part.ID]; known {
+			continue
+		}
+
+		drive := part
+		s.attachUsbDrive(mounter, &drive)
+		s.usbDrives[drive.ID] = &drive
+		changed = true
+	}
+
+	for id, drive := range s.usbDrives {
+		if present[id] {
+			continue
+		}
+		s.detachUsbDrive(mounter, drive)
+		delete(s.usbDrives, id)
+		changed = true
+	}
+
+	return changed
+}
+
+// attachUsbDrive mounts a newly detected partition and links it into the MPD
+// music directory. Partitions without music are unmounted again.
+func (s *Service) attachUsbDrive(mounter Mounter, drive *UsbDrive) {
+	name := s.usbDriveName(drive)
+	mountPoint := filepath.Join(UsbMountBase, name)
+
+	log.Info().Str("device", drive.Device).Str("label", drive.Label).Msg("USB drive detected")
+
+	if err := mounter.CreateMountPoint(mountPoint); err != nil {
+		log.Warn().Err(err).Str("mountPoint", mountPoint).Msg("Failed to create USB mount point")
+		return
+	}
+
+	if err := mounter.MountDevice(drive.Device, mountPoint); err != nil {
+		log.Warn().Err(err).Str("device", drive.Device).Msg("Failed to mount USB drive")
+		mounter.RemoveMountPoint(mountPoint)
+		return
+	}
+
+	if !s.hasAudio(mountPoint) {
+		log.Info().Str("device", drive.Device).Msg("USB drive has no music, leaving it unmounted")
+		if err := mounter.Unmount(mountPoint); err == nil {
+			mounter.RemoveMountPoint(mountPoint)
+		}
+		return
+	}
+
+	drive.Mounted = true
+	drive.MountPoint = mountPoint
+	drive.HasAudio = true
+
+	symlinkPath := filepath.Join(MpdMusicDir, "USB", name)
+	if err := mounter.CreateSymlink(mountPoint, symlinkPath); err != nil {
+		log.Warn().Err(err).Str("symlink", symlinkPath).Msg("Failed to link USB drive into music directory")
+	}
+}
+
+// detachUsbDrive cleans up after a partition that has been removed.
+func (s *Service) detachUsbDrive(mounter Mounter, drive *UsbDrive) {
+	log.Info().Str("device", drive.Device).Msg("USB drive removed")
+
+	if !drive.Mounted {
+		return
+	}
+
+	if err := unmountUsbDrive(mounter, drive); err != nil {
+		log.Warn().Err(err).Str("mountPoint", drive.MountPoint).Msg("Failed to unmount removed USB drive")
+	}
+}
+
+// usbDriveName returns a unique directory name for a partition, based on its
+// label where available. Must be called with usbMu held.
+func (s *Service) usbDriveName(drive *UsbDrive) string {
+	name := sanitizeName(drive.Label)
+	if name == "" {
+		return drive.ID
+	}
+	for _, other := range s.usbDrives {
+		if other.MountPoint != "" && filepath.Base(other.MountPoint) == name {
+			return name + "-" + drive.ID
+		}
+	}
+	return name
+}
+
+// StartUsbWatcher polls for USB devices until ctx is cancelled, calling onChange
+// with the current drive list whenever a drive is attached or removed.
+func (s *Service) StartUsbWatcher(ctx context.Context, interval time.Duration, onChange func([]UsbDrive)) {
+	if interval <= 0 {
+		interval = DefaultUsbPollInterval
+	}
+
+	go func() {
+		log.Info().Dur("interval", interval).Msg("USB watcher started")
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// Pick up drives that were attached before startup
+		if s.ScanUsbDrives() && onChange != nil {
+			onChange(s.ListUsbDrives())
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("USB watcher stopped")
+				return
+			case <-ticker.C:
+				if s.ScanUsbDrives() && onChange != nil {
+					onChange(s.ListUsbDrives())
+				}
+			}
+		}
+	}()
+}
+
+// containsAudioFiles reports whether a directory tree holds any music files,
+// searching at most audioScanMaxDepth levels deep.
+func containsAudioFiles(root string) bool {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			if rel != "." && strings.Count(rel, string(filepath.Separator)) >= audioScanMaxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if audioExtensions[strings.ToLower(filepath.Ext(path))] {
+			return errAudioFound
+		}
+		return nil
+	})
+	return errors.Is(err, errAudioFound)
+}
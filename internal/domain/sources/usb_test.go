@@ -0,0 +1,246 @@
+package sources
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// MockUsbDetector implements UsbDetector for testing.
+type MockUsbDetector struct {
+	Partitions []UsbDrive
+	Error      error
+	PoweredOff []string
+}
+
+func (m *MockUsbDetector) ListPartitions() ([]UsbDrive, error) {
+	return m.Partitions, m.Error
+}
+
+func (m *MockUsbDetector) PowerOff(device string) error {
+	m.PoweredOff = append(m.PoweredOff, device)
+	return nil
+}
+
+func newUsbTestService(t *testing.T, hasAudio bool) (*Service, *MockMounter, *MockUsbDetector) {
+	t.Helper()
+
+	mounter := NewMockMounter()
+	s, err := NewService(filepath.Join(t.TempDir(), "sources.json"), mounter)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	detector := &MockUsbDetector{}
+	s.SetUsbDetector(detector)
+	s.hasAudio = func(string) bool { return hasAudio }
+	return s, mounter, detector
+}
+
+func TestService_ScanUsbDrives_MountsNewDrive(t *testing.T) {
+	s, mounter, detector := newUsbTestService(t, true)
+	detector.Partitions = []UsbDrive{{ID: "sda1", Device: "/dev/sda1", Label: "My Music", FSType: "exfat"}}
+
+	if !s.ScanUsbDrives() {
+		t.Fatal("ScanUsbDrives returned false, want change")
+	}
+
+	drives := s.ListUsbDrives()
+	if len(drives) != 1 {
+		t.Fatalf("ListUsbDrives returned %d drives, want 1", len(drives))
+	}
+	want := filepath.Join(UsbMountBase, "My_Music")
+	if !drives[0].Mounted || drives[0].MountPoint != want {
+		t.Errorf("drive = %+v, want mounted at %s", drives[0], want)
+	}
+	if !mounter.MountedPaths[want] {
+		t.Error("drive was not mounted")
+	}
+
+	// A second scan with the same drive reports no change
+	if s.ScanUsbDrives() {
+		t.Error("second ScanUsbDrives returned true, want no change")
+	}
+}
+
+func TestService_ScanUsbDrives_SkipsDriveWithoutMusic(t *testing.T) {
+	s, mounter, detector := newUsbTestService(t, false)
+	detector.Partitions = []UsbDrive{{ID: "sda1", Device: "/dev/sda1", FSType: "vfat"}}
+
+	if !s.ScanUsbDrives() {
+		t.Fatal("ScanUsbDrives returned false, want change")
+	}
+
+	drives := s.ListUsbDrives()
+	if len(drives) != 1 || drives[0].Mounted {
+		t.Errorf("drives = %+v, want one unmounted drive", drives)
+	}
+	if len(mounter.MountedPaths) != 0 {
+		t.Error("drive without music should be unmounted again")
+	}
+}
+
+func TestService_ScanUsbDrives_RemovedDrive(t *testing.T) {
+	s, mounter, detector := newUsbTestService(t, true)
+	detector.Partitions = []UsbDrive{{ID: "sda1", Device: "/dev/sda1", FSType: "vfat"}}
+	s.ScanUsbDrives()
+
+	detector.Partitions = nil
+	if !s.ScanUsbDrives() {
+		t.Fatal("ScanUsbDrives returned false, want change")
+	}
+
+	if drives := s.ListUsbDrives(); len(drives) != 0 {
+		t.Errorf("ListUsbDrives returned %d drives, want 0", len(drives))
+	}
+	if !mounter.UnmountCalled || len(mounter.MountedPaths) != 0 {
+		t.Error("removed drive was not unmounted")
+	}
+}
+
+func TestService_ScanUsbDrives_DuplicateLabels(t *testing.T) {
+	s, _, detector := newUsbTestService(t, true)
+	detector.Partitions = []UsbDrive{
+		{ID: "sda1", Device: "/dev/sda1", Label: "MUSIC", FSType: "vfat"},
+		{ID: "sdb1", Device: "/dev/sdb1", Label: "MUSIC", FSType: "vfat"},
+	}
+	s.ScanUsbDrives()
+
+	drives := s.ListUsbDrives()
+	if len(drives) != 2 {
+		t.Fatalf("ListUsbDrives returned %d drives, want 2", len(drives))
+	}
+	if drives[0].MountPoint == drives[1].MountPoint {
+		t.Errorf("drives share mount point %s", drives[0].MountPoint)
+	}
+}
+
+func TestService_EjectUsbDrive(t *testing.T) {
+	s, mounter, detector := newUsbTestService(t, true)
+	detector.Partitions = []UsbDrive{{ID: "sda1", Device: "/dev/sda1", Label: "MUSIC", FSType: "vfat"}}
+	s.ScanUsbDrives()
+
+	drive, err := s.GetUsbDrive("sda1")
+	if err != nil {
+		t.Fatalf("GetUsbDrive failed: %v", err)
+	}
+	if drive.LibraryPath() != "USB/MUSIC" {
+		t.Errorf("LibraryPath() = %q, want %q", drive.LibraryPath(), "USB/MUSIC")
+	}
+
+	result, err := s.EjectUsbDrive("sda1")
+	if err != nil {
+		t.Fatalf("EjectUsbDrive returned error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("EjectUsbDrive failed: %s", result.Error)
+	}
+
+	if len(mounter.MountedPaths) != 0 {
+		t.Error("ejected drive is still mounted")
+	}
+	if len(detector.PoweredOff) != 1 || detector.PoweredOff[0] != "/dev/sda1" {
+		t.Errorf("PoweredOff = %v, want [/dev/sda1]", detector.PoweredOff)
+	}
+
+	drives := s.ListUsbDrives()
+	if len(drives) != 1 || drives[0].Mounted {
+		t.Errorf("drives = %+v, want one unmounted drive", drives)
+	}
+
+	// Once unplugged, the drive disappears without another unmount attempt
+	mounter.UnmountCalled = false
+	detector.Partitions = nil
+	s.ScanUsbDrives()
+	if mounter.UnmountCalled {
+		t.Error("ejected drive was unmounted twice")
+	}
+}
+
+func TestService_EjectUsbDrive_Failures(t *testing.T) {
+	s, mounter, detector := newUsbTestService(t, true)
+	detector.Partitions = []UsbDrive{{ID: "sda1", Device: "/dev/sda1", FSType: "vfat"}}
+	s.ScanUsbDrives()
+
+	if result, _ := s.EjectUsbDrive("sdz9"); result.Success {
+		t.Error("EjectUsbDrive of unknown drive succeeded, want failure")
+	}
+
+	mounter.UnmountError = errors.New("target is busy")
+	result, _ := s.EjectUsbDrive("sda1")
+	if result.Success {
+		t.Fatal("EjectUsbDrive succeeded while unmount failed, want failure")
+	}
+	if len(detector.PoweredOff) != 0 {
+		t.Error("busy drive should not be powered off")
+	}
+}
+
+func TestService_EjectUsbDrive_UnmountsWholeDisk(t *testing.T) {
+	s, mounter, detector := newUsbTestService(t, true)
+	detector.Partitions = []UsbDrive{
+		{ID: "sda1", Device: "/dev/sda1", Label: "MUSIC", FSType: "vfat"},
+		{ID: "sda2", Device: "/dev/sda2", Label: "MORE", FSType: "exfat"},
+		{ID: "sdb1", Device: "/dev/sdb1", Label: "OTHER", FSType: "vfat"},
+	}
+	s.ScanUsbDrives()
+
+	result, _ := s.EjectUsbDrive("sda1")
+	if !result.Success {
+		t.Fatalf("EjectUsbDrive failed: %s", result.Error)
+	}
+
+	for _, drive := range s.ListUsbDrives() {
+		if wantMounted := drive.ID == "sdb1"; drive.Mounted != wantMounted {
+			t.Errorf("%s mounted = %v, want %v", drive.ID, drive.Mounted, wantMounted)
+		}
+	}
+	if len(mounter.MountedPaths) != 1 {
+		t.Errorf("mounted paths = %v, want only sdb1's", mounter.MountedPaths)
+	}
+}
+
+func TestService_UsbEjectLibraryPaths(t *testing.T) {
+	s, _, detector := newUsbTestService(t, true)
+	detector.Partitions = []UsbDrive{
+		{ID: "sda1", Device: "/dev/sda1", Label: "MUSIC", FSType: "vfat"},
+		{ID: "sda2", Device: "/dev/sda2", Label: "MORE", FSType: "exfat"},
+		{ID: "sdb1", Device: "/dev/sdb1", Label: "OTHER", FSType: "vfat"},
+	}
+	s.ScanUsbDrives()
+
+	paths, err := s.UsbEjectLibraryPaths("sda2")
+	if err != nil {
+		t.Fatalf("UsbEjectLibraryPaths failed: %v", err)
+	}
+	if want := []string{"USB/MUSIC", "USB/MORE"}; !slices.Equal(paths, want) {
+		t.Errorf("UsbEjectLibraryPaths(sda2) = %v, want %v", paths, want)
+	}
+
+	if _, err := s.UsbEjectLibraryPaths("sdz9"); err == nil {
+		t.Error("UsbEjectLibraryPaths of unknown drive succeeded, want error")
+	}
+}
+
+func TestContainsAudioFiles(t *testing.T) {
+	root := t.TempDir()
+	if containsAudioFiles(root) {
+		t.Error("empty directory reported as containing audio")
+	}
+
+	docs := filepath.Join(root, "Documents")
+	os.MkdirAll(docs, 0755)
+	os.WriteFile(filepath.Join(docs, "notes.txt"), nil, 0644)
+	if containsAudioFiles(root) {
+		t.Error("directory with only documents reported as containing audio")
+	}
+
+	album := filepath.Join(root, "Artist", "Album")
+	os.MkdirAll(album, 0755)
+	os.WriteFile(filepath.Join(album, "01 Track.FLAC"), nil, 0644)
+	if !containsAudioFiles(root) {
+		t.Error("directory with FLAC file not reported as containing audio")
+	}
+}
@@ -0,0 +1,250 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// wsddProbeWait is how long ProbeMatches are collected after probing
+	wsddProbeWait = 2 * time.Second
+
+	// wsddGetTimeout bounds each device metadata request
+	wsddGetTimeout = 2 * time.Second
+
+	wsddMaxResponse = 64 * 1024
+)
+
+// wsddGroup is the IPv4 WS-Discovery multicast address.
+var wsddGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 3702}
+
+const wsddProbeTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:wsd="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:wsdp="http://schemas.xmlsoap.org/ws/2006/02/devprof" xmlns:pub="http://schemas.microsoft.com/windows/pub/2005/07">
+<soap:Header>
+<wsa:To>urn:schemas-xmlsoap-org:ws:2005:04:discovery</wsa:To>
+<wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</wsa:Action>
+<wsa:MessageID>%s</wsa:MessageID>
+</soap:Header>
+<soap:Body><wsd:Probe><wsd:Types>wsdp:Device pub:Computer</wsd:Types></wsd:Probe></soap:Body>
+</soap:Envelope>`
+
+const wsddGetTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing">
+<soap:Header>
+<wsa:To>%s</wsa:To>
+<wsa:Action>http://schemas.xmlsoap.org/ws/2004/09/transfer/Get</wsa:Action>
+<wsa:MessageID>%s</wsa:MessageID>
+<wsa:ReplyTo><wsa:Address>http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</wsa:Address></wsa:ReplyTo>
+</soap:Header>
+<soap:Body/>
+</soap:Envelope>`
+
+// wsddProbeMatch is one device answering a WS-Discovery probe.
+type wsddProbeMatch struct {
+	Endpoint string
+	XAddrs   []string
+}
+
+// discoverViaWSDD probes for WS-Discovery hosts, which is the only way
+// Windows 10/11 announces itself once SMB1/NetBIOS browsing is off.
+func (d *LinuxDiscoverer) discoverViaWSDD(ctx context.Context) []NasDevice {
+	devices := make([]NasDevice, 0)
+
+	group := d.wsddAddr
+	if group == nil {
+		group = wsddGroup
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		log.Debug().Err(err).Msg("WSDD probe failed")
+		return devices
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(wsddProbeWait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	messageID := "urn:uuid:" + uuid.NewString()
+	probe := []byte(fmt.Sprintf(wsddProbeTemplate, messageID))
+	if _, err := conn.WriteToUDP(probe, group); err != nil {
+		log.Debug().Err(err).Msg("WSDD probe failed")
+		return devices
+	}
+
+	type found struct {
+		ip    string
+		match wsddProbeMatch
+	}
+	var matches []found
+	seen := make(map[string]bool)
+	buf := make([]byte, wsddMaxResponse)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break // Deadline reached or ctx ended
+		}
+		probeMatches, err := parseWSDDProbeMatches(buf[:n], messageID)
+		if err != nil {
+			log.Debug().Err(err).Str("from", from.String()).Msg("Ignoring WSDD response")
+			continue
+		}
+		ip := from.IP.String()
+		for _, match := range probeMatches {
+			if !seen[ip] {
+				seen[ip] = true
+				matches = append(matches, found{ip: ip, match: match})
+			}
+		}
+	}
+
+	// Resolve names and workgroups concurrently
+	devices = make([]NasDevice, len(matches))
+	var wg sync.WaitGroup
+	for i, m := range matches {
+		devices[i] = NasDevice{Name: m.ip, IP: m.ip}
+		wg.Add(1)
+		go func(device *NasDevice, match wsddProbeMatch) {
+			defer wg.Done()
+			xaddr := pickXAddr(match.XAddrs, device.IP)
+			if xaddr == "" {
+				return
+			}
+			name, workgroup, err := fetchWSDDComputer(ctx, xaddr, match.Endpoint)
+			if err != nil {
+				log.Debug().Err(err).Str("ip", device.IP).Msg("WSDD metadata request failed")
+				return
+			}
+			if name != "" {
+				device.Name = name
+				device.Hostname = name
+			}
+			device.Workgroup = workgroup
+		}(&devices[i], m.match)
+	}
+	wg.Wait()
+
+	return devices
+}
+
+// parseWSDDProbeMatches extracts the matches of a ProbeMatches message
+// answering messageID.
+func parseWSDDProbeMatches(data []byte, messageID string) ([]wsddProbeMatch, error) {
+	var env struct {
+		RelatesTo string `xml:"Header>RelatesTo"`
+		Matches   []struct {
+			Address string `xml:"EndpointReference>Address"`
+			XAddrs  string `xml:"XAddrs"`
+		} `xml:"Body>ProbeMatches>ProbeMatch"`
+	}
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(env.RelatesTo) != messageID {
+		return nil, fmt.Errorf("not a reply to our probe")
+	}
+
+	matches := make([]wsddProbeMatch, 0, len(env.Matches))
+	for _, m := range env.Matches {
+		matches = append(matches, wsddProbeMatch{
+			Endpoint: strings.TrimSpace(m.Address),
+			XAddrs:   strings.Fields(m.XAddrs),
+		})
+	}
+	return matches, nil
+}
+
+// pickXAddr prefers the transport address on the IP the match came from.
+func pickXAddr(xaddrs []string, ip string) string {
+	for _, xaddr := range xaddrs {
+		if u, err := url.Parse(xaddr); err == nil && u.Hostname() == ip {
+			return xaddr
+		}
+	}
+	if len(xaddrs) > 0 {
+		return xaddrs[0]
+	}
+	return ""
+}
+
+// fetchWSDDComputer asks a device for its metadata and returns the computer
+// name and workgroup (or domain) it advertises.
+func fetchWSDDComputer(ctx context.Context, xaddr, endpoint string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wsddGetTimeout)
+	defer cancel()
+
+	var to bytes.Buffer
+	xml.EscapeText(&to, []byte(endpoint))
+	body := fmt.Sprintf(wsddGetTemplate, to.String(), "urn:uuid:"+uuid.NewString())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, xaddr, strings.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("metadata request returned %s", resp.Status)
+	}
+
+	computer, err := findWSDDComputer(io.LimitReader(resp.Body, wsddMaxResponse))
+	if err != nil {
+		return "", "", err
+	}
+	name, workgroup := parseWSDDComputer(computer)
+	return name, workgroup, nil
+}
+
+// findWSDDComputer returns the text of the first pub:Computer element.
+func findWSDDComputer(r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return "", nil
+			}
+			return "", err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "Computer" {
+			var text string
+			if err := dec.DecodeElement(&text, &start); err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(text), nil
+		}
+	}
+}
+
+// parseWSDDComputer splits "NAME/Workgroup:GROUP" or "NAME/Domain:DOMAIN".
+func parseWSDDComputer(computer string) (name, workgroup string) {
+	name, membership, _ := strings.Cut(computer, "/")
+	for _, prefix := range []string{"Workgroup:", "Domain:"} {
+		if group, ok := strings.CutPrefix(membership, prefix); ok {
+			return name, group
+		}
+	}
+	return name, ""
+}
@@ -0,0 +1,119 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+const testProbeMatches = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:wsd="http://schemas.xmlsoap.org/ws/2005/04/discovery">
+<soap:Header>
+<wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/ProbeMatches</wsa:Action>
+<wsa:RelatesTo>%s</wsa:RelatesTo>
+</soap:Header>
+<soap:Body><wsd:ProbeMatches><wsd:ProbeMatch>
+<wsa:EndpointReference><wsa:Address>urn:uuid:2f0e9d6c-0000-4000-8000-000000000001</wsa:Address></wsa:EndpointReference>
+<wsd:Types>wsdp:Device pub:Computer</wsd:Types>
+<wsd:XAddrs>%s</wsd:XAddrs>
+</wsd:ProbeMatch></wsd:ProbeMatches></soap:Body>
+</soap:Envelope>`
+
+const testMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsx="http://schemas.xmlsoap.org/ws/2004/09/mex" xmlns:wsdp="http://schemas.xmlsoap.org/ws/2006/02/devprof" xmlns:pub="http://schemas.microsoft.com/windows/pub/2005/07">
+<soap:Body><wsx:Metadata><wsx:MetadataSection Dialect="http://schemas.xmlsoap.org/ws/2006/02/devprof/Relationship">
+<wsdp:Relationship><wsdp:Host><wsdp:Types>pub:Computer</wsdp:Types><pub:Computer>DESKTOP-MUSIC/Workgroup:HOME</pub:Computer></wsdp:Host></wsdp:Relationship>
+</wsx:MetadataSection></wsx:Metadata></soap:Body>
+</soap:Envelope>`
+
+func TestLinuxDiscoverer_DiscoverViaWSDD(t *testing.T) {
+	var gotTo string
+	meta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf [4096]byte
+		n, _ := r.Body.Read(buf[:])
+		if m := regexp.MustCompile(`<wsa:To>(.*?)</wsa:To>`).FindSubmatch(buf[:n]); m != nil {
+			gotTo = string(m[1])
+		}
+		fmt.Fprint(w, testMetadata)
+	}))
+	defer meta.Close()
+
+	// Stand-in for a Windows host answering probes
+	responder, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responder.Close()
+	go func() {
+		buf := make([]byte, 4096)
+		n, from, err := responder.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		m := regexp.MustCompile(`<wsa:MessageID>(.*?)</wsa:MessageID>`).FindSubmatch(buf[:n])
+		if m == nil {
+			return
+		}
+		responder.WriteToUDP([]byte(fmt.Sprintf(testProbeMatches, m[1], meta.URL+"/wsd")), from)
+	}()
+
+	d := &LinuxDiscoverer{wsddAddr: responder.LocalAddr().(*net.UDPAddr)}
+	devices := d.discoverViaWSDD(context.Background())
+
+	if len(devices) != 1 {
+		t.Fatalf("got %d devices, want 1: %+v", len(devices), devices)
+	}
+	want := NasDevice{Name: "DESKTOP-MUSIC", IP: "127.0.0.1", Hostname: "DESKTOP-MUSIC", Workgroup: "HOME"}
+	if devices[0] != want {
+		t.Errorf("device = %+v, want %+v", devices[0], want)
+	}
+	if gotTo != "urn:uuid:2f0e9d6c-0000-4000-8000-000000000001" {
+		t.Errorf("metadata request addressed to %q, want the endpoint", gotTo)
+	}
+}
+
+func TestParseWSDDProbeMatches_IgnoresOtherProbes(t *testing.T) {
+	data := []byte(fmt.Sprintf(testProbeMatches, "urn:uuid:other", "http://192.168.1.20:5357/x"))
+	if _, err := parseWSDDProbeMatches(data, "urn:uuid:mine"); err == nil {
+		t.Error("accepted a reply to another probe")
+	}
+
+	matches, err := parseWSDDProbeMatches(data, "urn:uuid:other")
+	if err != nil || len(matches) != 1 || matches[0].XAddrs[0] != "http://192.168.1.20:5357/x" {
+		t.Errorf("parseWSDDProbeMatches = %+v, %v", matches, err)
+	}
+}
+
+func TestParseWSDDComputer(t *testing.T) {
+	tests := []struct {
+		in, name, workgroup string
+	}{
+		{"DESKTOP-MUSIC/Workgroup:HOME", "DESKTOP-MUSIC", "HOME"},
+		{"OFFICE-PC/Domain:CORP", "OFFICE-PC", "CORP"},
+		{"LAPTOP/Not a domain member", "LAPTOP", ""},
+		{"BARE", "BARE", ""},
+	}
+	for _, tt := range tests {
+		name, workgroup := parseWSDDComputer(tt.in)
+		if name != tt.name || workgroup != tt.workgroup {
+			t.Errorf("parseWSDDComputer(%q) = %q, %q; want %q, %q", tt.in, name, workgroup, tt.name, tt.workgroup)
+		}
+	}
+}
+
+func TestPickXAddr(t *testing.T) {
+	xaddrs := []string{"http://[fe80::1]:5357/x", "http://192.168.1.20:5357/x"}
+	if got := pickXAddr(xaddrs, "192.168.1.20"); got != xaddrs[1] {
+		t.Errorf("pickXAddr = %q, want the IPv4 address", got)
+	}
+	if got := pickXAddr(xaddrs, "192.168.1.99"); got != xaddrs[0] {
+		t.Errorf("pickXAddr = %q, want the first address", got)
+	}
+	if got := pickXAddr(nil, "192.168.1.20"); got != "" {
+		t.Errorf("pickXAddr(nil) = %q", got)
+	}
+}
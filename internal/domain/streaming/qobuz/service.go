@@ -19,6 +19,9 @@ const (
 
 	// QobuzIconPath is the path to the Qobuz icon.
 	QobuzIconPath = "/albumart?sourceicon=music_service/qobuz/qobuz.svg"
+
+	// maxTrackArt bounds how many tracks' album art is remembered.
+	maxTrackArt = 5000
 )
 
 // Service implements the Qobuz streaming service.
@@ -28,6 +31,10 @@ type Service struct {
 	configPath string
 	mu         sync.RWMutex
 	status     *streaming.StreamingStatus
+
+	// Album art of tracks seen while browsing, keyed by qobuz://track URI
+	artMu    sync.RWMutex
+	trackArt map[string]string
 }
 
 // Config holds Qobuz-specific configuration.
@@ -43,6 +50,7 @@ func NewService(configPath string) (*Service, error) {
 	s := &Service{
 		configPath: configPath,
 		config:     &Config{},
+		trackArt:   make(map[string]string),
 		status: &streaming.StreamingStatus{
 			LoggedIn: false,
 		},
@@ -290,6 +298,7 @@ func (s *Service) Search(query string, limit int) (*streaming.BrowseResult, erro
 			})
 		}
 	}
+	s.rememberTrackArt(items)
 
 	return &streaming.BrowseResult{
 		Navigation: streaming.Navigation{
@@ -304,6 +313,31 @@ func (s *Service) Search(query string, limit int) (*streaming.BrowseResult, erro
 	}, nil
 }
 
+// AlbumArtURL returns the album art of a qobuz://track URI seen while
+// browsing or searching, or the Qobuz icon if it hasn't been seen.
+func (s *Service) AlbumArtURL(uri string) string {
+	s.artMu.RLock()
+	defer s.artMu.RUnlock()
+	if art := s.trackArt[uri]; art != "" {
+		return art
+	}
+	return QobuzIconPath
+}
+
+// rememberTrackArt records the album art of the songs in items for AlbumArtURL.
+func (s *Service) rememberTrackArt(items []streaming.BrowseItem) {
+	s.artMu.Lock()
+	defer s.artMu.Unlock()
+	if len(s.trackArt)+len(items) > maxTrackArt {
+		clear(s.trackArt)
+	}
+	for _, item := range items {
+		if item.Type == "song" && item.AlbumArt != "" {
+			s.trackArt[item.URI] = item.AlbumArt
+		}
+	}
+}
+
 // GetStreamURL returns the streaming URL for a track.
 func (s *Service) GetStreamURL(trackID string) (*streaming.TrackStreamInfo, error) {
 	if !s.IsLoggedIn() {
@@ -449,6 +483,7 @@ func (s *Service) browseAlbum(albumID string) (*streaming.BrowseResult, error) {
 			TrackNumber: track.TrackNumber,
 		})
 	}
+	s.rememberTrackArt(items)
 
 	artistName := ""
 	if album.Artist != nil {
@@ -549,6 +584,7 @@ func (s *Service) browsePlaylist(playlistID string) (*streaming.BrowseResult, er
 			Duration: track.Duration,
 		})
 	}
+	s.rememberTrackArt(items)
 
 	return &streaming.BrowseResult{
 		Navigation: streaming.Navigation{
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/streaming"
 )
 
 func TestNewService(t *testing.T) {
@@ -197,3 +199,26 @@ func TestURIParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestAlbumArtURL(t *testing.T) {
+	svc, err := NewService(filepath.Join(t.TempDir(), "qobuz.json"))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	if got := svc.AlbumArtURL("qobuz://track/1"); got != QobuzIconPath {
+		t.Errorf("unseen track art = %q, want the Qobuz icon", got)
+	}
+
+	svc.rememberTrackArt([]streaming.BrowseItem{
+		{Type: "song", URI: "qobuz://track/1", AlbumArt: "https://static.qobuz.com/1.jpg"},
+		{Type: "album", URI: "qobuz://album/2", AlbumArt: "https://static.qobuz.com/2.jpg"},
+	})
+
+	if got := svc.AlbumArtURL("qobuz://track/1"); got != "https://static.qobuz.com/1.jpg" {
+		t.Errorf("seen track art = %q, want its album art", got)
+	}
+	if got := svc.AlbumArtURL("qobuz://album/2"); got != QobuzIconPath {
+		t.Errorf("album art = %q, only songs should be remembered", got)
+	}
+}
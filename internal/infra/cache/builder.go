@@ -3,10 +3,12 @@ package cache
 
 import (
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -34,6 +36,7 @@ type AlbumDetailsData struct {
 	FirstTrack  string
 	TotalTime   int
 	Year        int
+	AddedAt     time.Time // When the newest track was added (zero if unknown)
 }
 
 // TrackData represents track data from MPD.
@@ -93,6 +96,30 @@ func (c *DefaultPathClassifier) GetSourceType(uri string) string {
 	return "local"
 }
 
+// ErrBuildInProgress is returned by FullBuild while another build is running.
+var ErrBuildInProgress = errors.New("cache build already in progress")
+
+// Build phases reported in BuildProgress, in order.
+const (
+	BuildPhaseClearing = "clearing"
+	BuildPhaseAlbums   = "albums"
+	BuildPhaseArtists  = "artists"
+	BuildPhaseRadio    = "radio"
+	BuildPhaseComplete = "complete"
+	BuildPhaseFailed   = "failed"
+)
+
+// buildProgressSteps limits progress reports to about one per percent of a phase.
+const buildProgressSteps = 100
+
+// BuildProgress reports how far a full build has got through its current phase.
+type BuildProgress struct {
+	Phase string `json:"phase"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	Error string `json:"error,omitempty"` // Set when Phase is BuildPhaseFailed
+}
+
 // Builder handles building and updating the cache from MPD.
 type Builder struct {
 	db         *DB
@@ -100,6 +127,8 @@ type Builder struct {
 	provider   MPDDataProvider
 	classifier PathClassifier
 	basePaths  []string // Base paths to scan (e.g., ["INTERNAL", "USB", "NAS"])
+	building   atomic.Bool
+	progress   func(BuildProgress)
 }
 
 // NewBuilder creates a new cache builder.
@@ -121,8 +150,35 @@ func (b *Builder) SetBasePaths(paths []string) {
 	b.basePaths = paths
 }
 
-// FullBuild performs a complete cache rebuild from MPD.
+// SetProgressFunc sets a function called as full builds progress. It must
+// not block, as it runs on the build goroutine.
+func (b *Builder) SetProgressFunc(fn func(BuildProgress)) {
+	b.progress = fn
+}
+
+// IsBuilding reports whether a full build is running.
+func (b *Builder) IsBuilding() bool {
+	return b.building.Load()
+}
+
+// FullBuild wipes the cache and rebuilds it from MPD. Only one build runs at
+// a time; others return ErrBuildInProgress.
 func (b *Builder) FullBuild() error {
+	if !b.building.CompareAndSwap(false, true) {
+		return ErrBuildInProgress
+	}
+	defer b.building.Store(false)
+
+	err := b.fullBuild()
+	if err != nil {
+		b.report(BuildProgress{Phase: BuildPhaseFailed, Error: err.Error()})
+	} else {
+		b.report(BuildProgress{Phase: BuildPhaseComplete})
+	}
+	return err
+}
+
+func (b *Builder) fullBuild() error {
 	startTime := time.Now()
 	log.Info().Msg("Starting full cache build from MPD")
 
@@ -130,6 +186,7 @@ func (b *Builder) FullBuild() error {
 	defer b.db.SetBuildingState(false, 100)
 
 	// Clear existing cache
+	b.report(BuildProgress{Phase: BuildPhaseClearing})
 	if err := b.db.Clear(); err != nil {
 		return fmt.Errorf("failed to clear cache: %w", err)
 	}
@@ -174,59 +231,50 @@ func (b *Builder) FullBuild() error {
 
 // buildAlbums builds the albums cache from MPD.
 func (b *Builder) buildAlbums() error {
-	tx, err := b.db.BeginTx()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	albumCount := 0
-
+	// Fetch every base path first so progress has a total
+	var albums []AlbumDetailsData
 	for _, basePath := range b.basePaths {
-		albums, err := b.provider.GetAlbumDetails(basePath)
+		details, err := b.provider.GetAlbumDetails(basePath)
 		if err != nil {
 			log.Warn().Err(err).Str("basePath", basePath).Msg("Failed to get albums for base path")
 			continue
 		}
+		albums = append(albums, details...)
+	}
 
-		for _, album := range albums {
-			if album.Album == "" {
-				continue
-			}
-
-			// Generate album ID
-			albumID := generateAlbumID(album.AlbumArtist, album.Album)
-
-			// Get source type from first track path
-			source := b.classifier.GetSourceType(album.FirstTrack)
-
-			// Get directory URI for playback
-			uri := filepath.Dir(album.FirstTrack)
-
-			cachedAlbum := &CachedAlbum{
-				ID:            albumID,
-				Title:         album.Album,
-				AlbumArtist:   album.AlbumArtist,
-				URI:           uri,
-				FirstTrack:    album.FirstTrack,
-				TrackCount:    album.TrackCount,
-				TotalDuration: album.TotalTime,
-				Source:        source,
-				Year:          album.Year,
-				AddedAt:       time.Now(), // Would be better to get from file mtime
-			}
+	var rows BulkData
+	for _, album := range albums {
+		if album.Album == "" {
+			continue
+		}
 
-			if err := b.dao.InsertAlbumTx(tx, cachedAlbum); err != nil {
-				log.Warn().Err(err).Str("album", album.Album).Msg("Failed to insert album")
-				continue
-			}
+		// Get directory URI for playback
+		uri := filepath.Dir(album.FirstTrack)
 
-			albumCount++
+		addedAt := album.AddedAt
+		if addedAt.IsZero() {
+			addedAt = time.Now()
 		}
+
+		rows.Albums = append(rows.Albums, &CachedAlbum{
+			ID:            generateAlbumID(album.AlbumArtist, album.Album),
+			Title:         album.Album,
+			AlbumArtist:   album.AlbumArtist,
+			URI:           uri,
+			FirstTrack:    album.FirstTrack,
+			TrackCount:    album.TrackCount,
+			TotalDuration: album.TotalTime,
+			Source:        b.classifier.GetSourceType(album.FirstTrack),
+			Year:          album.Year,
+			AddedAt:       addedAt,
+		})
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit albums: %w", err)
+	progress := b.phaseReporter(BuildPhaseAlbums, rows.Len())
+	progress(0)
+	albumCount, err := b.dao.BulkInsert(rows, func(done, _ int) { progress(done) })
+	if err != nil {
+		return fmt.Errorf("failed to insert albums: %w", err)
 	}
 
 	log.Debug().Int("count", albumCount).Msg("Albums cached")
@@ -240,36 +288,26 @@ func (b *Builder) buildArtists() error {
 		return fmt.Errorf("failed to get artist counts: %w", err)
 	}
 
-	tx, err := b.db.BeginTx()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
+	var rows BulkData
 	for artistName, albumCount := range artistCounts {
 		if artistName == "" {
 			continue
 		}
-
-		artistID := generateArtistID(artistName)
-
-		artist := &CachedArtist{
-			ID:         artistID,
+		rows.Artists = append(rows.Artists, &CachedArtist{
+			ID:         generateArtistID(artistName),
 			Name:       artistName,
 			AlbumCount: albumCount,
-		}
-
-		if err := b.dao.InsertArtistTx(tx, artist); err != nil {
-			log.Warn().Err(err).Str("artist", artistName).Msg("Failed to insert artist")
-			continue
-		}
+		})
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit artists: %w", err)
+	progress := b.phaseReporter(BuildPhaseArtists, rows.Len())
+	progress(0)
+	artistCount, err := b.dao.BulkInsert(rows, func(done, _ int) { progress(done) })
+	if err != nil {
+		return fmt.Errorf("failed to insert artists: %w", err)
 	}
 
-	log.Debug().Int("count", len(artistCounts)).Msg("Artists cached")
+	log.Debug().Int("count", artistCount).Msg("Artists cached")
 	return nil
 }
 
@@ -287,8 +325,10 @@ func (b *Builder) buildRadioStations() error {
 	defer tx.Rollback()
 
 	radioCount := 0
+	progress := b.phaseReporter(BuildPhaseRadio, len(playlists))
 
-	for _, playlist := range playlists {
+	for i, playlist := range playlists {
+		progress(i)
 		// Only process playlists that look like radio stations
 		if !strings.HasPrefix(playlist, "Radio/") && !strings.HasPrefix(strings.ToLower(playlist), "radio") {
 			continue
@@ -327,6 +367,8 @@ func (b *Builder) buildRadioStations() error {
 		radioCount++
 	}
 
+	progress(len(playlists))
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit radio stations: %w", err)
 	}
@@ -335,6 +377,24 @@ func (b *Builder) buildRadioStations() error {
 	return nil
 }
 
+// phaseReporter returns a function reporting done of total for a build
+// phase. It reports the start and end of the phase and about once per
+// percent in between.
+func (b *Builder) phaseReporter(phase string, total int) func(done int) {
+	step := max(total/buildProgressSteps, 1)
+	return func(done int) {
+		if done == 0 || done == total || done%step == 0 {
+			b.report(BuildProgress{Phase: phase, Done: done, Total: total})
+		}
+	}
+}
+
+func (b *Builder) report(progress BuildProgress) {
+	if b.progress != nil {
+		b.progress(progress)
+	}
+}
+
 // BuildAlbumTracks builds the track cache for a specific album.
 // This is called on-demand when tracks are requested.
 func (b *Builder) BuildAlbumTracks(albumID, album, albumArtist string) error {
@@ -343,20 +403,12 @@ func (b *Builder) BuildAlbumTracks(albumID, album, albumArtist string) error {
 		return fmt.Errorf("failed to get album tracks: %w", err)
 	}
 
-	tx, err := b.db.BeginTx()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
+	var rows BulkData
 	for _, track := range tracks {
-		trackID := generateTrackID(track.File)
-		source := b.classifier.GetSourceType(track.File)
-
-		trackNumber, _ := strconv.Atoi(track.Track)
+		trackNumber := ParseNumberTag(track.Track)
 		discNumber := 1
-		if track.Disc != "" {
-			discNumber, _ = strconv.Atoi(track.Disc)
+		if n := ParseNumberTag(track.Disc); n > 0 {
+			discNumber = n
 		}
 
 		duration := 0
@@ -368,8 +420,8 @@ func (b *Builder) BuildAlbumTracks(albumID, album, albumArtist string) error {
 			duration, _ = strconv.Atoi(track.Time)
 		}
 
-		cachedTrack := &CachedTrack{
-			ID:          trackID,
+		rows.Tracks = append(rows.Tracks, &CachedTrack{
+			ID:          generateTrackID(track.File),
 			AlbumID:     albumID,
 			Title:       track.Title,
 			Artist:      track.Artist,
@@ -377,19 +429,13 @@ func (b *Builder) BuildAlbumTracks(albumID, album, albumArtist string) error {
 			TrackNumber: trackNumber,
 			DiscNumber:  discNumber,
 			Duration:    duration,
-			Source:      source,
-		}
-
-		if err := b.dao.InsertTrackTx(tx, cachedTrack); err != nil {
-			log.Warn().Err(err).Str("track", track.Title).Msg("Failed to insert track")
-			continue
-		}
+			Source:      b.classifier.GetSourceType(track.File),
+		})
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit tracks: %w", err)
+	if _, err := b.dao.BulkInsert(rows, nil); err != nil {
+		return fmt.Errorf("failed to insert tracks: %w", err)
 	}
-
 	return nil
 }
 
@@ -412,3 +458,44 @@ func generateRadioID(name, uri string) string {
 	data := name + "\x00" + uri
 	return fmt.Sprintf("%x", md5.Sum([]byte(data)))
 }
+
+// discTags are the tag spellings checked for a track's disc number.
+var discTags = []string{"Disc", "disc", "DiscNumber"}
+
+// ParseNumberTag parses a track or disc number tag, which can be "1" or "1/12".
+// It returns 0 if the tag is missing or malformed.
+func ParseNumberTag(tag string) int {
+	if idx := strings.Index(tag, "/"); idx > 0 {
+		tag = tag[:idx]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(tag))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// DiscNumber returns a track's disc number from its MPD tags, defaulting to 1
+// when untagged.
+func DiscNumber(tags map[string]string) int {
+	for _, tag := range discTags {
+		if n := ParseNumberTag(tags[tag]); n > 0 {
+			return n
+		}
+	}
+	return 1
+}
+
+// TrackAddedAt returns when a track was added to the library, from MPD's
+// "Added" tag (MPD 0.24+) or, failing that, the file modification time. It
+// returns the zero time if neither is known.
+func TrackAddedAt(tags map[string]string) time.Time {
+	for _, key := range []string{"Added", "Last-Modified"} {
+		if value := tags[key]; value != "" {
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,193 @@
+package cache_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
+)
+
+// fakeProvider serves a fixed library. If block is set, GetAlbumDetails
+// signals started and waits for block to close.
+type fakeProvider struct {
+	albums  []cache.AlbumDetailsData
+	artists map[string]int
+	started chan struct{}
+	block   chan struct{}
+}
+
+func (f *fakeProvider) GetAlbumDetails(basePath string) ([]cache.AlbumDetailsData, error) {
+	if f.block != nil {
+		f.started <- struct{}{}
+		<-f.block
+	}
+	if basePath != "NAS" {
+		return nil, nil
+	}
+	return f.albums, nil
+}
+
+func (f *fakeProvider) GetArtistsWithAlbumCounts() (map[string]int, error) {
+	return f.artists, nil
+}
+
+func (f *fakeProvider) FindAlbumTracks(album, albumArtist string) ([]cache.TrackData, error) {
+	return nil, nil
+}
+
+func (f *fakeProvider) ListPlaylists() ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeProvider) ListPlaylistInfo(name string) ([]cache.TrackData, error) {
+	return nil, nil
+}
+
+func openTestDB(t *testing.T) *cache.DB {
+	t.Helper()
+	db := cache.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBuilderFullBuildReportsProgress(t *testing.T) {
+	db := openTestDB(t)
+	provider := &fakeProvider{
+		albums: []cache.AlbumDetailsData{
+			{Album: "Kind of Blue", AlbumArtist: "Miles Davis", FirstTrack: "NAS/Miles/Kind of Blue/01.flac"},
+			{Album: "Blue Train", AlbumArtist: "John Coltrane", FirstTrack: "NAS/Coltrane/Blue Train/01.flac"},
+		},
+		artists: map[string]int{"Miles Davis": 1, "John Coltrane": 1},
+	}
+	builder := cache.NewBuilder(db, provider, nil)
+
+	var reports []cache.BuildProgress
+	builder.SetProgressFunc(func(p cache.BuildProgress) {
+		reports = append(reports, p)
+	})
+
+	if err := builder.FullBuild(); err != nil {
+		t.Fatalf("FullBuild: %v", err)
+	}
+
+	stats, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.AlbumCount != 2 || stats.ArtistCount != 2 {
+		t.Errorf("cached %d albums and %d artists, want 2 and 2", stats.AlbumCount, stats.ArtistCount)
+	}
+
+	if len(reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+	if reports[0].Phase != cache.BuildPhaseClearing {
+		t.Errorf("first phase = %q, want %q", reports[0].Phase, cache.BuildPhaseClearing)
+	}
+	if last := reports[len(reports)-1]; last.Phase != cache.BuildPhaseComplete {
+		t.Errorf("last phase = %q, want %q", last.Phase, cache.BuildPhaseComplete)
+	}
+
+	// Each counted phase ends with done == total
+	final := map[string]cache.BuildProgress{}
+	for _, p := range reports {
+		final[p.Phase] = p
+	}
+	for phase, want := range map[string]int{cache.BuildPhaseAlbums: 2, cache.BuildPhaseArtists: 2} {
+		if got := final[phase]; got.Done != want || got.Total != want {
+			t.Errorf("%s ended at %d/%d, want %d/%d", phase, got.Done, got.Total, want, want)
+		}
+	}
+}
+
+func TestBuilderRejectsConcurrentBuild(t *testing.T) {
+	db := openTestDB(t)
+	provider := &fakeProvider{
+		started: make(chan struct{}),
+		block:   make(chan struct{}),
+	}
+	builder := cache.NewBuilder(db, provider, nil)
+	builder.SetBasePaths([]string{"NAS"})
+
+	done := make(chan error)
+	go func() { done <- builder.FullBuild() }()
+	<-provider.started
+
+	if !builder.IsBuilding() {
+		t.Error("IsBuilding() = false during a build")
+	}
+	if err := builder.FullBuild(); !errors.Is(err, cache.ErrBuildInProgress) {
+		t.Errorf("second FullBuild = %v, want ErrBuildInProgress", err)
+	}
+
+	close(provider.block)
+	if err := <-done; err != nil {
+		t.Fatalf("FullBuild: %v", err)
+	}
+	if builder.IsBuilding() {
+		t.Error("IsBuilding() = true after the build finished")
+	}
+}
+
+func TestDiscNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want int
+	}{
+		{"plain", map[string]string{"Disc": "2"}, 2},
+		{"with total", map[string]string{"Disc": "2/3"}, 2},
+		{"lowercase tag", map[string]string{"disc": "3"}, 3},
+		{"DiscNumber tag", map[string]string{"DiscNumber": "4/4"}, 4},
+		{"missing defaults to 1", map[string]string{}, 1},
+		{"malformed defaults to 1", map[string]string{"Disc": "A"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.DiscNumber(tt.tags); got != tt.want {
+				t.Errorf("DiscNumber(%v) = %d, want %d", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackAddedAt(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want time.Time
+	}{
+		{
+			name: "added tag preferred",
+			tags: map[string]string{"Added": "2025-03-02T10:00:00Z", "Last-Modified": "2020-01-01T00:00:00Z"},
+			want: time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "mtime fallback",
+			tags: map[string]string{"Last-Modified": "2020-01-01T00:00:00Z"},
+			want: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "unparseable",
+			tags: map[string]string{"Added": "yesterday"},
+		},
+		{
+			name: "missing",
+			tags: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.TrackAddedAt(tt.tags); !got.Equal(tt.want) {
+				t.Errorf("TrackAddedAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
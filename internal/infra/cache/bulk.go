@@ -0,0 +1,92 @@
+// Package cache provides a SQLite-based caching layer for library metadata.
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// BulkInsertBatchSize is how many rows BulkInsert commits per transaction.
+const BulkInsertBatchSize = 2000
+
+// BulkData holds rows for BulkInsert.
+type BulkData struct {
+	Albums  []*CachedAlbum
+	Artists []*CachedArtist
+	Tracks  []*CachedTrack
+}
+
+// Len returns the total number of rows.
+func (d BulkData) Len() int {
+	return len(d.Albums) + len(d.Artists) + len(d.Tracks)
+}
+
+// BulkInsert inserts or updates albums, then artists, then tracks, committing
+// every BulkInsertBatchSize rows. Grouping rows into a few large transactions
+// avoids a journal sync per row, which dominates build time on SD cards,
+// while the batch limit keeps the WAL from growing without bound on large
+// libraries. Rows that fail are logged and skipped, as in a per-row build.
+// progress, if not nil, is called after each row with the rows done so far.
+// It returns how many rows were stored.
+func (dao *DAO) BulkInsert(data BulkData, progress func(done, total int)) (int, error) {
+	total := data.Len()
+	if total == 0 {
+		return 0, nil
+	}
+
+	var tx *sql.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+
+	done, stored, pending := 0, 0, 0
+	insert := func(kind, name string, fn func(*sql.Tx) error) error {
+		if tx == nil {
+			var err error
+			if tx, err = dao.db.BeginTx(); err != nil {
+				return err
+			}
+		}
+		if err := fn(tx); err != nil {
+			log.Warn().Err(err).Str(kind, name).Msgf("Failed to insert %s", kind)
+		} else {
+			stored++
+		}
+		done++
+		pending++
+
+		if pending == BulkInsertBatchSize || done == total {
+			err := tx.Commit()
+			tx = nil
+			if err != nil {
+				return fmt.Errorf("failed to commit batch: %w", err)
+			}
+			pending = 0
+		}
+		if progress != nil {
+			progress(done, total)
+		}
+		return nil
+	}
+
+	for _, album := range data.Albums {
+		if err := insert("album", album.Title, func(tx *sql.Tx) error { return dao.InsertAlbumTx(tx, album) }); err != nil {
+			return stored, err
+		}
+	}
+	for _, artist := range data.Artists {
+		if err := insert("artist", artist.Name, func(tx *sql.Tx) error { return dao.InsertArtistTx(tx, artist) }); err != nil {
+			return stored, err
+		}
+	}
+	for _, track := range data.Tracks {
+		if err := insert("track", track.Title, func(tx *sql.Tx) error { return dao.InsertTrackTx(tx, track) }); err != nil {
+			return stored, err
+		}
+	}
+	return stored, nil
+}
@@ -0,0 +1,129 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
+)
+
+func bulkAlbums(n int) []*cache.CachedAlbum {
+	albums := make([]*cache.CachedAlbum, n)
+	for i := range albums {
+		albums[i] = &cache.CachedAlbum{
+			ID:          fmt.Sprintf("album-%d", i),
+			Title:       fmt.Sprintf("Album %d", i),
+			AlbumArtist: fmt.Sprintf("Artist %d", i%50),
+			URI:         fmt.Sprintf("NAS/Music/Album %d", i),
+			FirstTrack:  fmt.Sprintf("NAS/Music/Album %d/01.flac", i),
+			Source:      "nas",
+		}
+	}
+	return albums
+}
+
+func TestDAOBulkInsert(t *testing.T) {
+	db := openTestDB(t)
+	dao := cache.NewDAO(db)
+
+	n := cache.BulkInsertBatchSize + 500
+	data := cache.BulkData{
+		Albums:  bulkAlbums(n),
+		Artists: []*cache.CachedArtist{{ID: "artist-1", Name: "Miles Davis", AlbumCount: 1}},
+		Tracks: []*cache.CachedTrack{
+			{ID: "track-1", AlbumID: "album-0", Title: "So What", URI: "NAS/Music/Album 0/01.flac", Source: "nas"},
+		},
+	}
+
+	var calls, lastDone, lastTotal int
+	stored, err := dao.BulkInsert(data, func(done, total int) {
+		calls++
+		lastDone, lastTotal = done, total
+	})
+	if err != nil {
+		t.Fatalf("BulkInsert: %v", err)
+	}
+	if want := n + 2; stored != want {
+		t.Errorf("stored %d rows, want %d", stored, want)
+	}
+	if calls != n+2 || lastDone != n+2 || lastTotal != n+2 {
+		t.Errorf("progress called %d times ending at %d/%d, want %d calls ending at %d/%d",
+			calls, lastDone, lastTotal, n+2, n+2, n+2)
+	}
+
+	stats, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.AlbumCount != n || stats.ArtistCount != 1 || stats.TrackCount != 1 {
+		t.Errorf("cached %d albums, %d artists, %d tracks; want %d, 1, 1",
+			stats.AlbumCount, stats.ArtistCount, stats.TrackCount, n)
+	}
+
+	// Re-inserting updates in place rather than duplicating
+	if _, err := dao.BulkInsert(cache.BulkData{Albums: bulkAlbums(10)}, nil); err != nil {
+		t.Fatalf("second BulkInsert: %v", err)
+	}
+	if stats, _ := db.GetStats(); stats.AlbumCount != n {
+		t.Errorf("after re-insert cached %d albums, want %d", stats.AlbumCount, n)
+	}
+}
+
+func TestDAOBulkInsertEmpty(t *testing.T) {
+	dao := cache.NewDAO(openTestDB(t))
+
+	stored, err := dao.BulkInsert(cache.BulkData{}, func(done, total int) {
+		t.Error("progress called for empty data")
+	})
+	if err != nil || stored != 0 {
+		t.Errorf("BulkInsert(empty) = %d, %v; want 0, nil", stored, err)
+	}
+}
+
+// The two benchmarks below compare one transaction per row against
+// BulkInsert. Run with: go test -bench Insert ./internal/infra/cache/
+const benchAlbumCount = 1000
+
+func BenchmarkInsertAlbumsIndividually(b *testing.B) {
+	albums := bulkAlbums(benchAlbumCount)
+	for b.Loop() {
+		b.StopTimer()
+		db := cache.NewDB(b.TempDir() + "/bench.db")
+		if err := db.Open(); err != nil {
+			b.Fatal(err)
+		}
+		dao := cache.NewDAO(db)
+		b.StartTimer()
+
+		for _, album := range albums {
+			if err := dao.InsertAlbum(album); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkBulkInsertAlbums(b *testing.B) {
+	albums := bulkAlbums(benchAlbumCount)
+	for b.Loop() {
+		b.StopTimer()
+		db := cache.NewDB(b.TempDir() + "/bench.db")
+		if err := db.Open(); err != nil {
+			b.Fatal(err)
+		}
+		dao := cache.NewDAO(db)
+		b.StartTimer()
+
+		if _, err := dao.BulkInsert(cache.BulkData{Albums: albums}, nil); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+}
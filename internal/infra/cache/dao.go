@@ -32,7 +32,7 @@ func (dao *DAO) InsertAlbum(album *CachedAlbum) error {
 	now := time.Now().Format(time.RFC3339)
 	addedAt := ""
 	if !album.AddedAt.IsZero() {
-		addedAt = album.AddedAt.Format(time.RFC3339)
+		addedAt = album.AddedAt.UTC().Format(time.RFC3339)
 	}
 	lastPlayed := ""
 	if !album.LastPlayed.IsZero() {
@@ -63,7 +63,7 @@ func (dao *DAO) InsertAlbumTx(tx *sql.Tx, album *CachedAlbum) error {
 	now := time.Now().Format(time.RFC3339)
 	addedAt := ""
 	if !album.AddedAt.IsZero() {
-		addedAt = album.AddedAt.Format(time.RFC3339)
+		addedAt = album.AddedAt.UTC().Format(time.RFC3339)
 	}
 
 	_, err := tx.Exec(`
@@ -137,6 +137,20 @@ func (dao *DAO) GetAlbum(id string) (*CachedAlbum, error) {
 	return album, nil
 }
 
+// scopeCondition returns the WHERE condition on the source column for a
+// browse scope, or "" for 'all' and unknown scopes. 'local' includes USB.
+func scopeCondition(scope string) string {
+	switch scope {
+	case "nas":
+		return "source = 'nas'"
+	case "local":
+		return "(source = 'local' OR source = 'usb')"
+	case "usb":
+		return "source = 'usb'"
+	}
+	return ""
+}
+
 // QueryAlbums queries albums with filters, sorting, and pagination.
 func (dao *DAO) QueryAlbums(filter AlbumFilter, sort SortOrder, pag Pagination) ([]*CachedAlbum, int, error) {
 	db := dao.db.DB()
@@ -148,15 +162,8 @@ func (dao *DAO) QueryAlbums(filter AlbumFilter, sort SortOrder, pag Pagination)
 	var conditions []string
 	var args []interface{}
 
-	if filter.Scope != "" && filter.Scope != "all" {
-		switch filter.Scope {
-		case "nas":
-			conditions = append(conditions, "source = 'nas'")
-		case "local":
-			conditions = append(conditions, "(source = 'local' OR source = 'usb')")
-		case "usb":
-			conditions = append(conditions, "source = 'usb'")
-		}
+	if cond := scopeCondition(filter.Scope); cond != "" {
+		conditions = append(conditions, cond)
 	}
 
 	if filter.Query != "" {
@@ -247,6 +254,137 @@ func (dao *DAO) QueryAlbums(filter AlbumFilter, sort SortOrder, pag Pagination)
 	return albums, total, nil
 }
 
+// FindAlbumsByYear returns albums released in the given year.
+func (dao *DAO) FindAlbumsByYear(year int) ([]*CachedAlbum, error) {
+	return dao.findAlbumsByYearRange(year, year+1)
+}
+
+// FindAlbumsByDecade returns albums released in the decade starting at the given
+// year (e.g. 1970 for the 1970s).
+func (dao *DAO) FindAlbumsByDecade(decade int) ([]*CachedAlbum, error) {
+	decade -= decade % 10
+	return dao.findAlbumsByYearRange(decade, decade+10)
+}
+
+// findAlbumsByYearRange returns albums with from <= year < to, oldest first.
+func (dao *DAO) findAlbumsByYearRange(from, to int) ([]*CachedAlbum, error) {
+	db := dao.db.DB()
+	if db == nil {
+		return nil, fmt.Errorf("database not open")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, title, album_artist, uri, first_track, track_count, total_duration, source,
+			year, added_at, last_played, artwork_id, created_at, updated_at
+		FROM albums WHERE year >= ? AND year < ?
+		ORDER BY year, album_artist COLLATE NOCASE, title COLLATE NOCASE
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var albums []*CachedAlbum
+	for rows.Next() {
+		album := &CachedAlbum{}
+		var addedAt, lastPlayed, createdAt, updatedAt sql.NullString
+		var year sql.NullInt64
+		var artworkID, firstTrack sql.NullString
+
+		err := rows.Scan(
+			&album.ID, &album.Title, &album.AlbumArtist, &album.URI, &firstTrack, &album.TrackCount,
+			&album.TotalDuration, &album.Source, &year, &addedAt, &lastPlayed,
+			&artworkID, &createdAt, &updatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if year.Valid {
+			album.Year = int(year.Int64)
+		}
+		if firstTrack.Valid {
+			album.FirstTrack = firstTrack.String
+		}
+		if addedAt.Valid {
+			album.AddedAt, _ = time.Parse(time.RFC3339, addedAt.String)
+		}
+		if lastPlayed.Valid {
+			album.LastPlayed, _ = time.Parse(time.RFC3339, lastPlayed.String)
+		}
+		if artworkID.Valid {
+			album.ArtworkID = artworkID.String
+		}
+
+		albums = append(albums, album)
+	}
+
+	return albums, rows.Err()
+}
+
+// ListDecades returns the decades that have albums, with album counts, oldest first.
+// Albums without a year are not counted.
+func (dao *DAO) ListDecades() ([]DecadeCount, error) {
+	db := dao.db.DB()
+	if db == nil {
+		return nil, fmt.Errorf("database not open")
+	}
+
+	rows, err := db.Query(`
+		SELECT (year / 10) * 10 AS decade, COUNT(*)
+		FROM albums WHERE year > 0
+		GROUP BY decade ORDER BY decade
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decades []DecadeCount
+	for rows.Next() {
+		var dc DecadeCount
+		if err := rows.Scan(&dc.Decade, &dc.Count); err != nil {
+			return nil, err
+		}
+		decades = append(decades, dc)
+	}
+
+	return decades, rows.Err()
+}
+
+// ListSourceCounts returns the number of cached albums and tracks from each
+// source that has any, ordered by source.
+func (dao *DAO) ListSourceCounts() ([]SourceCount, error) {
+	db := dao.db.DB()
+	if db == nil {
+		return nil, fmt.Errorf("database not open")
+	}
+
+	rows, err := db.Query(`
+		SELECT source, COUNT(*), COALESCE(SUM(track_count), 0), MAX(created_at)
+		FROM albums GROUP BY source ORDER BY source
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []SourceCount
+	for rows.Next() {
+		var sc SourceCount
+		var createdAt sql.NullString
+		if err := rows.Scan(&sc.Source, &sc.AlbumCount, &sc.TrackCount, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.Valid {
+			sc.LastScanned, _ = time.Parse(time.RFC3339, createdAt.String)
+		}
+		sources = append(sources, sc)
+	}
+
+	return sources, rows.Err()
+}
+
 // --- Artist Operations ---
 
 // InsertArtist inserts or updates an artist in the cache.
@@ -358,8 +496,74 @@ func (dao *DAO) QueryArtists(query string, pag Pagination) ([]*CachedArtist, int
 	return artists, total, nil
 }
 
+// ListArtistIndex returns the leading letters of artist names matching
+// query, with how many artists start with each and where the first one is
+// in the list QueryArtists returns. Names starting with anything but an
+// ASCII letter are grouped under "#". Letters are returned in order, "#"
+// first.
+func (dao *DAO) ListArtistIndex(query string) ([]ArtistLetterCount, error) {
+	db := dao.db.DB()
+	if db == nil {
+		return nil, fmt.Errorf("database not open")
+	}
+
+	whereClause := ""
+	var args []interface{}
+	if query != "" {
+		whereClause = "WHERE name LIKE ? COLLATE NOCASE"
+		args = append(args, "%"+query+"%")
+	}
+
+	// Offsets come from the same ordering as QueryArtists
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT letter, COUNT(*), MIN(pos) FROM (
+			SELECT CASE
+					WHEN upper(substr(name, 1, 1)) BETWEEN 'A' AND 'Z' THEN upper(substr(name, 1, 1))
+					ELSE '#'
+				END AS letter,
+				ROW_NUMBER() OVER (ORDER BY name COLLATE NOCASE) - 1 AS pos
+			FROM artists %s
+		)
+		GROUP BY letter ORDER BY letter
+	`, whereClause), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []ArtistLetterCount
+	for rows.Next() {
+		var lc ArtistLetterCount
+		if err := rows.Scan(&lc.Letter, &lc.Count, &lc.Offset); err != nil {
+			return nil, err
+		}
+		letters = append(letters, lc)
+	}
+
+	return letters, rows.Err()
+}
+
 // --- Track Operations ---
 
+// upsertTrackSQL inserts a track or updates the existing row for its URI.
+// The URI is the track's identity across rebuilds (IDs are derived from it),
+// so both InsertTrack and InsertTrackTx conflict on the uri UNIQUE column.
+const upsertTrackSQL = `
+	INSERT INTO tracks (id, album_id, title, artist, uri, track_number, disc_number, duration, source, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(uri) DO UPDATE SET
+		album_id = ?, title = ?, artist = ?, track_number = ?, disc_number = ?, duration = ?, source = ?
+`
+
+// trackUpsertArgs returns the arguments for upsertTrackSQL.
+func trackUpsertArgs(track *CachedTrack, now string) []any {
+	return []any{
+		track.ID, track.AlbumID, track.Title, track.Artist, track.URI,
+		track.TrackNumber, track.DiscNumber, track.Duration, track.Source, now,
+		track.AlbumID, track.Title, track.Artist, track.TrackNumber, track.DiscNumber, track.Duration, track.Source,
+	}
+}
+
 // InsertTrack inserts a track in the cache.
 func (dao *DAO) InsertTrack(track *CachedTrack) error {
 	db := dao.db.DB()
@@ -369,16 +573,7 @@ func (dao *DAO) InsertTrack(track *CachedTrack) error {
 
 	now := time.Now().Format(time.RFC3339)
 
-	_, err := db.Exec(`
-		INSERT INTO tracks (id, album_id, title, artist, uri, track_number, disc_number, duration, source, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			album_id = ?, title = ?, artist = ?, track_number = ?, disc_number = ?, duration = ?, source = ?
-	`,
-		track.ID, track.AlbumID, track.Title, track.Artist, track.URI,
-		track.TrackNumber, track.DiscNumber, track.Duration, track.Source, now,
-		track.AlbumID, track.Title, track.Artist, track.TrackNumber, track.DiscNumber, track.Duration, track.Source,
-	)
+	_, err := db.Exec(upsertTrackSQL, trackUpsertArgs(track, now)...)
 	return err
 }
 
@@ -386,30 +581,34 @@ func (dao *DAO) InsertTrack(track *CachedTrack) error {
 func (dao *DAO) InsertTrackTx(tx *sql.Tx, track *CachedTrack) error {
 	now := time.Now().Format(time.RFC3339)
 
-	_, err := tx.Exec(`
-		INSERT INTO tracks (id, album_id, title, artist, uri, track_number, disc_number, duration, source, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(uri) DO UPDATE SET
-			album_id = ?, title = ?, artist = ?, track_number = ?, disc_number = ?, duration = ?, source = ?
-	`,
-		track.ID, track.AlbumID, track.Title, track.Artist, track.URI,
-		track.TrackNumber, track.DiscNumber, track.Duration, track.Source, now,
-		track.AlbumID, track.Title, track.Artist, track.TrackNumber, track.DiscNumber, track.Duration, track.Source,
-	)
+	_, err := tx.Exec(upsertTrackSQL, trackUpsertArgs(track, now)...)
 	return err
 }
 
 // GetTracksByAlbum retrieves all tracks for an album.
 func (dao *DAO) GetTracksByAlbum(albumID string) ([]*CachedTrack, error) {
+	return dao.QueryTracks(TrackFilter{AlbumID: albumID})
+}
+
+// QueryTracks retrieves an album's tracks, keeping only those from the
+// filter's scope. An album can span sources (a local copy of some tracks
+// and the rest on the NAS), so scoped browsing filters per track rather
+// than trusting the album's source.
+func (dao *DAO) QueryTracks(filter TrackFilter) ([]*CachedTrack, error) {
 	db := dao.db.DB()
 	if db == nil {
 		return nil, fmt.Errorf("database not open")
 	}
 
+	conditions := []string{"album_id = ?"}
+	if cond := scopeCondition(filter.Scope); cond != "" {
+		conditions = append(conditions, cond)
+	}
+
 	rows, err := db.Query(`
 		SELECT id, album_id, title, artist, uri, track_number, disc_number, duration, source, created_at
-		FROM tracks WHERE album_id = ? ORDER BY disc_number, track_number
-	`, albumID)
+		FROM tracks WHERE `+strings.Join(conditions, " AND ")+` ORDER BY disc_number, track_number
+	`, filter.AlbumID)
 	if err != nil {
 		return nil, err
 	}
@@ -3,14 +3,19 @@ package cache
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/mattn/go-sqlite3" // SQLite driver
 	"github.com/rs/zerolog/log"
-	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
 const (
@@ -19,27 +24,103 @@ const (
 
 	// DefaultDBPath is the default path for the cache database.
 	DefaultDBPath = "data/library.db"
+
+	// maxCorruptFiles is how many corrupt databases set aside are kept; the
+	// oldest are deleted beyond it.
+	maxCorruptFiles = 3
 )
 
+// Options tunes how the cache database is opened.
+type Options struct {
+	// JournalMode is the SQLite journal mode. WAL lets browse queries read
+	// while a rebuild writes instead of failing with "database is locked".
+	JournalMode string
+	// BusyTimeout is how long a connection waits for a lock before failing.
+	BusyTimeout time.Duration
+	// Synchronous is the SQLite synchronous level. NORMAL is safe with WAL
+	// (a power cut can lose the last commits, never corrupt the file) and
+	// saves an fsync per commit on slow SD cards.
+	Synchronous string
+	// MaxOpenConns bounds the connection pool. With WAL, readers on extra
+	// connections proceed alongside the one writer.
+	MaxOpenConns int
+}
+
+// DefaultOptions suits a Pi serving browse requests during rebuilds.
+var DefaultOptions = Options{
+	JournalMode:  "WAL",
+	BusyTimeout:  5 * time.Second,
+	Synchronous:  "NORMAL",
+	MaxOpenConns: 4,
+}
+
+// OptionsFromEnv returns DefaultOptions overridden by STELLAR_CACHE_JOURNAL_MODE,
+// STELLAR_CACHE_BUSY_TIMEOUT (a duration such as "10s"),
+// STELLAR_CACHE_SYNCHRONOUS and STELLAR_CACHE_MAX_CONNS. Invalid values are
+// logged and ignored.
+func OptionsFromEnv() Options {
+	opts := DefaultOptions
+	if v := os.Getenv("STELLAR_CACHE_JOURNAL_MODE"); v != "" {
+		opts.JournalMode = v
+	}
+	if v := os.Getenv("STELLAR_CACHE_BUSY_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			opts.BusyTimeout = d
+		} else {
+			log.Warn().Str("value", v).Msg("Ignoring invalid STELLAR_CACHE_BUSY_TIMEOUT")
+		}
+	}
+	if v := os.Getenv("STELLAR_CACHE_SYNCHRONOUS"); v != "" {
+		opts.Synchronous = v
+	}
+	if v := os.Getenv("STELLAR_CACHE_MAX_CONNS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			opts.MaxOpenConns = n
+		} else {
+			log.Warn().Str("value", v).Msg("Ignoring invalid STELLAR_CACHE_MAX_CONNS")
+		}
+	}
+	return opts
+}
+
+// dsn returns the connection string for path. The pragmas go in the DSN so
+// the driver applies them to every pooled connection, not just the first.
+func (o Options) dsn(path string) string {
+	params := url.Values{}
+	params.Set("_journal_mode", o.JournalMode)
+	params.Set("_busy_timeout", strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10))
+	params.Set("_synchronous", o.Synchronous)
+	return path + "?" + params.Encode()
+}
+
 // DB represents the SQLite cache database.
 type DB struct {
 	mu       sync.RWMutex
 	db       *sql.DB
 	path     string
+	opts     Options
 	isBuilding bool
 	buildProgress int
+	recovered     bool // Open replaced a corrupt database file
 }
 
-// NewDB creates a new cache database instance.
+// NewDB creates a new cache database instance using DefaultOptions.
 func NewDB(path string) *DB {
 	if path == "" {
 		path = DefaultDBPath
 	}
 	return &DB{
 		path: path,
+		opts: DefaultOptions,
 	}
 }
 
+// WithOptions sets the options used by Open.
+func (d *DB) WithOptions(opts Options) *DB {
+	d.opts = opts
+	return d
+}
+
 // Open opens the database and initializes the schema.
 func (d *DB) Open() error {
 	d.mu.Lock()
@@ -51,16 +132,33 @@ func (d *DB) Open() error {
 		return fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	// Open database
-	db, err := sql.Open("sqlite3", d.path+"?_journal=WAL&_busy_timeout=5000")
+	db, err := openSQLite(d.path, d.opts)
 	if err != nil {
-		return fmt.Errorf("failed to open cache database: %w", err)
+		return err
 	}
 
-	// Set connection pool settings
-	db.SetMaxOpenConns(1) // SQLite only supports one writer
-	db.SetMaxIdleConns(1)
-	db.SetConnMaxLifetime(time.Hour)
+	// A power cut mid-write can leave the file corrupt. The cache can always
+	// be rebuilt from MPD, so set the bad file aside and start fresh rather
+	// than failing every query. Other failures, such as a locked or
+	// unreadable file, leave it alone.
+	if err := checkIntegrity(db); err != nil {
+		db.Close()
+		if !errors.Is(err, errCorrupt) {
+			return fmt.Errorf("failed to check cache database: %w", err)
+		}
+		aside, moveErr := moveAside(d.path)
+		if moveErr != nil {
+			return fmt.Errorf("cache database is corrupt (%v) and could not be moved aside: %w", err, moveErr)
+		}
+		log.Error().Err(err).Str("path", d.path).Str("movedTo", aside).
+			Msg("Cache database failed integrity check, recreating it")
+		pruneCorrupt(d.path, maxCorruptFiles)
+
+		if db, err = openSQLite(d.path, d.opts); err != nil {
+			return err
+		}
+		d.recovered = true
+	}
 
 	d.db = db
 
@@ -74,6 +172,114 @@ func (d *DB) Open() error {
 	return nil
 }
 
+// Recovered reports whether Open found the database corrupt and replaced it
+// with an empty one that needs a rebuild.
+func (d *DB) Recovered() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.recovered
+}
+
+// openSQLite opens the database file with the given connection settings.
+func openSQLite(path string, opts Options) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", opts.dsn(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database: %w", err)
+	}
+
+	// Set connection pool settings; writers still take turns via busy_timeout
+	conns := max(opts.MaxOpenConns, 1)
+	db.SetMaxOpenConns(conns)
+	db.SetMaxIdleConns(conns)
+	db.SetConnMaxLifetime(time.Hour)
+	return db, nil
+}
+
+// errCorrupt marks checkIntegrity failures that mean the file is damaged.
+var errCorrupt = errors.New("cache database is corrupt")
+
+// checkIntegrity runs PRAGMA quick_check, which skips the slow index
+// cross-checks of integrity_check. Damage it reports, or a file SQLite
+// rejects as corrupt or not a database at all, is wrapped in errCorrupt.
+func checkIntegrity(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA quick_check")
+	if err != nil {
+		return sqliteCorrupt(err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return sqliteCorrupt(err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return sqliteCorrupt(err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", errCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// sqliteCorrupt wraps err in errCorrupt if SQLite failed with
+// SQLITE_CORRUPT or SQLITE_NOTADB.
+func sqliteCorrupt(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrCorrupt || sqliteErr.Code == sqlite3.ErrNotADB) {
+		return fmt.Errorf("%w: %v", errCorrupt, err)
+	}
+	return err
+}
+
+// moveAside renames a database and its WAL and shared-memory files to a
+// timestamped .corrupt name, returning the new database path.
+func moveAside(path string) (string, error) {
+	aside := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(path, aside); err != nil {
+		return "", err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Rename(path+suffix, aside+suffix); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return aside, nil
+}
+
+// pruneCorrupt deletes all but the newest keep databases set aside by
+// moveAside for path, along with their WAL and shared-memory files.
+func pruneCorrupt(path string, keep int) {
+	matches, err := filepath.Glob(path + ".corrupt-*")
+	if err != nil {
+		return
+	}
+	var asides []string
+	for _, match := range matches {
+		if !strings.HasSuffix(match, "-wal") && !strings.HasSuffix(match, "-shm") {
+			asides = append(asides, match)
+		}
+	}
+	if len(asides) <= keep {
+		return
+	}
+
+	// Timestamps sort in name order, oldest first
+	sort.Strings(asides)
+	for _, aside := range asides[:len(asides)-keep] {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if err := os.Remove(aside + suffix); err != nil && !os.IsNotExist(err) {
+				log.Warn().Err(err).Str("path", aside+suffix).Msg("Failed to delete old corrupt cache database")
+			}
+		}
+	}
+}
+
 // Close closes the database connection.
 func (d *DB) Close() error {
 	d.mu.Lock()
@@ -1,8 +1,12 @@
 package cache_test
 
 import (
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -322,6 +326,122 @@ func TestDAOInsertAndGetTracks(t *testing.T) {
 	}
 }
 
+func TestDAOInsertTrackUpsertsByURI(t *testing.T) {
+	insertDirect := func(dao *cache.DAO, db *cache.DB, track *cache.CachedTrack) error {
+		return dao.InsertTrack(track)
+	}
+	insertTx := func(dao *cache.DAO, db *cache.DB, track *cache.CachedTrack) error {
+		tx, err := db.BeginTx()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := dao.InsertTrackTx(tx, track); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	tests := []struct {
+		name          string
+		first, second func(*cache.DAO, *cache.DB, *cache.CachedTrack) error
+	}{
+		{"InsertTrack twice", insertDirect, insertDirect},
+		{"InsertTrackTx twice", insertTx, insertTx},
+		{"InsertTrack then InsertTrackTx", insertDirect, insertTx},
+		{"InsertTrackTx then InsertTrack", insertTx, insertDirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			dao := cache.NewDAO(db)
+			if err := dao.InsertAlbum(&cache.CachedAlbum{ID: "album1", Title: "Album", AlbumArtist: "Artist", URI: "NAS/Album", Source: "nas"}); err != nil {
+				t.Fatalf("Failed to insert album: %v", err)
+			}
+
+			track := &cache.CachedTrack{
+				ID:          "track1",
+				AlbumID:     "album1",
+				Title:       "Old Title",
+				Artist:      "Artist",
+				URI:         "NAS/Album/01.flac",
+				TrackNumber: 1,
+				DiscNumber:  1,
+				Source:      "nas",
+			}
+			if err := tt.first(dao, db, track); err != nil {
+				t.Fatalf("first insert: %v", err)
+			}
+
+			updated := *track
+			updated.Title = "New Title"
+			updated.Duration = 240
+			if err := tt.second(dao, db, &updated); err != nil {
+				t.Fatalf("second insert: %v", err)
+			}
+
+			tracks, err := dao.GetTracksByAlbum("album1")
+			if err != nil {
+				t.Fatalf("Failed to get tracks: %v", err)
+			}
+			if len(tracks) != 1 {
+				t.Fatalf("Expected 1 track after upsert, got %d", len(tracks))
+			}
+			if tracks[0].Title != "New Title" || tracks[0].Duration != 240 {
+				t.Errorf("Expected updated track, got title %q duration %d", tracks[0].Title, tracks[0].Duration)
+			}
+		})
+	}
+}
+
+func TestDAOQueryTracksByScope(t *testing.T) {
+	db := openTestDB(t)
+	dao := cache.NewDAO(db)
+
+	if err := dao.InsertAlbum(&cache.CachedAlbum{ID: "album1", Title: "Album", AlbumArtist: "Artist", URI: "INTERNAL/Album", Source: "local"}); err != nil {
+		t.Fatalf("Failed to insert album: %v", err)
+	}
+
+	// One album with tracks copied locally, on USB and left on the NAS
+	tracks := []*cache.CachedTrack{
+		{ID: "t1", AlbumID: "album1", Title: "One", Artist: "Artist", URI: "INTERNAL/Album/01.flac", TrackNumber: 1, Source: "local"},
+		{ID: "t2", AlbumID: "album1", Title: "Two", Artist: "Artist", URI: "NAS/Album/02.flac", TrackNumber: 2, Source: "nas"},
+		{ID: "t3", AlbumID: "album1", Title: "Three", Artist: "Artist", URI: "USB/Album/03.flac", TrackNumber: 3, Source: "usb"},
+		{ID: "t4", AlbumID: "album1", Title: "Four", Artist: "Artist", URI: "NAS/Album/04.flac", TrackNumber: 4, Source: "nas"},
+	}
+	for _, track := range tracks {
+		if err := dao.InsertTrack(track); err != nil {
+			t.Fatalf("Failed to insert %s: %v", track.Title, err)
+		}
+	}
+
+	tests := []struct {
+		scope string
+		want  []string
+	}{
+		{"", []string{"One", "Two", "Three", "Four"}},
+		{"all", []string{"One", "Two", "Three", "Four"}},
+		{"nas", []string{"Two", "Four"}},
+		{"local", []string{"One", "Three"}},
+		{"usb", []string{"Three"}},
+	}
+
+	for _, tt := range tests {
+		got, err := dao.QueryTracks(cache.TrackFilter{AlbumID: "album1", Scope: tt.scope})
+		if err != nil {
+			t.Fatalf("QueryTracks(%q): %v", tt.scope, err)
+		}
+		var titles []string
+		for _, track := range got {
+			titles = append(titles, track.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("QueryTracks(%q) = %v, want %v", tt.scope, titles, tt.want)
+		}
+	}
+}
+
 func TestDBClear(t *testing.T) {
 	// Create temp directory
 	tmpDir, err := os.MkdirTemp("", "cache_test")
@@ -370,6 +490,232 @@ func TestDBClear(t *testing.T) {
 	}
 }
 
+func TestDBOpenRecoversCorruptFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	// Garbage where the database should be, as left by a torn write
+	garbage := make([]byte, 8192)
+	for i := range garbage {
+		garbage[i] = byte(i * 31)
+	}
+	if err := os.WriteFile(dbPath, garbage, 0644); err != nil {
+		t.Fatalf("Failed to write corrupt file: %v", err)
+	}
+
+	db := cache.NewDB(dbPath)
+	if err := db.Open(); err != nil {
+		t.Fatalf("Open should recover from a corrupt file: %v", err)
+	}
+	defer db.Close()
+
+	if !db.Recovered() {
+		t.Error("Recovered() = false after replacing a corrupt file")
+	}
+	stats, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("Failed to get stats from recreated database: %v", err)
+	}
+	if stats.AlbumCount != 0 || stats.SchemaVersion != cache.CurrentSchemaVersion {
+		t.Errorf("recreated database: %d albums, schema %q", stats.AlbumCount, stats.SchemaVersion)
+	}
+
+	aside, _ := filepath.Glob(dbPath + ".corrupt-*")
+	if len(aside) != 1 {
+		t.Fatalf("expected the corrupt file moved aside, found %v", aside)
+	}
+	if data, _ := os.ReadFile(aside[0]); len(data) != len(garbage) {
+		t.Errorf("moved-aside file has %d bytes, want %d", len(data), len(garbage))
+	}
+}
+
+func TestDBOpenKeepsNewestCorruptFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	// Files set aside by earlier recoveries
+	for _, stamp := range []string{"20240101-000000", "20240102-000000", "20240103-000000"} {
+		os.WriteFile(dbPath+".corrupt-"+stamp, []byte("old"), 0644)
+	}
+	os.WriteFile(dbPath+".corrupt-20240101-000000-wal", []byte("old"), 0644)
+	os.WriteFile(dbPath, []byte(strings.Repeat("not a database ", 512)), 0644)
+
+	db := cache.NewDB(dbPath)
+	if err := db.Open(); err != nil {
+		t.Fatalf("Open should recover from a corrupt file: %v", err)
+	}
+	defer db.Close()
+
+	aside, _ := filepath.Glob(dbPath + ".corrupt-*")
+	if len(aside) != 3 {
+		t.Fatalf("kept %v, want the 3 newest", aside)
+	}
+	for _, path := range aside {
+		if strings.Contains(path, "20240101") {
+			t.Errorf("oldest corrupt file %s was kept", path)
+		}
+	}
+}
+
+func TestDBOpenLeavesLockedFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	// Another process holding an exclusive lock is not corruption
+	locker, err := sql.Open("sqlite3", dbPath+"?_journal_mode=DELETE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer locker.Close()
+	conn, err := locker.Conn(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(t.Context(), "CREATE TABLE t (x); BEGIN EXCLUSIVE; INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := cache.DefaultOptions
+	opts.JournalMode = "DELETE"
+	opts.BusyTimeout = 50 * time.Millisecond
+	db := cache.NewDB(dbPath).WithOptions(opts)
+	if err := db.Open(); err == nil {
+		db.Close()
+		t.Fatal("Open of a locked database succeeded, want an error")
+	}
+	if db.Recovered() {
+		t.Error("Recovered() = true for a locked database")
+	}
+	if aside, _ := filepath.Glob(dbPath + ".corrupt-*"); len(aside) != 0 {
+		t.Errorf("locked database was moved aside to %v", aside)
+	}
+}
+
+func TestDBOpenHealthyFileNotRecovered(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db := cache.NewDB(dbPath)
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.Close()
+
+	// Reopening an intact database keeps it
+	db = cache.NewDB(dbPath)
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	if db.Recovered() {
+		t.Error("Recovered() = true for an intact database")
+	}
+}
+
+func TestDBOpenAppliesOptions(t *testing.T) {
+	db := cache.NewDB(filepath.Join(t.TempDir(), "test.db")).WithOptions(cache.Options{
+		JournalMode:  "WAL",
+		BusyTimeout:  1500 * time.Millisecond,
+		Synchronous:  "NORMAL",
+		MaxOpenConns: 2,
+	})
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var journal string
+	var busy, sync int
+	if err := db.DB().QueryRow("PRAGMA journal_mode").Scan(&journal); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB().QueryRow("PRAGMA busy_timeout").Scan(&busy); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB().QueryRow("PRAGMA synchronous").Scan(&sync); err != nil {
+		t.Fatal(err)
+	}
+	if journal != "wal" {
+		t.Errorf("journal_mode = %q, want wal", journal)
+	}
+	if busy != 1500 {
+		t.Errorf("busy_timeout = %d, want 1500", busy)
+	}
+	if sync != 1 { // NORMAL
+		t.Errorf("synchronous = %d, want 1 (NORMAL)", sync)
+	}
+}
+
+func TestDBConcurrentReadsDuringBulkInsert(t *testing.T) {
+	db := cache.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	dao := cache.NewDAO(db)
+
+	stop := make(chan struct{})
+	errs := make(chan error, 100)
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, _, err := dao.QueryAlbums(cache.AlbumFilter{Scope: "all"}, cache.SortAlphabetical, cache.NewPagination(1, 50)); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := db.GetStats(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	// Bulk insert in one transaction, as a rebuild does
+	tx, err := db.BeginTx()
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	for i := range 2000 {
+		album := &cache.CachedAlbum{
+			ID:          fmt.Sprintf("album%d", i),
+			Title:       fmt.Sprintf("Album %d", i),
+			AlbumArtist: "Artist",
+			URI:         fmt.Sprintf("NAS/Artist/Album %d", i),
+			Source:      "nas",
+		}
+		if err := dao.InsertAlbumTx(tx, album); err != nil {
+			tx.Rollback()
+			t.Fatalf("InsertAlbumTx: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	close(stop)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("read during bulk insert failed: %v", err)
+	}
+
+	stats, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.AlbumCount != 2000 {
+		t.Errorf("AlbumCount = %d, want 2000", stats.AlbumCount)
+	}
+}
+
 func TestPagination(t *testing.T) {
 	pag := cache.NewPagination(1, 50)
 	if pag.Page != 1 {
@@ -400,3 +746,161 @@ func TestPagination(t *testing.T) {
 		t.Errorf("Expected max limit 200, got %d", pag.Limit)
 	}
 }
+
+func TestDAOFindAlbumsByYearAndDecade(t *testing.T) {
+	db := cache.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	dao := cache.NewDAO(db)
+
+	albums := []*cache.CachedAlbum{
+		{ID: "a1", Title: "Rumours", AlbumArtist: "Fleetwood Mac", Source: "local", Year: 1977},
+		{ID: "a2", Title: "Aja", AlbumArtist: "Steely Dan", Source: "local", Year: 1977},
+		{ID: "a3", Title: "Horses", AlbumArtist: "Patti Smith", Source: "nas", Year: 1975},
+		{ID: "a4", Title: "Nevermind", AlbumArtist: "Nirvana", Source: "local", Year: 1991},
+		{ID: "a5", Title: "Untagged", AlbumArtist: "Unknown", Source: "local"},
+	}
+	for _, album := range albums {
+		if err := dao.InsertAlbum(album); err != nil {
+			t.Fatalf("Failed to insert %s: %v", album.ID, err)
+		}
+	}
+
+	byYear, err := dao.FindAlbumsByYear(1977)
+	if err != nil {
+		t.Fatalf("FindAlbumsByYear failed: %v", err)
+	}
+	if len(byYear) != 2 {
+		t.Fatalf("Expected 2 albums from 1977, got %d", len(byYear))
+	}
+	if byYear[0].Title != "Rumours" || byYear[1].Title != "Aja" {
+		t.Errorf("Expected [Rumours Aja] ordered by artist, got [%s %s]", byYear[0].Title, byYear[1].Title)
+	}
+
+	byDecade, err := dao.FindAlbumsByDecade(1970)
+	if err != nil {
+		t.Fatalf("FindAlbumsByDecade failed: %v", err)
+	}
+	if len(byDecade) != 3 {
+		t.Fatalf("Expected 3 albums from the 1970s, got %d", len(byDecade))
+	}
+	if byDecade[0].Title != "Horses" {
+		t.Errorf("Expected oldest album 'Horses' first, got '%s'", byDecade[0].Title)
+	}
+
+	// A year inside the decade is rounded down to the decade
+	if rounded, _ := dao.FindAlbumsByDecade(1974); len(rounded) != 3 {
+		t.Errorf("Expected FindAlbumsByDecade(1974) to return 3 albums, got %d", len(rounded))
+	}
+
+	decades, err := dao.ListDecades()
+	if err != nil {
+		t.Fatalf("ListDecades failed: %v", err)
+	}
+	want := []cache.DecadeCount{{Decade: 1970, Count: 3}, {Decade: 1990, Count: 1}}
+	if len(decades) != len(want) {
+		t.Fatalf("Expected %d decades, got %v", len(want), decades)
+	}
+	for i := range want {
+		if decades[i] != want[i] {
+			t.Errorf("decades[%d] = %+v, want %+v", i, decades[i], want[i])
+		}
+	}
+}
+
+func TestDAOListArtistIndex(t *testing.T) {
+	db := cache.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	dao := cache.NewDAO(db)
+
+	for i, name := range []string{"beck", "Abba", "10cc", "Björk", "ACDC", "!!!", "Blur", "Zappa"} {
+		if err := dao.InsertArtist(&cache.CachedArtist{ID: fmt.Sprintf("artist%d", i), Name: name}); err != nil {
+			t.Fatalf("Failed to insert %s: %v", name, err)
+		}
+	}
+
+	letters, err := dao.ListArtistIndex("")
+	if err != nil {
+		t.Fatalf("ListArtistIndex failed: %v", err)
+	}
+	// List order: !!!, 10cc, Abba, ACDC, beck, Björk, Blur, Zappa
+	want := []cache.ArtistLetterCount{
+		{Letter: "#", Count: 2, Offset: 0},
+		{Letter: "A", Count: 2, Offset: 2},
+		{Letter: "B", Count: 3, Offset: 4},
+		{Letter: "Z", Count: 1, Offset: 7},
+	}
+	if len(letters) != len(want) {
+		t.Fatalf("Expected %d letters, got %+v", len(want), letters)
+	}
+	for i := range want {
+		if letters[i] != want[i] {
+			t.Errorf("letters[%d] = %+v, want %+v", i, letters[i], want[i])
+		}
+	}
+
+	// Offsets match the first page of QueryArtists for the letter
+	artists, _, err := dao.QueryArtists("", cache.Pagination{Page: 1, Limit: 1, Offset: want[2].Offset})
+	if err != nil {
+		t.Fatalf("QueryArtists failed: %v", err)
+	}
+	if len(artists) != 1 || artists[0].Name != "beck" {
+		t.Errorf("Artist at B offset = %+v, want beck", artists)
+	}
+
+	filtered, err := dao.ListArtistIndex("b")
+	if err != nil {
+		t.Fatalf("ListArtistIndex(\"b\") failed: %v", err)
+	}
+	// Matches: Abba, beck, Björk, Blur
+	wantFiltered := []cache.ArtistLetterCount{{Letter: "A", Count: 1, Offset: 0}, {Letter: "B", Count: 3, Offset: 1}}
+	if len(filtered) != len(wantFiltered) || filtered[0] != wantFiltered[0] || filtered[1] != wantFiltered[1] {
+		t.Errorf("filtered index = %+v, want %+v", filtered, wantFiltered)
+	}
+}
+
+func TestDAOListSourceCounts(t *testing.T) {
+	db := cache.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err := db.Open(); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	dao := cache.NewDAO(db)
+
+	albums := []*cache.CachedAlbum{
+		{ID: "a1", Title: "One", AlbumArtist: "A", Source: "nas", TrackCount: 10},
+		{ID: "a2", Title: "Two", AlbumArtist: "B", Source: "nas", TrackCount: 12},
+		{ID: "a3", Title: "Three", AlbumArtist: "C", Source: "local", TrackCount: 8},
+	}
+	for _, album := range albums {
+		if err := dao.InsertAlbum(album); err != nil {
+			t.Fatalf("Failed to insert %s: %v", album.ID, err)
+		}
+	}
+
+	sources, err := dao.ListSourceCounts()
+	if err != nil {
+		t.Fatalf("ListSourceCounts failed: %v", err)
+	}
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %+v", sources)
+	}
+	local, nas := sources[0], sources[1]
+	if local.Source != "local" || local.AlbumCount != 1 || local.TrackCount != 8 {
+		t.Errorf("Unexpected local counts: %+v", local)
+	}
+	if nas.Source != "nas" || nas.AlbumCount != 2 || nas.TrackCount != 22 {
+		t.Errorf("Unexpected nas counts: %+v", nas)
+	}
+	if nas.LastScanned.IsZero() || time.Since(nas.LastScanned) > time.Minute {
+		t.Errorf("LastScanned = %v, want about now", nas.LastScanned)
+	}
+}
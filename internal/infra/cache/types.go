@@ -3,6 +3,9 @@ package cache
 
 import "time"
 
+// VariousArtists is the album artist used for compilation albums.
+const VariousArtists = "Various Artists"
+
 // CachedAlbum represents an album stored in the cache.
 type CachedAlbum struct {
 	ID            string    `json:"id"`             // MD5(albumArtist || album)
@@ -97,6 +100,28 @@ type CacheStats struct {
 	BuildProgress int       `json:"buildProgress"` // 0-100
 }
 
+// DecadeCount is the number of cached albums released in a decade.
+type DecadeCount struct {
+	Decade int `json:"decade"` // First year of the decade, e.g. 1970
+	Count  int `json:"count"`  // Number of albums
+}
+
+// SourceCount is the size of one source's share of the cache.
+type SourceCount struct {
+	Source      string    `json:"source"` // 'local', 'usb', 'nas'
+	AlbumCount  int       `json:"albumCount"`
+	TrackCount  int       `json:"trackCount"`  // Sum of the albums' track counts
+	LastScanned time.Time `json:"lastScanned"` // When the newest album from the source was cached
+}
+
+// ArtistLetterCount is the number of cached artists whose names start with
+// a letter, for an A-Z jump bar.
+type ArtistLetterCount struct {
+	Letter string `json:"letter"` // "A" to "Z", or "#" for anything else
+	Count  int    `json:"count"`  // Number of artists
+	Offset int    `json:"offset"` // Position of the first one in the artist list
+}
+
 // AlbumFilter defines filters for album queries.
 type AlbumFilter struct {
 	Scope  string // 'all', 'nas', 'local', 'usb'
@@ -104,6 +129,12 @@ type AlbumFilter struct {
 	Artist string // Filter by artist
 }
 
+// TrackFilter defines filters for track queries.
+type TrackFilter struct {
+	AlbumID string // Album to list
+	Scope   string // 'all', 'nas', 'local', 'usb'
+}
+
 // SortOrder defines how results should be sorted.
 type SortOrder string
 
@@ -121,6 +152,10 @@ type Pagination struct {
 	Offset  int // Calculated from Page and Limit
 }
 
+// AllRows is a Pagination that returns every row; SQLite treats a negative
+// LIMIT as no limit.
+var AllRows = Pagination{Page: 1, Limit: -1}
+
 // NewPagination creates a new pagination with defaults.
 func NewPagination(page, limit int) Pagination {
 	if page < 1 {
@@ -0,0 +1,101 @@
+package dlna
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// soapEnvelope wraps an action body in a SOAP 1.1 envelope.
+const soapEnvelope = `<?xml version="1.0" encoding="utf-8"?>` +
+	`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+	`<s:Body>%s</s:Body></s:Envelope>`
+
+// soapArg is a single action argument.
+type soapArg struct {
+	name  string
+	value string
+}
+
+// escapeXML escapes text for use in an XML element.
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// soapAction calls an AVTransport action on a renderer.
+func (s *Service) soapAction(ctx context.Context, controlURL, action string, args ...soapArg) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, AVTransportType)
+	for _, arg := range args {
+		fmt.Fprintf(&body, "<%s>%s</%s>", arg.name, escapeXML(arg.value), arg.name)
+	}
+	fmt.Fprintf(&body, "</u:%s>", action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL,
+		strings.NewReader(fmt.Sprintf(soapEnvelope, body.String())))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPACTION", fmt.Sprintf(`"%s#%s"`, AVTransportType, action))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if code := upnpErrorCode(data); code != "" {
+			return fmt.Errorf("%s failed: UPnP error %s", action, code)
+		}
+		return fmt.Errorf("%s failed: HTTP %d", action, resp.StatusCode)
+	}
+	return nil
+}
+
+// upnpErrorCode extracts the errorCode from a SOAP fault body.
+func upnpErrorCode(data []byte) string {
+	var fault struct {
+		Code string `xml:"Body>Fault>detail>UPnPError>errorCode"`
+	}
+	if err := xml.Unmarshal(data, &fault); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(fault.Code)
+}
+
+// didlMetadata builds the DIDL-Lite description of a music track.
+func didlMetadata(url string, meta Metadata) string {
+	mimeType := meta.MimeType
+	if mimeType == "" {
+		mimeType = "audio/mpeg"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" ` +
+		`xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+	b.WriteString(`<item id="0" parentID="-1" restricted="1">`)
+	fmt.Fprintf(&b, "<dc:title>%s</dc:title>", escapeXML(meta.Title))
+	if meta.Artist != "" {
+		fmt.Fprintf(&b, "<upnp:artist>%s</upnp:artist>", escapeXML(meta.Artist))
+	}
+	if meta.Album != "" {
+		fmt.Fprintf(&b, "<upnp:album>%s</upnp:album>", escapeXML(meta.Album))
+	}
+	if meta.AlbumArtURI != "" {
+		fmt.Fprintf(&b, "<upnp:albumArtURI>%s</upnp:albumArtURI>", escapeXML(meta.AlbumArtURI))
+	}
+	b.WriteString("<upnp:class>object.item.audioItem.musicTrack</upnp:class>")
+	fmt.Fprintf(&b, `<res protocolInfo="http-get:*:%s:*">%s</res>`, escapeXML(mimeType), escapeXML(url))
+	b.WriteString("</item></DIDL-Lite>")
+	return b.String()
+}
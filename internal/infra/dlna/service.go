@@ -0,0 +1,199 @@
+package dlna
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultSearchWait is how long discovery listens for SSDP responses
+	DefaultSearchWait = 3 * time.Second
+
+	// DefaultTimeout for description and control requests
+	DefaultTimeout = 10 * time.Second
+)
+
+// Option is a functional option for configuring the Service.
+type Option func(*Service)
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Service) {
+		s.httpClient = client
+	}
+}
+
+// WithSearchWait sets how long discovery waits for renderers to answer.
+func WithSearchWait(wait time.Duration) Option {
+	return func(s *Service) {
+		s.searchWait = wait
+	}
+}
+
+// Service discovers renderers and casts media to them. Discovered renderers
+// are remembered by UDN so a cast doesn't need a new search.
+type Service struct {
+	httpClient *http.Client
+	searchWait time.Duration
+
+	// search returns device description URLs; replaced in tests
+	search func(ctx context.Context) ([]string, error)
+
+	mu        sync.RWMutex
+	renderers map[string]Renderer
+}
+
+// NewService creates a DLNA service.
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		searchWait: DefaultSearchWait,
+		renderers:  make(map[string]Renderer),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.search = func(ctx context.Context) ([]string, error) {
+		return searchLocations(ctx, MediaRendererType, s.searchWait)
+	}
+	return s
+}
+
+// Discover searches the network for media renderers, sorted by name.
+func (s *Service) Discover(ctx context.Context) ([]Renderer, error) {
+	locations, err := s.search(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SSDP search failed: %w", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		renderers []Renderer
+	)
+	for _, location := range locations {
+		wg.Add(1)
+		go func(location string) {
+			defer wg.Done()
+			r, err := s.describe(ctx, location)
+			if err != nil {
+				log.Debug().Err(err).Str("location", location).Msg("Skipping UPnP device")
+				return
+			}
+			mu.Lock()
+			renderers = append(renderers, r)
+			mu.Unlock()
+		}(location)
+	}
+	wg.Wait()
+
+	sort.Slice(renderers, func(i, j int) bool {
+		return renderers[i].Name < renderers[j].Name
+	})
+
+	s.mu.Lock()
+	s.renderers = make(map[string]Renderer, len(renderers))
+	for _, r := range renderers {
+		s.renderers[r.UDN] = r
+	}
+	s.mu.Unlock()
+
+	log.Info().Int("count", len(renderers)).Msg("DLNA renderer discovery complete")
+	return renderers, nil
+}
+
+// GetRenderers runs discovery and wraps the result for Socket.io.
+func (s *Service) GetRenderers(ctx context.Context) RenderersResponse {
+	renderers, err := s.Discover(ctx)
+	if err != nil {
+		return RenderersResponse{Renderers: []Renderer{}, Error: err.Error()}
+	}
+	if renderers == nil {
+		renderers = []Renderer{}
+	}
+	return RenderersResponse{Renderers: renderers}
+}
+
+// Renderer returns a renderer by UDN, searching again if it isn't known yet.
+func (s *Service) Renderer(ctx context.Context, udn string) (Renderer, error) {
+	s.mu.RLock()
+	r, ok := s.renderers[udn]
+	s.mu.RUnlock()
+	if ok {
+		return r, nil
+	}
+
+	if _, err := s.Discover(ctx); err != nil {
+		return Renderer{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r, ok := s.renderers[udn]; ok {
+		return r, nil
+	}
+	return Renderer{}, fmt.Errorf("%w: %s", ErrRendererNotFound, udn)
+}
+
+// Cast loads url on the renderer and starts playback.
+func (s *Service) Cast(ctx context.Context, r Renderer, url string, meta Metadata) error {
+	err := s.soapAction(ctx, r.ControlURL, "SetAVTransportURI",
+		soapArg{"InstanceID", "0"},
+		soapArg{"CurrentURI", url},
+		soapArg{"CurrentURIMetaData", didlMetadata(url, meta)},
+	)
+	if err != nil {
+		return err
+	}
+	return s.soapAction(ctx, r.ControlURL, "Play",
+		soapArg{"InstanceID", "0"},
+		soapArg{"Speed", "1"},
+	)
+}
+
+// Stop stops playback on the renderer.
+func (s *Service) Stop(ctx context.Context, r Renderer) error {
+	return s.soapAction(ctx, r.ControlURL, "Stop", soapArg{"InstanceID", "0"})
+}
+
+// describe fetches and parses a device description.
+func (s *Service) describe(ctx context.Context, location string) (Renderer, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return Renderer{}, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return Renderer{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Renderer{}, fmt.Errorf("device description returned HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDescriptionSize))
+	if err != nil {
+		return Renderer{}, err
+	}
+	return parseDescription(location, data)
+}
+
+// LocalIPFor returns the local address used to reach host, which is the
+// address a renderer on that network can fetch streams from.
+func LocalIPFor(host string) (string, error) {
+	// Connecting a UDP socket sends nothing but selects the outgoing interface
+	conn, err := net.Dial("udp4", net.JoinHostPort(host, "1900"))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
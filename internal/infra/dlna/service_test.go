@@ -0,0 +1,210 @@
+package dlna
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const rendererDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName>Living Room Speaker</friendlyName>
+    <manufacturer>Acme</manufacturer>
+    <modelName>Streamer 2</modelName>
+    <UDN>uuid:renderer-1</UDN>
+    <serviceList>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType>
+        <controlURL>/rc</controlURL>
+      </service>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+        <controlURL>/upnp/control/avt</controlURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+const serverDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaServer:1</deviceType>
+    <friendlyName>NAS</friendlyName>
+    <UDN>uuid:server-1</UDN>
+  </device>
+</root>`
+
+func TestParseSearchResponse(t *testing.T) {
+	resp := "HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=1800\r\n" +
+		"LOCATION: http://192.168.1.50:49152/description.xml\r\n" +
+		"ST: urn:schemas-upnp-org:device:MediaRenderer:1\r\n" +
+		"\r\n"
+
+	if got := parseSearchResponse([]byte(resp)); got != "http://192.168.1.50:49152/description.xml" {
+		t.Errorf("location = %q", got)
+	}
+	if got := parseSearchResponse([]byte("garbage")); got != "" {
+		t.Errorf("location for garbage = %q, want empty", got)
+	}
+}
+
+func TestParseDescription(t *testing.T) {
+	r, err := parseDescription("http://192.168.1.50:49152/description.xml", []byte(rendererDescription))
+	if err != nil {
+		t.Fatalf("parseDescription failed: %v", err)
+	}
+
+	if r.UDN != "uuid:renderer-1" || r.Name != "Living Room Speaker" || r.Model != "Streamer 2" {
+		t.Errorf("renderer = %+v", r)
+	}
+	if r.ControlURL != "http://192.168.1.50:49152/upnp/control/avt" {
+		t.Errorf("ControlURL = %q", r.ControlURL)
+	}
+	if r.Address != "192.168.1.50" {
+		t.Errorf("Address = %q", r.Address)
+	}
+
+	if _, err := parseDescription("http://192.168.1.60/d.xml", []byte(serverDescription)); !errors.Is(err, ErrNoAVTransport) {
+		t.Errorf("err for media server = %v, want ErrNoAVTransport", err)
+	}
+}
+
+// fakeRenderer serves a device description and records SOAP actions.
+type fakeRenderer struct {
+	mu      sync.Mutex
+	actions []string
+	bodies  []string
+	fail    bool
+}
+
+func (f *fakeRenderer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/description.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(rendererDescription))
+	})
+	mux.HandleFunc("/upnp/control/avt", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.actions = append(f.actions, r.Header.Get("SOAPACTION"))
+		f.bodies = append(f.bodies, string(body))
+		f.mu.Unlock()
+
+		if f.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>` +
+				`<detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>714</errorCode></UPnPError></detail>` +
+				`</s:Fault></s:Body></s:Envelope>`))
+		}
+	})
+	return mux
+}
+
+func TestService_DiscoverAndCast(t *testing.T) {
+	fake := &fakeRenderer{}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+
+	s := NewService()
+	s.search = func(ctx context.Context) ([]string, error) {
+		return []string{server.URL + "/description.xml"}, nil
+	}
+
+	resp := s.GetRenderers(context.Background())
+	if resp.Error != "" || len(resp.Renderers) != 1 {
+		t.Fatalf("GetRenderers = %+v, want one renderer", resp)
+	}
+
+	r, err := s.Renderer(context.Background(), "uuid:renderer-1")
+	if err != nil {
+		t.Fatalf("Renderer failed: %v", err)
+	}
+
+	err = s.Cast(context.Background(), r, "http://192.168.1.10:3001/stream?path=a%26b.flac", Metadata{
+		Title:    "Song & Dance",
+		Artist:   "Artist",
+		MimeType: "audio/flac",
+	})
+	if err != nil {
+		t.Fatalf("Cast failed: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	want := []string{
+		`"urn:schemas-upnp-org:service:AVTransport:1#SetAVTransportURI"`,
+		`"urn:schemas-upnp-org:service:AVTransport:1#Play"`,
+	}
+	if len(fake.actions) != len(want) || fake.actions[0] != want[0] || fake.actions[1] != want[1] {
+		t.Fatalf("actions = %v, want %v", fake.actions, want)
+	}
+
+	// The URL and the nested DIDL-Lite metadata must both be escaped
+	setURI := fake.bodies[0]
+	if !strings.Contains(setURI, "<CurrentURI>http://192.168.1.10:3001/stream?path=a%26b.flac</CurrentURI>") {
+		t.Errorf("SetAVTransportURI body missing CurrentURI: %s", setURI)
+	}
+	if !strings.Contains(setURI, "&lt;dc:title&gt;Song &amp;amp; Dance&lt;/dc:title&gt;") {
+		t.Errorf("SetAVTransportURI body missing escaped title: %s", setURI)
+	}
+}
+
+func TestService_CastReportsUPnPError(t *testing.T) {
+	fake := &fakeRenderer{fail: true}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+
+	s := NewService()
+	r := Renderer{UDN: "uuid:renderer-1", ControlURL: server.URL + "/upnp/control/avt"}
+
+	err := s.Cast(context.Background(), r, "http://example/a.flac", Metadata{Title: "A"})
+	if err == nil || !strings.Contains(err.Error(), "UPnP error 714") {
+		t.Errorf("err = %v, want UPnP error 714", err)
+	}
+}
+
+func TestService_RendererNotFound(t *testing.T) {
+	s := NewService()
+	s.search = func(ctx context.Context) ([]string, error) { return nil, nil }
+
+	if _, err := s.Renderer(context.Background(), "uuid:missing"); !errors.Is(err, ErrRendererNotFound) {
+		t.Errorf("err = %v, want ErrRendererNotFound", err)
+	}
+}
+
+func TestIsAudioFile(t *testing.T) {
+	tests := map[string]bool{
+		"Music/a.FLAC":      true,
+		"Music/b.dsf":       true,
+		"Music/cover.jpg":   false,
+		"Music/unknown":     false,
+		"etc/shadow":        false,
+		"Music/notes.flac/": false,
+	}
+	for path, want := range tests {
+		if got := IsAudioFile(path); got != want {
+			t.Errorf("IsAudioFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestMimeType(t *testing.T) {
+	tests := map[string]string{
+		"Music/a.FLAC":  "audio/flac",
+		"Music/b.dsf":   "audio/x-dsf",
+		"Music/c.mp3":   "audio/mpeg",
+		"Music/unknown": "audio/mpeg",
+	}
+	for path, want := range tests {
+		if got := MimeType(path); got != want {
+			t.Errorf("MimeType(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
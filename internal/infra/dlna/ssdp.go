@@ -0,0 +1,172 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ssdpAddr is the SSDP multicast address
+	ssdpAddr = "239.255.255.250:1900"
+
+	// ssdpMX is how many seconds devices may wait before answering a search
+	ssdpMX = 2
+
+	// maxDescriptionSize caps a device description download
+	maxDescriptionSize = 1 << 20
+)
+
+// searchRequest builds an SSDP M-SEARCH for the given search target.
+func searchRequest(target string) []byte {
+	return []byte("M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		fmt.Sprintf("MX: %d\r\n", ssdpMX) +
+		"ST: " + target + "\r\n" +
+		"\r\n")
+}
+
+// searchLocations sends an M-SEARCH and collects the description URLs of the
+// devices that answer before wait elapses.
+func searchLocations(ctx context.Context, target string, wait time.Duration) ([]string, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send twice; SSDP is UDP and searches get lost
+	req := searchRequest(target)
+	for i := 0; i < 2; i++ {
+		if _, err := conn.WriteToUDP(req, dst); err != nil {
+			return nil, fmt.Errorf("send M-SEARCH: %w", err)
+		}
+	}
+
+	deadline := time.Now().Add(wait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	seen := make(map[string]bool)
+	var locations []string
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// Read deadline reached
+			break
+		}
+		if location := parseSearchResponse(buf[:n]); location != "" && !seen[location] {
+			seen[location] = true
+			locations = append(locations, location)
+		}
+	}
+	return locations, nil
+}
+
+// parseSearchResponse returns the LOCATION header of an M-SEARCH response.
+func parseSearchResponse(data []byte) string {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return ""
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	return resp.Header.Get("Location")
+}
+
+// deviceDescription is the subset of a UPnP device description we use.
+type deviceDescription struct {
+	URLBase string       `xml:"URLBase"`
+	Device  deviceRecord `xml:"device"`
+}
+
+type deviceRecord struct {
+	DeviceType   string          `xml:"deviceType"`
+	FriendlyName string          `xml:"friendlyName"`
+	Manufacturer string          `xml:"manufacturer"`
+	ModelName    string          `xml:"modelName"`
+	UDN          string          `xml:"UDN"`
+	Services     []serviceRecord `xml:"serviceList>service"`
+	Devices      []deviceRecord  `xml:"deviceList>device"`
+}
+
+type serviceRecord struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// findAVTransport returns the device and control URL of the first
+// AVTransport service, searching embedded devices too.
+func (d deviceRecord) findAVTransport() (deviceRecord, string, bool) {
+	for _, svc := range d.Services {
+		if strings.HasPrefix(svc.ServiceType, "urn:schemas-upnp-org:service:AVTransport:") {
+			return d, svc.ControlURL, true
+		}
+	}
+	for _, child := range d.Devices {
+		if dev, controlURL, ok := child.findAVTransport(); ok {
+			return dev, controlURL, true
+		}
+	}
+	return deviceRecord{}, "", false
+}
+
+// parseDescription builds a Renderer from a device description fetched from
+// location.
+func parseDescription(location string, data []byte) (Renderer, error) {
+	var desc deviceDescription
+	if err := xml.Unmarshal(data, &desc); err != nil {
+		return Renderer{}, fmt.Errorf("parse device description: %w", err)
+	}
+
+	dev, controlURL, ok := desc.Device.findAVTransport()
+	if !ok {
+		return Renderer{}, ErrNoAVTransport
+	}
+
+	base := location
+	if desc.URLBase != "" {
+		base = desc.URLBase
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return Renderer{}, fmt.Errorf("invalid base URL %q: %w", base, err)
+	}
+	control, err := baseURL.Parse(controlURL)
+	if err != nil {
+		return Renderer{}, fmt.Errorf("invalid control URL %q: %w", controlURL, err)
+	}
+
+	name := strings.TrimSpace(desc.Device.FriendlyName)
+	if name == "" {
+		name = strings.TrimSpace(dev.FriendlyName)
+	}
+
+	return Renderer{
+		UDN:          strings.TrimSpace(desc.Device.UDN),
+		Name:         name,
+		Manufacturer: strings.TrimSpace(desc.Device.Manufacturer),
+		Model:        strings.TrimSpace(desc.Device.ModelName),
+		Address:      baseURL.Hostname(),
+		Location:     location,
+		ControlURL:   control.String(),
+	}, nil
+}
@@ -0,0 +1,95 @@
+// Package dlna discovers UPnP/DLNA media renderers with SSDP and hands
+// playback to them through the AVTransport service.
+package dlna
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Errors returned by the DLNA service.
+var (
+	// ErrRendererNotFound is returned when a renderer UDN isn't on the network
+	ErrRendererNotFound = errors.New("renderer not found")
+
+	// ErrNoAVTransport is returned for devices without an AVTransport service
+	ErrNoAVTransport = errors.New("device has no AVTransport service")
+)
+
+// UPnP identifiers used for discovery and control.
+const (
+	// MediaRendererType is the SSDP search target for renderers
+	MediaRendererType = "urn:schemas-upnp-org:device:MediaRenderer:1"
+
+	// AVTransportType is the service type used to control playback
+	AVTransportType = "urn:schemas-upnp-org:service:AVTransport:1"
+)
+
+// Renderer is a UPnP media renderer discovered on the network.
+type Renderer struct {
+	UDN          string `json:"udn"`
+	Name         string `json:"name"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Address      string `json:"address"` // Host of the device description URL
+	Location     string `json:"location"`
+	ControlURL   string `json:"-"` // Absolute AVTransport control URL
+}
+
+// Metadata describes the media being cast, sent to the renderer as DIDL-Lite.
+type Metadata struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtURI string
+	MimeType    string
+}
+
+// RenderersResponse is the response to getDlnaRenderers.
+type RenderersResponse struct {
+	Renderers []Renderer `json:"renderers"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// CastResponse is the response to castToDlna.
+type CastResponse struct {
+	UDN   string `json:"udn"`
+	Name  string `json:"name,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Title string `json:"title,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// audioMimeTypes maps audio file extensions to the MIME types renderers expect.
+var audioMimeTypes = map[string]string{
+	".flac": "audio/flac",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".aif":  "audio/aiff",
+	".aiff": "audio/aiff",
+	".dsf":  "audio/x-dsf",
+	".dff":  "audio/x-dff",
+	".wv":   "audio/x-wavpack",
+	".ape":  "audio/x-ape",
+}
+
+// IsAudioFile reports whether path has an audio extension renderers can be
+// sent.
+func IsAudioFile(path string) bool {
+	_, ok := audioMimeTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// MimeType returns the MIME type for an audio file path, defaulting to
+// audio/mpeg.
+func MimeType(path string) string {
+	if mimeType, ok := audioMimeTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return mimeType
+	}
+	return "audio/mpeg"
+}
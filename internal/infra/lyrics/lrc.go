@@ -0,0 +1,58 @@
+package lyrics
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lrcTimestamp matches an LRC line timestamp such as [01:23.45] or [01:23].
+var lrcTimestamp = regexp.MustCompile(`\[(\d+):(\d{1,2}(?:\.\d+)?)\]`)
+
+// ParseLRC parses LRC text into lines ordered by time. Lines with several
+// timestamps are repeated at each one; metadata tags such as [ar:...] and
+// lines without a timestamp are skipped.
+func ParseLRC(text string) []Line {
+	var lines []Line
+
+	for _, raw := range strings.Split(text, "\n") {
+		raw = strings.TrimSpace(raw)
+
+		var times []float64
+		for {
+			loc := lrcTimestamp.FindStringSubmatchIndex(raw)
+			if loc == nil || loc[0] != 0 {
+				break
+			}
+			minutes, _ := strconv.Atoi(raw[loc[2]:loc[3]])
+			seconds, _ := strconv.ParseFloat(raw[loc[4]:loc[5]], 64)
+			times = append(times, float64(minutes)*60+seconds)
+			raw = raw[loc[1]:]
+		}
+
+		text := strings.TrimSpace(raw)
+		for _, t := range times {
+			lines = append(lines, Line{Time: t, Text: text})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Time < lines[j].Time
+	})
+	return lines
+}
+
+// plainLines splits plain lyrics into untimed lines.
+func plainLines(text string) []Line {
+	if text == "" {
+		return []Line{}
+	}
+
+	raw := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	lines := make([]Line, len(raw))
+	for i, line := range raw {
+		lines[i] = Line{Text: strings.TrimSpace(line)}
+	}
+	return lines
+}
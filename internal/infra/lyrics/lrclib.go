@@ -0,0 +1,182 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultLrclibBaseURL is the LRCLIB API base URL
+	DefaultLrclibBaseURL = "https://lrclib.net"
+
+	// DefaultLrclibUserAgent identifies the app as LRCLIB asks clients to
+	DefaultLrclibUserAgent = "Stellar/1.5.0 (https://github.com/edumarques81/stellar-volumio-audioplayer-backend)"
+
+	// DefaultLrclibTimeout for HTTP requests
+	DefaultLrclibTimeout = 15 * time.Second
+
+	// SourceLrclib identifies lyrics fetched from LRCLIB
+	SourceLrclib = "lrclib"
+)
+
+// LrclibClient looks up plain and synced lyrics via the LRCLIB API.
+// No API key required.
+type LrclibClient struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// LrclibOption is a functional option for configuring the LRCLIB client.
+type LrclibOption func(*LrclibClient)
+
+// WithLrclibBaseURL sets a custom base URL (useful for testing).
+func WithLrclibBaseURL(url string) LrclibOption {
+	return func(c *LrclibClient) {
+		c.baseURL = url
+	}
+}
+
+// WithLrclibHTTPClient sets a custom HTTP client.
+func WithLrclibHTTPClient(client *http.Client) LrclibOption {
+	return func(c *LrclibClient) {
+		c.httpClient = client
+	}
+}
+
+// NewLrclibClient creates a new LRCLIB API client.
+func NewLrclibClient(opts ...LrclibOption) *LrclibClient {
+	c := &LrclibClient{
+		baseURL:   DefaultLrclibBaseURL,
+		userAgent: DefaultLrclibUserAgent,
+		httpClient: &http.Client{
+			Timeout: DefaultLrclibTimeout,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// lrclibRecord is a lyrics record from the LRCLIB search endpoint.
+type lrclibRecord struct {
+	ID           int    `json:"id"`
+	TrackName    string `json:"trackName"`
+	ArtistName   string `json:"artistName"`
+	Instrumental bool   `json:"instrumental"`
+	PlainLyrics  string `json:"plainLyrics"`
+	SyncedLyrics string `json:"syncedLyrics"`
+}
+
+// Lookup searches LRCLIB for a song and returns the best record, preferring
+// synced lyrics and exact artist matches. Returns ErrNotFound if nothing matches.
+func (c *LrclibClient) Lookup(ctx context.Context, artist, title string) (*Lyrics, error) {
+	searchURL := fmt.Sprintf("%s/api/search?artist_name=%s&track_name=%s",
+		c.baseURL, url.QueryEscape(artist), url.QueryEscape(title))
+
+	log.Debug().
+		Str("artist", artist).
+		Str("title", title).
+		Msg("Searching LRCLIB for lyrics")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Handle response status codes
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Success
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		log.Warn().Int("status", resp.StatusCode).Msg("LRCLIB temporary error")
+		return nil, ErrTemporaryFailure
+	default:
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var records []lrclibRecord
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	best := bestRecord(records, artist)
+	if best == nil {
+		return nil, ErrNotFound
+	}
+
+	log.Debug().
+		Str("artist", artist).
+		Str("title", title).
+		Int("lrclibID", best.ID).
+		Bool("synced", best.SyncedLyrics != "").
+		Msg("Found lyrics on LRCLIB")
+
+	return &Lyrics{
+		Artist:       artist,
+		Title:        title,
+		Found:        true,
+		Instrumental: best.Instrumental,
+		Plain:        best.PlainLyrics,
+		Synced:       best.SyncedLyrics,
+		Source:       SourceLrclib,
+	}, nil
+}
+
+// bestRecord picks the most useful record: exact artist matches before
+// others, then synced lyrics before plain ones before instrumentals.
+func bestRecord(records []lrclibRecord, artist string) *lrclibRecord {
+	var best *lrclibRecord
+	bestScore := 0
+
+	for i := range records {
+		r := &records[i]
+
+		score := 0
+		switch {
+		case r.SyncedLyrics != "":
+			score = 3
+		case r.PlainLyrics != "":
+			score = 2
+		case r.Instrumental:
+			score = 1
+		default:
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(r.ArtistName), strings.TrimSpace(artist)) {
+			score += 10
+		}
+
+		if score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,146 @@
+package lyrics
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// missRetryAfter is how long a "no lyrics" result is cached before the
+// provider is asked again.
+const missRetryAfter = 7 * 24 * time.Hour
+
+// Service looks up lyrics through a provider and caches the results on disk,
+// keyed by artist and title.
+type Service struct {
+	provider Provider
+	cacheDir string
+	now      func() time.Time
+}
+
+// NewService creates a lyrics service caching under cacheDir.
+func NewService(provider Provider, cacheDir string) *Service {
+	return &Service{
+		provider: provider,
+		cacheDir: cacheDir,
+		now:      time.Now,
+	}
+}
+
+// Get returns the lyrics for a song. A song without lyrics is not an error:
+// the response has Found set to false.
+func (s *Service) Get(ctx context.Context, artist, title string) Response {
+	artist = strings.TrimSpace(artist)
+	title = strings.TrimSpace(title)
+
+	resp := Response{Artist: artist, Title: title, Lines: []Line{}}
+	if artist == "" || title == "" {
+		resp.Error = "artist and title are required"
+		return resp
+	}
+
+	lyrics, err := s.lookup(ctx, artist, title)
+	if err != nil {
+		log.Warn().Err(err).Str("artist", artist).Str("title", title).Msg("Lyrics lookup failed")
+		resp.Error = fmt.Sprintf("lyrics lookup failed: %v", err)
+		return resp
+	}
+
+	return toResponse(lyrics)
+}
+
+// lookup returns cached lyrics or fetches and caches them.
+func (s *Service) lookup(ctx context.Context, artist, title string) (*Lyrics, error) {
+	path := s.cachePath(artist, title)
+
+	if cached, ok := s.readCache(path); ok {
+		if cached.Found || s.now().Sub(cached.FetchedAt) < missRetryAfter {
+			return cached, nil
+		}
+	}
+
+	lyrics, err := s.provider.Lookup(ctx, artist, title)
+	if errors.Is(err, ErrNotFound) {
+		lyrics, err = &Lyrics{Artist: artist, Title: title}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lyrics.FetchedAt = s.now()
+	s.writeCache(path, lyrics)
+	return lyrics, nil
+}
+
+// cachePath returns the cache file for a song. Matching is case-insensitive.
+func (s *Service) cachePath(artist, title string) string {
+	key := strings.ToLower(artist) + "\x00" + strings.ToLower(title)
+	hash := md5.Sum([]byte(key))
+	return filepath.Join(s.cacheDir, hex.EncodeToString(hash[:])+".json")
+}
+
+// readCache loads cached lyrics, returning false if there are none.
+func (s *Service) readCache(path string) (*Lyrics, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debug().Err(err).Str("file", path).Msg("Failed to read cached lyrics")
+		}
+		return nil, false
+	}
+
+	var lyrics Lyrics
+	if err := json.Unmarshal(data, &lyrics); err != nil {
+		log.Debug().Err(err).Str("file", path).Msg("Failed to parse cached lyrics")
+		return nil, false
+	}
+	return &lyrics, true
+}
+
+// writeCache stores lyrics on disk. Failures are logged, not returned, since
+// the lyrics are still usable.
+func (s *Service) writeCache(path string, lyrics *Lyrics) {
+	data, err := json.MarshalIndent(lyrics, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal lyrics")
+		return
+	}
+
+	if err := os.MkdirAll(s.cacheDir, 0755); err != nil {
+		log.Error().Err(err).Msg("Failed to create lyrics cache directory")
+		return
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Error().Err(err).Msg("Failed to cache lyrics")
+	}
+}
+
+// toResponse converts cached lyrics into the client payload.
+func toResponse(lyrics *Lyrics) Response {
+	resp := Response{
+		Artist:       lyrics.Artist,
+		Title:        lyrics.Title,
+		Found:        lyrics.Found,
+		Instrumental: lyrics.Instrumental,
+		Plain:        lyrics.Plain,
+		Source:       lyrics.Source,
+	}
+
+	if lines := ParseLRC(lyrics.Synced); len(lines) > 0 {
+		resp.Synced = true
+		resp.Lines = lines
+	} else {
+		resp.Lines = plainLines(lyrics.Plain)
+	}
+	return resp
+}
@@ -0,0 +1,192 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// countingProvider returns canned lyrics and counts lookups.
+type countingProvider struct {
+	lyrics *Lyrics
+	err    error
+	calls  int
+}
+
+func (p *countingProvider) Lookup(ctx context.Context, artist, title string) (*Lyrics, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	l := *p.lyrics
+	return &l, nil
+}
+
+func TestParseLRC(t *testing.T) {
+	text := "[ar:Artist]\n[ti:Song]\n[00:12.50]First line\n[00:05.00][01:00]Chorus\n[00:20.00]\nno timestamp"
+
+	got := ParseLRC(text)
+	want := []Line{
+		{Time: 5, Text: "Chorus"},
+		{Time: 12.5, Text: "First line"},
+		{Time: 20, Text: ""},
+		{Time: 60, Text: "Chorus"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLRC = %+v, want %+v", got, want)
+	}
+
+	if got := ParseLRC(""); len(got) != 0 {
+		t.Errorf("ParseLRC(\"\") = %+v, want no lines", got)
+	}
+}
+
+func TestService_Get_CachesLyrics(t *testing.T) {
+	provider := &countingProvider{lyrics: &Lyrics{
+		Artist: "Artist",
+		Title:  "Song",
+		Found:  true,
+		Plain:  "First line\nSecond line",
+		Synced: "[00:01.00]First line\n[00:02.00]Second line",
+		Source: SourceLrclib,
+	}}
+	service := NewService(provider, t.TempDir())
+
+	resp := service.Get(context.Background(), "Artist", "Song")
+	if resp.Error != "" || !resp.Found || !resp.Synced {
+		t.Fatalf("Get = %+v, want found synced lyrics", resp)
+	}
+	if len(resp.Lines) != 2 || resp.Lines[1].Time != 2 {
+		t.Errorf("Lines = %+v, want two timed lines", resp.Lines)
+	}
+
+	// Cached lookups are case-insensitive and skip the provider
+	service.Get(context.Background(), "ARTIST", "song")
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1", provider.calls)
+	}
+}
+
+func TestService_Get_PlainOnly(t *testing.T) {
+	provider := &countingProvider{lyrics: &Lyrics{Found: true, Plain: "Line one\r\nLine two"}}
+	service := NewService(provider, t.TempDir())
+
+	resp := service.Get(context.Background(), "Artist", "Song")
+	if resp.Synced {
+		t.Error("Synced = true, want false for plain lyrics")
+	}
+	if want := []Line{{Text: "Line one"}, {Text: "Line two"}}; !reflect.DeepEqual(resp.Lines, want) {
+		t.Errorf("Lines = %+v, want %+v", resp.Lines, want)
+	}
+}
+
+func TestService_Get_NotFound(t *testing.T) {
+	provider := &countingProvider{err: ErrNotFound}
+	service := NewService(provider, t.TempDir())
+
+	resp := service.Get(context.Background(), "Artist", "Unknown")
+	if resp.Error != "" || resp.Found || resp.Lines == nil || len(resp.Lines) != 0 {
+		t.Errorf("Get = %+v, want clean not-found response", resp)
+	}
+
+	// Misses are cached until they expire
+	service.Get(context.Background(), "Artist", "Unknown")
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1", provider.calls)
+	}
+
+	service.now = func() time.Time { return time.Now().Add(missRetryAfter + time.Hour) }
+	service.Get(context.Background(), "Artist", "Unknown")
+	if provider.calls != 2 {
+		t.Errorf("provider called %d times after miss expired, want 2", provider.calls)
+	}
+}
+
+func TestService_Get_Errors(t *testing.T) {
+	provider := &countingProvider{err: ErrTemporaryFailure}
+	service := NewService(provider, t.TempDir())
+
+	if resp := service.Get(context.Background(), "", "Song"); resp.Error == "" {
+		t.Error("Get without artist succeeded, want error")
+	}
+	if provider.calls != 0 {
+		t.Errorf("provider called %d times for invalid request, want 0", provider.calls)
+	}
+
+	if resp := service.Get(context.Background(), "Artist", "Song"); resp.Error == "" {
+		t.Error("Get with provider failure succeeded, want error")
+	}
+
+	// Failures are not cached
+	service.Get(context.Background(), "Artist", "Song")
+	if provider.calls != 2 {
+		t.Errorf("provider called %d times, want 2", provider.calls)
+	}
+}
+
+func TestLrclibClient_Lookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		statusCode int
+		wantErr    error
+		wantSynced string
+		wantPlain  string
+	}{
+		{
+			name: "prefers exact artist with synced lyrics",
+			response: `[
+				{"id": 1, "artistName": "Cover Band", "syncedLyrics": "[00:01.00]cover"},
+				{"id": 2, "artistName": "artist", "plainLyrics": "plain only"},
+				{"id": 3, "artistName": "Artist", "plainLyrics": "plain", "syncedLyrics": "[00:01.00]synced"}
+			]`,
+			statusCode: http.StatusOK,
+			wantSynced: "[00:01.00]synced",
+			wantPlain:  "plain",
+		},
+		{
+			name:       "empty results",
+			response:   `[]`,
+			statusCode: http.StatusOK,
+			wantErr:    ErrNotFound,
+		},
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			wantErr:    ErrTemporaryFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/search" || r.URL.Query().Get("artist_name") != "Artist" || r.URL.Query().Get("track_name") != "Song" {
+					t.Errorf("unexpected request: %s", r.URL)
+				}
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			client := NewLrclibClient(WithLrclibBaseURL(server.URL))
+			got, err := client.Lookup(context.Background(), "Artist", "Song")
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Lookup error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			if !got.Found || got.Synced != tt.wantSynced || got.Plain != tt.wantPlain {
+				t.Errorf("Lookup = %+v, want synced %q and plain %q", got, tt.wantSynced, tt.wantPlain)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Package lyrics fetches and caches song lyrics from web providers.
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Common errors
+var (
+	// ErrNotFound indicates the provider has no lyrics for the song
+	ErrNotFound = errors.New("lyrics not found")
+
+	// ErrTemporaryFailure indicates a temporary provider failure (should retry)
+	ErrTemporaryFailure = errors.New("temporary failure")
+)
+
+// Provider looks up lyrics for a song.
+type Provider interface {
+	Lookup(ctx context.Context, artist, title string) (*Lyrics, error)
+}
+
+// Line is a single lyrics line. Time is the offset into the song in seconds
+// and is only set for synced lyrics.
+type Line struct {
+	Time float64 `json:"time,omitempty"`
+	Text string  `json:"text"`
+}
+
+// Lyrics holds the lyrics of a song, as cached on disk.
+type Lyrics struct {
+	Artist       string    `json:"artist"`
+	Title        string    `json:"title"`
+	Found        bool      `json:"found"`
+	Instrumental bool      `json:"instrumental,omitempty"`
+	Plain        string    `json:"plain,omitempty"`
+	Synced       string    `json:"synced,omitempty"` // Raw LRC text
+	Source       string    `json:"source,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+// Response is the lyrics payload sent to clients. Lines holds the synced
+// lines when available and the plain lines otherwise.
+type Response struct {
+	Artist       string `json:"artist"`
+	Title        string `json:"title"`
+	Found        bool   `json:"found"`
+	Synced       bool   `json:"synced"`
+	Instrumental bool   `json:"instrumental,omitempty"`
+	Lines        []Line `json:"lines"`
+	Plain        string `json:"plain,omitempty"`
+	Source       string `json:"source,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
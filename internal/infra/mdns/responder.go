@@ -0,0 +1,596 @@
+// Package mdns advertises the service on the local network with multicast
+// DNS (RFC 6762) and DNS-SD (RFC 6763) so companion apps can discover the
+// player without knowing its address.
+package mdns
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// recordTTL is the TTL of advertised records in seconds
+	recordTTL = 120
+
+	// cacheFlush marks records this responder owns exclusively
+	cacheFlush = 1 << 15
+
+	// probeCount and probeInterval follow RFC 6762 section 8.1
+	probeCount    = 3
+	probeInterval = 250 * time.Millisecond
+
+	// announceCount and announceInterval follow RFC 6762 section 8.3
+	announceCount    = 2
+	announceInterval = time.Second
+
+	// servicesName lists service types for DNS-SD browsing
+	servicesName = "_services._dns-sd._udp.local."
+)
+
+// mdnsGroup is the IPv4 mDNS multicast address.
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Service describes the advertised service.
+type Service struct {
+	Instance string   // Human readable name, e.g. the device name
+	Type     string   // Service type, e.g. "_stellar._tcp"
+	Host     string   // Host name without the .local suffix
+	Port     int      // Service port
+	TXT      []string // key=value TXT record entries
+}
+
+// Responder answers mDNS queries for a single service.
+type Responder struct {
+	mu   sync.RWMutex // Guards the fields below up to addrs, which Update uses while serving
+	base Service      // Service as requested, before any renaming
+	svc  Service      // Service as advertised
+
+	// Rename state: suffixes in use and the names another host claimed
+	instanceSuffix int
+	hostSuffix     int
+	instanceTaken  bool
+	hostTaken      bool
+
+	// probing is set while the names are being claimed; queries get no
+	// answer until they are ours
+	probing bool
+
+	// addrs returns the IPv4 addresses to advertise; looked up per response
+	// so DHCP changes are picked up
+	addrs func() []net.IP
+
+	conn *net.UDPConn
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	// reprobe tells the advertiser to claim the names again, after a
+	// conflict or an Update
+	reprobe chan struct{}
+}
+
+// NewResponder creates a responder for svc. Call Start to begin advertising.
+func NewResponder(svc Service) *Responder {
+	svc = normalize(svc)
+	return &Responder{
+		base:    svc,
+		svc:     svc,
+		addrs:   localIPv4Addrs,
+		reprobe: make(chan struct{}, 1),
+	}
+}
+
+// normalize makes the instance and host names usable as DNS labels.
+func normalize(svc Service) Service {
+	// Dots would split the instance into extra labels
+	svc.Instance = strings.ReplaceAll(svc.Instance, ".", "-")
+	svc.Host = strings.TrimSuffix(strings.TrimSuffix(svc.Host, "."), ".local")
+	return svc
+}
+
+// Port returns the advertised service port.
+func (r *Responder) Port() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.svc.Port
+}
+
+// Update replaces the advertised service, e.g. after a hostname change. If
+// the responder is running, the old records get a goodbye and the new names
+// are probed and announced.
+func (r *Responder) Update(svc Service) {
+	svc = normalize(svc)
+
+	// Holding mu keeps Shutdown from closing the connection until the
+	// goodbye is sent
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil && !r.probing {
+		if msg, err := r.recordsFor(r.svc, 0).pack(0); err == nil {
+			r.conn.WriteToUDP(msg, mdnsGroup)
+		}
+	}
+	r.base = svc
+	r.svc = svc
+	r.instanceSuffix, r.hostSuffix = 0, 0
+	r.instanceTaken, r.hostTaken = false, false
+	if r.conn != nil {
+		r.probing = true
+		r.signalReprobe()
+	}
+	log.Info().Str("instance", svc.Instance).Str("host", svc.Host).Msg("mDNS advertisement updated")
+}
+
+// signalReprobe wakes the advertiser without blocking; one pending signal
+// is enough.
+func (r *Responder) signalReprobe() {
+	select {
+	case r.reprobe <- struct{}{}:
+	default:
+	}
+}
+
+// Start joins the mDNS group, then probes for and announces the service and
+// answers queries in the background.
+func (r *Responder) Start() error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conn = conn
+	r.stop = make(chan struct{})
+	r.probing = true
+
+	r.wg.Add(2)
+	go r.advertise(conn, r.stop)
+	go r.serve(conn)
+
+	log.Info().
+		Str("instance", r.svc.Instance).
+		Str("type", r.svc.Type).
+		Int("port", r.svc.Port).
+		Msg("mDNS advertisement started")
+	return nil
+}
+
+// Shutdown sends a goodbye so browsers drop the service immediately, then
+// stops answering queries. Names still being probed may belong to another
+// host, so they get no goodbye.
+func (r *Responder) Shutdown() {
+	r.mu.Lock()
+	conn := r.conn
+	if conn == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.conn = nil
+	close(r.stop)
+	probing := r.probing
+	goodbye, err := r.recordsFor(r.svc, 0).pack(0)
+	r.mu.Unlock()
+
+	if err == nil && !probing {
+		conn.WriteToUDP(goodbye, mdnsGroup)
+	}
+	conn.Close()
+	r.wg.Wait()
+
+	log.Info().Msg("mDNS advertisement stopped")
+}
+
+// advertise claims the service's names and announces them, starting over
+// whenever another host claims one of them or the service is updated. It
+// returns when stop is closed.
+func (r *Responder) advertise(conn *net.UDPConn, stop <-chan struct{}) {
+	defer r.wg.Done()
+
+	for {
+		if !r.probe(conn, stop) {
+			return
+		}
+		r.announce(conn, stop)
+
+		select {
+		case <-stop:
+			return
+		case <-r.reprobe:
+			r.rename()
+			r.mu.Lock()
+			r.probing = true
+			r.mu.Unlock()
+		}
+	}
+}
+
+// probe asks whether anyone else uses the instance and host names (RFC 6762
+// section 8.1), renaming them for as long as another host answers. It
+// returns false if stop is closed first.
+func (r *Responder) probe(conn *net.UDPConn, stop <-chan struct{}) bool {
+	// A random initial delay keeps hosts powered on together from probing in
+	// lockstep
+	wait := rand.N(probeInterval)
+	for sent := 0; ; {
+		select {
+		case <-stop:
+			return false
+		case <-r.reprobe:
+			r.rename()
+			sent, wait = 0, probeInterval
+			continue
+		case <-time.After(wait):
+		}
+
+		if sent == probeCount {
+			break
+		}
+		if msg, err := r.probeQuery(); err == nil {
+			if _, err := conn.WriteToUDP(msg, mdnsGroup); err != nil {
+				log.Debug().Err(err).Msg("Failed to send mDNS probe")
+			}
+		}
+		sent++
+		wait = probeInterval
+	}
+
+	r.mu.Lock()
+	r.probing = false
+	r.mu.Unlock()
+	return true
+}
+
+// probeQuery builds a probe for the service's names, carrying the records
+// it intends to publish in the authority section.
+func (r *Responder) probeQuery() ([]byte, error) {
+	r.mu.RLock()
+	svc := r.svc
+	r.mu.RUnlock()
+
+	var authorities []dnsmessage.Resource
+	for _, rec := range r.recordsFor(svc, recordTTL).answers {
+		if rec.Header.Class&cacheFlush != 0 {
+			rec.Header.Class &^= cacheFlush
+			authorities = append(authorities, rec)
+		}
+	}
+
+	question := func(name string) dnsmessage.Question {
+		return dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeALL, Class: dnsmessage.ClassINET}
+	}
+	msg := dnsmessage.Message{
+		Questions:   []dnsmessage.Question{question(svc.instanceName()), question(svc.hostName())},
+		Authorities: authorities,
+	}
+	return msg.Pack()
+}
+
+// announce sends unsolicited responses on conn until stop is closed or the
+// names need probing again.
+func (r *Responder) announce(conn *net.UDPConn, stop <-chan struct{}) {
+	for i := 0; i < announceCount; i++ {
+		r.mu.RLock()
+		probing := r.probing
+		r.mu.RUnlock()
+		if probing {
+			return
+		}
+		if msg, err := r.records(recordTTL).pack(0); err == nil {
+			if _, err := conn.WriteToUDP(msg, mdnsGroup); err != nil {
+				log.Debug().Err(err).Msg("Failed to send mDNS announcement")
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(announceInterval):
+		}
+	}
+}
+
+// conflicts reports whether packet is a response from another host that
+// publishes different data under the instance or host name, and notes which
+// name is taken for rename. Our own responses loop back with identical data
+// and goodbyes claim nothing, so neither counts.
+func (r *Responder) conflicts(packet []byte) bool {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil || !msg.Header.Response {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ours := r.recordsFor(r.svc, recordTTL)
+	instance := strings.ToLower(r.svc.instanceName())
+	host := strings.ToLower(r.svc.hostName())
+
+	found := false
+	for _, rec := range append(msg.Answers, msg.Additionals...) {
+		if rec.Header.TTL == 0 {
+			continue
+		}
+		name := strings.ToLower(rec.Header.Name.String())
+		if name != instance && name != host {
+			continue
+		}
+		if !ours.conflictsWith(rec) {
+			continue
+		}
+		if name == instance {
+			r.instanceTaken = true
+		} else {
+			r.hostTaken = true
+		}
+		found = true
+	}
+	return found
+}
+
+// rename picks new names for the ones another host claimed: "Name (2)" for
+// the instance and "name-2" for the host, counting up on further conflicts.
+func (r *Responder) rename() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.instanceTaken && !r.hostTaken {
+		return
+	}
+	if r.instanceTaken {
+		r.instanceSuffix = max(r.instanceSuffix, 1) + 1
+		r.svc.Instance = fmt.Sprintf("%s (%d)", r.base.Instance, r.instanceSuffix)
+	}
+	if r.hostTaken {
+		r.hostSuffix = max(r.hostSuffix, 1) + 1
+		r.svc.Host = fmt.Sprintf("%s-%d", r.base.Host, r.hostSuffix)
+	}
+	r.instanceTaken, r.hostTaken = false, false
+
+	log.Warn().
+		Str("instance", r.svc.Instance).
+		Str("host", r.svc.Host).
+		Msg("mDNS name in use by another host, renamed")
+}
+
+// serve answers queries until the connection is closed.
+func (r *Responder) serve(conn *net.UDPConn) {
+	defer r.wg.Done()
+
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Debug().Err(err).Msg("mDNS read failed")
+			continue
+		}
+
+		if r.conflicts(buf[:n]) {
+			r.signalReprobe()
+			continue
+		}
+
+		reply, ok := r.answer(buf[:n])
+		if !ok {
+			continue
+		}
+
+		// Legacy unicast queries (not from port 5353) get a direct reply
+		dst := mdnsGroup
+		if src.Port != mdnsGroup.Port {
+			dst = src
+		}
+		if _, err := conn.WriteToUDP(reply, dst); err != nil {
+			log.Debug().Err(err).Msg("Failed to send mDNS response")
+		}
+	}
+}
+
+// answer builds the response to a query, if any of its questions are ours.
+func (r *Responder) answer(query []byte) ([]byte, bool) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil || header.Response {
+		return nil, false
+	}
+	r.mu.RLock()
+	probing := r.probing
+	r.mu.RUnlock()
+	if probing {
+		return nil, false
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, false
+	}
+
+	all := r.records(recordTTL)
+	var resp recordSet
+	for _, q := range questions {
+		name := strings.ToLower(q.Name.String())
+		for _, rec := range all.answers {
+			if strings.ToLower(rec.Header.Name.String()) != name {
+				continue
+			}
+			if q.Type != dnsmessage.TypeALL && q.Type != rec.Header.Type {
+				continue
+			}
+			resp.answers = append(resp.answers, rec)
+		}
+	}
+	if len(resp.answers) == 0 {
+		return nil, false
+	}
+
+	// Send the records a browser needs next along with the answer
+	for _, rec := range all.answers {
+		if !resp.has(rec) {
+			resp.additionals = append(resp.additionals, rec)
+		}
+	}
+
+	msg, err := resp.pack(header.ID)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to build mDNS response")
+		return nil, false
+	}
+	return msg, true
+}
+
+// recordSet is the answer and additional sections of a response.
+type recordSet struct {
+	answers     []dnsmessage.Resource
+	additionals []dnsmessage.Resource
+}
+
+// has reports whether rec is already an answer.
+func (s recordSet) has(rec dnsmessage.Resource) bool {
+	for _, a := range s.answers {
+		if a.Header.Name == rec.Header.Name && a.Header.Type == rec.Header.Type && a.Body == rec.Body {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictsWith reports whether rec has the name and type of one of our
+// unique records but none of their data. Types we don't publish are left to
+// whoever does, e.g. the system's own responder adding AAAA records.
+func (s recordSet) conflictsWith(rec dnsmessage.Resource) bool {
+	name := strings.ToLower(rec.Header.Name.String())
+	published := false
+	for _, a := range s.answers {
+		if a.Header.Class&cacheFlush == 0 || a.Header.Type != rec.Header.Type ||
+			strings.ToLower(a.Header.Name.String()) != name {
+			continue
+		}
+		if reflect.DeepEqual(a.Body, rec.Body) {
+			return false
+		}
+		published = true
+	}
+	return published
+}
+
+// pack encodes the records as an authoritative response.
+func (s recordSet) pack(id uint16) ([]byte, error) {
+	msg := dnsmessage.Message{
+		Header:      dnsmessage.Header{ID: id, Response: true, Authoritative: true},
+		Answers:     s.answers,
+		Additionals: s.additionals,
+	}
+	return msg.Pack()
+}
+
+// records returns all records for the service with the given TTL. A TTL of
+// zero is a goodbye.
+func (r *Responder) records(ttl uint32) recordSet {
+	r.mu.RLock()
+	svc := r.svc
+	r.mu.RUnlock()
+	return r.recordsFor(svc, ttl)
+}
+
+// instanceName is the fully qualified service instance name.
+func (svc Service) instanceName() string {
+	return svc.Instance + "." + svc.Type + ".local."
+}
+
+// hostName is the fully qualified host name.
+func (svc Service) hostName() string {
+	return svc.Host + ".local."
+}
+
+// recordsFor returns all records for svc with the given TTL.
+func (r *Responder) recordsFor(svc Service, ttl uint32) recordSet {
+	serviceType := svc.Type + ".local."
+	instance := svc.instanceName()
+	host := svc.hostName()
+
+	header := func(name string, typ dnsmessage.Type, unique bool) dnsmessage.ResourceHeader {
+		class := dnsmessage.ClassINET
+		if unique {
+			class |= cacheFlush
+		}
+		return dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  typ,
+			Class: class,
+			TTL:   ttl,
+		}
+	}
+
+	txt := svc.TXT
+	if len(txt) == 0 {
+		txt = []string{""} // A TXT record must have at least one string
+	}
+
+	set := recordSet{answers: []dnsmessage.Resource{
+		{
+			Header: header(serviceType, dnsmessage.TypePTR, false),
+			Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(instance)},
+		},
+		{
+			Header: header(servicesName, dnsmessage.TypePTR, false),
+			Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(serviceType)},
+		},
+		{
+			Header: header(instance, dnsmessage.TypeSRV, true),
+			Body:   &dnsmessage.SRVResource{Target: dnsmessage.MustNewName(host), Port: uint16(svc.Port)},
+		},
+		{
+			Header: header(instance, dnsmessage.TypeTXT, true),
+			Body:   &dnsmessage.TXTResource{TXT: txt},
+		},
+	}}
+
+	for _, ip := range r.addrs() {
+		var a dnsmessage.AResource
+		copy(a.A[:], ip.To4())
+		set.answers = append(set.answers, dnsmessage.Resource{
+			Header: header(host, dnsmessage.TypeA, true),
+			Body:   &a,
+		})
+	}
+	return set
+}
+
+// localIPv4Addrs returns the IPv4 addresses of interfaces that are up,
+// excluding loopback.
+func localIPv4Addrs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				if ip4 := ipNet.IP.To4(); ip4 != nil {
+					ips = append(ips, ip4)
+				}
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,309 @@
+package mdns
+
+import (
+	"net"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func testResponder() *Responder {
+	r := NewResponder(Service{
+		Instance: "stellar.living",
+		Type:     "_stellar._tcp",
+		Host:     "stellar.local",
+		Port:     3001,
+		TXT:      []string{"version=1.0.0"},
+	})
+	r.addrs = func() []net.IP { return []net.IP{net.IPv4(192, 168, 1, 20)} }
+	return r
+}
+
+func buildQuery(t *testing.T, name string, typ dnsmessage.Type) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 7},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  typ,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("pack query: %v", err)
+	}
+	return data
+}
+
+func TestNewResponder_NormalizesNames(t *testing.T) {
+	r := testResponder()
+	if r.svc.Instance != "stellar-living" {
+		t.Errorf("Instance = %q, want stellar-living", r.svc.Instance)
+	}
+	if r.svc.Host != "stellar" {
+		t.Errorf("Host = %q, want stellar", r.svc.Host)
+	}
+}
+
+func TestAnswer_ServicePTRQuery(t *testing.T) {
+	r := testResponder()
+
+	reply, ok := r.answer(buildQuery(t, "_stellar._tcp.local.", dnsmessage.TypePTR))
+	if !ok {
+		t.Fatal("expected a response to our service type")
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(reply); err != nil {
+		t.Fatalf("unpack reply: %v", err)
+	}
+	if !msg.Header.Response || msg.Header.ID != 7 {
+		t.Errorf("header = %+v, want response with ID 7", msg.Header)
+	}
+	if len(msg.Answers) != 1 {
+		t.Fatalf("got %d answers, want 1", len(msg.Answers))
+	}
+	ptr, ok := msg.Answers[0].Body.(*dnsmessage.PTRResource)
+	if !ok || ptr.PTR.String() != "stellar-living._stellar._tcp.local." {
+		t.Errorf("PTR answer = %v", msg.Answers[0].Body)
+	}
+
+	// SRV, TXT and A come along as additionals
+	found := map[dnsmessage.Type]dnsmessage.ResourceBody{}
+	for _, rec := range msg.Additionals {
+		found[rec.Header.Type] = rec.Body
+	}
+	if srv, ok := found[dnsmessage.TypeSRV].(*dnsmessage.SRVResource); !ok || srv.Port != 3001 || srv.Target.String() != "stellar.local." {
+		t.Errorf("SRV additional = %v", found[dnsmessage.TypeSRV])
+	}
+	if txt, ok := found[dnsmessage.TypeTXT].(*dnsmessage.TXTResource); !ok || len(txt.TXT) != 1 || txt.TXT[0] != "version=1.0.0" {
+		t.Errorf("TXT additional = %v", found[dnsmessage.TypeTXT])
+	}
+	if a, ok := found[dnsmessage.TypeA].(*dnsmessage.AResource); !ok || a.A != [4]byte{192, 168, 1, 20} {
+		t.Errorf("A additional = %v", found[dnsmessage.TypeA])
+	}
+}
+
+func TestAnswer_IgnoresOtherNames(t *testing.T) {
+	r := testResponder()
+
+	if _, ok := r.answer(buildQuery(t, "_airplay._tcp.local.", dnsmessage.TypePTR)); ok {
+		t.Error("answered a query for another service")
+	}
+	if _, ok := r.answer(buildQuery(t, "stellar.local.", dnsmessage.TypeAAAA)); ok {
+		t.Error("answered an AAAA query without IPv6 records")
+	}
+}
+
+func TestRecords_Goodbye(t *testing.T) {
+	r := testResponder()
+
+	for _, rec := range r.records(0).answers {
+		if rec.Header.TTL != 0 {
+			t.Errorf("%v record has TTL %d in goodbye, want 0", rec.Header.Type, rec.Header.TTL)
+		}
+	}
+}
+
+func TestUpdate_ReplacesService(t *testing.T) {
+	r := testResponder()
+
+	r.Update(Service{Instance: "kitchen", Type: "_stellar._tcp", Host: "kitchen.local", Port: 3001})
+
+	if _, ok := r.answer(buildQuery(t, "stellar.local.", dnsmessage.TypeA)); ok {
+		t.Error("still answered for the old host")
+	}
+	if _, ok := r.answer(buildQuery(t, "kitchen.local.", dnsmessage.TypeA)); !ok {
+		t.Error("did not answer for the new host")
+	}
+}
+
+func TestUpdate_DuringShutdown(t *testing.T) {
+	r := testResponder()
+	if err := r.Start(); err != nil {
+		t.Skipf("multicast unavailable: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			r.Update(Service{Instance: "kitchen", Type: "_stellar._tcp", Host: "kitchen", Port: 3001})
+		}
+	}()
+	r.Shutdown()
+	<-done
+
+	// Updates after shutdown only change what would be advertised
+	r.Update(Service{Instance: "den", Type: "_stellar._tcp", Host: "den", Port: 3001})
+}
+
+// buildResponse packs records another host might send.
+func buildResponse(t *testing.T, records ...dnsmessage.Resource) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header:  dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: records,
+	}
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("pack response: %v", err)
+	}
+	return data
+}
+
+func resourceHeader(name string, typ dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  typ,
+		Class: dnsmessage.ClassINET | cacheFlush,
+		TTL:   ttl,
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	otherSRV := dnsmessage.Resource{
+		Header: resourceHeader("stellar-living._stellar._tcp.local.", dnsmessage.TypeSRV, recordTTL),
+		Body:   &dnsmessage.SRVResource{Target: dnsmessage.MustNewName("other.local."), Port: 8080},
+	}
+	otherA := dnsmessage.Resource{
+		Header: resourceHeader("stellar.local.", dnsmessage.TypeA, recordTTL),
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 168, 1, 99}},
+	}
+
+	tests := []struct {
+		name         string
+		packet       func(r *Responder) []byte
+		wantConflict bool
+		wantInstance string
+		wantHost     string
+	}{
+		{
+			name: "own records looped back",
+			packet: func(r *Responder) []byte {
+				data, _ := r.records(recordTTL).pack(0)
+				return data
+			},
+			wantInstance: "stellar-living",
+			wantHost:     "stellar",
+		},
+		{
+			name:         "instance taken",
+			packet:       func(*Responder) []byte { return buildResponse(t, otherSRV) },
+			wantConflict: true,
+			wantInstance: "stellar-living (2)",
+			wantHost:     "stellar",
+		},
+		{
+			name:         "host taken",
+			packet:       func(*Responder) []byte { return buildResponse(t, otherA) },
+			wantConflict: true,
+			wantInstance: "stellar-living",
+			wantHost:     "stellar-2",
+		},
+		{
+			name: "goodbye",
+			packet: func(*Responder) []byte {
+				bye := otherSRV
+				bye.Header.TTL = 0
+				return buildResponse(t, bye)
+			},
+			wantInstance: "stellar-living",
+			wantHost:     "stellar",
+		},
+		{
+			name: "unpublished type",
+			packet: func(*Responder) []byte {
+				return buildResponse(t, dnsmessage.Resource{
+					Header: resourceHeader("stellar.local.", dnsmessage.TypeAAAA, recordTTL),
+					Body:   &dnsmessage.AAAAResource{AAAA: [16]byte{0xfe, 0x80, 15: 1}},
+				})
+			},
+			wantInstance: "stellar-living",
+			wantHost:     "stellar",
+		},
+		{
+			name:         "query",
+			packet:       func(*Responder) []byte { return buildQuery(t, "stellar.local.", dnsmessage.TypeA) },
+			wantInstance: "stellar-living",
+			wantHost:     "stellar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testResponder()
+
+			if got := r.conflicts(tt.packet(r)); got != tt.wantConflict {
+				t.Errorf("conflicts = %v, want %v", got, tt.wantConflict)
+			}
+			r.rename()
+			if r.svc.Instance != tt.wantInstance || r.svc.Host != tt.wantHost {
+				t.Errorf("renamed to %q on %q, want %q on %q", r.svc.Instance, r.svc.Host, tt.wantInstance, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestRename_CountsUp(t *testing.T) {
+	r := testResponder()
+
+	for _, want := range []string{"stellar-living (2)", "stellar-living (3)"} {
+		r.instanceTaken = true
+		r.rename()
+		if r.svc.Instance != want {
+			t.Errorf("Instance = %q, want %q", r.svc.Instance, want)
+		}
+	}
+
+	// An update starts again from the requested name
+	r.Update(Service{Instance: "kitchen", Type: "_stellar._tcp", Host: "kitchen", Port: 3001})
+	r.instanceTaken = true
+	r.rename()
+	if r.svc.Instance != "kitchen (2)" {
+		t.Errorf("Instance after update = %q, want kitchen (2)", r.svc.Instance)
+	}
+}
+
+func TestAnswer_SilentWhileProbing(t *testing.T) {
+	r := testResponder()
+	r.probing = true
+
+	if _, ok := r.answer(buildQuery(t, "stellar.local.", dnsmessage.TypeA)); ok {
+		t.Error("answered for a name that is still being probed")
+	}
+}
+
+func TestProbeQuery(t *testing.T) {
+	r := testResponder()
+
+	data, err := r.probeQuery()
+	if err != nil {
+		t.Fatalf("probeQuery: %v", err)
+	}
+	var msg dnsmessage.Message
+	if err := msg.Unpack(data); err != nil {
+		t.Fatalf("unpack probe: %v", err)
+	}
+	if msg.Header.Response {
+		t.Error("probe is a response, want a query")
+	}
+
+	var names []string
+	for _, q := range msg.Questions {
+		if q.Type != dnsmessage.TypeALL {
+			t.Errorf("question %v has type %v, want ANY", q.Name, q.Type)
+		}
+		names = append(names, q.Name.String())
+	}
+	if want := []string{"stellar-living._stellar._tcp.local.", "stellar.local."}; !slices.Equal(names, want) {
+		t.Errorf("questions = %v, want %v", names, want)
+	}
+
+	// The unique records we intend to publish: SRV, TXT and A
+	if len(msg.Authorities) != 3 {
+		t.Errorf("got %d authority records, want 3", len(msg.Authorities))
+	}
+}
@@ -0,0 +1,49 @@
+package mpd
+
+import "testing"
+
+func TestDetectCapabilities_ProtocolVersion(t *testing.T) {
+	host, port := fakeMPD(t, []string{"albumart", "commands", "readpicture", "status"})
+	c := NewClient(host, port, "")
+	defer c.Close()
+
+	flags, err := c.DetectCapabilities()
+	if err != nil {
+		t.Fatalf("DetectCapabilities failed: %v", err)
+	}
+	if flags.ProtocolVersion != "0.23.5" {
+		t.Errorf("ProtocolVersion = %q, want the greeting's 0.23.5", flags.ProtocolVersion)
+	}
+	if !flags.HasReadPicture || !flags.HasAlbumArt {
+		t.Errorf("flags = %+v, want readpicture and albumart", flags)
+	}
+
+	version, err := c.ProtocolVersion()
+	if err != nil || version != "0.23.5" {
+		t.Errorf("ProtocolVersion() = %q, %v; want 0.23.5", version, err)
+	}
+}
+
+func TestProtocolVersionWithoutServer(t *testing.T) {
+	c := NewClient("127.0.0.1", 1, "")
+	if _, err := c.ProtocolVersion(); err == nil {
+		t.Error("ProtocolVersion should fail when MPD is unreachable")
+	}
+}
+
+func TestCapabilities_DetectedOnConnect(t *testing.T) {
+	host, port := fakeMPD(t, []string{"commands", "readpicture"})
+	c := NewClient(host, port, "")
+	defer c.Close()
+
+	if _, ok := c.Capabilities(); ok {
+		t.Error("Capabilities reported as known before connecting")
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	flags, ok := c.Capabilities()
+	if !ok || !flags.HasReadPicture || flags.HasAlbumArt || flags.ProtocolVersion != "0.23.5" {
+		t.Errorf("Capabilities() = %+v, %v; want readpicture only on 0.23.5", flags, ok)
+	}
+}
@@ -2,312 +2,383 @@
 package mpd
 
 import (
+	"errors"
 	"fmt"
+	"path"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fhs/gompd/v2/mpd"
 	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
+)
+
+// Default timeouts; see Client.SetTimeouts.
+const (
+	DefaultConnectTimeout = 5 * time.Second
+	DefaultCommandTimeout = 30 * time.Second // Long enough to list a large library
 )
 
-// Client wraps the MPD client with reconnection logic.
+// DefaultQueryConnections is the default size of the library query pool;
+// see Client.SetQueryConnections.
+const DefaultQueryConnections = 2
+
+// Client wraps the MPD client with reconnection logic. Connecting and each
+// command are bounded by timeouts, so a hung MPD fails with ErrTimeout
+// instead of blocking the caller.
+//
+// Playback and queue commands share one control connection. Library queries
+// (browse, search, album art) use a pool of their own, since MPD answers the
+// commands on a connection one at a time and a search of a large NAS library
+// would otherwise delay a pause. The watcher has its own connection too.
+// Each connection reconnects on its own: the control connection under
+// controlMu, so reopening it doesn't hold up queries, and pooled ones in
+// their slots.
 type Client struct {
-	mu       sync.RWMutex
-	client   *mpd.Client
-	watcher  *mpd.Watcher
-	host     string
-	port     int
-	password string
+	mu             sync.RWMutex // Guards the settings, watcher, pool and capabilities
+	controlMu      sync.Mutex   // Guards conn; held while it is reopened
+	conn           *conn        // Control connection; nil until connected, or after it was dropped
+	pool           *connPool    // Library query connections; nil to use the control connection
+	watcher        *mpd.Watcher
+	host           string
+	port           int
+	password       string
+	connectTimeout time.Duration
+	commandTimeout time.Duration
+	capabilities   *CapabilityFlags // Detected on each connect; nil until connected
 }
 
 // NewClient creates a new MPD client wrapper.
 func NewClient(host string, port int, password string) *Client {
 	return &Client{
-		host:     host,
-		port:     port,
-		password: password,
+		host:           host,
+		port:           port,
+		password:       password,
+		connectTimeout: DefaultConnectTimeout,
+		commandTimeout: DefaultCommandTimeout,
+		pool:           newConnPool(DefaultQueryConnections),
 	}
 }
 
-// Connect establishes connection to MPD.
-func (c *Client) Connect() error {
+// SetQueryConnections sets how many connections library queries may open
+// alongside the control connection. One dedicates a single connection to
+// queries; zero runs them on the control connection. It should be called
+// before the client is used.
+func (c *Client) SetQueryConnections(n int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	return c.connectLocked()
+	if c.pool != nil {
+		c.pool.closeIdle()
+	}
+	c.pool = nil
+	if n > 0 {
+		c.pool = newConnPool(n)
+	}
+}
+
+// querySource returns where library queries get their connections.
+func (c *Client) querySource() connSource {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.pool == nil {
+		return controlSource{c}
+	}
+	return poolSource{c, c.pool}
+}
+
+// SetTimeouts sets how long connecting to MPD, and each command, may take
+// before failing with ErrTimeout. Zero waits indefinitely.
+func (c *Client) SetTimeouts(connect, command time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connectTimeout = connect
+	c.commandTimeout = command
+}
+
+// timeouts returns the connect and command timeouts.
+func (c *Client) timeouts() (connect, command time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connectTimeout, c.commandTimeout
+}
+
+// Connect establishes connection to MPD.
+func (c *Client) Connect() error {
+	c.controlMu.Lock()
+	defer c.controlMu.Unlock()
+
+	_, err := c.connectLocked()
+	return err
 }
 
-// connectLocked establishes connection (must hold lock).
-func (c *Client) connectLocked() error {
+// connectLocked establishes the control connection (must hold controlMu).
+func (c *Client) connectLocked() (*conn, error) {
 	addr := fmt.Sprintf("%s:%d", c.host, c.port)
 	log.Info().Str("addr", addr).Msg("Connecting to MPD")
 
-	client, err := mpd.Dial("tcp", addr)
+	connectTimeout, commandTimeout := c.timeouts()
+
+	cn, err := c.dial(connectTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to connect to MPD: %w", err)
+		return nil, err
 	}
+	client := cn.client
+	log.Info().Str("protocol", client.Version()).Msg("Connected to MPD")
 
-	if c.password != "" {
-		if err := client.Command("password %s", c.password).OK(); err != nil {
-			client.Close()
-			return fmt.Errorf("MPD authentication failed: %w", err)
-		}
+	// A reconnect may reach an upgraded MPD, so capabilities are re-detected
+	capabilities, err := withTimeout(commandTimeout, func() (*CapabilityFlags, error) {
+		cn.mu.RLock()
+		defer cn.mu.RUnlock()
+		return detectCapabilities(client), nil
+	}, nil)
+	if err != nil {
+		cn.close()
+		return nil, fmt.Errorf("failed to detect MPD capabilities: %w", err)
 	}
 
-	c.client = client
-	log.Info().Msg("Connected to MPD")
-	return nil
+	c.mu.Lock()
+	c.capabilities = capabilities
+	c.mu.Unlock()
+
+	c.conn = cn
+	return cn, nil
 }
 
-// ensureConnected checks connection and reconnects if needed.
-func (c *Client) ensureConnected() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// dial opens and authenticates a connection to MPD within timeout.
+func (c *Client) dial(timeout time.Duration) (*conn, error) {
+	return dialConn(fmt.Sprintf("%s:%d", c.host, c.port), c.password, timeout)
+}
+
+// connection returns the control connection, connecting if there is none.
+func (c *Client) connection() (*conn, error) {
+	c.controlMu.Lock()
+	defer c.controlMu.Unlock()
 
-	if c.client == nil {
-		return c.connectLocked()
+	if c.conn != nil {
+		return c.conn, nil
 	}
+	return c.connectLocked()
+}
 
-	// Try a ping to check if connection is alive
-	if err := c.client.Ping(); err != nil {
-		log.Warn().Err(err).Msg("MPD connection lost, reconnecting...")
-		// Close old connection
-		c.client.Close()
-		c.client = nil
-		// Reconnect
-		return c.connectLocked()
+// drop discards a control connection that was lost or timed out, so the
+// next command reconnects. Closing it fails commands still waiting on it.
+func (c *Client) drop(cn *conn) {
+	c.controlMu.Lock()
+	if c.conn == cn {
+		c.conn = nil
 	}
+	c.controlMu.Unlock()
 
-	return nil
+	cn.close()
 }
 
-// Close closes the MPD connection.
+// Close closes the MPD connections.
 func (c *Client) Close() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if c.watcher != nil {
 		c.watcher.Close()
 		c.watcher = nil
 	}
+	if c.pool != nil {
+		c.pool.closeIdle()
+	}
+	c.mu.Unlock()
 
-	if c.client != nil {
-		err := c.client.Close()
-		c.client = nil
-		return err
+	c.controlMu.Lock()
+	cn := c.conn
+	c.conn = nil
+	c.controlMu.Unlock()
+
+	if cn == nil {
+		return nil
 	}
-	return nil
+	return cn.close()
 }
 
-// Ping checks if the connection is alive.
+// Ping checks if the control connection is alive.
 func (c *Client) Ping() error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.controlMu.Lock()
+	cn := c.conn
+	c.controlMu.Unlock()
 
-	if c.client == nil {
+	if cn == nil {
 		return fmt.Errorf("not connected")
 	}
-	return c.client.Ping()
+	_, timeout := c.timeouts()
+	_, err := withTimeout(timeout, func() (struct{}, error) {
+		return struct{}{}, cn.ping()
+	}, nil)
+	if errors.Is(err, ErrTimeout) {
+		c.drop(cn)
+	}
+	return err
 }
 
 // Status returns the current MPD status.
 func (c *Client) Status() (mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Status()
+	return command(c, func(client *mpd.Client) (mpd.Attrs, error) {
+		return client.Status()
+	})
 }
 
 // CurrentSong returns the currently playing song.
 func (c *Client) CurrentSong() (mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.CurrentSong()
+	return command(c, func(client *mpd.Client) (mpd.Attrs, error) {
+		return client.CurrentSong()
+	})
 }
 
 // Play starts playback. If pos is -1, resumes current track.
 func (c *Client) Play(pos int) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if pos < 0 {
-		return c.client.Play(-1)
-	}
-	return c.client.Play(pos)
+	return c.exec(func(client *mpd.Client) error {
+		if pos < 0 {
+			return client.Play(-1)
+		}
+		return client.Play(pos)
+	})
 }
 
 // Pause toggles pause state.
 func (c *Client) Pause(pause bool) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Pause(pause)
+	return c.exec(func(client *mpd.Client) error {
+		return client.Pause(pause)
+	})
 }
 
 // Stop stops playback.
 func (c *Client) Stop() error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Stop()
+	return c.exec(func(client *mpd.Client) error {
+		return client.Stop()
+	})
 }
 
 // Next plays the next song.
 func (c *Client) Next() error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Next()
+	return c.exec(func(client *mpd.Client) error {
+		return client.Next()
+	})
 }
 
 // Previous plays the previous song.
 func (c *Client) Previous() error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Previous()
+	return c.exec(func(client *mpd.Client) error {
+		return client.Previous()
+	})
 }
 
 // Seek seeks to position in current song (seconds).
 func (c *Client) Seek(pos int) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	status, err := c.client.Status()
-	if err != nil {
-		return err
-	}
+	return c.exec(func(client *mpd.Client) error {
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
 
-	songPos, err := strconv.Atoi(status["song"])
-	if err != nil {
-		return fmt.Errorf("no song playing")
-	}
+		songPos, err := strconv.Atoi(status["song"])
+		if err != nil {
+			return fmt.Errorf("no song playing")
+		}
 
-	return c.client.Seek(songPos, pos)
+		return client.Seek(songPos, pos)
+	})
 }
 
 // SetVolume sets the volume (0-100).
 func (c *Client) SetVolume(vol int) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
 	if vol < 0 {
 		vol = 0
 	} else if vol > 100 {
 		vol = 100
 	}
 
-	return c.client.SetVolume(vol)
+	return c.exec(func(client *mpd.Client) error {
+		return client.SetVolume(vol)
+	})
 }
 
 // SetRandom sets random/shuffle mode.
 func (c *Client) SetRandom(on bool) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Random(on)
+	return c.exec(func(client *mpd.Client) error {
+		return client.Random(on)
+	})
 }
 
 // SetRepeat sets repeat mode.
 func (c *Client) SetRepeat(on bool) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Repeat(on)
+	return c.exec(func(client *mpd.Client) error {
+		return client.Repeat(on)
+	})
 }
 
 // SetSingle sets single mode (repeat single song).
 func (c *Client) SetSingle(on bool) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.Single(on)
+	return c.exec(func(client *mpd.Client) error {
+		return client.Single(on)
+	})
 }
 
 // PlaylistInfo returns the current queue.
 func (c *Client) PlaylistInfo() ([]mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.PlaylistInfo(-1, -1)
+	return command(c, func(client *mpd.Client) ([]mpd.Attrs, error) {
+		return client.PlaylistInfo(-1, -1)
+	})
 }
 
 // Clear clears the current queue.
 func (c *Client) Clear() error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	return c.exec(func(client *mpd.Client) error {
+		return client.Clear()
+	})
+}
 
-	return c.client.Clear()
+// Shuffle randomly reorders the whole queue. Unlike random mode the new
+// order is permanent. A playing song is moved to the front and keeps playing.
+func (c *Client) Shuffle() error {
+	return c.exec(func(client *mpd.Client) error {
+		return client.Shuffle(-1, -1)
+	})
 }
 
 // Add adds a URI to the queue.
 func (c *Client) Add(uri string) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
+	return c.exec(func(client *mpd.Client) error {
+		return client.Add(uri)
+	})
+}
+
+// addManyBatchSize caps the number of adds sent in a single command list.
+const addManyBatchSize = 500
+
+// AddMany adds several URIs to the queue in order, batching them into
+// command lists instead of sending one round trip per URI.
+func (c *Client) AddMany(uris []string) error {
+	if len(uris) == 0 {
+		return nil
 	}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	return c.exec(func(client *mpd.Client) error {
+		for start := 0; start < len(uris); start += addManyBatchSize {
+			end := start + addManyBatchSize
+			if end > len(uris) {
+				end = len(uris)
+			}
 
-	return c.client.Add(uri)
+			cmds := client.BeginCommandList()
+			for _, uri := range uris[start:end] {
+				cmds.Add(uri)
+			}
+			if err := cmds.End(); err != nil {
+				return fmt.Errorf("add batch %d-%d: %w", start, end, err)
+			}
+		}
+		return nil
+	})
 }
 
 // Watch starts watching for MPD subsystem changes.
@@ -315,7 +386,11 @@ func (c *Client) Add(uri string) error {
 func (c *Client) Watch(subsystems ...string) (<-chan string, error) {
 	addr := fmt.Sprintf("%s:%d", c.host, c.port)
 
-	watcher, err := mpd.NewWatcher("tcp", addr, c.password, subsystems...)
+	connectTimeout, _ := c.timeouts()
+
+	watcher, err := withTimeout(connectTimeout, func() (*mpd.Watcher, error) {
+		return mpd.NewWatcher("tcp", addr, c.password, subsystems...)
+	}, closeWatcher)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create watcher: %w", err)
 	}
@@ -346,52 +421,37 @@ func (c *Client) Watch(subsystems ...string) (<-chan string, error) {
 	return ch, nil
 }
 
+// closeWatcher closes a watcher that was opened after its caller gave up.
+func closeWatcher(watcher *mpd.Watcher) {
+	watcher.Close()
+}
+
 // ListAllInfo lists all songs in the database.
 func (c *Client) ListAllInfo(uri string) ([]mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.ListAllInfo(uri)
+	return query(c, func(client *mpd.Client) ([]mpd.Attrs, error) {
+		return client.ListAllInfo(uri)
+	})
 }
 
 // ListInfo lists contents of a directory.
 func (c *Client) ListInfo(uri string) ([]mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.ListInfo(uri)
+	return query(c, func(client *mpd.Client) ([]mpd.Attrs, error) {
+		return client.ListInfo(uri)
+	})
 }
 
 // ReadPicture retrieves embedded album art for a song.
 func (c *Client) ReadPicture(uri string) ([]byte, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.ReadPicture(uri)
+	return query(c, func(client *mpd.Client) ([]byte, error) {
+		return client.ReadPicture(uri)
+	})
 }
 
 // AlbumArt retrieves album art from the music directory (cover.jpg, etc).
 func (c *Client) AlbumArt(uri string) ([]byte, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return c.client.AlbumArt(uri)
+	return query(c, func(client *mpd.Client) ([]byte, error) {
+		return client.AlbumArt(uri)
+	})
 }
 
 // CapabilityFlags represents MPD server capabilities.
@@ -423,174 +483,230 @@ type AlbumInfo struct {
 // ListAlbums returns all unique albums from the MPD database grouped by album artist.
 // This uses MPD's "list" command which is much faster than scanning directories.
 func (c *Client) ListAlbums() ([]AlbumInfo, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use "list album group albumartist" to get albums with their artists
-	// AttrsList("Album") tells the parser that each new entry starts with "Album:" key
-	attrs, err := c.client.Command("list album group albumartist").AttrsList("Album")
-	if err != nil {
-		return nil, fmt.Errorf("failed to list albums: %w", err)
-	}
+	return query(c, func(client *mpd.Client) ([]AlbumInfo, error) {
+		// Use "list album group albumartist" to get albums with their artists
+		// AttrsList("Album") tells the parser that each new entry starts with "Album:" key
+		attrs, err := client.Command("list album group albumartist").AttrsList("Album")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list albums: %w", err)
+		}
 
-	var albums []AlbumInfo
-	for _, attr := range attrs {
-		album := attr["Album"]
-		artist := attr["AlbumArtist"]
-		if album != "" {
-			albums = append(albums, AlbumInfo{
-				Album:       album,
-				AlbumArtist: artist,
-			})
+		var albums []AlbumInfo
+		for _, attr := range attrs {
+			album := attr["Album"]
+			artist := attr["AlbumArtist"]
+			if album != "" {
+				albums = append(albums, AlbumInfo{
+					Album:       album,
+					AlbumArtist: artist,
+				})
+			}
 		}
-	}
 
-	return albums, nil
+		return albums, nil
+	})
 }
 
 // FindAlbumTracks finds all tracks for a specific album and optionally album artist.
 // Returns track information including file paths, which can be used to determine source.
 func (c *Client) FindAlbumTracks(album string, albumArtist string) ([]mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
+	return query(c, func(client *mpd.Client) ([]mpd.Attrs, error) {
+		// Build the find command
+		// Format: find album "album name" albumartist "artist name"
+		var cmd *mpd.Command
+		if albumArtist != "" {
+			cmd = client.Command("find album %s albumartist %s", album, albumArtist)
+		} else {
+			cmd = client.Command("find album %s", album)
+		}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+		// AttrsList("file") tells the parser each song starts with "file:" key
+		return cmd.AttrsList("file")
+	})
+}
 
-	// Build the find command
-	// Format: find album "album name" albumartist "artist name"
-	var cmd *mpd.Command
-	if albumArtist != "" {
-		cmd = c.client.Command("find album %s albumartist %s", album, albumArtist)
-	} else {
-		cmd = c.client.Command("find album %s", album)
+// Search returns the songs matching every tag and value pair in terms, e.g.
+// Search("artist", "Miles Davis", "album", "Kind of Blue"). Like MPD's search
+// command, values match case-insensitively anywhere in the tag.
+func (c *Client) Search(terms ...string) ([]mpd.Attrs, error) {
+	if len(terms) == 0 || len(terms)%2 != 0 {
+		return nil, fmt.Errorf("search needs tag and value pairs, got %d terms", len(terms))
 	}
 
-	// AttrsList("file") tells the parser each song starts with "file:" key
-	return cmd.AttrsList("file")
+	format := "search" + strings.Repeat(" %s", len(terms))
+	args := make([]interface{}, len(terms))
+	for i, term := range terms {
+		args[i] = term
+	}
+
+	return query(c, func(client *mpd.Client) ([]mpd.Attrs, error) {
+		return client.Command(format, args...).AttrsList("file")
+	})
 }
 
 // SearchByBase searches for all songs within a specific base path.
 // This is useful for filtering songs by source (e.g., INTERNAL, USB).
 func (c *Client) SearchByBase(basePath string) ([]mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use "search base" to find songs under a path
-	// MPD supports: search base "INTERNAL"
-	// AttrsList("file") tells the parser each song starts with "file:" key
-	return c.client.Command("search base %s", basePath).AttrsList("file")
+	return query(c, func(client *mpd.Client) ([]mpd.Attrs, error) {
+		// Use "search base" to find songs under a path
+		// MPD supports: search base "INTERNAL"
+		// AttrsList("file") tells the parser each song starts with "file:" key
+		return client.Command("search base %s", basePath).AttrsList("file")
+	})
 }
 
 // ListAlbumsInBase returns unique albums that have tracks in the specified base path.
 // This combines "list album" filtering with base path checking.
 func (c *Client) ListAlbumsInBase(basePath string) ([]AlbumInfo, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use search base to get all songs in the path, then extract unique albums
-	// AttrsList("file") tells the parser each song starts with "file:" key
-	songs, err := c.client.Command("search base %s", basePath).AttrsList("file")
-	if err != nil {
-		return nil, fmt.Errorf("failed to search base %s: %w", basePath, err)
-	}
+	return query(c, func(client *mpd.Client) ([]AlbumInfo, error) {
+		// Use search base to get all songs in the path, then extract unique albums
+		// AttrsList("file") tells the parser each song starts with "file:" key
+		songs, err := client.Command("search base %s", basePath).AttrsList("file")
+		if err != nil {
+			return nil, fmt.Errorf("failed to search base %s: %w", basePath, err)
+		}
 
-	// Extract unique album/artist combinations
-	seen := make(map[string]bool)
-	var albums []AlbumInfo
+		// Extract unique album/artist combinations
+		seen := make(map[string]bool)
+		var albums []AlbumInfo
 
-	for _, song := range songs {
-		album := song["Album"]
-		artist := song["AlbumArtist"]
-		if artist == "" {
-			artist = song["Artist"]
-		}
+		for _, song := range songs {
+			album := song["Album"]
+			artist := song["AlbumArtist"]
+			if artist == "" {
+				artist = song["Artist"]
+			}
 
-		// Skip songs without album tag
-		if album == "" {
-			continue
-		}
+			// Skip songs without album tag
+			if album == "" {
+				continue
+			}
 
-		key := album + "\x00" + artist
-		if !seen[key] {
-			seen[key] = true
-			albums = append(albums, AlbumInfo{
-				Album:       album,
-				AlbumArtist: artist,
-			})
+			key := album + "\x00" + artist
+			if !seen[key] {
+				seen[key] = true
+				albums = append(albums, AlbumInfo{
+					Album:       album,
+					AlbumArtist: artist,
+				})
+			}
 		}
-	}
 
-	return albums, nil
+		return albums, nil
+	})
 }
 
 // GetAlbumDetails returns detailed information about an album including track count
 // and a representative track path (for album art and source detection).
 type AlbumDetails struct {
-	Album       string
-	AlbumArtist string
-	TrackCount  int
-	FirstTrack  string // Path to first track (for album art)
-	TotalTime   int    // Total duration in seconds
+	Album          string
+	AlbumArtist    string
+	ArtistInferred bool     // No AlbumArtist tag; AlbumArtist was derived from the track artists
+	TrackArtists   []string // Distinct Artist tags of the tracks, sorted
+	TrackCount     int
+	FirstTrack     string    // Path to first track (for album art)
+	TotalTime      int       // Total duration in seconds
+	AddedAt        time.Time // When the newest track was added (zero if unknown)
+	Year           int       // Release year from the Date tag (0 if unknown)
+	IsCompilation  bool      // Various-artists album (Compilation tag or differing track artists)
 }
 
 // GetAlbumDetails retrieves detailed information for albums within a base path.
 func (c *Client) GetAlbumDetails(basePath string) ([]AlbumDetails, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
+	return query(c, func(client *mpd.Client) ([]AlbumDetails, error) {
+		// Get all songs in the base path
+		// AttrsList("file") tells the parser each song starts with "file:" key
+		songs, err := client.Command("search base %s", basePath).AttrsList("file")
+		if err != nil {
+			return nil, fmt.Errorf("failed to search base %s: %w", basePath, err)
+		}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+		return GroupAlbumDetails(songs), nil
+	})
+}
 
-	// Get all songs in the base path
-	// AttrsList("file") tells the parser each song starts with "file:" key
-	songs, err := c.client.Command("search base %s", basePath).AttrsList("file")
-	if err != nil {
-		return nil, fmt.Errorf("failed to search base %s: %w", basePath, err)
+// albumGroup collects the songs of one album while grouping.
+type albumGroup struct {
+	details     AlbumDetails
+	artists     map[string]bool // Distinct track artists
+	primaries   map[string]bool // Distinct track artists without featured guests
+	compilation bool            // Any track has the Compilation tag set
+}
+
+// featuringSeparators introduce guest artists in an Artist tag.
+var featuringSeparators = []string{" feat. ", " feat ", " ft. ", " ft ", " featuring "}
+
+// PrimaryArtist returns artist without any featured guests, so
+// "Singer feat. Guest" becomes "Singer".
+func PrimaryArtist(artist string) string {
+	lower := strings.ToLower(artist)
+	for _, sep := range featuringSeparators {
+		if i := strings.Index(lower, sep); i > 0 {
+			artist, lower = artist[:i], lower[:i]
+		}
 	}
+	return strings.TrimSpace(artist)
+}
 
-	// Group songs by album
-	albumMap := make(map[string]*AlbumDetails)
+// GroupAlbumDetails groups songs into albums. Songs with an AlbumArtist tag are
+// grouped by album and album artist, whatever the track artists. Songs without
+// one are grouped by album and directory and the album artist is inferred
+// from the track artists with featured guests removed: one artist is used as
+// is, while several, or the Compilation tag, put the album under
+// cache.VariousArtists with IsCompilation set. Inferred artists are marked with
+// ArtistInferred.
+func GroupAlbumDetails(songs []mpd.Attrs) []AlbumDetails {
+	groups := make(map[string]*albumGroup)
+	var order []string
 
 	for _, song := range songs {
 		album := song["Album"]
-		artist := song["AlbumArtist"]
-		if artist == "" {
-			artist = song["Artist"]
-		}
 
 		// Skip songs without album tag
 		if album == "" {
 			continue
 		}
 
-		key := album + "\x00" + artist
+		albumArtist := song["AlbumArtist"]
+		key := album + "\x00" + albumArtist
+		if albumArtist == "" {
+			key = album + "\x00\x00" + path.Dir(song["file"])
+		}
 
-		if _, exists := albumMap[key]; !exists {
-			albumMap[key] = &AlbumDetails{
-				Album:       album,
-				AlbumArtist: artist,
-				FirstTrack:  song["file"],
+		group, exists := groups[key]
+		if !exists {
+			group = &albumGroup{
+				details: AlbumDetails{
+					Album:       album,
+					AlbumArtist: albumArtist,
+					FirstTrack:  song["file"],
+				},
+				artists:   make(map[string]bool),
+				primaries: make(map[string]bool),
 			}
+			groups[key] = group
+			order = append(order, key)
 		}
 
-		details := albumMap[key]
+		details := &group.details
 		details.TrackCount++
 
+		if artist := strings.TrimSpace(song["Artist"]); artist != "" {
+			group.artists[artist] = true
+			group.primaries[PrimaryArtist(artist)] = true
+		}
+		if song["Compilation"] == "1" {
+			group.compilation = true
+		}
+
+		if added := cache.TrackAddedAt(song); added.After(details.AddedAt) {
+			details.AddedAt = added
+		}
+
+		if details.Year == 0 {
+			details.Year = SongYear(song)
+		}
+
 		// Parse duration
 		if dur, err := strconv.Atoi(song["Time"]); err == nil {
 			details.TotalTime += dur
@@ -599,189 +715,286 @@ func (c *Client) GetAlbumDetails(basePath string) ([]AlbumDetails, error) {
 		}
 	}
 
-	// Convert map to slice
+	// Resolve album artists, then merge groups that ended up with the same
+	// album and artist (e.g. one album spread over several directories)
+	merged := make(map[string]*AlbumDetails)
 	var albums []AlbumDetails
-	for _, details := range albumMap {
-		albums = append(albums, *details)
+	var mergedOrder []string
+
+	for _, key := range order {
+		group := groups[key]
+		details := group.details
+
+		details.TrackArtists = sortedKeys(group.artists)
+		if details.AlbumArtist == "" {
+			details.ArtistInferred = true
+			if group.compilation || len(group.primaries) > 1 {
+				details.AlbumArtist = cache.VariousArtists
+			} else {
+				for artist := range group.primaries {
+					details.AlbumArtist = artist
+				}
+			}
+		}
+		details.IsCompilation = group.compilation || strings.EqualFold(details.AlbumArtist, cache.VariousArtists)
+
+		albumKey := details.Album + "\x00" + details.AlbumArtist
+		existing, exists := merged[albumKey]
+		if !exists {
+			merged[albumKey] = &details
+			mergedOrder = append(mergedOrder, albumKey)
+			continue
+		}
+
+		existing.TrackCount += details.TrackCount
+		existing.TotalTime += details.TotalTime
+		existing.IsCompilation = existing.IsCompilation || details.IsCompilation
+		existing.ArtistInferred = existing.ArtistInferred && details.ArtistInferred
+		existing.TrackArtists = mergeSorted(existing.TrackArtists, details.TrackArtists)
+		if details.AddedAt.After(existing.AddedAt) {
+			existing.AddedAt = details.AddedAt
+		}
+		if existing.Year == 0 {
+			existing.Year = details.Year
+		}
+	}
+
+	for _, key := range mergedOrder {
+		albums = append(albums, *merged[key])
 	}
 
-	return albums, nil
+	return albums
 }
 
-// ListArtists returns all unique album artists from the MPD database.
-func (c *Client) ListArtists() ([]string, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
+// sortedKeys returns the keys of set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
 	}
+	slices.Sort(keys)
+	return keys
+}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// mergeSorted returns the sorted union of two sorted slices.
+func mergeSorted(a, b []string) []string {
+	merged := slices.Concat(a, b)
+	slices.Sort(merged)
+	return slices.Compact(merged)
+}
 
-	// Use "list albumartist" to get all unique album artists
-	// AttrsList("AlbumArtist") tells the parser each entry starts with "AlbumArtist:" key
-	attrs, err := c.client.Command("list albumartist").AttrsList("AlbumArtist")
+// SongYear returns the release year from a song's Date tag, which MPD reports
+// as "1977" or "1977-02-04". It returns 0 if the tag is missing or malformed.
+func SongYear(song map[string]string) int {
+	date := song["Date"]
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
 	if err != nil {
-		return nil, fmt.Errorf("failed to list artists: %w", err)
+		return 0
 	}
+	return year
+}
 
-	var artists []string
-	for _, attr := range attrs {
-		artist := attr["AlbumArtist"]
-		if artist != "" {
-			artists = append(artists, artist)
+// ListRecentlyAdded returns the URIs of the newest songs under basePath, newest first.
+// It sorts by the "added" timestamp (MPD 0.24+) and falls back to the file
+// modification time on servers that do not track it.
+func (c *Client) ListRecentlyAdded(basePath string, limit int) ([]string, error) {
+	return query(c, func(client *mpd.Client) ([]string, error) {
+		songs, err := client.Command("search base %s sort -added window 0:%d", basePath, limit).AttrsList("file")
+		if err != nil {
+			songs, err = client.Command("search base %s sort -Last-Modified window 0:%d", basePath, limit).AttrsList("file")
+			if err != nil {
+				return nil, fmt.Errorf("failed to list recently added in %s: %w", basePath, err)
+			}
+		}
+
+		uris := make([]string, 0, len(songs))
+		for _, song := range songs {
+			if file := song["file"]; file != "" {
+				uris = append(uris, file)
+			}
+		}
+
+		return uris, nil
+	})
+}
+
+// ListArtists returns all unique album artists from the MPD database.
+func (c *Client) ListArtists() ([]string, error) {
+	return query(c, func(client *mpd.Client) ([]string, error) {
+		// Use "list albumartist" to get all unique album artists
+		// AttrsList("AlbumArtist") tells the parser each entry starts with "AlbumArtist:" key
+		attrs, err := client.Command("list albumartist").AttrsList("AlbumArtist")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artists: %w", err)
+		}
+
+		var artists []string
+		for _, attr := range attrs {
+			artist := attr["AlbumArtist"]
+			if artist != "" {
+				artists = append(artists, artist)
+			}
 		}
-	}
 
-	return artists, nil
+		return artists, nil
+	})
 }
 
 // FindAlbumsByArtist finds all albums by a specific album artist.
 func (c *Client) FindAlbumsByArtist(artist string) ([]AlbumInfo, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
+	return query(c, func(client *mpd.Client) ([]AlbumInfo, error) {
+		// Use "list album albumartist X" to get albums by artist
+		attrs, err := client.Command("list album albumartist %s", artist).AttrsList("Album")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find albums by artist: %w", err)
+		}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+		var albums []AlbumInfo
+		for _, attr := range attrs {
+			album := attr["Album"]
+			if album != "" {
+				albums = append(albums, AlbumInfo{
+					Album:       album,
+					AlbumArtist: artist,
+				})
+			}
+		}
 
-	// Use "list album albumartist X" to get albums by artist
-	attrs, err := c.client.Command("list album albumartist %s", artist).AttrsList("Album")
-	if err != nil {
-		return nil, fmt.Errorf("failed to find albums by artist: %w", err)
-	}
+		return albums, nil
+	})
+}
 
-	var albums []AlbumInfo
-	for _, attr := range attrs {
-		album := attr["Album"]
-		if album != "" {
-			albums = append(albums, AlbumInfo{
-				Album:       album,
-				AlbumArtist: artist,
-			})
+// ListGenres returns all unique genres from the MPD database.
+func (c *Client) ListGenres() ([]string, error) {
+	return query(c, func(client *mpd.Client) ([]string, error) {
+		// Use "list genre" to get all unique genres
+		attrs, err := client.Command("list genre").AttrsList("Genre")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list genres: %w", err)
 		}
-	}
 
-	return albums, nil
+		var genres []string
+		for _, attr := range attrs {
+			genre := attr["Genre"]
+			if genre != "" {
+				genres = append(genres, genre)
+			}
+		}
+
+		return genres, nil
+	})
 }
 
-// ListPlaylists returns all saved playlists.
-func (c *Client) ListPlaylists() ([]string, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
+// FindAlbumsByGenre finds all albums tagged with a specific genre.
+func (c *Client) FindAlbumsByGenre(genre string) ([]AlbumInfo, error) {
+	return query(c, func(client *mpd.Client) ([]AlbumInfo, error) {
+		// Use "list album genre X group albumartist" to get albums with their artists
+		attrs, err := client.Command("list album genre %s group albumartist", genre).AttrsList("Album")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find albums by genre: %w", err)
+		}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+		var albums []AlbumInfo
+		for _, attr := range attrs {
+			album := attr["Album"]
+			if album != "" {
+				albums = append(albums, AlbumInfo{
+					Album:       album,
+					AlbumArtist: attr["AlbumArtist"],
+				})
+			}
+		}
 
-	// Use "listplaylists" to get all saved playlists
-	attrs, err := c.client.Command("listplaylists").AttrsList("playlist")
-	if err != nil {
-		return nil, fmt.Errorf("failed to list playlists: %w", err)
-	}
+		return albums, nil
+	})
+}
 
-	var playlists []string
-	for _, attr := range attrs {
-		playlist := attr["playlist"]
-		if playlist != "" {
-			playlists = append(playlists, playlist)
+// ListPlaylists returns all saved playlists.
+func (c *Client) ListPlaylists() ([]string, error) {
+	return query(c, func(client *mpd.Client) ([]string, error) {
+		// Use "listplaylists" to get all saved playlists
+		attrs, err := client.Command("listplaylists").AttrsList("playlist")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list playlists: %w", err)
+		}
+
+		var playlists []string
+		for _, attr := range attrs {
+			playlist := attr["playlist"]
+			if playlist != "" {
+				playlists = append(playlists, playlist)
+			}
 		}
-	}
 
-	return playlists, nil
+		return playlists, nil
+	})
 }
 
 // ListPlaylistInfo returns the contents of a specific playlist.
 func (c *Client) ListPlaylistInfo(name string) ([]mpd.Attrs, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use "listplaylistinfo" to get playlist contents
-	return c.client.Command("listplaylistinfo %s", name).AttrsList("file")
+	return query(c, func(client *mpd.Client) ([]mpd.Attrs, error) {
+		// Use "listplaylistinfo" to get playlist contents
+		return client.Command("listplaylistinfo %s", name).AttrsList("file")
+	})
 }
 
 // SavePlaylist saves the current queue as a new playlist.
 func (c *Client) SavePlaylist(name string) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Use "save" to save current queue as playlist
-	return c.client.Command("save %s", name).OK()
+	return c.execExclusive(func(client *mpd.Client) error {
+		// Use "save" to save current queue as playlist
+		return client.Command("save %s", name).OK()
+	})
 }
 
 // DeletePlaylist removes a saved playlist.
 func (c *Client) DeletePlaylist(name string) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Use "rm" to delete playlist
-	return c.client.Command("rm %s", name).OK()
+	return c.execExclusive(func(client *mpd.Client) error {
+		// Use "rm" to delete playlist
+		return client.Command("rm %s", name).OK()
+	})
 }
 
 // LoadPlaylist loads a playlist into the queue and optionally plays it.
 func (c *Client) LoadPlaylist(name string, play bool) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Clear the queue first
-	if err := c.client.Clear(); err != nil {
-		return fmt.Errorf("failed to clear queue: %w", err)
-	}
+	return c.execExclusive(func(client *mpd.Client) error {
+		// Clear the queue first
+		if err := client.Clear(); err != nil {
+			return fmt.Errorf("failed to clear queue: %w", err)
+		}
 
-	// Load the playlist
-	if err := c.client.Command("load %s", name).OK(); err != nil {
-		return fmt.Errorf("failed to load playlist: %w", err)
-	}
+		// Load the playlist
+		if err := client.Command("load %s", name).OK(); err != nil {
+			return fmt.Errorf("failed to load playlist: %w", err)
+		}
 
-	// Start playback if requested
-	if play {
-		if err := c.client.Play(0); err != nil {
-			return fmt.Errorf("failed to start playback: %w", err)
+		// Start playback if requested
+		if play {
+			if err := client.Play(0); err != nil {
+				return fmt.Errorf("failed to start playback: %w", err)
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // PlaylistAdd adds a URI to a saved playlist.
 func (c *Client) PlaylistAdd(playlistName, uri string) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Use "playlistadd" to add song to playlist
-	return c.client.Command("playlistadd %s %s", playlistName, uri).OK()
+	return c.execExclusive(func(client *mpd.Client) error {
+		// Use "playlistadd" to add song to playlist
+		return client.Command("playlistadd %s %s", playlistName, uri).OK()
+	})
 }
 
 // PlaylistDelete removes a song at position from a saved playlist.
 func (c *Client) PlaylistDelete(playlistName string, pos int) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Use "playlistdelete" to remove song from playlist
-	return c.client.Command("playlistdelete %s %d", playlistName, pos).OK()
+	return c.execExclusive(func(client *mpd.Client) error {
+		// Use "playlistdelete" to remove song from playlist
+		return client.Command("playlistdelete %s %d", playlistName, pos).OK()
+	})
 }
 
 // FindSongInPlaylist finds the position of a URI in a playlist, returns -1 if not found.
@@ -799,32 +1012,147 @@ func (c *Client) FindSongInPlaylist(playlistName, uri string) (int, error) {
 	return -1, nil
 }
 
-// DetectCapabilities detects what features the MPD server supports.
-// This queries the server for available commands and protocol version.
+// ErrStickersDisabled is returned by the sticker methods when MPD was built
+// or configured without a sticker database.
+var ErrStickersDisabled = errors.New("mpd sticker database is disabled")
+
+// stickerError wraps errors that mean stickers are unavailable in
+// ErrStickersDisabled so callers can fall back to their own storage.
+func stickerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "sticker database is disabled") ||
+		strings.Contains(msg, "no sticker database") ||
+		strings.Contains(msg, `unknown command "sticker"`) {
+		return fmt.Errorf("%w: %v", ErrStickersDisabled, err)
+	}
+	return err
+}
+
+// isNoSuchSticker reports whether MPD answered that a sticker does not exist.
+func isNoSuchSticker(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "no such sticker")
+}
+
+// SetSticker attaches a named value to a song.
+func (c *Client) SetSticker(uri, name, value string) error {
+	return c.exec(func(client *mpd.Client) error {
+		return stickerError(client.StickerSet(uri, name, value))
+	})
+}
+
+// GetSticker returns a named value attached to a song, or an empty string if
+// the song has no such sticker.
+func (c *Client) GetSticker(uri, name string) (string, error) {
+	return command(c, func(client *mpd.Client) (string, error) {
+		sticker, err := client.StickerGet(uri, name)
+		if isNoSuchSticker(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", stickerError(err)
+		}
+		return sticker.Value, nil
+	})
+}
+
+// DeleteSticker removes a named value from a song. Deleting a sticker that
+// does not exist is not an error.
+func (c *Client) DeleteSticker(uri, name string) error {
+	return c.exec(func(client *mpd.Client) error {
+		err := client.StickerDelete(uri, name)
+		if isNoSuchSticker(err) {
+			return nil
+		}
+		return stickerError(err)
+	})
+}
+
+// ListStickers returns all stickers attached to a song, keyed by name.
+func (c *Client) ListStickers(uri string) (map[string]string, error) {
+	return command(c, func(client *mpd.Client) (map[string]string, error) {
+		stickers, err := client.StickerList(uri)
+		if err != nil {
+			return nil, stickerError(err)
+		}
+
+		result := make(map[string]string, len(stickers))
+		for _, sticker := range stickers {
+			result[sticker.Name] = sticker.Value
+		}
+		return result, nil
+	})
+}
+
+// FindStickers returns the value of a named sticker for every song that has
+// it, keyed by song URI.
+func (c *Client) FindStickers(name string) (map[string]string, error) {
+	return query(c, func(client *mpd.Client) (map[string]string, error) {
+		uris, stickers, err := client.StickerFind("", name)
+		if err != nil {
+			return nil, stickerError(err)
+		}
+
+		result := make(map[string]string, len(uris))
+		for i, uri := range uris {
+			result[uri] = stickers[i].Value
+		}
+		return result, nil
+	})
+}
+
+// ProtocolVersion returns the protocol version MPD announced when the
+// connection was opened, e.g. "0.24.0".
+func (c *Client) ProtocolVersion() (string, error) {
+	return command(c, func(client *mpd.Client) (string, error) {
+		return client.Version(), nil
+	})
+}
+
+// DetectCapabilities detects what features the MPD server supports and
+// refreshes the flags returned by Capabilities.
 func (c *Client) DetectCapabilities() (*CapabilityFlags, error) {
-	if err := c.ensureConnected(); err != nil {
+	flags, err := call(c, controlSource{c}, true, func(client *mpd.Client) (*CapabilityFlags, error) {
+		return detectCapabilities(client), nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	c.mu.Lock()
+	c.capabilities = flags
+	c.mu.Unlock()
+
+	copied := *flags
+	return &copied, nil
+}
+
+// Capabilities returns the capabilities detected when the connection was
+// opened. ok is false if MPD hasn't been reached yet.
+func (c *Client) Capabilities() (flags CapabilityFlags, ok bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	flags := &CapabilityFlags{}
-
-	// Get protocol version from status
-	status, err := c.client.Status()
-	if err == nil {
-		// Protocol version isn't in status, we need to parse from initial connection
-		// For now, we'll detect capabilities by trying commands
+	if c.capabilities == nil {
+		return CapabilityFlags{}, false
 	}
-	_ = status // Avoid unused variable warning
+	return *c.capabilities, true
+}
+
+// detectCapabilities queries the server for available commands and protocol
+// version, probing features that have no command of their own by trial.
+func detectCapabilities(client *mpd.Client) *CapabilityFlags {
+	// The protocol version comes from the "OK MPD x.y.z" greeting
+	flags := &CapabilityFlags{ProtocolVersion: client.Version()}
 
 	// Get list of available commands
 	// The "commands" command returns all available commands
-	attrs, err := c.client.Command("commands").AttrsList("command")
+	attrs, err := client.Command("commands").AttrsList("command")
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to get MPD commands list, assuming basic capabilities")
-		return flags, nil
+		return flags
 	}
 
 	// Check for specific commands
@@ -840,25 +1168,26 @@ func (c *Client) DetectCapabilities() (*CapabilityFlags, error) {
 
 	// Test if "list" command supports grouping by trying a harmless command
 	// If grouping works, we have MPD 0.21+
-	_, err = c.client.Command("list album group albumartist window 0:1").AttrsList("Album")
+	_, err = client.Command("list album group albumartist window 0:1").AttrsList("Album")
 	if err == nil {
 		flags.HasGrouping = true
 	}
 
 	// Test for "added" tag support (MPD 0.24+) by checking if sort by added works
-	_, err = c.client.Command("search any '' sort added window 0:1").AttrsList("file")
+	_, err = client.Command("search any '' sort added window 0:1").AttrsList("file")
 	if err == nil {
 		flags.HasAddedTag = true
 	}
 
 	log.Info().
+		Str("protocol", flags.ProtocolVersion).
 		Bool("readpicture", flags.HasReadPicture).
 		Bool("albumart", flags.HasAlbumArt).
 		Bool("grouping", flags.HasGrouping).
 		Bool("added_tag", flags.HasAddedTag).
 		Msg("Detected MPD capabilities")
 
-	return flags, nil
+	return flags
 }
 
 // WatchDatabase starts watching for MPD database changes.
@@ -871,147 +1200,117 @@ func (c *Client) WatchDatabase() (<-chan string, error) {
 
 // GetDatabaseStats returns statistics about the MPD database.
 func (c *Client) GetDatabaseStats() (*DatabaseStats, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use "stats" command to get database statistics
-	attrs, err := c.client.Command("stats").Attrs()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get stats: %w", err)
-	}
+	return query(c, func(client *mpd.Client) (*DatabaseStats, error) {
+		// Use "stats" command to get database statistics
+		attrs, err := client.Command("stats").Attrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats: %w", err)
+		}
 
-	stats := &DatabaseStats{}
+		stats := &DatabaseStats{}
 
-	if v, err := strconv.Atoi(attrs["artists"]); err == nil {
-		stats.Artists = v
-	}
-	if v, err := strconv.Atoi(attrs["albums"]); err == nil {
-		stats.Albums = v
-	}
-	if v, err := strconv.Atoi(attrs["songs"]); err == nil {
-		stats.Songs = v
-	}
-	if v, err := strconv.Atoi(attrs["uptime"]); err == nil {
-		stats.Uptime = v
-	}
-	if v, err := strconv.Atoi(attrs["db_playtime"]); err == nil {
-		stats.DbPlaytime = v
-	}
-	if v, err := strconv.Atoi(attrs["db_update"]); err == nil {
-		stats.DbUpdate = v
-	}
-	if v, err := strconv.Atoi(attrs["playtime"]); err == nil {
-		stats.PlayTime = v
-	}
+		if v, err := strconv.Atoi(attrs["artists"]); err == nil {
+			stats.Artists = v
+		}
+		if v, err := strconv.Atoi(attrs["albums"]); err == nil {
+			stats.Albums = v
+		}
+		if v, err := strconv.Atoi(attrs["songs"]); err == nil {
+			stats.Songs = v
+		}
+		if v, err := strconv.Atoi(attrs["uptime"]); err == nil {
+			stats.Uptime = v
+		}
+		if v, err := strconv.Atoi(attrs["db_playtime"]); err == nil {
+			stats.DbPlaytime = v
+		}
+		if v, err := strconv.Atoi(attrs["db_update"]); err == nil {
+			stats.DbUpdate = v
+		}
+		if v, err := strconv.Atoi(attrs["playtime"]); err == nil {
+			stats.PlayTime = v
+		}
 
-	return stats, nil
+		return stats, nil
+	})
 }
 
 // CountAlbums returns the total count of unique albums in the database.
 // This is more efficient than fetching all albums when only count is needed.
 func (c *Client) CountAlbums() (int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return 0, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use "list album" and count results (more accurate than stats which might be cached)
-	attrs, err := c.client.Command("list album").AttrsList("Album")
-	if err != nil {
-		return 0, fmt.Errorf("failed to count albums: %w", err)
-	}
+	return query(c, func(client *mpd.Client) (int, error) {
+		// Use "list album" and count results (more accurate than stats which might be cached)
+		attrs, err := client.Command("list album").AttrsList("Album")
+		if err != nil {
+			return 0, fmt.Errorf("failed to count albums: %w", err)
+		}
 
-	return len(attrs), nil
+		return len(attrs), nil
+	})
 }
 
 // CountArtists returns the total count of unique album artists in the database.
 // This is more efficient than fetching all artists when only count is needed.
 func (c *Client) CountArtists() (int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return 0, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use "list albumartist" and count results
-	attrs, err := c.client.Command("list albumartist").AttrsList("AlbumArtist")
-	if err != nil {
-		return 0, fmt.Errorf("failed to count artists: %w", err)
-	}
+	return query(c, func(client *mpd.Client) (int, error) {
+		// Use "list albumartist" and count results
+		attrs, err := client.Command("list albumartist").AttrsList("AlbumArtist")
+		if err != nil {
+			return 0, fmt.Errorf("failed to count artists: %w", err)
+		}
 
-	return len(attrs), nil
+		return len(attrs), nil
+	})
 }
 
 // CountAlbumsForArtist returns the count of albums by a specific artist.
 // This is more efficient than the N+1 query pattern.
 func (c *Client) CountAlbumsForArtist(artist string) (int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return 0, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	attrs, err := c.client.Command("list album albumartist %s", artist).AttrsList("Album")
-	if err != nil {
-		return 0, fmt.Errorf("failed to count albums for artist: %w", err)
-	}
+	return query(c, func(client *mpd.Client) (int, error) {
+		attrs, err := client.Command("list album albumartist %s", artist).AttrsList("Album")
+		if err != nil {
+			return 0, fmt.Errorf("failed to count albums for artist: %w", err)
+		}
 
-	return len(attrs), nil
+		return len(attrs), nil
+	})
 }
 
 // GetArtistsWithAlbumCounts returns all artists with their album counts efficiently.
 // This avoids the N+1 query problem by using MPD's grouping feature.
 func (c *Client) GetArtistsWithAlbumCounts() (map[string]int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return nil, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Get all albums grouped by artist
-	attrs, err := c.client.Command("list album group albumartist").AttrsList("Album")
-	if err != nil {
-		return nil, fmt.Errorf("failed to list albums with artists: %w", err)
-	}
+	return query(c, func(client *mpd.Client) (map[string]int, error) {
+		// Get all albums grouped by artist
+		attrs, err := client.Command("list album group albumartist").AttrsList("Album")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list albums with artists: %w", err)
+		}
 
-	// Count albums per artist
-	counts := make(map[string]int)
-	for _, attr := range attrs {
-		artist := attr["AlbumArtist"]
-		if artist != "" {
-			counts[artist]++
+		// Count albums per artist
+		counts := make(map[string]int)
+		for _, attr := range attrs {
+			artist := attr["AlbumArtist"]
+			if artist != "" {
+				counts[artist]++
+			}
 		}
-	}
 
-	return counts, nil
+		return counts, nil
+	})
 }
 
 // Update initiates a database update (rescan) for the music directory.
 // If uri is empty, it updates the entire database.
 // Returns the job ID for the update.
 func (c *Client) Update(uri string) (int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return 0, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	jobID, err := c.client.Update(uri)
-	if err != nil {
-		return 0, fmt.Errorf("failed to update database: %w", err)
-	}
+	return command(c, func(client *mpd.Client) (int, error) {
+		jobID, err := client.Update(uri)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update database: %w", err)
+		}
 
-	return jobID, nil
+		return jobID, nil
+	})
 }
 
 // ============================================================
@@ -1022,118 +1321,117 @@ func (c *Client) Update(uri string) (int, error) {
 // If position is -1, adds to the end of the queue.
 // If position >= 0, inserts at that position.
 func (c *Client) AddId(uri string, position int) (int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return 0, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// Use addid command which returns the song ID
-	var attrs mpd.Attrs
-	var err error
-
-	if position >= 0 {
-		attrs, err = c.client.Command("addid %s %d", uri, position).Attrs()
-	} else {
-		attrs, err = c.client.Command("addid %s", uri).Attrs()
-	}
+	return command(c, func(client *mpd.Client) (int, error) {
+		// Use addid command which returns the song ID
+		var attrs mpd.Attrs
+		var err error
+
+		if position >= 0 {
+			attrs, err = client.Command("addid %s %d", uri, position).Attrs()
+		} else {
+			attrs, err = client.Command("addid %s", uri).Attrs()
+		}
 
-	if err != nil {
-		return 0, fmt.Errorf("failed to add song: %w", err)
-	}
+		if err != nil {
+			return 0, fmt.Errorf("failed to add song: %w", err)
+		}
 
-	// Parse the returned ID
-	idStr := attrs["Id"]
-	if idStr == "" {
-		return 0, fmt.Errorf("no song ID returned")
-	}
+		// Parse the returned ID
+		idStr := attrs["Id"]
+		if idStr == "" {
+			return 0, fmt.Errorf("no song ID returned")
+		}
 
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		return 0, fmt.Errorf("invalid song ID: %w", err)
-	}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid song ID: %w", err)
+		}
 
-	return id, nil
+		return id, nil
+	})
 }
 
 // Move moves a song in the queue from one position to another.
 // Note: gompd's Move function takes (start, end, to) for range moves.
 // We use (from, from+1, to) to move a single song from position 'from' to 'to'.
 func (c *Client) Move(from, to int) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
+	return c.exec(func(client *mpd.Client) error {
+		return client.Move(from, from+1, to)
+	})
+}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// MoveId moves the song with the given queue ID to a position. Unlike Move
+// it is unaffected by other clients shifting positions in the meantime.
+func (c *Client) MoveId(id, to int) error {
+	return c.exec(func(client *mpd.Client) error {
+		return client.MoveID(id, to)
+	})
+}
 
-	return c.client.Move(from, from+1, to)
+// SetPriority sets the priority (0-255) of the song at a queue position. In
+// random mode higher priority songs are played first; the queue order itself
+// is unchanged.
+func (c *Client) SetPriority(pos, prio int) error {
+	return c.exec(func(client *mpd.Client) error {
+		return client.Command("prio %d %d", prio, pos).OK()
+	})
 }
 
 // Delete removes a song from the queue by position.
 func (c *Client) Delete(pos int) error {
-	if err := c.ensureConnected(); err != nil {
-		return err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	return c.exec(func(client *mpd.Client) error {
+		return client.Delete(pos, pos+1)
+	})
+}
 
-	return c.client.Delete(pos, pos+1)
+// DeleteRange removes the songs at positions start through end-1 from the queue.
+func (c *Client) DeleteRange(start, end int) error {
+	return c.exec(func(client *mpd.Client) error {
+		return client.Delete(start, end)
+	})
 }
 
 // GetCurrentPosition returns the position of the currently playing song.
 // Returns -1 if nothing is playing.
 func (c *Client) GetCurrentPosition() (int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return 0, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	status, err := c.client.Status()
-	if err != nil {
-		return 0, err
-	}
+	return command(c, func(client *mpd.Client) (int, error) {
+		status, err := client.Status()
+		if err != nil {
+			return 0, err
+		}
 
-	songPos := status["song"]
-	if songPos == "" {
-		return -1, nil
-	}
+		songPos := status["song"]
+		if songPos == "" {
+			return -1, nil
+		}
 
-	pos, err := strconv.Atoi(songPos)
-	if err != nil {
-		return -1, nil
-	}
+		pos, err := strconv.Atoi(songPos)
+		if err != nil {
+			return -1, nil
+		}
 
-	return pos, nil
+		return pos, nil
+	})
 }
 
 // GetQueueLength returns the number of songs in the queue.
 func (c *Client) GetQueueLength() (int, error) {
-	if err := c.ensureConnected(); err != nil {
-		return 0, err
-	}
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	status, err := c.client.Status()
-	if err != nil {
-		return 0, err
-	}
+	return command(c, func(client *mpd.Client) (int, error) {
+		status, err := client.Status()
+		if err != nil {
+			return 0, err
+		}
 
-	lengthStr := status["playlistlength"]
-	if lengthStr == "" {
-		return 0, nil
-	}
+		lengthStr := status["playlistlength"]
+		if lengthStr == "" {
+			return 0, nil
+		}
 
-	length, err := strconv.Atoi(lengthStr)
-	if err != nil {
-		return 0, nil
-	}
+		length, err := strconv.Atoi(lengthStr)
+		if err != nil {
+			return 0, nil
+		}
 
-	return length, nil
+		return length, nil
+	})
 }
@@ -1,9 +1,18 @@
 package mpd_test
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
+	gompd "github.com/fhs/gompd/v2/mpd"
 )
 
 func TestNewClient(t *testing.T) {
@@ -202,6 +211,16 @@ func TestClientCountArtistsWithoutConnect(t *testing.T) {
 	}
 }
 
+func TestClientSearchRejectsUnpairedTerms(t *testing.T) {
+	client := mpd.NewClient("localhost", 6600, "")
+
+	for _, terms := range [][]string{nil, {"artist"}, {"artist", "Miles Davis", "album"}} {
+		if _, err := client.Search(terms...); err == nil {
+			t.Errorf("Search(%q) should fail", terms)
+		}
+	}
+}
+
 // Tests for Volumio integration queue manipulation methods
 
 func TestClientAddIdWithoutConnect(t *testing.T) {
@@ -231,6 +250,15 @@ func TestClientMoveWithoutConnect(t *testing.T) {
 	}
 }
 
+func TestClientShuffleWithoutConnect(t *testing.T) {
+	client := mpd.NewClient("localhost", 6600, "")
+
+	err := client.Shuffle()
+	if err == nil {
+		t.Error("Shuffle should fail when not connected")
+	}
+}
+
 func TestClientDeleteWithoutConnect(t *testing.T) {
 	client := mpd.NewClient("localhost", 6600, "")
 
@@ -257,3 +285,388 @@ func TestClientGetQueueLengthWithoutConnect(t *testing.T) {
 		t.Error("GetQueueLength should fail when not connected")
 	}
 }
+
+func TestSongYear(t *testing.T) {
+	tests := []struct {
+		date string
+		want int
+	}{
+		{"1977", 1977},
+		{"1977-02-04", 1977},
+		{"", 0},
+		{"77", 0},
+		{"unknown", 0},
+	}
+
+	for _, tt := range tests {
+		if got := mpd.SongYear(map[string]string{"Date": tt.date}); got != tt.want {
+			t.Errorf("SongYear(%q) = %d, want %d", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestGroupAlbumDetails_Compilations(t *testing.T) {
+	songs := []gompd.Attrs{
+		// Compilation without AlbumArtist: each track names a different artist
+		{"file": "INTERNAL/Hits/01.flac", "Album": "Hits", "Artist": "Artist A", "Time": "100"},
+		{"file": "INTERNAL/Hits/02.flac", "Album": "Hits", "Artist": "Artist B", "Time": "200"},
+		{"file": "INTERNAL/Hits/03.flac", "Album": "Hits", "Artist": "Artist C", "Time": "300"},
+		// Regular album without AlbumArtist, split over two disc folders
+		{"file": "INTERNAL/Band/Live/CD1/01.flac", "Album": "Live", "Artist": "Band"},
+		{"file": "INTERNAL/Band/Live/CD2/01.flac", "Album": "Live", "Artist": "Band"},
+		// Compilation tag with a single artist credited
+		{"file": "INTERNAL/Mix/01.flac", "Album": "Mix", "Artist": "DJ", "Compilation": "1"},
+		// Tagged album artist
+		{"file": "INTERNAL/Tagged/01.flac", "Album": "Tagged", "AlbumArtist": "Various Artists", "Artist": "X"},
+		{"file": "INTERNAL/Solo/01.flac", "Album": "Solo", "AlbumArtist": "Singer", "Artist": "Singer feat. Guest"},
+	}
+
+	albums := mpd.GroupAlbumDetails(songs)
+
+	byTitle := make(map[string]mpd.AlbumDetails)
+	for _, album := range albums {
+		byTitle[album.Album] = album
+	}
+	if len(albums) != 5 {
+		t.Fatalf("GroupAlbumDetails returned %d albums, want 5: %+v", len(albums), albums)
+	}
+
+	tests := []struct {
+		album         string
+		artist        string
+		trackCount    int
+		isCompilation bool
+	}{
+		{"Hits", cache.VariousArtists, 3, true},
+		{"Live", "Band", 2, false},
+		{"Mix", cache.VariousArtists, 1, true},
+		{"Tagged", "Various Artists", 1, true},
+		{"Solo", "Singer", 1, false},
+	}
+
+	for _, tt := range tests {
+		got := byTitle[tt.album]
+		if got.AlbumArtist != tt.artist || got.TrackCount != tt.trackCount || got.IsCompilation != tt.isCompilation {
+			t.Errorf("%s = {artist %q, tracks %d, compilation %v}, want {%q, %d, %v}",
+				tt.album, got.AlbumArtist, got.TrackCount, got.IsCompilation, tt.artist, tt.trackCount, tt.isCompilation)
+		}
+	}
+
+	if hits := byTitle["Hits"]; hits.TotalTime != 600 || hits.FirstTrack != "INTERNAL/Hits/01.flac" {
+		t.Errorf("Hits = {time %d, first %q}, want {600, INTERNAL/Hits/01.flac}", hits.TotalTime, hits.FirstTrack)
+	}
+}
+
+func TestGroupAlbumDetails_ArtistDisambiguation(t *testing.T) {
+	songs := []gompd.Attrs{
+		// Tagged album artist with a featured guest on one track
+		{"file": "INTERNAL/Singer/Debut/01.flac", "Album": "Debut", "AlbumArtist": "Singer", "Artist": "Singer"},
+		{"file": "INTERNAL/Singer/Debut/02.flac", "Album": "Debut", "AlbumArtist": "Singer", "Artist": "Singer feat. Guest"},
+		// Distinct album artist from every track artist
+		{"file": "INTERNAL/Orchestra/Suite/01.flac", "Album": "Suite", "AlbumArtist": "Orchestra", "Artist": "Soloist A"},
+		{"file": "INTERNAL/Orchestra/Suite/02.flac", "Album": "Suite", "AlbumArtist": "Orchestra", "Artist": "Soloist B"},
+		// No album artist: guests must not turn the album into a compilation
+		{"file": "INTERNAL/Band/Second/01.flac", "Album": "Second", "Artist": "Band"},
+		{"file": "INTERNAL/Band/Second/02.flac", "Album": "Second", "Artist": "Band ft. Rapper"},
+		{"file": "INTERNAL/Band/Second/03.flac", "Album": "Second", "Artist": "Band Featuring Choir"},
+	}
+
+	albums := mpd.GroupAlbumDetails(songs)
+	if len(albums) != 3 {
+		t.Fatalf("GroupAlbumDetails returned %d albums, want 3: %+v", len(albums), albums)
+	}
+	byTitle := make(map[string]mpd.AlbumDetails)
+	for _, album := range albums {
+		byTitle[album.Album] = album
+	}
+
+	tests := []struct {
+		album         string
+		artist        string
+		inferred      bool
+		isCompilation bool
+		trackArtists  []string
+	}{
+		{"Debut", "Singer", false, false, []string{"Singer", "Singer feat. Guest"}},
+		{"Suite", "Orchestra", false, false, []string{"Soloist A", "Soloist B"}},
+		{"Second", "Band", true, false, []string{"Band", "Band Featuring Choir", "Band ft. Rapper"}},
+	}
+	for _, tt := range tests {
+		got := byTitle[tt.album]
+		if got.AlbumArtist != tt.artist || got.ArtistInferred != tt.inferred || got.IsCompilation != tt.isCompilation {
+			t.Errorf("%s = {artist %q, inferred %v, compilation %v}, want {%q, %v, %v}",
+				tt.album, got.AlbumArtist, got.ArtistInferred, got.IsCompilation, tt.artist, tt.inferred, tt.isCompilation)
+		}
+		if !slices.Equal(got.TrackArtists, tt.trackArtists) {
+			t.Errorf("%s track artists = %q, want %q", tt.album, got.TrackArtists, tt.trackArtists)
+		}
+	}
+}
+
+func TestPrimaryArtist(t *testing.T) {
+	tests := map[string]string{
+		"Singer":               "Singer",
+		"Singer feat. Guest":   "Singer",
+		"Singer Feat Guest":    "Singer",
+		"Singer ft. A feat. B": "Singer",
+		"Band featuring Choir": "Band",
+		" Padded ":             "Padded",
+		"Featherweight":        "Featherweight",
+		"Soft Cell":            "Soft Cell",
+	}
+	for artist, want := range tests {
+		if got := mpd.PrimaryArtist(artist); got != want {
+			t.Errorf("PrimaryArtist(%q) = %q, want %q", artist, got, want)
+		}
+	}
+}
+
+// listenMPD starts a TCP listener that hands each connection to serve, and
+// closes the connections when the test ends so blocked commands return.
+func listenMPD(t *testing.T, serve func(conn net.Conn)) (host string, port int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+			go serve(conn)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func TestClientConnectTimeout(t *testing.T) {
+	// Accepts connections but never sends the MPD greeting
+	host, port := listenMPD(t, func(conn net.Conn) {})
+
+	client := mpd.NewClient(host, port, "")
+	client.SetTimeouts(200*time.Millisecond, time.Second)
+
+	start := time.Now()
+	err := client.Connect()
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, mpd.ErrTimeout) {
+		t.Fatalf("Connect() error = %v, want ErrTimeout", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Connect() took %s, want about the 200ms timeout", elapsed)
+	}
+}
+
+func TestClientCommandTimeout(t *testing.T) {
+	// Greets and answers every command except status, which hangs
+	host, port := listenMPD(t, func(conn net.Conn) {
+		fmt.Fprint(conn, "OK MPD 0.23.5\n")
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if scanner.Text() == "status" {
+				continue
+			}
+			fmt.Fprint(conn, "OK\n")
+		}
+	})
+
+	client := mpd.NewClient(host, port, "")
+	client.SetTimeouts(time.Second, 200*time.Millisecond)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	_, err := client.Status()
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, mpd.ErrTimeout) {
+		t.Fatalf("Status() error = %v, want ErrTimeout", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Status() took %s, want about the 200ms timeout", elapsed)
+	}
+
+	// The hung connection is dropped and the next command reconnects
+	if err := client.Play(0); err != nil {
+		t.Errorf("Play() after timeout error = %v", err)
+	}
+}
+
+func TestClientCommandTimeoutClosesConnection(t *testing.T) {
+	closed := make(chan struct{}, 1)
+
+	// Never answers status; reports when the client closes the connection
+	host, port := listenMPD(t, func(conn net.Conn) {
+		fmt.Fprint(conn, "OK MPD 0.23.5\n")
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if scanner.Text() == "status" {
+				continue
+			}
+			fmt.Fprint(conn, "OK\n")
+		}
+		closed <- struct{}{}
+	})
+
+	client := mpd.NewClient(host, port, "")
+	client.SetTimeouts(time.Second, 200*time.Millisecond)
+	client.SetQueryConnections(0)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Status(); !errors.Is(err, mpd.ErrTimeout) {
+		t.Fatalf("Status() error = %v, want ErrTimeout", err)
+	}
+
+	// The socket is closed although status is still waiting for MPD
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("timed-out connection was not closed")
+	}
+}
+
+func TestClientQueryPoolAuthenticates(t *testing.T) {
+	var mu sync.Mutex
+	authenticated := 0
+
+	// Requires the password on every connection before answering
+	host, port := listenMPD(t, func(conn net.Conn) {
+		fmt.Fprint(conn, "OK MPD 0.23.5\n")
+		authed := false
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == `password "secret"`:
+				authed = true
+				mu.Lock()
+				authenticated++
+				mu.Unlock()
+				fmt.Fprint(conn, "OK\n")
+			case !authed:
+				fmt.Fprintf(conn, "ACK [4@0] {%s} you don't have permission\n", line)
+			case line == "list album group albumartist":
+				fmt.Fprint(conn, "Album: Kind of Blue\nAlbumArtist: Miles Davis\nOK\n")
+			default:
+				fmt.Fprint(conn, "OK\n")
+			}
+		}
+	})
+
+	client := mpd.NewClient(host, port, "secret")
+	client.SetTimeouts(time.Second, time.Second)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	albums, err := client.ListAlbums()
+	if err != nil {
+		t.Fatalf("ListAlbums() error = %v", err)
+	}
+	if len(albums) != 1 || albums[0].Album != "Kind of Blue" {
+		t.Errorf("ListAlbums() = %+v", albums)
+	}
+
+	// The control connection and the pooled query connection
+	mu.Lock()
+	defer mu.Unlock()
+	if authenticated != 2 {
+		t.Errorf("%d connections authenticated, want 2", authenticated)
+	}
+}
+
+func TestClientBlockedQueryDoesNotBlockCommand(t *testing.T) {
+	queryStarted := make(chan struct{})
+	var once sync.Once
+	var mu sync.Mutex
+	connections := 0
+
+	// Hangs on the album listing, answers everything else
+	host, port := listenMPD(t, func(conn net.Conn) {
+		mu.Lock()
+		connections++
+		mu.Unlock()
+
+		fmt.Fprint(conn, "OK MPD 0.23.5\n")
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if scanner.Text() == "list album group albumartist" {
+				once.Do(func() { close(queryStarted) })
+				continue
+			}
+			fmt.Fprint(conn, "OK\n")
+		}
+	})
+
+	client := mpd.NewClient(host, port, "")
+	client.SetTimeouts(time.Second, 500*time.Millisecond)
+	client.SetQueryConnections(1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	queryDone := make(chan error, 1)
+	go func() {
+		_, err := client.ListAlbums()
+		queryDone <- err
+	}()
+
+	select {
+	case <-queryStarted:
+	case <-time.After(time.Second):
+		t.Fatal("query never reached MPD")
+	}
+
+	start := time.Now()
+	if err := client.Pause(true); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Pause() took %s while a query was blocked", elapsed)
+	}
+
+	// The query times out and only its own connection is replaced
+	if err := <-queryDone; !errors.Is(err, mpd.ErrTimeout) {
+		t.Errorf("ListAlbums() error = %v, want ErrTimeout", err)
+	}
+	if err := client.Pause(false); err != nil {
+		t.Fatalf("Pause() after query timeout error = %v", err)
+	}
+	if _, err := client.ListGenres(); err != nil {
+		t.Fatalf("ListGenres() after query timeout error = %v", err)
+	}
+
+	// Control, the timed-out query connection and its replacement
+	mu.Lock()
+	defer mu.Unlock()
+	if connections != 3 {
+		t.Errorf("%d connections opened, want 3", connections)
+	}
+}
@@ -0,0 +1,321 @@
+// Package mpd provides a wrapper around the gompd MPD client.
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrTimeout is returned when MPD doesn't accept a connection or answer a
+// command within the client's timeouts.
+var ErrTimeout = errors.New("MPD did not respond in time")
+
+// conn is one connection to MPD. Commands hold mu for reading while they use
+// client, or for writing when they need the connection to themselves.
+type conn struct {
+	mu     sync.RWMutex
+	client *mpd.Client
+	nc     net.Conn // Socket to MPD; closing it fails commands waiting on MPD
+}
+
+// dialConn connects to MPD at addr within timeout (zero waits indefinitely)
+// and authenticates with password when it is set.
+//
+// gompd dials by address and keeps its socket to itself, so it is given a
+// loopback connection relayed to a socket dialed here. Owning that socket
+// puts the handshake under a deadline and lets close fail a command stuck
+// waiting on MPD, which closing the gompd client can't do while it is in use.
+func dialConn(addr, password string, timeout time.Duration) (*conn, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	timedOut := func(err error) error {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return fmt.Errorf("failed to connect to MPD: %w after %s", ErrTimeout, timeout)
+		}
+		return fmt.Errorf("failed to connect to MPD: %w", err)
+	}
+
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, timedOut(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to connect to MPD: %w", err)
+	}
+	go relay(ln, nc)
+
+	nc.SetDeadline(deadline)
+	client, err := mpd.Dial("tcp", ln.Addr().String())
+	ln.Close()
+	if err != nil {
+		nc.Close()
+		return nil, timedOut(err)
+	}
+	if password != "" {
+		if err := client.Command("password %s", password).OK(); err != nil {
+			nc.Close()
+			client.Close()
+			if timeoutErr := timedOut(err); errors.Is(timeoutErr, ErrTimeout) {
+				return nil, timeoutErr
+			}
+			return nil, fmt.Errorf("MPD authentication failed: %w", err)
+		}
+	}
+	nc.SetDeadline(time.Time{})
+
+	return &conn{client: client, nc: nc}, nil
+}
+
+// relay accepts gompd's connection on ln and copies between it and nc until
+// either side closes, then closes both.
+func relay(ln net.Listener, nc net.Conn) {
+	local, err := ln.Accept()
+	ln.Close()
+	if err != nil {
+		nc.Close()
+		return
+	}
+	go func() {
+		io.Copy(local, nc)
+		local.Close()
+	}()
+	io.Copy(nc, local)
+	nc.Close()
+}
+
+// ping checks that MPD still answers on the connection.
+func (cn *conn) ping() error {
+	cn.mu.RLock()
+	defer cn.mu.RUnlock()
+	return cn.client.Ping()
+}
+
+// close closes the socket to MPD at once, so commands still waiting on it
+// fail rather than hold the connection, and releases the gompd client once
+// they have returned.
+func (cn *conn) close() error {
+	err := cn.nc.Close()
+	go func() {
+		cn.mu.Lock()
+		defer cn.mu.Unlock()
+		cn.client.Close()
+	}()
+	if errors.Is(err, net.ErrClosed) {
+		return nil // MPD closed it first
+	}
+	return err
+}
+
+// withTimeout runs fn and returns its result, or ErrTimeout if it hasn't
+// finished after timeout. gompd has no deadlines of its own, so a timed-out
+// fn keeps running until MPD answers or the connection is closed; a result
+// it still produces is passed to discard, if set. Zero waits indefinitely.
+func withTimeout[T any](timeout time.Duration, fn func() (T, error), discard func(T)) (T, error) {
+	if timeout <= 0 {
+		return fn()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		if discard != nil {
+			go func() {
+				if r := <-done; r.err == nil {
+					discard(r.value)
+				}
+			}()
+		}
+		var zero T
+		return zero, fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	}
+}
+
+// connSource hands out connections to commands.
+type connSource interface {
+	acquire() (*conn, error) // Returns a connection, connecting if needed
+	release(cn *conn)        // Returns a connection after use
+	drop(cn *conn)           // Returns a connection that was lost or timed out
+}
+
+// controlSource is the Client's control connection, used for playback and
+// queue commands.
+type controlSource struct {
+	c *Client
+}
+
+func (s controlSource) acquire() (*conn, error) { return s.c.connection() }
+func (s controlSource) release(*conn)           {}
+func (s controlSource) drop(cn *conn)           { s.c.drop(cn) }
+
+// connPool lends connections to library queries, opening up to its size on
+// demand, so a slow search doesn't hold up playback commands.
+type connPool struct {
+	slots chan *conn // Free slots; nil for a slot without an open connection
+}
+
+// newConnPool creates a pool of size connections, none open yet.
+func newConnPool(size int) *connPool {
+	p := &connPool{slots: make(chan *conn, size)}
+	for i := 0; i < size; i++ {
+		p.slots <- nil
+	}
+	return p
+}
+
+// closeIdle closes the connections not currently lent out.
+func (p *connPool) closeIdle() {
+	for {
+		select {
+		case cn := <-p.slots:
+			if cn != nil {
+				cn.close()
+			}
+			defer func() { p.slots <- nil }()
+		default:
+			return
+		}
+	}
+}
+
+// poolSource takes connections from the Client's query pool.
+type poolSource struct {
+	c    *Client
+	pool *connPool
+}
+
+// acquire waits up to the command timeout for a free slot, then opens a
+// connection for it if it has none.
+func (s poolSource) acquire() (*conn, error) {
+	connectTimeout, commandTimeout := s.c.timeouts()
+
+	var cn *conn
+	if commandTimeout > 0 {
+		timer := time.NewTimer(commandTimeout)
+		defer timer.Stop()
+		select {
+		case cn = <-s.pool.slots:
+		case <-timer.C:
+			return nil, fmt.Errorf("no MPD query connection free: %w after %s", ErrTimeout, commandTimeout)
+		}
+	} else {
+		cn = <-s.pool.slots
+	}
+
+	if cn != nil {
+		return cn, nil
+	}
+	cn, err := s.c.dial(connectTimeout)
+	if err != nil {
+		s.pool.slots <- nil
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (s poolSource) release(cn *conn) {
+	s.pool.slots <- cn
+}
+
+func (s poolSource) drop(cn *conn) {
+	s.pool.slots <- nil
+	cn.close()
+}
+
+// call runs fn on a connection from src within the command timeout,
+// reconnecting first if MPD closed the connection. A connection that times
+// out is dropped so the next command gets a fresh one. exclusive keeps other
+// commands off the connection while fn runs.
+func call[T any](c *Client, src connSource, exclusive bool, fn func(client *mpd.Client) (T, error)) (T, error) {
+	var zero T
+
+	cn, err := src.acquire()
+	if err != nil {
+		return zero, err
+	}
+	_, timeout := c.timeouts()
+
+	// MPD closes idle connections, so check this one is still alive
+	if _, err := withTimeout(timeout, func() (struct{}, error) {
+		return struct{}{}, cn.ping()
+	}, nil); err != nil {
+		src.drop(cn)
+		if errors.Is(err, ErrTimeout) {
+			return zero, err
+		}
+		log.Warn().Err(err).Msg("MPD connection lost, reconnecting...")
+		if cn, err = src.acquire(); err != nil {
+			return zero, err
+		}
+	}
+
+	value, err := withTimeout(timeout, func() (T, error) {
+		if exclusive {
+			cn.mu.Lock()
+			defer cn.mu.Unlock()
+		} else {
+			cn.mu.RLock()
+			defer cn.mu.RUnlock()
+		}
+		return fn(cn.client)
+	}, nil)
+	if errors.Is(err, ErrTimeout) {
+		src.drop(cn)
+	} else {
+		src.release(cn)
+	}
+	return value, err
+}
+
+// command runs a playback or queue command that returns a value on the
+// control connection; see call.
+func command[T any](c *Client, fn func(client *mpd.Client) (T, error)) (T, error) {
+	return call(c, controlSource{c}, false, fn)
+}
+
+// query runs a library query on a pooled connection, or on the control
+// connection if the pool is disabled; see call.
+func query[T any](c *Client, fn func(client *mpd.Client) (T, error)) (T, error) {
+	return call(c, c.querySource(), false, fn)
+}
+
+// exec runs a command that only returns an error; see call.
+func (c *Client) exec(fn func(client *mpd.Client) error) error {
+	_, err := command(c, func(client *mpd.Client) (struct{}, error) {
+		return struct{}{}, fn(client)
+	})
+	return err
+}
+
+// execExclusive is exec for commands that must not be interleaved with
+// others, such as clearing the queue and loading a playlist.
+func (c *Client) execExclusive(fn func(client *mpd.Client) error) error {
+	_, err := call(c, controlSource{c}, true, func(client *mpd.Client) (struct{}, error) {
+		return struct{}{}, fn(client)
+	})
+	return err
+}
@@ -0,0 +1,192 @@
+package mpd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrNotExclusive is returned when MPD lets clients without the password
+// control playback, so exclusive access cannot be guaranteed.
+var ErrNotExclusive = errors.New("MPD accepts playback commands without a password")
+
+// ErrRemoteMPD is returned when counting clients of an MPD on another host,
+// whose connections aren't visible in this host's /proc.
+var ErrRemoteMPD = errors.New("MPD runs on another host")
+
+// procRoot is the proc filesystem mount point.
+var procRoot = "/proc"
+
+// tcpEstablished is the ESTABLISHED state code in /proc/net/tcp.
+const tcpEstablished = "01"
+
+// VerifyExclusive opens an unauthenticated connection and checks that MPD
+// refuses it playback commands. Only then does the password keep other
+// clients from controlling the player.
+func (c *Client) VerifyExclusive() error {
+	addr := fmt.Sprintf("%s:%d", c.host, c.port)
+
+	connectTimeout, commandTimeout := c.timeouts()
+
+	cn, err := dialConn(addr, "", connectTimeout)
+	if err != nil {
+		return err
+	}
+	defer cn.close()
+
+	commands, err := withTimeout(commandTimeout, func() ([]string, error) {
+		cn.mu.RLock()
+		defer cn.mu.RUnlock()
+		return cn.client.Command("commands").Strings("command")
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list MPD commands: %w", err)
+	}
+	for _, cmd := range commands {
+		if cmd == "play" {
+			return ErrNotExclusive
+		}
+	}
+	return nil
+}
+
+// ClientCounter reports how many clients other than this backend are
+// connected to MPD.
+type ClientCounter interface {
+	OtherClients() (int, error)
+}
+
+// ProcClientCounter counts MPD clients from /proc/net/tcp. It only sees
+// connections to an MPD running on this host.
+type ProcClientCounter struct {
+	host string
+	port int
+}
+
+// NewProcClientCounter creates a counter for the MPD at host:port.
+func NewProcClientCounter(host string, port int) *ProcClientCounter {
+	return &ProcClientCounter{host: host, port: port}
+}
+
+// OtherClients counts MPD's established connections whose peer isn't one of
+// this process's sockets.
+func (p *ProcClientCounter) OtherClients() (int, error) {
+	if !isLocalHost(p.host) {
+		return 0, ErrRemoteMPD
+	}
+
+	conns, err := readProcNetTCP(filepath.Join(procRoot, "net", "tcp"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read TCP connections: %w", err)
+	}
+	// tcp6 is absent when IPv6 is disabled
+	if conns6, err := readProcNetTCP(filepath.Join(procRoot, "net", "tcp6")); err == nil {
+		conns = append(conns, conns6...)
+	}
+
+	return countOtherClients(conns, p.port, ownSocketInodes()), nil
+}
+
+// isLocalHost reports whether host refers to this machine.
+func isLocalHost(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// tcpConn is an established connection from /proc/net/tcp.
+type tcpConn struct {
+	LocalPort  int
+	RemotePort int
+	Inode      string
+}
+
+// readProcNetTCP reads a /proc/net/tcp or tcp6 file.
+func readProcNetTCP(path string) ([]tcpConn, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseProcNetTCP(string(data)), nil
+}
+
+// parseProcNetTCP returns the established connections in /proc/net/tcp format:
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//	 0: 0100007F:19C8 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 4242
+func parseProcNetTCP(content string) []tcpConn {
+	var conns []tcpConn
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpEstablished {
+			continue
+		}
+		local, ok1 := hexPort(fields[1])
+		remote, ok2 := hexPort(fields[2])
+		if !ok1 || !ok2 {
+			continue
+		}
+		conns = append(conns, tcpConn{LocalPort: local, RemotePort: remote, Inode: fields[9]})
+	}
+	return conns
+}
+
+// hexPort extracts the port from an "ADDR:PORT" hex address.
+func hexPort(addr string) (int, bool) {
+	_, port, ok := strings.Cut(addr, ":")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(port, 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// countOtherClients counts MPD-side connections on port whose peer isn't one
+// of our sockets. Peers are matched by port alone, since a loopback
+// connection can appear as IPv4 on our side and IPv4-mapped IPv6 on MPD's.
+func countOtherClients(conns []tcpConn, port int, own map[string]bool) int {
+	ownPorts := make(map[int]bool)
+	for _, c := range conns {
+		if c.RemotePort == port && own[c.Inode] {
+			ownPorts[c.LocalPort] = true
+		}
+	}
+
+	count := 0
+	for _, c := range conns {
+		if c.LocalPort == port && !ownPorts[c.RemotePort] {
+			count++
+		}
+	}
+	return count
+}
+
+// ownSocketInodes returns the socket inodes open in this process.
+func ownSocketInodes() map[string]bool {
+	inodes := make(map[string]bool)
+	fds, err := os.ReadDir(filepath.Join(procRoot, "self", "fd"))
+	if err != nil {
+		return inodes
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(procRoot, "self", "fd", fd.Name()))
+		if err != nil {
+			continue
+		}
+		if inode, ok := strings.CutPrefix(target, "socket:["); ok {
+			inodes[strings.TrimSuffix(inode, "]")] = true
+		}
+	}
+	return inodes
+}
@@ -0,0 +1,168 @@
+package mpd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeMPD serves the greeting and answers "commands" with the given list,
+// the way MPD does for a connection that hasn't sent a password.
+func fakeMPD(t *testing.T, commands []string) (host string, port int) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				fmt.Fprint(conn, "OK MPD 0.23.5\n")
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch strings.TrimSpace(line) {
+					case "commands":
+						for _, c := range commands {
+							fmt.Fprintf(conn, "command: %s\n", c)
+						}
+						fmt.Fprint(conn, "OK\n")
+					case "close":
+						return
+					default:
+						fmt.Fprint(conn, "OK\n")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func TestVerifyExclusive(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		want     error
+	}{
+		{"locked down", []string{"close", "commands", "password", "ping"}, nil},
+		{"open", []string{"close", "commands", "password", "ping", "play", "status"}, ErrNotExclusive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := fakeMPD(t, tt.commands)
+			c := NewClient(host, port, "secret")
+			if err := c.VerifyExclusive(); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyExclusive() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyExclusiveConnectFailure(t *testing.T) {
+	c := NewClient("127.0.0.1", 1, "secret")
+	if err := c.VerifyExclusive(); err == nil {
+		t.Error("VerifyExclusive() = nil, want connection error")
+	}
+}
+
+func TestParseProcNetTCP(t *testing.T) {
+	content := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:19C8 00000000:0000 0A 00000000:00000000 00:00000000 00000000   110        0 1000 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:19C8 0100007F:C350 01 00000000:00000000 00:00000000 00000000   110        0 1001 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:C350 0100007F:19C8 01 00000000:00000000 00:00000000 00000000  1000        0 1002 1 0000000000000000 20 4 30 10 -1
+`
+	conns := parseProcNetTCP(content)
+	want := []tcpConn{
+		{LocalPort: 6600, RemotePort: 50000, Inode: "1001"},
+		{LocalPort: 50000, RemotePort: 6600, Inode: "1002"},
+	}
+	if len(conns) != len(want) {
+		t.Fatalf("got %d connections, want %d: %+v", len(conns), len(want), conns)
+	}
+	for i := range want {
+		if conns[i] != want[i] {
+			t.Errorf("conn %d = %+v, want %+v", i, conns[i], want[i])
+		}
+	}
+}
+
+func TestCountOtherClients(t *testing.T) {
+	conns := []tcpConn{
+		// Our command connection and its MPD side
+		{LocalPort: 50000, RemotePort: 6600, Inode: "1"},
+		{LocalPort: 6600, RemotePort: 50000, Inode: "2"},
+		// Our idle watcher
+		{LocalPort: 50001, RemotePort: 6600, Inode: "3"},
+		{LocalPort: 6600, RemotePort: 50001, Inode: "4"},
+		// Another client, e.g. a phone app
+		{LocalPort: 6600, RemotePort: 41234, Inode: "5"},
+		// Unrelated connection
+		{LocalPort: 50002, RemotePort: 443, Inode: "6"},
+	}
+	own := map[string]bool{"1": true, "3": true, "6": true}
+
+	if got := countOtherClients(conns, 6600, own); got != 1 {
+		t.Errorf("countOtherClients() = %d, want 1", got)
+	}
+	if got := countOtherClients(conns, 6600, map[string]bool{}); got != 3 {
+		t.Errorf("countOtherClients() with no own sockets = %d, want 3", got)
+	}
+}
+
+func TestProcClientCounter_OwnConnection(t *testing.T) {
+	if _, err := os.Stat("/proc/net/tcp"); err != nil {
+		t.Skip("no /proc/net/tcp on this system")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			defer conn.Close()
+			conn.Read(make([]byte, 1))
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	counter := NewProcClientCounter("127.0.0.1", ln.Addr().(*net.TCPAddr).Port)
+	got, err := counter.OtherClients()
+	if err != nil {
+		t.Fatalf("OtherClients() error = %v", err)
+	}
+	if got != 0 {
+		t.Errorf("OtherClients() = %d, want 0 for our own connection", got)
+	}
+}
+
+func TestProcClientCounter_RemoteHost(t *testing.T) {
+	counter := NewProcClientCounter("192.168.1.50", 6600)
+	if _, err := counter.OtherClients(); !errors.Is(err, ErrRemoteMPD) {
+		t.Errorf("OtherClients() error = %v, want ErrRemoteMPD", err)
+	}
+}
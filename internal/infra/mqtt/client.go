@@ -0,0 +1,278 @@
+// Package mqtt provides a minimal MQTT 3.1.1 client for publishing player
+// state and receiving commands. Only QoS 0 is supported, which is all the
+// home-automation bridge needs.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultPort is the standard unencrypted MQTT port
+	DefaultPort = "1883"
+
+	// DefaultKeepAlive is the keep-alive interval sent to the broker
+	DefaultKeepAlive = 30 * time.Second
+
+	// dialTimeout bounds connecting and waiting for CONNACK
+	dialTimeout = 10 * time.Second
+
+	// minBackoff and maxBackoff bound the reconnect delay
+	minBackoff = 1 * time.Second
+	maxBackoff = 60 * time.Second
+)
+
+// ErrNotConnected is returned when publishing while the broker is unreachable.
+var ErrNotConnected = errors.New("mqtt not connected")
+
+// connackErrors describe the CONNACK refusal codes.
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Options configures a Client.
+type Options struct {
+	Broker    string // host, host:port or tcp://host:port
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // DefaultKeepAlive if zero
+
+	// Last will, published by the broker if the connection drops. It is also
+	// published by the client itself on a clean shutdown.
+	WillTopic   string
+	WillPayload []byte
+	WillRetain  bool
+
+	// OnConnect is called after every successful (re)connect, e.g. to
+	// subscribe and publish availability.
+	OnConnect func(c *Client)
+
+	// OnMessage is called for every message received on a subscription.
+	OnMessage func(topic string, payload []byte)
+}
+
+// Client is an MQTT client that keeps reconnecting until its context ends.
+type Client struct {
+	opts     Options
+	mu       sync.Mutex // Guards conn and serializes writes
+	conn     net.Conn
+	packetID uint16
+}
+
+// NewClient creates a client. Call Run to connect.
+func NewClient(opts Options) *Client {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+	return &Client{opts: opts}
+}
+
+// BrokerAddress normalizes a broker setting to host:port.
+func BrokerAddress(broker string) string {
+	broker = strings.TrimPrefix(broker, "tcp://")
+	broker = strings.TrimPrefix(broker, "mqtt://")
+	if _, _, err := net.SplitHostPort(broker); err != nil {
+		return net.JoinHostPort(broker, DefaultPort)
+	}
+	return broker
+}
+
+// Run connects to the broker and reconnects with exponential backoff until
+// ctx is cancelled.
+func (c *Client) Run(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		connected, err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = minBackoff
+		}
+		log.Warn().Err(err).Str("broker", c.opts.Broker).Dur("retryIn", backoff).Msg("MQTT connection lost")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Connected returns true while a broker connection is up.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// Publish sends a QoS 0 message.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	return c.write(encodePublish(topic, payload, retain))
+}
+
+// Subscribe requests QoS 0 delivery for the given topic filters.
+func (c *Client) Subscribe(filters ...string) error {
+	c.mu.Lock()
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1 // Packet identifiers must be non-zero
+	}
+	id := c.packetID
+	c.mu.Unlock()
+
+	return c.write(encodeSubscribe(id, filters...))
+}
+
+// write sends a raw packet on the current connection.
+func (c *Client) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// session runs one connection until it fails or ctx ends. It reports whether
+// the broker accepted the connection.
+func (c *Client) session(ctx context.Context) (bool, error) {
+	addr := BrokerAddress(c.opts.Broker)
+
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return false, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	if err := c.handshake(conn, reader); err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+
+	log.Info().Str("broker", addr).Msg("MQTT connected")
+	if c.opts.OnConnect != nil {
+		c.opts.OnConnect(c)
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- c.readLoop(conn, reader)
+	}()
+
+	ping := time.NewTicker(c.opts.KeepAlive / 2)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// A clean DISCONNECT suppresses the will, so publish it ourselves
+			if c.opts.WillTopic != "" {
+				c.Publish(c.opts.WillTopic, c.opts.WillPayload, c.opts.WillRetain)
+			}
+			c.write(encodeDisconnect())
+			return true, ctx.Err()
+		case err := <-readErr:
+			return true, err
+		case <-ping.C:
+			if err := c.write(encodePingreq()); err != nil {
+				return true, fmt.Errorf("ping: %w", err)
+			}
+		}
+	}
+}
+
+// handshake sends CONNECT and waits for CONNACK.
+func (c *Client) handshake(conn net.Conn, reader *bufio.Reader) error {
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	connect := encodeConnect(connectOptions{
+		clientID:    c.opts.ClientID,
+		username:    c.opts.Username,
+		password:    c.opts.Password,
+		keepAlive:   uint16(c.opts.KeepAlive / time.Second),
+		willTopic:   c.opts.WillTopic,
+		willPayload: c.opts.WillPayload,
+		willRetain:  c.opts.WillRetain,
+	})
+	if _, err := conn.Write(connect); err != nil {
+		return fmt.Errorf("send connect: %w", err)
+	}
+
+	p, err := readPacket(reader)
+	if err != nil {
+		return fmt.Errorf("read connack: %w", err)
+	}
+	if p.kind != packetConnack || len(p.body) < 2 {
+		return fmt.Errorf("expected connack, got packet type %d", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		if reason, ok := connackErrors[code]; ok {
+			return fmt.Errorf("connection refused: %s", reason)
+		}
+		return fmt.Errorf("connection refused: code %d", code)
+	}
+	return nil
+}
+
+// readLoop dispatches incoming packets until the connection fails. The broker
+// must send something (at least PINGRESP) within 1.5 keep-alive intervals.
+func (c *Client) readLoop(conn net.Conn, reader *bufio.Reader) error {
+	for {
+		conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+
+		p, err := readPacket(reader)
+		if err != nil {
+			return err
+		}
+
+		switch p.kind {
+		case packetPublish:
+			topic, payload, err := decodePublish(p)
+			if err != nil {
+				log.Debug().Err(err).Msg("Ignoring malformed MQTT publish")
+				continue
+			}
+			if c.opts.OnMessage != nil {
+				c.opts.OnMessage(topic, payload)
+			}
+		case packetSuback, packetPingresp:
+			// Nothing to do for QoS 0
+		default:
+			log.Debug().Int("type", int(p.kind)).Msg("Ignoring MQTT packet")
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRemainingLength_RoundTrip(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151} {
+		buf := encodePacket(packetPublish<<4, make([]byte, n))
+		p, err := readPacket(bufio.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			t.Fatalf("readPacket(%d) failed: %v", n, err)
+		}
+		if len(p.body) != n {
+			t.Errorf("body length = %d, want %d", len(p.body), n)
+		}
+	}
+}
+
+func TestPublish_RoundTrip(t *testing.T) {
+	buf := encodePublish("stellar/state", []byte(`{"status":"play"}`), true)
+	p, err := readPacket(bufio.NewReader(bytes.NewReader(buf)))
+	if err != nil {
+		t.Fatalf("readPacket failed: %v", err)
+	}
+	if p.kind != packetPublish || p.flags&0x01 == 0 {
+		t.Errorf("kind = %d flags = %#x, want retained publish", p.kind, p.flags)
+	}
+
+	topic, payload, err := decodePublish(p)
+	if err != nil {
+		t.Fatalf("decodePublish failed: %v", err)
+	}
+	if topic != "stellar/state" || string(payload) != `{"status":"play"}` {
+		t.Errorf("got %q %q", topic, payload)
+	}
+}
+
+func TestBrokerAddress(t *testing.T) {
+	tests := map[string]string{
+		"broker":              "broker:1883",
+		"broker:1884":         "broker:1884",
+		"tcp://10.0.0.2":      "10.0.0.2:1883",
+		"tcp://10.0.0.2:1884": "10.0.0.2:1884",
+	}
+	for in, want := range tests {
+		if got := BrokerAddress(in); got != want {
+			t.Errorf("BrokerAddress(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// fakeBroker accepts one connection, acknowledges CONNECT and forwards the
+// remaining packets to the test.
+func fakeBroker(t *testing.T, connackCode byte) (string, <-chan packet, chan<- []byte) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan packet, 16)
+	send := make(chan []byte, 16)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		p, err := readPacket(reader)
+		if err != nil || p.kind != packetConnect {
+			return
+		}
+		received <- p
+		conn.Write([]byte{packetConnack << 4, 2, 0, connackCode})
+
+		go func() {
+			for data := range send {
+				conn.Write(data)
+			}
+		}()
+		for {
+			p, err := readPacket(reader)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- p
+		}
+	}()
+
+	return ln.Addr().String(), received, send
+}
+
+func nextPacket(t *testing.T, ch <-chan packet) packet {
+	t.Helper()
+	select {
+	case p := <-ch:
+		return p
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for packet")
+		return packet{}
+	}
+}
+
+func TestClient_ConnectPublishSubscribe(t *testing.T) {
+	addr, received, send := fakeBroker(t, 0)
+
+	messages := make(chan string, 1)
+	client := NewClient(Options{
+		Broker:      addr,
+		ClientID:    "test",
+		WillTopic:   "stellar/availability",
+		WillPayload: []byte("offline"),
+		WillRetain:  true,
+		OnConnect: func(c *Client) {
+			c.Subscribe("stellar/cmd/+")
+			c.Publish("stellar/availability", []byte("online"), true)
+		},
+		OnMessage: func(topic string, payload []byte) {
+			messages <- topic + "=" + string(payload)
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(done)
+	}()
+
+	connect := nextPacket(t, received)
+	if flags := connect.body[7]; flags&flagWill == 0 || flags&flagWillRetain == 0 {
+		t.Errorf("connect flags = %#x, want retained will", flags)
+	}
+
+	if p := nextPacket(t, received); p.kind != packetSubscribe {
+		t.Errorf("first packet after connect = %d, want SUBSCRIBE", p.kind)
+	}
+	p := nextPacket(t, received)
+	if topic, payload, _ := decodePublish(p); topic != "stellar/availability" || string(payload) != "online" {
+		t.Errorf("got publish %q %q, want availability online", topic, payload)
+	}
+
+	send <- encodePublish("stellar/cmd/volume", []byte("40"), false)
+	select {
+	case msg := <-messages:
+		if msg != "stellar/cmd/volume=40" {
+			t.Errorf("message = %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+
+	// Shutting down publishes the will and disconnects cleanly
+	cancel()
+	p = nextPacket(t, received)
+	if topic, payload, _ := decodePublish(p); topic != "stellar/availability" || string(payload) != "offline" {
+		t.Errorf("got publish %q %q, want availability offline", topic, payload)
+	}
+	if p := nextPacket(t, received); p.kind != packetDisconnect {
+		t.Errorf("last packet = %d, want DISCONNECT", p.kind)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if client.Connected() {
+		t.Error("client still connected after Run returned")
+	}
+}
+
+func TestClient_RefusedConnection(t *testing.T) {
+	addr, _, _ := fakeBroker(t, 5)
+
+	client := NewClient(Options{Broker: addr, ClientID: "test"})
+	connected, err := client.session(context.Background())
+	if connected || err == nil {
+		t.Fatalf("session = %v, %v; want refused", connected, err)
+	}
+	if err := client.Publish("stellar/state", nil, false); err != ErrNotConnected {
+		t.Errorf("Publish while disconnected = %v, want ErrNotConnected", err)
+	}
+}
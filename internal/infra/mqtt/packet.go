@@ -0,0 +1,197 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types (upper nibble of the fixed header).
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	maxRemainingBytes = 4 // Remaining length uses at most four bytes
+)
+
+// Connect flags.
+const (
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// errMalformedLength is returned for a remaining length longer than four bytes.
+var errMalformedLength = errors.New("malformed remaining length")
+
+// packet is a decoded control packet.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// encodeString appends an MQTT UTF-8 string (two-byte length prefix).
+func encodeString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// encodeRemainingLength appends the variable-length remaining length.
+func encodeRemainingLength(buf []byte, n int) []byte {
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			return buf
+		}
+	}
+}
+
+// encodePacket builds a complete packet from its header byte and body.
+func encodePacket(header byte, body []byte) []byte {
+	buf := []byte{header}
+	buf = encodeRemainingLength(buf, len(body))
+	return append(buf, body...)
+}
+
+// connectOptions are the fields of a CONNECT packet.
+type connectOptions struct {
+	clientID    string
+	username    string
+	password    string
+	keepAlive   uint16 // Seconds
+	willTopic   string
+	willPayload []byte
+	willRetain  bool
+}
+
+// encodeConnect builds a CONNECT packet with a clean session.
+func encodeConnect(opts connectOptions) []byte {
+	flags := byte(flagCleanSession)
+	if opts.willTopic != "" {
+		flags |= flagWill
+		if opts.willRetain {
+			flags |= flagWillRetain
+		}
+	}
+	if opts.username != "" {
+		flags |= flagUsername
+		if opts.password != "" {
+			flags |= flagPassword
+		}
+	}
+
+	body := encodeString(nil, "MQTT")
+	body = append(body, 4, flags) // Protocol level 4 = 3.1.1
+	body = binary.BigEndian.AppendUint16(body, opts.keepAlive)
+	body = encodeString(body, opts.clientID)
+	if opts.willTopic != "" {
+		body = encodeString(body, opts.willTopic)
+		body = encodeString(body, string(opts.willPayload))
+	}
+	if opts.username != "" {
+		body = encodeString(body, opts.username)
+		if opts.password != "" {
+			body = encodeString(body, opts.password)
+		}
+	}
+	return encodePacket(packetConnect<<4, body)
+}
+
+// encodePublish builds a QoS 0 PUBLISH packet.
+func encodePublish(topic string, payload []byte, retain bool) []byte {
+	header := byte(packetPublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	body := encodeString(nil, topic)
+	body = append(body, payload...)
+	return encodePacket(header, body)
+}
+
+// encodeSubscribe builds a SUBSCRIBE packet requesting QoS 0 for each filter.
+func encodeSubscribe(packetID uint16, filters ...string) []byte {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	for _, filter := range filters {
+		body = encodeString(body, filter)
+		body = append(body, 0) // Requested QoS
+	}
+	return encodePacket(packetSubscribe<<4|0x02, body) // SUBSCRIBE has reserved flags 0010
+}
+
+// encodePingreq builds a PINGREQ packet.
+func encodePingreq() []byte {
+	return []byte{packetPingreq << 4, 0}
+}
+
+// encodeDisconnect builds a DISCONNECT packet.
+func encodeDisconnect() []byte {
+	return []byte{packetDisconnect << 4, 0}
+}
+
+// readPacket reads one control packet.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == maxRemainingBytes {
+			return packet{}, errMalformedLength
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// decodePublish returns the topic and payload of a PUBLISH packet.
+func decodePublish(p packet) (string, []byte, error) {
+	if len(p.body) < 2 {
+		return "", nil, fmt.Errorf("publish packet too short")
+	}
+	topicLen := int(binary.BigEndian.Uint16(p.body))
+	rest := p.body[2:]
+	if len(rest) < topicLen {
+		return "", nil, fmt.Errorf("publish topic truncated")
+	}
+	topic := string(rest[:topicLen])
+	rest = rest[topicLen:]
+
+	// QoS 1 and 2 messages carry a packet identifier before the payload
+	if qos := (p.flags >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return "", nil, fmt.Errorf("publish packet id truncated")
+		}
+		rest = rest[2:]
+	}
+	return topic, rest, nil
+}
@@ -0,0 +1,212 @@
+// Package smb lists the shares of an SMB server over SMB2 using go-smb2, so
+// share browsing works without the samba client tools.
+//
+// go-smb2 enumerates share names only, so share types and comments are not
+// reported. Servers that only speak SMB1 are reported as errors so callers
+// can fall back to another implementation.
+package smb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// Port is the SMB over TCP port.
+const Port = 445
+
+// guestUser is used when no username is given; go-smb2 has no anonymous
+// logon, and servers map unknown users to guest when they allow it.
+const guestUser = "guest"
+
+// NTSTATUS codes for failures that other credentials might fix
+const (
+	statusAccessDenied        uint32 = 0xC0000022
+	statusLogonFailure        uint32 = 0xC000006D
+	statusAccountRestriction  uint32 = 0xC000006E
+	statusPasswordExpired     uint32 = 0xC0000071
+	statusAccountDisabled     uint32 = 0xC0000072
+	statusLogonTypeNotGranted uint32 = 0xC000015B
+)
+
+// statusBadNetworkName is returned for shares that cannot be connected.
+const statusBadNetworkName uint32 = 0xC00000CC
+
+// ErrAccessDenied is matched by errors.Is for logon failures and refused
+// access, i.e. errors that other credentials might fix.
+var ErrAccessDenied = errors.New("smb: access denied")
+
+// StatusError is an SMB2 response carrying a failure status.
+type StatusError struct {
+	Status uint32
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("smb: request failed with status 0x%08X", e.Status)
+}
+
+// Is reports authentication and authorisation failures as ErrAccessDenied.
+func (e *StatusError) Is(target error) bool {
+	if target != ErrAccessDenied {
+		return false
+	}
+	switch e.Status {
+	case statusAccessDenied, statusLogonFailure, statusAccountRestriction,
+		statusPasswordExpired, statusAccountDisabled, statusLogonTypeNotGranted:
+		return true
+	}
+	return false
+}
+
+// Access is what the authenticated user may do on a share.
+type Access int
+
+const (
+	AccessUnknown   Access = iota // Not checked, or the check failed
+	AccessNone                    // The server refused to connect the share
+	AccessRead                    // The share can be read
+	AccessReadWrite               // The share can be read and written
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessNone:
+		return "none"
+	case AccessRead:
+		return "read-only"
+	case AccessReadWrite:
+		return "read-write"
+	}
+	return ""
+}
+
+// Share is a share advertised by an SMB server.
+type Share struct {
+	Name    string
+	Special bool   // Administrative or IPC share such as ADMIN$, C$ or IPC$
+	Access  Access // Checked for non-special shares only
+}
+
+// ListShares connects to host as username (as guest when empty) and lists
+// its shares, checking access to each non-special share. A username of the
+// form DOMAIN\user selects the NTLM domain.
+func ListShares(ctx context.Context, host, username, password string) ([]Share, error) {
+	var dialer net.Dialer
+	nc, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, fmt.Sprint(Port)))
+	if err != nil {
+		return nil, err
+	}
+	defer nc.Close()
+
+	domain, user := splitDomain(username)
+	if user == "" {
+		user = guestUser
+	}
+	d := &smb2.Dialer{
+		Initiator: &smb2.NTLMInitiator{
+			User:     user,
+			Password: password,
+			Domain:   domain,
+		},
+	}
+	session, err := d.DialContext(ctx, nc)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	defer session.Logoff()
+	session = session.WithContext(ctx)
+
+	names, err := session.ListSharenames()
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	shares := make([]Share, 0, len(names))
+	for _, name := range names {
+		share := Share{Name: name, Special: strings.HasSuffix(name, "$")}
+		if !share.Special {
+			share.Access, err = checkAccess(ctx, session, name)
+			if err != nil {
+				return nil, contextError(ctx, err)
+			}
+		}
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+// checkAccess mounts a share and opens its root for reading and writing
+// without creating or changing anything. Failure statuses leave the access
+// unknown, since other shares may still connect; only other errors, such as
+// a broken connection, are returned.
+func checkAccess(ctx context.Context, session *smb2.Session, name string) (Access, error) {
+	fs, err := session.Mount(name)
+	if err != nil {
+		return accessOnFailure(err)
+	}
+	defer fs.Umount()
+	fs = fs.WithContext(ctx)
+
+	root, err := fs.OpenFile(".", os.O_RDWR, 0)
+	if err == nil {
+		root.Close()
+		return AccessReadWrite, nil
+	}
+	if !errors.Is(wrapError(err), ErrAccessDenied) {
+		return accessOnFailure(err)
+	}
+
+	root, err = fs.Open(".")
+	if err == nil {
+		root.Close()
+		return AccessRead, nil
+	}
+	return accessOnFailure(err)
+}
+
+// accessOnFailure maps a failed share check to an Access.
+func accessOnFailure(err error) (Access, error) {
+	var statusErr *StatusError
+	switch err := wrapError(err); {
+	case errors.Is(err, ErrAccessDenied), errors.As(err, &statusErr) && statusErr.Status == statusBadNetworkName:
+		return AccessNone, nil
+	case errors.As(err, &statusErr):
+		return AccessUnknown, nil
+	default:
+		return AccessUnknown, err
+	}
+}
+
+// wrapError converts go-smb2 failure statuses, including the access denied
+// status it reports as os.ErrPermission, to StatusError.
+func wrapError(err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return &StatusError{Status: statusAccessDenied}
+	}
+	var respErr *smb2.ResponseError
+	if errors.As(err, &respErr) {
+		return &StatusError{Status: respErr.Code}
+	}
+	return err
+}
+
+// contextError reports a failure caused by ctx ending as the context's error.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return wrapError(err)
+}
+
+// splitDomain splits DOMAIN\user; other forms are passed through as the user.
+func splitDomain(username string) (domain, user string) {
+	if d, u, ok := strings.Cut(username, `\`); ok {
+		return d, u
+	}
+	return "", username
+}
@@ -0,0 +1,59 @@
+package smb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+func TestWrapError(t *testing.T) {
+	logon := wrapError(&smb2.ResponseError{Code: statusLogonFailure})
+	if !errors.Is(logon, ErrAccessDenied) {
+		t.Errorf("logon failure = %v, want ErrAccessDenied", logon)
+	}
+
+	denied := wrapError(&os.PathError{Op: "open", Err: os.ErrPermission})
+	if !errors.Is(denied, ErrAccessDenied) {
+		t.Errorf("permission error = %v, want ErrAccessDenied", denied)
+	}
+
+	if err := wrapError(&smb2.ResponseError{Code: statusBadNetworkName}); errors.Is(err, ErrAccessDenied) {
+		t.Errorf("bad network name = %v, want not ErrAccessDenied", err)
+	}
+	if err := wrapError(io.EOF); err != io.EOF {
+		t.Errorf("EOF = %v, want it unchanged", err)
+	}
+}
+
+func TestAccessOnFailure(t *testing.T) {
+	tests := []struct {
+		err     error
+		want    Access
+		wantErr bool
+	}{
+		{&smb2.ResponseError{Code: statusAccessDenied}, AccessNone, false},
+		{&os.PathError{Op: "open", Err: os.ErrPermission}, AccessNone, false},
+		{&smb2.ResponseError{Code: statusBadNetworkName}, AccessNone, false},
+		{&smb2.ResponseError{Code: 0xC00000BB}, AccessUnknown, false}, // STATUS_NOT_SUPPORTED
+		{fmt.Errorf("read: %w", io.EOF), AccessUnknown, true},
+	}
+	for _, tt := range tests {
+		got, err := accessOnFailure(tt.err)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("accessOnFailure(%v) = %v, %v; want %v, error %v", tt.err, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSplitDomain(t *testing.T) {
+	if d, u := splitDomain(`WORKGROUP\alice`); d != "WORKGROUP" || u != "alice" {
+		t.Errorf("splitDomain = %q, %q", d, u)
+	}
+	if d, u := splitDomain("alice@example.com"); d != "" || u != "alice@example.com" {
+		t.Errorf("splitDomain = %q, %q", d, u)
+	}
+}
@@ -0,0 +1,137 @@
+// Package hostname changes the device hostname, keeping /etc/hostname,
+// /etc/hosts and the running system in step.
+package hostname
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// MaxLength is the longest hostname accepted: a single DNS label (RFC 1123).
+const MaxLength = 63
+
+// hostsLoopback is the address Debian and Raspberry Pi OS map the hostname
+// to in /etc/hosts.
+const hostsLoopback = "127.0.1.1"
+
+// Files edited by Set.
+const (
+	hostnameFile = "/etc/hostname"
+	hostsFile    = "/etc/hosts"
+)
+
+// applyCommand makes the running system use the new hostname; the name is
+// appended. A variable so tests can stand in for it.
+var applyCommand = []string{"sudo", "hostnamectl", "set-hostname"}
+
+// Files reads and writes the root-owned files Set edits, such as
+// socketio.SudoConfigWriter, which writes through sudo.
+type Files interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, content []byte) error
+}
+
+// Validate checks name is a valid single-label hostname (RFC 1123): 1-63
+// letters, digits and hyphens, not starting or ending with a hyphen. Dots
+// are refused since the name is also advertised as <name>.local.
+func Validate(name string) error {
+	if name == "" || len(name) > MaxLength {
+		return fmt.Errorf("hostname must be 1-%d characters", MaxLength)
+	}
+	if name[0] == '-' || name[len(name)-1] == '-' {
+		return errors.New("hostname cannot start or end with a hyphen")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Errorf("hostname may only contain letters, digits and hyphens, not %q", r)
+		}
+	}
+	return nil
+}
+
+// Set changes the hostname to name: it writes /etc/hostname and /etc/hosts
+// through files, then applies the name with hostnamectl. If any step fails,
+// the files written so far are restored so they never disagree.
+func Set(files Files, name string) error {
+	if err := Validate(name); err != nil {
+		return err
+	}
+	old, _ := os.Hostname()
+
+	oldHostname, err := files.ReadFile(hostnameFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading %s: %w", hostnameFile, err)
+	}
+	oldHosts, err := files.ReadFile(hostsFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading %s: %w", hostsFile, err)
+	}
+
+	var written []string
+	restore := func() {
+		for _, path := range written {
+			content := oldHostname
+			if path == hostsFile {
+				content = oldHosts
+			}
+			files.WriteFile(path, content)
+		}
+	}
+
+	if err := files.WriteFile(hostnameFile, []byte(name+"\n")); err != nil {
+		return fmt.Errorf("writing %s: %w", hostnameFile, err)
+	}
+	written = append(written, hostnameFile)
+	if err := files.WriteFile(hostsFile, []byte(updateHosts(string(oldHosts), old, name))); err != nil {
+		restore()
+		return fmt.Errorf("writing %s: %w", hostsFile, err)
+	}
+	written = append(written, hostsFile)
+
+	args := append(append([]string{}, applyCommand[1:]...), name)
+	if out, err := exec.Command(applyCommand[0], args...).CombinedOutput(); err != nil {
+		restore()
+		return fmt.Errorf("applying hostname: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// updateHosts renames old to name in an /etc/hosts file. Only the
+// 127.0.1.1 entry and other entries naming old are changed; if there is no
+// 127.0.1.1 entry, one is added so the name keeps resolving locally.
+func updateHosts(hosts, old, name string) string {
+	lines := strings.Split(strings.TrimSuffix(hosts, "\n"), "\n")
+	if hosts == "" {
+		lines = nil
+	}
+
+	found := false
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == hostsLoopback {
+			lines[i] = hostsLoopback + "\t" + name
+			found = true
+			continue
+		}
+		changed := false
+		for j, field := range fields[1:] {
+			if old != "" && field == old {
+				fields[j+1] = name
+				changed = true
+			}
+		}
+		if changed {
+			lines[i] = fields[0] + "\t" + strings.Join(fields[1:], " ")
+		}
+	}
+	if !found {
+		lines = append(lines, hostsLoopback+"\t"+name)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,135 @@
+package hostname
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// memFiles is an in-memory Files that can fail writes to one path.
+type memFiles struct {
+	data      map[string]string
+	failWrite string
+}
+
+func (m *memFiles) ReadFile(path string) ([]byte, error) {
+	content, ok := m.data[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (m *memFiles) WriteFile(path string, content []byte) error {
+	if path == m.failWrite {
+		return errors.New("permission denied")
+	}
+	m.data[path] = string(content)
+	return nil
+}
+
+func withApplyCommand(t *testing.T, command ...string) {
+	t.Helper()
+	saved := applyCommand
+	applyCommand = command
+	t.Cleanup(func() { applyCommand = saved })
+}
+
+func TestSet(t *testing.T) {
+	withApplyCommand(t, "true")
+	files := &memFiles{data: map[string]string{
+		hostnameFile: "stellar\n",
+		hostsFile:    "127.0.0.1\tlocalhost\n127.0.1.1\tstellar\n",
+	}}
+
+	if err := Set(files, "kitchen"); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+	if got := files.data[hostnameFile]; got != "kitchen\n" {
+		t.Errorf("%s = %q, want kitchen", hostnameFile, got)
+	}
+	if got := files.data[hostsFile]; !strings.Contains(got, "127.0.1.1\tkitchen\n") {
+		t.Errorf("%s = %q, want the 127.0.1.1 entry renamed", hostsFile, got)
+	}
+}
+
+func TestSet_RestoresFilesOnFailure(t *testing.T) {
+	original := map[string]string{
+		hostnameFile: "stellar\n",
+		hostsFile:    "127.0.0.1\tlocalhost\n127.0.1.1\tstellar\n",
+	}
+	tests := []struct {
+		name      string
+		apply     string
+		failWrite string
+	}{
+		{"hosts write fails", "true", hostsFile},
+		{"hostnamectl fails", "false", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withApplyCommand(t, tt.apply)
+			files := &memFiles{data: map[string]string{}, failWrite: tt.failWrite}
+			for path, content := range original {
+				files.data[path] = content
+			}
+
+			if err := Set(files, "kitchen"); err == nil {
+				t.Fatal("Set() succeeded, want error")
+			}
+			for path, want := range original {
+				if got := files.data[path]; got != want {
+					t.Errorf("%s = %q after failure, want %q restored", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"stellar", "Living-Room", "pi4", "a", strings.Repeat("x", 63)}
+	for _, name := range valid {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", name, err)
+		}
+	}
+
+	invalid := []string{"", strings.Repeat("x", 64), "-stellar", "stellar-", "living room", "stellar.local", "stéllar", "under_score"}
+	for _, name := range invalid {
+		if err := Validate(name); err == nil {
+			t.Errorf("Validate(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestUpdateHosts(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts string
+		want  string
+	}{
+		{
+			"debian layout",
+			"127.0.0.1\tlocalhost\n::1\t\tlocalhost ip6-localhost ip6-loopback\n\n127.0.1.1\t\tstellar\n",
+			"127.0.0.1\tlocalhost\n::1\t\tlocalhost ip6-localhost ip6-loopback\n\n127.0.1.1\tkitchen\n",
+		},
+		{
+			"old name on another entry",
+			"127.0.0.1 localhost stellar\n# stellar is the player\n",
+			"127.0.0.1\tlocalhost kitchen\n# stellar is the player\n127.0.1.1\tkitchen\n",
+		},
+		{
+			"empty file",
+			"",
+			"127.0.1.1\tkitchen\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := updateHosts(tt.hosts, "stellar", "kitchen"); got != tt.want {
+				t.Errorf("updateHosts() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
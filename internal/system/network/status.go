@@ -0,0 +1,214 @@
+// Package network reports the device's network connection: which interface
+// is up, its addresses, and Wi-Fi signal or ethernet link speed.
+package network
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Status represents the current network connection status.
+type Status struct {
+	Type     string `json:"type"`     // "wifi", "ethernet", "none"
+	SSID     string `json:"ssid"`     // WiFi network name (if wifi)
+	Signal   int    `json:"signal"`   // WiFi signal strength 0-100 (if wifi)
+	IP       string `json:"ip"`       // IP address
+	Strength int    `json:"strength"` // Signal strength level 0-3 (for icon)
+
+	// Interface details for diagnostics
+	Interface string   `json:"interface,omitempty"` // e.g. eth0 or wlan0
+	MAC       string   `json:"mac,omitempty"`
+	Gateway   string   `json:"gateway,omitempty"`
+	DNS       []string `json:"dns,omitempty"`
+	LinkSpeed int      `json:"linkSpeed,omitempty"` // Ethernet link speed in Mbit/s
+}
+
+// GetStatus returns the current network connection status.
+func GetStatus() Status {
+	status := Status{
+		Type:     "none",
+		Signal:   0,
+		Strength: 0,
+	}
+
+	// Check ethernet first (usually eth0 or end0 on newer Pi)
+	for _, iface := range []string{"eth0", "end0"} {
+		carrierPath := "/sys/class/net/" + iface + "/carrier"
+		if data, err := os.ReadFile(carrierPath); err == nil {
+			if strings.TrimSpace(string(data)) == "1" {
+				status.Type = "ethernet"
+				status.IP = getIPAddress(iface)
+				status.Signal = 100
+				status.Strength = 3
+				addDetails(&status, iface)
+				status.LinkSpeed = readIntFile("/sys/class/net/" + iface + "/speed")
+				return status
+			}
+		}
+	}
+
+	// Check WiFi (usually wlan0)
+	for _, iface := range []string{"wlan0", "wlan1"} {
+		operstatePath := "/sys/class/net/" + iface + "/operstate"
+		if data, err := os.ReadFile(operstatePath); err == nil {
+			if strings.TrimSpace(string(data)) == "up" {
+				status.Type = "wifi"
+				status.IP = getIPAddress(iface)
+				status.SSID, status.Signal = getWifiInfo(iface)
+				// Convert signal to strength level (0-3)
+				switch {
+				case status.Signal >= 70:
+					status.Strength = 3 // Full signal
+				case status.Signal >= 50:
+					status.Strength = 2 // Medium
+				case status.Signal >= 30:
+					status.Strength = 1 // Weak
+				default:
+					status.Strength = 0 // Very weak
+				}
+				addDetails(&status, iface)
+				return status
+			}
+		}
+	}
+
+	return status
+}
+
+// addDetails fills in the interface, MAC address, default gateway and DNS
+// servers for the connected interface.
+func addDetails(status *Status, iface string) {
+	status.Interface = iface
+	if data, err := os.ReadFile("/sys/class/net/" + iface + "/address"); err == nil {
+		status.MAC = strings.TrimSpace(string(data))
+	}
+	if data, err := os.ReadFile("/proc/net/route"); err == nil {
+		status.Gateway = parseDefaultGateway(string(data), iface)
+	}
+	if data, err := os.ReadFile("/etc/resolv.conf"); err == nil {
+		status.DNS = parseNameservers(string(data))
+	}
+}
+
+// parseDefaultGateway returns the default route's gateway for iface from
+// /proc/net/route, where addresses are little-endian hex.
+func parseDefaultGateway(routes, iface string) string {
+	for _, line := range strings.Split(routes, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != iface || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		return ip.String()
+	}
+	return ""
+}
+
+// parseNameservers returns the nameserver addresses in a resolv.conf.
+func parseNameservers(resolvConf string) []string {
+	var servers []string
+	for _, line := range strings.Split(resolvConf, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// readIntFile returns the integer in a sysfs file, or 0 if it can't be read
+// or is negative, as speed is while the link is down.
+func readIntFile(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// getIPAddress returns the IP address for a given interface.
+func getIPAddress(iface string) string {
+	out, err := exec.Command("ip", "-4", "addr", "show", iface).Output()
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(string(out), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "inet ") {
+			parts := strings.Fields(line)
+			if len(parts) >= 2 {
+				ip := strings.Split(parts[1], "/")[0]
+				return ip
+			}
+		}
+	}
+	return ""
+}
+
+// getWifiInfo returns SSID and signal strength (0-100) for a WiFi interface.
+func getWifiInfo(iface string) (string, int) {
+	ssid := ""
+
+	// Get SSID using iwgetid
+	out, err := exec.Command("iwgetid", iface, "-r").Output()
+	if err == nil {
+		ssid = strings.TrimSpace(string(out))
+	}
+
+	data, err := os.ReadFile("/proc/net/wireless")
+	if err != nil {
+		return ssid, 0
+	}
+	return ssid, parseWirelessSignal(string(data), iface)
+}
+
+// parseWirelessSignal returns the signal strength (0-100) of iface from
+// /proc/net/wireless, whose lines read "wlan0: 0000   54.  -56.  -256 ...":
+// status, link quality, signal level and noise. Link quality is preferred,
+// falling back to the signal level in dBm.
+func parseWirelessSignal(wireless, iface string) int {
+	for _, line := range strings.Split(wireless, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.TrimSuffix(fields[0], ":") != iface {
+			continue
+		}
+
+		if q, err := strconv.Atoi(strings.TrimSuffix(fields[2], ".")); err == nil {
+			// Link quality is usually out of 70 (the iwconfig scale), but
+			// some drivers report a percentage
+			switch {
+			case q > 0 && q <= 70:
+				return (q * 100) / 70
+			case q > 70 && q <= 100:
+				return q
+			}
+		}
+		if dbm, err := strconv.Atoi(strings.TrimSuffix(fields[3], ".")); err == nil && dbm < 0 {
+			return SignalPercent(dbm)
+		}
+		return 0
+	}
+	return 0
+}
+
+// SignalPercent converts a signal level in dBm to 0-100, -100 dBm and below
+// being 0 and -50 dBm and above 100.
+func SignalPercent(dbm int) int {
+	return min(max(2*(dbm+100), 0), 100)
+}
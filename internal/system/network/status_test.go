@@ -0,0 +1,82 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetStatus(t *testing.T) {
+	// GetStatus should return a valid Status struct
+	status := GetStatus()
+
+	// Type should be one of: wifi, ethernet, none
+	validTypes := map[string]bool{"wifi": true, "ethernet": true, "none": true}
+	if !validTypes[status.Type] {
+		t.Errorf("Invalid network type: %s", status.Type)
+	}
+
+	// Strength should be 0-3
+	if status.Strength < 0 || status.Strength > 3 {
+		t.Errorf("Invalid strength: %d (should be 0-3)", status.Strength)
+	}
+
+	// Signal should be 0-100
+	if status.Signal < 0 || status.Signal > 100 {
+		t.Errorf("Invalid signal: %d (should be 0-100)", status.Signal)
+	}
+}
+
+func TestParseDefaultGateway(t *testing.T) {
+	routes := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"wlan0\t00000000\t0101A8C0\t0003\t0\t0\t600\t00000000\t0\t0\t0\n" +
+		"eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n" +
+		"eth0\t00000000\tFEFFA8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n"
+
+	if got := parseDefaultGateway(routes, "wlan0"); got != "192.168.1.1" {
+		t.Errorf("parseDefaultGateway(wlan0) = %q, want 192.168.1.1", got)
+	}
+	if got := parseDefaultGateway(routes, "end0"); got != "" {
+		t.Errorf("parseDefaultGateway(end0) = %q, want none", got)
+	}
+}
+
+func TestParseNameservers(t *testing.T) {
+	conf := "# Generated by NetworkManager\nsearch home\nnameserver 192.168.1.1\nnameserver  fd00::1\n#nameserver 8.8.8.8\n"
+	want := []string{"192.168.1.1", "fd00::1"}
+	if got := parseNameservers(conf); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNameservers() = %v, want %v", got, want)
+	}
+}
+
+func TestParseWirelessSignal(t *testing.T) {
+	header := "Inter-| sta-|   Quality        |   Discarded packets               | Missed | WE\n" +
+		" face | tus | link level noise |  nwid  crypt   frag  retry   misc | beacon | 22\n"
+	tests := []struct {
+		name  string
+		lines string
+		iface string
+		want  int
+	}{
+		{"quality out of 70", "wlan0: 0000   49.  -61.  -256        0      0      0      0      0        0\n", "wlan0", 70},
+		{"quality as percentage", "wlan0: 0000   85.  -40.  -256        0      0      0      0      0        0\n", "wlan0", 85},
+		{"dBm when quality is zero", "wlan0: 0000    0.  -75.  -256        0      0      0      0      0        0\n", "wlan0", 50},
+		{"other interface", "wlan1: 0000   70.  -30.  -256        0      0      0      0      0        0\n", "wlan0", 0},
+		{"prefix of another interface", "wlan10: 0000   70.  -30.  -256        0      0      0      0      0        0\n", "wlan1", 0},
+		{"no interfaces", "", "wlan0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseWirelessSignal(header+tt.lines, tt.iface); got != tt.want {
+				t.Errorf("parseWirelessSignal() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignalPercent(t *testing.T) {
+	for dbm, want := range map[int]int{-110: 0, -100: 0, -75: 50, -50: 100, -30: 100} {
+		if got := SignalPercent(dbm); got != want {
+			t.Errorf("SignalPercent(%d) = %d, want %d", dbm, got, want)
+		}
+	}
+}
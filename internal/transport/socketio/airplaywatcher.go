@@ -0,0 +1,67 @@
+package socketio
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/airplay"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
+)
+
+// externalSourceAirplay marks player state that comes from an AirPlay client.
+const externalSourceAirplay = "airplay"
+
+// StartAirplayWatcher follows shairport-sync's metadata pipe. Session changes
+// are pushed as pushAirplayStatus and re-broadcast as player state, so the UI
+// shows the AirPlay track instead of stale MPD state.
+func (s *Server) StartAirplayWatcher(ctx context.Context) {
+	if s.airplayService == nil {
+		log.Debug().Msg("AirPlay watcher not started: airplay service not available")
+		return
+	}
+
+	s.airplayService.Watch(ctx, func(playback airplay.Playback) {
+		log.Info().
+			Bool("active", playback.Active).
+			Bool("playing", playback.Playing).
+			Str("client", playback.Client).
+			Str("title", playback.Track.Title).
+			Msg("AirPlay session changed")
+
+		s.emitAll("pushAirplayStatus", playback)
+		s.BroadcastState()
+	})
+}
+
+// applyExternalSource overlays an active AirPlay session onto MPD state.
+func (s *Server) applyExternalSource(state *player.PlayerState) {
+	if s.airplayService == nil {
+		return
+	}
+
+	playback := s.airplayService.Playback()
+	if !playback.Active {
+		return
+	}
+
+	status := player.StatusPause
+	if playback.Playing {
+		status = player.StatusPlay
+	}
+
+	state.ExternalSource = externalSourceAirplay
+	state.ExternalClient = playback.Client
+	state.Service = externalSourceAirplay
+	state.TrackType = externalSourceAirplay
+	state.Status = status
+	state.Title = playback.Track.Title
+	state.Artist = playback.Track.Artist
+	state.Album = playback.Track.Album
+	state.AlbumArt = ""
+	state.URI = ""
+	state.Seek = 0
+	state.Elapsed = 0
+	state.Duration = 0
+	state.Playing = false // No position to interpolate
+}
@@ -2,20 +2,49 @@
 package socketio
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/audio"
+)
+
+const (
+	// mpdConfigPath is the MPD configuration file managed by the audio settings.
+	mpdConfigPath = "/etc/mpd.conf"
+	// alsaConfigPath is the system-wide ALSA configuration file.
+	alsaConfigPath = "/etc/asound.conf"
+	// mpdServiceName is the service restarted after MPD config changes.
+	mpdServiceName = "mpd"
+	// mpdConfigBackupPrefix is the path prefix for timestamped config backups.
+	mpdConfigBackupPrefix = "/etc/mpd.conf.stellar.bak"
+	// mpdConfigBackupTimeFormat sorts lexicographically in chronological order.
+	mpdConfigBackupTimeFormat = "20060102-150405.000"
+	// maxMPDConfigBackups is the number of backups kept before pruning the oldest.
+	maxMPDConfigBackups = 5
+	// mpdConfigCheckTimeout bounds an MPD config check run.
+	mpdConfigCheckTimeout = 5 * time.Second
 )
 
+// mpdBinary is the MPD executable used to check configs before writing them.
+var mpdBinary = "mpd"
+
 // BitPerfectStatus represents the result of a bit-perfect configuration check.
 type BitPerfectStatus struct {
 	Status   string   `json:"status"`   // "ok", "warning", "error"
 	Issues   []string `json:"issues"`   // Critical issues preventing bit-perfect
 	Warnings []string `json:"warnings"` // Non-critical warnings
 	Config   []string `json:"config"`   // Current configuration details
+
+	Exclusive    bool `json:"exclusive"`              // Exclusive MPD access mode is on
+	OtherClients int  `json:"otherClients,omitempty"` // MPD clients other than this backend
 }
 
 // PlaybackOption represents an audio output option.
@@ -59,6 +88,13 @@ type MixerModeResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// RollbackMPDConfigResponse represents the result of restoring an MPD config backup.
+type RollbackMPDConfigResponse struct {
+	Success bool   `json:"success"`
+	Backup  string `json:"backup,omitempty"` // Backup file that was restored
+	Error   string `json:"error,omitempty"`
+}
+
 // ApplyBitPerfectResponse represents the result of applying all bit-perfect settings.
 type ApplyBitPerfectResponse struct {
 	Success bool     `json:"success"`
@@ -66,8 +102,69 @@ type ApplyBitPerfectResponse struct {
 	Errors  []string `json:"errors"`  // Any errors encountered
 }
 
+// AudioConfig manages the audio settings stored in the MPD config file.
+// File access and MPD restarts go through injectable interfaces so the
+// settings logic can be tested without touching the system.
+type AudioConfig struct {
+	writer   ConfigWriter
+	services ServiceManager
+}
+
+// NewAudioConfig creates an AudioConfig using the given config writer and service manager.
+func NewAudioConfig(writer ConfigWriter, services ServiceManager) *AudioConfig {
+	return &AudioConfig{
+		writer:   writer,
+		services: services,
+	}
+}
+
+// NewDefaultAudioConfig creates an AudioConfig that writes via sudo and restarts MPD via systemd.
+func NewDefaultAudioConfig() *AudioConfig {
+	return NewAudioConfig(NewSudoConfigWriter(), NewSystemdServiceManager())
+}
+
+// defaultAudioConfig backs the package-level audio config functions.
+var defaultAudioConfig = NewDefaultAudioConfig()
+
+// GetPlaybackOptions returns available audio output devices using the default AudioConfig.
+func GetPlaybackOptions() PlaybackOptionsResponse { return defaultAudioConfig.GetPlaybackOptions() }
+
+// GetCurrentAudioOutput reads the current audio output device using the default AudioConfig.
+func GetCurrentAudioOutput() string { return defaultAudioConfig.GetCurrentAudioOutput() }
+
+// SetPlaybackSettings changes the audio output device using the default AudioConfig.
+func SetPlaybackSettings(deviceName string) error {
+	return defaultAudioConfig.SetPlaybackSettings(deviceName)
+}
+
+// GetBitPerfectStatus checks bit-perfect configuration using the default AudioConfig.
+func GetBitPerfectStatus() BitPerfectStatus { return defaultAudioConfig.GetBitPerfectStatus() }
+
+// RollbackMPDConfig restores the latest MPD config backup using the default AudioConfig.
+func RollbackMPDConfig() RollbackMPDConfigResponse { return defaultAudioConfig.RollbackMPDConfig() }
+
+// GetDsdMode returns the DSD playback mode using the default AudioConfig.
+func GetDsdMode() DsdModeResponse { return defaultAudioConfig.GetDsdMode() }
+
+// SetDsdMode sets the DSD playback mode using the default AudioConfig.
+func SetDsdMode(mode string) DsdModeResponse { return defaultAudioConfig.SetDsdMode(mode) }
+
+// GetMixerMode returns the mixer mode using the default AudioConfig.
+func GetMixerMode() MixerModeResponse { return defaultAudioConfig.GetMixerMode() }
+
+// SetMixerMode sets the mixer mode using the default AudioConfig.
+func SetMixerMode(enabled bool) MixerModeResponse { return defaultAudioConfig.SetMixerMode(enabled) }
+
+// ApplyBitPerfect applies bit-perfect settings using the default AudioConfig.
+func ApplyBitPerfect() ApplyBitPerfectResponse { return defaultAudioConfig.ApplyBitPerfect() }
+
+// restartMPD restarts the MPD service so config changes take effect.
+func (c *AudioConfig) restartMPD() error {
+	return c.services.Restart(mpdServiceName)
+}
+
 // GetPlaybackOptions returns available audio output devices.
-func GetPlaybackOptions() PlaybackOptionsResponse {
+func (c *AudioConfig) GetPlaybackOptions() PlaybackOptionsResponse {
 	response := PlaybackOptionsResponse{
 		Options:     []PlaybackOptionsSection{},
 		SystemCards: []string{},
@@ -125,7 +222,7 @@ func GetPlaybackOptions() PlaybackOptionsResponse {
 		}
 	}
 
-	selectedDevice := GetCurrentAudioOutput()
+	selectedDevice := c.GetCurrentAudioOutput()
 
 	if selectedDevice == "" {
 		for _, opt := range options {
@@ -160,8 +257,8 @@ func GetPlaybackOptions() PlaybackOptionsResponse {
 }
 
 // GetCurrentAudioOutput reads the current audio output device from MPD config.
-func GetCurrentAudioOutput() string {
-	data, err := os.ReadFile("/etc/mpd.conf")
+func (c *AudioConfig) GetCurrentAudioOutput() string {
+	data, err := c.writer.ReadFile(mpdConfigPath)
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to read MPD config for audio output")
 		return ""
@@ -234,13 +331,13 @@ func getCardNameByNumber(cardNum string) string {
 }
 
 // SetPlaybackSettings changes the audio output device in MPD config.
-func SetPlaybackSettings(deviceName string) error {
+func (c *AudioConfig) SetPlaybackSettings(deviceName string) error {
 	cardNum := getCardNumberByName(deviceName)
 	if cardNum == "" {
 		return exec.ErrNotFound
 	}
 
-	data, err := os.ReadFile("/etc/mpd.conf")
+	data, err := c.writer.ReadFile(mpdConfigPath)
 	if err != nil {
 		return err
 	}
@@ -274,12 +371,11 @@ func SetPlaybackSettings(deviceName string) error {
 	}
 
 	newContent := strings.Join(newLines, "\n")
-	if err := writeMPDConfig(newContent); err != nil {
+	if err := c.writeMPDConfig(newContent); err != nil {
 		return err
 	}
 
-	cmd := exec.Command("sudo", "systemctl", "restart", "mpd")
-	if err := cmd.Run(); err != nil {
+	if err := c.restartMPD(); err != nil {
 		log.Error().Err(err).Msg("Failed to restart MPD after changing audio output")
 		return err
 	}
@@ -309,16 +405,16 @@ func getCardNumberByName(cardName string) string {
 }
 
 // GetBitPerfectStatus checks bit-perfect audio configuration natively in Go.
-func GetBitPerfectStatus() BitPerfectStatus {
+func (c *AudioConfig) GetBitPerfectStatus() BitPerfectStatus {
 	mpdConfig := ""
-	if data, err := os.ReadFile("/etc/mpd.conf"); err == nil {
+	if data, err := c.writer.ReadFile(mpdConfigPath); err == nil {
 		mpdConfig = string(data)
 	} else {
 		log.Warn().Err(err).Msg("Failed to read MPD config")
 	}
 
 	alsaConfig := ""
-	if data, err := os.ReadFile("/etc/asound.conf"); err == nil {
+	if data, err := c.writer.ReadFile(alsaConfigPath); err == nil {
 		alsaConfig = string(data)
 	}
 
@@ -404,6 +500,19 @@ func CheckBitPerfectFromConfig(mpdConfig, alsaConfig, aplayOutput string) BitPer
 		}
 	}
 
+	// Check 7b: ALSA DSP plugins (EQ, crossfeed, downmix, resampling). They
+	// only touch the audio when MPD plays through the ALSA PCM chain; a
+	// direct hw: device bypasses them.
+	directHW := strings.HasPrefix(extractConfigValue(mpdConfig, "device"), "hw:")
+	for _, plugin := range alsaDSPPlugins(alsaConfig) {
+		msg := fmt.Sprintf("ALSA: '%s' plugin (%s) configured", plugin, alsaDSPPluginTypes[plugin])
+		if directHW {
+			status.Warnings = append(status.Warnings, msg+" - bypassed by direct hardware output")
+		} else {
+			status.Issues = append(status.Issues, msg+" - audio will be processed")
+		}
+	}
+
 	// Check 8: USB DAC presence (Singxer SU-6)
 	if aplayOutput != "" {
 		if strings.Contains(aplayOutput, "U20SU6") || strings.Contains(aplayOutput, "SU-6") || strings.Contains(aplayOutput, "SU6") {
@@ -425,6 +534,42 @@ func CheckBitPerfectFromConfig(mpdConfig, alsaConfig, aplayOutput string) BitPer
 	return status
 }
 
+// alsaDSPPluginTypes maps ALSA PCM plugin types that alter the signal to a
+// description. "equal" and "bs2b" are the plugin names used by the alsaequal
+// and libbs2b packages.
+var alsaDSPPluginTypes = map[string]string{
+	"ladspa":    "LADSPA DSP",
+	"eq":        "equalizer",
+	"equal":     "equalizer",
+	"crossfeed": "crossfeed",
+	"bs2b":      "crossfeed",
+	"vdownmix":  "channel downmix",
+	"rate":      "sample rate conversion",
+}
+
+// alsaTypeRe matches a PCM "type" definition, e.g. `type ladspa` or `type "equal"`.
+var alsaTypeRe = regexp.MustCompile(`\btype\s*=?\s*"?([A-Za-z0-9_]+)"?`)
+
+// alsaDSPPlugins returns the DSP plugin types used in an ALSA config, in
+// order of first appearance. Comments are ignored.
+func alsaDSPPlugins(alsaConfig string) []string {
+	var plugins []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(alsaConfig, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, m := range alsaTypeRe.FindAllStringSubmatch(line, -1) {
+			plugin := m[1]
+			if _, ok := alsaDSPPluginTypes[plugin]; ok && !seen[plugin] {
+				seen[plugin] = true
+				plugins = append(plugins, plugin)
+			}
+		}
+	}
+	return plugins
+}
+
 // matchConfigValue checks if a config setting has a specific value.
 func matchConfigValue(config, setting, value string) bool {
 	lines := strings.Split(config, "\n")
@@ -479,25 +624,343 @@ func NormalizeBitPerfectStatus(status BitPerfectStatus) BitPerfectStatus {
 	return status
 }
 
-// writeMPDConfig writes the MPD config file using sudo to handle permissions.
-func writeMPDConfig(content string) error {
-	cmd := exec.Command("sudo", "tee", "/etc/mpd.conf")
-	cmd.Stdin = strings.NewReader(content)
-	cmd.Stdout = nil
-	if err := cmd.Run(); err != nil {
+// writeMPDConfig writes the MPD config file through the config writer.
+// The current config is backed up first; the write is aborted if the backup fails.
+func (c *AudioConfig) writeMPDConfig(content string) error {
+	if err := validateMPDConfig(content); err != nil {
+		return fmt.Errorf("refusing to write invalid MPD config: %w", err)
+	}
+
+	backup, err := c.backupMPDConfig()
+	if err != nil {
+		return fmt.Errorf("failed to back up MPD config: %w", err)
+	}
+	log.Info().Str("backup", backup).Msg("MPD config backed up")
+
+	if err := c.writer.WriteFile(mpdConfigPath, []byte(content)); err != nil {
+		c.restoreMPDConfigBackup(backup)
 		return err
 	}
+
+	// Verify what actually landed on disk before MPD gets restarted with it
+	written, err := c.writer.ReadFile(mpdConfigPath)
+	if err == nil {
+		err = validateMPDConfig(string(written))
+	}
+	if err != nil {
+		c.restoreMPDConfigBackup(backup)
+		return fmt.Errorf("written MPD config failed validation, backup restored: %w", err)
+	}
 	return nil
 }
 
+// restoreMPDConfigBackup copies a backup over the live MPD config without restarting MPD.
+func (c *AudioConfig) restoreMPDConfigBackup(backup string) {
+	if err := c.writer.CopyFile(backup, mpdConfigPath); err != nil {
+		log.Error().Err(err).Str("backup", backup).Msg("Failed to restore MPD config backup")
+		return
+	}
+	log.Warn().Str("backup", backup).Msg("MPD config restored from backup")
+}
+
+// backupMPDConfig copies the current MPD config to a timestamped backup file
+// and prunes old backups beyond maxMPDConfigBackups.
+func (c *AudioConfig) backupMPDConfig() (string, error) {
+	backup := mpdConfigBackupPrefix + "." + time.Now().Format(mpdConfigBackupTimeFormat)
+	if err := c.writer.CopyFile(mpdConfigPath, backup); err != nil {
+		return "", err
+	}
+
+	backups, err := c.listMPDConfigBackups()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to list MPD config backups for pruning")
+		return backup, nil
+	}
+	for _, old := range staleBackups(backups, maxMPDConfigBackups) {
+		if err := c.writer.RemoveFile(old); err != nil {
+			log.Warn().Err(err).Str("backup", old).Msg("Failed to prune MPD config backup")
+		}
+	}
+
+	return backup, nil
+}
+
+// listMPDConfigBackups returns the existing MPD config backups, oldest first.
+func (c *AudioConfig) listMPDConfigBackups() ([]string, error) {
+	backups, err := c.writer.Glob(mpdConfigBackupPrefix + ".*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// staleBackups returns the backups that exceed the keep limit, oldest first.
+// The input must be sorted oldest first.
+func staleBackups(backups []string, keep int) []string {
+	if len(backups) <= keep {
+		return nil
+	}
+	return backups[:len(backups)-keep]
+}
+
+// RollbackMPDConfig restores the most recent MPD config backup and restarts MPD.
+// The restored backup is removed so repeated rollbacks step further back in history.
+func (c *AudioConfig) RollbackMPDConfig() RollbackMPDConfigResponse {
+	response := RollbackMPDConfigResponse{Success: false}
+
+	backups, err := c.listMPDConfigBackups()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list MPD config backups")
+		response.Error = "Failed to list MPD config backups: " + err.Error()
+		return response
+	}
+	if len(backups) == 0 {
+		response.Error = "No MPD config backup available"
+		return response
+	}
+
+	latest := backups[len(backups)-1]
+	response.Backup = latest
+
+	if err := c.writer.CopyFile(latest, mpdConfigPath); err != nil {
+		log.Error().Err(err).Str("backup", latest).Msg("Failed to restore MPD config backup")
+		response.Error = "Failed to restore MPD config: " + err.Error()
+		return response
+	}
+
+	if err := c.writer.RemoveFile(latest); err != nil {
+		log.Warn().Err(err).Str("backup", latest).Msg("Failed to remove restored MPD config backup")
+	}
+
+	if err := c.restartMPD(); err != nil {
+		log.Error().Err(err).Msg("Failed to restart MPD")
+		response.Error = "Config restored but failed to restart MPD: " + err.Error()
+		return response
+	}
+
+	log.Info().Str("backup", latest).Msg("MPD config rolled back successfully")
+	response.Success = true
+	return response
+}
+
+// replaceDopSetting returns the config content with the DoP setting changed to match mode.
+func replaceDopSetting(content, mode string) (string, error) {
+	dopValue := "no"
+	if mode == "dop" {
+		dopValue = "yes"
+	}
+
+	switch {
+	case strings.Contains(content, `dop             "yes"`):
+		return strings.Replace(content, `dop             "yes"`, `dop             "`+dopValue+`"`, 1), nil
+	case strings.Contains(content, `dop             "no"`):
+		return strings.Replace(content, `dop             "no"`, `dop             "`+dopValue+`"`, 1), nil
+	case strings.Contains(content, `dop "yes"`):
+		return strings.Replace(content, `dop "yes"`, `dop "`+dopValue+`"`, 1), nil
+	case strings.Contains(content, `dop "no"`):
+		return strings.Replace(content, `dop "no"`, `dop "`+dopValue+`"`, 1), nil
+	}
+	return "", fmt.Errorf("could not find dop setting in MPD config")
+}
+
+// replaceMixerType returns the config content with mixer_type set to "software" or "none".
+func replaceMixerType(content string, enabled bool) (string, error) {
+	mixerValue := "none"
+	if enabled {
+		mixerValue = "software"
+	}
+
+	re := regexp.MustCompile(`(mixer_type\s+)"(?:software|none)"`)
+	if !re.MatchString(content) {
+		return "", fmt.Errorf("could not find mixer_type setting in MPD config")
+	}
+	return re.ReplaceAllString(content, `${1}"`+mixerValue+`"`), nil
+}
+
+// bitPerfectSetting describes a single config replacement applied by ApplyBitPerfect.
+type bitPerfectSetting struct {
+	name        string
+	pattern     string
+	replacement string
+	checkOk     string
+}
+
+// bitPerfectSettings are the optimal settings enforced by ApplyBitPerfect.
+var bitPerfectSettings = []bitPerfectSetting{
+	{
+		name:        "mixer_type",
+		pattern:     `(mixer_type\s+)"software"`,
+		replacement: `${1}"none"`,
+		checkOk:     `mixer_type\s+"none"`,
+	},
+	{
+		name:        "auto_resample",
+		pattern:     `(auto_resample\s+)"yes"`,
+		replacement: `${1}"no"`,
+		checkOk:     `auto_resample\s+"no"`,
+	},
+	{
+		name:        "auto_format",
+		pattern:     `(auto_format\s+)"yes"`,
+		replacement: `${1}"no"`,
+		checkOk:     `auto_format\s+"no"`,
+	},
+	{
+		name:        "auto_channels",
+		pattern:     `(auto_channels\s+)"yes"`,
+		replacement: `${1}"no"`,
+		checkOk:     `auto_channels\s+"no"`,
+	},
+}
+
+// applyBitPerfectSettings returns the config content with all bit-perfect
+// replacements applied, along with a description of each changed setting.
+func applyBitPerfectSettings(content string) (string, []string) {
+	applied := []string{}
+	for _, setting := range bitPerfectSettings {
+		re := regexp.MustCompile(setting.pattern)
+		if re.MatchString(content) {
+			content = re.ReplaceAllString(content, setting.replacement)
+			applied = append(applied, setting.name+" = bit-perfect")
+		}
+	}
+	return content, applied
+}
+
+// validateMPDConfig checks MPD config content with MPD's own parser, falling
+// back to parseMPDConfig when the mpd binary is not installed.
+func validateMPDConfig(content string) error {
+	path, err := exec.LookPath(mpdBinary)
+	if err != nil {
+		return parseMPDConfig(content)
+	}
+	return checkMPDConfig(path, content)
+}
+
+// checkMPDConfig runs `mpd --kill` against a temporary copy of content. MPD
+// has no check-only mode, but --kill reads the whole config before looking
+// for the daemon to stop. With pid_file removed from the copy it then fails
+// with a fixed complaint about pid_file, which means the config parsed; any
+// other failure is a config error.
+func checkMPDConfig(mpdPath, content string) error {
+	tmp, err := os.CreateTemp("", "mpd-*.conf")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary MPD config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(withoutPidFile(content))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temporary MPD config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mpdConfigCheckTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, mpdPath, "--kill", tmp.Name()).CombinedOutput()
+	output := strings.TrimSpace(strings.ReplaceAll(string(out), tmp.Name(), "config"))
+	if err == nil || strings.Contains(output, "pid_file") {
+		return nil
+	}
+	if output == "" {
+		return fmt.Errorf("mpd rejected config: %w", err)
+	}
+	return fmt.Errorf("mpd rejected config: %s", output)
+}
+
+// withoutPidFile blanks pid_file settings, keeping line numbers intact for
+// MPD's error messages, so checking a config never signals the running MPD.
+func withoutPidFile(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		key, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+		key, _, _ = strings.Cut(key, "\t")
+		if key == "pid_file" {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseMPDConfig performs a structural parse of MPD config content.
+// Every non-comment line must be a `key "value"` pair, a block opener
+// (`name {`) or a block closer (`}`), and blocks must be balanced and not nested.
+func parseMPDConfig(content string) error {
+	depth := 0
+	for i, line := range strings.Split(content, "\n") {
+		lineNum := i + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		switch {
+		case trimmed == "}":
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("line %d: unexpected '}'", lineNum)
+			}
+		case strings.HasSuffix(trimmed, "{"):
+			if depth > 0 {
+				return fmt.Errorf("line %d: nested block not allowed", lineNum)
+			}
+			if strings.TrimSpace(strings.TrimSuffix(trimmed, "{")) == "" {
+				return fmt.Errorf("line %d: block without a name", lineNum)
+			}
+			depth++
+		default:
+			if err := parseMPDConfigLine(trimmed); err != nil {
+				return fmt.Errorf("line %d: %w", lineNum, err)
+			}
+		}
+	}
+
+	if depth != 0 {
+		return fmt.Errorf("unclosed block at end of config")
+	}
+	return nil
+}
+
+// parseMPDConfigLine checks that a trimmed line is a `key "value"` pair,
+// optionally followed by a comment.
+func parseMPDConfigLine(line string) error {
+	sep := strings.IndexAny(line, " \t")
+	if sep == -1 {
+		return fmt.Errorf("missing value for %q", line)
+	}
+
+	key := line[:sep]
+	value := strings.TrimSpace(line[sep:])
+	if !strings.HasPrefix(value, `"`) {
+		return fmt.Errorf("value for %q must be quoted", key)
+	}
+
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++ // Skip escaped character
+		case '"':
+			rest := strings.TrimSpace(value[i+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return fmt.Errorf("unexpected text after value for %q", key)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unterminated quoted value for %q", key)
+}
+
 // GetDsdMode returns the current DSD playback mode from MPD config.
-func GetDsdMode() DsdModeResponse {
+func (c *AudioConfig) GetDsdMode() DsdModeResponse {
 	response := DsdModeResponse{
 		Mode:    "native",
 		Success: true,
 	}
 
-	data, err := os.ReadFile("/etc/mpd.conf")
+	data, err := c.writer.ReadFile(mpdConfigPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to read MPD config")
 		response.Error = "Failed to read MPD config"
@@ -516,7 +979,7 @@ func GetDsdMode() DsdModeResponse {
 }
 
 // SetDsdMode sets the DSD playback mode in MPD config and restarts MPD.
-func SetDsdMode(mode string) DsdModeResponse {
+func (c *AudioConfig) SetDsdMode(mode string) DsdModeResponse {
 	response := DsdModeResponse{
 		Mode:    mode,
 		Success: false,
@@ -527,42 +990,26 @@ func SetDsdMode(mode string) DsdModeResponse {
 		return response
 	}
 
-	data, err := os.ReadFile("/etc/mpd.conf")
+	data, err := c.writer.ReadFile(mpdConfigPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to read MPD config")
 		response.Error = "Failed to read MPD config"
 		return response
 	}
 
-	content := string(data)
-	var newContent string
-
-	dopValue := "no"
-	if mode == "dop" {
-		dopValue = "yes"
-	}
-
-	if strings.Contains(content, `dop             "yes"`) {
-		newContent = strings.Replace(content, `dop             "yes"`, `dop             "`+dopValue+`"`, 1)
-	} else if strings.Contains(content, `dop             "no"`) {
-		newContent = strings.Replace(content, `dop             "no"`, `dop             "`+dopValue+`"`, 1)
-	} else if strings.Contains(content, `dop "yes"`) {
-		newContent = strings.Replace(content, `dop "yes"`, `dop "`+dopValue+`"`, 1)
-	} else if strings.Contains(content, `dop "no"`) {
-		newContent = strings.Replace(content, `dop "no"`, `dop "`+dopValue+`"`, 1)
-	} else {
-		response.Error = "Could not find dop setting in MPD config"
+	newContent, err := replaceDopSetting(string(data), mode)
+	if err != nil {
+		response.Error = err.Error()
 		return response
 	}
 
-	if err := writeMPDConfig(newContent); err != nil {
+	if err := c.writeMPDConfig(newContent); err != nil {
 		log.Error().Err(err).Msg("Failed to write MPD config")
 		response.Error = "Failed to write MPD config: " + err.Error()
 		return response
 	}
 
-	cmd := exec.Command("sudo", "systemctl", "restart", "mpd")
-	if err := cmd.Run(); err != nil {
+	if err := c.restartMPD(); err != nil {
 		log.Error().Err(err).Msg("Failed to restart MPD")
 		response.Error = "Config updated but failed to restart MPD: " + err.Error()
 		return response
@@ -574,13 +1021,13 @@ func SetDsdMode(mode string) DsdModeResponse {
 }
 
 // GetMixerMode returns whether software mixer is enabled.
-func GetMixerMode() MixerModeResponse {
+func (c *AudioConfig) GetMixerMode() MixerModeResponse {
 	response := MixerModeResponse{
 		Enabled: false,
 		Success: true,
 	}
 
-	data, err := os.ReadFile("/etc/mpd.conf")
+	data, err := c.writer.ReadFile(mpdConfigPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to read MPD config")
 		response.Error = "Failed to read MPD config"
@@ -607,41 +1054,32 @@ func GetMixerMode() MixerModeResponse {
 }
 
 // SetMixerMode enables or disables the software mixer in MPD config and restarts MPD.
-func SetMixerMode(enabled bool) MixerModeResponse {
+func (c *AudioConfig) SetMixerMode(enabled bool) MixerModeResponse {
 	response := MixerModeResponse{
 		Enabled: enabled,
 		Success: false,
 	}
 
-	data, err := os.ReadFile("/etc/mpd.conf")
+	data, err := c.writer.ReadFile(mpdConfigPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to read MPD config")
 		response.Error = "Failed to read MPD config"
 		return response
 	}
 
-	content := string(data)
-
-	mixerValue := "none"
-	if enabled {
-		mixerValue = "software"
-	}
-
-	re := regexp.MustCompile(`(mixer_type\s+)"(?:software|none)"`)
-	if !re.MatchString(content) {
-		response.Error = "Could not find mixer_type setting in MPD config"
+	newContent, err := replaceMixerType(string(data), enabled)
+	if err != nil {
+		response.Error = err.Error()
 		return response
 	}
-	newContent := re.ReplaceAllString(content, `${1}"`+mixerValue+`"`)
 
-	if err := writeMPDConfig(newContent); err != nil {
+	if err := c.writeMPDConfig(newContent); err != nil {
 		log.Error().Err(err).Msg("Failed to write MPD config")
 		response.Error = "Failed to write MPD config: " + err.Error()
 		return response
 	}
 
-	cmd := exec.Command("sudo", "systemctl", "restart", "mpd")
-	if err := cmd.Run(); err != nil {
+	if err := c.restartMPD(); err != nil {
 		log.Error().Err(err).Msg("Failed to restart MPD")
 		response.Error = "Config updated but failed to restart MPD: " + err.Error()
 		return response
@@ -653,14 +1091,14 @@ func SetMixerMode(enabled bool) MixerModeResponse {
 }
 
 // ApplyBitPerfect applies all optimal bit-perfect settings to MPD config.
-func ApplyBitPerfect() ApplyBitPerfectResponse {
+func (c *AudioConfig) ApplyBitPerfect() ApplyBitPerfectResponse {
 	response := ApplyBitPerfectResponse{
 		Success: false,
 		Applied: []string{},
 		Errors:  []string{},
 	}
 
-	data, err := os.ReadFile("/etc/mpd.conf")
+	data, err := c.writer.ReadFile(mpdConfigPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to read MPD config")
 		response.Errors = append(response.Errors, "Failed to read MPD config")
@@ -668,50 +1106,11 @@ func ApplyBitPerfect() ApplyBitPerfectResponse {
 	}
 
 	content := string(data)
-	newContent := content
-
-	settingsToApply := []struct {
-		name        string
-		pattern     string
-		replacement string
-		checkOk     string
-	}{
-		{
-			name:        "mixer_type",
-			pattern:     `(mixer_type\s+)"software"`,
-			replacement: `${1}"none"`,
-			checkOk:     `mixer_type\s+"none"`,
-		},
-		{
-			name:        "auto_resample",
-			pattern:     `(auto_resample\s+)"yes"`,
-			replacement: `${1}"no"`,
-			checkOk:     `auto_resample\s+"no"`,
-		},
-		{
-			name:        "auto_format",
-			pattern:     `(auto_format\s+)"yes"`,
-			replacement: `${1}"no"`,
-			checkOk:     `auto_format\s+"no"`,
-		},
-		{
-			name:        "auto_channels",
-			pattern:     `(auto_channels\s+)"yes"`,
-			replacement: `${1}"no"`,
-			checkOk:     `auto_channels\s+"no"`,
-		},
-	}
-
-	for _, setting := range settingsToApply {
-		re := regexp.MustCompile(setting.pattern)
-		if re.MatchString(newContent) {
-			newContent = re.ReplaceAllString(newContent, setting.replacement)
-			response.Applied = append(response.Applied, setting.name+" = bit-perfect")
-		}
-	}
+	newContent, applied := applyBitPerfectSettings(content)
+	response.Applied = append(response.Applied, applied...)
 
 	if len(response.Applied) == 0 {
-		for _, setting := range settingsToApply {
+		for _, setting := range bitPerfectSettings {
 			re := regexp.MustCompile(setting.checkOk)
 			if re.MatchString(content) {
 				response.Applied = append(response.Applied, setting.name+" already set to optimal")
@@ -722,14 +1121,13 @@ func ApplyBitPerfect() ApplyBitPerfectResponse {
 		return response
 	}
 
-	if err := writeMPDConfig(newContent); err != nil {
+	if err := c.writeMPDConfig(newContent); err != nil {
 		log.Error().Err(err).Msg("Failed to write MPD config")
 		response.Errors = append(response.Errors, "Failed to write MPD config: "+err.Error())
 		return response
 	}
 
-	cmd := exec.Command("sudo", "systemctl", "restart", "mpd")
-	if err := cmd.Run(); err != nil {
+	if err := c.restartMPD(); err != nil {
 		log.Error().Err(err).Msg("Failed to restart MPD")
 		response.Errors = append(response.Errors, "Config updated but failed to restart MPD: "+err.Error())
 		return response
@@ -743,6 +1141,33 @@ func ApplyBitPerfect() ApplyBitPerfectResponse {
 // BroadcastAudioStatus sends audio status to all connected clients.
 func (s *Server) BroadcastAudioStatus() {
 	status := s.audioController.GetStatus()
-	s.io.Emit("pushAudioStatus", status)
+	s.emitAll("pushAudioStatus", status)
 	log.Debug().Bool("locked", status.Locked).Interface("format", status.Format).Msg("Broadcast audio status")
 }
+
+// audioLockRecheckDelay is how long after a playback change the audio status
+// is sent again, as MPD reports playing before the ALSA device is running.
+const audioLockRecheckDelay = time.Second
+
+// recheckAudioLock sends the audio status again once the DAC has had time
+// to lock onto (or drop) the stream. Without ALSA state the lock follows
+// MPD's play state, which was already sent.
+func (s *Server) recheckAudioLock() {
+	time.AfterFunc(audioLockRecheckDelay, func() {
+		if s.audioController.GetStatus().LockSource == audio.LockSourceALSA {
+			s.BroadcastAudioStatus()
+		}
+	})
+}
+
+// syncDsdMode passes the DSD mode in the MPD config to the audio controller
+// so DSD formats in pushAudioStatus say whether they play native or as DoP.
+func (s *Server) syncDsdMode() {
+	mode := s.audioConfig.GetDsdMode()
+	if !mode.Success {
+		return
+	}
+	if s.audioController.SetDsdMode(mode.Mode) {
+		s.BroadcastAudioStatus()
+	}
+}
@@ -0,0 +1,493 @@
+package socketio
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaleBackups(t *testing.T) {
+	backups := []string{"a", "b", "c", "d", "e", "f", "g"}
+
+	tests := []struct {
+		name string
+		in   []string
+		keep int
+		want []string
+	}{
+		{name: "under limit", in: backups[:3], keep: 5, want: nil},
+		{name: "at limit", in: backups[:5], keep: 5, want: nil},
+		{name: "over limit", in: backups, keep: 5, want: []string{"a", "b"}},
+		{name: "empty", in: nil, keep: 5, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := staleBackups(tt.in, tt.keep)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("staleBackups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMPDConfigBackupTimeFormat_SortsChronologically(t *testing.T) {
+	base := time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+	times := []time.Time{
+		base.Add(2 * time.Second),
+		base,
+		base.Add(1500 * time.Millisecond),
+		base.Add(10 * time.Millisecond),
+	}
+
+	var names []string
+	for _, ts := range times {
+		names = append(names, mpdConfigBackupPrefix+"."+ts.Format(mpdConfigBackupTimeFormat))
+	}
+	sort.Strings(names)
+
+	for i := 1; i < len(names); i++ {
+		prev, _ := time.Parse(mpdConfigBackupTimeFormat, names[i-1][len(mpdConfigBackupPrefix)+1:])
+		cur, _ := time.Parse(mpdConfigBackupTimeFormat, names[i][len(mpdConfigBackupPrefix)+1:])
+		if !prev.Before(cur) {
+			t.Errorf("backup names not in chronological order: %s before %s", names[i-1], names[i])
+		}
+	}
+}
+
+// sampleMPDConfig mirrors the layout of configs/mpd.conf.bitperfect with every
+// setting that the audio config functions rewrite.
+const sampleMPDConfig = `# MPD configuration
+music_directory    "/var/lib/mpd/music"
+playlist_directory "/var/lib/mpd/playlists"
+
+audio_output {
+    type            "alsa"
+    name            "USB DAC"
+    device          "hw:1,0"
+    mixer_type      "software"
+    auto_resample   "yes"
+    auto_format     "yes"
+    auto_channels   "yes"
+    dop             "no"
+}
+
+decoder {
+    plugin "wildmidi"
+    enabled "no"
+}
+`
+
+func TestParseMPDConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "sample config", config: sampleMPDConfig},
+		{name: "empty", config: ""},
+		{name: "comments only", config: "# nothing\n   # here\n"},
+		{name: "trailing comment", config: `bind_to_address "any" # listen everywhere`},
+		{name: "escaped quote", config: `name "My \"Best\" DAC"`},
+		{name: "tab separated", config: "audio_output {\n\ttype\t\"alsa\"\n}"},
+		{name: "unbalanced open", config: "audio_output {\n    type \"alsa\"\n", wantErr: true},
+		{name: "unbalanced close", config: "type \"alsa\"\n}\n", wantErr: true},
+		{name: "nested block", config: "audio_output {\n    decoder {\n    }\n}", wantErr: true},
+		{name: "unquoted value", config: "mixer_type none", wantErr: true},
+		{name: "missing value", config: "mixer_type", wantErr: true},
+		{name: "unterminated quote", config: `mixer_type "none`, wantErr: true},
+		{name: "junk after value", config: `mixer_type "none" extra`, wantErr: true},
+		{name: "anonymous block", config: "{\n}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseMPDConfig(tt.config)
+			if tt.wantErr && err == nil {
+				t.Error("parseMPDConfig() succeeded, want error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("parseMPDConfig() error = %v", err)
+			}
+		})
+	}
+}
+
+// fakeMPD is a stand-in for `mpd --kill <config>`: it reports a syntax
+// error for configs containing "bogus", leaves a marker next to itself as if
+// it stopped the daemon if the config has a pid_file, and otherwise fails the
+// way MPD does without one.
+const fakeMPD = `#!/bin/sh
+if grep -q bogus "$2"; then
+	echo "Error in $2 on line 1: unknown setting" >&2
+	exit 1
+fi
+if grep -q pid_file "$2"; then
+	touch "$0.killed"
+	exit 0
+fi
+echo "exception: no pid_file specified in the config file" >&2
+exit 1
+`
+
+func TestValidateMPDConfig(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "mpd")
+	if err := os.WriteFile(bin, []byte(fakeMPD), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		binary  string
+		config  string
+		wantErr string
+	}{
+		{name: "accepted by mpd", binary: bin, config: sampleMPDConfig},
+		{name: "pid_file not used", binary: bin, config: "pid_file \"/run/mpd/pid\"\n" + sampleMPDConfig},
+		{name: "rejected by mpd", binary: bin, config: "bogus \"yes\"", wantErr: "mpd rejected config: Error in config on line 1"},
+		{name: "structural fallback", binary: filepath.Join(t.TempDir(), "missing"), config: "mixer_type none", wantErr: "must be quoted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := mpdBinary
+			mpdBinary = tt.binary
+			defer func() { mpdBinary = old }()
+
+			err := validateMPDConfig(tt.config)
+			if _, statErr := os.Stat(bin + ".killed"); statErr == nil {
+				t.Fatal("config check stopped the running MPD")
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateMPDConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateMPDConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReplaceDopSetting_KeepsConfigValid(t *testing.T) {
+	for _, mode := range []string{"dop", "native"} {
+		t.Run(mode, func(t *testing.T) {
+			got, err := replaceDopSetting(sampleMPDConfig, mode)
+			if err != nil {
+				t.Fatalf("replaceDopSetting() error = %v", err)
+			}
+			if err := parseMPDConfig(got); err != nil {
+				t.Errorf("result is not a valid config: %v", err)
+			}
+			want := "native"
+			if matchConfigValue(got, "dop", "yes") {
+				want = "dop"
+			}
+			if want != mode {
+				t.Errorf("dop mode = %q, want %q", want, mode)
+			}
+			if strings.Count(got, "{") != strings.Count(sampleMPDConfig, "{") ||
+				strings.Count(got, "}") != strings.Count(sampleMPDConfig, "}") {
+				t.Error("brace count changed after replacement")
+			}
+		})
+	}
+}
+
+func TestReplaceDopSetting_MissingSetting(t *testing.T) {
+	if _, err := replaceDopSetting("audio_output {\n}\n", "dop"); err == nil {
+		t.Error("replaceDopSetting() succeeded without a dop setting, want error")
+	}
+}
+
+func TestReplaceMixerType_KeepsConfigValid(t *testing.T) {
+	tests := []struct {
+		enabled bool
+		want    string
+	}{
+		{enabled: false, want: "none"},
+		{enabled: true, want: "software"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := replaceMixerType(sampleMPDConfig, tt.enabled)
+			if err != nil {
+				t.Fatalf("replaceMixerType() error = %v", err)
+			}
+			if err := parseMPDConfig(got); err != nil {
+				t.Errorf("result is not a valid config: %v", err)
+			}
+			if !matchConfigValue(got, "mixer_type", tt.want) {
+				t.Errorf("mixer_type not set to %q in:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplaceMixerType_MissingSetting(t *testing.T) {
+	if _, err := replaceMixerType("audio_output {\n}\n", true); err == nil {
+		t.Error("replaceMixerType() succeeded without a mixer_type setting, want error")
+	}
+}
+
+func TestApplyBitPerfectSettings_KeepsConfigValid(t *testing.T) {
+	got, applied := applyBitPerfectSettings(sampleMPDConfig)
+
+	if len(applied) != len(bitPerfectSettings) {
+		t.Errorf("applied %d settings, want %d: %v", len(applied), len(bitPerfectSettings), applied)
+	}
+	if err := parseMPDConfig(got); err != nil {
+		t.Errorf("result is not a valid config: %v", err)
+	}
+	for setting, want := range map[string]string{
+		"mixer_type":    "none",
+		"auto_resample": "no",
+		"auto_format":   "no",
+		"auto_channels": "no",
+	} {
+		if !matchConfigValue(got, setting, want) {
+			t.Errorf("%s not set to %q", setting, want)
+		}
+	}
+
+	// A second pass must be a no-op
+	again, applied := applyBitPerfectSettings(got)
+	if len(applied) != 0 {
+		t.Errorf("second pass applied %v, want nothing", applied)
+	}
+	if again != got {
+		t.Error("second pass modified the config")
+	}
+}
+
+// mockConfigWriter implements ConfigWriter with an in-memory filesystem.
+type mockConfigWriter struct {
+	files      map[string]string
+	writeError error
+	corrupt    bool // Simulates a truncated write that lands on disk
+}
+
+func newMockConfigWriter(mpdConfig string) *mockConfigWriter {
+	return &mockConfigWriter{
+		files: map[string]string{mpdConfigPath: mpdConfig},
+	}
+}
+
+func (m *mockConfigWriter) ReadFile(path string) ([]byte, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (m *mockConfigWriter) WriteFile(path string, content []byte) error {
+	if m.writeError != nil {
+		return m.writeError
+	}
+	if m.corrupt {
+		content = content[:len(content)/2]
+	}
+	m.files[path] = string(content)
+	return nil
+}
+
+func (m *mockConfigWriter) CopyFile(src, dst string) error {
+	content, ok := m.files[src]
+	if !ok {
+		return os.ErrNotExist
+	}
+	m.files[dst] = content
+	return nil
+}
+
+func (m *mockConfigWriter) RemoveFile(path string) error {
+	delete(m.files, path)
+	return nil
+}
+
+func (m *mockConfigWriter) Glob(pattern string) ([]string, error) {
+	var matches []string
+	for path := range m.files {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			matches = append(matches, path)
+		}
+	}
+	return matches, nil
+}
+
+func (m *mockConfigWriter) backups() []string {
+	matches, _ := m.Glob(mpdConfigBackupPrefix + ".*")
+	sort.Strings(matches)
+	return matches
+}
+
+// mockServiceManager implements ServiceManager and records restarts.
+type mockServiceManager struct {
+	restarted    []string
+	restartError error
+}
+
+func (m *mockServiceManager) Restart(service string) error {
+	m.restarted = append(m.restarted, service)
+	return m.restartError
+}
+
+func TestAudioConfig_SetDsdMode(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	services := &mockServiceManager{}
+	cfg := NewAudioConfig(writer, services)
+
+	result := cfg.SetDsdMode("dop")
+	if !result.Success {
+		t.Fatalf("SetDsdMode failed: %s", result.Error)
+	}
+
+	if got := cfg.GetDsdMode(); got.Mode != "dop" {
+		t.Errorf("GetDsdMode() = %q, want %q", got.Mode, "dop")
+	}
+	if len(writer.backups()) != 1 {
+		t.Errorf("got %d backups, want 1", len(writer.backups()))
+	}
+	if !reflect.DeepEqual(services.restarted, []string{mpdServiceName}) {
+		t.Errorf("restarted = %v, want [%s]", services.restarted, mpdServiceName)
+	}
+}
+
+func TestAudioConfig_SetDsdMode_InvalidMode(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	services := &mockServiceManager{}
+	cfg := NewAudioConfig(writer, services)
+
+	if result := cfg.SetDsdMode("bogus"); result.Success {
+		t.Error("SetDsdMode succeeded with invalid mode")
+	}
+	if len(services.restarted) != 0 {
+		t.Errorf("MPD restarted %d times, want 0", len(services.restarted))
+	}
+}
+
+func TestAudioConfig_SetMixerMode_RestartFailure(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	services := &mockServiceManager{restartError: errors.New("no systemd")}
+	cfg := NewAudioConfig(writer, services)
+
+	result := cfg.SetMixerMode(false)
+	if result.Success {
+		t.Error("SetMixerMode succeeded despite restart failure")
+	}
+	if !strings.Contains(result.Error, "failed to restart MPD") {
+		t.Errorf("Error = %q, want restart failure", result.Error)
+	}
+	if got := cfg.GetMixerMode(); got.Enabled {
+		t.Error("config was not updated before the restart attempt")
+	}
+}
+
+func TestAudioConfig_ApplyBitPerfect(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	services := &mockServiceManager{}
+	cfg := NewAudioConfig(writer, services)
+
+	result := cfg.ApplyBitPerfect()
+	if !result.Success {
+		t.Fatalf("ApplyBitPerfect failed: %v", result.Errors)
+	}
+	if len(services.restarted) != 1 {
+		t.Errorf("MPD restarted %d times, want 1", len(services.restarted))
+	}
+
+	// Second run is already optimal and must not restart MPD
+	result = cfg.ApplyBitPerfect()
+	if !result.Success {
+		t.Fatalf("second ApplyBitPerfect failed: %v", result.Errors)
+	}
+	if len(services.restarted) != 1 {
+		t.Errorf("MPD restarted %d times after no-op apply, want 1", len(services.restarted))
+	}
+}
+
+func TestAudioConfig_WriteMPDConfig_RejectsInvalidContent(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	cfg := NewAudioConfig(writer, &mockServiceManager{})
+
+	if err := cfg.writeMPDConfig("audio_output {\n"); err == nil {
+		t.Fatal("writeMPDConfig accepted unbalanced config")
+	}
+	if writer.files[mpdConfigPath] != sampleMPDConfig {
+		t.Error("live config was modified")
+	}
+	if len(writer.backups()) != 0 {
+		t.Errorf("got %d backups, want 0", len(writer.backups()))
+	}
+}
+
+func TestAudioConfig_WriteMPDConfig_RestoresBackupOnCorruptWrite(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	writer.corrupt = true
+	services := &mockServiceManager{}
+	cfg := NewAudioConfig(writer, services)
+
+	result := cfg.SetMixerMode(false)
+	if result.Success {
+		t.Fatal("SetMixerMode succeeded despite corrupt write")
+	}
+	if writer.files[mpdConfigPath] != sampleMPDConfig {
+		t.Error("original config was not restored from backup")
+	}
+	if len(services.restarted) != 0 {
+		t.Errorf("MPD restarted %d times, want 0", len(services.restarted))
+	}
+}
+
+func TestAudioConfig_BackupsArePruned(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	for i := 0; i < maxMPDConfigBackups+3; i++ {
+		writer.files[fmt.Sprintf("%s.20240101-0000%02d.000", mpdConfigBackupPrefix, i)] = sampleMPDConfig
+	}
+	cfg := NewAudioConfig(writer, &mockServiceManager{})
+
+	if result := cfg.SetDsdMode("dop"); !result.Success {
+		t.Fatalf("SetDsdMode failed: %s", result.Error)
+	}
+	if got := len(writer.backups()); got != maxMPDConfigBackups {
+		t.Errorf("got %d backups, want %d", got, maxMPDConfigBackups)
+	}
+}
+
+func TestAudioConfig_RollbackMPDConfig(t *testing.T) {
+	writer := newMockConfigWriter(sampleMPDConfig)
+	services := &mockServiceManager{}
+	cfg := NewAudioConfig(writer, services)
+
+	if result := cfg.SetMixerMode(false); !result.Success {
+		t.Fatalf("SetMixerMode failed: %s", result.Error)
+	}
+
+	result := cfg.RollbackMPDConfig()
+	if !result.Success {
+		t.Fatalf("RollbackMPDConfig failed: %s", result.Error)
+	}
+	if writer.files[mpdConfigPath] != sampleMPDConfig {
+		t.Error("config was not restored to the pre-change content")
+	}
+	if len(writer.backups()) != 0 {
+		t.Errorf("restored backup was not consumed, %d remain", len(writer.backups()))
+	}
+	if len(services.restarted) != 2 {
+		t.Errorf("MPD restarted %d times, want 2", len(services.restarted))
+	}
+
+	if result := cfg.RollbackMPDConfig(); result.Success {
+		t.Error("RollbackMPDConfig succeeded with no backups left")
+	}
+}
@@ -0,0 +1,179 @@
+package socketio
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zishang520/socket.io/servers/socket/v3"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/auth"
+)
+
+// protectedEvents change system configuration or storage. They always need
+// full access when a token is configured, whatever other access a
+// connection is given.
+var protectedEvents = map[string]bool{
+	"addNasShare":         true,
+	"connectWifi":         true,
+	"deleteNasShare":      true,
+	"exportConfig":        true,
+	"factoryReset":        true,
+	"importConfig":        true,
+	"applyBitPerfect":     true,
+	"rollbackMpdConfig":   true,
+	"setDsdMode":          true,
+	"setHostname":         true,
+	"setMixerMode":        true,
+	"setPlaybackSettings": true,
+}
+
+// guestEvents are the read-only events, besides get*, that guests may send.
+// Guests see what's playing and can browse, but cannot change playback,
+// volume, the queue or any settings.
+var guestEvents = map[string]bool{
+	"initSocket":            true,
+	"browseLibrary":         true,
+	"browseFolder":          true,
+	"listPlaylist":          true,
+	"listAlarms":            true,
+	"searchQueue":           true,
+	"qobuzSearch":           true,
+	"searchAdvanced":        true,
+	"enrichment:status":     true,
+	"library:albums:list":   true,
+	"library:artists:list":  true,
+	"library:artist:albums": true,
+	"library:album:tracks":  true,
+	"library:radio:list":    true,
+	"library:cache:status":  true,
+}
+
+// clientSession is attached to each socket when it connects.
+type clientSession struct {
+	Role   auth.Role       // Access level granted by the presented token
+	ctx    context.Context // Cancelled when the client disconnects
+	cancel context.CancelFunc
+
+	initialSync sync.Once // Guards pushInitialState
+}
+
+// newClientSession creates a session whose context lives until the client
+// disconnects.
+func newClientSession(role auth.Role) *clientSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &clientSession{Role: role, ctx: ctx, cancel: cancel}
+}
+
+// sessionOf returns a client's session, or an empty one for sockets that
+// bypassed the auth middleware.
+func sessionOf(client *socket.Socket) *clientSession {
+	if session, ok := client.Data().(*clientSession); ok {
+		return session
+	}
+	return &clientSession{ctx: context.Background(), cancel: func() {}}
+}
+
+// SetAuthenticator requires connections to present the given token. A nil
+// or disabled authenticator leaves Socket.IO open.
+func (s *Server) SetAuthenticator(a *auth.Authenticator) {
+	s.auth = a
+}
+
+// authMiddleware rejects connections without a valid token before they reach
+// the connection handler, so the client gets a connect_error.
+func (s *Server) authMiddleware(client *socket.Socket, next func(*socket.ExtendedError)) {
+	role := s.auth.Role(handshakeToken(client.Handshake()))
+	client.SetData(newClientSession(role))
+
+	if role == auth.RoleNone {
+		log.Warn().Str("ip", extractRemoteIP(client)).Msg("Rejected unauthenticated connection")
+		next(socket.NewExtendedError("unauthorized", map[string]any{"error": "unauthorized"}))
+		return
+	}
+	next(nil)
+}
+
+// clientRole returns the access level a client connected with.
+func clientRole(client *socket.Socket) auth.Role {
+	return sessionOf(client).Role
+}
+
+// eventGuard returns a socket middleware that drops events the client's role
+// doesn't allow, so every handler is covered wherever it is registered.
+func (s *Server) eventGuard(client *socket.Socket) func([]any, func(error)) {
+	return func(event []any, next func(error)) {
+		var name string
+		if len(event) > 0 {
+			name, _ = event[0].(string)
+		}
+		if canRun(s.auth, clientRole(client), name) {
+			next(nil)
+			return
+		}
+
+		log.Warn().Str("id", string(client.Id())).Str("event", name).Msg("Refused command not allowed for client role")
+		client.Emit("pushToastMessage", map[string]interface{}{
+			"type":    "error",
+			"title":   "Not Authorized",
+			"message": "This device has read-only access",
+		})
+	}
+}
+
+// canRun reports whether a client with role may send event under
+// authenticator a.
+func canRun(a *auth.Authenticator, role auth.Role, event string) bool {
+	if !a.Enabled() {
+		return true
+	}
+	switch role {
+	case auth.RoleFull:
+		return true
+	case auth.RoleGuest:
+		return !protectedEvents[event] && isReadOnlyEvent(event)
+	}
+	return false
+}
+
+// isReadOnlyEvent reports whether event only reads state.
+func isReadOnlyEvent(event string) bool {
+	return strings.HasPrefix(event, "get") || guestEvents[event]
+}
+
+// handshakeToken returns the token from the Socket.IO v3+ auth payload, the
+// connection query string (v2 clients), or an Authorization header.
+func handshakeToken(hs *socket.Handshake) string {
+	if hs == nil {
+		return ""
+	}
+	if token, ok := hs.Auth[auth.QueryParam].(string); ok && token != "" {
+		return token
+	}
+	if token := firstValue(hs.Query[auth.QueryParam]); token != "" {
+		return token
+	}
+	for name, value := range hs.Headers {
+		if strings.EqualFold(name, "Authorization") {
+			if token, ok := auth.BearerToken(firstValue(value)); ok {
+				return token
+			}
+		}
+	}
+	return ""
+}
+
+// firstValue returns a handshake query or header value, which may be a
+// string or a list of strings.
+func firstValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package socketio
+
+import (
+	"testing"
+
+	"github.com/zishang520/socket.io/servers/socket/v3"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/auth"
+)
+
+func TestHandshakeToken(t *testing.T) {
+	tests := []struct {
+		name string
+		hs   *socket.Handshake
+		want string
+	}{
+		{"nil handshake", nil, ""},
+		{"auth payload", &socket.Handshake{Auth: map[string]any{"token": "a"}}, "a"},
+		{"query string", &socket.Handshake{Query: map[string]any{"token": []string{"q"}}}, "q"},
+		{"authorization header", &socket.Handshake{Headers: map[string]any{"Authorization": []string{"Bearer h"}}}, "h"},
+		{"lowercase header", &socket.Handshake{Headers: map[string]any{"authorization": "Bearer h"}}, "h"},
+		{
+			"auth payload wins",
+			&socket.Handshake{
+				Auth:  map[string]any{"token": "a"},
+				Query: map[string]any{"token": []string{"q"}},
+			},
+			"a",
+		},
+		{"none", &socket.Handshake{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handshakeToken(tt.hs); got != tt.want {
+				t.Errorf("handshakeToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanRun(t *testing.T) {
+	enabled := auth.New("s3cret", auth.WithGuestToken("guest"))
+
+	tests := []struct {
+		name  string
+		a     *auth.Authenticator
+		role  auth.Role
+		event string
+		want  bool
+	}{
+		{"auth disabled, protected event", nil, auth.RoleNone, "applyBitPerfect", true},
+		{"full, protected event", enabled, auth.RoleFull, "deleteNasShare", true},
+		{"full, mutating event", enabled, auth.RoleFull, "clearQueue", true},
+		{"guest, protected event", enabled, auth.RoleGuest, "setDsdMode", false},
+		{"guest, volume", enabled, auth.RoleGuest, "volume", false},
+		{"guest, queue edit", enabled, auth.RoleGuest, "addToQueue", false},
+		{"guest, play", enabled, auth.RoleGuest, "play", false},
+		{"guest, get event", enabled, auth.RoleGuest, "getState", true},
+		{"guest, browse", enabled, auth.RoleGuest, "browseLibrary", true},
+		{"guest, library list", enabled, auth.RoleGuest, "library:albums:list", true},
+		{"guest, cache rebuild", enabled, auth.RoleGuest, "library:cache:rebuild", false},
+		{"none, get event", enabled, auth.RoleNone, "getState", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canRun(tt.a, tt.role, tt.event); got != tt.want {
+				t.Errorf("canRun(%s, %s) = %v, want %v", tt.role, tt.event, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package socketio
 
 import (
+	"errors"
+
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/library"
-	"github.com/rs/zerolog/log"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
 	"github.com/zishang520/socket.io/servers/socket/v3"
 )
 
@@ -23,13 +25,36 @@ func NewCacheHandlers(cachedService *library.CachedService, server *Server) *Cac
 // RegisterHandlers registers all cache-related Socket.IO handlers.
 func (h *CacheHandlers) RegisterHandlers(client *socket.Socket) {
 	// Cache status
-	client.On("library:cache:status", func(args ...interface{}) {
-		h.handleGetCacheStatus(client)
+	h.server.on(client, "library:cache:status", func(cmd *Command, args ...any) {
+		h.handleGetCacheStatus(cmd)
 	})
 
-	// Cache rebuild
-	client.On("library:cache:rebuild", func(args ...interface{}) {
-		h.handleRebuildCache(client)
+	// Cache rebuild; progress is broadcast on pushCacheProgress
+	h.server.on(client, "library:cache:rebuild", func(cmd *Command, args ...any) {
+		h.handleRebuildCache(cmd)
+	})
+	h.server.on(client, "rebuildCache", func(cmd *Command, args ...any) {
+		h.handleRebuildCache(cmd)
+	})
+
+	// Albums by year or decade
+	h.server.on(client, "getAlbumsByYear", func(cmd *Command, args ...any) {
+		h.handleGetAlbumsByYear(cmd, args...)
+	})
+
+	// Decades with album counts
+	h.server.on(client, "getDecades", func(cmd *Command, args ...any) {
+		h.handleGetDecades(cmd)
+	})
+
+	// Album and track counts per source
+	h.server.on(client, "getSourceStats", func(cmd *Command, args ...any) {
+		h.handleGetSourceStats(cmd)
+	})
+
+	// Leading letters of artist names for an A-Z jump bar
+	h.server.on(client, "getArtistIndex", func(cmd *Command, args ...any) {
+		h.handleGetArtistIndex(cmd, args...)
 	})
 }
 
@@ -48,13 +73,13 @@ type CacheStatusResponse struct {
 }
 
 // handleGetCacheStatus handles the library:cache:status event.
-func (h *CacheHandlers) handleGetCacheStatus(client *socket.Socket) {
-	log.Debug().Msg("Received library:cache:status")
+func (h *CacheHandlers) handleGetCacheStatus(cmd *Command) {
+	cmd.Log.Debug().Msg("Received library:cache:status")
 
 	stats, err := h.cachedService.GetCacheStatus()
 	if err != nil {
-		log.Warn().Err(err).Msg("Failed to get cache status")
-		client.Emit("pushLibraryCacheStatus", CacheStatusResponse{})
+		cmd.Log.Warn().Err(err).Msg("Failed to get cache status")
+		cmd.Emit("pushLibraryCacheStatus", CacheStatusResponse{})
 		return
 	}
 
@@ -74,13 +99,13 @@ func (h *CacheHandlers) handleGetCacheStatus(client *socket.Socket) {
 		resp.LastUpdated = stats.LastUpdated.Format("2006-01-02T15:04:05Z07:00")
 	}
 
-	log.Debug().
+	cmd.Log.Debug().
 		Int("albums", resp.AlbumCount).
 		Int("artists", resp.ArtistCount).
 		Bool("building", resp.IsBuilding).
 		Msg("Sending pushLibraryCacheStatus")
 
-	client.Emit("pushLibraryCacheStatus", resp)
+	cmd.Emit("pushLibraryCacheStatus", resp)
 }
 
 // CacheUpdatedEvent represents the cache updated event payload.
@@ -93,21 +118,35 @@ type CacheUpdatedEvent struct {
 }
 
 // handleRebuildCache handles the library:cache:rebuild event.
-func (h *CacheHandlers) handleRebuildCache(client *socket.Socket) {
-	log.Info().Msg("Received library:cache:rebuild - starting rebuild")
+func (h *CacheHandlers) handleRebuildCache(cmd *Command) {
+	cmd.Log.Info().Msg("Received library:cache:rebuild - starting rebuild")
+
+	if h.cachedService.IsRebuilding() {
+		cmd.Emit("pushToastMessage", map[string]interface{}{
+			"type":    "info",
+			"title":   "Library Cache",
+			"message": "A cache rebuild is already in progress",
+		})
+		h.handleGetCacheStatus(cmd)
+		return
+	}
 
 	// Start rebuild in background
 	go func() {
 		err := h.cachedService.RebuildCache()
+		if errors.Is(err, cache.ErrBuildInProgress) {
+			cmd.Log.Info().Msg("Cache rebuild already in progress")
+			return
+		}
 		if err != nil {
-			log.Error().Err(err).Msg("Cache rebuild failed")
+			cmd.Log.Error().Err(err).Msg("Cache rebuild failed")
 			return
 		}
 
 		// Get updated stats
 		stats, err := h.cachedService.GetCacheStatus()
 		if err != nil {
-			log.Warn().Err(err).Msg("Failed to get cache status after rebuild")
+			cmd.Log.Warn().Err(err).Msg("Failed to get cache status after rebuild")
 			return
 		}
 
@@ -120,8 +159,8 @@ func (h *CacheHandlers) handleRebuildCache(client *socket.Socket) {
 		}
 
 		if h.server != nil && h.server.io != nil {
-			h.server.io.Emit("library:cache:updated", event)
-			log.Info().
+			h.server.emitAll("library:cache:updated", event)
+			cmd.Log.Info().
 				Int("albums", event.AlbumCount).
 				Int("artists", event.ArtistCount).
 				Msg("Cache rebuild complete, broadcasted update")
@@ -129,5 +168,69 @@ func (h *CacheHandlers) handleRebuildCache(client *socket.Socket) {
 	}()
 
 	// Immediately respond with current status
-	h.handleGetCacheStatus(client)
+	h.handleGetCacheStatus(cmd)
+}
+
+// handleGetAlbumsByYear handles the getAlbumsByYear event.
+func (h *CacheHandlers) handleGetAlbumsByYear(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received getAlbumsByYear")
+
+	req := library.GetAlbumsByYearRequest{}
+
+	// Parse request payload
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			if year, ok := payload["year"].(float64); ok {
+				req.Year = int(year)
+			}
+			if decade, ok := payload["decade"].(float64); ok {
+				req.Decade = int(decade)
+			}
+		}
+	}
+
+	resp := h.cachedService.GetAlbumsByYear(req)
+
+	cmd.Log.Debug().
+		Int("year", req.Year).
+		Int("decade", req.Decade).
+		Int("albumCount", len(resp.Albums)).
+		Msg("Sending pushAlbumsByYear")
+
+	cmd.Emit("pushAlbumsByYear", resp)
+}
+
+// handleGetDecades handles the getDecades event.
+func (h *CacheHandlers) handleGetDecades(cmd *Command) {
+	cmd.Log.Debug().Msg("Received getDecades")
+	cmd.Emit("pushDecades", h.cachedService.GetDecades())
+}
+
+// handleGetSourceStats handles the getSourceStats event.
+func (h *CacheHandlers) handleGetSourceStats(cmd *Command) {
+	cmd.Log.Debug().Msg("Received getSourceStats")
+	cmd.Emit("pushSourceStats", h.cachedService.GetSourceStats())
+}
+
+// handleGetArtistIndex handles the getArtistIndex event.
+func (h *CacheHandlers) handleGetArtistIndex(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received getArtistIndex")
+
+	req := library.GetArtistIndexRequest{}
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			if query, ok := payload["query"].(string); ok {
+				req.Query = query
+			}
+		}
+	}
+
+	resp := h.cachedService.GetArtistIndex(req)
+
+	cmd.Log.Debug().
+		Int("letters", len(resp.Letters)).
+		Int("total", resp.Total).
+		Msg("Sending pushArtistIndex")
+
+	cmd.Emit("pushArtistIndex", resp)
 }
@@ -0,0 +1,70 @@
+package socketio
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/zishang520/socket.io/servers/socket/v3"
+)
+
+// Command is one incoming Socket.IO event being handled. Its logger and
+// replies carry a short correlation ID, so a single command can be followed
+// through the logs even when several clients are active at once.
+type Command struct {
+	ID     string
+	Event  string
+	Log    zerolog.Logger
+	Ctx    context.Context // Cancelled when the client disconnects
+	client *socket.Socket
+}
+
+// CommandMeta is the envelope sent alongside replies to a command.
+type CommandMeta struct {
+	CorrelationID string `json:"correlationId"`
+	Event         string `json:"event"` // The event that triggered the reply
+}
+
+// newCommand creates a Command with a fresh correlation ID and a logger that
+// tags every line with it.
+func newCommand(ctx context.Context, client *socket.Socket, clientID, event string) *Command {
+	id := newCorrelationID()
+	return &Command{
+		ID:    id,
+		Event: event,
+		Ctx:   ctx,
+		Log: log.With().
+			Str("cid", id).
+			Str("id", clientID).
+			Str("event", event).
+			Logger(),
+		client: client,
+	}
+}
+
+// Emit replies to the client that sent the command. The correlation ID goes
+// in a trailing CommandMeta argument, which clients that only read the first
+// argument (such as Volumio UIs) ignore.
+func (c *Command) Emit(event string, v any) {
+	c.client.Emit(event, v, CommandMeta{CorrelationID: c.ID, Event: c.Event})
+}
+
+// on registers a handler that receives a Command for each incoming event.
+func (s *Server) on(client *socket.Socket, event string, fn func(cmd *Command, args ...any)) {
+	clientID := string(client.Id())
+	ctx := sessionOf(client).ctx
+	client.On(event, func(args ...any) {
+		fn(newCommand(ctx, client, clientID, event), args...)
+	})
+}
+
+// newCorrelationID returns a random 8-character hex ID.
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
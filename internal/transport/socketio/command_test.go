@@ -0,0 +1,57 @@
+package socketio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestNewCorrelationID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := newCorrelationID()
+		if len(id) != 8 {
+			t.Fatalf("newCorrelationID() = %q, want 8 characters", id)
+		}
+		if seen[id] {
+			t.Fatalf("newCorrelationID() returned duplicate %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewCommand_LoggerCarriesCorrelationID(t *testing.T) {
+	var buf bytes.Buffer
+	orig, origLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&buf)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		log.Logger = orig
+		zerolog.SetGlobalLevel(origLevel)
+	})
+
+	cmd := newCommand(context.Background(), nil, "client-1", "replaceAndPlay")
+	cmd.Log.Info().Msg("first")
+	cmd.Log.Debug().Msg("second")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]string
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("unmarshal %s: %v", line, err)
+		}
+		if entry["cid"] != cmd.ID {
+			t.Errorf("cid = %q, want %q", entry["cid"], cmd.ID)
+		}
+		if entry["id"] != "client-1" || entry["event"] != "replaceAndPlay" {
+			t.Errorf("entry = %v, want client-1/replaceAndPlay", entry)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package socketio
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/localmusic"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/sources"
+)
+
+// ConfigExportVersion is the version of the config export format. Imports
+// of a newer version are refused.
+const ConfigExportVersion = 1
+
+// ConfigExport bundles the user's configuration for moving it to another
+// device: NAS shares (without passwords), favorites, alarms and audio
+// settings.
+type ConfigExport struct {
+	Version    int                   `json:"version"`
+	ExportedAt time.Time             `json:"exportedAt"`
+	NasShares  []sources.ShareExport `json:"nasShares"`
+	Favorites  []localmusic.Favorite `json:"favorites"`
+	Alarms     []player.Alarm        `json:"alarms"`
+	Audio      *AudioSettingsExport  `json:"audio,omitempty"`
+}
+
+// AudioSettingsExport holds the audio settings carried in a config export.
+type AudioSettingsExport struct {
+	OutputDevice  string               `json:"outputDevice,omitempty"`
+	DsdMode       string               `json:"dsdMode,omitempty"` // "native" or "dop"
+	SoftwareMixer *bool                `json:"softwareMixer,omitempty"`
+	Fade          *player.FadeSettings `json:"fade,omitempty"`
+}
+
+// ImportConfigRequest is the request to importConfig.
+type ImportConfigRequest struct {
+	Config ConfigExport `json:"config"`
+	// Passwords of NAS shares exported with hasPassword, keyed by share name.
+	// Shares missing one are reported with needsPassword and can be imported
+	// by repeating the request with it.
+	Passwords map[string]string `json:"passwords,omitempty"`
+}
+
+// ImportConfigResponse reports what importConfig restored.
+type ImportConfigResponse struct {
+	Success   bool                  `json:"success"`
+	NasShares []sources.MountResult `json:"nasShares"`
+	Favorites int                   `json:"favorites"` // Favorites added
+	Alarms    int                   `json:"alarms"`    // Alarms added
+	Audio     []string              `json:"audio"`     // Audio settings changed
+	Errors    []string              `json:"errors"`    // Parts that could not be restored
+	Error     string                `json:"error,omitempty"`
+}
+
+// ExportConfig returns the current configuration as a ConfigExport.
+func (s *Server) ExportConfig() ConfigExport {
+	export := ConfigExport{
+		Version:    ConfigExportVersion,
+		ExportedAt: time.Now().UTC(),
+		NasShares:  []sources.ShareExport{},
+		Favorites:  []localmusic.Favorite{},
+		Alarms:     []player.Alarm{},
+	}
+	if s.sourcesService != nil {
+		export.NasShares = s.sourcesService.ExportNasShares()
+	}
+	if s.localMusicService != nil {
+		export.Favorites = s.localMusicService.ListFavorites("").Favorites
+	}
+	if s.alarmStore != nil {
+		export.Alarms = s.alarmStore.List()
+	}
+
+	audio := &AudioSettingsExport{
+		OutputDevice: s.audioConfig.GetCurrentAudioOutput(),
+	}
+	if mixer := s.audioConfig.GetMixerMode(); mixer.Success {
+		audio.SoftwareMixer = &mixer.Enabled
+	}
+	if mode := s.audioConfig.GetDsdMode(); mode.Success {
+		audio.DsdMode = mode.Mode
+	}
+	if s.playerService != nil {
+		fade := s.playerService.FadeSettings()
+		audio.Fade = &fade
+	}
+	export.Audio = audio
+	return export
+}
+
+// ImportConfig restores a ConfigExport. Each part is merged into the current
+// configuration rather than replacing it, so importing twice is harmless.
+// NAS shares are mounted as they are added and MPD rescans once any mounted.
+func (s *Server) ImportConfig(req ImportConfigRequest) ImportConfigResponse {
+	resp := ImportConfigResponse{
+		NasShares: []sources.MountResult{},
+		Audio:     []string{},
+		Errors:    []string{},
+	}
+	if err := validateConfigExport(req.Config); err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	cfg := req.Config
+
+	if len(cfg.NasShares) > 0 {
+		if s.sourcesService == nil {
+			resp.Errors = append(resp.Errors, "NAS shares: sources service not available")
+		} else {
+			resp.NasShares = s.sourcesService.ImportNasShares(cfg.NasShares, req.Passwords)
+			mounted := false
+			for _, result := range resp.NasShares {
+				mounted = mounted || result.Mounted
+			}
+			shares, _ := s.sourcesService.ListNasShares()
+			s.emitAll("pushListNasShares", shares)
+			if mounted {
+				if _, err := s.mpdClient.Update(""); err != nil {
+					log.Warn().Err(err).Msg("Failed to trigger MPD update after importing NAS shares")
+				}
+			}
+		}
+	}
+
+	if len(cfg.Favorites) > 0 {
+		if s.localMusicService == nil {
+			resp.Errors = append(resp.Errors, "favorites: local music service not available")
+		} else {
+			resp.Favorites = s.localMusicService.ImportFavorites(cfg.Favorites)
+		}
+	}
+
+	if len(cfg.Alarms) > 0 {
+		if s.alarmStore == nil {
+			resp.Errors = append(resp.Errors, "alarms: alarms not available")
+		} else if added, err := s.alarmStore.Import(cfg.Alarms); err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("alarms: %v", err))
+		} else {
+			resp.Alarms = added
+			s.emitAll("pushAlarms", s.alarmStore.List())
+		}
+	}
+
+	if cfg.Audio != nil {
+		applied, errs := s.importAudioSettings(*cfg.Audio)
+		resp.Audio = append(resp.Audio, applied...)
+		resp.Errors = append(resp.Errors, errs...)
+	}
+
+	resp.Success = len(resp.Errors) == 0
+	log.Info().
+		Int("nasShares", len(resp.NasShares)).
+		Int("favorites", resp.Favorites).
+		Int("alarms", resp.Alarms).
+		Strs("audio", resp.Audio).
+		Strs("errors", resp.Errors).
+		Msg("Imported configuration")
+	return resp
+}
+
+// importAudioSettings applies the exported audio settings that differ from
+// the current ones, returning the settings changed and any failures. Each
+// MPD config change restarts MPD, so unchanged settings are left alone.
+func (s *Server) importAudioSettings(audio AudioSettingsExport) (applied, errs []string) {
+	if audio.OutputDevice != "" && audio.OutputDevice != s.audioConfig.GetCurrentAudioOutput() {
+		if err := s.audioConfig.SetPlaybackSettings(audio.OutputDevice); err != nil {
+			errs = append(errs, fmt.Sprintf("output device %q: %v", audio.OutputDevice, err))
+		} else {
+			applied = append(applied, "outputDevice")
+			s.emitAll("pushPlaybackOptions", s.audioConfig.GetPlaybackOptions())
+		}
+	}
+
+	if audio.DsdMode != "" && audio.DsdMode != s.audioConfig.GetDsdMode().Mode {
+		if result := s.audioConfig.SetDsdMode(audio.DsdMode); !result.Success {
+			errs = append(errs, "DSD mode: "+result.Error)
+		} else {
+			applied = append(applied, "dsdMode")
+			s.emitAll("pushDsdMode", result)
+		}
+	}
+
+	if audio.SoftwareMixer != nil && s.audioConfig.GetMixerMode().Enabled != *audio.SoftwareMixer {
+		if result := s.audioConfig.SetMixerMode(*audio.SoftwareMixer); !result.Success {
+			errs = append(errs, "software mixer: "+result.Error)
+		} else {
+			applied = append(applied, "softwareMixer")
+			s.emitAll("pushMixerMode", result)
+		}
+	}
+
+	if audio.Fade != nil && s.playerService != nil && *audio.Fade != s.playerService.FadeSettings() {
+		if err := s.playerService.SetFadeSettings(*audio.Fade); err != nil {
+			errs = append(errs, fmt.Sprintf("fade settings: %v", err))
+		} else {
+			applied = append(applied, "fade")
+			s.emitAll("pushFadeSettings", *audio.Fade)
+		}
+	}
+	return applied, errs
+}
+
+// validateConfigExport checks that an export is one this version can import.
+func validateConfigExport(cfg ConfigExport) error {
+	if cfg.Version < 1 {
+		return fmt.Errorf("not a config export: missing version")
+	}
+	if cfg.Version > ConfigExportVersion {
+		return fmt.Errorf("config export version %d is newer than supported version %d", cfg.Version, ConfigExportVersion)
+	}
+	if cfg.Audio != nil && cfg.Audio.DsdMode != "" && cfg.Audio.DsdMode != "native" && cfg.Audio.DsdMode != "dop" {
+		return fmt.Errorf("invalid DSD mode %q", cfg.Audio.DsdMode)
+	}
+	return nil
+}
+
+// DecodeImportConfigRequest decodes an ImportConfigRequest. A bare
+// ConfigExport, as saved by exportConfig, is accepted too.
+func DecodeImportConfigRequest(data []byte) (ImportConfigRequest, error) {
+	var req ImportConfigRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, fmt.Errorf("invalid import data: %w", err)
+	}
+	if req.Config.Version == 0 {
+		var export ConfigExport
+		if err := json.Unmarshal(data, &export); err == nil && export.Version != 0 {
+			req.Config = export
+		}
+	}
+	return req, nil
+}
+
+// parseImportConfigRequest decodes importConfig event data, sent as an
+// object or as a JSON string.
+func parseImportConfigRequest(arg any) (ImportConfigRequest, error) {
+	switch v := arg.(type) {
+	case string:
+		return DecodeImportConfigRequest([]byte(v))
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ImportConfigRequest{}, fmt.Errorf("invalid import data: %w", err)
+		}
+		return DecodeImportConfigRequest(data)
+	}
+	return ImportConfigRequest{}, fmt.Errorf("invalid import data format")
+}
@@ -7,15 +7,19 @@ import (
 
 // BroadcastDebouncer collapses rapid MPD subsystem events into batched broadcasts.
 // Multiple subsystem changes within the debounce window result in a single
-// broadcast for each affected type (state and/or queue).
+// broadcast for each affected type (state and/or queue). With a maximum wait
+// set, a continuous stream of events, such as a seek bar or volume drag, is
+// still broadcast at that interval instead of only once it ends.
 type BroadcastDebouncer struct {
 	window        time.Duration
+	maxWait       time.Duration // 0 waits for the events to stop
 	stateCallback func()
 	queueCallback func()
 
 	mu           sync.Mutex
 	pendingState bool
 	pendingQueue bool
+	pendingSince time.Time // First trigger not yet flushed; zero when idle
 	timer        *time.Timer
 	stopped      bool
 }
@@ -31,9 +35,18 @@ func NewBroadcastDebouncer(window time.Duration, stateCallback, queueCallback fu
 	}
 }
 
+// WithMaxWait bounds how long triggers can defer the callbacks: they fire at
+// most maxWait after the first trigger since the last flush. Since the
+// callbacks read the current state when they run, each broadcast carries the
+// latest snapshot.
+func (d *BroadcastDebouncer) WithMaxWait(maxWait time.Duration) *BroadcastDebouncer {
+	d.maxWait = maxWait
+	return d
+}
+
 // Trigger records that the given MPD subsystem has changed.
 // The actual broadcast callbacks are deferred until the debounce window elapses
-// without further triggers.
+// without further triggers, or the maximum wait is reached.
 func (d *BroadcastDebouncer) Trigger(subsystem string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -50,11 +63,19 @@ func (d *BroadcastDebouncer) Trigger(subsystem string) {
 		d.pendingQueue = true
 	}
 
-	// Reset the timer
+	// Reset the timer, but not past the maximum wait
+	now := time.Now()
+	if d.pendingSince.IsZero() {
+		d.pendingSince = now
+	}
+	delay := d.window
+	if d.maxWait > 0 {
+		delay = max(min(delay, d.maxWait-now.Sub(d.pendingSince)), 0)
+	}
 	if d.timer != nil {
 		d.timer.Stop()
 	}
-	d.timer = time.AfterFunc(d.window, d.flush)
+	d.timer = time.AfterFunc(delay, d.flush)
 }
 
 // flush fires callbacks for any pending flags and resets them.
@@ -64,6 +85,7 @@ func (d *BroadcastDebouncer) flush() {
 	doQueue := d.pendingQueue
 	d.pendingState = false
 	d.pendingQueue = false
+	d.pendingSince = time.Time{}
 	d.mu.Unlock()
 
 	if doState && d.stateCallback != nil {
@@ -160,3 +160,29 @@ func TestDebouncerTriggerAfterStopIsIgnored(t *testing.T) {
 		t.Errorf("expected 0 state callbacks after stop+trigger, got %d", got)
 	}
 }
+
+func TestDebouncerMaxWaitFiresDuringContinuousTriggers(t *testing.T) {
+	var stateCalls int32
+
+	d := NewBroadcastDebouncer(50*time.Millisecond,
+		func() { atomic.AddInt32(&stateCalls, 1) },
+		func() {},
+	).WithMaxWait(50 * time.Millisecond)
+	defer d.Stop()
+
+	// A 300ms drag keeps resetting the window; without a maximum wait
+	// nothing would be broadcast until it ends
+	for i := 0; i < 60; i++ {
+		d.Trigger("mixer")
+		time.Sleep(5 * time.Millisecond)
+	}
+	during := atomic.LoadInt32(&stateCalls)
+	time.Sleep(100 * time.Millisecond)
+
+	if during < 3 {
+		t.Errorf("expected periodic state callbacks during continuous triggers, got %d", during)
+	}
+	if got := atomic.LoadInt32(&stateCalls); got > 8 {
+		t.Errorf("expected state callbacks limited to about one per 50ms, got %d", got)
+	}
+}
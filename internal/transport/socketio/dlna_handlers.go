@@ -0,0 +1,176 @@
+package socketio
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zishang520/socket.io/servers/socket/v3"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/dlna"
+)
+
+// dlnaTimeout bounds discovery plus a cast, including renderer round trips.
+const dlnaTimeout = 20 * time.Second
+
+// DlnaHandlers contains Socket.IO handlers for casting to UPnP/DLNA renderers.
+// MPD stays the default output; casting is an explicit per-track action.
+type DlnaHandlers struct {
+	dlnaService *dlna.Service
+	server      *Server
+}
+
+// NewDlnaHandlers creates a new DlnaHandlers instance.
+func NewDlnaHandlers(dlnaService *dlna.Service, server *Server) *DlnaHandlers {
+	return &DlnaHandlers{
+		dlnaService: dlnaService,
+		server:      server,
+	}
+}
+
+// RegisterHandlers registers all DLNA-related Socket.IO handlers.
+func (h *DlnaHandlers) RegisterHandlers(client *socket.Socket) {
+	h.server.on(client, "getDlnaRenderers", func(cmd *Command, args ...any) {
+		h.handleGetRenderers(cmd)
+	})
+
+	h.server.on(client, "castToDlna", func(cmd *Command, args ...any) {
+		h.handleCast(cmd, args...)
+	})
+}
+
+// handleGetRenderers searches the network and emits pushDlnaRenderers.
+func (h *DlnaHandlers) handleGetRenderers(cmd *Command) {
+	cmd.Log.Debug().Msg("Received getDlnaRenderers")
+
+	// Discovery waits for SSDP responses; don't block the socket's event loop
+	go func() {
+		ctx, cancel := context.WithTimeout(cmd.Ctx, dlnaTimeout)
+		defer cancel()
+
+		resp := h.dlnaService.GetRenderers(ctx)
+		cmd.Log.Debug().Int("count", len(resp.Renderers)).Msg("Sending pushDlnaRenderers")
+		cmd.Emit("pushDlnaRenderers", resp)
+	}()
+}
+
+// handleCast sends the current track to a renderer and pauses local playback.
+// Payload: {udn: string}
+func (h *DlnaHandlers) handleCast(cmd *Command, args ...any) {
+	cmd.Log.Debug().Interface("args", args).Msg("Received castToDlna")
+
+	var udn string
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			udn = getString(payload, "udn")
+		}
+	}
+	if udn == "" {
+		cmd.Emit("pushDlnaCast", dlna.CastResponse{Error: "udn is required"})
+		return
+	}
+
+	if h.server.mpdClient == nil {
+		cmd.Emit("pushDlnaCast", dlna.CastResponse{UDN: udn, Error: "MPD not available"})
+		return
+	}
+	song, err := h.server.mpdClient.CurrentSong()
+	if err != nil || song["file"] == "" {
+		cmd.Emit("pushDlnaCast", dlna.CastResponse{UDN: udn, Error: "Nothing is playing"})
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dlnaTimeout)
+		defer cancel()
+
+		resp := h.cast(ctx, udn, song)
+		if resp.Error != "" {
+			cmd.Log.Error().Str("udn", udn).Str("error", resp.Error).Msg("DLNA cast failed")
+			cmd.Emit("pushToastMessage", map[string]interface{}{
+				"type":    "error",
+				"title":   "Cast Failed",
+				"message": resp.Error,
+			})
+		} else {
+			cmd.Emit("pushToastMessage", map[string]interface{}{
+				"type":    "success",
+				"title":   "Casting",
+				"message": fmt.Sprintf("Playing on %s", resp.Name),
+			})
+		}
+		cmd.Emit("pushDlnaCast", resp)
+	}()
+}
+
+// cast resolves the renderer, builds a URL it can fetch and starts playback.
+func (h *DlnaHandlers) cast(ctx context.Context, udn string, song map[string]string) dlna.CastResponse {
+	resp := dlna.CastResponse{UDN: udn, Title: song["Title"]}
+
+	renderer, err := h.dlnaService.Renderer(ctx, udn)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Name = renderer.Name
+
+	file := song["file"]
+	meta := dlna.Metadata{
+		Title:    song["Title"],
+		Artist:   song["Artist"],
+		Album:    song["Album"],
+		MimeType: dlna.MimeType(file),
+	}
+	if meta.Title == "" {
+		meta.Title = file
+	}
+
+	if isStreamURI(file) {
+		// Web radio and other streams can be fetched by the renderer directly
+		resp.URL = file
+	} else {
+		base, err := h.streamBaseURL(renderer)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.URL = base + "/stream?path=" + url.QueryEscape(file)
+		meta.AlbumArtURI = base + "/albumart?path=" + url.QueryEscape(file)
+	}
+
+	if err := h.dlnaService.Cast(ctx, renderer, resp.URL, meta); err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	// The renderer is now the output; stop playing locally too
+	if h.server.playerService != nil {
+		if err := h.server.playerService.Pause(); err != nil {
+			log.Warn().Err(err).Msg("Failed to pause MPD after casting")
+		}
+	}
+
+	log.Info().Str("renderer", renderer.Name).Str("url", resp.URL).Msg("Cast to DLNA renderer")
+	return resp
+}
+
+// streamBaseURL returns this server's base URL as reachable from the renderer.
+func (h *DlnaHandlers) streamBaseURL(renderer dlna.Renderer) (string, error) {
+	if h.server.httpPort == "" {
+		return "", fmt.Errorf("HTTP port not configured")
+	}
+	ip, err := dlna.LocalIPFor(renderer.Address)
+	if err != nil {
+		return "", fmt.Errorf("no route to renderer: %w", err)
+	}
+	return "http://" + net.JoinHostPort(ip, h.server.httpPort), nil
+}
+
+// isStreamURI reports whether an MPD song URI is a network stream.
+func isStreamURI(uri string) bool {
+	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")
+}
@@ -115,16 +115,16 @@ func NewEnrichmentHandlers(cfg EnrichmentConfig, server *Server) *EnrichmentHand
 
 // RegisterHandlers registers enrichment-related Socket.IO handlers.
 func (h *EnrichmentHandlers) RegisterHandlers(client *socket.Socket) {
-	client.On("enrichment:status", func(args ...interface{}) {
-		h.handleGetStatus(client)
+	h.server.on(client, "enrichment:status", func(cmd *Command, args ...any) {
+		h.handleGetStatus(cmd)
 	})
 
-	client.On("enrichment:queue", func(args ...interface{}) {
-		h.handleQueueMissing(client)
+	h.server.on(client, "enrichment:queue", func(cmd *Command, args ...any) {
+		h.handleQueueMissing(cmd)
 	})
 
-	client.On("enrichment:artists:queue", func(args ...interface{}) {
-		h.handleQueueArtistImages(client)
+	h.server.on(client, "enrichment:artists:queue", func(cmd *Command, args ...any) {
+		h.handleQueueArtistImages(cmd)
 	})
 }
 
@@ -183,16 +183,16 @@ type EnrichmentStatusResponse struct {
 	QueueRunning  bool `json:"queueRunning"`
 }
 
-func (h *EnrichmentHandlers) handleGetStatus(client *socket.Socket) {
-	log.Debug().Msg("Received enrichment:status")
-	client.Emit("pushEnrichmentStatus", h.getStatus())
+func (h *EnrichmentHandlers) handleGetStatus(cmd *Command) {
+	cmd.Log.Debug().Msg("Received enrichment:status")
+	cmd.Emit("pushEnrichmentStatus", h.getStatus())
 }
 
-func (h *EnrichmentHandlers) handleQueueMissing(client *socket.Socket) {
-	log.Info().Msg("Received enrichment:queue - queuing missing artwork")
+func (h *EnrichmentHandlers) handleQueueMissing(cmd *Command) {
+	cmd.Log.Info().Msg("Received enrichment:queue - queuing missing artwork")
 
 	if h.coordinator == nil {
-		client.Emit("pushEnrichmentQueueResult", map[string]interface{}{
+		cmd.Emit("pushEnrichmentQueueResult", map[string]interface{}{
 			"success": false,
 			"error":   "enrichment coordinator not available",
 		})
@@ -201,15 +201,15 @@ func (h *EnrichmentHandlers) handleQueueMissing(client *socket.Socket) {
 
 	go func() {
 		if err := h.coordinator.QueueMissingArtwork(h.ctx); err != nil {
-			log.Error().Err(err).Msg("Failed to queue missing artwork")
+			cmd.Log.Error().Err(err).Msg("Failed to queue missing artwork")
 			return
 		}
 		if h.server != nil && h.server.io != nil {
-			h.server.io.Emit("pushEnrichmentStatus", h.getStatus())
+			h.server.emitAll("pushEnrichmentStatus", h.getStatus())
 		}
 	}()
 
-	client.Emit("pushEnrichmentQueueResult", map[string]interface{}{
+	cmd.Emit("pushEnrichmentQueueResult", map[string]interface{}{
 		"success": true,
 		"message": "Enrichment queue processing started",
 	})
@@ -261,11 +261,11 @@ func (p *cacheDAOAlbumProvider) UpdateAlbumArtwork(albumID, artworkID string) er
 }
 
 // handleQueueArtistImages queues missing artist images for enrichment.
-func (h *EnrichmentHandlers) handleQueueArtistImages(client *socket.Socket) {
-	log.Info().Msg("Received enrichment:artists:queue - queuing missing artist images")
+func (h *EnrichmentHandlers) handleQueueArtistImages(cmd *Command) {
+	cmd.Log.Info().Msg("Received enrichment:artists:queue - queuing missing artist images")
 
 	if h.coordinator == nil {
-		client.Emit("pushEnrichmentArtistQueueResult", map[string]interface{}{
+		cmd.Emit("pushEnrichmentArtistQueueResult", map[string]interface{}{
 			"success": false,
 			"error":   "enrichment coordinator not available",
 		})
@@ -274,15 +274,15 @@ func (h *EnrichmentHandlers) handleQueueArtistImages(client *socket.Socket) {
 
 	go func() {
 		if err := h.coordinator.QueueMissingArtistImages(h.ctx); err != nil {
-			log.Error().Err(err).Msg("Failed to queue missing artist images")
+			cmd.Log.Error().Err(err).Msg("Failed to queue missing artist images")
 			return
 		}
 		if h.server != nil && h.server.io != nil {
-			h.server.io.Emit("pushEnrichmentStatus", h.getStatus())
+			h.server.emitAll("pushEnrichmentStatus", h.getStatus())
 		}
 	}()
 
-	client.Emit("pushEnrichmentArtistQueueResult", map[string]interface{}{
+	cmd.Emit("pushEnrichmentArtistQueueResult", map[string]interface{}{
 		"success": true,
 		"message": "Artist image enrichment queue processing started",
 	})
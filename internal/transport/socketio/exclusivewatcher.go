@@ -0,0 +1,112 @@
+package socketio
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	mpdclient "github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
+)
+
+// exclusiveCheckInterval is how often exclusive mode re-counts MPD clients.
+const exclusiveCheckInterval = 15 * time.Second
+
+// exclusiveState is the latest MPD client count taken in exclusive mode.
+type exclusiveState struct {
+	otherClients int
+	err          error
+}
+
+// SetExclusive turns on exclusive mode. The counter reports MPD clients this
+// backend didn't open; any it finds are surfaced in the bit-perfect status.
+func (s *Server) SetExclusive(counter mpdclient.ClientCounter) {
+	s.exclusiveCounter = counter
+}
+
+// StartExclusiveWatcher periodically counts other MPD clients and re-pushes
+// the bit-perfect status when the count changes.
+func (s *Server) StartExclusiveWatcher(ctx context.Context) {
+	if s.exclusiveCounter == nil {
+		return
+	}
+
+	go func() {
+		log.Info().Msg("Exclusive mode watcher started")
+		ticker := time.NewTicker(exclusiveCheckInterval)
+		defer ticker.Stop()
+
+		s.checkExclusive()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("Exclusive mode watcher stopped")
+				return
+			case <-ticker.C:
+				if s.checkExclusive() {
+					s.emitAll("pushBitPerfect", s.bitPerfectStatus())
+				}
+			}
+		}
+	}()
+}
+
+// checkExclusive recounts other MPD clients and reports whether the result changed.
+func (s *Server) checkExclusive() bool {
+	others, err := s.exclusiveCounter.OtherClients()
+
+	s.exclusiveMu.Lock()
+	prev := s.exclusive
+	s.exclusive = exclusiveState{otherClients: others, err: err}
+	s.exclusiveMu.Unlock()
+
+	changed := others != prev.otherClients || (err == nil) != (prev.err == nil)
+	if !changed {
+		return false
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Exclusive mode: cannot count MPD clients")
+	} else if others > 0 {
+		log.Warn().Int("otherClients", others).Msg("Exclusive mode: other MPD clients connected")
+	} else {
+		log.Info().Msg("Exclusive mode: no other MPD clients connected")
+	}
+	return true
+}
+
+// bitPerfectStatus returns the bit-perfect check with exclusive mode applied.
+func (s *Server) bitPerfectStatus() BitPerfectStatus {
+	status := s.audioConfig.GetBitPerfectStatus()
+	if s.exclusiveCounter == nil {
+		return status
+	}
+
+	s.exclusiveMu.Lock()
+	state := s.exclusive
+	s.exclusiveMu.Unlock()
+
+	applyExclusiveStatus(&status, state)
+	return status
+}
+
+// applyExclusiveStatus marks the status as exclusive and warns about other
+// MPD clients, which could change the output format or volume mid-playback.
+func applyExclusiveStatus(status *BitPerfectStatus, state exclusiveState) {
+	status.Exclusive = true
+	status.OtherClients = state.otherClients
+
+	switch {
+	case state.err != nil:
+		status.Warnings = append(status.Warnings, "Exclusive mode: cannot count MPD clients - "+state.err.Error())
+	case state.otherClients > 0:
+		status.Warnings = append(status.Warnings, fmt.Sprintf("Exclusive mode: %d other MPD client(s) connected", state.otherClients))
+	default:
+		status.Config = append(status.Config, "Exclusive mode: no other MPD clients (good)")
+		return
+	}
+
+	if status.Status == "ok" {
+		status.Status = "warning"
+	}
+}
@@ -0,0 +1,78 @@
+package socketio
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeClientCounter returns a fixed MPD client count.
+type fakeClientCounter struct {
+	others int
+	err    error
+}
+
+func (f *fakeClientCounter) OtherClients() (int, error) { return f.others, f.err }
+
+func TestCheckExclusive_ReportsChanges(t *testing.T) {
+	counter := &fakeClientCounter{}
+	s := &Server{exclusiveCounter: counter}
+
+	if s.checkExclusive() {
+		t.Error("checkExclusive() = true with no other clients at startup, want false")
+	}
+
+	counter.others = 2
+	if !s.checkExclusive() {
+		t.Error("checkExclusive() = false after a client connected, want true")
+	}
+	if s.checkExclusive() {
+		t.Error("checkExclusive() = true with unchanged count, want false")
+	}
+
+	counter.others, counter.err = 0, errors.New("no /proc")
+	if !s.checkExclusive() {
+		t.Error("checkExclusive() = false after counting failed, want true")
+	}
+}
+
+func TestApplyExclusiveStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		state        exclusiveState
+		wantStatus   string
+		wantWarnings int
+	}{
+		{name: "no other clients", state: exclusiveState{}, wantStatus: "ok", wantWarnings: 0},
+		{name: "other clients", state: exclusiveState{otherClients: 2}, wantStatus: "warning", wantWarnings: 1},
+		{name: "count failed", state: exclusiveState{err: errors.New("remote")}, wantStatus: "warning", wantWarnings: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := BitPerfectStatus{Status: "ok"}
+			applyExclusiveStatus(&status, tt.state)
+
+			if !status.Exclusive {
+				t.Error("Exclusive = false, want true")
+			}
+			if status.OtherClients != tt.state.otherClients {
+				t.Errorf("OtherClients = %d, want %d", status.OtherClients, tt.state.otherClients)
+			}
+			if status.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", status.Status, tt.wantStatus)
+			}
+			if len(status.Warnings) != tt.wantWarnings {
+				t.Errorf("Warnings = %v, want %d", status.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestApplyExclusiveStatus_KeepsError(t *testing.T) {
+	status := BitPerfectStatus{Status: "error", Issues: []string{"resampler"}}
+	applyExclusiveStatus(&status, exclusiveState{otherClients: 1})
+
+	if status.Status != "error" {
+		t.Errorf("Status = %q, want error to outrank the exclusive warning", status.Status)
+	}
+}
@@ -0,0 +1,85 @@
+package socketio
+
+import (
+	"time"
+
+	"github.com/zishang520/socket.io/servers/socket/v3"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/external"
+)
+
+// externalSettleDelay is how long to wait after starting or stopping a
+// daemon before reading its status back.
+const externalSettleDelay = 2 * time.Second
+
+// ExternalSourceHandlers contains Socket.IO handlers for managing alternate
+// audio daemons (Audirvana, Roon Bridge, shairport-sync, squeezelite).
+type ExternalSourceHandlers struct {
+	registry *external.Registry
+	server   *Server
+}
+
+// NewExternalSourceHandlers creates a new ExternalSourceHandlers instance.
+func NewExternalSourceHandlers(registry *external.Registry, server *Server) *ExternalSourceHandlers {
+	return &ExternalSourceHandlers{
+		registry: registry,
+		server:   server,
+	}
+}
+
+// RegisterHandlers registers all external source Socket.IO handlers.
+func (h *ExternalSourceHandlers) RegisterHandlers(client *socket.Socket) {
+	h.server.on(client, "getExternalSources", func(cmd *Command, args ...any) {
+		h.handleGetExternalSources(cmd)
+	})
+
+	h.server.on(client, "controlExternalSource", func(cmd *Command, args ...any) {
+		h.handleControlExternalSource(cmd, args...)
+	})
+}
+
+// handleGetExternalSources emits pushExternalSources with every source's status.
+func (h *ExternalSourceHandlers) handleGetExternalSources(cmd *Command) {
+	cmd.Log.Debug().Msg("Received getExternalSources")
+
+	// systemctl calls can take a moment per source
+	go func() {
+		cmd.Emit("pushExternalSources", h.registry.List())
+	}()
+}
+
+// handleControlExternalSource starts or stops a source.
+// Payload: {name: string, action: "start"|"stop"}
+func (h *ExternalSourceHandlers) handleControlExternalSource(cmd *Command, args ...any) {
+	cmd.Log.Debug().Interface("args", args).Msg("Received controlExternalSource")
+
+	var name, action string
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			name = getString(payload, "name")
+			action = getString(payload, "action")
+		}
+	}
+
+	go func() {
+		resp := external.ControlResponse{Name: name, Action: action}
+
+		if err := h.registry.Control(name, action); err != nil {
+			cmd.Log.Error().Err(err).Str("name", name).Str("action", action).Msg("External source control failed")
+			resp.Error = err.Error()
+		} else {
+			resp.Success = true
+			time.Sleep(externalSettleDelay)
+		}
+
+		if src, ok := h.registry.Get(name); ok {
+			resp.Status = src.Status()
+		}
+		cmd.Emit("pushExternalSourceResult", resp)
+
+		// Everyone's source list changes when a daemon starts or stops
+		if resp.Success {
+			h.server.emitAll("pushExternalSources", h.registry.List())
+		}
+	}()
+}
@@ -0,0 +1,201 @@
+package socketio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
+)
+
+// factoryResetFiles are the data files copied into a factory reset backup
+// alongside the config export. The export leaves out NAS passwords and play
+// history, so the raw files are kept for a full manual restore.
+var factoryResetFiles = []string{"sources.json", "favorites.json", "playback_history.json"}
+
+// FactoryResetResponse reports the outcome of factoryReset.
+type FactoryResetResponse struct {
+	Success bool     `json:"success"`
+	Backup  string   `json:"backup,omitempty"` // Directory holding the pre-reset state
+	Reset   []string `json:"reset"`            // Parts that were reset
+	Errors  []string `json:"errors"`           // Parts that could not be reset
+	Error   string   `json:"error,omitempty"`
+}
+
+// FactoryReset clears the library cache, play history, favorites and NAS
+// shares and puts the audio settings back to their bit-perfect defaults. It
+// does nothing unless confirm is set. The current state is backed up first
+// and the reset is aborted if that fails; the backup's config-export.json
+// can be restored with importConfig.
+func (s *Server) FactoryReset(confirm bool) FactoryResetResponse {
+	resp := FactoryResetResponse{
+		Reset:  []string{},
+		Errors: []string{},
+	}
+	if !confirm {
+		resp.Error = "factory reset requires confirm: true"
+		return resp
+	}
+	if s.dataDir == "" {
+		resp.Error = "factory reset not available: data directory not set"
+		return resp
+	}
+
+	backup, err := s.backupForFactoryReset()
+	if err != nil {
+		log.Error().Err(err).Msg("Factory reset aborted: backup failed")
+		resp.Error = "failed to back up current state: " + err.Error()
+		return resp
+	}
+	resp.Backup = backup
+	log.Warn().Str("backup", backup).Msg("Factory reset starting")
+
+	if s.cacheDB != nil {
+		if err := s.cacheDB.Clear(); err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("library cache: %v", err))
+		} else {
+			resp.Reset = append(resp.Reset, "libraryCache")
+		}
+	}
+
+	if s.localMusicService != nil {
+		s.localMusicService.ClearHistory()
+		s.localMusicService.ClearFavorites()
+		resp.Reset = append(resp.Reset, "history", "favorites")
+		s.emitAll("pushHistoryCleared", map[string]interface{}{"success": true})
+		s.emitAll("pushFavorites", s.localMusicService.ListFavorites(""))
+	}
+
+	if s.sourcesService != nil {
+		if errs := s.deleteAllNasShares(); len(errs) > 0 {
+			resp.Errors = append(resp.Errors, errs...)
+		} else {
+			resp.Reset = append(resp.Reset, "nasShares")
+		}
+		shares, _ := s.sourcesService.ListNasShares()
+		s.emitAll("pushListNasShares", shares)
+	}
+
+	applied, errs := s.resetAudioSettings()
+	resp.Reset = append(resp.Reset, applied...)
+	resp.Errors = append(resp.Errors, errs...)
+
+	// Rescan without the removed shares; the cache is rebuilt from the result
+	if _, err := s.mpdClient.Update(""); err != nil {
+		log.Warn().Err(err).Msg("Failed to trigger MPD update after factory reset")
+	}
+	s.handleDatabaseUpdate()
+
+	resp.Success = len(resp.Errors) == 0
+	log.Warn().
+		Str("backup", backup).
+		Strs("reset", resp.Reset).
+		Strs("errors", resp.Errors).
+		Msg("Factory reset complete")
+	return resp
+}
+
+// deleteAllNasShares unmounts and removes every configured NAS share.
+func (s *Server) deleteAllNasShares() []string {
+	shares, err := s.sourcesService.ListNasShares()
+	if err != nil {
+		return []string{fmt.Sprintf("NAS shares: %v", err)}
+	}
+	var errs []string
+	for _, share := range shares {
+		result, err := s.sourcesService.DeleteNasShare(share.ID)
+		if err == nil && !result.Success {
+			err = errors.New(result.Error)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("NAS share %q: %v", share.Name, err))
+		}
+	}
+	return errs
+}
+
+// resetAudioSettings applies the bit-perfect MPD settings, switches DSD back
+// to native playback and restores the default fades. MPD is restarted by
+// each config change that was needed.
+func (s *Server) resetAudioSettings() (applied, errs []string) {
+	if result := s.audioConfig.ApplyBitPerfect(); !result.Success {
+		for _, e := range result.Errors {
+			errs = append(errs, "bit-perfect: "+e)
+		}
+	} else {
+		applied = append(applied, "bitPerfect")
+	}
+	s.emitAll("pushBitPerfect", s.bitPerfectStatus())
+	s.emitAll("pushMixerMode", s.audioConfig.GetMixerMode())
+
+	if mode := s.audioConfig.GetDsdMode(); mode.Success && mode.Mode != "native" {
+		if result := s.audioConfig.SetDsdMode("native"); !result.Success {
+			errs = append(errs, "DSD mode: "+result.Error)
+		} else {
+			applied = append(applied, "dsdMode")
+			s.emitAll("pushDsdMode", result)
+		}
+	}
+
+	if s.playerService != nil && s.playerService.FadeSettings() != player.DefaultFadeSettings {
+		if err := s.playerService.SetFadeSettings(player.DefaultFadeSettings); err != nil {
+			errs = append(errs, fmt.Sprintf("fade settings: %v", err))
+		} else {
+			applied = append(applied, "fade")
+			s.emitAll("pushFadeSettings", player.DefaultFadeSettings)
+		}
+	}
+	return applied, errs
+}
+
+// backupForFactoryReset saves the current configuration export and data
+// files to a new timestamped directory under dataDir/backups and returns it.
+// The MPD config is backed up separately by the audio config write.
+func (s *Server) backupForFactoryReset() (string, error) {
+	dir := filepath.Join(s.dataDir, "backups", "factory-reset-"+time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(s.ExportConfig(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config-export.json"), data, 0600); err != nil {
+		return "", err
+	}
+
+	for _, name := range factoryResetFiles {
+		err := copyFile(filepath.Join(s.dataDir, name), filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return dir, nil
+}
+
+// copyFile copies src to dst, readable only by the owner since the sources
+// config holds NAS passwords.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,104 @@
+package socketio
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zishang520/socket.io/servers/socket/v3"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/audio"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/auth"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/system/network"
+)
+
+// InitialState is the pushInitialState payload sent once on connect, in
+// place of separate pushClientRole, pushState, pushQueue, pushNetworkStatus,
+// pushSystemInfo, pushLcdStatus and pushAudioStatus events, so a client can
+// render from a single event. The individual events are still sent on
+// request and as things change. Revision is the state and queue revision the
+// payload is current as of.
+type InitialState struct {
+	Revision uint64                   `json:"revision"`
+	Role     auth.Role                `json:"role"`
+	State    *player.PlayerState      `json:"state,omitempty"` // Omitted if MPD can't be reached
+	Queue    []map[string]interface{} `json:"queue"`
+	Network  network.Status           `json:"network"`
+	System   SystemInfo               `json:"system"`
+	LCD      LCDStatus                `json:"lcd"`
+	Audio    audio.AudioStatus        `json:"audio"`
+}
+
+// mpdReadyAttempts and mpdReadyInterval bound how long the initial sync
+// waits for MPD to answer a ping, e.g. while it is still starting up.
+const (
+	mpdReadyAttempts = 10
+	mpdReadyInterval = 200 * time.Millisecond
+)
+
+// pushInitialState sends a newly connected client its InitialState, once per
+// connection. It waits for MPD to respond first so a client connecting on a
+// cold start doesn't render an empty player, but sends what it has if MPD
+// stays unreachable. A client reconnecting with the current revision gets a
+// pushResumed acknowledgement instead.
+func (s *Server) pushInitialState(client *socket.Socket) {
+	session := sessionOf(client)
+	session.initialSync.Do(func() {
+		if s.canResume(client) {
+			client.Emit("pushResumed", ResumeAck{Revision: s.currentRevision()})
+			return
+		}
+		if err := s.waitForMPD(session.ctx); err != nil {
+			if session.ctx.Err() != nil {
+				return
+			}
+			log.Warn().Err(err).Str("id", string(client.Id())).Msg("MPD not ready, sending initial state without it")
+		}
+		client.Emit("pushInitialState", s.initialState(client))
+	})
+}
+
+// waitForMPD returns once MPD answers a ping, retrying briefly, or the last
+// error once the attempts run out or ctx is done.
+func (s *Server) waitForMPD(ctx context.Context) error {
+	var err error
+	for attempt := 0; attempt < mpdReadyAttempts; attempt++ {
+		if err = s.mpdClient.Ping(); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(mpdReadyInterval):
+		}
+	}
+	return err
+}
+
+// initialState gathers a client's InitialState.
+func (s *Server) initialState(client *socket.Socket) InitialState {
+	initial := InitialState{
+		Revision: s.currentRevision(), // Read first so changes while gathering aren't claimed
+		Role:     clientRole(client),
+		Queue:    []map[string]interface{}{},
+		Network:  network.GetStatus(),
+		System:   GetSystemInfo(),
+		LCD:      GetLCDStatus(),
+		Audio:    s.audioController.GetStatus(),
+	}
+
+	if state, err := s.playerService.GetState(); err != nil {
+		log.Error().Err(err).Msg("Failed to get state for initial sync")
+	} else {
+		s.applyExternalSource(state)
+		explainStateError(state)
+		initial.State = state
+	}
+	if queue, err := s.playerService.GetQueue(); err != nil {
+		log.Error().Err(err).Msg("Failed to get queue for initial sync")
+	} else if queue != nil {
+		initial.Queue = queue
+	}
+	return initial
+}
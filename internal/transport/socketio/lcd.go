@@ -289,6 +289,6 @@ func SetLCDPower(on bool) error {
 // BroadcastLCDStatus sends LCD status to all connected clients.
 func (s *Server) BroadcastLCDStatus() {
 	status := GetLCDStatus()
-	s.io.Emit("pushLcdStatus", status)
+	s.emitAll("pushLcdStatus", status)
 	log.Debug().Bool("isOn", status.IsOn).Msg("Broadcast LCD status")
 }
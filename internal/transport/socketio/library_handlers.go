@@ -2,7 +2,6 @@ package socketio
 
 import (
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/library"
-	"github.com/rs/zerolog/log"
 	"github.com/zishang520/socket.io/servers/socket/v3"
 )
 
@@ -11,58 +10,90 @@ type LibraryService interface {
 	GetAlbums(req library.GetAlbumsRequest) library.AlbumsResponse
 	GetArtists(req library.GetArtistsRequest) library.ArtistsResponse
 	GetArtistAlbums(req library.GetArtistAlbumsRequest) library.ArtistAlbumsResponse
+	GetGenres(req library.GetGenresRequest) library.GenresResponse
+	GetGenreAlbums(req library.GetGenreAlbumsRequest) library.GenreAlbumsResponse
 	GetAlbumTracks(req library.GetAlbumTracksRequest) library.AlbumTracksResponse
+	BrowseFolder(req library.BrowseFolderRequest) library.FolderResponse
+	SearchAdvanced(req library.SearchAdvancedRequest) library.SearchAdvancedResponse
+	GetRecentlyAdded(req library.GetRecentlyAddedRequest) library.RecentlyAddedResponse
 	GetRadioStations(req library.GetRadioRequest) library.RadioResponse
 }
 
 // LibraryHandlers contains Socket.IO handlers for library operations.
 type LibraryHandlers struct {
 	libraryService LibraryService
+	server         *Server
 }
 
 // NewLibraryHandlers creates a new LibraryHandlers instance.
-func NewLibraryHandlers(libraryService LibraryService) *LibraryHandlers {
+func NewLibraryHandlers(libraryService LibraryService, server *Server) *LibraryHandlers {
 	return &LibraryHandlers{
 		libraryService: libraryService,
+		server:         server,
 	}
 }
 
 // RegisterHandlers registers all library-related Socket.IO handlers.
 func (h *LibraryHandlers) RegisterHandlers(client *socket.Socket) {
 	// Albums listing
-	client.On("library:albums:list", func(args ...interface{}) {
-		h.handleGetAlbums(client, args...)
+	h.server.on(client, "library:albums:list", func(cmd *Command, args ...any) {
+		h.handleGetAlbums(cmd, args...)
 	})
 
 	// Artists listing
-	client.On("library:artists:list", func(args ...interface{}) {
-		h.handleGetArtists(client, args...)
+	h.server.on(client, "library:artists:list", func(cmd *Command, args ...any) {
+		h.handleGetArtists(cmd, args...)
 	})
 
 	// Artist albums
-	client.On("library:artist:albums", func(args ...interface{}) {
-		h.handleGetArtistAlbums(client, args...)
+	h.server.on(client, "library:artist:albums", func(cmd *Command, args ...any) {
+		h.handleGetArtistAlbums(cmd, args...)
+	})
+
+	// Genres listing
+	h.server.on(client, "getGenres", func(cmd *Command, args ...any) {
+		h.handleGetGenres(cmd, args...)
+	})
+
+	// Genre albums
+	h.server.on(client, "getGenreAlbums", func(cmd *Command, args ...any) {
+		h.handleGetGenreAlbums(cmd, args...)
 	})
 
 	// Album tracks
-	client.On("library:album:tracks", func(args ...interface{}) {
-		h.handleGetAlbumTracks(client, args...)
+	h.server.on(client, "library:album:tracks", func(cmd *Command, args ...any) {
+		h.handleGetAlbumTracks(cmd, args...)
+	})
+
+	// Newest albums for the home screen
+	h.server.on(client, "getRecentlyAdded", func(cmd *Command, args ...any) {
+		h.handleGetRecentlyAdded(cmd, args...)
+	})
+
+	// Folder view
+	h.server.on(client, "browseFolder", func(cmd *Command, args ...any) {
+		h.handleBrowseFolder(cmd, args...)
+	})
+
+	// Field-scoped search
+	h.server.on(client, "searchAdvanced", func(cmd *Command, args ...any) {
+		h.handleSearchAdvanced(cmd, args...)
 	})
 
 	// Radio stations
-	client.On("library:radio:list", func(args ...interface{}) {
-		h.handleGetRadioStations(client, args...)
+	h.server.on(client, "library:radio:list", func(cmd *Command, args ...any) {
+		h.handleGetRadioStations(cmd, args...)
 	})
 
 	// Radio play
-	client.On("library:radio:play", func(args ...interface{}) {
-		h.handlePlayRadio(client, args...)
+	h.server.on(client, "library:radio:play", func(cmd *Command, args ...any) {
+		h.handlePlayRadio(cmd, args...)
 	})
 }
 
 // handleGetAlbums handles the library:albums:list event.
-func (h *LibraryHandlers) handleGetAlbums(client *socket.Socket, args ...interface{}) {
-	log.Debug().Msg("Received library:albums:list")
+func (h *LibraryHandlers) handleGetAlbums(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received library:albums:list")
 
 	req := library.GetAlbumsRequest{
 		Scope: library.ScopeAll,
@@ -94,18 +125,18 @@ func (h *LibraryHandlers) handleGetAlbums(client *socket.Socket, args ...interfa
 
 	resp := h.libraryService.GetAlbums(req)
 
-	log.Debug().
+	cmd.Log.Debug().
 		Str("scope", string(req.Scope)).
 		Int("albumCount", len(resp.Albums)).
 		Int("total", resp.Pagination.Total).
 		Msg("Sending pushLibraryAlbums")
 
-	client.Emit("pushLibraryAlbums", resp)
+	cmd.Emit("pushLibraryAlbums", resp)
 }
 
 // handleGetArtists handles the library:artists:list event.
-func (h *LibraryHandlers) handleGetArtists(client *socket.Socket, args ...interface{}) {
-	log.Debug().Msg("Received library:artists:list")
+func (h *LibraryHandlers) handleGetArtists(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received library:artists:list")
 
 	req := library.GetArtistsRequest{
 		Page:  1,
@@ -129,17 +160,17 @@ func (h *LibraryHandlers) handleGetArtists(client *socket.Socket, args ...interf
 
 	resp := h.libraryService.GetArtists(req)
 
-	log.Debug().
+	cmd.Log.Debug().
 		Int("artistCount", len(resp.Artists)).
 		Int("total", resp.Pagination.Total).
 		Msg("Sending pushLibraryArtists")
 
-	client.Emit("pushLibraryArtists", resp)
+	cmd.Emit("pushLibraryArtists", resp)
 }
 
 // handleGetArtistAlbums handles the library:artist:albums event.
-func (h *LibraryHandlers) handleGetArtistAlbums(client *socket.Socket, args ...interface{}) {
-	log.Debug().Msg("Received library:artist:albums")
+func (h *LibraryHandlers) handleGetArtistAlbums(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received library:artist:albums")
 
 	req := library.GetArtistAlbumsRequest{
 		Sort:  library.SortAlphabetical,
@@ -167,17 +198,89 @@ func (h *LibraryHandlers) handleGetArtistAlbums(client *socket.Socket, args ...i
 
 	resp := h.libraryService.GetArtistAlbums(req)
 
-	log.Debug().
+	cmd.Log.Debug().
 		Str("artist", req.Artist).
 		Int("albumCount", len(resp.Albums)).
 		Msg("Sending pushLibraryArtistAlbums")
 
-	client.Emit("pushLibraryArtistAlbums", resp)
+	cmd.Emit("pushLibraryArtistAlbums", resp)
+}
+
+// handleGetGenres handles the getGenres event.
+func (h *LibraryHandlers) handleGetGenres(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received getGenres")
+
+	req := library.GetGenresRequest{
+		Page:  1,
+		Limit: library.DefaultLimit,
+	}
+
+	// Parse request payload
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			if query, ok := payload["query"].(string); ok {
+				req.Query = query
+			}
+			if page, ok := payload["page"].(float64); ok {
+				req.Page = int(page)
+			}
+			if limit, ok := payload["limit"].(float64); ok {
+				req.Limit = int(limit)
+			}
+		}
+	}
+
+	resp := h.libraryService.GetGenres(req)
+
+	cmd.Log.Debug().
+		Int("genreCount", len(resp.Genres)).
+		Int("total", resp.Pagination.Total).
+		Msg("Sending pushGenres")
+
+	cmd.Emit("pushGenres", resp)
+}
+
+// handleGetGenreAlbums handles the getGenreAlbums event.
+func (h *LibraryHandlers) handleGetGenreAlbums(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received getGenreAlbums")
+
+	req := library.GetGenreAlbumsRequest{
+		Sort:  library.SortAlphabetical,
+		Page:  1,
+		Limit: library.DefaultLimit,
+	}
+
+	// Parse request payload
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			if genre, ok := payload["genre"].(string); ok {
+				req.Genre = genre
+			}
+			if sort, ok := payload["sort"].(string); ok {
+				req.Sort = library.SortOrder(sort)
+			}
+			if page, ok := payload["page"].(float64); ok {
+				req.Page = int(page)
+			}
+			if limit, ok := payload["limit"].(float64); ok {
+				req.Limit = int(limit)
+			}
+		}
+	}
+
+	resp := h.libraryService.GetGenreAlbums(req)
+
+	cmd.Log.Debug().
+		Str("genre", req.Genre).
+		Int("albumCount", len(resp.Albums)).
+		Msg("Sending pushGenreAlbums")
+
+	cmd.Emit("pushGenreAlbums", resp)
 }
 
 // handleGetAlbumTracks handles the library:album:tracks event.
-func (h *LibraryHandlers) handleGetAlbumTracks(client *socket.Socket, args ...interface{}) {
-	log.Debug().Msg("Received library:album:tracks")
+func (h *LibraryHandlers) handleGetAlbumTracks(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received library:album:tracks")
 
 	req := library.GetAlbumTracksRequest{}
 
@@ -195,17 +298,42 @@ func (h *LibraryHandlers) handleGetAlbumTracks(client *socket.Socket, args ...in
 
 	resp := h.libraryService.GetAlbumTracks(req)
 
-	log.Debug().
+	cmd.Log.Debug().
 		Str("album", req.Album).
 		Int("trackCount", len(resp.Tracks)).
 		Msg("Sending pushLibraryAlbumTracks")
 
-	client.Emit("pushLibraryAlbumTracks", resp)
+	cmd.Emit("pushLibraryAlbumTracks", resp)
+}
+
+// handleBrowseFolder handles the browseFolder event.
+func (h *LibraryHandlers) handleBrowseFolder(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received browseFolder")
+
+	req := library.BrowseFolderRequest{}
+
+	// Parse request payload
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			if uri, ok := payload["uri"].(string); ok {
+				req.URI = uri
+			}
+		}
+	}
+
+	resp := h.libraryService.BrowseFolder(req)
+
+	cmd.Log.Debug().
+		Str("uri", resp.URI).
+		Int("entryCount", len(resp.Entries)).
+		Msg("Sending pushBrowseFolder")
+
+	cmd.Emit("pushBrowseFolder", resp)
 }
 
 // handleGetRadioStations handles the library:radio:list event.
-func (h *LibraryHandlers) handleGetRadioStations(client *socket.Socket, args ...interface{}) {
-	log.Debug().Msg("Received library:radio:list")
+func (h *LibraryHandlers) handleGetRadioStations(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received library:radio:list")
 
 	req := library.GetRadioRequest{
 		Page:  1,
@@ -229,19 +357,19 @@ func (h *LibraryHandlers) handleGetRadioStations(client *socket.Socket, args ...
 
 	resp := h.libraryService.GetRadioStations(req)
 
-	log.Debug().
+	cmd.Log.Debug().
 		Int("stationCount", len(resp.Stations)).
 		Int("total", resp.Pagination.Total).
 		Msg("Sending pushLibraryRadio")
 
-	client.Emit("pushLibraryRadio", resp)
+	cmd.Emit("pushLibraryRadio", resp)
 }
 
 // handlePlayRadio handles the library:radio:play event.
 // Note: This delegates to the player service which is not injected here.
 // The actual implementation should use the player service from the main server.
-func (h *LibraryHandlers) handlePlayRadio(client *socket.Socket, args ...interface{}) {
-	log.Debug().Msg("Received library:radio:play")
+func (h *LibraryHandlers) handlePlayRadio(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received library:radio:play")
 
 	// Parse request payload
 	var uri string
@@ -254,14 +382,73 @@ func (h *LibraryHandlers) handlePlayRadio(client *socket.Socket, args ...interfa
 	}
 
 	if uri == "" {
-		log.Warn().Msg("library:radio:play received without URI")
+		cmd.Log.Warn().Msg("library:radio:play received without URI")
 		return
 	}
 
-	log.Info().Str("uri", uri).Msg("Radio play requested - delegating to player")
+	cmd.Log.Info().Str("uri", uri).Msg("Radio play requested - delegating to player")
 
 	// Note: The actual playback should be handled by emitting a replaceAndPlay event
 	// or calling the player service directly. For now, we emit an internal event.
 	// The main server should handle this by calling player.ReplaceAndPlay(uri)
-	client.Emit("_internal:radio:play", map[string]string{"uri": uri})
+	cmd.Emit("_internal:radio:play", map[string]string{"uri": uri})
+}
+
+// handleGetRecentlyAdded handles the getRecentlyAdded event.
+func (h *LibraryHandlers) handleGetRecentlyAdded(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received getRecentlyAdded")
+
+	req := library.GetRecentlyAddedRequest{}
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			if limit, ok := payload["limit"].(float64); ok {
+				req.Limit = int(limit)
+			}
+		}
+	}
+
+	resp := h.libraryService.GetRecentlyAdded(req)
+
+	cmd.Log.Debug().
+		Int("albumCount", len(resp.Albums)).
+		Msg("Sending pushRecentlyAdded")
+
+	cmd.Emit("pushRecentlyAdded", resp)
+}
+
+// handleSearchAdvanced handles the searchAdvanced event.
+func (h *LibraryHandlers) handleSearchAdvanced(cmd *Command, args ...any) {
+	cmd.Log.Debug().Msg("Received searchAdvanced")
+
+	req := library.SearchAdvancedRequest{}
+
+	// Parse request payload
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			if artist, ok := payload["artist"].(string); ok {
+				req.Artist = artist
+			}
+			if album, ok := payload["album"].(string); ok {
+				req.Album = album
+			}
+			if title, ok := payload["title"].(string); ok {
+				req.Title = title
+			}
+			if genre, ok := payload["genre"].(string); ok {
+				req.Genre = genre
+			}
+			if limit, ok := payload["limit"].(float64); ok {
+				req.Limit = int(limit)
+			}
+		}
+	}
+
+	resp := h.libraryService.SearchAdvanced(req)
+
+	cmd.Log.Debug().
+		Int("albumCount", len(resp.Albums)).
+		Int("trackCount", len(resp.Tracks)).
+		Msg("Sending pushSearchAdvanced")
+
+	cmd.Emit("pushSearchAdvanced", resp)
 }
@@ -59,11 +59,16 @@ func (a *LibraryMPDAdapter) GetAlbumDetails(basePath string) ([]library.AlbumDet
 	result := make([]library.AlbumDetails, len(details))
 	for i, d := range details {
 		result[i] = library.AlbumDetails{
-			Album:       d.Album,
-			AlbumArtist: d.AlbumArtist,
-			TrackCount:  d.TrackCount,
-			FirstTrack:  d.FirstTrack,
-			TotalTime:   d.TotalTime,
+			Album:          d.Album,
+			AlbumArtist:    d.AlbumArtist,
+			ArtistInferred: d.ArtistInferred,
+			TrackArtists:   d.TrackArtists,
+			TrackCount:     d.TrackCount,
+			FirstTrack:     d.FirstTrack,
+			TotalTime:      d.TotalTime,
+			Year:           d.Year,
+			AddedAt:        d.AddedAt,
+			IsCompilation:  d.IsCompilation,
 		}
 	}
 	return result, nil
@@ -91,6 +96,28 @@ func (a *LibraryMPDAdapter) FindAlbumsByArtist(artist string) ([]library.AlbumIn
 	return result, nil
 }
 
+// ListGenres returns all unique genres.
+func (a *LibraryMPDAdapter) ListGenres() ([]string, error) {
+	return a.client.ListGenres()
+}
+
+// FindAlbumsByGenre returns albums tagged with a specific genre.
+func (a *LibraryMPDAdapter) FindAlbumsByGenre(genre string) ([]library.AlbumInfo, error) {
+	albums, err := a.client.FindAlbumsByGenre(genre)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]library.AlbumInfo, len(albums))
+	for i, album := range albums {
+		result[i] = library.AlbumInfo{
+			Album:       album.Album,
+			AlbumArtist: album.AlbumArtist,
+		}
+	}
+	return result, nil
+}
+
 // FindAlbumTracks returns tracks for a specific album.
 func (a *LibraryMPDAdapter) FindAlbumTracks(album, albumArtist string) ([]map[string]string, error) {
 	tracks, err := a.client.FindAlbumTracks(album, albumArtist)
@@ -109,6 +136,41 @@ func (a *LibraryMPDAdapter) FindAlbumTracks(album, albumArtist string) ([]map[st
 	return result, nil
 }
 
+// Search returns the songs matching every tag and value pair in terms.
+func (a *LibraryMPDAdapter) Search(terms ...string) ([]map[string]string, error) {
+	songs, err := a.client.Search(terms...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]string, len(songs))
+	for i, song := range songs {
+		result[i] = make(map[string]string, len(song))
+		for k, v := range song {
+			result[i][k] = v
+		}
+	}
+	return result, nil
+}
+
+// ListInfo returns the contents of a directory.
+func (a *LibraryMPDAdapter) ListInfo(uri string) ([]map[string]string, error) {
+	entries, err := a.client.ListInfo(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert mpd.Attrs to map[string]string
+	result := make([]map[string]string, len(entries))
+	for i, entry := range entries {
+		result[i] = make(map[string]string)
+		for k, v := range entry {
+			result[i][k] = v
+		}
+	}
+	return result, nil
+}
+
 // ListPlaylists returns all saved playlists.
 func (a *LibraryMPDAdapter) ListPlaylists() ([]string, error) {
 	return a.client.ListPlaylists()
@@ -0,0 +1,38 @@
+package socketio
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// logLevels are the levels clients may select at runtime.
+var logLevels = map[string]zerolog.Level{
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+}
+
+// LogLevelResponse represents the current global log level.
+type LogLevelResponse struct {
+	Level   string `json:"level"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// GetLogLevel returns the current global log level.
+func GetLogLevel() string {
+	return zerolog.GlobalLevel().String()
+}
+
+// SetLogLevel changes the global log level without a restart.
+// Accepts "debug", "info", "warn" or "error".
+func SetLogLevel(level string) error {
+	lvl, ok := logLevels[level]
+	if !ok {
+		return fmt.Errorf("invalid log level %q: must be debug, info, warn or error", level)
+	}
+	zerolog.SetGlobalLevel(lvl)
+	return nil
+}
@@ -0,0 +1,31 @@
+package socketio
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	orig := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(orig) })
+
+	for _, level := range []string{"debug", "info", "warn", "error"} {
+		if err := SetLogLevel(level); err != nil {
+			t.Fatalf("SetLogLevel(%q) error = %v", level, err)
+		}
+		if got := GetLogLevel(); got != level {
+			t.Errorf("GetLogLevel() = %q after SetLogLevel(%q)", got, level)
+		}
+	}
+
+	// Levels outside the allowed set leave the current level alone
+	for _, level := range []string{"", "trace", "panic", "DEBUG"} {
+		if err := SetLogLevel(level); err == nil {
+			t.Errorf("SetLogLevel(%q) = nil, want error", level)
+		}
+	}
+	if got := GetLogLevel(); got != "error" {
+		t.Errorf("GetLogLevel() = %q after rejected changes, want error", got)
+	}
+}
@@ -0,0 +1,76 @@
+package socketio
+
+import (
+	"context"
+	"time"
+
+	"github.com/zishang520/socket.io/servers/socket/v3"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/lyrics"
+)
+
+// lyricsLookupTimeout bounds a single getLyrics request, including the
+// provider round trip on a cache miss.
+const lyricsLookupTimeout = 20 * time.Second
+
+// LyricsHandlers contains Socket.IO handlers for lyrics lookups.
+type LyricsHandlers struct {
+	lyricsService *lyrics.Service
+	server        *Server
+}
+
+// NewLyricsHandlers creates a new LyricsHandlers instance.
+func NewLyricsHandlers(lyricsService *lyrics.Service, server *Server) *LyricsHandlers {
+	return &LyricsHandlers{
+		lyricsService: lyricsService,
+		server:        server,
+	}
+}
+
+// RegisterHandlers registers all lyrics-related Socket.IO handlers.
+func (h *LyricsHandlers) RegisterHandlers(client *socket.Socket) {
+	h.server.on(client, "getLyrics", func(cmd *Command, args ...any) {
+		h.handleGetLyrics(cmd, args...)
+	})
+}
+
+// handleGetLyrics handles the getLyrics event. The payload may carry an
+// artist/title pair; otherwise the currently playing track is used.
+func (h *LyricsHandlers) handleGetLyrics(cmd *Command, args ...any) {
+	cmd.Log.Debug().Interface("args", args).Msg("Received getLyrics")
+
+	var artist, title string
+	if len(args) > 0 {
+		if payload, ok := args[0].(map[string]interface{}); ok {
+			artist = getString(payload, "artist")
+			title = getString(payload, "title")
+		}
+	}
+
+	if (artist == "" || title == "") && h.server.mpdClient != nil {
+		song, err := h.server.mpdClient.CurrentSong()
+		if err != nil {
+			cmd.Log.Debug().Err(err).Msg("Failed to get current song for lyrics")
+		} else {
+			artist = song["Artist"]
+			title = song["Title"]
+		}
+	}
+
+	// Lookups can wait on the network; don't block the socket's event loop
+	go func() {
+		ctx, cancel := context.WithTimeout(cmd.Ctx, lyricsLookupTimeout)
+		defer cancel()
+
+		resp := h.lyricsService.Get(ctx, artist, title)
+
+		cmd.Log.Debug().
+			Str("artist", resp.Artist).
+			Str("title", resp.Title).
+			Bool("found", resp.Found).
+			Bool("synced", resp.Synced).
+			Msg("Sending pushLyrics")
+
+		cmd.Emit("pushLyrics", resp)
+	}()
+}
@@ -2,53 +2,59 @@ package socketio
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/sources"
 	"github.com/rs/zerolog/log"
 )
 
-// StartMountWatcher periodically checks for unmounted NAS shares and attempts to remount them.
+// StartMountWatcher starts the sources service's NAS health watcher, which remounts
+// unmounted or stale shares every interval, and broadcasts mounted state changes.
 // Follows the same pattern as StartNetworkWatcher.
-func (s *Server) StartMountWatcher(ctx context.Context) {
+func (s *Server) StartMountWatcher(ctx context.Context, interval time.Duration) {
 	if s.sourcesService == nil {
 		log.Debug().Msg("Mount watcher not started: sources service not available")
 		return
 	}
 
-	go func() {
-		log.Info().Msg("Mount watcher started (60s interval)")
-		ticker := time.NewTicker(60 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Info().Msg("Mount watcher stopped")
-				return
-			case <-ticker.C:
-				unmounted := s.sourcesService.GetUnmountedShares()
-				if len(unmounted) == 0 {
-					continue
-				}
-
-				log.Info().Int("unmounted", len(unmounted)).Msg("Mount watcher detected unmounted shares")
-				mounted := s.sourcesService.RemountUnmountedShares()
-
-				if mounted > 0 {
-					log.Info().Int("remounted", mounted).Msg("Mount watcher remounted shares")
-
-					// Trigger MPD database update
-					if _, err := s.mpdClient.Update(""); err != nil {
-						log.Warn().Err(err).Msg("Mount watcher: MPD update failed")
-					}
-
-					// Broadcast updated share list to all clients
-					shares, err := s.sourcesService.ListNasShares()
-					if err == nil {
-						s.io.Emit("pushListNasShares", shares)
-					}
-				}
+	s.sourcesService.StartHealthWatcher(ctx, interval, s.handleNasHealthChanges)
+}
+
+// handleNasHealthChanges notifies clients about NAS shares whose mounted state changed.
+func (s *Server) handleNasHealthChanges(changes []sources.ShareHealthChange) {
+	remounted := 0
+	for _, change := range changes {
+		result := sources.SourceResult{Success: change.Mounted}
+		if change.Mounted {
+			if change.Remounted {
+				remounted++
 			}
+			result.Message = fmt.Sprintf("NAS share '%s' is available", change.Name)
+		} else {
+			result.Error = fmt.Sprintf("NAS share '%s' is unavailable: %s", change.Name, change.Error)
+		}
+
+		log.Info().
+			Str("id", change.ID).
+			Bool("mounted", change.Mounted).
+			Bool("remounted", change.Remounted).
+			Msg("Mount watcher detected NAS state change")
+		s.emitAll("pushNasShareResult", result)
+	}
+
+	if remounted > 0 {
+		log.Info().Int("remounted", remounted).Msg("Mount watcher remounted shares")
+
+		// Trigger MPD database update
+		if _, err := s.mpdClient.Update(""); err != nil {
+			log.Warn().Err(err).Msg("Mount watcher: MPD update failed")
 		}
-	}()
+	}
+
+	// Broadcast updated share list to all clients
+	shares, err := s.sourcesService.ListNasShares()
+	if err == nil {
+		s.emitAll("pushListNasShares", shares)
+	}
 }
@@ -0,0 +1,73 @@
+package socketio
+
+import "fmt"
+
+// MpdCapabilities lists the optional MPD features the backend relies on.
+type MpdCapabilities struct {
+	ReadPicture bool `json:"readPicture"` // Embedded album art (MPD 0.22+)
+	AlbumArt    bool `json:"albumArt"`    // Folder album art (MPD 0.21+)
+	Grouping    bool `json:"grouping"`    // "list ... group" support
+	AddedTag    bool `json:"addedTag"`    // "added" timestamps (MPD 0.24+)
+}
+
+// MpdInfo describes the running MPD, pushed on pushMpdInfo.
+type MpdInfo struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Capabilities    MpdCapabilities `json:"capabilities"`
+}
+
+// MpdStats is the /api/v1/stats response: MPD info plus database statistics.
+type MpdStats struct {
+	MpdInfo
+	Artists    int `json:"artists"`
+	Albums     int `json:"albums"`
+	Songs      int `json:"songs"`
+	Uptime     int `json:"uptime"`     // Seconds since MPD started
+	DbPlaytime int `json:"dbPlaytime"` // Seconds of music in the database
+	DbUpdate   int `json:"dbUpdate"`   // Unix time of the last database update
+	PlayTime   int `json:"playTime"`   // Seconds MPD has played
+}
+
+// GetMpdInfo returns the MPD protocol version and the capabilities detected
+// when the MPD connection was opened.
+func (s *Server) GetMpdInfo() (MpdInfo, error) {
+	// Reconnects if needed, which re-detects capabilities
+	if _, err := s.mpdClient.ProtocolVersion(); err != nil {
+		return MpdInfo{}, fmt.Errorf("MPD unavailable: %w", err)
+	}
+	flags, ok := s.mpdClient.Capabilities()
+	if !ok {
+		return MpdInfo{}, fmt.Errorf("MPD capabilities not detected")
+	}
+	return MpdInfo{
+		ProtocolVersion: flags.ProtocolVersion,
+		Capabilities: MpdCapabilities{
+			ReadPicture: flags.HasReadPicture,
+			AlbumArt:    flags.HasAlbumArt,
+			Grouping:    flags.HasGrouping,
+			AddedTag:    flags.HasAddedTag,
+		},
+	}, nil
+}
+
+// GetStats returns MPD info and database statistics.
+func (s *Server) GetStats() (MpdStats, error) {
+	info, err := s.GetMpdInfo()
+	if err != nil {
+		return MpdStats{}, err
+	}
+	db, err := s.mpdClient.GetDatabaseStats()
+	if err != nil {
+		return MpdStats{}, err
+	}
+	return MpdStats{
+		MpdInfo:    info,
+		Artists:    db.Artists,
+		Albums:     db.Albums,
+		Songs:      db.Songs,
+		Uptime:     db.Uptime,
+		DbPlaytime: db.DbPlaytime,
+		DbUpdate:   db.DbUpdate,
+		PlayTime:   db.PlayTime,
+	}, nil
+}
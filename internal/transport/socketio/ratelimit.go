@@ -0,0 +1,171 @@
+package socketio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zishang520/socket.io/servers/socket/v3"
+)
+
+// RateLimit is a token bucket for one event: a client may send Burst events
+// at once, and regains one every Interval.
+type RateLimit struct {
+	Interval time.Duration
+	Burst    int
+	Reply    string // Event the rate-limited response is sent on
+}
+
+// DefaultRateLimits covers the events that hit the network or external APIs.
+var DefaultRateLimits = map[string]RateLimit{
+	"discoverNasDevices": {Interval: 10 * time.Second, Burst: 1, Reply: "pushNasDevices"},
+	"browseNasShares":    {Interval: 2 * time.Second, Burst: 3, Reply: "pushBrowseNasShares"},
+	"qobuzSearch":        {Interval: time.Second, Burst: 3, Reply: "pushQobuzSearchResult"},
+	"connectWifi":        {Interval: 5 * time.Second, Burst: 1, Reply: "pushWifiConnect"},
+}
+
+// RateLimitedResponse is sent on the event's reply in place of a result.
+type RateLimitedResponse struct {
+	Error      string `json:"error"`
+	RetryAfter int    `json:"retryAfter"` // Seconds until the next attempt is allowed
+}
+
+// tokenBucket tracks one client's allowance for one event.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter applies per-client, per-event token buckets.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]map[string]*tokenBucket // clientID -> event -> bucket
+	now     func() time.Time
+}
+
+// NewRateLimiter creates a limiter enforcing limits, keyed by event name.
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: make(map[string]map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token for clientID's event. When none is left it returns
+// false and how long until one is.
+func (l *RateLimiter) Allow(clientID, event string) (bool, time.Duration) {
+	limit, ok := l.limits[event]
+	if !ok || limit.Interval <= 0 || limit.Burst <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	events := l.buckets[clientID]
+	if events == nil {
+		events = make(map[string]*tokenBucket)
+		l.buckets[clientID] = events
+	}
+	b := events[event]
+	if b == nil {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		events[event] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+float64(now.Sub(b.last))/float64(limit.Interval))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) * float64(limit.Interval))
+}
+
+// Remove forgets a disconnected client's buckets.
+func (l *RateLimiter) Remove(clientID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, clientID)
+}
+
+// SetRateLimits replaces the rate limits applied to new events.
+func (s *Server) SetRateLimits(limits map[string]RateLimit) {
+	s.rateLimiter = NewRateLimiter(limits)
+}
+
+// rateLimitGuard returns a socket middleware that drops events over their
+// rate limit, answering on the event's reply with a RateLimitedResponse.
+func (s *Server) rateLimitGuard(client *socket.Socket) func([]any, func(error)) {
+	clientID := string(client.Id())
+	return func(event []any, next func(error)) {
+		var name string
+		if len(event) > 0 {
+			name, _ = event[0].(string)
+		}
+
+		ok, retryAfter := s.rateLimiter.Allow(clientID, name)
+		if ok {
+			next(nil)
+			return
+		}
+
+		log.Warn().Str("id", clientID).Str("event", name).Dur("retryAfter", retryAfter).Msg("Rate limited")
+		if reply := s.rateLimiter.limits[name].Reply; reply != "" {
+			client.Emit(reply, RateLimitedResponse{
+				Error:      "rate limited",
+				RetryAfter: int(math.Ceil(retryAfter.Seconds())),
+			})
+		}
+	}
+}
+
+// ParseRateLimits applies overrides of the form
+// "event=interval[/burst],..." (e.g. "qobuzSearch=2s/5") to a copy of base.
+// Reply events of overridden limits are kept.
+func ParseRateLimits(spec string, base map[string]RateLimit) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit, len(base))
+	for event, limit := range base {
+		limits[event] = limit
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		event, value, ok := strings.Cut(entry, "=")
+		if !ok || event == "" {
+			return nil, fmt.Errorf("invalid rate limit %q: want event=interval[/burst]", entry)
+		}
+
+		intervalStr, burstStr, hasBurst := strings.Cut(value, "/")
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("invalid rate limit interval %q for %s", intervalStr, event)
+		}
+
+		limit := limits[event]
+		limit.Interval = interval
+		if limit.Burst == 0 {
+			limit.Burst = 1
+		}
+		if hasBurst {
+			burst, err := strconv.Atoi(burstStr)
+			if err != nil || burst < 1 {
+				return nil, fmt.Errorf("invalid rate limit burst %q for %s", burstStr, event)
+			}
+			limit.Burst = burst
+		}
+		limits[event] = limit
+	}
+	return limits, nil
+}
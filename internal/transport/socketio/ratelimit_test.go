@@ -0,0 +1,124 @@
+package socketio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(map[string]RateLimit{
+		"discoverNasDevices": {Interval: 10 * time.Second, Burst: 1},
+		"qobuzSearch":        {Interval: time.Second, Burst: 2},
+	})
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.Allow("a", "discoverNasDevices"); !ok {
+		t.Fatal("first discovery refused")
+	}
+	ok, retry := l.Allow("a", "discoverNasDevices")
+	if ok {
+		t.Fatal("second discovery allowed within the interval")
+	}
+	if retry != 10*time.Second {
+		t.Errorf("retryAfter = %v, want 10s", retry)
+	}
+
+	// Buckets are per client and per event
+	if ok, _ := l.Allow("b", "discoverNasDevices"); !ok {
+		t.Error("other client's discovery refused")
+	}
+	if ok, _ := l.Allow("a", "qobuzSearch"); !ok {
+		t.Error("unrelated event refused")
+	}
+
+	now = now.Add(4 * time.Second)
+	if ok, retry := l.Allow("a", "discoverNasDevices"); ok || retry != 6*time.Second {
+		t.Errorf("Allow after 4s = %v, %v; want false, 6s", ok, retry)
+	}
+
+	now = now.Add(6 * time.Second)
+	if ok, _ := l.Allow("a", "discoverNasDevices"); !ok {
+		t.Error("discovery refused after the interval elapsed")
+	}
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(map[string]RateLimit{"qobuzSearch": {Interval: time.Second, Burst: 3}})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a", "qobuzSearch"); !ok {
+			t.Fatalf("search %d refused within burst", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a", "qobuzSearch"); ok {
+		t.Error("search allowed beyond burst")
+	}
+
+	// A long pause refills only up to the burst
+	now = now.Add(time.Minute)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if ok, _ := l.Allow("a", "qobuzSearch"); ok {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("allowed %d after refill, want 3", allowed)
+	}
+}
+
+func TestRateLimiter_UnlimitedAndRemove(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(map[string]RateLimit{
+		"discoverNasDevices": {Interval: time.Hour, Burst: 1},
+		"browseNasShares":    {Interval: 0, Burst: 1},
+	})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.Allow("a", "getState"); !ok {
+			t.Fatal("unlimited event refused")
+		}
+		if ok, _ := l.Allow("a", "browseNasShares"); !ok {
+			t.Fatal("disabled limit refused")
+		}
+	}
+
+	l.Allow("a", "discoverNasDevices")
+	l.Remove("a")
+	if ok, _ := l.Allow("a", "discoverNasDevices"); !ok {
+		t.Error("limit survived Remove")
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("qobuzSearch=2s/5, discoverNasDevices=0,rescanDb=1m", DefaultRateLimits)
+	if err != nil {
+		t.Fatalf("ParseRateLimits error = %v", err)
+	}
+
+	if got := limits["qobuzSearch"]; got.Interval != 2*time.Second || got.Burst != 5 || got.Reply != "pushQobuzSearchResult" {
+		t.Errorf("qobuzSearch = %+v, want 2s/5 keeping its reply", got)
+	}
+	if got := limits["discoverNasDevices"]; got.Interval != 0 {
+		t.Errorf("discoverNasDevices interval = %v, want 0 (disabled)", got.Interval)
+	}
+	if got := limits["rescanDb"]; got.Interval != time.Minute || got.Burst != 1 {
+		t.Errorf("rescanDb = %+v, want 1m/1", got)
+	}
+	if got := limits["browseNasShares"]; got != DefaultRateLimits["browseNasShares"] {
+		t.Errorf("browseNasShares = %+v, want default", got)
+	}
+	if DefaultRateLimits["qobuzSearch"].Burst == 5 {
+		t.Error("ParseRateLimits modified the defaults")
+	}
+
+	for _, spec := range []string{"qobuzSearch", "=1s", "qobuzSearch=fast", "qobuzSearch=1s/0", "qobuzSearch=-1s"} {
+		if _, err := ParseRateLimits(spec, DefaultRateLimits); err == nil {
+			t.Errorf("ParseRateLimits(%q) = nil error, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package socketio
+
+import "fmt"
+
+// DatabaseUpdating is broadcast on pushDatabaseUpdating when an MPD database
+// update starts.
+type DatabaseUpdating struct {
+	JobID int    `json:"jobId"`
+	Path  string `json:"path,omitempty"` // Empty for a full rescan
+}
+
+// RescanShareResult is the response to rescanNasShare.
+type RescanShareResult struct {
+	Success bool   `json:"success"`
+	ShareID string `json:"shareId"`
+	Path    string `json:"path,omitempty"` // Rescanned path within the music directory
+	JobID   int    `json:"jobId,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// rescanNasShare starts an MPD database update limited to one NAS share's
+// directory, broadcasting pushDatabaseUpdating when it starts.
+func (s *Server) rescanNasShare(shareID string) RescanShareResult {
+	result := RescanShareResult{ShareID: shareID}
+	if s.sourcesService == nil {
+		result.Error = "sources service not available"
+		return result
+	}
+
+	path, err := s.sourcesService.NasShareMusicPath(shareID)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Path = path
+
+	jobID, err := s.mpdClient.Update(path)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to start rescan: %v", err)
+		return result
+	}
+
+	result.Success = true
+	result.JobID = jobID
+	s.emitAll("pushDatabaseUpdating", DatabaseUpdating{JobID: jobID, Path: path})
+	return result
+}
@@ -0,0 +1,69 @@
+package socketio
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/zishang520/socket.io/servers/socket/v3"
+)
+
+// revisionParam is the handshake auth or query key a reconnecting client
+// passes its last-seen revision in.
+const revisionParam = "revision"
+
+// ResumeAck is the pushResumed payload, sent on connect instead of
+// pushInitialState when the client already has the current revision.
+type ResumeAck struct {
+	Revision uint64 `json:"revision"`
+}
+
+// initialRevision starts revisions at the current time in milliseconds.
+// Broadcasts bump it far slower than once a millisecond, so a revision a
+// client kept from before a restart can't match one issued after it.
+func initialRevision() uint64 {
+	return uint64(time.Now().UnixMilli())
+}
+
+// nextRevision bumps the revision for a state or queue broadcast and
+// returns it.
+func (s *Server) nextRevision() uint64 {
+	return s.revision.Add(1)
+}
+
+// currentRevision returns the revision of the last state or queue broadcast.
+func (s *Server) currentRevision() uint64 {
+	return s.revision.Load()
+}
+
+// canResume reports whether a connecting client already has the current
+// state and queue, having seen the latest revision before reconnecting.
+func (s *Server) canResume(client *socket.Socket) bool {
+	last, ok := handshakeRevision(client.Handshake())
+	return ok && last == s.currentRevision()
+}
+
+// handshakeRevision returns the last-seen revision from the Socket.IO auth
+// payload or the connection query string, if the client sent one.
+func handshakeRevision(hs *socket.Handshake) (uint64, bool) {
+	if hs == nil {
+		return 0, false
+	}
+	switch v := hs.Auth[revisionParam].(type) {
+	case float64:
+		if v > 0 && v == float64(uint64(v)) {
+			return uint64(v), true
+		}
+	case string:
+		return parseRevision(v)
+	}
+	return parseRevision(firstValue(hs.Query[revisionParam]))
+}
+
+// parseRevision parses a revision sent as a string.
+func parseRevision(v string) (uint64, bool) {
+	revision, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || revision == 0 {
+		return 0, false
+	}
+	return revision, true
+}
@@ -0,0 +1,33 @@
+package socketio
+
+import (
+	"testing"
+
+	"github.com/zishang520/socket.io/servers/socket/v3"
+)
+
+func TestHandshakeRevision(t *testing.T) {
+	tests := []struct {
+		name   string
+		hs     *socket.Handshake
+		want   uint64
+		wantOK bool
+	}{
+		{"nil handshake", nil, 0, false},
+		{"none", &socket.Handshake{}, 0, false},
+		{"auth number", &socket.Handshake{Auth: map[string]any{"revision": float64(1700000000123)}}, 1700000000123, true},
+		{"auth string", &socket.Handshake{Auth: map[string]any{"revision": "42"}}, 42, true},
+		{"query string", &socket.Handshake{Query: map[string]any{"revision": []string{"7"}}}, 7, true},
+		{"fractional", &socket.Handshake{Auth: map[string]any{"revision": 1.5}}, 0, false},
+		{"zero", &socket.Handshake{Auth: map[string]any{"revision": float64(0)}}, 0, false},
+		{"garbage", &socket.Handshake{Query: map[string]any{"revision": "abc"}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := handshakeRevision(tt.hs)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("handshakeRevision() = %d, %v; want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
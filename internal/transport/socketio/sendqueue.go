@@ -0,0 +1,141 @@
+package socketio
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// clientSendBuffer is how many broadcasts can wait for a slow client before
+// further ones are dropped for it.
+const clientSendBuffer = 64
+
+// coalescedEvents carry a complete snapshot, so a newer one replaces any
+// still waiting to be sent rather than queueing behind it.
+var coalescedEvents = map[string]bool{
+	"pushState":            true,
+	"pushQueue":            true,
+	"pushAudioStatus":      true,
+	"pushNetworkStatus":    true,
+	"pushLastPlayedTracks": true,
+	"pushRevision":         true,
+}
+
+// pendingEmit is a broadcast waiting to be sent to a client.
+type pendingEmit struct {
+	event   string
+	payload interface{}
+}
+
+// clientSender delivers broadcasts to one client from its own goroutine, so
+// a client on a poor link can't hold up broadcasts to everyone else. Waiting
+// snapshots are coalesced to the latest; other events beyond the buffer are
+// dropped for that client.
+type clientSender struct {
+	id   string
+	emit func(event string, payload interface{})
+	wake chan struct{}
+
+	mu        sync.Mutex
+	queue     []pendingEmit
+	dropped   int // Events discarded because the buffer was full
+	coalesced int // Snapshots replaced by a newer one before being sent
+}
+
+// newClientSender creates a sender that passes broadcasts to emit. Call run
+// to start delivering them.
+func newClientSender(id string, emit func(event string, payload interface{})) *clientSender {
+	return &clientSender{
+		id:   id,
+		emit: emit,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Send queues a broadcast without blocking.
+func (c *clientSender) Send(event string, payload interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if coalescedEvents[event] {
+		for i := range c.queue {
+			if c.queue[i].event == event {
+				c.queue[i].payload = payload
+				c.coalesced++
+				return
+			}
+		}
+	}
+	if len(c.queue) >= clientSendBuffer {
+		c.dropped++
+		if c.dropped == 1 {
+			log.Warn().Str("id", c.id).Str("event", event).Msg("Client is not keeping up, dropping broadcasts")
+		}
+		return
+	}
+
+	c.queue = append(c.queue, pendingEmit{event: event, payload: payload})
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends queued broadcasts in order until ctx is cancelled, then logs how
+// many were dropped or coalesced.
+func (c *clientSender) run(ctx context.Context) {
+	defer c.logStats()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.wake:
+		}
+
+		for {
+			next, ok := c.next()
+			if !ok {
+				break
+			}
+			c.emit(next.event, next.payload)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// next removes and returns the oldest queued broadcast.
+func (c *clientSender) next() (pendingEmit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.queue) == 0 {
+		return pendingEmit{}, false
+	}
+	next := c.queue[0]
+	c.queue[0] = pendingEmit{}
+	c.queue = c.queue[1:]
+	return next, true
+}
+
+// Stats returns how many broadcasts were dropped and coalesced so far.
+func (c *clientSender) Stats() (dropped, coalesced int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped, c.coalesced
+}
+
+// logStats reports the client's dropped and coalesced broadcasts, if any.
+func (c *clientSender) logStats() {
+	dropped, coalesced := c.Stats()
+	if dropped == 0 && coalesced == 0 {
+		return
+	}
+	log.Info().
+		Str("id", c.id).
+		Int("dropped", dropped).
+		Int("coalesced", coalesced).
+		Msg("Client broadcast backlog")
+}
@@ -0,0 +1,109 @@
+package socketio
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingEmit collects emitted events and can be blocked to stand in for a
+// stalled client.
+type recordingEmit struct {
+	mu      sync.Mutex
+	events  []string
+	block   chan struct{} // Emits wait until closed; nil never blocks
+	started chan struct{} // Receives once per emit before blocking
+}
+
+func (r *recordingEmit) emit(event string, payload interface{}) {
+	if r.started != nil {
+		r.started <- struct{}{}
+	}
+	if r.block != nil {
+		<-r.block
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("%s:%v", event, payload))
+}
+
+func (r *recordingEmit) got() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func TestClientSender_DeliversInOrder(t *testing.T) {
+	rec := &recordingEmit{}
+	sender := newClientSender("c1", rec.emit)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sender.run(ctx)
+
+	sender.Send("pushToastMessage", 1)
+	sender.Send("pushState", 2)
+	sender.Send("pushQueue", 3)
+
+	want := []string{"pushToastMessage:1", "pushState:2", "pushQueue:3"}
+	waitForEvents(t, rec, len(want))
+	if got := rec.got(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("emitted %v, want %v", got, want)
+	}
+}
+
+func TestClientSender_CoalescesAndDropsForStalledClient(t *testing.T) {
+	rec := &recordingEmit{block: make(chan struct{}), started: make(chan struct{}, 1)}
+	sender := newClientSender("slow", rec.emit)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sender.run(ctx)
+
+	// The first event is taken and the client stalls on it
+	sender.Send("pushState", 0)
+	<-rec.started
+	rec.started = nil
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 10; i++ {
+			sender.Send("pushState", i)
+		}
+		for i := 0; i < clientSendBuffer+5; i++ {
+			sender.Send("pushToastMessage", i)
+		}
+		sender.Send("pushQueue", "latest")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Send blocked on a stalled client")
+	}
+
+	dropped, coalesced := sender.Stats()
+	// pushState 1 is queued and 2-10 replace it; the buffer then fills with
+	// toasts and the rest, including pushQueue, are dropped
+	if coalesced != 9 || dropped != 7 {
+		t.Errorf("Stats() = dropped %d, coalesced %d; want 7, 9", dropped, coalesced)
+	}
+
+	close(rec.block)
+	waitForEvents(t, rec, clientSendBuffer+1)
+	got := rec.got()
+	if got[0] != "pushState:0" || got[1] != "pushState:10" {
+		t.Errorf("emitted %v first, want pushState:0 then the latest pushState:10", got[:2])
+	}
+}
+
+func waitForEvents(t *testing.T, rec *recordingEmit, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(rec.got()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("emitted %d events, want %d", len(rec.got()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -4,11 +4,15 @@ package socketio
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -16,15 +20,23 @@ import (
 	"github.com/zishang520/socket.io/v3/pkg/types"
 
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/audio"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/auth"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/airplay"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/artwork"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/audirvana"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/device"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/external"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/library"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/localmusic"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/player"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/sources"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/streaming/qobuz"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/cache"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/dlna"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/lyrics"
 	mpdclient "github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/infra/mpd"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/system/hostname"
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/system/network"
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/version"
 )
 
@@ -34,6 +46,8 @@ type Server struct {
 	playerService       *player.Service
 	mpdClient           *mpdclient.Client
 	audioController     *audio.Controller
+	audioConfig         *AudioConfig
+	configWriter        ConfigWriter // Writes root-owned system files such as /etc/hostname
 	sourcesService      *sources.Service
 	qobuzService        *qobuz.Service
 	localMusicService   *localmusic.Service
@@ -42,17 +56,38 @@ type Server struct {
 	libraryHandlers     *LibraryHandlers
 	cacheHandlers       *CacheHandlers
 	enrichmentHandlers  *EnrichmentHandlers
+	lyricsHandlers      *LyricsHandlers
+	dlnaHandlers        *DlnaHandlers
+	httpPort            string // HTTP port, used to build URLs for other devices
+	events              *EventHub // SSE subscribers to broadcasts
 	cacheDB             *cache.DB
 	cacheDAO            *cache.DAO
 	audirvanaService    *audirvana.Service
+	airplayService      *airplay.Service
+	externalHandlers    *ExternalSourceHandlers
 	deviceService       *device.Service   // Volumio device identity
 	volumioHandlers     *VolumioHandlers  // Volumio Connect compatibility
 	connLimiter         *ConnectionLimiter // Limits concurrent external connections
+	exclusiveCounter    mpdclient.ClientCounter // Counts other MPD clients; nil unless exclusive mode
+	auth                *auth.Authenticator     // Optional token auth; nil leaves Socket.IO open
+	rateLimiter         *RateLimiter            // Per-client limits on expensive events
+	exclusiveMu         sync.Mutex
+	exclusive           exclusiveState
 	mu                  sync.RWMutex
 	clients             map[string]*socket.Socket
-	lastNetwork         NetworkStatus
+	senders             map[string]*clientSender // Per-client broadcast queues, guarded by mu
+	lastNetwork         network.Status
 	lastBroadcastMu     sync.Mutex
 	lastBroadcastState  map[string]interface{} // Last state sent via BroadcastState for diffing
+	revision            atomic.Uint64          // Bumped by each state and queue broadcast; see revision.go
+	historyThrottler    *BroadcastThrottler    // Limits pushLastPlayedTracks broadcasts
+	alarmStore          *player.AlarmStore     // Scheduled playback; nil disables alarm events
+	dataDir             string                 // Where user data lives; factory reset backs it up from here
+	artworkPrewarmer    *artwork.Prewarmer     // Fills the artwork cache after builds; nil when disabled
+	artPriority         *artwork.PriorityStore // Embedded or folder art first for /albumart; nil disables its events
+	hostnameListener    func(SystemInfo)       // Told about hostname changes, e.g. to update mDNS; may be nil
+	prewarmCtx          context.Context
+	prewarmCancel       context.CancelFunc
 }
 
 // NewServer creates a new Socket.io server.
@@ -80,7 +115,7 @@ func NewServer(playerService *player.Service, mpdClient *mpdclient.Client, sourc
 	}
 
 	// Initialize cache database
-	cacheDB := cache.NewDB(os.ExpandEnv("$HOME/stellar-backend/data/library.db"))
+	cacheDB := cache.NewDB(os.ExpandEnv("$HOME/stellar-backend/data/library.db")).WithOptions(cache.OptionsFromEnv())
 	if err := cacheDB.Open(); err != nil {
 		log.Warn().Err(err).Msg("Failed to open cache database, caching disabled")
 		cacheDB = nil
@@ -91,20 +126,20 @@ func NewServer(playerService *player.Service, mpdClient *mpdclient.Client, sourc
 	// Initialize library service with adapters (only if localMusicSvc is provided)
 	var librarySvc *library.Service
 	var cachedSvc *library.CachedService
-	var libraryHandlers *LibraryHandlers
 	if localMusicSvc != nil {
 		mpdAdapter := NewLibraryMPDAdapter(mpdClient)
 		classifierAdapter := NewLibraryClassifierAdapter(localMusicSvc.GetClassifier())
 		librarySvc = library.NewService(mpdAdapter, classifierAdapter)
 		cachedSvc = library.NewCachedService(mpdAdapter, classifierAdapter, cacheDB)
-		// Use CachedService for library handlers to enable caching and artwork resolution
-		libraryHandlers = NewLibraryHandlers(cachedSvc)
 	}
 
 	// Create cache DAO for enrichment
 	var cacheDAO *cache.DAO
 	if cacheDB != nil {
 		cacheDAO = cache.NewDAO(cacheDB)
+		if localMusicSvc != nil {
+			localMusicSvc.SetCache(cacheDB)
+		}
 	}
 
 	// Initialize device service for Volumio Connect app compatibility
@@ -119,26 +154,56 @@ func NewServer(playerService *player.Service, mpdClient *mpdclient.Client, sourc
 		playerService:     playerService,
 		mpdClient:         mpdClient,
 		audioController:   audio.NewController(bitPerfect),
+		audioConfig:       NewDefaultAudioConfig(),
+		configWriter:      NewSudoConfigWriter(),
 		sourcesService:    sourcesService,
 		qobuzService:      qobuzSvc,
 		localMusicService: localMusicSvc,
 		libraryService:    librarySvc,
 		cachedService:     cachedSvc,
-		libraryHandlers:   libraryHandlers,
 		cacheDB:           cacheDB,
 		cacheDAO:          cacheDAO,
 		audirvanaService:  audirvana.NewService(),
+		airplayService:    airplay.NewService(),
 		deviceService:     deviceSvc,
 		connLimiter:       NewConnectionLimiter(1), // 1 external + unlimited local
+		rateLimiter:       NewRateLimiter(DefaultRateLimits),
 		clients:           make(map[string]*socket.Socket),
+		senders:           make(map[string]*clientSender),
+		events:            NewEventHub(),
+	}
+
+	s.revision.Store(initialRevision())
+
+	// Broadcast play history at most every 2s so rapid track changes don't flood clients
+	s.historyThrottler = NewBroadcastThrottler(2*time.Second, s.BroadcastLastPlayedTracks)
+
+	// Now-playing art: the /albumart endpoint for library files, the
+	// streaming service's art for streaming tracks
+	if playerService != nil && localMusicSvc != nil {
+		var streamingArt player.StreamingArtFunc
+		if qobuzSvc != nil {
+			streamingArt = func(uri string) string {
+				if strings.HasPrefix(uri, "qobuz://") {
+					return qobuzSvc.AlbumArtURL(uri)
+				}
+				return ""
+			}
+		}
+		playerService.SetAlbumArtSources(localMusicSvc.GetClassifier(), streamingArt)
 	}
 
 	// Initialize Volumio handlers (must be after s is created)
 	s.volumioHandlers = NewVolumioHandlers(deviceSvc, playerService, s)
 
-	// Initialize cache handlers if cached service is available
+	// Initialize library and cache handlers if cached service is available.
+	// Use CachedService for library handlers to enable caching and artwork resolution.
 	if cachedSvc != nil {
+		s.libraryHandlers = NewLibraryHandlers(cachedSvc, s)
 		s.cacheHandlers = NewCacheHandlers(cachedSvc, s)
+		cachedSvc.SetBuildProgressFunc(func(progress cache.BuildProgress) {
+			s.emitAll("pushCacheProgress", progress)
+		})
 	}
 
 	// Initialize enrichment handlers if cache is available
@@ -150,13 +215,71 @@ func NewServer(playerService *player.Service, mpdClient *mpdclient.Client, sourc
 		}, s)
 	}
 
+	// Initialize lyrics handlers (LRCLIB lookups cached on disk)
+	lyricsSvc := lyrics.NewService(lyrics.NewLrclibClient(), os.ExpandEnv("$HOME/stellar-backend/data/lyrics"))
+	s.lyricsHandlers = NewLyricsHandlers(lyricsSvc, s)
+
+	// Register alternate audio daemons behind one external source interface
+	s.externalHandlers = NewExternalSourceHandlers(external.NewRegistry(
+		s.audirvanaService,
+		s.airplayService,
+		external.NewRoonBridge(),
+		external.NewSqueezelite(),
+	), s)
+
+	// Initialize DLNA handlers (casting to UPnP renderers is opt-in per track)
+	s.dlnaHandlers = NewDlnaHandlers(dlna.NewService(), s)
+
+	// Broadcast sleep timer changes so every client can count down
+	playerService.SetSleepTimerListener(func(status player.SleepTimerStatus) {
+		s.emitAll("pushSleepTimer", status)
+	})
+
+	s.syncDsdMode()
 	s.setupHandlers()
 
 	return s, nil
 }
 
+// SetAudioConfig replaces the AudioConfig used by the audio settings handlers.
+// This allows non-systemd hosts to supply their own MPD restart command.
+func (s *Server) SetAudioConfig(cfg *AudioConfig) {
+	s.audioConfig = cfg
+	s.syncDsdMode()
+}
+
+// SetHTTPPort sets the HTTP port other devices use to fetch streams and art.
+func (s *Server) SetHTTPPort(port string) {
+	s.httpPort = port
+}
+
+// SetAlarmStore enables the alarm events backed by store.
+func (s *Server) SetAlarmStore(store *player.AlarmStore) {
+	s.alarmStore = store
+}
+
+// SetArtPriority enables the album art priority events backed by store.
+func (s *Server) SetArtPriority(store *artwork.PriorityStore) {
+	s.artPriority = store
+}
+
+// SetHostnameListener registers fn to be called with the new system info
+// after setHostname changes the hostname.
+func (s *Server) SetHostnameListener(fn func(SystemInfo)) {
+	s.hostnameListener = fn
+}
+
+// SetDataDir sets the directory holding sources, favorites, history and
+// alarms. Factory reset refuses to run until it is set, since it backs the
+// files up from there first.
+func (s *Server) SetDataDir(dir string) {
+	s.dataDir = dir
+}
+
 // setupHandlers registers all Socket.io event handlers.
 func (s *Server) setupHandlers() {
+	s.io.Use(s.authMiddleware)
+
 	s.io.On("connection", func(clients ...any) {
 		client := clients[0].(*socket.Socket)
 		clientID := string(client.Id())
@@ -188,25 +311,26 @@ func (s *Server) setupHandlers() {
 				oldClient.Disconnect(true)
 				s.mu.Lock()
 				delete(s.clients, evictedID)
+				delete(s.senders, evictedID)
 				s.mu.Unlock()
 			}
 		}
 
+		// Broadcasts reach the client through its own queue so a slow link
+		// doesn't stall them for everyone
+		sender := newClientSender(clientID, func(event string, payload interface{}) {
+			client.Emit(event, payload)
+		})
+		go sender.run(sessionOf(client).ctx)
+
 		s.mu.Lock()
 		s.clients[clientID] = client
+		s.senders[clientID] = sender
 		s.mu.Unlock()
 
-		// Send initial state after small delay
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			s.pushState(client)
-			s.pushQueue(client)
-			// Also send network, LCD, system info, and audio status
-			client.Emit("pushNetworkStatus", GetNetworkStatus())
-			client.Emit("pushSystemInfo", GetSystemInfo())
-			client.Emit("pushLcdStatus", GetLCDStatus())
-			client.Emit("pushAudioStatus", s.audioController.GetStatus())
-		}()
+		// Drop events the client's role doesn't allow (guests are read-only)
+		client.Use(s.eventGuard(client))
+		client.Use(s.rateLimitGuard(client))
 
 		// Handle disconnect
 		client.On("disconnect", func(args ...any) {
@@ -219,8 +343,12 @@ func (s *Server) setupHandlers() {
 			log.Info().Str("id", clientID).Str("reason", reason).Msg("Client disconnected")
 
 			s.connLimiter.Remove(clientID)
+			s.rateLimiter.Remove(clientID)
+			// Abort discovery and other work still running for this client
+			sessionOf(client).cancel()
 			s.mu.Lock()
 			delete(s.clients, clientID)
+			delete(s.senders, clientID)
 			s.mu.Unlock()
 		})
 
@@ -239,150 +367,441 @@ func (s *Server) setupHandlers() {
 			s.enrichmentHandlers.RegisterHandlers(client)
 		}
 
+		// Register lyrics handlers
+		if s.lyricsHandlers != nil {
+			s.lyricsHandlers.RegisterHandlers(client)
+		}
+
+		// Register external source handlers
+		if s.externalHandlers != nil {
+			s.externalHandlers.RegisterHandlers(client)
+		}
+
+		// Register DLNA handlers
+		if s.dlnaHandlers != nil {
+			s.dlnaHandlers.RegisterHandlers(client)
+		}
+
 		// Register Volumio Connect compatibility handlers
 		if s.volumioHandlers != nil {
 			s.volumioHandlers.RegisterHandlers(client)
 		}
 
 		// Player control events
-		client.On("getState", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getState")
+		s.on(client, "getState", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getState")
 			s.pushState(client)
 		})
 
-		client.On("play", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("data", args).Msg("play")
+		// Transport controls share argument parsing with the REST API
+		for _, name := range player.Commands {
+			name := name
+			s.on(client, name, func(cmd *Command, args ...any) {
+				cmd.Log.Debug().Interface("data", args).Msg(name)
+
+				value, hasValue := 0, false
+				if len(args) > 0 {
+					value, hasValue = player.ParseCommandValue(args[0])
+				}
+
+				if err := s.playerService.ExecuteCommand(name, value, hasValue); err != nil {
+					cmd.Log.Error().Err(err).Str("command", name).Msg("Transport command failed")
+					if name == player.CommandPlay {
+						cmd.Emit("pushToastMessage", map[string]interface{}{
+							"type":    "error",
+							"title":   "Playback Failed",
+							"message": audio.ExplainPlaybackError(err.Error(), audio.FindDeviceOwners()),
+						})
+					}
+				}
+			})
+		}
+
+		s.on(client, "mute", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("mute")
+			// TODO: Implement mute tracking (MPD doesn't have native mute)
+		})
+
+		s.on(client, "setRandom", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("setRandom")
+			if len(args) > 0 {
+				if m, ok := args[0].(map[string]interface{}); ok {
+					if v, ok := m["value"].(bool); ok {
+						if err := s.playerService.SetRandom(v); err != nil {
+							cmd.Log.Error().Err(err).Msg("SetRandom failed")
+						}
+					}
+				}
+			}
+		})
 
-			pos := -1 // Default: resume
+		s.on(client, "setRepeat", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("setRepeat")
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
-					if v, ok := m["value"].(float64); ok {
-						pos = int(v)
+					repeat, _ := m["value"].(bool)
+					single, _ := m["repeatSingle"].(bool)
+					if err := s.playerService.SetRepeat(repeat, single); err != nil {
+						cmd.Log.Error().Err(err).Msg("SetRepeat failed")
 					}
 				}
 			}
+		})
+
+		// Sleep timer events; changes are broadcast as pushSleepTimer
+		s.on(client, "getSleepTimer", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getSleepTimer")
+			cmd.Emit("pushSleepTimer", s.playerService.SleepTimer())
+		})
+
+		s.on(client, "setSleepTimer", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("data", args).Msg("setSleepTimer")
+			minutes := 0 // Rejected by SetSleepTimer
+			if len(args) > 0 {
+				if m, ok := args[0].(map[string]interface{}); ok {
+					minutes, _ = player.ParseCommandValue(m["minutes"])
+				} else {
+					minutes, _ = player.ParseCommandValue(args[0])
+				}
+			}
+			if _, err := s.playerService.SetSleepTimer(minutes); err != nil {
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Sleep Timer",
+					"message": err.Error(),
+				})
+			}
+		})
+
+		s.on(client, "cancelSleepTimer", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("cancelSleepTimer")
+			s.playerService.CancelSleepTimer()
+		})
+
+		s.on(client, "getMpdInfo", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getMpdInfo")
+			info, err := s.GetMpdInfo()
+			if err != nil {
+				cmd.Log.Warn().Err(err).Msg("getMpdInfo failed")
+			}
+			cmd.Emit("pushMpdInfo", info)
+		})
+
+		// Fade settings; changes are broadcast as pushFadeSettings
+		s.on(client, "getFadeSettings", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getFadeSettings")
+			cmd.Emit("pushFadeSettings", s.playerService.FadeSettings())
+		})
+
+		s.on(client, "setFadeSettings", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("data", args).Msg("setFadeSettings")
+			var data map[string]interface{}
+			if len(args) > 0 {
+				data, _ = args[0].(map[string]interface{})
+			}
+			// Fields left out keep their current value
+			settings := s.playerService.FadeSettings()
+			if enabled, ok := data["enabled"].(bool); ok {
+				settings.Enabled = enabled
+			}
+			settings.FadeInMs = getIntFromMap(data, "fadeInMs", settings.FadeInMs)
+			settings.FadeOutMs = getIntFromMap(data, "fadeOutMs", settings.FadeOutMs)
 
-			if err := s.playerService.Play(pos); err != nil {
-				log.Error().Err(err).Msg("Play failed")
+			if err := s.playerService.SetFadeSettings(settings); err != nil {
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Fade Settings",
+					"message": err.Error(),
+				})
+				return
 			}
+			s.emitAll("pushFadeSettings", settings)
 		})
 
-		client.On("pause", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("pause")
-			if err := s.playerService.Pause(); err != nil {
-				log.Error().Err(err).Msg("Pause failed")
+		// Album art priority: "embedded" or "folder" art first; changes are broadcast
+		s.on(client, "getAlbumArtPriority", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getAlbumArtPriority")
+			if s.artPriority == nil {
+				return
 			}
+			cmd.Emit("pushAlbumArtPriority", map[string]interface{}{"priority": s.artPriority.Get()})
 		})
 
-		client.On("stop", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("stop")
-			if err := s.playerService.Stop(); err != nil {
-				log.Error().Err(err).Msg("Stop failed")
+		s.on(client, "setAlbumArtPriority", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("data", args).Msg("setAlbumArtPriority")
+			if s.artPriority == nil {
+				return
+			}
+			var priority string
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					priority, _ = data["priority"].(string)
+				}
+			}
+			if err := s.artPriority.Set(artwork.Priority(priority)); err != nil {
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Album Art",
+					"message": err.Error(),
+				})
+				return
 			}
+			s.emitAll("pushAlbumArtPriority", map[string]interface{}{"priority": s.artPriority.Get()})
 		})
 
-		client.On("next", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("next")
-			if err := s.playerService.Next(); err != nil {
-				log.Error().Err(err).Msg("Next failed")
+		// Alarm events; changes are broadcast as pushAlarms
+		s.on(client, "listAlarms", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("listAlarms")
+			if s.alarmStore == nil {
+				cmd.Emit("pushAlarms", []player.Alarm{})
+				return
 			}
+			cmd.Emit("pushAlarms", s.alarmStore.List())
 		})
 
-		client.On("prev", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("prev")
-			if err := s.playerService.Previous(); err != nil {
-				log.Error().Err(err).Msg("Previous failed")
+		s.on(client, "addAlarm", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("data", args).Msg("addAlarm")
+			if s.alarmStore == nil {
+				return
+			}
+			var data map[string]interface{}
+			if len(args) > 0 {
+				data, _ = args[0].(map[string]interface{})
 			}
+			if _, err := s.alarmStore.Add(parseAlarm(data)); err != nil {
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Alarm",
+					"message": err.Error(),
+				})
+				return
+			}
+			s.emitAll("pushAlarms", s.alarmStore.List())
 		})
 
-		client.On("seek", func(args ...any) {
+		s.on(client, "removeAlarm", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("data", args).Msg("removeAlarm")
+			if s.alarmStore == nil {
+				return
+			}
+			var id string
 			if len(args) > 0 {
-				if pos, ok := args[0].(float64); ok {
-					log.Debug().Str("id", clientID).Float64("pos", pos).Msg("seek")
-					if err := s.playerService.Seek(int(pos)); err != nil {
-						log.Error().Err(err).Msg("Seek failed")
-					}
+				if data, ok := args[0].(map[string]interface{}); ok {
+					id = getString(data, "id")
+				} else if v, ok := args[0].(string); ok {
+					id = v
 				}
 			}
+			if err := s.alarmStore.Remove(id); err != nil {
+				cmd.Log.Warn().Err(err).Msg("removeAlarm failed")
+				return
+			}
+			s.emitAll("pushAlarms", s.alarmStore.List())
+		})
+
+		// Queue events
+		s.on(client, "getQueue", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getQueue")
+			s.pushQueue(client)
 		})
 
-		client.On("volume", func(args ...any) {
+		// Search the queue server-side so long queues needn't be shipped whole
+		s.on(client, "searchQueue", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("searchQueue")
+			var query string
 			if len(args) > 0 {
-				if vol, ok := args[0].(float64); ok {
-					log.Debug().Str("id", clientID).Float64("vol", vol).Msg("volume")
-					if err := s.playerService.SetVolume(int(vol)); err != nil {
-						log.Error().Err(err).Msg("SetVolume failed")
-					}
+				if data, ok := args[0].(map[string]interface{}); ok {
+					query = getString(data, "query")
+				} else if q, ok := args[0].(string); ok {
+					query = q
 				}
 			}
+			results, err := s.playerService.SearchQueue(query)
+			if err != nil {
+				cmd.Log.Error().Err(err).Msg("SearchQueue failed")
+				results = []map[string]interface{}{}
+			}
+			cmd.Emit("pushSearchQueue", map[string]interface{}{
+				"query":   query,
+				"results": results,
+			})
 		})
 
-		client.On("mute", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("data", args).Msg("mute")
-			// TODO: Implement mute tracking (MPD doesn't have native mute)
+		s.on(client, "clearQueue", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("clearQueue")
+			if err := s.playerService.ClearQueue(); err != nil {
+				cmd.Log.Error().Err(err).Msg("ClearQueue failed")
+			}
 		})
 
-		client.On("setRandom", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("data", args).Msg("setRandom")
+		s.on(client, "addToQueue", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("addToQueue")
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
-					if v, ok := m["value"].(bool); ok {
-						if err := s.playerService.SetRandom(v); err != nil {
-							log.Error().Err(err).Msg("SetRandom failed")
+					if uri, ok := m["uri"].(string); ok {
+						if err := s.playerService.AddToQueue(uri); err != nil {
+							cmd.Log.Error().Err(err).Msg("AddToQueue failed")
+							if errors.Is(err, player.ErrQueueFull) {
+								cmd.Emit("pushToastMessage", map[string]interface{}{
+									"type":    "error",
+									"title":   "Queue Full",
+									"message": err.Error(),
+								})
+							}
 						}
 					}
 				}
 			}
 		})
 
-		client.On("setRepeat", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("data", args).Msg("setRepeat")
+		// Folder playback events - queue every audio file under a directory
+		s.on(client, "playFolder", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("playFolder")
+			var uri string
+			replace := true
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
-					repeat, _ := m["value"].(bool)
-					single, _ := m["repeatSingle"].(bool)
-					if err := s.playerService.SetRepeat(repeat, single); err != nil {
-						log.Error().Err(err).Msg("SetRepeat failed")
+					uri = getString(m, "uri")
+					if clear, ok := m["clear"].(bool); ok {
+						replace = clear
 					}
 				}
 			}
+
+			songs, err := s.playerService.PlayFolder(uri, replace)
+			if err != nil {
+				cmd.Log.Error().Err(err).Str("uri", uri).Msg("PlayFolder failed")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Play Folder Failed",
+					"message": err.Error(),
+				})
+				return
+			}
+
+			// Record the first track for local sources
+			first := songs[0]
+			if s.localMusicService != nil && s.localMusicService.IsLocalSource(first["file"]) {
+				s.localMusicService.RecordTrackPlay(first["file"], first["Title"], first["Artist"], first["Album"],
+					s.playerService.AlbumArtURL(first["file"]), localmusic.PlayOriginFolderContext)
+				s.historyThrottler.Trigger()
+			}
 		})
 
-		// Queue events
-		client.On("getQueue", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getQueue")
-			s.pushQueue(client)
+		s.on(client, "addFolder", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("addFolder")
+			var uri string
+			if len(args) > 0 {
+				if m, ok := args[0].(map[string]interface{}); ok {
+					uri = getString(m, "uri")
+				}
+			}
+
+			songs, err := s.playerService.AddFolder(uri)
+			if err != nil {
+				cmd.Log.Error().Err(err).Str("uri", uri).Msg("AddFolder failed")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Add Folder Failed",
+					"message": err.Error(),
+				})
+				return
+			}
+
+			cmd.Emit("pushToastMessage", map[string]interface{}{
+				"type":    "success",
+				"title":   "Added to Queue",
+				"message": fmt.Sprintf("%d tracks added to queue", len(songs)),
+			})
 		})
 
-		client.On("clearQueue", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("clearQueue")
-			if err := s.playerService.ClearQueue(); err != nil {
-				log.Error().Err(err).Msg("ClearQueue failed")
+		s.on(client, "getArtworkProgress", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getArtworkProgress")
+			var progress artwork.PrewarmProgress
+			if s.artworkPrewarmer != nil {
+				progress = s.artworkPrewarmer.Progress()
+			}
+			cmd.Emit("pushArtworkProgress", progress)
+		})
+
+		// Shuffle the queue order once; random playback mode is setRandom
+		s.on(client, "shuffleQueue", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("shuffleQueue")
+			if err := s.playerService.ShuffleQueue(); err != nil {
+				cmd.Log.Error().Err(err).Msg("ShuffleQueue failed")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Shuffle Failed",
+					"message": err.Error(),
+				})
+				return
 			}
+			s.BroadcastQueue()
 		})
 
-		client.On("addToQueue", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("data", args).Msg("addToQueue")
+		// Bump a track in random-mode ordering without moving it in the queue
+		s.on(client, "setQueuePriority", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("setQueuePriority")
+			pos, prio := -1, -1
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
-					if uri, ok := m["uri"].(string); ok {
-						if err := s.playerService.AddToQueue(uri); err != nil {
-							log.Error().Err(err).Msg("AddToQueue failed")
-						}
+					pos = getIntFromMap(m, "position", -1)
+					prio = getIntFromMap(m, "priority", -1)
+				}
+			}
+
+			if err := s.playerService.SetQueuePriority(pos, prio); err != nil {
+				cmd.Log.Error().Err(err).Int("position", pos).Int("priority", prio).Msg("SetQueuePriority failed")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Set Priority Failed",
+					"message": err.Error(),
+				})
+				return
+			}
+			s.BroadcastQueue()
+		})
+
+		s.on(client, "addAlbumShuffled", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("addAlbumShuffled")
+			var uri string
+			if len(args) > 0 {
+				if m, ok := args[0].(map[string]interface{}); ok {
+					uri = getString(m, "uri")
+					if uri == "" {
+						uri = getString(m, "albumUri")
 					}
 				}
 			}
+
+			songs, err := s.playerService.AddAlbumShuffled(uri)
+			if err != nil {
+				cmd.Log.Error().Err(err).Str("uri", uri).Msg("AddAlbumShuffled failed")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
+					"type":    "error",
+					"title":   "Add Album Failed",
+					"message": err.Error(),
+				})
+				return
+			}
+
+			s.BroadcastQueue()
+			cmd.Emit("pushToastMessage", map[string]interface{}{
+				"type":    "success",
+				"title":   "Added to Queue",
+				"message": fmt.Sprintf("%d tracks added to queue in shuffled order", len(songs)),
+			})
 		})
 
 		// Browse events
-		client.On("getBrowseSources", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getBrowseSources")
+		s.on(client, "getBrowseSources", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getBrowseSources")
 			sources := s.getBrowseSources()
-			client.Emit("pushBrowseSources", sources)
+			cmd.Emit("pushBrowseSources", sources)
 		})
 
-		client.On("browseLibrary", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("data", args).Msg("browseLibrary")
+		s.on(client, "browseLibrary", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("browseLibrary")
 
 			uri := ""
 			if len(args) > 0 {
@@ -396,7 +815,7 @@ func (s *Server) setupHandlers() {
 			// Handle Qobuz URIs
 			if strings.HasPrefix(uri, "qobuz://") {
 				if s.qobuzService == nil {
-					client.Emit("pushBrowseLibrary", map[string]interface{}{
+					cmd.Emit("pushBrowseLibrary", map[string]interface{}{
 						"navigation": map[string]interface{}{
 							"lists": []interface{}{},
 						},
@@ -406,7 +825,7 @@ func (s *Server) setupHandlers() {
 				}
 
 				if !s.qobuzService.IsLoggedIn() {
-					client.Emit("pushBrowseLibrary", map[string]interface{}{
+					cmd.Emit("pushBrowseLibrary", map[string]interface{}{
 						"navigation": map[string]interface{}{
 							"lists": []interface{}{},
 						},
@@ -417,8 +836,8 @@ func (s *Server) setupHandlers() {
 
 				result, err := s.qobuzService.HandleBrowseURI(uri)
 				if err != nil {
-					log.Error().Err(err).Str("uri", uri).Msg("Qobuz browse failed")
-					client.Emit("pushBrowseLibrary", map[string]interface{}{
+					cmd.Log.Error().Err(err).Str("uri", uri).Msg("Qobuz browse failed")
+					cmd.Emit("pushBrowseLibrary", map[string]interface{}{
 						"navigation": map[string]interface{}{
 							"lists": []interface{}{},
 						},
@@ -427,33 +846,34 @@ func (s *Server) setupHandlers() {
 					return
 				}
 
-				client.Emit("pushBrowseLibrary", result)
+				cmd.Emit("pushBrowseLibrary", result)
 				return
 			}
 
 			// Handle local library URIs
 			result, err := s.playerService.BrowseLibrary(uri)
 			if err != nil {
-				log.Error().Err(err).Str("uri", uri).Msg("BrowseLibrary failed")
-				client.Emit("pushBrowseLibrary", map[string]interface{}{
+				cmd.Log.Error().Err(err).Str("uri", uri).Msg("BrowseLibrary failed")
+				cmd.Emit("pushBrowseLibrary", map[string]interface{}{
 					"navigation": map[string]interface{}{
 						"lists": []interface{}{},
 					},
 				})
 				return
 			}
-			client.Emit("pushBrowseLibrary", result)
+			cmd.Emit("pushBrowseLibrary", result)
 		})
 
-		client.On("replaceAndPlay", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("data", args).Msg("replaceAndPlay")
+		s.on(client, "replaceAndPlay", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("data", args).Msg("replaceAndPlay")
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
 					if uri, ok := m["uri"].(string); ok {
 						if err := s.playerService.ReplaceAndPlay(uri); err != nil {
-							log.Error().Err(err).Msg("ReplaceAndPlay failed")
+							cmd.Log.Error().Err(err).Msg("ReplaceAndPlay failed")
 							return
 						}
+						cmd.Log.Debug().Str("uri", uri).Msg("Queue replaced and playback started")
 
 						// Record play history for local sources
 						if s.localMusicService != nil && s.localMusicService.IsLocalSource(uri) {
@@ -464,6 +884,9 @@ func (s *Server) setupHandlers() {
 							if albumArt == "" {
 								albumArt = getString(m, "albumArt")
 							}
+							if albumArt == "" {
+								albumArt = s.playerService.AlbumArtURL(uri)
+							}
 
 							// Determine play origin - default to manual track
 							origin := localmusic.PlayOriginManualTrack
@@ -477,6 +900,8 @@ func (s *Server) setupHandlers() {
 							}
 
 							s.localMusicService.RecordTrackPlay(uri, title, artist, album, albumArt, origin)
+							cmd.Log.Debug().Str("uri", uri).Str("origin", string(origin)).Msg("Play history recorded")
+							s.historyThrottler.Trigger()
 						}
 					}
 				}
@@ -484,110 +909,252 @@ func (s *Server) setupHandlers() {
 		})
 
 		// Network status events
-		client.On("getNetworkStatus", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getNetworkStatus")
-			status := GetNetworkStatus()
-			client.Emit("pushNetworkStatus", status)
+		s.on(client, "getNetworkStatus", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getNetworkStatus")
+			status := network.GetStatus()
+			cmd.Emit("pushNetworkStatus", status)
+		})
+
+		s.on(client, "scanWifiNetworks", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("scanWifiNetworks")
+			networks, err := ScanWifiNetworks(cmd.Ctx)
+			if err != nil {
+				cmd.Log.Warn().Err(err).Msg("Wi-Fi scan failed")
+				cmd.Emit("pushWifiNetworks", WifiScanResponse{Networks: []WifiNetwork{}, Error: err.Error()})
+				return
+			}
+			cmd.Emit("pushWifiNetworks", WifiScanResponse{Networks: networks})
+		})
+
+		s.on(client, "connectWifi", func(cmd *Command, args ...any) {
+			var ssid, password string
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					ssid = getString(data, "ssid")
+					password = getString(data, "password")
+				}
+			}
+			// The password is never logged
+			cmd.Log.Info().Str("ssid", ssid).Bool("open", password == "").Msg("connectWifi")
+
+			if err := ConnectWifi(cmd.Ctx, ssid, password); err != nil {
+				cmd.Log.Warn().Err(err).Str("ssid", ssid).Msg("Wi-Fi connect failed")
+				cmd.Emit("pushWifiConnect", WifiConnectResponse{SSID: ssid, Error: err.Error()})
+				return
+			}
+			cmd.Log.Info().Str("ssid", ssid).Msg("Connected to Wi-Fi")
+			cmd.Emit("pushWifiConnect", WifiConnectResponse{Success: true, SSID: ssid})
+			s.BroadcastNetworkStatus()
 		})
 
 		// LCD control events
-		client.On("getLcdStatus", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getLcdStatus")
+		s.on(client, "getLcdStatus", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getLcdStatus")
 			status := GetLCDStatus()
-			client.Emit("pushLcdStatus", status)
+			cmd.Emit("pushLcdStatus", status)
 		})
 
-		client.On("lcdStandby", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("lcdStandby")
+		s.on(client, "lcdStandby", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("lcdStandby")
 			if err := SetLCDPower(false); err != nil {
-				log.Error().Err(err).Msg("lcdStandby failed")
+				cmd.Log.Error().Err(err).Msg("lcdStandby failed")
 				return
 			}
 			s.BroadcastLCDStatus()
 		})
 
-		client.On("lcdWake", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("lcdWake")
+		s.on(client, "lcdWake", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("lcdWake")
 			if err := SetLCDPower(true); err != nil {
-				log.Error().Err(err).Msg("lcdWake failed")
+				cmd.Log.Error().Err(err).Msg("lcdWake failed")
 				return
 			}
 			s.BroadcastLCDStatus()
 		})
 
 		// Audio status events
-		client.On("getAudioStatus", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getAudioStatus")
+		s.on(client, "getAudioStatus", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getAudioStatus")
 			status := s.audioController.GetStatus()
-			client.Emit("pushAudioStatus", status)
+			cmd.Emit("pushAudioStatus", status)
 		})
 
 		// Version info event
-		client.On("getVersion", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getVersion")
-			client.Emit("pushVersion", version.GetInfo())
+		s.on(client, "getVersion", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getVersion")
+			cmd.Emit("pushVersion", version.GetInfo())
 		})
 
 		// System info event
-		client.On("getSystemInfo", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getSystemInfo")
-			client.Emit("pushSystemInfo", GetSystemInfo())
+		s.on(client, "getSystemInfo", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getSystemInfo")
+			cmd.Emit("pushSystemInfo", GetSystemInfo())
+		})
+
+		s.on(client, "setHostname", func(cmd *Command, args ...any) {
+			var name string
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					name = getString(data, "hostname")
+				}
+			}
+			cmd.Log.Info().Str("hostname", name).Msg("setHostname")
+
+			if err := hostname.Set(s.configWriter, name); err != nil {
+				cmd.Log.Error().Err(err).Str("hostname", name).Msg("Failed to set hostname")
+				cmd.Emit("pushSetHostname", SetHostnameResponse{Hostname: name, Error: err.Error()})
+				return
+			}
+			cmd.Emit("pushSetHostname", SetHostnameResponse{Success: true, Hostname: name})
+
+			info := GetSystemInfo()
+			s.emitAll("pushSystemInfo", info)
+			if s.hostnameListener != nil {
+				s.hostnameListener(info)
+			}
+		})
+
+		// Log level events - toggle debug logging without restarting.
+		// Only full-access clients may change it (guests are limited to get*
+		// events); the level resets to the --debug flag on restart.
+		s.on(client, "getLogLevel", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getLogLevel")
+			cmd.Emit("pushLogLevel", LogLevelResponse{Level: GetLogLevel(), Success: true})
+		})
+
+		s.on(client, "setLogLevel", func(cmd *Command, args ...any) {
+			var level string
+			if len(args) > 0 {
+				switch v := args[0].(type) {
+				case string:
+					level = v
+				case map[string]interface{}:
+					level = getString(v, "level")
+				}
+			}
+
+			if err := SetLogLevel(level); err != nil {
+				cmd.Log.Warn().Str("level", level).Msg("Rejected setLogLevel")
+				cmd.Emit("pushLogLevel", LogLevelResponse{Level: GetLogLevel(), Error: err.Error()})
+				return
+			}
+
+			cmd.Log.Info().Str("level", level).Msg("Log level changed")
+			s.emitAll("pushLogLevel", LogLevelResponse{Level: GetLogLevel(), Success: true})
 		})
 
 		// Rescan database event - triggers MPD to scan for new/changed music files
-		client.On("rescanDb", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("rescanDb requested")
+		s.on(client, "rescanDb", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("rescanDb requested")
 			jobID, err := s.mpdClient.Update("")
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to start database update")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Error().Err(err).Msg("Failed to start database update")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Rescan Failed",
 					"message": err.Error(),
 				})
 				return
 			}
-			log.Info().Int("jobID", jobID).Msg("MPD database update started")
-			client.Emit("pushToastMessage", map[string]interface{}{
-				"type":    "success",
-				"title":   "Rescan Started",
-				"message": "Music library rescan has started. This may take a while.",
-			})
+			cmd.Log.Info().Int("jobID", jobID).Msg("MPD database update started")
+			s.emitAll("pushDatabaseUpdating", DatabaseUpdating{JobID: jobID})
+			cmd.Emit("pushToastMessage", map[string]interface{}{
+				"type":    "success",
+				"title":   "Rescan Started",
+				"message": "Music library rescan has started. This may take a while.",
+			})
+		})
+
+		// Rescan a single NAS share instead of the whole library
+		s.on(client, "rescanNasShare", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("rescanNasShare requested")
+
+			var shareID string
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					shareID = getString(data, "id")
+				} else if id, ok := args[0].(string); ok {
+					shareID = id
+				}
+			}
+			if shareID == "" {
+				cmd.Emit("pushRescanNasShare", RescanShareResult{Error: "invalid share ID"})
+				return
+			}
+
+			result := s.rescanNasShare(shareID)
+			if !result.Success {
+				cmd.Log.Error().Str("shareId", shareID).Str("error", result.Error).Msg("Failed to rescan NAS share")
+			} else {
+				cmd.Log.Info().Str("path", result.Path).Int("jobID", result.JobID).Msg("NAS share rescan started")
+			}
+			cmd.Emit("pushRescanNasShare", result)
+		})
+
+		// Configuration export and import, for moving to another device
+		s.on(client, "exportConfig", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("exportConfig requested")
+			cmd.Emit("pushExportConfig", s.ExportConfig())
+		})
+
+		s.on(client, "factoryReset", func(cmd *Command, args ...any) {
+			cmd.Log.Warn().Interface("args", args).Msg("factoryReset requested")
+			var confirm bool
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					confirm, _ = data["confirm"].(bool)
+				}
+			}
+			cmd.Emit("pushFactoryReset", s.FactoryReset(confirm))
+		})
+
+		s.on(client, "importConfig", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("importConfig requested")
+			if len(args) == 0 {
+				cmd.Emit("pushImportConfig", ImportConfigResponse{Error: "missing import data"})
+				return
+			}
+			req, err := parseImportConfigRequest(args[0])
+			if err != nil {
+				cmd.Emit("pushImportConfig", ImportConfigResponse{Error: err.Error()})
+				return
+			}
+			cmd.Emit("pushImportConfig", s.ImportConfig(req))
 		})
 
 		// Bit-perfect configuration check event
-		client.On("getBitPerfect", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("getBitPerfect requested")
-			result := GetBitPerfectStatus()
-			log.Info().Str("status", result.Status).Int("issues", len(result.Issues)).Int("config", len(result.Config)).Msg("pushBitPerfect")
-			client.Emit("pushBitPerfect", result)
+		s.on(client, "getBitPerfect", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getBitPerfect requested")
+			result := s.bitPerfectStatus()
+			cmd.Log.Info().Str("status", result.Status).Int("issues", len(result.Issues)).Int("config", len(result.Config)).Msg("pushBitPerfect")
+			cmd.Emit("pushBitPerfect", result)
 		})
 
 		// Playback options event (audio devices)
-		client.On("getPlaybackOptions", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("getPlaybackOptions requested")
-			options := GetPlaybackOptions()
-			log.Info().Int("sections", len(options.Options)).Int("cards", len(options.SystemCards)).Msg("pushPlaybackOptions")
-			client.Emit("pushPlaybackOptions", options)
+		s.on(client, "getPlaybackOptions", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getPlaybackOptions requested")
+			options := s.audioConfig.GetPlaybackOptions()
+			cmd.Log.Info().Int("sections", len(options.Options)).Int("cards", len(options.SystemCards)).Msg("pushPlaybackOptions")
+			cmd.Emit("pushPlaybackOptions", options)
 		})
 
 		// Set playback settings (change audio output)
-		client.On("setPlaybackSettings", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("setPlaybackSettings requested")
+		s.on(client, "setPlaybackSettings", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("setPlaybackSettings requested")
 
 			response := map[string]interface{}{"success": false}
 
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
 					if device, ok := m["output_device"].(string); ok {
-						if err := SetPlaybackSettings(device); err != nil {
-							log.Error().Err(err).Str("device", device).Msg("Failed to set audio output")
+						if err := s.audioConfig.SetPlaybackSettings(device); err != nil {
+							cmd.Log.Error().Err(err).Str("device", device).Msg("Failed to set audio output")
 							response["error"] = err.Error()
 						} else {
 							response["success"] = true
 							// Broadcast updated playback options to all clients
-							options := GetPlaybackOptions()
-							s.io.Emit("pushPlaybackOptions", options)
+							options := s.audioConfig.GetPlaybackOptions()
+							s.emitAll("pushPlaybackOptions", options)
 						}
 					}
 				}
@@ -599,65 +1166,80 @@ func (s *Server) setupHandlers() {
 					callback([]any{response}, nil)
 				}
 			}
-			client.Emit("pushPlaybackSettings", response)
+			cmd.Emit("pushPlaybackSettings", response)
 		})
 
 		// DSD mode events
-		client.On("getDsdMode", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("getDsdMode requested")
-			mode := GetDsdMode()
-			log.Info().Str("mode", mode.Mode).Msg("pushDsdMode")
-			client.Emit("pushDsdMode", mode)
+		s.on(client, "getDsdMode", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getDsdMode requested")
+			mode := s.audioConfig.GetDsdMode()
+			cmd.Log.Info().Str("mode", mode.Mode).Msg("pushDsdMode")
+			cmd.Emit("pushDsdMode", mode)
 		})
 
-		client.On("setDsdMode", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("setDsdMode requested")
+		s.on(client, "setDsdMode", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("setDsdMode requested")
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
 					if mode, ok := m["mode"].(string); ok {
-						result := SetDsdMode(mode)
-						log.Info().Bool("success", result.Success).Str("mode", result.Mode).Msg("pushDsdMode")
-						client.Emit("pushDsdMode", result)
+						result := s.audioConfig.SetDsdMode(mode)
+						cmd.Log.Info().Bool("success", result.Success).Str("mode", result.Mode).Msg("pushDsdMode")
+						cmd.Emit("pushDsdMode", result)
 						// Broadcast to all clients
-						s.io.Emit("pushDsdMode", result)
+						s.emitAll("pushDsdMode", result)
+						if result.Success {
+							s.syncDsdMode()
+						}
 					}
 				}
 			}
 		})
 
 		// Mixer mode events
-		client.On("getMixerMode", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("getMixerMode requested")
-			mode := GetMixerMode()
-			log.Info().Bool("enabled", mode.Enabled).Msg("pushMixerMode")
-			client.Emit("pushMixerMode", mode)
+		s.on(client, "getMixerMode", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getMixerMode requested")
+			mode := s.audioConfig.GetMixerMode()
+			cmd.Log.Info().Bool("enabled", mode.Enabled).Msg("pushMixerMode")
+			cmd.Emit("pushMixerMode", mode)
 		})
 
-		client.On("setMixerMode", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("setMixerMode requested")
+		s.on(client, "setMixerMode", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("setMixerMode requested")
 			if len(args) > 0 {
 				if m, ok := args[0].(map[string]interface{}); ok {
 					if enabled, ok := m["enabled"].(bool); ok {
-						result := SetMixerMode(enabled)
-						log.Info().Bool("success", result.Success).Bool("enabled", result.Enabled).Msg("pushMixerMode")
-						client.Emit("pushMixerMode", result)
+						result := s.audioConfig.SetMixerMode(enabled)
+						cmd.Log.Info().Bool("success", result.Success).Bool("enabled", result.Enabled).Msg("pushMixerMode")
+						cmd.Emit("pushMixerMode", result)
 						// Broadcast to all clients
-						s.io.Emit("pushMixerMode", result)
+						s.emitAll("pushMixerMode", result)
 					}
 				}
 			}
 		})
 
 		// Apply all bit-perfect settings
-		client.On("applyBitPerfect", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("applyBitPerfect requested")
-			result := ApplyBitPerfect()
-			log.Info().Bool("success", result.Success).Strs("applied", result.Applied).Msg("pushApplyBitPerfect")
-			client.Emit("pushApplyBitPerfect", result)
+		s.on(client, "applyBitPerfect", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("applyBitPerfect requested")
+			result := s.audioConfig.ApplyBitPerfect()
+			cmd.Log.Info().Bool("success", result.Success).Strs("applied", result.Applied).Msg("pushApplyBitPerfect")
+			cmd.Emit("pushApplyBitPerfect", result)
 			// Refresh bit-perfect status for all clients
-			s.io.Emit("pushBitPerfect", GetBitPerfectStatus())
+			s.emitAll("pushBitPerfect", s.bitPerfectStatus())
 			// Refresh mixer mode for all clients
-			s.io.Emit("pushMixerMode", GetMixerMode())
+			s.emitAll("pushMixerMode", s.audioConfig.GetMixerMode())
+		})
+
+		// Restore the most recent MPD config backup
+		s.on(client, "rollbackMpdConfig", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("rollbackMpdConfig requested")
+			result := s.audioConfig.RollbackMPDConfig()
+			cmd.Log.Info().Bool("success", result.Success).Str("backup", result.Backup).Msg("pushRollbackMpdConfig")
+			cmd.Emit("pushRollbackMpdConfig", result)
+			// Refresh all config-derived settings for all clients
+			s.emitAll("pushBitPerfect", s.bitPerfectStatus())
+			s.emitAll("pushDsdMode", s.audioConfig.GetDsdMode())
+			s.emitAll("pushMixerMode", s.audioConfig.GetMixerMode())
 		})
 
 		// ============================================================
@@ -665,27 +1247,27 @@ func (s *Server) setupHandlers() {
 		// ============================================================
 
 		// List all configured NAS shares
-		client.On("getListNasShares", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("getListNasShares requested")
+		s.on(client, "getListNasShares", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getListNasShares requested")
 			if s.sourcesService == nil {
-				client.Emit("pushListNasShares", []sources.NasShare{})
+				cmd.Emit("pushListNasShares", []sources.NasShare{})
 				return
 			}
 			shares, err := s.sourcesService.ListNasShares()
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to list NAS shares")
-				client.Emit("pushListNasShares", []sources.NasShare{})
+				cmd.Log.Error().Err(err).Msg("Failed to list NAS shares")
+				cmd.Emit("pushListNasShares", []sources.NasShare{})
 				return
 			}
-			log.Info().Int("count", len(shares)).Msg("pushListNasShares")
-			client.Emit("pushListNasShares", shares)
+			cmd.Log.Info().Int("count", len(shares)).Msg("pushListNasShares")
+			cmd.Emit("pushListNasShares", shares)
 		})
 
 		// Add a new NAS share
-		client.On("addNasShare", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("addNasShare requested")
+		s.on(client, "addNasShare", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("addNasShare requested")
 			if s.sourcesService == nil {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "sources service not available",
 				})
@@ -693,7 +1275,7 @@ func (s *Server) setupHandlers() {
 			}
 
 			if len(args) == 0 {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "missing share data",
 				})
@@ -703,52 +1285,179 @@ func (s *Server) setupHandlers() {
 			// Parse the request
 			data, ok := args[0].(map[string]interface{})
 			if !ok {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "invalid share data format",
 				})
 				return
 			}
 
-			req := sources.AddNasShareRequest{
-				Name:     getString(data, "name"),
-				IP:       getString(data, "ip"),
-				Path:     getString(data, "path"),
-				FSType:   getString(data, "fstype"),
-				Username: getString(data, "username"),
-				Password: getString(data, "password"),
-				Options:  getString(data, "options"),
-			}
+			req := parseAddNasShareRequest(data)
 
 			result, err := s.sourcesService.AddNasShare(req)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to add NAS share")
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Log.Error().Err(err).Msg("Failed to add NAS share")
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   err.Error(),
 				})
 				return
 			}
 
-			log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
-			client.Emit("pushNasShareResult", result)
+			cmd.Log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
+			cmd.Emit("pushNasShareResult", result)
 
 			// Also push updated list to all clients
 			if result.Success {
 				shares, _ := s.sourcesService.ListNasShares()
-				s.io.Emit("pushListNasShares", shares)
+				s.emitAll("pushListNasShares", shares)
 				// Trigger MPD database update
 				if _, err := s.mpdClient.Update(""); err != nil {
-					log.Warn().Err(err).Msg("Failed to trigger MPD update after adding NAS share")
+					cmd.Log.Warn().Err(err).Msg("Failed to trigger MPD update after adding NAS share")
+				}
+			}
+		})
+
+		// Get attached USB drives
+		s.on(client, "getUsbDevices", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getUsbDevices requested")
+			if s.sourcesService == nil {
+				cmd.Emit("pushUsbDevices", []sources.UsbDrive{})
+				return
+			}
+			cmd.Emit("pushUsbDevices", s.sourcesService.ListUsbDrives())
+		})
+
+		// Get total/used/free space of each music source
+		s.on(client, "getStorageInfo", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getStorageInfo requested")
+			if s.sourcesService == nil {
+				cmd.Emit("pushStorageInfo", []sources.SourceUsage{})
+				return
+			}
+			usage := s.sourcesService.GetSourceUsage()
+			cmd.Log.Info().Int("sources", len(usage)).Msg("pushStorageInfo")
+			cmd.Emit("pushStorageInfo", usage)
+		})
+
+		// Safely eject a USB drive
+		s.on(client, "ejectUsbDevice", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("ejectUsbDevice requested")
+			if s.sourcesService == nil {
+				cmd.Emit("pushUsbDeviceResult", sources.SourceResult{
+					Success: false,
+					Error:   "sources service not available",
+				})
+				return
+			}
+
+			var driveID string
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					driveID = getString(data, "id")
+				} else if id, ok := args[0].(string); ok {
+					driveID = id
+				}
+			}
+
+			libraryPaths, err := s.sourcesService.UsbEjectLibraryPaths(driveID)
+			if err != nil {
+				cmd.Emit("pushUsbDeviceResult", sources.SourceResult{
+					Success: false,
+					Error:   "USB drive not found",
+				})
+				return
+			}
+
+			// Stop playback if the current track is read from this drive, or
+			// from another partition on the same disk that the eject unmounts
+			if len(libraryPaths) > 0 {
+				if state, err := s.playerService.GetState(); err == nil {
+					onDisk := slices.ContainsFunc(libraryPaths, func(path string) bool {
+						return strings.HasPrefix(state.URI, path+"/")
+					})
+					if state.Status != player.StatusStop && onDisk {
+						cmd.Log.Info().Str("uri", state.URI).Msg("Stopping playback before ejecting USB drive")
+						if err := s.playerService.StopNow(); err != nil {
+							cmd.Emit("pushUsbDeviceResult", sources.SourceResult{
+								Success: false,
+								Error:   "USB drive is in use by playback",
+							})
+							return
+						}
+					}
+				}
+			}
+
+			result, err := s.sourcesService.EjectUsbDrive(driveID)
+			if err != nil {
+				cmd.Log.Error().Err(err).Msg("Failed to eject USB drive")
+				cmd.Emit("pushUsbDeviceResult", sources.SourceResult{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+
+			cmd.Log.Info().Bool("success", result.Success).Msg("pushUsbDeviceResult")
+			cmd.Emit("pushUsbDeviceResult", result)
+
+			if result.Success {
+				s.emitAll("pushUsbDevices", s.sourcesService.ListUsbDrives())
+				// Drop the drive's tracks from the MPD database
+				if _, err := s.mpdClient.Update(""); err != nil {
+					cmd.Log.Warn().Err(err).Msg("Failed to trigger MPD update after ejecting USB drive")
 				}
 			}
 		})
 
+		// Test a NAS share connection without saving it
+		s.on(client, "testNasShare", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("testNasShare requested")
+			if s.sourcesService == nil {
+				cmd.Emit("pushTestNasShareResult", sources.SourceResult{
+					Success: false,
+					Error:   "sources service not available",
+				})
+				return
+			}
+
+			if len(args) == 0 {
+				cmd.Emit("pushTestNasShareResult", sources.SourceResult{
+					Success: false,
+					Error:   "missing share data",
+				})
+				return
+			}
+
+			data, ok := args[0].(map[string]interface{})
+			if !ok {
+				cmd.Emit("pushTestNasShareResult", sources.SourceResult{
+					Success: false,
+					Error:   "invalid share data format",
+				})
+				return
+			}
+
+			result, err := s.sourcesService.TestConnection(parseAddNasShareRequest(data))
+			if err != nil {
+				cmd.Log.Error().Err(err).Msg("Failed to test NAS share")
+				cmd.Emit("pushTestNasShareResult", sources.SourceResult{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+
+			cmd.Log.Info().Bool("success", result.Success).Msg("pushTestNasShareResult")
+			cmd.Emit("pushTestNasShareResult", result)
+		})
+
 		// Delete a NAS share
-		client.On("deleteNasShare", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("deleteNasShare requested")
+		s.on(client, "deleteNasShare", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("deleteNasShare requested")
 			if s.sourcesService == nil {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "sources service not available",
 				})
@@ -756,7 +1465,7 @@ func (s *Server) setupHandlers() {
 			}
 
 			if len(args) == 0 {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "missing share ID",
 				})
@@ -771,7 +1480,7 @@ func (s *Server) setupHandlers() {
 			}
 
 			if shareID == "" {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "invalid share ID",
 				})
@@ -780,29 +1489,29 @@ func (s *Server) setupHandlers() {
 
 			result, err := s.sourcesService.DeleteNasShare(shareID)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to delete NAS share")
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Log.Error().Err(err).Msg("Failed to delete NAS share")
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   err.Error(),
 				})
 				return
 			}
 
-			log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
-			client.Emit("pushNasShareResult", result)
+			cmd.Log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
+			cmd.Emit("pushNasShareResult", result)
 
 			// Also push updated list to all clients
 			if result.Success {
 				shares, _ := s.sourcesService.ListNasShares()
-				s.io.Emit("pushListNasShares", shares)
+				s.emitAll("pushListNasShares", shares)
 			}
 		})
 
 		// Mount a NAS share
-		client.On("mountNasShare", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("mountNasShare requested")
+		s.on(client, "mountNasShare", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("mountNasShare requested")
 			if s.sourcesService == nil {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "sources service not available",
 				})
@@ -819,7 +1528,7 @@ func (s *Server) setupHandlers() {
 			}
 
 			if shareID == "" {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "missing share ID",
 				})
@@ -828,57 +1537,57 @@ func (s *Server) setupHandlers() {
 
 			result, err := s.sourcesService.MountNasShare(shareID)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to mount NAS share")
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Log.Error().Err(err).Msg("Failed to mount NAS share")
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   err.Error(),
 				})
 				return
 			}
 
-			log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
-			client.Emit("pushNasShareResult", result)
+			cmd.Log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
+			cmd.Emit("pushNasShareResult", result)
 
 			// Push updated list and trigger MPD update
 			if result.Success {
 				shares, _ := s.sourcesService.ListNasShares()
-				s.io.Emit("pushListNasShares", shares)
+				s.emitAll("pushListNasShares", shares)
 				if _, err := s.mpdClient.Update(""); err != nil {
-					log.Warn().Err(err).Msg("Failed to trigger MPD update after mounting NAS share")
+					cmd.Log.Warn().Err(err).Msg("Failed to trigger MPD update after mounting NAS share")
 				}
 			}
 		})
 
 		// Discover NAS devices on the network
-		client.On("discoverNasDevices", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("discoverNasDevices requested")
+		s.on(client, "discoverNasDevices", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("discoverNasDevices requested")
 			if s.sourcesService == nil {
-				client.Emit("pushNasDevices", sources.DiscoverResult{
+				cmd.Emit("pushNasDevices", sources.DiscoverResult{
 					Devices: []sources.NasDevice{},
 					Error:   "sources service not available",
 				})
 				return
 			}
 
-			result, err := s.sourcesService.DiscoverNasDevices()
+			result, err := s.sourcesService.DiscoverNasDevices(cmd.Ctx)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to discover NAS devices")
-				client.Emit("pushNasDevices", sources.DiscoverResult{
+				cmd.Log.Error().Err(err).Msg("Failed to discover NAS devices")
+				cmd.Emit("pushNasDevices", sources.DiscoverResult{
 					Devices: []sources.NasDevice{},
 					Error:   err.Error(),
 				})
 				return
 			}
 
-			log.Info().Int("count", len(result.Devices)).Msg("pushNasDevices")
-			client.Emit("pushNasDevices", result)
+			cmd.Log.Info().Int("count", len(result.Devices)).Msg("pushNasDevices")
+			cmd.Emit("pushNasDevices", result)
 		})
 
 		// Browse shares on a NAS device
-		client.On("browseNasShares", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("browseNasShares requested")
+		s.on(client, "browseNasShares", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("browseNasShares requested")
 			if s.sourcesService == nil {
-				client.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
+				cmd.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
 					Shares: []sources.ShareInfo{},
 					Error:  "sources service not available",
 				})
@@ -886,7 +1595,7 @@ func (s *Server) setupHandlers() {
 			}
 
 			if len(args) == 0 {
-				client.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
+				cmd.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
 					Shares: []sources.ShareInfo{},
 					Error:  "missing host data",
 				})
@@ -895,7 +1604,7 @@ func (s *Server) setupHandlers() {
 
 			data, ok := args[0].(map[string]interface{})
 			if !ok {
-				client.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
+				cmd.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
 					Shares: []sources.ShareInfo{},
 					Error:  "invalid request format",
 				})
@@ -910,32 +1619,32 @@ func (s *Server) setupHandlers() {
 			password := getString(data, "password")
 
 			if host == "" {
-				client.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
+				cmd.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
 					Shares: []sources.ShareInfo{},
 					Error:  "host is required",
 				})
 				return
 			}
 
-			result, err := s.sourcesService.BrowseNasShares(host, username, password)
+			result, err := s.sourcesService.BrowseNasShares(cmd.Ctx, host, username, password)
 			if err != nil {
-				log.Error().Err(err).Str("host", host).Msg("Failed to browse NAS shares")
-				client.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
+				cmd.Log.Error().Err(err).Str("host", host).Msg("Failed to browse NAS shares")
+				cmd.Emit("pushBrowseNasShares", sources.BrowseSharesResult{
 					Shares: []sources.ShareInfo{},
 					Error:  err.Error(),
 				})
 				return
 			}
 
-			log.Info().Int("count", len(result.Shares)).Str("host", host).Msg("pushBrowseNasShares")
-			client.Emit("pushBrowseNasShares", result)
+			cmd.Log.Info().Int("count", len(result.Shares)).Str("host", host).Msg("pushBrowseNasShares")
+			cmd.Emit("pushBrowseNasShares", result)
 		})
 
 		// Unmount a NAS share
-		client.On("unmountNasShare", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("unmountNasShare requested")
+		s.on(client, "unmountNasShare", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("unmountNasShare requested")
 			if s.sourcesService == nil {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "sources service not available",
 				})
@@ -952,7 +1661,7 @@ func (s *Server) setupHandlers() {
 			}
 
 			if shareID == "" {
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   "missing share ID",
 				})
@@ -961,21 +1670,21 @@ func (s *Server) setupHandlers() {
 
 			result, err := s.sourcesService.UnmountNasShare(shareID)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to unmount NAS share")
-				client.Emit("pushNasShareResult", sources.SourceResult{
+				cmd.Log.Error().Err(err).Msg("Failed to unmount NAS share")
+				cmd.Emit("pushNasShareResult", sources.SourceResult{
 					Success: false,
 					Error:   err.Error(),
 				})
 				return
 			}
 
-			log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
-			client.Emit("pushNasShareResult", result)
+			cmd.Log.Info().Bool("success", result.Success).Msg("pushNasShareResult")
+			cmd.Emit("pushNasShareResult", result)
 
 			// Push updated list
 			if result.Success {
 				shares, _ := s.sourcesService.ListNasShares()
-				s.io.Emit("pushListNasShares", shares)
+				s.emitAll("pushListNasShares", shares)
 			}
 		})
 
@@ -984,25 +1693,25 @@ func (s *Server) setupHandlers() {
 		// ============================================================
 
 		// Get Qobuz login status
-		client.On("getQobuzStatus", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("getQobuzStatus requested")
+		s.on(client, "getQobuzStatus", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getQobuzStatus requested")
 			if s.qobuzService == nil {
-				client.Emit("pushQobuzStatus", map[string]interface{}{
+				cmd.Emit("pushQobuzStatus", map[string]interface{}{
 					"loggedIn": false,
 					"error":    "Qobuz service not available",
 				})
 				return
 			}
 			status := s.qobuzService.GetStatus()
-			log.Info().Bool("loggedIn", status.LoggedIn).Str("email", status.Email).Msg("pushQobuzStatus")
-			client.Emit("pushQobuzStatus", status)
+			cmd.Log.Info().Bool("loggedIn", status.LoggedIn).Str("email", status.Email).Msg("pushQobuzStatus")
+			cmd.Emit("pushQobuzStatus", status)
 		})
 
 		// Login to Qobuz
-		client.On("qobuzLogin", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("qobuzLogin requested")
+		s.on(client, "qobuzLogin", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("qobuzLogin requested")
 			if s.qobuzService == nil {
-				client.Emit("pushQobuzLoginResult", map[string]interface{}{
+				cmd.Emit("pushQobuzLoginResult", map[string]interface{}{
 					"success": false,
 					"error":   "Qobuz service not available",
 				})
@@ -1010,7 +1719,7 @@ func (s *Server) setupHandlers() {
 			}
 
 			if len(args) == 0 {
-				client.Emit("pushQobuzLoginResult", map[string]interface{}{
+				cmd.Emit("pushQobuzLoginResult", map[string]interface{}{
 					"success": false,
 					"error":   "missing credentials",
 				})
@@ -1019,7 +1728,7 @@ func (s *Server) setupHandlers() {
 
 			data, ok := args[0].(map[string]interface{})
 			if !ok {
-				client.Emit("pushQobuzLoginResult", map[string]interface{}{
+				cmd.Emit("pushQobuzLoginResult", map[string]interface{}{
 					"success": false,
 					"error":   "invalid request format",
 				})
@@ -1030,7 +1739,7 @@ func (s *Server) setupHandlers() {
 			password := getString(data, "password")
 
 			if email == "" || password == "" {
-				client.Emit("pushQobuzLoginResult", map[string]interface{}{
+				cmd.Emit("pushQobuzLoginResult", map[string]interface{}{
 					"success": false,
 					"error":   "email and password are required",
 				})
@@ -1039,30 +1748,30 @@ func (s *Server) setupHandlers() {
 
 			result, err := s.qobuzService.Login(email, password)
 			if err != nil {
-				log.Error().Err(err).Msg("Qobuz login failed")
-				client.Emit("pushQobuzLoginResult", map[string]interface{}{
+				cmd.Log.Error().Err(err).Msg("Qobuz login failed")
+				cmd.Emit("pushQobuzLoginResult", map[string]interface{}{
 					"success": false,
 					"error":   err.Error(),
 				})
 				return
 			}
 
-			log.Info().Bool("success", result.Success).Msg("pushQobuzLoginResult")
-			client.Emit("pushQobuzLoginResult", result)
+			cmd.Log.Info().Bool("success", result.Success).Msg("pushQobuzLoginResult")
+			cmd.Emit("pushQobuzLoginResult", result)
 
 			// Broadcast updated status to all clients
 			if result.Success {
-				s.io.Emit("pushQobuzStatus", s.qobuzService.GetStatus())
+				s.emitAll("pushQobuzStatus", s.qobuzService.GetStatus())
 				// Also update browse sources
 				s.broadcastBrowseSources()
 			}
 		})
 
 		// Logout from Qobuz
-		client.On("qobuzLogout", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("qobuzLogout requested")
+		s.on(client, "qobuzLogout", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("qobuzLogout requested")
 			if s.qobuzService == nil {
-				client.Emit("pushQobuzLogoutResult", map[string]interface{}{
+				cmd.Emit("pushQobuzLogoutResult", map[string]interface{}{
 					"success": false,
 					"error":   "Qobuz service not available",
 				})
@@ -1070,45 +1779,45 @@ func (s *Server) setupHandlers() {
 			}
 
 			if err := s.qobuzService.Logout(); err != nil {
-				log.Error().Err(err).Msg("Qobuz logout failed")
-				client.Emit("pushQobuzLogoutResult", map[string]interface{}{
+				cmd.Log.Error().Err(err).Msg("Qobuz logout failed")
+				cmd.Emit("pushQobuzLogoutResult", map[string]interface{}{
 					"success": false,
 					"error":   err.Error(),
 				})
 				return
 			}
 
-			log.Info().Msg("Qobuz logout successful")
-			client.Emit("pushQobuzLogoutResult", map[string]interface{}{
+			cmd.Log.Info().Msg("Qobuz logout successful")
+			cmd.Emit("pushQobuzLogoutResult", map[string]interface{}{
 				"success": true,
 				"message": "Successfully logged out from Qobuz",
 			})
 
 			// Broadcast updated status to all clients
-			s.io.Emit("pushQobuzStatus", s.qobuzService.GetStatus())
+			s.emitAll("pushQobuzStatus", s.qobuzService.GetStatus())
 			// Also update browse sources
 			s.broadcastBrowseSources()
 		})
 
 		// Search Qobuz
-		client.On("qobuzSearch", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("qobuzSearch requested")
+		s.on(client, "qobuzSearch", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("qobuzSearch requested")
 			if s.qobuzService == nil {
-				client.Emit("pushQobuzSearchResult", map[string]interface{}{
+				cmd.Emit("pushQobuzSearchResult", map[string]interface{}{
 					"error": "Qobuz service not available",
 				})
 				return
 			}
 
 			if !s.qobuzService.IsLoggedIn() {
-				client.Emit("pushQobuzSearchResult", map[string]interface{}{
+				cmd.Emit("pushQobuzSearchResult", map[string]interface{}{
 					"error": "not logged in to Qobuz",
 				})
 				return
 			}
 
 			if len(args) == 0 {
-				client.Emit("pushQobuzSearchResult", map[string]interface{}{
+				cmd.Emit("pushQobuzSearchResult", map[string]interface{}{
 					"error": "missing search query",
 				})
 				return
@@ -1116,7 +1825,7 @@ func (s *Server) setupHandlers() {
 
 			data, ok := args[0].(map[string]interface{})
 			if !ok {
-				client.Emit("pushQobuzSearchResult", map[string]interface{}{
+				cmd.Emit("pushQobuzSearchResult", map[string]interface{}{
 					"error": "invalid request format",
 				})
 				return
@@ -1124,7 +1833,7 @@ func (s *Server) setupHandlers() {
 
 			query := getString(data, "query")
 			if query == "" {
-				client.Emit("pushQobuzSearchResult", map[string]interface{}{
+				cmd.Emit("pushQobuzSearchResult", map[string]interface{}{
 					"error": "query is required",
 				})
 				return
@@ -1137,15 +1846,15 @@ func (s *Server) setupHandlers() {
 
 			result, err := s.qobuzService.Search(query, limit)
 			if err != nil {
-				log.Error().Err(err).Str("query", query).Msg("Qobuz search failed")
-				client.Emit("pushQobuzSearchResult", map[string]interface{}{
+				cmd.Log.Error().Err(err).Str("query", query).Msg("Qobuz search failed")
+				cmd.Emit("pushQobuzSearchResult", map[string]interface{}{
 					"error": err.Error(),
 				})
 				return
 			}
 
-			log.Info().Str("query", query).Msg("pushQobuzSearchResult")
-			client.Emit("pushQobuzSearchResult", result)
+			cmd.Log.Info().Str("query", query).Msg("pushQobuzSearchResult")
+			cmd.Emit("pushQobuzSearchResult", result)
 		})
 
 		// ============================================================
@@ -1153,10 +1862,10 @@ func (s *Server) setupHandlers() {
 		// ============================================================
 
 		// Get local albums (local disk + USB only)
-		client.On("getLocalAlbums", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("getLocalAlbums requested")
+		s.on(client, "getLocalAlbums", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("getLocalAlbums requested")
 			if s.localMusicService == nil {
-				client.Emit("pushLocalAlbums", map[string]interface{}{
+				cmd.Emit("pushLocalAlbums", map[string]interface{}{
 					"albums":      []interface{}{},
 					"totalCount":  0,
 					"filteredOut": 0,
@@ -1179,6 +1888,9 @@ func (s *Server) setupHandlers() {
 					if query, ok := data["query"].(string); ok {
 						req.Query = query
 					}
+					if page, ok := data["page"].(float64); ok {
+						req.Page = int(page)
+					}
 					if limit, ok := data["limit"].(float64); ok {
 						req.Limit = int(limit)
 					}
@@ -1186,19 +1898,19 @@ func (s *Server) setupHandlers() {
 			}
 
 			resp := s.localMusicService.GetLocalAlbums(req)
-			log.Info().
+			cmd.Log.Info().
 				Int("albumCount", len(resp.Albums)).
 				Int("filteredOut", resp.FilteredOut).
 				Str("sort", string(req.Sort)).
 				Msg("pushLocalAlbums")
-			client.Emit("pushLocalAlbums", resp)
+			cmd.Emit("pushLocalAlbums", resp)
 		})
 
 		// Get last played tracks (local sources + manual plays only)
-		client.On("getLastPlayedTracks", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("getLastPlayedTracks requested")
+		s.on(client, "getLastPlayedTracks", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("getLastPlayedTracks requested")
 			if s.localMusicService == nil {
-				client.Emit("pushLastPlayedTracks", map[string]interface{}{
+				cmd.Emit("pushLastPlayedTracks", map[string]interface{}{
 					"tracks":     []interface{}{},
 					"totalCount": 0,
 					"error":      "local music service not available",
@@ -1220,22 +1932,25 @@ func (s *Server) setupHandlers() {
 					if limit, ok := data["limit"].(float64); ok {
 						req.Limit = int(limit)
 					}
+					if dedupe, ok := data["dedupe"].(bool); ok {
+						req.Dedupe = dedupe
+					}
 				}
 			}
 
 			resp := s.localMusicService.GetLastPlayedTracks(req)
-			log.Info().
+			cmd.Log.Info().
 				Int("trackCount", len(resp.Tracks)).
 				Str("sort", string(req.Sort)).
 				Msg("pushLastPlayedTracks")
-			client.Emit("pushLastPlayedTracks", resp)
+			cmd.Emit("pushLastPlayedTracks", resp)
 		})
 
 		// Get tracks for a specific album
-		client.On("getAlbumTracks", func(args ...any) {
-			log.Info().Str("id", clientID).Interface("args", args).Msg("getAlbumTracks requested")
+		s.on(client, "getAlbumTracks", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("getAlbumTracks requested")
 			if s.localMusicService == nil {
-				client.Emit("pushAlbumTracks", map[string]interface{}{
+				cmd.Emit("pushAlbumTracks", map[string]interface{}{
 					"tracks":     []interface{}{},
 					"totalCount": 0,
 					"error":      "local music service not available",
@@ -1255,16 +1970,16 @@ func (s *Server) setupHandlers() {
 			}
 
 			resp := s.localMusicService.GetAlbumTracks(req)
-			log.Info().
+			cmd.Log.Info().
 				Str("albumUri", req.AlbumURI).
 				Int("trackCount", len(resp.Tracks)).
 				Msg("pushAlbumTracks")
-			client.Emit("pushAlbumTracks", resp)
+			cmd.Emit("pushAlbumTracks", resp)
 		})
 
 		// Record a manual track play (for history tracking)
-		client.On("recordTrackPlay", func(args ...any) {
-			log.Debug().Str("id", clientID).Interface("args", args).Msg("recordTrackPlay requested")
+		s.on(client, "recordTrackPlay", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("args", args).Msg("recordTrackPlay requested")
 			if s.localMusicService == nil {
 				return
 			}
@@ -1287,6 +2002,9 @@ func (s *Server) setupHandlers() {
 			artist := getString(data, "artist")
 			album := getString(data, "album")
 			albumArt := getString(data, "albumArt")
+			if albumArt == "" {
+				albumArt = s.playerService.AlbumArtURL(uri)
+			}
 			originStr := getString(data, "origin")
 
 			origin := localmusic.PlayOriginManualTrack
@@ -1300,48 +2018,243 @@ func (s *Server) setupHandlers() {
 			}
 
 			s.localMusicService.RecordTrackPlay(uri, title, artist, album, albumArt, origin)
-			log.Debug().
+			s.historyThrottler.Trigger()
+			cmd.Log.Debug().
 				Str("uri", uri).
 				Str("origin", string(origin)).
 				Msg("Track play recorded")
 		})
 
 		// Get history statistics
-		client.On("getHistoryStats", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("getHistoryStats requested")
+		s.on(client, "getHistoryStats", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getHistoryStats requested")
 			if s.localMusicService == nil {
-				client.Emit("pushHistoryStats", map[string]interface{}{
+				cmd.Emit("pushHistoryStats", map[string]interface{}{
 					"error": "local music service not available",
 				})
 				return
 			}
 
 			stats := s.localMusicService.GetHistoryStats()
-			client.Emit("pushHistoryStats", stats)
+			cmd.Emit("pushHistoryStats", stats)
 		})
 
 		// Clear history
-		client.On("clearHistory", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("clearHistory requested")
+		s.on(client, "clearHistory", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("clearHistory requested")
 			if s.localMusicService == nil {
 				return
 			}
 
 			s.localMusicService.ClearHistory()
-			client.Emit("pushHistoryCleared", map[string]interface{}{
+			cmd.Emit("pushHistoryCleared", map[string]interface{}{
 				"success": true,
 			})
 		})
 
+		// Generate a smart playlist ("on_repeat", "forgotten" or "recent") as a list of track URIs
+		s.on(client, "getSmartPlaylist", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("args", args).Msg("getSmartPlaylist requested")
+
+			var kind string
+			limit := 0
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					kind = getString(data, "kind")
+					if l, ok := data["limit"].(float64); ok {
+						limit = int(l)
+					}
+				}
+			}
+
+			resp := localmusic.SmartPlaylistResponse{
+				Kind: localmusic.SmartPlaylistKind(kind),
+				URIs: []string{},
+			}
+			if s.localMusicService == nil {
+				resp.Error = "local music service not available"
+				cmd.Emit("pushSmartPlaylist", resp)
+				return
+			}
+
+			uris, err := s.localMusicService.GenerateSmartPlaylist(kind, limit)
+			if err != nil {
+				cmd.Log.Warn().Err(err).Str("kind", kind).Msg("Failed to generate smart playlist")
+				resp.Error = err.Error()
+				cmd.Emit("pushSmartPlaylist", resp)
+				return
+			}
+
+			resp.URIs = uris
+			resp.TotalCount = len(uris)
+			cmd.Emit("pushSmartPlaylist", resp)
+		})
+
+		// Get favorites, optionally filtered by type ("track" or "album")
+		s.on(client, "getFavorites", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("args", args).Msg("getFavorites requested")
+			if s.localMusicService == nil {
+				cmd.Emit("pushFavorites", localmusic.FavoritesResponse{
+					Favorites: []localmusic.Favorite{},
+					Error:     "local music service not available",
+				})
+				return
+			}
+
+			var favType localmusic.FavoriteType
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					favType = localmusic.FavoriteType(getString(data, "type"))
+				}
+			}
+
+			cmd.Emit("pushFavorites", s.localMusicService.ListFavorites(favType))
+		})
+
+		// Mark a track or album as a favorite
+		s.on(client, "addFavorite", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("addFavorite requested")
+			if s.localMusicService == nil || len(args) == 0 {
+				return
+			}
+
+			data, ok := args[0].(map[string]interface{})
+			if !ok {
+				return
+			}
+
+			uri := getString(data, "uri")
+			favType := localmusic.FavoriteType(getString(data, "type"))
+			if err := s.localMusicService.AddFavorite(uri, favType); err != nil {
+				cmd.Emit("pushFavorites", localmusic.FavoritesResponse{
+					Favorites: []localmusic.Favorite{},
+					Error:     err.Error(),
+				})
+				return
+			}
+
+			// Push updated favorites to all clients
+			s.emitAll("pushFavorites", s.localMusicService.ListFavorites(""))
+		})
+
+		// Unmark a favorite track or album
+		s.on(client, "removeFavorite", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("removeFavorite requested")
+			if s.localMusicService == nil || len(args) == 0 {
+				return
+			}
+
+			var uri string
+			if data, ok := args[0].(map[string]interface{}); ok {
+				uri = getString(data, "uri")
+			} else if u, ok := args[0].(string); ok {
+				uri = u
+			}
+			if uri == "" {
+				return
+			}
+
+			s.localMusicService.RemoveFavorite(uri)
+
+			// Push updated favorites to all clients
+			s.emitAll("pushFavorites", s.localMusicService.ListFavorites(""))
+		})
+
+		// Rate a track from 1 to 5 stars (0 clears the rating)
+		s.on(client, "setRating", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Interface("args", args).Msg("setRating requested")
+			if s.localMusicService == nil || len(args) == 0 {
+				return
+			}
+
+			data, ok := args[0].(map[string]interface{})
+			if !ok {
+				return
+			}
+
+			uri := getString(data, "uri")
+			rating, ok := data["rating"].(float64)
+			if !ok || rating != float64(int(rating)) {
+				cmd.Emit("pushRating", localmusic.RatingResponse{
+					URI:   uri,
+					Error: "rating must be a whole number between 0 and 5",
+				})
+				return
+			}
+
+			if err := s.localMusicService.SetRating(uri, int(rating)); err != nil {
+				cmd.Emit("pushRating", localmusic.RatingResponse{
+					URI:   uri,
+					Error: err.Error(),
+				})
+				return
+			}
+
+			// Push the new rating to all clients
+			s.emitAll("pushRating", localmusic.RatingResponse{
+				URI:    uri,
+				Rating: s.localMusicService.GetRating(uri),
+			})
+		})
+
+		// Get the rating of a track
+		s.on(client, "getRating", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("args", args).Msg("getRating requested")
+			if len(args) == 0 {
+				return
+			}
+
+			var uri string
+			if data, ok := args[0].(map[string]interface{}); ok {
+				uri = getString(data, "uri")
+			} else if u, ok := args[0].(string); ok {
+				uri = u
+			}
+
+			resp := localmusic.RatingResponse{URI: uri}
+			if s.localMusicService == nil {
+				resp.Error = "local music service not available"
+			} else {
+				resp.Rating = s.localMusicService.GetRating(uri)
+			}
+			cmd.Emit("pushRating", resp)
+		})
+
+		// Get highly rated tracks as a queue-ready list
+		s.on(client, "getTopRated", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Interface("args", args).Msg("getTopRated requested")
+			if s.localMusicService == nil {
+				cmd.Emit("pushTopRated", localmusic.TopRatedResponse{
+					URIs:  []string{},
+					Error: "local music service not available",
+				})
+				return
+			}
+
+			minRating, limit := 0, 0
+			if len(args) > 0 {
+				if data, ok := args[0].(map[string]interface{}); ok {
+					if r, ok := data["minRating"].(float64); ok {
+						minRating = int(r)
+					}
+					if l, ok := data["limit"].(float64); ok {
+						limit = int(l)
+					}
+				}
+			}
+
+			cmd.Emit("pushTopRated", s.localMusicService.GetTopRated(minRating, limit))
+		})
+
 		// ============================================================
 		// Audirvana Integration Events
 		// ============================================================
 
 		// Get Audirvana status (detection and discovery)
-		client.On("getAudirvanaStatus", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("getAudirvanaStatus requested")
+		s.on(client, "getAudirvanaStatus", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("getAudirvanaStatus requested")
 			if s.audirvanaService == nil {
-				client.Emit("pushAudirvanaStatus", audirvana.Status{
+				cmd.Emit("pushAudirvanaStatus", audirvana.Status{
 					Installed: false,
 					Service: audirvana.ServiceStatus{
 						Loaded:  false,
@@ -1356,27 +2269,27 @@ func (s *Server) setupHandlers() {
 			}
 
 			status := s.audirvanaService.GetStatus()
-			log.Info().
+			cmd.Log.Info().
 				Bool("installed", status.Installed).
 				Bool("running", status.Service.Running).
 				Int("instances", len(status.Instances)).
 				Msg("pushAudirvanaStatus")
-			client.Emit("pushAudirvanaStatus", status)
+			cmd.Emit("pushAudirvanaStatus", status)
 		})
 
 		// Start Audirvana service
-		client.On("audirvanaStartService", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("audirvanaStartService requested")
+		s.on(client, "audirvanaStartService", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("audirvanaStartService requested")
 			if s.audirvanaService == nil {
-				client.Emit("pushAudirvanaStatus", audirvana.Status{
+				cmd.Emit("pushAudirvanaStatus", audirvana.Status{
 					Error: "audirvana service not available",
 				})
 				return
 			}
 
 			if err := s.audirvanaService.StartService(); err != nil {
-				log.Error().Err(err).Msg("Failed to start Audirvana service")
-				client.Emit("pushAudirvanaStatus", audirvana.Status{
+				cmd.Log.Error().Err(err).Msg("Failed to start Audirvana service")
+				cmd.Emit("pushAudirvanaStatus", audirvana.Status{
 					Error: "Failed to start service: " + err.Error(),
 				})
 				return
@@ -1385,24 +2298,24 @@ func (s *Server) setupHandlers() {
 			// Wait a moment for service to start, then get status
 			time.Sleep(2 * time.Second)
 			status := s.audirvanaService.GetStatus()
-			client.Emit("pushAudirvanaStatus", status)
+			cmd.Emit("pushAudirvanaStatus", status)
 			// Broadcast to all clients
-			s.io.Emit("pushAudirvanaStatus", status)
+			s.emitAll("pushAudirvanaStatus", status)
 		})
 
 		// Stop Audirvana service
-		client.On("audirvanaStopService", func(args ...any) {
-			log.Info().Str("id", clientID).Msg("audirvanaStopService requested")
+		s.on(client, "audirvanaStopService", func(cmd *Command, args ...any) {
+			cmd.Log.Info().Msg("audirvanaStopService requested")
 			if s.audirvanaService == nil {
-				client.Emit("pushAudirvanaStatus", audirvana.Status{
+				cmd.Emit("pushAudirvanaStatus", audirvana.Status{
 					Error: "audirvana service not available",
 				})
 				return
 			}
 
 			if err := s.audirvanaService.StopService(); err != nil {
-				log.Error().Err(err).Msg("Failed to stop Audirvana service")
-				client.Emit("pushAudirvanaStatus", audirvana.Status{
+				cmd.Log.Error().Err(err).Msg("Failed to stop Audirvana service")
+				cmd.Emit("pushAudirvanaStatus", audirvana.Status{
 					Error: "Failed to stop service: " + err.Error(),
 				})
 				return
@@ -1411,30 +2324,53 @@ func (s *Server) setupHandlers() {
 			// Wait a moment for service to stop, then get status
 			time.Sleep(1 * time.Second)
 			status := s.audirvanaService.GetStatus()
-			client.Emit("pushAudirvanaStatus", status)
+			cmd.Emit("pushAudirvanaStatus", status)
 			// Broadcast to all clients
-			s.io.Emit("pushAudirvanaStatus", status)
+			s.emitAll("pushAudirvanaStatus", status)
+		})
+
+		// ============================================================
+		// AirPlay Integration Events
+		// ============================================================
+
+		// Get AirPlay status (shairport-sync service and current session)
+		s.on(client, "getAirplayStatus", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("getAirplayStatus requested")
+			if s.airplayService == nil {
+				cmd.Emit("pushAirplayStatus", airplay.Status{
+					Error: "airplay service not available",
+				})
+				return
+			}
+
+			status := s.airplayService.GetStatus()
+			cmd.Log.Debug().
+				Bool("installed", status.Installed).
+				Bool("running", status.Service.Running).
+				Bool("active", status.Active).
+				Msg("pushAirplayStatus")
+			cmd.Emit("pushAirplayStatus", status)
 		})
 
 		// ==================== PLAYLIST HANDLERS ====================
 
 		// List all playlists
-		client.On("listPlaylist", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("listPlaylist requested")
+		s.on(client, "listPlaylist", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("listPlaylist requested")
 			playlists, err := s.mpdClient.ListPlaylists()
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to list playlists")
-				client.Emit("pushListPlaylist", []string{})
+				cmd.Log.Error().Err(err).Msg("Failed to list playlists")
+				cmd.Emit("pushListPlaylist", []string{})
 				return
 			}
-			client.Emit("pushListPlaylist", playlists)
+			cmd.Emit("pushListPlaylist", playlists)
 		})
 
 		// Create a new playlist (saves current queue)
-		client.On("createPlaylist", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("createPlaylist requested")
+		s.on(client, "createPlaylist", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("createPlaylist requested")
 			if len(args) == 0 {
-				log.Warn().Msg("createPlaylist: no arguments")
+				cmd.Log.Warn().Msg("createPlaylist: no arguments")
 				return
 			}
 
@@ -1447,8 +2383,8 @@ func (s *Server) setupHandlers() {
 			}
 
 			if name == "" {
-				log.Warn().Msg("createPlaylist: empty name")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Warn().Msg("createPlaylist: empty name")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Error",
 					"message": "Playlist name cannot be empty",
@@ -1458,8 +2394,8 @@ func (s *Server) setupHandlers() {
 
 			// Save current queue as playlist
 			if err := s.mpdClient.SavePlaylist(name); err != nil {
-				log.Error().Err(err).Str("name", name).Msg("Failed to create playlist")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Error().Err(err).Str("name", name).Msg("Failed to create playlist")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Error",
 					"message": "Failed to create playlist: " + err.Error(),
@@ -1467,8 +2403,8 @@ func (s *Server) setupHandlers() {
 				return
 			}
 
-			log.Info().Str("name", name).Msg("Playlist created")
-			client.Emit("pushToastMessage", map[string]interface{}{
+			cmd.Log.Info().Str("name", name).Msg("Playlist created")
+			cmd.Emit("pushToastMessage", map[string]interface{}{
 				"type":    "success",
 				"title":   "Playlist Created",
 				"message": "Playlist '" + name + "' created",
@@ -1476,14 +2412,14 @@ func (s *Server) setupHandlers() {
 
 			// Refresh playlist list
 			playlists, _ := s.mpdClient.ListPlaylists()
-			client.Emit("pushListPlaylist", playlists)
+			cmd.Emit("pushListPlaylist", playlists)
 		})
 
 		// Delete a playlist
-		client.On("deletePlaylist", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("deletePlaylist requested")
+		s.on(client, "deletePlaylist", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("deletePlaylist requested")
 			if len(args) == 0 {
-				log.Warn().Msg("deletePlaylist: no arguments")
+				cmd.Log.Warn().Msg("deletePlaylist: no arguments")
 				return
 			}
 
@@ -1496,13 +2432,13 @@ func (s *Server) setupHandlers() {
 			}
 
 			if name == "" {
-				log.Warn().Msg("deletePlaylist: empty name")
+				cmd.Log.Warn().Msg("deletePlaylist: empty name")
 				return
 			}
 
 			if err := s.mpdClient.DeletePlaylist(name); err != nil {
-				log.Error().Err(err).Str("name", name).Msg("Failed to delete playlist")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Error().Err(err).Str("name", name).Msg("Failed to delete playlist")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Error",
 					"message": "Failed to delete playlist: " + err.Error(),
@@ -1510,8 +2446,8 @@ func (s *Server) setupHandlers() {
 				return
 			}
 
-			log.Info().Str("name", name).Msg("Playlist deleted")
-			client.Emit("pushToastMessage", map[string]interface{}{
+			cmd.Log.Info().Str("name", name).Msg("Playlist deleted")
+			cmd.Emit("pushToastMessage", map[string]interface{}{
 				"type":    "success",
 				"title":   "Playlist Deleted",
 				"message": "Playlist '" + name + "' deleted",
@@ -1519,14 +2455,14 @@ func (s *Server) setupHandlers() {
 
 			// Refresh playlist list
 			playlists, _ := s.mpdClient.ListPlaylists()
-			client.Emit("pushListPlaylist", playlists)
+			cmd.Emit("pushListPlaylist", playlists)
 		})
 
 		// Play a playlist (load and start playing)
-		client.On("playPlaylist", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("playPlaylist requested")
+		s.on(client, "playPlaylist", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("playPlaylist requested")
 			if len(args) == 0 {
-				log.Warn().Msg("playPlaylist: no arguments")
+				cmd.Log.Warn().Msg("playPlaylist: no arguments")
 				return
 			}
 
@@ -1539,13 +2475,13 @@ func (s *Server) setupHandlers() {
 			}
 
 			if name == "" {
-				log.Warn().Msg("playPlaylist: empty name")
+				cmd.Log.Warn().Msg("playPlaylist: empty name")
 				return
 			}
 
 			if err := s.mpdClient.LoadPlaylist(name, true); err != nil {
-				log.Error().Err(err).Str("name", name).Msg("Failed to play playlist")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Error().Err(err).Str("name", name).Msg("Failed to play playlist")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Error",
 					"message": "Failed to play playlist: " + err.Error(),
@@ -1553,7 +2489,7 @@ func (s *Server) setupHandlers() {
 				return
 			}
 
-			log.Info().Str("name", name).Msg("Playing playlist")
+			cmd.Log.Info().Str("name", name).Msg("Playing playlist")
 
 			// Unicast to requesting client only; MPD watcher handles broadcast
 			s.pushState(client)
@@ -1561,16 +2497,16 @@ func (s *Server) setupHandlers() {
 		})
 
 		// Add item to a playlist
-		client.On("addToPlaylist", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("addToPlaylist requested")
+		s.on(client, "addToPlaylist", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("addToPlaylist requested")
 			if len(args) == 0 {
-				log.Warn().Msg("addToPlaylist: no arguments")
+				cmd.Log.Warn().Msg("addToPlaylist: no arguments")
 				return
 			}
 
 			data, ok := args[0].(map[string]interface{})
 			if !ok {
-				log.Warn().Msg("addToPlaylist: invalid argument type")
+				cmd.Log.Warn().Msg("addToPlaylist: invalid argument type")
 				return
 			}
 
@@ -1579,13 +2515,13 @@ func (s *Server) setupHandlers() {
 			title := getString(data, "title")
 
 			if playlistName == "" || uri == "" {
-				log.Warn().Msg("addToPlaylist: missing name or uri")
+				cmd.Log.Warn().Msg("addToPlaylist: missing name or uri")
 				return
 			}
 
 			if err := s.mpdClient.PlaylistAdd(playlistName, uri); err != nil {
-				log.Error().Err(err).Str("playlist", playlistName).Str("uri", uri).Msg("Failed to add to playlist")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Error().Err(err).Str("playlist", playlistName).Str("uri", uri).Msg("Failed to add to playlist")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Error",
 					"message": "Failed to add to playlist: " + err.Error(),
@@ -1597,8 +2533,8 @@ func (s *Server) setupHandlers() {
 			if displayName == "" {
 				displayName = uri
 			}
-			log.Info().Str("playlist", playlistName).Str("uri", uri).Msg("Added to playlist")
-			client.Emit("pushToastMessage", map[string]interface{}{
+			cmd.Log.Info().Str("playlist", playlistName).Str("uri", uri).Msg("Added to playlist")
+			cmd.Emit("pushToastMessage", map[string]interface{}{
 				"type":    "success",
 				"title":   "Added to Playlist",
 				"message": displayName + " added to '" + playlistName + "'",
@@ -1606,16 +2542,16 @@ func (s *Server) setupHandlers() {
 		})
 
 		// Remove item from a playlist
-		client.On("removeFromPlaylist", func(args ...any) {
-			log.Debug().Str("id", clientID).Msg("removeFromPlaylist requested")
+		s.on(client, "removeFromPlaylist", func(cmd *Command, args ...any) {
+			cmd.Log.Debug().Msg("removeFromPlaylist requested")
 			if len(args) == 0 {
-				log.Warn().Msg("removeFromPlaylist: no arguments")
+				cmd.Log.Warn().Msg("removeFromPlaylist: no arguments")
 				return
 			}
 
 			data, ok := args[0].(map[string]interface{})
 			if !ok {
-				log.Warn().Msg("removeFromPlaylist: invalid argument type")
+				cmd.Log.Warn().Msg("removeFromPlaylist: invalid argument type")
 				return
 			}
 
@@ -1623,15 +2559,15 @@ func (s *Server) setupHandlers() {
 			uri := getString(data, "uri")
 
 			if playlistName == "" || uri == "" {
-				log.Warn().Msg("removeFromPlaylist: missing name or uri")
+				cmd.Log.Warn().Msg("removeFromPlaylist: missing name or uri")
 				return
 			}
 
 			// Find the position of the song in the playlist
 			pos, err := s.mpdClient.FindSongInPlaylist(playlistName, uri)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to find song in playlist")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Error().Err(err).Msg("Failed to find song in playlist")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Error",
 					"message": "Failed to find song in playlist",
@@ -1640,8 +2576,8 @@ func (s *Server) setupHandlers() {
 			}
 
 			if pos < 0 {
-				log.Warn().Str("uri", uri).Msg("Song not found in playlist")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Warn().Str("uri", uri).Msg("Song not found in playlist")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Not Found",
 					"message": "Song not found in playlist",
@@ -1650,8 +2586,8 @@ func (s *Server) setupHandlers() {
 			}
 
 			if err := s.mpdClient.PlaylistDelete(playlistName, pos); err != nil {
-				log.Error().Err(err).Str("playlist", playlistName).Int("pos", pos).Msg("Failed to remove from playlist")
-				client.Emit("pushToastMessage", map[string]interface{}{
+				cmd.Log.Error().Err(err).Str("playlist", playlistName).Int("pos", pos).Msg("Failed to remove from playlist")
+				cmd.Emit("pushToastMessage", map[string]interface{}{
 					"type":    "error",
 					"title":   "Error",
 					"message": "Failed to remove from playlist: " + err.Error(),
@@ -1659,13 +2595,16 @@ func (s *Server) setupHandlers() {
 				return
 			}
 
-			log.Info().Str("playlist", playlistName).Str("uri", uri).Msg("Removed from playlist")
-			client.Emit("pushToastMessage", map[string]interface{}{
+			cmd.Log.Info().Str("playlist", playlistName).Str("uri", uri).Msg("Removed from playlist")
+			cmd.Emit("pushToastMessage", map[string]interface{}{
 				"type":    "success",
 				"title":   "Removed",
 				"message": "Item removed from '" + playlistName + "'",
 			})
 		})
+
+		// Send everything needed to render once every handler is registered
+		go s.pushInitialState(client)
 	})
 }
 
@@ -1694,6 +2633,40 @@ func getString(m map[string]interface{}, key string) string {
 	return ""
 }
 
+// parseAddNasShareRequest builds an AddNasShareRequest from Socket.io event data.
+func parseAddNasShareRequest(data map[string]interface{}) sources.AddNasShareRequest {
+	req := sources.AddNasShareRequest{
+		Name:     getString(data, "name"),
+		IP:       getString(data, "ip"),
+		Path:     getString(data, "path"),
+		FSType:   getString(data, "fstype"),
+		Username: getString(data, "username"),
+		Password: getString(data, "password"),
+		Options:  getString(data, "options"),
+	}
+	if readOnly, ok := data["readOnly"].(bool); ok {
+		req.ReadOnly = &readOnly
+	}
+	return req
+}
+
+// parseAlarm builds an Alarm from Socket.io event data.
+func parseAlarm(data map[string]interface{}) player.Alarm {
+	alarm := player.Alarm{
+		Time:   getString(data, "time"),
+		URI:    getString(data, "uri"),
+		Volume: getIntFromMap(data, "volume", 0),
+	}
+	if days, ok := data["days"].([]interface{}); ok {
+		for _, day := range days {
+			if d, ok := player.ParseCommandValue(day); ok {
+				alarm.Days = append(alarm.Days, d)
+			}
+		}
+	}
+	return alarm
+}
+
 // getBrowseSources returns the list of available music sources.
 func (s *Server) getBrowseSources() []map[string]interface{} {
 	sources := []map[string]interface{}{
@@ -1727,7 +2700,7 @@ func (s *Server) getBrowseSources() []map[string]interface{} {
 // broadcastBrowseSources sends updated browse sources to all clients.
 func (s *Server) broadcastBrowseSources() {
 	sources := s.getBrowseSources()
-	s.io.Emit("pushBrowseSources", sources)
+	s.emitAll("pushBrowseSources", sources)
 }
 
 // pushState sends current state to a client.
@@ -1737,9 +2710,20 @@ func (s *Server) pushState(client *socket.Socket) {
 		log.Error().Err(err).Msg("Failed to get state")
 		return
 	}
+	s.applyExternalSource(state)
+	explainStateError(state)
+	state.Revision = s.currentRevision()
 	client.Emit("pushState", state)
 }
 
+// explainStateError names the process holding the audio device when MPD
+// reports that it couldn't open the output.
+func explainStateError(state *player.PlayerState) {
+	if state.Error != "" {
+		state.Error = audio.ExplainPlaybackError(state.Error, audio.FindDeviceOwners())
+	}
+}
+
 // pushQueue sends current queue to a client.
 func (s *Server) pushQueue(client *socket.Socket) {
 	queue, err := s.playerService.GetQueue()
@@ -1757,31 +2741,24 @@ func (s *Server) BroadcastState() {
 		log.Error().Err(err).Msg("Failed to get state for broadcast")
 		return
 	}
+	s.applyExternalSource(state)
+	explainStateError(state)
 
 	// State diffing: skip broadcast if key fields haven't changed
-	if s.isStateSame(state) {
+	fields := state.Map()
+	if s.isStateSame(fields) {
 		log.Debug().Msg("State unchanged, skipping broadcast")
 		return
 	}
-	s.saveLastState(state)
+	s.saveLastState(fields)
+	state.Revision = s.nextRevision()
 
-	s.io.Emit("pushState", state)
+	s.emitAll("pushState", state)
 
 	// Update audio controller with current state
-	mpdState, _ := state["status"].(string)
-	audioFormat := ""
-	if sr, ok := state["samplerate"].(string); ok {
-		audioFormat = sr
-		if bd, ok := state["bitdepth"].(string); ok {
-			audioFormat += ":" + bd
-		}
-		if ch, ok := state["channels"].(string); ok {
-			audioFormat += ":" + ch
-		}
-	}
-
-	if s.audioController.UpdateFromMPDStatus(mpdState, audioFormat) {
+	if s.audioController.UpdateFromMPDStatus(state.Status, state.AudioFormat(), state.TrackType) {
 		s.BroadcastAudioStatus()
+		s.recheckAudioLock()
 	}
 
 	if log.Debug().Enabled() {
@@ -1800,7 +2777,8 @@ func (s *Server) BroadcastState() {
 var stateCompareKeys = []string{
 	"status", "position", "title", "artist", "album",
 	"volume", "duration", "random", "repeat", "repeatSingle",
-	"samplerate", "bitdepth", "trackType",
+	"samplerate", "bitdepth", "trackType", "externalSource", "error",
+	"queuePosition", "queueLength",
 }
 
 // isStateSame returns true if the new state matches the last broadcast state
@@ -1847,11 +2825,34 @@ func (s *Server) BroadcastQueue() {
 		return
 	}
 
-	s.io.Emit("pushQueue", queue)
+	// The queue payload is a bare list, so its revision follows separately
+	revision := s.nextRevision()
+	s.emitAll("pushQueue", queue)
+	s.emitAll("pushRevision", ResumeAck{Revision: revision})
+}
+
+// BroadcastLastPlayedTracks sends the recently played tracks to all connected clients,
+// using the same defaults as getLastPlayedTracks.
+func (s *Server) BroadcastLastPlayedTracks() {
+	if s.localMusicService == nil {
+		return
+	}
+
+	resp := s.localMusicService.GetLastPlayedTracks(localmusic.GetLastPlayedRequest{
+		Sort:  localmusic.TrackSortLastPlayed,
+		Limit: 50,
+	})
+	log.Debug().Int("trackCount", len(resp.Tracks)).Msg("Broadcasting pushLastPlayedTracks")
+	s.emitAll("pushLastPlayedTracks", resp)
 }
 
+// stateBroadcastInterval is how often state is broadcast at most while MPD
+// reports changes continuously, e.g. during a seek bar or volume drag.
+const stateBroadcastInterval = 100 * time.Millisecond
+
 // StartMPDWatcher starts watching MPD for changes and broadcasts updates.
-// Uses a debouncer to collapse rapid events (e.g., volume knob) into single broadcasts.
+// Uses a debouncer to collapse rapid events (e.g., volume knob) into single
+// broadcasts, sent at most every stateBroadcastInterval while they continue.
 func (s *Server) StartMPDWatcher(ctx context.Context) error {
 	subsystems := []string{"player", "mixer", "playlist", "options", "database"}
 	events, err := s.mpdClient.Watch(subsystems...)
@@ -1859,7 +2860,8 @@ func (s *Server) StartMPDWatcher(ctx context.Context) error {
 		return err
 	}
 
-	debouncer := NewBroadcastDebouncer(100*time.Millisecond, s.BroadcastState, s.BroadcastQueue)
+	debouncer := NewBroadcastDebouncer(stateBroadcastInterval, s.BroadcastState, s.BroadcastQueue).
+		WithMaxWait(stateBroadcastInterval)
 
 	go func() {
 		defer debouncer.Stop()
@@ -1890,8 +2892,13 @@ func (s *Server) StartMPDWatcher(ctx context.Context) error {
 	return nil
 }
 
-// handleDatabaseUpdate handles MPD database changes by rebuilding the library cache.
+// handleDatabaseUpdate handles MPD database changes by dropping the album
+// track listings kept in memory and rebuilding the library cache.
 func (s *Server) handleDatabaseUpdate() {
+	if s.localMusicService != nil {
+		s.localMusicService.InvalidateAlbumTracks()
+	}
+
 	if s.cachedService == nil {
 		return
 	}
@@ -1900,6 +2907,10 @@ func (s *Server) handleDatabaseUpdate() {
 
 	go func() {
 		if err := s.cachedService.RebuildCache(); err != nil {
+			if errors.Is(err, cache.ErrBuildInProgress) {
+				log.Info().Msg("Cache rebuild already in progress, skipping")
+				return
+			}
 			log.Error().Err(err).Msg("Failed to rebuild cache after database update")
 			return
 		}
@@ -1933,7 +2944,11 @@ func (s *Server) InitializeCache() {
 
 	// If cache is empty, trigger a background build
 	if stats.AlbumCount == 0 && stats.ArtistCount == 0 {
-		log.Info().Msg("Library cache is empty, triggering background build")
+		if s.cacheDB != nil && s.cacheDB.Recovered() {
+			log.Warn().Msg("Library cache was corrupt and has been recreated, rebuilding from MPD")
+		} else {
+			log.Info().Msg("Library cache is empty, triggering background build")
+		}
 		go func() {
 			if err := s.cachedService.RebuildCache(); err != nil {
 				log.Error().Err(err).Msg("Background cache rebuild failed")
@@ -1951,11 +2966,55 @@ func (s *Server) InitializeCache() {
 	}
 }
 
-// triggerEnrichment queues missing artwork for enrichment.
+// triggerEnrichment pre-warms artwork from local sources, then queues the
+// albums still missing artwork for web enrichment.
 func (s *Server) triggerEnrichment() {
-	if s.enrichmentHandlers != nil {
-		s.enrichmentHandlers.QueueMissingArtwork()
+	go func() {
+		s.prewarmArtwork()
+		if s.enrichmentHandlers != nil {
+			s.enrichmentHandlers.QueueMissingArtwork()
+		}
+	}()
+}
+
+// EnableArtworkPrewarm caches album artwork from the filesystem and MPD for
+// the whole library after each cache build, using concurrency workers.
+// Call before InitializeCache.
+func (s *Server) EnableArtworkPrewarm(finder artwork.FilesystemArtworkFinder, concurrency int) {
+	if s.cacheDAO == nil {
+		log.Warn().Msg("Artwork pre-warm disabled: no cache database")
+		return
+	}
+
+	resolver := artwork.NewResolver(s.mpdClient, artwork.NewCacheDAOAdapter(s.cacheDAO),
+		os.ExpandEnv("$HOME/stellar-backend/data/cache/artwork")).WithFilesystem(finder)
+	s.artworkPrewarmer = artwork.NewPrewarmer(resolver, concurrency).
+		WithBusy(func() bool {
+			status, err := s.mpdClient.Status()
+			return err == nil && status["state"] == "play"
+		}).
+		WithProgress(func(progress artwork.PrewarmProgress) {
+			s.emitAll("pushArtworkProgress", progress)
+		})
+	s.prewarmCtx, s.prewarmCancel = context.WithCancel(context.Background())
+}
+
+// prewarmArtwork caches artwork for albums that have none, blocking until done.
+func (s *Server) prewarmArtwork() {
+	if s.artworkPrewarmer == nil {
+		return
+	}
+
+	missing, err := s.cacheDAO.GetAlbumsWithoutArtwork()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to list albums for artwork pre-warm")
+		return
 	}
+	albums := make([]artwork.PrewarmAlbum, len(missing))
+	for i, a := range missing {
+		albums[i] = artwork.PrewarmAlbum{ID: a.ID, FirstTrack: a.FirstTrack}
+	}
+	s.artworkPrewarmer.Run(s.prewarmCtx, albums)
 }
 
 // broadcastCacheUpdated broadcasts cache update event to all clients.
@@ -1977,7 +3036,7 @@ func (s *Server) broadcastCacheUpdated() {
 		"artistCount": stats.ArtistCount,
 		"trackCount":  stats.TrackCount,
 	}
-	s.io.Emit("library:cache:updated", event)
+	s.emitAll("library:cache:updated", event)
 }
 
 // ArtistArtworkInfo contains artist artwork information for HTTP serving.
@@ -2048,6 +3107,9 @@ func (s *Server) GetArtistArtwork(artistID, artistName string) *ArtistArtworkInf
 // Close closes the Socket.io server and cache database.
 func (s *Server) Close() error {
 	s.io.Close(nil)
+	if s.prewarmCancel != nil {
+		s.prewarmCancel()
+	}
 	if s.enrichmentHandlers != nil {
 		s.enrichmentHandlers.Close()
 	}
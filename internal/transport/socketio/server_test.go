@@ -106,27 +106,6 @@ func TestServerBroadcastLCDStatusWithoutClients(t *testing.T) {
 	server.BroadcastLCDStatus()
 }
 
-func TestGetNetworkStatus(t *testing.T) {
-	// GetNetworkStatus should return a valid NetworkStatus struct
-	status := socketio.GetNetworkStatus()
-
-	// Type should be one of: wifi, ethernet, none
-	validTypes := map[string]bool{"wifi": true, "ethernet": true, "none": true}
-	if !validTypes[status.Type] {
-		t.Errorf("Invalid network type: %s", status.Type)
-	}
-
-	// Strength should be 0-3
-	if status.Strength < 0 || status.Strength > 3 {
-		t.Errorf("Invalid strength: %d (should be 0-3)", status.Strength)
-	}
-
-	// Signal should be 0-100
-	if status.Signal < 0 || status.Signal > 100 {
-		t.Errorf("Invalid signal: %d (should be 0-100)", status.Signal)
-	}
-}
-
 func TestGetLCDStatus(t *testing.T) {
 	// GetLCDStatus should return a valid LCDStatus struct
 	status := socketio.GetLCDStatus()
@@ -630,6 +609,149 @@ pcm.!default {
 	}
 }
 
+// dspMPDConfig plays through the ALSA default PCM, so ALSA plugins apply
+const dspMPDConfig = `
+audio_output {
+	type            "alsa"
+	device          "default"
+	mixer_type      "none"
+}
+`
+
+// expectALSADSPIssue checks that an ALSA plugin type is reported as an issue.
+func expectALSADSPIssue(t *testing.T, alsaConfig, plugin string) {
+	t.Helper()
+	status := socketio.CheckBitPerfectFromConfig(dspMPDConfig, alsaConfig, "")
+
+	if status.Status != "error" {
+		t.Errorf("Expected status 'error' for '%s' plugin, got '%s'", plugin, status.Status)
+	}
+	found := false
+	for _, issue := range status.Issues {
+		if contains(issue, "'"+plugin+"'") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("Expected '%s' plugin issue in: %v", plugin, status.Issues)
+	}
+}
+
+func TestCheckBitPerfectFromConfig_ALSALadspa(t *testing.T) {
+	alsaConfig := `
+pcm.!default {
+	type plug
+	slave.pcm "ladspa"
+}
+pcm.ladspa {
+	type ladspa
+	slave.pcm "hw:0,0"
+	path "/usr/lib/ladspa"
+	plugins [ { label dysonCompress } ]
+}
+`
+	expectALSADSPIssue(t, alsaConfig, "ladspa")
+}
+
+func TestCheckBitPerfectFromConfig_ALSAEqualizer(t *testing.T) {
+	// alsaequal names its plugin "equal"
+	expectALSADSPIssue(t, `
+pcm.!default {
+	type "equal"
+	slave.pcm "plughw:0,0"
+}
+`, "equal")
+
+	expectALSADSPIssue(t, `pcm.eq { type eq slave.pcm "hw:0,0" }`, "eq")
+}
+
+func TestCheckBitPerfectFromConfig_ALSACrossfeed(t *testing.T) {
+	expectALSADSPIssue(t, `
+pcm.!default {
+	type bs2b
+	slave.pcm "hw:0,0"
+}
+`, "bs2b")
+
+	expectALSADSPIssue(t, `
+pcm.headphones {
+	type crossfeed
+	slave.pcm "hw:0,0"
+}
+`, "crossfeed")
+}
+
+func TestCheckBitPerfectFromConfig_ALSAVdownmix(t *testing.T) {
+	expectALSADSPIssue(t, `
+pcm.!default {
+	type vdownmix
+	slave.pcm "hw:0,0"
+}
+`, "vdownmix")
+}
+
+func TestCheckBitPerfectFromConfig_ALSARate(t *testing.T) {
+	expectALSADSPIssue(t, `
+pcm.!default {
+	type rate
+	slave {
+		pcm "hw:0,0"
+		rate 48000
+	}
+}
+`, "rate")
+}
+
+func TestCheckBitPerfectFromConfig_ALSADSPCommentedOut(t *testing.T) {
+	alsaConfig := `
+# pcm.eq { type equal }
+pcm.!default {
+	type hw
+	card 0
+}
+`
+	status := socketio.CheckBitPerfectFromConfig(dspMPDConfig, alsaConfig, "")
+
+	for _, issue := range status.Issues {
+		if contains(issue, "plugin") {
+			t.Errorf("Unexpected plugin issue for commented-out config: %s", issue)
+		}
+	}
+}
+
+func TestCheckBitPerfectFromConfig_ALSADSPBypassedByHardware(t *testing.T) {
+	// MPD writes to hw: directly, so the DSP chain is not in the signal path
+	mpdConfig := `
+audio_output {
+	type            "alsa"
+	device          "hw:0,0"
+	mixer_type      "none"
+}
+`
+	alsaConfig := `
+pcm.!default {
+	type ladspa
+	slave.pcm "hw:0,0"
+}
+`
+	status := socketio.CheckBitPerfectFromConfig(mpdConfig, alsaConfig, "")
+
+	if len(status.Issues) > 0 {
+		t.Errorf("Expected no issues with direct hardware output, got: %v", status.Issues)
+	}
+	found := false
+	for _, warning := range status.Warnings {
+		if contains(warning, "'ladspa'") && contains(warning, "bypassed") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("Expected bypassed 'ladspa' warning in: %v", status.Warnings)
+	}
+}
+
 func TestCheckBitPerfectFromConfig_USBDACDetected(t *testing.T) {
 	// Test USB DAC detection (Singxer SU-6)
 	mpdConfig := `
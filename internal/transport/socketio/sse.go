@@ -0,0 +1,195 @@
+package socketio
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/system/network"
+)
+
+const (
+	// sseKeepAliveInterval is how often an idle SSE stream gets a comment line
+	// so proxies and clients don't time it out.
+	sseKeepAliveInterval = 15 * time.Second
+
+	// sseBufferSize is how many events a slow SSE subscriber can lag behind
+	// before further events are dropped for it.
+	sseBufferSize = 32
+)
+
+// sseEvents are the broadcasts forwarded to SSE subscribers.
+var sseEvents = map[string]bool{
+	"pushState":         true,
+	"pushQueue":         true,
+	"pushNetworkStatus": true,
+}
+
+// EventMessage is a broadcast event with its JSON-encoded payload.
+type EventMessage struct {
+	Name string
+	Data []byte
+}
+
+// EventHub fans broadcasts out to non-Socket.io subscribers such as SSE
+// streams. Slow subscribers drop events rather than blocking broadcasts.
+type EventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan EventMessage]struct{}
+}
+
+// NewEventHub creates an empty event hub.
+func NewEventHub() *EventHub {
+	return &EventHub{
+		subscribers: make(map[chan EventMessage]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber. Call the returned function to unsubscribe.
+func (h *EventHub) Subscribe() (<-chan EventMessage, func()) {
+	ch := make(chan EventMessage, sseBufferSize)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends an event to every subscriber. The payload is encoded once.
+func (h *EventHub) Publish(name string, payload interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.subscribers) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Error().Err(err).Str("event", name).Msg("Failed to encode event for subscribers")
+		return
+	}
+
+	msg := EventMessage{Name: name, Data: data}
+	for ch := range h.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			log.Debug().Str("event", name).Msg("Event subscriber is behind, dropping event")
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscribers.
+func (h *EventHub) SubscriberCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// Events returns the hub that receives state, queue and network broadcasts.
+func (s *Server) Events() *EventHub {
+	return s.events
+}
+
+// emitAll broadcasts an event to all Socket.io clients and, for the events
+// SSE streams carry, to event hub subscribers. Socket.io clients are sent it
+// through their send queues, so a slow client falls behind on its own.
+func (s *Server) emitAll(event string, payload interface{}) {
+	s.mu.RLock()
+	for _, sender := range s.senders {
+		sender.Send(event, payload)
+	}
+	s.mu.RUnlock()
+	if s.events != nil && sseEvents[event] {
+		s.events.Publish(event, payload)
+	}
+}
+
+// ServeEvents streams pushState, pushQueue and pushNetworkStatus as named
+// Server-Sent Events for clients that can't speak Socket.io.
+func (s *Server) ServeEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Debug().Err(err).Msg("Failed to clear SSE write deadline")
+	}
+
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	log.Info().Str("remote", r.RemoteAddr).Msg("SSE client connected")
+	defer log.Info().Str("remote", r.RemoteAddr).Msg("SSE client disconnected")
+
+	// Send the current snapshot, as a new Socket.io client gets on connect
+	if s.playerService != nil {
+		if state, err := s.playerService.GetState(); err == nil {
+			s.applyExternalSource(state)
+			explainStateError(state)
+			writeSSE(w, "pushState", state)
+		}
+		if queue, err := s.playerService.GetQueue(); err == nil {
+			writeSSE(w, "pushQueue", queue)
+		}
+	}
+	writeSSE(w, "pushNetworkStatus", network.GetStatus())
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-events:
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Name, msg.Data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE writes a single named event.
+func writeSSE(w http.ResponseWriter, event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Error().Err(err).Str("event", event).Msg("Failed to encode SSE event")
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,132 @@
+package socketio
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventHub_PublishSubscribe(t *testing.T) {
+	hub := NewEventHub()
+
+	events, unsubscribe := hub.Subscribe()
+	if hub.SubscriberCount() != 1 {
+		t.Fatalf("SubscriberCount = %d, want 1", hub.SubscriberCount())
+	}
+
+	hub.Publish("pushState", map[string]interface{}{"status": "play"})
+
+	select {
+	case msg := <-events:
+		if msg.Name != "pushState" || string(msg.Data) != `{"status":"play"}` {
+			t.Errorf("got %s %s, want pushState {\"status\":\"play\"}", msg.Name, msg.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+	if hub.SubscriberCount() != 0 {
+		t.Errorf("SubscriberCount after unsubscribe = %d, want 0", hub.SubscriberCount())
+	}
+}
+
+func TestEventHub_SlowSubscriberDropsEvents(t *testing.T) {
+	hub := NewEventHub()
+	events, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	// Publishing never blocks, even when the subscriber is not reading
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < sseBufferSize*2; i++ {
+			hub.Publish("pushQueue", []int{i})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+
+	if len(events) != sseBufferSize {
+		t.Errorf("buffered %d events, want %d", len(events), sseBufferSize)
+	}
+}
+
+func TestServeEvents_StreamsBroadcasts(t *testing.T) {
+	s := &Server{events: NewEventHub()}
+
+	srv := httptest.NewServer(http.HandlerFunc(s.ServeEvents))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() (string, string) {
+		var name, data string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "" && name != "":
+				return name, data
+			}
+		}
+	}
+
+	// Initial snapshot
+	if name, _ := readEvent(); name != "pushNetworkStatus" {
+		t.Errorf("first event = %q, want pushNetworkStatus", name)
+	}
+
+	s.events.Publish("pushQueue", []string{"a.flac"})
+	if name, data := readEvent(); name != "pushQueue" || data != `["a.flac"]` {
+		t.Errorf("got %s %s, want pushQueue [\"a.flac\"]", name, data)
+	}
+
+	// Disconnecting unsubscribes the stream
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for s.events.SubscriberCount() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s.events.SubscriberCount() != 0 {
+		t.Error("subscriber not removed after client disconnect")
+	}
+}
+
+func TestServeEvents_RejectsNonGet(t *testing.T) {
+	s := &Server{events: NewEventHub()}
+
+	rec := httptest.NewRecorder()
+	s.ServeEvents(rec, httptest.NewRequest(http.MethodPost, "/api/v1/events", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
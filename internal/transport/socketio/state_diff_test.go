@@ -101,3 +101,24 @@ func TestIsStateSame_TitleChange_ReturnsFalse(t *testing.T) {
 		t.Error("isStateSame should return false when title changed")
 	}
 }
+
+func TestIsStateSame_QueueLengthChange_ReturnsFalse(t *testing.T) {
+	s := &Server{}
+
+	s.saveLastState(map[string]interface{}{
+		"status":        "play",
+		"position":      2,
+		"queuePosition": 2,
+		"queueLength":   5,
+	})
+
+	// Adding to the queue changes nothing else, but clients need the new length
+	if s.isStateSame(map[string]interface{}{
+		"status":        "play",
+		"position":      2,
+		"queuePosition": 2,
+		"queueLength":   6,
+	}) {
+		t.Error("isStateSame should return false when queueLength changed")
+	}
+}
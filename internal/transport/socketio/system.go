@@ -21,6 +21,13 @@ type SystemInfo struct {
 	Hardware      string `json:"hardware"`      // Hardware model
 }
 
+// SetHostnameResponse is the pushSetHostname payload.
+type SetHostnameResponse struct {
+	Success  bool   `json:"success"`
+	Hostname string `json:"hostname"`
+	Error    string `json:"error,omitempty"`
+}
+
 // GetSystemInfo returns basic system information.
 func GetSystemInfo() SystemInfo {
 	info := SystemInfo{
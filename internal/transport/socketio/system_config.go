@@ -0,0 +1,103 @@
+package socketio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigWriter defines the interface for reading and writing system config files.
+// This allows for mocking in tests and different permission models.
+type ConfigWriter interface {
+	// ReadFile returns the contents of a config file.
+	ReadFile(path string) ([]byte, error)
+
+	// WriteFile replaces the contents of a config file.
+	WriteFile(path string, content []byte) error
+
+	// CopyFile copies a config file, preserving its mode.
+	CopyFile(src, dst string) error
+
+	// RemoveFile removes a config file. Missing files are not an error.
+	RemoveFile(path string) error
+
+	// Glob returns the config files matching a pattern.
+	Glob(pattern string) ([]string, error)
+}
+
+// ServiceManager defines the interface for controlling system services.
+type ServiceManager interface {
+	// Restart restarts the named service.
+	Restart(service string) error
+}
+
+// SudoConfigWriter implements ConfigWriter using sudo for writes to
+// root-owned files such as /etc/mpd.conf. Reads go straight to the filesystem.
+type SudoConfigWriter struct{}
+
+// NewSudoConfigWriter creates a new sudo-based config writer.
+func NewSudoConfigWriter() *SudoConfigWriter {
+	return &SudoConfigWriter{}
+}
+
+// ReadFile reads a config file directly.
+func (w *SudoConfigWriter) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// WriteFile writes a config file via `sudo tee`.
+func (w *SudoConfigWriter) WriteFile(path string, content []byte) error {
+	cmd := exec.Command("sudo", "tee", path)
+	cmd.Stdin = strings.NewReader(string(content))
+	cmd.Stdout = nil
+	return cmd.Run()
+}
+
+// CopyFile copies a config file via `sudo cp -p`.
+func (w *SudoConfigWriter) CopyFile(src, dst string) error {
+	if output, err := exec.Command("sudo", "cp", "-p", src, dst).CombinedOutput(); err != nil {
+		return fmt.Errorf("copy failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// RemoveFile removes a config file via `sudo rm -f`.
+func (w *SudoConfigWriter) RemoveFile(path string) error {
+	if output, err := exec.Command("sudo", "rm", "-f", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("remove failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Glob lists config files matching a pattern.
+func (w *SudoConfigWriter) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// CommandServiceManager implements ServiceManager by running a fixed command
+// with the service name appended as the final argument.
+type CommandServiceManager struct {
+	command []string
+}
+
+// NewCommandServiceManager creates a service manager that restarts services
+// with the given command, e.g. "sudo sv restart" on runit systems.
+func NewCommandServiceManager(command ...string) *CommandServiceManager {
+	return &CommandServiceManager{command: command}
+}
+
+// NewSystemdServiceManager creates a service manager using `sudo systemctl restart`.
+func NewSystemdServiceManager() *CommandServiceManager {
+	return NewCommandServiceManager("sudo", "systemctl", "restart")
+}
+
+// Restart runs the restart command for the named service.
+func (m *CommandServiceManager) Restart(service string) error {
+	if len(m.command) == 0 {
+		return fmt.Errorf("no restart command configured")
+	}
+	args := append(append([]string{}, m.command[1:]...), service)
+	return exec.Command(m.command[0], args...).Run()
+}
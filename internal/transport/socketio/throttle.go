@@ -0,0 +1,64 @@
+package socketio
+
+import (
+	"sync"
+	"time"
+)
+
+// BroadcastThrottler limits a broadcast to at most once per interval.
+// The first trigger schedules the callback at the end of the interval and
+// further triggers before then are absorbed, so the broadcast always reflects
+// the latest state without flooding clients.
+type BroadcastThrottler struct {
+	interval time.Duration
+	callback func()
+
+	mu      sync.Mutex
+	pending bool
+	timer   *time.Timer
+	stopped bool
+}
+
+// NewBroadcastThrottler creates a throttler that calls callback at most once per interval.
+func NewBroadcastThrottler(interval time.Duration, callback func()) *BroadcastThrottler {
+	return &BroadcastThrottler{
+		interval: interval,
+		callback: callback,
+	}
+}
+
+// Trigger requests a broadcast. It is a no-op if one is already scheduled.
+func (t *BroadcastThrottler) Trigger() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped || t.pending {
+		return
+	}
+
+	t.pending = true
+	t.timer = time.AfterFunc(t.interval, t.flush)
+}
+
+// flush fires the callback and allows the next trigger to schedule another.
+func (t *BroadcastThrottler) flush() {
+	t.mu.Lock()
+	t.pending = false
+	t.mu.Unlock()
+
+	if t.callback != nil {
+		t.callback()
+	}
+}
+
+// Stop prevents any further callbacks from firing.
+func (t *BroadcastThrottler) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.pending = false
+}
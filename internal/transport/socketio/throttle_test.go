@@ -0,0 +1,57 @@
+package socketio
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottlerRapidTriggersCollapseToOne(t *testing.T) {
+	var calls int32
+
+	th := NewBroadcastThrottler(50*time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+	defer th.Stop()
+
+	for i := 0; i < 10; i++ {
+		th.Trigger()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("expected 1 callback, got %d", got)
+	}
+}
+
+func TestThrottlerFiresDuringContinuousTriggers(t *testing.T) {
+	var calls int32
+
+	th := NewBroadcastThrottler(30*time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+	defer th.Stop()
+
+	// Trigger continuously for well over one interval; unlike a debouncer,
+	// the throttler must not wait for the triggers to stop.
+	for i := 0; i < 20; i++ {
+		th.Trigger()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if got := atomic.LoadInt32(&calls); got < 1 {
+		t.Errorf("expected callbacks while triggers continue, got %d", got)
+	}
+}
+
+func TestThrottlerStopPreventsCallback(t *testing.T) {
+	var calls int32
+
+	th := NewBroadcastThrottler(30*time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+	th.Trigger()
+	th.Stop()
+	th.Trigger()
+
+	time.Sleep(60 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("expected 0 callbacks after Stop, got %d", got)
+	}
+}
@@ -0,0 +1,29 @@
+package socketio
+
+import (
+	"context"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/sources"
+	"github.com/rs/zerolog/log"
+)
+
+// StartUsbWatcher starts USB hotplug detection. When drives are attached or
+// removed, MPD rescans its library and clients receive pushUsbDevices.
+// Follows the same pattern as StartMountWatcher.
+func (s *Server) StartUsbWatcher(ctx context.Context) {
+	if s.sourcesService == nil {
+		log.Debug().Msg("USB watcher not started: sources service not available")
+		return
+	}
+
+	s.sourcesService.StartUsbWatcher(ctx, sources.DefaultUsbPollInterval, func(drives []sources.UsbDrive) {
+		log.Info().Int("count", len(drives)).Msg("USB devices changed")
+
+		// Trigger MPD database update
+		if _, err := s.mpdClient.Update(""); err != nil {
+			log.Warn().Err(err).Msg("USB watcher: MPD update failed")
+		}
+
+		s.emitAll("pushUsbDevices", drives)
+	})
+}
@@ -3,7 +3,6 @@
 package socketio
 
 import (
-	"github.com/rs/zerolog/log"
 	"github.com/zishang520/socket.io/servers/socket/v3"
 
 	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/domain/device"
@@ -28,33 +27,31 @@ func NewVolumioHandlers(deviceSvc *device.Service, playerSvc *player.Service, se
 
 // RegisterHandlers registers all Volumio-specific Socket.IO event handlers.
 func (h *VolumioHandlers) RegisterHandlers(client *socket.Socket) {
-	clientID := string(client.Id())
-
 	// Device discovery events
-	h.registerDeviceHandlers(client, clientID)
+	h.registerDeviceHandlers(client)
 
 	// Player control events
-	h.registerPlayerHandlers(client, clientID)
+	h.registerPlayerHandlers(client)
 
 	// Queue manipulation events
-	h.registerQueueHandlers(client, clientID)
+	h.registerQueueHandlers(client)
 }
 
 // registerDeviceHandlers registers device identity and discovery handlers.
-func (h *VolumioHandlers) registerDeviceHandlers(client *socket.Socket, clientID string) {
+func (h *VolumioHandlers) registerDeviceHandlers(client *socket.Socket) {
 	// initSocket - Register connecting device (acknowledgment for Volumio Connect apps)
-	client.On("initSocket", func(args ...any) {
-		log.Debug().Str("id", clientID).Interface("data", args).Msg("initSocket")
+	h.server.on(client, "initSocket", func(cmd *Command, args ...any) {
+		cmd.Log.Debug().Interface("data", args).Msg("initSocket")
 		// No-op for now - this is used by Volumio Connect apps to register themselves
 		// We just acknowledge the connection
 	})
 
 	// getDeviceInfo - Return device UUID and name
-	client.On("getDeviceInfo", func(args ...any) {
-		log.Debug().Str("id", clientID).Msg("getDeviceInfo")
+	h.server.on(client, "getDeviceInfo", func(cmd *Command, args ...any) {
+		cmd.Log.Debug().Msg("getDeviceInfo")
 
 		if h.deviceService == nil {
-			client.Emit("pushDeviceInfo", map[string]interface{}{
+			cmd.Emit("pushDeviceInfo", map[string]interface{}{
 				"uuid": "",
 				"name": "Stellar",
 			})
@@ -62,24 +59,25 @@ func (h *VolumioHandlers) registerDeviceHandlers(client *socket.Socket, clientID
 		}
 
 		info := h.deviceService.GetDeviceInfo()
-		client.Emit("pushDeviceInfo", map[string]interface{}{
+		cmd.Emit("pushDeviceInfo", map[string]interface{}{
 			"uuid": info.UUID,
 			"name": info.Name,
 		})
 	})
 
 	// getMultiRoomDevices - Return self in device list (single device mode)
-	client.On("getMultiRoomDevices", func(args ...any) {
-		log.Debug().Str("id", clientID).Msg("getMultiRoomDevices")
+	h.server.on(client, "getMultiRoomDevices", func(cmd *Command, args ...any) {
+		cmd.Log.Debug().Msg("getMultiRoomDevices")
 
 		// Get current player state for the device list
 		var state map[string]interface{}
 		if h.playerService != nil {
-			var err error
-			state, err = h.playerService.GetState()
+			playerState, err := h.playerService.GetState()
 			if err != nil {
-				log.Warn().Err(err).Msg("Failed to get state for multi-room device list")
+				cmd.Log.Warn().Err(err).Msg("Failed to get state for multi-room device list")
 				state = map[string]interface{}{}
+			} else {
+				state = playerState.Map()
 			}
 		}
 
@@ -103,35 +101,35 @@ func (h *VolumioHandlers) registerDeviceHandlers(client *socket.Socket, clientID
 			"list": []map[string]interface{}{deviceEntry},
 		}
 
-		client.Emit("pushMultiRoomDevices", response)
+		cmd.Emit("pushMultiRoomDevices", response)
 	})
 }
 
 // registerPlayerHandlers registers Volumio-specific player control handlers.
-func (h *VolumioHandlers) registerPlayerHandlers(client *socket.Socket, clientID string) {
+func (h *VolumioHandlers) registerPlayerHandlers(client *socket.Socket) {
 	// toggle - Play/pause toggle (commonly used by Volumio Connect apps)
-	client.On("toggle", func(args ...any) {
-		log.Debug().Str("id", clientID).Msg("toggle")
+	h.server.on(client, "toggle", func(cmd *Command, args ...any) {
+		cmd.Log.Debug().Msg("toggle")
 
 		if h.playerService == nil {
-			log.Warn().Msg("Player service not available for toggle")
+			cmd.Log.Warn().Msg("Player service not available for toggle")
 			return
 		}
 
 		if err := h.playerService.Toggle(); err != nil {
-			log.Error().Err(err).Msg("Toggle failed")
+			cmd.Log.Error().Err(err).Msg("Toggle failed")
 		}
 	})
 }
 
 // registerQueueHandlers registers queue manipulation handlers.
-func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket, clientID string) {
+func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket) {
 	// addPlay - Add to queue and play immediately
-	client.On("addPlay", func(args ...any) {
-		log.Debug().Str("id", clientID).Interface("data", args).Msg("addPlay")
+	h.server.on(client, "addPlay", func(cmd *Command, args ...any) {
+		cmd.Log.Debug().Interface("data", args).Msg("addPlay")
 
 		if h.playerService == nil {
-			log.Warn().Msg("Player service not available for addPlay")
+			cmd.Log.Warn().Msg("Player service not available for addPlay")
 			return
 		}
 
@@ -139,7 +137,7 @@ func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket, clientID
 			if m, ok := args[0].(map[string]interface{}); ok {
 				if uri, ok := m["uri"].(string); ok && uri != "" {
 					if err := h.playerService.AddAndPlay(uri); err != nil {
-						log.Error().Err(err).Str("uri", uri).Msg("AddPlay failed")
+						cmd.Log.Error().Err(err).Str("uri", uri).Msg("AddPlay failed")
 					}
 					// MPD watcher handles broadcast via debouncer
 				}
@@ -148,19 +146,19 @@ func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket, clientID
 	})
 
 	// playNext / addToQueueNext - Insert as next track
-	client.On("playNext", func(args ...any) {
-		h.handlePlayNext(args, clientID)
+	h.server.on(client, "playNext", func(cmd *Command, args ...any) {
+		h.handlePlayNext(cmd, args)
 	})
-	client.On("addToQueueNext", func(args ...any) {
-		h.handlePlayNext(args, clientID)
+	h.server.on(client, "addToQueueNext", func(cmd *Command, args ...any) {
+		h.handlePlayNext(cmd, args)
 	})
 
 	// moveQueue - Reorder queue items
-	client.On("moveQueue", func(args ...any) {
-		log.Debug().Str("id", clientID).Interface("data", args).Msg("moveQueue")
+	h.server.on(client, "moveQueue", func(cmd *Command, args ...any) {
+		cmd.Log.Debug().Interface("data", args).Msg("moveQueue")
 
 		if h.playerService == nil {
-			log.Warn().Msg("Player service not available for moveQueue")
+			cmd.Log.Warn().Msg("Player service not available for moveQueue")
 			return
 		}
 
@@ -171,7 +169,7 @@ func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket, clientID
 
 				if from >= 0 && to >= 0 {
 					if err := h.playerService.MoveQueueItem(from, to); err != nil {
-						log.Error().Err(err).Int("from", from).Int("to", to).Msg("MoveQueue failed")
+						cmd.Log.Error().Err(err).Int("from", from).Int("to", to).Msg("MoveQueue failed")
 					}
 					// MPD watcher handles broadcast via debouncer
 				}
@@ -180,11 +178,11 @@ func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket, clientID
 	})
 
 	// removeFromQueue - Remove item from queue
-	client.On("removeFromQueue", func(args ...any) {
-		log.Debug().Str("id", clientID).Interface("data", args).Msg("removeFromQueue")
+	h.server.on(client, "removeFromQueue", func(cmd *Command, args ...any) {
+		cmd.Log.Debug().Interface("data", args).Msg("removeFromQueue")
 
 		if h.playerService == nil {
-			log.Warn().Msg("Player service not available for removeFromQueue")
+			cmd.Log.Warn().Msg("Player service not available for removeFromQueue")
 			return
 		}
 
@@ -206,7 +204,7 @@ func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket, clientID
 
 			if pos >= 0 {
 				if err := h.playerService.RemoveQueueItem(pos); err != nil {
-					log.Error().Err(err).Int("position", pos).Msg("RemoveFromQueue failed")
+					cmd.Log.Error().Err(err).Int("position", pos).Msg("RemoveFromQueue failed")
 				}
 				// MPD watcher handles broadcast via debouncer
 			}
@@ -215,11 +213,11 @@ func (h *VolumioHandlers) registerQueueHandlers(client *socket.Socket, clientID
 }
 
 // handlePlayNext handles the playNext/addToQueueNext event.
-func (h *VolumioHandlers) handlePlayNext(args []any, clientID string) {
-	log.Debug().Str("id", clientID).Interface("data", args).Msg("playNext")
+func (h *VolumioHandlers) handlePlayNext(cmd *Command, args []any) {
+	cmd.Log.Debug().Interface("data", args).Msg("playNext")
 
 	if h.playerService == nil {
-		log.Warn().Msg("Player service not available for playNext")
+		cmd.Log.Warn().Msg("Player service not available for playNext")
 		return
 	}
 
@@ -227,9 +225,14 @@ func (h *VolumioHandlers) handlePlayNext(args []any, clientID string) {
 		if m, ok := args[0].(map[string]interface{}); ok {
 			if uri, ok := m["uri"].(string); ok && uri != "" {
 				if err := h.playerService.InsertNext(uri); err != nil {
-					log.Error().Err(err).Str("uri", uri).Msg("PlayNext failed")
+					cmd.Log.Error().Err(err).Str("uri", uri).Msg("PlayNext failed")
+					return
+				}
+				// Push the new order straight away rather than waiting on the
+				// debounced MPD watcher, so the track shows up as next at once
+				if h.server != nil {
+					h.server.BroadcastQueue()
 				}
-				// MPD watcher handles broadcast via debouncer
 			}
 		}
 	}
@@ -0,0 +1,255 @@
+package socketio
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// wifiConnectTimeout bounds joining a network, which includes the
+	// handshake and getting an address.
+	wifiConnectTimeout = 45 * time.Second
+
+	// wifiConnectPoll is how often wpa_supplicant setups are checked for the
+	// new connection.
+	wifiConnectPoll = time.Second
+)
+
+// wifiConnectMu keeps connects from different clients from interleaving.
+var wifiConnectMu sync.Mutex
+
+// errWifiAuth is returned when the network rejects the password.
+var errWifiAuth = errors.New("incorrect Wi-Fi password")
+
+// WifiConnectResponse is the pushWifiConnect payload.
+type WifiConnectResponse struct {
+	Success bool   `json:"success"`
+	SSID    string `json:"ssid"`
+	Error   string `json:"error,omitempty"`
+}
+
+// validateWifiCredentials checks an SSID and password before any system
+// configuration is touched. An empty password joins an open network.
+func validateWifiCredentials(ssid, password string) error {
+	if ssid == "" || len(ssid) > 32 {
+		return fmt.Errorf("SSID must be 1-32 bytes")
+	}
+	if password == "" {
+		return nil
+	}
+	if len(password) == 64 && isHex(password) {
+		return nil // Raw PSK
+	}
+	if len(password) < 8 || len(password) > 63 {
+		return fmt.Errorf("password must be 8-63 characters")
+	}
+	for _, r := range password {
+		if r < ' ' || r > '~' {
+			return fmt.Errorf("password may only contain printable ASCII characters")
+		}
+	}
+	return nil
+}
+
+// isHex reports whether s consists of hex digits only.
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ConnectWifi joins a Wi-Fi network and saves it so it is rejoined after a
+// reboot. NetworkManager is used where it runs, as on current Raspberry Pi
+// OS; otherwise the network is added through wpa_supplicant. Both are
+// driven through sudo.
+func ConnectWifi(ctx context.Context, ssid, password string) error {
+	if err := validateWifiCredentials(ssid, password); err != nil {
+		return err
+	}
+	iface := wirelessInterface()
+	if iface == "" {
+		return fmt.Errorf("no Wi-Fi interface found")
+	}
+
+	wifiConnectMu.Lock()
+	defer wifiConnectMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, wifiConnectTimeout)
+	defer cancel()
+
+	if _, err := exec.LookPath("nmcli"); err == nil {
+		return connectNetworkManager(ctx, iface, ssid, password)
+	}
+	if _, err := exec.LookPath("wpa_cli"); err == nil {
+		return connectWpaSupplicant(ctx, iface, ssid, password)
+	}
+	return fmt.Errorf("neither NetworkManager nor wpa_supplicant is available")
+}
+
+// connectNetworkManager joins the network with nmcli, which saves it as a
+// connection profile. A password is answered to nmcli's prompt on stdin
+// rather than passed as an argument, where any local user could read it.
+func connectNetworkManager(ctx context.Context, iface, ssid, password string) error {
+	args := []string{"nmcli", "--wait", strconv.Itoa(int(wifiConnectTimeout.Seconds()))}
+	if password != "" {
+		args = append(args, "--ask")
+	}
+	args = append(args, "device", "wifi", "connect", ssid, "ifname", iface)
+
+	cmd := exec.CommandContext(ctx, "sudo", args...)
+	if password != "" {
+		cmd.Stdin = strings.NewReader(password + "\n")
+	}
+	// Errors go to stderr, clear of the password prompt on stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return explainNmcliError(strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+// explainNmcliError turns nmcli's error output for a failed connect into an
+// error a user can act on.
+func explainNmcliError(out string, err error) error {
+	lower := strings.ToLower(out)
+	switch {
+	case strings.Contains(lower, "secrets were required"),
+		strings.Contains(lower, "802-11-wireless-security.psk: property is invalid"),
+		strings.Contains(lower, "4-way handshake"):
+		return errWifiAuth
+	case strings.Contains(lower, "no network with ssid"):
+		return fmt.Errorf("network not found")
+	case out != "":
+		return fmt.Errorf("%s", strings.TrimPrefix(out, "Error: "))
+	}
+	return err
+}
+
+// connectWpaSupplicant adds the network to wpa_supplicant, switches to it
+// and saves the config once it has associated.
+func connectWpaSupplicant(ctx context.Context, iface, ssid, password string) error {
+	id, err := wpaCli(ctx, iface, "add_network")
+	if err != nil {
+		return err
+	}
+	// Any failure drops the new network and falls back to the ones that
+	// worked before
+	abandon := func() {
+		cleanup, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		wpaCli(cleanup, iface, "remove_network", id)
+		wpaCli(cleanup, iface, "enable_network", "all")
+		wpaCli(cleanup, iface, "reassociate")
+	}
+
+	// wpa_supplicant takes the SSID as hex, which needs no quoting
+	if _, err := wpaCli(ctx, iface, "set_network", id, "ssid", hex.EncodeToString([]byte(ssid))); err != nil {
+		abandon()
+		return err
+	}
+	switch {
+	case password == "":
+		_, err = wpaCli(ctx, iface, "set_network", id, "key_mgmt", "NONE")
+	case len(password) == 64 && isHex(password):
+		err = wpaCliSecret(ctx, iface, id, password)
+	default:
+		// A passphrase goes in quotes, used literally up to the last one
+		err = wpaCliSecret(ctx, iface, id, `"`+password+`"`)
+	}
+	if err != nil {
+		abandon()
+		return err
+	}
+	if _, err := wpaCli(ctx, iface, "select_network", id); err != nil {
+		abandon()
+		return err
+	}
+
+	ticker := time.NewTicker(wifiConnectPoll)
+	defer ticker.Stop()
+	for {
+		status, _ := wpaCli(ctx, iface, "status")
+		if wpaConnectedTo(status, ssid) {
+			// select_network disabled the others; keep them as fallbacks
+			wpaCli(ctx, iface, "enable_network", "all")
+			if _, err := wpaCli(ctx, iface, "save_config"); err != nil {
+				return fmt.Errorf("connected, but saving the network failed: %w", err)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			abandon()
+			if password != "" {
+				return fmt.Errorf("could not connect to %s, check the password", ssid)
+			}
+			return fmt.Errorf("could not connect to %s", ssid)
+		case <-ticker.C:
+		}
+	}
+}
+
+// wpaCli runs a wpa_cli command on iface through sudo and returns its
+// trimmed output.
+func wpaCli(ctx context.Context, iface string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "sudo", append([]string{"wpa_cli", "-i", iface}, args...)...).Output()
+	result := strings.TrimSpace(string(out))
+	if err == nil && result == "FAIL" {
+		err = fmt.Errorf("wpa_cli %s failed", args[0])
+	}
+	return result, err
+}
+
+// wpaCliSecret sets a network's psk by typing the command into an
+// interactive wpa_cli on stdin, so the key never appears in a command line,
+// where any local user could read it.
+func wpaCliSecret(ctx context.Context, iface, id, psk string) error {
+	cmd := exec.CommandContext(ctx, "sudo", "wpa_cli", "-i", iface)
+	cmd.Stdin = strings.NewReader("set_network " + id + " psk " + psk + "\nquit\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("wpa_cli set_network failed: %w", err)
+	}
+	return wpaInteractiveResult(string(out))
+}
+
+// wpaInteractiveResult checks the reply to a single command in interactive
+// wpa_cli output, where replies may follow a "> " prompt among the banner.
+func wpaInteractiveResult(out string) error {
+	for _, line := range strings.Split(out, "\n") {
+		switch strings.TrimSpace(strings.TrimLeft(line, "> ")) {
+		case "OK":
+			return nil
+		case "FAIL":
+			return fmt.Errorf("wpa_cli set_network failed")
+		}
+	}
+	return fmt.Errorf("wpa_cli set_network gave no reply")
+}
+
+// wpaConnectedTo reports whether `wpa_cli status` output shows a completed
+// association with ssid.
+func wpaConnectedTo(status, ssid string) bool {
+	var completed, matches bool
+	for _, line := range strings.Split(status, "\n") {
+		switch line {
+		case "wpa_state=COMPLETED":
+			completed = true
+		case "ssid=" + ssid:
+			matches = true
+		}
+	}
+	return completed && matches
+}
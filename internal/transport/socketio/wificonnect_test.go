@@ -0,0 +1,77 @@
+package socketio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateWifiCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		ssid     string
+		password string
+		ok       bool
+	}{
+		{"open network", "Cafe", "", true},
+		{"passphrase", "Home", "correct horse", true},
+		{"raw psk", "Home", strings.Repeat("ab", 32), true},
+		{"missing ssid", "", "correct horse", false},
+		{"long ssid", strings.Repeat("x", 33), "", false},
+		{"short password", "Home", "secret", false},
+		{"long password", "Home", strings.Repeat("x", 64), false},
+		{"non-ascii password", "Home", "pässwörter", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWifiCredentials(tt.ssid, tt.password)
+			if (err == nil) != tt.ok {
+				t.Errorf("validateWifiCredentials(%q, %q) = %v, want ok %v", tt.ssid, tt.password, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExplainNmcliError(t *testing.T) {
+	failed := errors.New("exit status 4")
+
+	err := explainNmcliError("Error: Connection activation failed: (7) Secrets were required, but not provided.", failed)
+	if !errors.Is(err, errWifiAuth) {
+		t.Errorf("bad password: got %v, want %v", err, errWifiAuth)
+	}
+	if err := explainNmcliError("Error: No network with SSID 'Gone' found.", failed); err == nil || err.Error() != "network not found" {
+		t.Errorf("missing network: got %v", err)
+	}
+	if err := explainNmcliError("Error: Device 'wlan0' not found.", failed); err == nil || err.Error() != "Device 'wlan0' not found." {
+		t.Errorf("other failure: got %v", err)
+	}
+	if err := explainNmcliError("", failed); err != failed {
+		t.Errorf("no output: got %v, want %v", err, failed)
+	}
+}
+
+func TestWpaConnectedTo(t *testing.T) {
+	status := "bssid=11:22:33:44:55:66\nfreq=5180\nssid=Home\nid=1\nmode=station\nwpa_state=COMPLETED\nip_address=192.168.1.20"
+	if !wpaConnectedTo(status, "Home") {
+		t.Error("wpaConnectedTo(Home) = false for a completed association")
+	}
+	if wpaConnectedTo(status, "Cafe") {
+		t.Error("wpaConnectedTo(Cafe) = true for another network")
+	}
+	if wpaConnectedTo(strings.Replace(status, "COMPLETED", "4WAY_HANDSHAKE", 1), "Home") {
+		t.Error("wpaConnectedTo(Home) = true mid-handshake")
+	}
+}
+
+func TestWpaInteractiveResult(t *testing.T) {
+	banner := "wpa_cli v2.10\nCopyright (c) 2004-2022, Jouni Malinen <j@w1.fi> and contributors\n\nSelected interface 'wlan0'\n\nInteractive mode\n\n"
+	if err := wpaInteractiveResult(banner + "> OK\n> "); err != nil {
+		t.Errorf("wpaInteractiveResult(OK) = %v, want nil", err)
+	}
+	if err := wpaInteractiveResult(banner + "> FAIL\n> "); err == nil {
+		t.Error("wpaInteractiveResult(FAIL) = nil, want error")
+	}
+	if err := wpaInteractiveResult("Could not connect to wpa_supplicant: wlan0 - re-trying\n"); err == nil {
+		t.Error("wpaInteractiveResult without a reply = nil, want error")
+	}
+}
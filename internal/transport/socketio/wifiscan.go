@@ -0,0 +1,233 @@
+package socketio
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edumarques81/stellar-volumio-audioplayer-backend/internal/system/network"
+)
+
+const (
+	// wifiScanTimeout bounds a scan; iw can take several seconds per band.
+	wifiScanTimeout = 15 * time.Second
+
+	// wifiScanCacheTTL is how long scan results are reused, so clients
+	// refreshing a setup screen don't each trigger a scan.
+	wifiScanCacheTTL = 30 * time.Second
+)
+
+// WifiNetwork is a network found by a Wi-Fi scan.
+type WifiNetwork struct {
+	SSID     string `json:"ssid"`
+	Signal   int    `json:"signal"`   // Signal strength 0-100
+	Security string `json:"security"` // "open", "wep", "wpa", "wpa2" or "wpa3"
+}
+
+// WifiScanResponse is the pushWifiNetworks payload.
+type WifiScanResponse struct {
+	Networks []WifiNetwork `json:"networks"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// wifiScanCache shares one scan between concurrent requests and reuses its
+// result for wifiScanCacheTTL.
+type wifiScanCache struct {
+	scan func() ([]WifiNetwork, error)
+
+	mu       sync.Mutex
+	networks []WifiNetwork
+	scanned  time.Time
+	inflight *wifiScan // Scan in progress; nil when there is none
+}
+
+// wifiScan is one scan; networks and err are set before done is closed.
+type wifiScan struct {
+	done     chan struct{}
+	networks []WifiNetwork
+	err      error
+}
+
+// wifiScans caches scans for all clients.
+var wifiScans = &wifiScanCache{scan: scanWifi}
+
+// ScanWifiNetworks returns the Wi-Fi networks in range, strongest first,
+// scanning at most once every wifiScanCacheTTL. ctx only bounds the wait;
+// the scan itself runs to completion for anyone else waiting on it.
+func ScanWifiNetworks(ctx context.Context) ([]WifiNetwork, error) {
+	return wifiScans.get(ctx)
+}
+
+func (c *wifiScanCache) get(ctx context.Context) ([]WifiNetwork, error) {
+	c.mu.Lock()
+	if c.networks != nil && time.Since(c.scanned) < wifiScanCacheTTL {
+		networks := c.networks
+		c.mu.Unlock()
+		return networks, nil
+	}
+	scan := c.inflight
+	if scan == nil {
+		scan = &wifiScan{done: make(chan struct{})}
+		c.inflight = scan
+		go c.run(scan)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-scan.done:
+		return scan.networks, scan.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run performs scan and caches a successful result.
+func (c *wifiScanCache) run(scan *wifiScan) {
+	scan.networks, scan.err = c.scan()
+
+	c.mu.Lock()
+	c.inflight = nil
+	if scan.err == nil {
+		c.networks = scan.networks
+		c.scanned = time.Now()
+	}
+	c.mu.Unlock()
+	close(scan.done)
+}
+
+// scanWifi scans with `sudo iw` on the first wireless interface, under its
+// own timeout rather than any one client's context.
+func scanWifi() ([]WifiNetwork, error) {
+	iface := wirelessInterface()
+	if iface == "" {
+		return nil, fmt.Errorf("no Wi-Fi interface found")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wifiScanTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sudo", "iw", "dev", iface, "scan").Output()
+	if err != nil {
+		if _, lookErr := exec.LookPath("iw"); lookErr != nil {
+			return nil, fmt.Errorf("scanning needs the iw tool: %w", lookErr)
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("scan on %s timed out", iface)
+		}
+		return nil, fmt.Errorf("scan on %s failed: %w", iface, err)
+	}
+	return parseIwScan(string(out)), nil
+}
+
+// wirelessInterface returns the first wireless network interface, or "".
+func wirelessInterface() string {
+	matches, _ := filepath.Glob("/sys/class/net/*/wireless")
+	if len(matches) == 0 {
+		return ""
+	}
+	sort.Strings(matches)
+	return filepath.Base(filepath.Dir(matches[0]))
+}
+
+// unescapeIwSSID turns an SSID as printed by iw back into its raw bytes. iw
+// prints bytes that aren't printable ASCII, backslashes and leading or
+// trailing spaces as \xNN, so a name like "Café" arrives as Caf\xc3\xa9.
+func unescapeIwSSID(s string) string {
+	s = strings.TrimSpace(s)
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseIwScan parses `iw dev <iface> scan` output into networks, keeping
+// the strongest access point of each SSID and leaving out hidden ones.
+func parseIwScan(out string) []WifiNetwork {
+	best := make(map[string]WifiNetwork)
+	var current *WifiNetwork
+	var rsn, wpa, privacy, sae bool
+
+	finish := func() {
+		if current == nil || current.SSID == "" {
+			return
+		}
+		switch {
+		case sae:
+			current.Security = "wpa3"
+		case rsn:
+			current.Security = "wpa2"
+		case wpa:
+			current.Security = "wpa"
+		case privacy:
+			current.Security = "wep"
+		default:
+			current.Security = "open"
+		}
+		if prev, ok := best[current.SSID]; !ok || current.Signal > prev.Signal {
+			best[current.SSID] = *current
+		}
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(line, "BSS ") {
+			finish()
+			current = &WifiNetwork{}
+			rsn, wpa, privacy, sae = false, false, false, false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "SSID:"):
+			current.SSID = unescapeIwSSID(strings.TrimPrefix(trimmed, "SSID:"))
+			// Hidden networks show as NULs
+			if strings.Trim(current.SSID, "\x00") == "" {
+				current.SSID = ""
+			}
+		case strings.HasPrefix(trimmed, "signal:"):
+			fields := strings.Fields(strings.TrimPrefix(trimmed, "signal:"))
+			if len(fields) > 0 {
+				if dbm, err := strconv.ParseFloat(fields[0], 64); err == nil {
+					current.Signal = network.SignalPercent(int(dbm))
+				}
+			}
+		case strings.HasPrefix(trimmed, "capability:"):
+			privacy = strings.Contains(trimmed, "Privacy")
+		case strings.HasPrefix(trimmed, "RSN:"):
+			rsn = true
+		case strings.HasPrefix(trimmed, "WPA:"):
+			wpa = true
+		case strings.Contains(trimmed, "Authentication suites:"):
+			sae = sae || (rsn && strings.Contains(trimmed, "SAE"))
+		}
+	}
+	finish()
+
+	networks := make([]WifiNetwork, 0, len(best))
+	for _, n := range best {
+		networks = append(networks, n)
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		if networks[i].Signal != networks[j].Signal {
+			return networks[i].Signal > networks[j].Signal
+		}
+		return networks[i].SSID < networks[j].SSID
+	})
+	return networks
+}
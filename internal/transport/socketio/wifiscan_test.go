@@ -0,0 +1,128 @@
+package socketio
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+const iwScanOutput = `BSS 11:22:33:44:55:66(on wlan0) -- associated
+	freq: 5180
+	signal: -48.00 dBm
+	SSID: Home
+	capability: ESS Privacy SpectrumMgmt (0x0111)
+	RSN:	 * Version: 1
+		 * Group cipher: CCMP
+		 * Authentication suites: PSK
+BSS 11:22:33:44:55:67(on wlan0)
+	signal: -70.00 dBm
+	SSID: Home
+	capability: ESS Privacy (0x0011)
+	RSN:	 * Version: 1
+		 * Authentication suites: PSK
+BSS aa:bb:cc:dd:ee:01(on wlan0)
+	signal: -60.00 dBm
+	SSID: Cafe
+	capability: ESS (0x0001)
+BSS aa:bb:cc:dd:ee:02(on wlan0)
+	signal: -55.00 dBm
+	SSID: Neighbour
+	capability: ESS Privacy (0x0011)
+	RSN:	 * Version: 1
+		 * Authentication suites: SAE
+BSS aa:bb:cc:dd:ee:03(on wlan0)
+	signal: -80.00 dBm
+	SSID: Old
+	capability: ESS Privacy (0x0011)
+	WPA:	 * Version: 1
+		 * Authentication suites: PSK
+BSS aa:bb:cc:dd:ee:04(on wlan0)
+	signal: -82.00 dBm
+	SSID: Legacy
+	capability: ESS Privacy (0x0011)
+BSS aa:bb:cc:dd:ee:07(on wlan0)
+	signal: -65.00 dBm
+	SSID: Caf\xc3\xa9
+	capability: ESS (0x0001)
+BSS aa:bb:cc:dd:ee:05(on wlan0)
+	signal: -40.00 dBm
+	SSID: \x00\x00\x00\x00
+	capability: ESS Privacy (0x0011)
+BSS aa:bb:cc:dd:ee:06(on wlan0)
+	signal: -45.00 dBm
+	SSID:
+	capability: ESS (0x0001)
+`
+
+func TestParseIwScan(t *testing.T) {
+	want := []WifiNetwork{
+		{SSID: "Home", Signal: 100, Security: "wpa2"},
+		{SSID: "Neighbour", Signal: 90, Security: "wpa3"},
+		{SSID: "Cafe", Signal: 80, Security: "open"},
+		{SSID: "Café", Signal: 70, Security: "open"},
+		{SSID: "Old", Signal: 40, Security: "wpa"},
+		{SSID: "Legacy", Signal: 36, Security: "wep"},
+	}
+	if got := parseIwScan(iwScanOutput); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseIwScan() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestUnescapeIwSSID(t *testing.T) {
+	tests := map[string]string{
+		`Home`:          "Home",
+		`Caf\xc3\xa9`:   "Café",
+		`\x20Lobby\x20`: " Lobby ",
+		`Back\x5cslash`: `Back\slash`,
+		`My Net`:        "My Net",
+		`Odd\xzz`:       `Odd\xzz`,
+		`Cut\x4`:        `Cut\x4`,
+		`\x00\x00`:      "\x00\x00",
+	}
+	for in, want := range tests {
+		if got := unescapeIwSSID(in); got != want {
+			t.Errorf("unescapeIwSSID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseIwScanEmpty(t *testing.T) {
+	if got := parseIwScan(""); got == nil || len(got) != 0 {
+		t.Errorf("parseIwScan(\"\") = %#v, want an empty list", got)
+	}
+}
+
+func TestWifiScanCacheSharesScan(t *testing.T) {
+	release := make(chan struct{})
+	var scans atomic.Int32
+	cache := &wifiScanCache{scan: func() ([]WifiNetwork, error) {
+		scans.Add(1)
+		<-release
+		return []WifiNetwork{{SSID: "Home", Signal: 80, Security: "wpa2"}}, nil
+	}}
+
+	// A client that gives up doesn't cancel the scan others wait for
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.get(ctx); err != context.Canceled {
+		t.Fatalf("get with cancelled context error = %v, want context.Canceled", err)
+	}
+
+	result := make(chan []WifiNetwork)
+	go func() {
+		networks, _ := cache.get(context.Background())
+		result <- networks
+	}()
+	close(release)
+	if networks := <-result; len(networks) != 1 || networks[0].SSID != "Home" {
+		t.Errorf("get = %+v, want Home", networks)
+	}
+
+	if _, err := cache.get(context.Background()); err != nil {
+		t.Fatalf("cached get error = %v", err)
+	}
+	if n := scans.Load(); n != 1 {
+		t.Errorf("%d scans, want 1", n)
+	}
+}